@@ -6,14 +6,28 @@ import (
 	"os"
 
 	"github.com/talkincode/sshmcp/internal/app"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// Build information injected with -ldflags "-X main.Version=... -X main.Commit=... -X main.BuildDate=..."
+var (
+	Version   = ""
+	Commit    = ""
+	BuildDate = ""
 )
 
 func main() {
+	app.SetBuildInfo(Version, Commit, BuildDate)
 	if err := app.Run(os.Args); err != nil {
 		if errors.Is(err, app.ErrUsage) {
 			os.Exit(1)
 		}
-		fmt.Fprintf(os.Stderr, "sshx: %v\n", err)
+		var interruptErr *app.InterruptError
+		if errors.As(err, &interruptErr) {
+			fmt.Fprintf(os.Stderr, "sshx: %s\n", logger.Plain(err.Error()))
+			os.Exit(interruptErr.ExitCode())
+		}
+		fmt.Fprintf(os.Stderr, "sshx: %s\n", logger.Plain(err.Error()))
 		os.Exit(1)
 	}
 }
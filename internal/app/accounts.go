@@ -0,0 +1,125 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// accountPublicKey reads the public key file given with --pubkey, which
+// --push-key requires and --add-user installs for the new user
+func accountPublicKey(config *sshclient.Config) (string, error) {
+	if config.PublicKeyPath == "" {
+		if config.AccountAction == "push-key" {
+			return "", fmt.Errorf("--push-key needs --pubkey=FILE")
+		}
+		return "", nil
+	}
+	data, err := os.ReadFile(expandHome(config.PublicKeyPath)) // #nosec G304 -- public key chosen by the user
+	if err != nil {
+		return "", fmt.Errorf("failed to read public key: %w", err)
+	}
+	if _, _, err := sshclient.ParsePublicKey(string(data)); err != nil {
+		return "", fmt.Errorf("%s: %w", config.PublicKeyPath, err)
+	}
+	return string(data), nil
+}
+
+// accountName is the user --account names, or the login user
+func accountName(config *sshclient.Config) string {
+	if config.Account != "" {
+		return config.Account
+	}
+	return config.User
+}
+
+// handleAccount creates users and manages their authorized keys on the host
+// (--add-user, --push-key, --list-keys and --revoke-key). Every action is
+// idempotent: existing users and keys are left alone and revoking a key that
+// is gone succeeds.
+func handleAccount(client *sshclient.SSHClient, config *sshclient.Config, publicKey string) error {
+	lg := logger.GetLogger()
+	req := sshclient.RequestFromConfig(config)
+	account := accountName(config)
+
+	switch config.AccountAction {
+	case "add-user":
+		created, err := client.CreateUser(config.Account, config.AccountShell, req)
+		if err != nil {
+			return fmt.Errorf("failed to create user %s: %w", config.Account, err)
+		}
+		if created {
+			lg.Success("Created user %s on %s", config.Account, config.Host)
+		} else {
+			lg.Info("User %s already exists on %s", config.Account, config.Host)
+		}
+		if publicKey == "" {
+			return nil
+		}
+		return pushKey(client, config.Account, config.Host, publicKey, req)
+	case "push-key":
+		return pushKey(client, account, config.Host, publicKey, req)
+	case "list-keys":
+		keys, err := client.AuthorizedKeys(account, req)
+		if err != nil {
+			return fmt.Errorf("failed to list the keys of %s: %w", account, err)
+		}
+		if config.JSONOutput {
+			data, err := json.MarshalIndent(keys, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode keys: %w", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		fmt.Print(formatAuthorizedKeys(keys))
+		return nil
+	case "revoke-key":
+		revoked, err := client.RevokeAuthorizedKeys(account, config.KeyMatch, req)
+		if err != nil {
+			return fmt.Errorf("failed to revoke keys of %s: %w", account, err)
+		}
+		if len(revoked) == 0 {
+			lg.Info("No key of %s on %s matches %s", account, config.Host, config.KeyMatch)
+			return nil
+		}
+		fmt.Print(formatAuthorizedKeys(revoked))
+		lg.Success("Revoked %d key(s) of %s on %s", len(revoked), account, config.Host)
+		return nil
+	}
+	return fmt.Errorf("unknown account action: %s", config.AccountAction)
+}
+
+// pushKey installs publicKey for account and reports whether it was new
+func pushKey(client *sshclient.SSHClient, account, host, publicKey string, req sshclient.Request) error {
+	key, added, err := client.AddAuthorizedKey(account, publicKey, req)
+	if err != nil {
+		return fmt.Errorf("failed to add key for %s: %w", account, err)
+	}
+	if added {
+		logger.GetLogger().Success("Added %s for %s on %s", key.Fingerprint, account, host)
+	} else {
+		logger.GetLogger().Info("%s is already authorized for %s on %s", key.Fingerprint, account, host)
+	}
+	return nil
+}
+
+// formatAuthorizedKeys lists keys one per line: fingerprint, type and comment
+func formatAuthorizedKeys(keys []sshclient.AuthorizedKey) string {
+	var output strings.Builder
+	for _, key := range keys {
+		line := key.Fingerprint + "  " + key.Type
+		if key.Comment != "" {
+			line += "  " + key.Comment
+		}
+		if len(key.Options) > 0 {
+			line += "  [" + strings.Join(key.Options, ",") + "]"
+		}
+		output.WriteString(line + "\n")
+	}
+	return output.String()
+}
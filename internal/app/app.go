@@ -7,7 +7,9 @@ import (
 	"log"
 	"net"
 	"os"
+	"os/signal"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 
@@ -20,7 +22,24 @@ import (
 var ErrUsage = errors.New("usage displayed")
 
 // Run executes the CLI using the provided arguments (typically os.Args).
-func Run(args []string) (err error) {
+// SIGINT/SIGTERM cancel the running operation, drain the connection pool and
+// return an *InterruptError carrying the conventional exit code.
+func Run(args []string) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, shutdownSignals...)
+	defer signal.Stop(sigCh)
+
+	return runUntilSignal(func() error { return run(args) }, sigCh)
+}
+
+// run executes the CLI without signal handling
+func run(args []string) (err error) {
+	logger.SetPlain(plainOutputRequested(args))
+	registerMiddleware()
+	configureSecretCache()
+	configurePool()
+	dropExpiredHosts()
+
 	// Handle MCP stdio mode
 	if len(args) >= 2 && (args[1] == "mcp-stdio" || args[1] == "--mcp-stdio") {
 		// Standard log output should be disabled to avoid interfering with JSON-RPC
@@ -62,7 +81,30 @@ func Run(args []string) (err error) {
 			}
 		}
 
+		// Drain pooled connections when the client disconnects
+		defer runShutdownHooks()
+
+		settings, settingsErr := LoadSettings()
+		if settingsErr == nil {
+			if pinned := pinHosts(sshclient.GetConnectionPool(), settings); len(pinned) > 0 {
+				logger.GetLogger().Debug("Pinned connections: %s", strings.Join(pinned, ", "))
+			}
+		}
+
 		server := NewMCPServer()
+		if restrictErr := server.RestrictTools(mcpToolFilter(settings, args[2:])); restrictErr != nil {
+			return restrictErr
+		}
+		caller, authErr := mcpCallerFromEnv(settings)
+		if authErr != nil {
+			return authErr
+		}
+		server.caller = caller
+		server.scheduler = NewScheduler(settings)
+		server.scheduler.Start()
+		defer server.scheduler.Stop()
+		defer startEventSinks(settings)()
+		defer startExpiryPruning()()
 		if startErr := server.Start(); startErr != nil {
 			return startErr
 		}
@@ -79,6 +121,32 @@ func Run(args []string) (err error) {
 	//nolint:errcheck // Loading .env is optional
 	_ = godotenv.Load()
 
+	// Check the local environment
+	if args[1] == "doctor" || args[1] == "--doctor" {
+		return handleDoctor()
+	}
+
+	// Report or update the installed version
+	if args[1] == "--version" || args[1] == "version" {
+		fmt.Println(GetBuildInfo())
+		return nil
+	}
+	if args[1] == "self-update" {
+		return handleSelfUpdate(args)
+	}
+	// Generate a keypair and enroll it on hosts
+	if args[1] == "keygen" {
+		return handleKeygen(args[2:])
+	}
+	// Serve the tools over the local REST API
+	if args[1] == "daemon" {
+		return handleDaemon(args[2:])
+	}
+	// Serve CLI calls made with --control-persist
+	if args[1] == controlMasterCommand {
+		return handleControlMaster(args[2:])
+	}
+
 	// Set log level from environment variable
 	if logLevelStr := os.Getenv("SSHX_LOG_LEVEL"); logLevelStr != "" {
 		logLevel := logger.LogLevelFromString(logLevelStr)
@@ -87,6 +155,13 @@ func Run(args []string) (err error) {
 
 	// Parse command-line arguments
 	config := ParseArgs(args)
+	applyVerbosity(config.Verbosity)
+	if config.Force {
+		config.ConfirmForce = forceConfirmation(os.Stdin, os.Stderr, isTerminal(os.Stdin), config.ForceReason == "")
+	}
+	if settings, settingsErr := LoadSettings(); settingsErr == nil {
+		applyHostKeySettings(config, settings)
+	}
 
 	// Handle password management mode
 	if config.Mode == "password" {
@@ -104,15 +179,110 @@ func Run(args []string) (err error) {
 		return nil
 	}
 
-	// Try to resolve host from settings if not an IP address
-	if config.Host != "" && !isIPAddress(config.Host) {
-		if resolveErr := resolveHostFromSettings(config); resolveErr != nil {
+	// Handle multi-host file transfers
+	if config.Mode == "sftp" && config.Hosts != "" {
+		if fleetErr := HandleFleetTransfer(config); fleetErr != nil {
+			return fmt.Errorf("multi-host transfer failed: %w", fleetErr)
+		}
+		return nil
+	}
+
+	// Handle MCP trust store management
+	if config.Mode == "knownhosts" {
+		if khErr := HandleKnownHostsManagement(config); khErr != nil {
+			return fmt.Errorf("known_hosts management failed: %w", khErr)
+		}
+		return nil
+	}
+
+	// Handle recording playback
+	if config.Mode == "play" {
+		return playRecording(config.LocalPath)
+	}
+
+	// Handle connection pool mode
+	if config.Mode == "pool" {
+		if poolErr := HandlePoolManagement(config); poolErr != nil {
+			return fmt.Errorf("pool management failed: %w", poolErr)
+		}
+		return nil
+	}
+
+	// Resolve the host name or address from settings
+	config.Source = "cli"
+	if config.Host != "" {
+		if resolveErr := resolveHostFromSettings(config); resolveErr != nil && !isIPAddress(config.Host) {
 			logger.GetLogger().Info("Note: Could not find host '%s' in settings, using as hostname directly", config.Host)
 		}
 	}
 
+	// Explain the safety check of a command without connecting
+	if config.Mode == "explain" {
+		return handleExplain(config)
+	}
+
+	// The agent is forwarded only to allowed hosts
+	if config.ForwardAgent || config.ForwardX11 {
+		settings, _ := LoadSettings() //nolint:errcheck // nothing is allowed without settings
+		if err = checkForwarding(config, settings); err != nil {
+			return err
+		}
+	}
+
+	// Commands run inside a container are wrapped in the runtime's exec
+	if config.Container != "" {
+		if config.Mode != "ssh" {
+			return fmt.Errorf("--container only applies to commands")
+		}
+		if err = sshclient.ValidateContainerName(config.Container); err != nil {
+			return err
+		}
+		if err = sshclient.ValidateContainerRuntime(config.ContainerRuntime); err != nil {
+			return err
+		}
+	}
+
+	// Watched commands are re-run until interrupted or a condition matches
+	watching := config.WatchInterval > 0 || config.WatchUntil != "" || config.WatchCount > 0
+	if watching {
+		if config.Mode != "ssh" {
+			return fmt.Errorf("--watch only applies to commands")
+		}
+		if _, err = watchOptions(config.WatchInterval, config.WatchUntil, config.WatchCount); err != nil {
+			return err
+		}
+	}
+
+	var trashPurgeAge time.Duration
+	if config.Mode == "trash-purge" && config.TrashPurgeAge != "" {
+		if trashPurgeAge, err = parseDayDuration(config.TrashPurgeAge); err != nil {
+			return fmt.Errorf("--trash-purge: %w", err)
+		}
+	}
+
+	var publicKey string
+	if config.Mode == "account" {
+		if publicKey, err = accountPublicKey(config); err != nil {
+			return err
+		}
+	}
+
+	var waitCondition sshclient.WaitCondition
+	if config.Mode == "wait" {
+		if waitCondition, err = sshclient.ParseWaitCondition(config.WaitFor); err != nil {
+			return err
+		}
+	}
+
+	var expectSteps []sshclient.ExpectStep
+	if config.Mode == "interact" {
+		if expectSteps, err = loadExpectScript(config.InteractScript, os.Stdin); err != nil {
+			return err
+		}
+	}
+
 	// Auto-fill sudo password if needed
-	if strings.Contains(config.Command, "sudo") && config.SudoKey != "" {
+	if (strings.Contains(config.Command, "sudo") || config.RunAs != "") && config.SudoKey != "" {
 		password, pwdErr := sshclient.GetSudoPassword(config.SudoKey)
 		if pwdErr != nil {
 			logger.GetLogger().Warning("failed to get sudo password from keyring: %v", pwdErr)
@@ -123,12 +293,35 @@ func Run(args []string) (err error) {
 		}
 	}
 
+	// Render upload templates for the resolved host
+	if config.Mode == "sftp" && config.UploadTemplate {
+		cleanup, tmplErr := renderUploadTemplate(config)
+		if tmplErr != nil {
+			return fmt.Errorf("template rendering failed: %w", tmplErr)
+		}
+		defer cleanup()
+	}
+
+	// Reuse the connection held by the control master
+	if usesControlMaster(config, watching) {
+		if persist := controlPersist(config); persist > 0 {
+			if handled, controlErr := executeViaControlMaster(config, persist); handled {
+				return controlErr
+			}
+		}
+	}
+
 	// Create SSH client
 	client, err := sshclient.NewSSHClient(config)
 	if err != nil {
 		return fmt.Errorf("failed to create SSH client: %w", err)
 	}
 	defer errutil.HandleCloseError(&err, client)
+	// Interrupting the process closes the connection so blocked sessions return
+	removeHook := onShutdown(func() {
+		_ = client.ForceClose() //nolint:errcheck // best-effort on shutdown
+	})
+	defer removeHook()
 
 	// Connect to remote host (use direct connection for CLI mode, no need for pooling)
 	if err = client.ConnectDirect(); err != nil {
@@ -137,23 +330,129 @@ func Run(args []string) (err error) {
 
 	// Handle SFTP mode
 	if config.Mode == "sftp" {
-		if err = client.ExecuteSftp(); err != nil {
+		if err = client.ExecuteSftp(sshclient.RequestFromConfig(config)); err != nil {
 			return fmt.Errorf("SFTP operation failed: %w", err)
 		}
 		return nil
 	}
 
-	// Handle SSH command execution
-	if err = client.ExecuteCommand(); err != nil {
-		// EOF is a normal session close signal, not an error
-		if !errutil.IsEOFError(err) {
-			return fmt.Errorf("failed to execute command: %w", err)
+	// Handle removal of leftover script files
+	if config.Mode == "cleanup" {
+		removed, cleanupErr := client.CleanupTempFiles(config.CleanupAge)
+		if cleanupErr != nil {
+			return cleanupErr
+		}
+		for _, path := range removed {
+			fmt.Println(path)
+		}
+		logger.GetLogger().Success("Removed %d leftover temp file(s) from %s", len(removed), config.Host)
+		return nil
+	}
+
+	// Handle purging of files moved to the remote trash
+	if config.Mode == "trash-purge" {
+		purged, purgeErr := client.PurgeTrash(trashPurgeAge)
+		if purgeErr != nil {
+			return purgeErr
+		}
+		for _, name := range purged.Removed {
+			fmt.Println(name)
+		}
+		logger.GetLogger().Success("Purged %d trash entries from %s, kept %d", len(purged.Removed), config.Host, purged.Kept)
+		return nil
+	}
+
+	// Record the state of the host, or compare it with the last record
+	if config.Mode == "snapshot" {
+		return handleSnapshot(client, config)
+	}
+	if config.Mode == "diff-snapshot" {
+		return handleDiffSnapshot(client, config)
+	}
+
+	// Create users and manage their authorized keys
+	if config.Mode == "account" {
+		return handleAccount(client, config, publicKey)
+	}
+
+	// Reboot the host and wait for it to return
+	if config.Mode == "reboot" {
+		return handleReboot(client, config)
+	}
+
+	// Handle script execution; output is streamed as the script runs
+	if config.Mode == "script" {
+		if config.ScriptTimeout == 0 {
+			if settings, settingsErr := LoadSettings(); settingsErr == nil {
+				config.ScriptTimeout = firstDuration("script_timeout", settings.ScriptTimeout)
+			}
 		}
+		config.StreamOutput = os.Stdout
+		if _, err = client.ExecuteScript(config.LocalPath, sshclient.RequestFromConfig(config)); err != nil {
+			return fmt.Errorf("failed to execute script: %w", err)
+		}
+		return nil
+	}
+
+	if watching {
+		err = watchCommand(client, config)
+		return err
+	}
+
+	// Block until the condition holds on the remote host
+	if config.Mode == "wait" {
+		logger.GetLogger().Info("Waiting for %s on %s...", waitCondition, config.Host)
+		waited, waitErr := client.WaitFor(waitCondition, config.WaitTimeout, config.WaitInterval, sshclient.RequestFromConfig(config))
+		if waitErr != nil {
+			return waitErr
+		}
+		logger.GetLogger().Success("%s ready after %s", waitCondition, waited)
+		return nil
+	}
+
+	// Answer the prompts of an interactive command
+	if config.Mode == "interact" {
+		return handleInteract(client, config, expectSteps)
+	}
+
+	// Handle SSH command execution
+	if err = client.ExecuteCommand(sshclient.RequestFromConfig(config)); err != nil {
+		return fmt.Errorf("failed to execute command: %w", err)
 	}
 
 	return nil
 }
 
+// plainOutputRequested reports whether emoji should be replaced with ASCII
+// markers: --no-color on the command line, or a non-empty NO_COLOR
+// (https://no-color.org). sshx prints no ANSI colors, so its emoji are the
+// only decoration to strip.
+func plainOutputRequested(args []string) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return true
+	}
+	for _, arg := range args[1:] {
+		if arg == "--no-color" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyVerbosity maps -q/-v/-vv onto the logger level for this invocation,
+// taking precedence over SSHX_LOG_LEVEL. Logs go to stderr, so in quiet
+// mode stdout carries only remote output; warnings and errors still show.
+func applyVerbosity(verbosity int) {
+	switch {
+	case verbosity < 0:
+		logger.GetLogger().SetLevel(logger.LogLevelWarning)
+	case verbosity == 1:
+		logger.GetLogger().SetLevel(logger.LogLevelDebug)
+	case verbosity >= 2:
+		logger.GetLogger().SetLevel(logger.LogLevelTrace)
+	}
+}
+
 // isIPAddress checks if a string is a valid IP address
 func isIPAddress(host string) bool {
 	return net.ParseIP(host) != nil
@@ -167,38 +466,83 @@ func resolveHostFromSettings(config *sshclient.Config) error {
 		return err
 	}
 
-	// Try to find host by name
-	hostConfig, err := GetHost(settings, config.Host)
-	if err != nil {
-		return err
+	target := config.Host
+	hostConfig := applyHostSettings(config, settings)
+	if hostConfig == nil {
+		return fmt.Errorf("host '%s' not found", target)
 	}
 
-	logger.GetLogger().Success("Found host '%s' in settings", config.Host)
+	lg := logger.GetLogger()
+	lg.Success("Found host '%s' in settings", hostConfig.Name)
+	if hostConfig.PasswordKey != "" && config.SudoKey == hostConfig.PasswordKey {
+		lg.Success("Using password key: %s", hostConfig.PasswordKey)
+	}
+	if config.KeyPath != "" {
+		lg.Success("Using SSH key: %s", config.KeyPath)
+	}
+	return nil
+}
 
-	// Update config with host settings
-	config.Host = hostConfig.Host
-	if config.Port == "" || config.Port == "22" {
-		if hostConfig.Port != "" {
-			config.Port = hostConfig.Port
+// lookupHost finds the configured host target refers to, by name first and
+// then by address
+func lookupHost(settings *Settings, target string) *HostConfig {
+	if settings == nil || target == "" {
+		return nil
+	}
+	for i := range settings.Hosts {
+		if settings.Hosts[i].Name == target {
+			return &settings.Hosts[i]
 		}
 	}
-	if config.User == "" || config.User == "master" {
-		if hostConfig.User != "" {
-			config.User = hostConfig.User
+	for i := range settings.Hosts {
+		if settings.Hosts[i].Host == target {
+			return &settings.Hosts[i]
 		}
 	}
+	return nil
+}
+
+// applyHostSettings fills config from the host that config.Host names or
+// addresses. The CLI and every MCP tool resolve hosts through it, so an
+// alias, its password key and its SSH key behave the same everywhere.
+// Values given explicitly win; the port, user and sudo key count as unset
+// while they hold their defaults. The default key and connection settings
+// also apply to hosts that are not configured. It returns the matched host
+// or nil.
+func applyHostSettings(config *sshclient.Config, settings *Settings) *HostConfig {
+	if settings == nil {
+		return nil
+	}
 
-	// Use configured password key if available
-	if hostConfig.PasswordKey != "" && config.SudoKey == sshclient.DefaultSudoKey {
-		config.SudoKey = hostConfig.PasswordKey
-		logger.GetLogger().Success("Using password key: %s", hostConfig.PasswordKey)
+	hostConfig := lookupHost(settings, config.Host)
+	if hostConfig != nil {
+		config.Alias = hostConfig.Name
+		config.Host = hostConfig.Host
+		if (config.Port == "" || config.Port == sshclient.DefaultSSHPort) && hostConfig.Port != "" {
+			config.Port = hostConfig.Port
+		}
+		if (config.User == "" || config.User == sshclient.DefaultSSHUser) && hostConfig.User != "" {
+			config.User = hostConfig.User
+		}
+		if hostConfig.PasswordKey != "" && (config.SudoKey == "" || config.SudoKey == sshclient.DefaultSudoKey) {
+			config.SudoKey = hostConfig.PasswordKey
+		}
+		// Record sessions on hosts that require it
+		if config.RecordPath == "" {
+			config.RecordPath = autoRecordPath(settings, hostConfig)
+		}
+		if config.UseKeyAuth && config.KeyPath == "" && hostConfig.Key != "" {
+			config.KeyPath = hostConfig.Key
+		}
+		// The passphrase belongs to the host's own key
+		if config.KeyPath == hostConfig.Key && config.KeyPassphraseKey == "" {
+			config.KeyPassphraseKey = hostConfig.KeyPassphraseKey
+		}
 	}
+	applyConnectionDefaults(config, hostConfig, settings)
 
-	// Use default SSH key from settings if available
 	if config.UseKeyAuth && config.KeyPath == "" && settings.Key != "" {
 		config.KeyPath = settings.Key
-		logger.GetLogger().Success("Using SSH key: %s", settings.Key)
 	}
-
-	return nil
+	return hostConfig
 }
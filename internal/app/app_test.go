@@ -7,6 +7,10 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
 )
 
 func TestRun_NoArgs(t *testing.T) {
@@ -207,3 +211,78 @@ func TestRun_ArgumentParsing(t *testing.T) {
 //     // Use deps instead of direct calls
 //     // This allows mocking for unit tests
 // }
+
+func TestApplyVerbosity(t *testing.T) {
+	lg := logger.GetLogger()
+	original := lg.GetLevel()
+	t.Cleanup(func() { lg.SetLevel(original) })
+
+	lg.SetLevel(logger.LogLevelInfo)
+	applyVerbosity(0)
+	if lg.GetLevel() != logger.LogLevelInfo {
+		t.Errorf("verbosity 0 changed level to %v", lg.GetLevel())
+	}
+
+	expected := map[int]logger.LogLevel{-1: logger.LogLevelWarning, 1: logger.LogLevelDebug, 2: logger.LogLevelTrace}
+	for verbosity, level := range expected {
+		applyVerbosity(verbosity)
+		if lg.GetLevel() != level {
+			t.Errorf("applyVerbosity(%d) level = %v, want %v", verbosity, lg.GetLevel(), level)
+		}
+	}
+}
+
+func TestPlainOutputRequested(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	if plainOutputRequested([]string{"sshx", "-h=web", "uptime"}) {
+		t.Error("plain output enabled without --no-color or NO_COLOR")
+	}
+	if !plainOutputRequested([]string{"sshx", "--no-color", "-h=web", "uptime"}) {
+		t.Error("--no-color did not enable plain output")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if !plainOutputRequested([]string{"sshx", "-h=web", "uptime"}) {
+		t.Error("NO_COLOR did not enable plain output")
+	}
+}
+
+func TestApplyHostSettings(t *testing.T) {
+	settings := &Settings{
+		Key:         "~/.ssh/default",
+		DialTimeout: "5s",
+		Hosts: []HostConfig{
+			{Name: "web1", Host: "10.0.0.1", Port: "2222", User: "deploy", PasswordKey: "web-sudo", Key: "~/.ssh/web"},
+			{Name: "db1", Host: "10.0.0.2"},
+		},
+	}
+
+	// By name, as both the CLI and the MCP server pass it
+	config := &sshclient.Config{Host: "web1", Port: sshclient.DefaultSSHPort, User: sshclient.DefaultSSHUser,
+		SudoKey: sshclient.DefaultSudoKey, UseKeyAuth: true}
+	if host := applyHostSettings(config, settings); host == nil || host.Name != "web1" {
+		t.Fatalf("expected web1, got %+v", host)
+	}
+	if config.Host != "10.0.0.1" || config.Port != "2222" || config.User != "deploy" || config.Alias != "web1" {
+		t.Errorf("target = %s@%s:%s (%s)", config.User, config.Host, config.Port, config.Alias)
+	}
+	if config.SudoKey != "web-sudo" || config.KeyPath != "~/.ssh/web" || config.DialTimeout != 5*time.Second {
+		t.Errorf("SudoKey = %q, KeyPath = %q, DialTimeout = %v", config.SudoKey, config.KeyPath, config.DialTimeout)
+	}
+
+	// By address, with explicit values winning
+	config = &sshclient.Config{Host: "10.0.0.1", User: "root", SudoKey: "mine", KeyPath: "~/.ssh/mine", UseKeyAuth: true}
+	applyHostSettings(config, settings)
+	if config.Alias != "web1" || config.User != "root" || config.SudoKey != "mine" || config.KeyPath != "~/.ssh/mine" {
+		t.Errorf("explicit values overridden: %+v", config)
+	}
+
+	// Unconfigured hosts still get the default key
+	config = &sshclient.Config{Host: "10.9.9.9", UseKeyAuth: true}
+	if host := applyHostSettings(config, settings); host != nil {
+		t.Errorf("expected no host, got %+v", host)
+	}
+	if config.KeyPath != "~/.ssh/default" {
+		t.Errorf("KeyPath = %q, want the default key", config.KeyPath)
+	}
+}
@@ -0,0 +1,202 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// AuditLogFile is the default audit log file name under ~/.sshmcp
+	AuditLogFile = "audit.log"
+	// AuditLogDisabled disables audit logging when used as audit_log in settings
+	AuditLogDisabled = "off"
+)
+
+// Audit entry statuses
+const (
+	AuditStatusSuccess = "success"
+	AuditStatusFailure = "failure"
+	AuditStatusBlocked = "blocked"
+	AuditStatusDenied  = "denied" // MCP tool call refused by the caller's token policy
+)
+
+// AuditEntry is a single line of the JSON-lines audit log
+type AuditEntry struct {
+	Time        time.Time                `json:"time"`
+	Source      string                   `json:"source,omitempty"`
+	Caller      string                   `json:"caller,omitempty"` // MCP token policy name
+	Client      string                   `json:"client,omitempty"` // MCP clientInfo name/version
+	Tool        string                   `json:"tool,omitempty"`   // MCP tool name
+	Host        string                   `json:"host,omitempty"`   // Configured host name
+	Address     string                   `json:"address"`
+	Port        string                   `json:"port,omitempty"`
+	User        string                   `json:"user"`
+	Command     string                   `json:"command,omitempty"`
+	Force       bool                     `json:"force,omitempty"`
+	ForceReason string                   `json:"force_reason,omitempty"` // Why a blocked command was forced
+	RollbackFor string                   `json:"rollback_for,omitempty"` // Failed step the command rolled back
+	Status      string                   `json:"status"`
+	Safety      *sshclient.SafetyFinding `json:"safety,omitempty"` // Rule of a flagged command that ran (bypassed when forced)
+	Error       string                   `json:"error,omitempty"`
+	DurationMs  int64                    `json:"duration_ms"`
+}
+
+var auditMu sync.Mutex
+
+// auditSubscriberBuffer is how many entries a subscriber may fall behind
+// before entries are dropped for it
+const auditSubscriberBuffer = 64
+
+var (
+	auditSubscribersMu sync.Mutex
+	auditSubscribers   = make(map[chan AuditEntry]struct{})
+)
+
+// subscribeAudit returns a channel receiving every audit entry recorded from
+// now on, and the function ending the subscription. Entries are recorded
+// whether or not the audit log is enabled; a subscriber that falls behind
+// misses entries instead of slowing down commands.
+func subscribeAudit() (<-chan AuditEntry, func()) {
+	ch := make(chan AuditEntry, auditSubscriberBuffer)
+	auditSubscribersMu.Lock()
+	auditSubscribers[ch] = struct{}{}
+	auditSubscribersMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			auditSubscribersMu.Lock()
+			delete(auditSubscribers, ch)
+			auditSubscribersMu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// publishAuditEntry hands an entry to the subscribers
+func publishAuditEntry(entry AuditEntry) {
+	auditSubscribersMu.Lock()
+	defer auditSubscribersMu.Unlock()
+	for ch := range auditSubscribers {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+// recordAuditEntry publishes an entry to the subscribers and appends it to
+// the audit log configured in settings
+func recordAuditEntry(settings *Settings, entry AuditEntry) {
+	publishAuditEntry(entry)
+	if path := auditLogPath(settings); path != "" {
+		if err := appendAuditEntry(path, entry); err != nil {
+			logger.GetLogger().Warning("failed to write audit log: %v", err)
+		}
+	}
+}
+
+// auditLogPath returns the audit log path configured in settings, or an empty
+// string when audit logging is disabled.
+func auditLogPath(settings *Settings) string {
+	if settings != nil && settings.AuditLog != "" {
+		if settings.AuditLog == AuditLogDisabled {
+			return ""
+		}
+		return expandHome(settings.AuditLog)
+	}
+
+	settingsDir, err := GetSettingsDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(settingsDir, AuditLogFile)
+}
+
+// newAuditEntry builds the audit record for a finished command
+func newAuditEntry(config *sshclient.Config, started time.Time, execErr error) AuditEntry {
+	entry := AuditEntry{
+		Time:        started.UTC(),
+		Source:      config.Source,
+		Caller:      config.Caller,
+		Client:      config.Client,
+		Host:        config.Alias,
+		Address:     config.Host,
+		Port:        config.Port,
+		User:        config.User,
+		Command:     logger.Redact(config.Command),
+		Force:       config.Force,
+		RollbackFor: logger.Redact(config.RollbackFor),
+		Status:      AuditStatusSuccess,
+		Safety:      config.SafetyFinding,
+		DurationMs:  time.Since(started).Milliseconds(),
+	}
+
+	if config.Force && config.SafetyFinding != nil && config.SafetyFinding.Severity == sshclient.SeverityBlock {
+		entry.ForceReason = logger.Redact(config.ForceReason)
+	}
+
+	if execErr != nil {
+		entry.Status = AuditStatusFailure
+		entry.Error = logger.Redact(execErr.Error())
+
+		var blocked *sshclient.BlockedCommandError
+		if errors.As(execErr, &blocked) {
+			entry.Status = AuditStatusBlocked
+		}
+	}
+
+	return entry
+}
+
+// appendAuditEntry appends an entry to the audit log, creating it if needed
+func appendAuditEntry(path string, entry AuditEntry) (err error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	// #nosec G304 -- audit log path comes from user settings
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// auditMiddleware records every command execution in the audit log
+func auditMiddleware(next sshclient.Executor) sshclient.Executor {
+	return func(config *sshclient.Config) (string, error) {
+		started := time.Now()
+		output, err := next(config)
+
+		settings, loadErr := LoadSettings()
+		if loadErr != nil {
+			settings = nil
+		}
+		recordAuditEntry(settings, newAuditEntry(config, started, err))
+		return output, err
+	}
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+func TestAuditLogPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	assert.Equal(t, filepath.Join(home, ".sshmcp", AuditLogFile), auditLogPath(nil))
+	assert.Equal(t, "/var/log/sshx.audit", auditLogPath(&Settings{AuditLog: "/var/log/sshx.audit"}))
+	assert.Empty(t, auditLogPath(&Settings{AuditLog: AuditLogDisabled}))
+}
+
+func TestNewAuditEntry(t *testing.T) {
+	config := &sshclient.Config{
+		Host: "10.0.0.5", Port: "22", User: "root", Command: "uptime", Alias: "prod-web",
+		Source: "mcp", Client: "claude-desktop/0.9.2", Caller: "ops",
+	}
+	started := time.Now()
+
+	entry := newAuditEntry(config, started, nil)
+	assert.Equal(t, AuditStatusSuccess, entry.Status)
+	assert.Equal(t, "prod-web", entry.Host)
+	assert.Equal(t, "mcp", entry.Source)
+	assert.Equal(t, "claude-desktop/0.9.2", entry.Client)
+	assert.Equal(t, "ops", entry.Caller)
+
+	entry = newAuditEntry(config, started, errors.New("exit status 1"))
+	assert.Equal(t, AuditStatusFailure, entry.Status)
+	assert.Equal(t, "exit status 1", entry.Error)
+
+	entry = newAuditEntry(config, started, sshclient.ValidateCommand("rm -rf /"))
+	assert.Equal(t, AuditStatusBlocked, entry.Status)
+	assert.Empty(t, entry.RollbackFor)
+
+	config.RollbackFor = "upload /etc/app.conf"
+	entry = newAuditEntry(config, started, nil)
+	assert.Equal(t, "upload /etc/app.conf", entry.RollbackFor)
+}
+
+func TestNewAuditEntry_ForcedBypass(t *testing.T) {
+	config := &sshclient.Config{
+		Host: "10.0.0.5", User: "root", Command: "sudo reboot", SafetyCheck: true,
+		Force: true, ForceReason: "kernel update approved in CHG-1234",
+	}
+	_, err := sshclient.SafetyCheckMiddleware(func(*sshclient.Config) (string, error) { return "", nil })(config)
+	require.NoError(t, err)
+
+	entry := newAuditEntry(config, time.Now(), nil)
+	assert.True(t, entry.Force)
+	assert.Equal(t, "kernel update approved in CHG-1234", entry.ForceReason)
+	require.NotNil(t, entry.Safety)
+	assert.Equal(t, "reboot", entry.Safety.Rule)
+	assert.Equal(t, sshclient.SeverityBlock, entry.Safety.Severity)
+
+	// A forced command that was never blocked records no bypass
+	config = &sshclient.Config{Command: "uptime", SafetyCheck: true, Force: true, ForceReason: "habit"}
+	_, err = sshclient.SafetyCheckMiddleware(func(*sshclient.Config) (string, error) { return "", nil })(config)
+	require.NoError(t, err)
+	entry = newAuditEntry(config, time.Now(), nil)
+	assert.Empty(t, entry.ForceReason)
+	assert.Nil(t, entry.Safety)
+}
+
+func TestAuditMiddleware_AppendsEntries(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	exec := sshclient.Chain(func(config *sshclient.Config) (string, error) {
+		return "ok", nil
+	}, auditMiddleware)
+
+	for _, command := range []string{"uptime", "df -h"} {
+		_, err := exec(&sshclient.Config{Host: "10.0.0.5", User: "root", Command: command})
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(home, ".sshmcp", AuditLogFile)) // #nosec G304 -- test temp file
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var entry AuditEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "df -h", entry.Command)
+	assert.Equal(t, AuditStatusSuccess, entry.Status)
+}
+
+func TestNewAuditEntry_RedactsSecrets(t *testing.T) {
+	logger.RegisterSecret("audit-s3cret")
+	defer logger.UnregisterSecret("audit-s3cret")
+
+	config := &sshclient.Config{Host: "10.0.0.5", User: "root", Command: "echo audit-s3cret"}
+	entry := newAuditEntry(config, time.Now(), errors.New("failed: audit-s3cret"))
+
+	assert.NotContains(t, entry.Command, "audit-s3cret")
+	assert.NotContains(t, entry.Error, "audit-s3cret")
+}
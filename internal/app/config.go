@@ -1,12 +1,20 @@
 package app
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
 )
 
+// defaultCleanupAge is how old leftover script files must be before
+// --cleanup-temp removes them, so scripts still running keep theirs
+const defaultCleanupAge = time.Hour
+
 // ParseArgs parses command-line arguments and returns a Config.
 func ParseArgs(args []string) *sshclient.Config {
 	config := &sshclient.Config{
@@ -36,6 +44,15 @@ func ParseArgs(args []string) *sshclient.Config {
 	if insecure := os.Getenv("SSH_INSECURE_HOST_KEY"); strings.EqualFold(insecure, "true") || insecure == "1" {
 		config.AllowInsecureHostKey = true
 	}
+	if revoked := os.Getenv("SSH_REVOKED_KEYS"); revoked != "" {
+		config.RevokedKeysPath = revoked
+	}
+	if ttl := os.Getenv("SSH_TRUST_TTL"); ttl != "" {
+		config.TrustTTL = parseTrustTTL(ttl)
+	}
+	if persist := os.Getenv("SSH_CONTROL_PERSIST"); persist != "" {
+		config.ControlPersist = firstDuration("SSH_CONTROL_PERSIST", persist)
+	}
 
 	if os.Getenv("SSH_NO_SAFETY_CHECK") == "true" {
 		config.SafetyCheck = false
@@ -55,20 +72,35 @@ func ParseArgs(args []string) *sshclient.Config {
 		switch {
 		case strings.HasPrefix(arg, "-h="), strings.HasPrefix(arg, "--host="):
 			config.Host = strings.SplitN(arg, "=", 2)[1]
+			setHostField(config, "host", config.Host)
 		case strings.HasPrefix(arg, "-p="), strings.HasPrefix(arg, "--port="):
 			config.Port = strings.SplitN(arg, "=", 2)[1]
+			setHostField(config, "port", config.Port)
 		case strings.HasPrefix(arg, "-u="), strings.HasPrefix(arg, "--user="):
 			config.User = strings.SplitN(arg, "=", 2)[1]
+			setHostField(config, "user", config.User)
 		case strings.HasPrefix(arg, "-i="), strings.HasPrefix(arg, "--key="):
 			config.KeyPath = strings.SplitN(arg, "=", 2)[1]
 			config.UseKeyAuth = true
+			setHostField(config, "key", config.KeyPath)
 		case strings.HasPrefix(arg, "-pk="), strings.HasPrefix(arg, "--password-key="):
 			config.SudoKey = strings.SplitN(arg, "=", 2)[1]
+			setHostField(config, "password_key", config.SudoKey)
 		case arg == "--no-key", arg == "--password-only":
 			config.UseKeyAuth = false
 			config.KeyPath = ""
 		case arg == "--key-auth":
 			config.UseKeyAuth = true
+		case arg == "--no-color":
+			// Applied in run before anything is logged
+		case arg == "-q", arg == "--quiet":
+			config.Verbosity = -1
+		case arg == "-v", arg == "--verbose":
+			if config.Verbosity >= 0 && config.Verbosity < 2 {
+				config.Verbosity++
+			}
+		case arg == "-vv":
+			config.Verbosity = 2
 		case arg == "--force", arg == "-f":
 			config.Force = true
 		case arg == "--accept-unknown-host":
@@ -79,6 +111,16 @@ func ParseArgs(args []string) *sshclient.Config {
 			config.AllowInsecureHostKey = false
 		case strings.HasPrefix(arg, "--known-hosts="):
 			config.KnownHostsPath = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--revoked-keys="):
+			config.RevokedKeysPath = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--trust-ttl="):
+			config.TrustTTL = parseTrustTTL(strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--force-reason="):
+			config.ForceReason = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--forward-agent":
+			config.ForwardAgent = true
+		case arg == "-X", arg == "--forward-x11":
+			config.ForwardX11 = true
 		case arg == "--no-safety-check":
 			config.SafetyCheck = false
 		case arg == "--sftp":
@@ -87,6 +129,11 @@ func ParseArgs(args []string) *sshclient.Config {
 			config.Mode = "sftp"
 			config.SftpAction = "upload"
 			config.LocalPath = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--upload-template="):
+			config.Mode = "sftp"
+			config.SftpAction = "upload"
+			config.UploadTemplate = true
+			config.LocalPath = strings.SplitN(arg, "=", 2)[1]
 		case strings.HasPrefix(arg, "--download="):
 			config.Mode = "sftp"
 			config.SftpAction = "download"
@@ -110,6 +157,49 @@ func ParseArgs(args []string) *sshclient.Config {
 			config.Mode = "sftp"
 			config.SftpAction = "remove"
 			config.RemotePath = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--explain":
+			config.Mode = "explain"
+		case arg == "--snapshot":
+			config.Mode = "snapshot"
+		case arg == "--diff-snapshot":
+			config.Mode = "diff-snapshot"
+		case strings.HasPrefix(arg, "--diff-snapshot="):
+			config.Mode = "diff-snapshot"
+			config.LocalPath = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--add-user="):
+			config.Mode = "account"
+			config.AccountAction = "add-user"
+			config.Account = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--push-key":
+			config.Mode = "account"
+			// --add-user also installs --pubkey for the new user
+			if config.AccountAction != "add-user" {
+				config.AccountAction = "push-key"
+			}
+		case arg == "--list-keys":
+			config.Mode = "account"
+			config.AccountAction = "list-keys"
+		case strings.HasPrefix(arg, "--revoke-key="):
+			config.Mode = "account"
+			config.AccountAction = "revoke-key"
+			config.KeyMatch = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--pubkey="):
+			config.PublicKeyPath = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--account="):
+			config.Account = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--user-shell="):
+			config.AccountShell = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--backup":
+			// --rm moves the target to the trash, --upload keeps the old file as .bak
+			config.RemoveBackup = true
+			config.UploadBackup = true
+		case arg == "--delta":
+			config.DeltaUpload = true
+		case arg == "--trash-purge":
+			config.Mode = "trash-purge"
+		case strings.HasPrefix(arg, "--trash-purge="):
+			config.Mode = "trash-purge"
+			config.TrashPurgeAge = strings.SplitN(arg, "=", 2)[1]
 		case strings.HasPrefix(arg, "--password-set="):
 			config.Mode = "password"
 			config.PasswordAction = "set"
@@ -136,19 +226,123 @@ func ParseArgs(args []string) *sshclient.Config {
 		case arg == "--password-list" || arg == "--password-ls":
 			config.Mode = "password"
 			config.PasswordAction = "list"
+		case strings.HasPrefix(arg, "--hosts="):
+			config.Hosts = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--validate="):
+			config.Validate = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--collect="):
+			config.Mode = "sftp"
+			config.SftpAction = "collect"
+			config.RemotePath = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--collect-cmd="):
+			config.Mode = "sftp"
+			config.SftpAction = "collect"
+			config.CollectCommand = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--aggregate":
+			config.AggregateOutput = true
+		case strings.HasPrefix(arg, "--canary="):
+			config.Rollout.Canary = rolloutCount(strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--batch-size="):
+			config.Rollout.BatchSize = rolloutCount(strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--max-failures="):
+			config.Rollout.MaxFailures = rolloutCount(strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--expect="):
+			config.Rollout.Expect = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--check="):
+			config.Rollout.Check = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--rollback="):
+			config.Rollback = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--manifest="):
+			config.ManifestPath = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--remote-manifest="):
+			config.RemoteManifest = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--into="):
+			config.LocalPath = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--max-size="):
+			config.MaxBytes = parseSize(strings.SplitN(arg, "=", 2)[1])
+		case arg == "--known-hosts-list":
+			config.Mode = "knownhosts"
+			config.KnownHostsAction = "list"
+		case strings.HasPrefix(arg, "--known-hosts-promote="):
+			config.Mode = "knownhosts"
+			config.KnownHostsAction = "promote"
+			config.HostName = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--known-hosts-deny="):
+			config.Mode = "knownhosts"
+			config.KnownHostsAction = "deny"
+			config.HostName = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--lock":
+			config.Mode = "password"
+			config.PasswordAction = "lock"
 		case arg == "--host-add":
 			config.Mode = "host"
 			config.HostAction = "add"
 		case arg == "--host-update":
 			config.Mode = "host"
 			config.HostAction = "update"
+		case arg == "--host-import":
+			config.Mode = "host"
+			config.HostAction = "import"
+		case strings.HasPrefix(arg, "--host-import="):
+			config.Mode = "host"
+			config.HostAction = "import"
+			config.SSHConfigPath = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--overwrite":
+			config.Overwrite = true
+		case arg == "--host-export-sshconfig":
+			config.Mode = "host"
+			config.HostAction = "export-sshconfig"
+		case strings.HasPrefix(arg, "--host-export-sshconfig="):
+			config.Mode = "host"
+			config.HostAction = "export-sshconfig"
+			config.SSHConfigPath = strings.SplitN(arg, "=", 2)[1]
 		case arg == "--host-list" || arg == "--host-ls":
 			config.Mode = "host"
 			config.HostAction = "list"
+		case strings.HasPrefix(arg, "--host-find="):
+			config.Mode = "host"
+			config.HostAction = "find"
+			config.HostFind = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--host-sync":
+			config.Mode = "host"
+			config.HostAction = "sync"
+		case strings.HasPrefix(arg, "--host-sync="):
+			config.Mode = "host"
+			config.HostAction = "sync"
+			config.SyncSource = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--discover":
+			config.Mode = "host"
+			config.HostAction = "discover"
+		case strings.HasPrefix(arg, "--discover="):
+			config.Mode = "host"
+			config.HostAction = "discover"
+			config.DiscoverCIDR = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--group-by="):
+			config.HostGroupBy = strings.SplitN(arg, "=", 2)[1]
 		case strings.HasPrefix(arg, "--host-test="):
 			config.Mode = "host"
 			config.HostAction = "test"
 			config.HostName = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--ping="):
+			config.Mode = "host"
+			config.HostAction = "ping"
+			config.Hosts = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--ping":
+			config.Mode = "host"
+			config.HostAction = "ping"
+		case arg == "--icmp":
+			config.PingICMP = true
+		case arg == "--skip-unreachable":
+			config.SkipUnreachable = true
+		case arg == "--only-unreachable":
+			config.OnlyUnreachable = true
+		case strings.HasPrefix(arg, "--diagnose="):
+			config.Mode = "host"
+			config.HostAction = "diagnose"
+			config.HostName = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--diagnose":
+			config.Mode = "host"
+			config.HostAction = "diagnose"
 		case arg == "--host-test-all":
 			config.Mode = "host"
 			config.HostAction = "test-all"
@@ -163,8 +357,118 @@ func ParseArgs(args []string) *sshclient.Config {
 			config.HostName = strings.SplitN(arg, "=", 2)[1]
 		case strings.HasPrefix(arg, "--host-desc="):
 			config.HostDescription = strings.SplitN(arg, "=", 2)[1]
+			setHostField(config, "description", config.HostDescription)
 		case strings.HasPrefix(arg, "--host-type="):
 			config.HostType = strings.SplitN(arg, "=", 2)[1]
+			setHostField(config, "type", config.HostType)
+		case strings.HasPrefix(arg, "--host-shell="):
+			setHostField(config, "shell", strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--host-device="):
+			setHostField(config, "device", strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--host-addresses="):
+			setHostField(config, "addresses", strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--host-meta="):
+			setHostField(config, "meta", strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--host-env="):
+			// Repeatable: one variable per flag, so values may hold commas
+			value := strings.SplitN(arg, "=", 2)[1]
+			if previous, ok := config.HostFields["env"]; ok {
+				value = previous + "\n" + value
+			}
+			setHostField(config, "env", value)
+		case strings.HasPrefix(arg, "--ttl="):
+			setHostField(config, "ttl", strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--record="):
+			config.RecordPath = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--script="):
+			config.Mode = "script"
+			config.LocalPath = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--cleanup-temp":
+			config.Mode = "cleanup"
+			config.CleanupAge = defaultCleanupAge
+		case strings.HasPrefix(arg, "--cleanup-temp="):
+			config.Mode = "cleanup"
+			config.CleanupAge = firstDuration("--cleanup-temp", strings.SplitN(arg, "=", 2)[1])
+		case arg == "--json":
+			config.JSONOutput = true
+		case arg == "--check-syntax":
+			config.CheckSyntax = true
+		case strings.HasPrefix(arg, "--run-as="):
+			config.RunAs = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--container="):
+			config.Container = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--container-runtime="):
+			config.ContainerRuntime = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--cwd="):
+			config.WorkDir = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--login-shell":
+			config.LoginShell = true
+		case strings.HasPrefix(arg, "--temp-dir="):
+			config.TempDir = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--script-timeout="):
+			config.ScriptTimeout = firstDuration("--script-timeout", strings.SplitN(arg, "=", 2)[1])
+		case arg == "--watch":
+			config.WatchInterval = sshclient.DefaultWatchInterval
+		case strings.HasPrefix(arg, "--watch="):
+			config.WatchInterval = firstDuration("--watch", strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--until="):
+			config.WatchUntil = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--watch-count="):
+			config.WatchCount, _ = strconv.Atoi(strings.SplitN(arg, "=", 2)[1]) //nolint:errcheck // invalid counts watch until interrupted
+		case strings.HasPrefix(arg, "--wait-for="):
+			config.Mode = "wait"
+			config.WaitFor = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--interact="):
+			config.Mode = "interact"
+			config.InteractScript = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--wait-timeout="):
+			config.WaitTimeout = firstDuration("--wait-timeout", strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--wait-interval="):
+			config.WaitInterval = firstDuration("--wait-interval", strings.SplitN(arg, "=", 2)[1])
+		case arg == "--reboot":
+			config.Mode = "reboot"
+		case strings.HasPrefix(arg, "--confirm="):
+			config.RebootConfirm = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--min-uptime="):
+			// 0 skips the check; an invalid value keeps the default
+			value := strings.SplitN(arg, "=", 2)[1]
+			config.Reboot.MinUptime = firstDuration("--min-uptime", value)
+			if value == "0" || value == "0s" {
+				config.Reboot.MinUptime = -1
+			}
+		case arg == "--allow-sessions":
+			config.Reboot.AllowSessions = true
+		case strings.HasPrefix(arg, "--verify="):
+			config.Reboot.Verify = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--dry-run":
+			config.Reboot.DryRun = true
+		case strings.HasPrefix(arg, "--play="):
+			config.Mode = "play"
+			config.LocalPath = strings.SplitN(arg, "=", 2)[1]
+		case strings.HasPrefix(arg, "--host-tags="):
+			config.HostTags = splitList(strings.SplitN(arg, "=", 2)[1])
+			setHostField(config, "tags", strings.SplitN(arg, "=", 2)[1])
+		case arg == "--pool-stats":
+			config.Mode = "pool"
+			config.PoolAction = "stats"
+		case arg == "--pool-flush":
+			config.Mode = "pool"
+			config.PoolAction = "flush"
+		case strings.HasPrefix(arg, "--pool-flush-host="):
+			config.Mode = "pool"
+			config.PoolAction = "flush"
+			config.Hosts = strings.SplitN(arg, "=", 2)[1]
+		case arg == "--control-persist":
+			config.ControlPersist = DefaultControlPersist
+		case strings.HasPrefix(arg, "--control-persist="):
+			config.ControlPersist = firstDuration("--control-persist", strings.SplitN(arg, "=", 2)[1])
+		case arg == "--control-exit":
+			config.Mode = "pool"
+			config.PoolAction = "control-exit"
+		case strings.HasPrefix(arg, "--warm="):
+			config.Mode = "pool"
+			config.PoolAction = "warm"
+			config.Hosts = strings.SplitN(arg, "=", 2)[1]
 		case arg == "--help":
 			PrintUsage()
 			os.Exit(0)
@@ -175,7 +479,7 @@ func ParseArgs(args []string) *sshclient.Config {
 		}
 	}
 
-	if config.Mode == "ssh" {
+	if config.Mode == "ssh" || config.Mode == "explain" || config.Mode == "interact" {
 		actualCmd := []string{}
 		for i := 1; i < len(args); i++ {
 			arg := args[i]
@@ -187,8 +491,103 @@ func ParseArgs(args []string) *sshclient.Config {
 
 		if len(actualCmd) > 0 {
 			config.Command = strings.Join(actualCmd, " ")
+		} else if config.Mode == "interact" {
+			// Without a command --interact drives the login shell
+			config.Command = ""
+		}
+	}
+
+	// Positional arguments are passed to a script run with --script
+	if config.Mode == "script" {
+		config.Command = ""
+		for _, arg := range args[1:] {
+			if !strings.HasPrefix(arg, "-") {
+				config.ScriptArgs = append(config.ScriptArgs, arg)
+			}
 		}
 	}
 
 	return config
 }
+
+// setHostField records a host field given explicitly on the command line
+func setHostField(config *sshclient.Config, field, value string) {
+	if config.HostFields == nil {
+		config.HostFields = make(map[string]string)
+	}
+	config.HostFields[field] = value
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseTrustTTL parses a trust TTL such as "720h" or "30d". Invalid values
+// disable expiry with a warning.
+func parseTrustTTL(value string) time.Duration {
+	ttl, err := parseDayDuration(value)
+	if err != nil {
+		logger.GetLogger().Warning("invalid trust TTL '%s', host key trust will not expire", value)
+		return 0
+	}
+	return ttl
+}
+
+// parseDayDuration parses a non-negative duration such as "90m", "720h" or
+// "30d"
+func parseDayDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid duration '%s' (e.g. 12h or 7d)", value)
+	}
+	return duration, nil
+}
+
+// rolloutCount parses a host count of --canary, --batch-size or
+// --max-failures. An invalid count is -1, which the rollout refuses rather
+// than running without the stage.
+func rolloutCount(value string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// parseSize parses a byte size such as "512", "64K", "10M" or "1G". Invalid
+// values fall back to the default cap with a warning.
+func parseSize(value string) int64 {
+	number := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(number, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(number, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(number, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		number = number[:len(number)-1]
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 {
+		logger.GetLogger().Warning("invalid size '%s', using the default cap", value)
+		return 0
+	}
+	return n * multiplier
+}
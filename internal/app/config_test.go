@@ -3,6 +3,7 @@ package app
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/talkincode/sshmcp/internal/sshclient"
 )
@@ -569,3 +570,440 @@ func TestParseArgs_ComplexCommand(t *testing.T) {
 		t.Errorf("Expected command '%s', got '%s'", expected, config.Command)
 	}
 }
+
+func TestParseArgs_Warm(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--warm=prod,web1"})
+
+	if config.Mode != "pool" {
+		t.Errorf("Expected mode 'pool', got %s", config.Mode)
+	}
+	if config.PoolAction != "warm" {
+		t.Errorf("Expected pool action 'warm', got %s", config.PoolAction)
+	}
+	if config.Hosts != "prod,web1" {
+		t.Errorf("Expected hosts 'prod,web1', got %s", config.Hosts)
+	}
+}
+
+func TestParseArgs_HostTags(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--host-add", "--host-name=web1", "--host-tags=prod, web,"})
+
+	if len(config.HostTags) != 2 || config.HostTags[0] != "prod" || config.HostTags[1] != "web" {
+		t.Errorf("Expected host tags [prod web], got %v", config.HostTags)
+	}
+}
+
+func TestParseArgs_HostMeta(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--host-update", "--host-name=web1", "--host-meta=rack=r12,owner=", "--ttl=4h", "--host-addresses=tailscale,10.8.0.1", "--host-device=cisco_ios"})
+	if config.HostFields["meta"] != "rack=r12,owner=" {
+		t.Errorf("Expected meta field 'rack=r12,owner=', got %q", config.HostFields["meta"])
+	}
+	if config.HostFields["ttl"] != "4h" {
+		t.Errorf("Expected ttl field '4h', got %q", config.HostFields["ttl"])
+	}
+	if config.HostFields["addresses"] != "tailscale,10.8.0.1" {
+		t.Errorf("Expected addresses field 'tailscale,10.8.0.1', got %q", config.HostFields["addresses"])
+	}
+	if config.HostFields["device"] != "cisco_ios" {
+		t.Errorf("Expected device field 'cisco_ios', got %q", config.HostFields["device"])
+	}
+
+	config = ParseArgs([]string{"sshx", "--host-update", "--host-name=web1", "--host-env=HTTP_PROXY=http://proxy:3128", "--host-env=NO_PROXY=localhost,.corp"})
+	if config.HostFields["env"] != "HTTP_PROXY=http://proxy:3128\nNO_PROXY=localhost,.corp" {
+		t.Errorf("Expected one env line per --host-env, got %q", config.HostFields["env"])
+	}
+
+	config = ParseArgs([]string{"sshx", "--host-find=env=prod,rack=r1*", "--group-by=owner"})
+	if config.Mode != "host" || config.HostAction != "find" {
+		t.Errorf("Expected host/find, got %s/%s", config.Mode, config.HostAction)
+	}
+	if config.HostFind != "env=prod,rack=r1*" || config.HostGroupBy != "owner" {
+		t.Errorf("Expected find 'env=prod,rack=r1*' grouped by owner, got %q by %q", config.HostFind, config.HostGroupBy)
+	}
+}
+
+func TestParseArgs_HostSync(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--host-sync"})
+	if config.Mode != "host" || config.HostAction != "sync" || config.SyncSource != "" {
+		t.Errorf("Expected host/sync of every source, got %s/%s %q", config.Mode, config.HostAction, config.SyncSource)
+	}
+
+	config = ParseArgs([]string{"sshx", "--host-sync=aws"})
+	if config.HostAction != "sync" || config.SyncSource != "aws" {
+		t.Errorf("Expected sync of aws, got %s %q", config.HostAction, config.SyncSource)
+	}
+}
+
+func TestParseArgs_Discover(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--discover"})
+	if config.Mode != "host" || config.HostAction != "discover" || config.DiscoverCIDR != "" {
+		t.Errorf("Expected host/discover of the local network, got %s/%s %q", config.Mode, config.HostAction, config.DiscoverCIDR)
+	}
+
+	config = ParseArgs([]string{"sshx", "--discover=10.0.40.0/22", "-p=2222"})
+	if config.HostAction != "discover" || config.DiscoverCIDR != "10.0.40.0/22" || config.Port != "2222" {
+		t.Errorf("Expected discover of 10.0.40.0/22 port 2222, got %s %q %q", config.HostAction, config.DiscoverCIDR, config.Port)
+	}
+}
+
+func TestParseArgs_RecordAndPlay(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=host", "--record=session.cast", "uptime"})
+	if config.RecordPath != "session.cast" {
+		t.Errorf("Expected record path 'session.cast', got %s", config.RecordPath)
+	}
+	if config.Command != "uptime" {
+		t.Errorf("Expected command 'uptime', got %s", config.Command)
+	}
+
+	config = ParseArgs([]string{"sshx", "--play=session.cast"})
+	if config.Mode != "play" {
+		t.Errorf("Expected mode 'play', got %s", config.Mode)
+	}
+	if config.LocalPath != "session.cast" {
+		t.Errorf("Expected local path 'session.cast', got %s", config.LocalPath)
+	}
+}
+
+func TestParseArgs_Lock(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--lock"})
+	if config.Mode != "password" || config.PasswordAction != "lock" {
+		t.Errorf("expected password/lock, got %s/%s", config.Mode, config.PasswordAction)
+	}
+}
+
+func TestParseArgs_HostKeyTrust(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--revoked-keys=/etc/ssh/revoked", "--trust-ttl=30d", "-h=web", "uptime"})
+	if config.RevokedKeysPath != "/etc/ssh/revoked" {
+		t.Errorf("RevokedKeysPath = %q", config.RevokedKeysPath)
+	}
+	if config.TrustTTL != 30*24*time.Hour {
+		t.Errorf("TrustTTL = %v", config.TrustTTL)
+	}
+}
+
+func TestParseTrustTTL(t *testing.T) {
+	tests := map[string]time.Duration{
+		"12h":   12 * time.Hour,
+		"7d":    7 * 24 * time.Hour,
+		"0":     0,
+		"bogus": 0,
+		"-1h":   0,
+	}
+	for input, expected := range tests {
+		if got := parseTrustTTL(input); got != expected {
+			t.Errorf("parseTrustTTL(%q) = %v, want %v", input, got, expected)
+		}
+	}
+}
+
+func TestParseDayDuration(t *testing.T) {
+	valid := map[string]time.Duration{
+		"90m": 90 * time.Minute,
+		"7d":  7 * 24 * time.Hour,
+		"0":   0,
+	}
+	for input, expected := range valid {
+		if got, err := parseDayDuration(input); err != nil || got != expected {
+			t.Errorf("parseDayDuration(%q) = %v, %v, want %v", input, got, err, expected)
+		}
+	}
+	for _, input := range []string{"", "bogus", "-1h", "-2d", "d"} {
+		if _, err := parseDayDuration(input); err == nil {
+			t.Errorf("parseDayDuration(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestParseArgs_HostFields(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--host-update", "--host-name=web1", "-p=22", "--host-tags=", "-i=~/.ssh/web", "--host-shell=sh"})
+	if config.HostAction != "update" {
+		t.Fatalf("HostAction = %q", config.HostAction)
+	}
+	expected := map[string]string{"port": "22", "tags": "", "key": "~/.ssh/web", "shell": "sh"}
+	if len(config.HostFields) != len(expected) {
+		t.Fatalf("HostFields = %v, want %v", config.HostFields, expected)
+	}
+	for field, value := range expected {
+		if got, ok := config.HostFields[field]; !ok || got != value {
+			t.Errorf("HostFields[%q] = %q, want %q", field, got, value)
+		}
+	}
+}
+
+func TestParseArgs_Verbosity(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected int
+	}{
+		{[]string{"sshx", "-h=web", "uptime"}, 0},
+		{[]string{"sshx", "-q", "-h=web", "uptime"}, -1},
+		{[]string{"sshx", "--quiet", "-h=web", "uptime"}, -1},
+		{[]string{"sshx", "-v", "-h=web", "uptime"}, 1},
+		{[]string{"sshx", "-v", "--verbose", "-h=web", "uptime"}, 2},
+		{[]string{"sshx", "-vv", "-h=web", "uptime"}, 2},
+		{[]string{"sshx", "-vv", "-v", "-h=web", "uptime"}, 2},
+	}
+	for _, tt := range tests {
+		config := ParseArgs(tt.args)
+		if config.Verbosity != tt.expected {
+			t.Errorf("ParseArgs(%v).Verbosity = %d, want %d", tt.args, config.Verbosity, tt.expected)
+		}
+		if config.Command != "uptime" {
+			t.Errorf("ParseArgs(%v).Command = %q, want uptime", tt.args, config.Command)
+		}
+	}
+}
+
+func TestParseArgs_Script(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=web", "--script=deploy.sh", "--script-timeout=90s", "--run-as=app", "--check-syntax", "prod", "--force-restart"})
+	if config.Mode != "script" {
+		t.Errorf("Mode = %q, want script", config.Mode)
+	}
+	if config.LocalPath != "deploy.sh" {
+		t.Errorf("LocalPath = %q, want deploy.sh", config.LocalPath)
+	}
+	if config.ScriptTimeout != 90*time.Second {
+		t.Errorf("ScriptTimeout = %v, want 90s", config.ScriptTimeout)
+	}
+	if len(config.ScriptArgs) != 1 || config.ScriptArgs[0] != "prod" {
+		t.Errorf("ScriptArgs = %v, want [prod]", config.ScriptArgs)
+	}
+	if config.Command != "" {
+		t.Errorf("Command = %q, want empty", config.Command)
+	}
+	if config.RunAs != "app" {
+		t.Errorf("RunAs = %q, want app", config.RunAs)
+	}
+	if !config.CheckSyntax {
+		t.Error("CheckSyntax = false, want true")
+	}
+}
+
+func TestParseArgs_Container(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=dock1", "--container=web1", "--container-runtime=podman", "ls /app"})
+	if config.Mode != "ssh" || config.Command != "ls /app" {
+		t.Errorf("Mode = %q, Command = %q, want ssh and ls /app", config.Mode, config.Command)
+	}
+	if config.Container != "web1" || config.ContainerRuntime != "podman" {
+		t.Errorf("Container = %q, ContainerRuntime = %q, want web1 and podman", config.Container, config.ContainerRuntime)
+	}
+}
+
+func TestParseArgs_WorkDirAndLoginShell(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=app1", "--cwd=/opt/app", "--login-shell", "bundle exec rake db:migrate"})
+	if config.Mode != "ssh" || config.Command != "bundle exec rake db:migrate" {
+		t.Errorf("Mode = %q, Command = %q, want ssh and bundle exec rake db:migrate", config.Mode, config.Command)
+	}
+	if config.WorkDir != "/opt/app" || !config.LoginShell {
+		t.Errorf("WorkDir = %q, LoginShell = %v, want /opt/app and true", config.WorkDir, config.LoginShell)
+	}
+}
+
+func TestParseArgs_Watch(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=web1", "--watch=10s", "--until=done", "--watch-count=5", "df -h /data"})
+	if config.Mode != "ssh" || config.Command != "df -h /data" {
+		t.Errorf("Mode = %q, Command = %q, want ssh and df -h /data", config.Mode, config.Command)
+	}
+	if config.WatchInterval != 10*time.Second || config.WatchUntil != "done" || config.WatchCount != 5 {
+		t.Errorf("WatchInterval = %v, WatchUntil = %q, WatchCount = %d", config.WatchInterval, config.WatchUntil, config.WatchCount)
+	}
+
+	config = ParseArgs([]string{"sshx", "-h=web1", "--watch", "uptime"})
+	if config.WatchInterval != sshclient.DefaultWatchInterval {
+		t.Errorf("--watch: WatchInterval = %v, want %v", config.WatchInterval, sshclient.DefaultWatchInterval)
+	}
+}
+
+func TestParseArgs_Interact(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=web1", "--interact=install.expect", "sudo", "apt-get", "install", "nginx"})
+	if config.Mode != "interact" || config.InteractScript != "install.expect" {
+		t.Errorf("Mode = %q, InteractScript = %q, want interact and install.expect", config.Mode, config.InteractScript)
+	}
+	if config.Command != "sudo apt-get install nginx" {
+		t.Errorf("Command = %q, want the positional command", config.Command)
+	}
+
+	config = ParseArgs([]string{"sshx", "-h=core-sw", "--interact=-"})
+	if config.Mode != "interact" || config.InteractScript != "-" || config.Command != "" {
+		t.Errorf("Mode = %q, InteractScript = %q, Command = %q", config.Mode, config.InteractScript, config.Command)
+	}
+}
+
+func TestParseArgs_WaitFor(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=db1", "--wait-for=port:5432", "--wait-timeout=2m", "--wait-interval=5s"})
+	if config.Mode != "wait" || config.WaitFor != "port:5432" {
+		t.Errorf("Mode = %q, WaitFor = %q, want wait and port:5432", config.Mode, config.WaitFor)
+	}
+	if config.WaitTimeout != 2*time.Minute || config.WaitInterval != 5*time.Second {
+		t.Errorf("WaitTimeout = %v, WaitInterval = %v", config.WaitTimeout, config.WaitInterval)
+	}
+}
+
+func TestParseArgs_Reboot(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=web1", "--reboot", "--confirm=web1", "--min-uptime=1h", "--allow-sessions", "--verify=systemctl is-active nginx", "--dry-run"})
+	if config.Mode != "reboot" || config.RebootConfirm != "web1" {
+		t.Errorf("Mode = %q, RebootConfirm = %q, want reboot and web1", config.Mode, config.RebootConfirm)
+	}
+	want := sshclient.RebootOptions{MinUptime: time.Hour, AllowSessions: true, Verify: "systemctl is-active nginx", DryRun: true}
+	if config.Reboot.MinUptime != want.MinUptime || config.Reboot.AllowSessions != want.AllowSessions || config.Reboot.Verify != want.Verify || config.Reboot.DryRun != want.DryRun {
+		t.Errorf("Reboot = %+v, want %+v", config.Reboot, want)
+	}
+
+	// 0 skips the uptime check, an invalid value keeps the default
+	if config := ParseArgs([]string{"sshx", "-h=web1", "--reboot", "--min-uptime=0"}); config.Reboot.MinUptime >= 0 {
+		t.Errorf("--min-uptime=0: MinUptime = %v, want negative", config.Reboot.MinUptime)
+	}
+	if config := ParseArgs([]string{"sshx", "-h=web1", "--reboot", "--min-uptime=soon"}); config.Reboot.MinUptime != 0 {
+		t.Errorf("--min-uptime=soon: MinUptime = %v, want 0", config.Reboot.MinUptime)
+	}
+}
+
+func TestParseArgs_Snapshot(t *testing.T) {
+	if config := ParseArgs([]string{"sshx", "-h=web1", "--snapshot"}); config.Mode != "snapshot" {
+		t.Errorf("--snapshot: Mode = %q, want snapshot", config.Mode)
+	}
+	config := ParseArgs([]string{"sshx", "-h=web1", "--diff-snapshot"})
+	if config.Mode != "diff-snapshot" || config.LocalPath != "" {
+		t.Errorf("--diff-snapshot: Mode = %q, LocalPath = %q", config.Mode, config.LocalPath)
+	}
+	config = ParseArgs([]string{"sshx", "-h=web1", "--diff-snapshot=old.json", "--json"})
+	if config.Mode != "diff-snapshot" || config.LocalPath != "old.json" || !config.JSONOutput {
+		t.Errorf("--diff-snapshot=old.json: Mode = %q, LocalPath = %q, JSONOutput = %v", config.Mode, config.LocalPath, config.JSONOutput)
+	}
+}
+
+func TestParseArgs_Accounts(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--push-key", "-h=web1", "--user=deploy", "--pubkey=~/.ssh/id_ed25519.pub"})
+	if config.Mode != "account" || config.AccountAction != "push-key" || config.PublicKeyPath != "~/.ssh/id_ed25519.pub" || config.User != "deploy" {
+		t.Errorf("--push-key: Mode = %q, AccountAction = %q, PublicKeyPath = %q, User = %q", config.Mode, config.AccountAction, config.PublicKeyPath, config.User)
+	}
+	config = ParseArgs([]string{"sshx", "-h=web1", "--add-user=deploy", "--user-shell=/bin/bash", "--push-key", "--pubkey=deploy.pub"})
+	if config.AccountAction != "add-user" || config.Account != "deploy" || config.AccountShell != "/bin/bash" {
+		t.Errorf("--add-user: AccountAction = %q, Account = %q, AccountShell = %q", config.AccountAction, config.Account, config.AccountShell)
+	}
+	config = ParseArgs([]string{"sshx", "-h=web1", "--list-keys", "--account=deploy"})
+	if config.AccountAction != "list-keys" || config.Account != "deploy" {
+		t.Errorf("--list-keys: AccountAction = %q, Account = %q", config.AccountAction, config.Account)
+	}
+	config = ParseArgs([]string{"sshx", "-h=web1", "--revoke-key=SHA256:abc"})
+	if config.AccountAction != "revoke-key" || config.KeyMatch != "SHA256:abc" {
+		t.Errorf("--revoke-key: AccountAction = %q, KeyMatch = %q", config.AccountAction, config.KeyMatch)
+	}
+}
+
+func TestParseArgs_CleanupTemp(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=web", "--cleanup-temp"})
+	if config.Mode != "cleanup" || config.CleanupAge != defaultCleanupAge {
+		t.Errorf("--cleanup-temp: Mode = %q, CleanupAge = %v", config.Mode, config.CleanupAge)
+	}
+
+	config = ParseArgs([]string{"sshx", "-h=web", "--cleanup-temp=0", "--temp-dir=/var/tmp"})
+	if config.Mode != "cleanup" || config.CleanupAge != 0 {
+		t.Errorf("--cleanup-temp=0: Mode = %q, CleanupAge = %v", config.Mode, config.CleanupAge)
+	}
+	if config.TempDir != "/var/tmp" {
+		t.Errorf("TempDir = %q, want /var/tmp", config.TempDir)
+	}
+
+}
+
+func TestParseArgs_Trash(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=web", "--backup", "--rm=/etc/app.conf"})
+	if config.Mode != "sftp" || config.SftpAction != "remove" || !config.RemoveBackup {
+		t.Errorf("--rm --backup: Mode = %q, SftpAction = %q, RemoveBackup = %v", config.Mode, config.SftpAction, config.RemoveBackup)
+	}
+
+	config = ParseArgs([]string{"sshx", "-h=web", "--upload=app.conf", "--to=/etc/app.conf", "--backup"})
+	if config.SftpAction != "upload" || !config.UploadBackup {
+		t.Errorf("--upload --backup: SftpAction = %q, UploadBackup = %v", config.SftpAction, config.UploadBackup)
+	}
+
+	config = ParseArgs([]string{"sshx", "-h=web", "--upload=app.db", "--to=/srv/app.db", "--delta"})
+	if config.SftpAction != "upload" || !config.DeltaUpload {
+		t.Errorf("--upload --delta: SftpAction = %q, DeltaUpload = %v", config.SftpAction, config.DeltaUpload)
+	}
+
+	config = ParseArgs([]string{"sshx", "-h=web", "--trash-purge"})
+	if config.Mode != "trash-purge" || config.TrashPurgeAge != "" {
+		t.Errorf("--trash-purge: Mode = %q, TrashPurgeAge = %q", config.Mode, config.TrashPurgeAge)
+	}
+
+	config = ParseArgs([]string{"sshx", "-h=web", "--trash-purge=7d"})
+	if config.Mode != "trash-purge" || config.TrashPurgeAge != "7d" {
+		t.Errorf("--trash-purge=7d: Mode = %q, TrashPurgeAge = %q", config.Mode, config.TrashPurgeAge)
+	}
+}
+
+func TestParseArgs_ForceReason(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=web", "-f", "--force-reason=planned maintenance", "sudo reboot"})
+	if !config.Force || config.ForceReason != "planned maintenance" || config.Command != "sudo reboot" {
+		t.Errorf("Force = %v, ForceReason = %q, Command = %q", config.Force, config.ForceReason, config.Command)
+	}
+}
+
+func TestParseArgs_Explain(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=web", "--explain", "--json", "rm -rf /"})
+	if config.Mode != "explain" || config.Command != "rm -rf /" || !config.JSONOutput {
+		t.Errorf("Mode = %q, Command = %q, JSONOutput = %v", config.Mode, config.Command, config.JSONOutput)
+	}
+}
+
+func TestParseArgs_HostTestJSON(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--host-test-all", "--json"})
+	if config.Mode != "host" || config.HostAction != "test-all" || !config.JSONOutput {
+		t.Errorf("Mode = %q, HostAction = %q, JSONOutput = %v", config.Mode, config.HostAction, config.JSONOutput)
+	}
+}
+
+func TestParseArgs_PoolStats(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--pool-stats", "--json"})
+	if config.Mode != "pool" || config.PoolAction != "stats" || !config.JSONOutput {
+		t.Errorf("Mode = %q, PoolAction = %q, JSONOutput = %v", config.Mode, config.PoolAction, config.JSONOutput)
+	}
+}
+
+func TestParseArgs_ControlPersist(t *testing.T) {
+	t.Setenv("SSH_CONTROL_PERSIST", "")
+	if config := ParseArgs([]string{"sshx", "-h=web1", "--control-persist", "uptime"}); config.ControlPersist != DefaultControlPersist || config.Command != "uptime" {
+		t.Errorf("ControlPersist = %s, Command = %q", config.ControlPersist, config.Command)
+	}
+	if config := ParseArgs([]string{"sshx", "-h=web1", "--control-persist=30m", "uptime"}); config.ControlPersist != 30*time.Minute {
+		t.Errorf("ControlPersist = %s, want 30m", config.ControlPersist)
+	}
+	t.Setenv("SSH_CONTROL_PERSIST", "5m")
+	if config := ParseArgs([]string{"sshx", "-h=web1", "uptime"}); config.ControlPersist != 5*time.Minute {
+		t.Errorf("ControlPersist = %s, want 5m from SSH_CONTROL_PERSIST", config.ControlPersist)
+	}
+	if config := ParseArgs([]string{"sshx", "--control-exit"}); config.Mode != "pool" || config.PoolAction != "control-exit" {
+		t.Errorf("Mode = %q, PoolAction = %q", config.Mode, config.PoolAction)
+	}
+}
+
+func TestParseArgs_HealthSelectors(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--hosts=prod", "--collect-cmd=uptime", "--skip-unreachable"})
+	if !config.SkipUnreachable || config.OnlyUnreachable {
+		t.Errorf("Expected only SkipUnreachable, got skip=%v only=%v", config.SkipUnreachable, config.OnlyUnreachable)
+	}
+
+	config = ParseArgs([]string{"sshx", "--warm=prod", "--only-unreachable"})
+	if !config.OnlyUnreachable || config.SkipUnreachable {
+		t.Errorf("Expected only OnlyUnreachable, got skip=%v only=%v", config.SkipUnreachable, config.OnlyUnreachable)
+	}
+}
+
+func TestParseArgs_Manifest(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--hosts=web", "--upload=app.jar", "--to=/opt/app/app.jar", "--manifest=release.json", "--remote-manifest=/opt/app/MANIFEST.json"})
+	if config.ManifestPath != "release.json" || config.RemoteManifest != "/opt/app/MANIFEST.json" {
+		t.Errorf("ManifestPath = %q, RemoteManifest = %q", config.ManifestPath, config.RemoteManifest)
+	}
+}
+
+func TestParseArgs_Forwarding(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=web1", "--forward-agent", "-X", "git pull"})
+	if !config.ForwardAgent || !config.ForwardX11 || config.Command != "git pull" {
+		t.Errorf("ForwardAgent = %v, ForwardX11 = %v, Command = %q", config.ForwardAgent, config.ForwardX11, config.Command)
+	}
+	if config := ParseArgs([]string{"sshx", "-h=web1", "--forward-x11", "xclock"}); config.ForwardAgent || !config.ForwardX11 {
+		t.Errorf("ForwardAgent = %v, ForwardX11 = %v", config.ForwardAgent, config.ForwardX11)
+	}
+}
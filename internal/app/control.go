@@ -0,0 +1,364 @@
+package app
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// DefaultControlPersist is how long a control master started by
+	// --control-persist stays up without requests
+	DefaultControlPersist = 10 * time.Minute
+	// ControlSocketFile is the unix socket of the control master, in the
+	// settings directory
+	ControlSocketFile = "control.sock"
+	// controlMasterCommand is the hidden sshx command running the control
+	// master in the background
+	controlMasterCommand = "control-master"
+	// controlStartTimeout bounds how long a CLI call waits for the control
+	// master it started to listen
+	controlStartTimeout = 3 * time.Second
+)
+
+// controlRequest is sent by a CLI call to the control master: a command to
+// run with the fully resolved configuration, hosts to Warm (names or groups)
+// with it as the base configuration, or Exit to stop the master
+type controlRequest struct {
+	Config sshclient.Config
+	Warm   string
+	Exit   bool
+}
+
+// controlResponse is the output of a command run by the control master, or
+// the error that ended it
+type controlResponse struct {
+	Output string
+	Error  string
+}
+
+// controlSocketPath returns the path of the control master socket
+func controlSocketPath() (string, error) {
+	dir, err := GetSettingsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ControlSocketFile), nil
+}
+
+// controlPersist returns how long the control master should be kept up:
+// --control-persist or SSH_CONTROL_PERSIST, then control_persist in the
+// settings. Zero runs the command over a direct connection.
+func controlPersist(config *sshclient.Config) time.Duration {
+	if config.ControlPersist > 0 {
+		return config.ControlPersist
+	}
+	settings, err := LoadSettings()
+	if err != nil {
+		return 0
+	}
+	return firstDuration("control_persist", settings.ControlPersist)
+}
+
+// usesControlMaster reports whether a CLI call can be handed to the control
+// master: plain commands only. Forced commands may need to prompt for
+// confirmation on this terminal, and forwarding needs this process's agent
+// and display, so they always connect directly.
+func usesControlMaster(config *sshclient.Config, watching bool) bool {
+	return config.Mode == "ssh" && config.Command != "" && !watching && !config.Force &&
+		!config.ForwardAgent && !config.ForwardX11
+}
+
+// executeViaControlMaster runs the command of config through the control
+// master, starting one when none is listening. It reports false when no
+// master could be reached, so the caller connects directly instead; once
+// the request was sent the command is never run a second time.
+func executeViaControlMaster(config *sshclient.Config, persist time.Duration) (bool, error) {
+	request := controlRequest{Config: *config}
+	// The CLI prints the whole output of a command
+	if request.Config.MaxOutputBytes == 0 {
+		request.Config.MaxOutputBytes = -1
+	}
+	handled, output, err := sendControlRequest(&request, persist)
+	fmt.Print(output)
+	return handled, err
+}
+
+// sendControlRequest hands request to the control master, starting one when
+// none is listening, and returns its output. It reports false when no
+// master could be reached; once the request was sent it is never repeated.
+func sendControlRequest(request *controlRequest, persist time.Duration) (bool, string, error) {
+	lg := logger.GetLogger()
+	conn, err := dialControlMaster()
+	if err != nil {
+		if conn, err = startControlMaster(persist); err != nil {
+			lg.Debug("Control master unavailable, connecting directly: %v", err)
+			return false, "", nil
+		}
+	}
+	defer func() { _ = conn.Close() }() //nolint:errcheck // response already read
+
+	if err = gob.NewEncoder(conn).Encode(request); err != nil {
+		lg.Debug("Control master unavailable, connecting directly: %v", err)
+		return false, "", nil
+	}
+	lg.Debug("Handing the request to the control master")
+
+	var response controlResponse
+	if err = gob.NewDecoder(conn).Decode(&response); err != nil {
+		return true, "", fmt.Errorf("control master failed: %w", err)
+	}
+	if response.Error != "" {
+		return true, response.Output, errors.New(response.Error)
+	}
+	return true, response.Output, nil
+}
+
+// dialControlMaster connects to a running control master
+func dialControlMaster() (net.Conn, error) {
+	path, err := controlSocketPath()
+	if err != nil {
+		return nil, err
+	}
+	return net.DialTimeout("unix", path, time.Second)
+}
+
+// startControlMaster starts a control master in the background and connects
+// to it. Concurrent calls may each start one; all but the first to listen
+// exit again.
+func startControlMaster(persist time.Duration) (net.Conn, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate sshx: %w", err)
+	}
+	cmd := exec.Command(executable, controlMasterCommand, "--control-persist="+persist.String()) // #nosec G204 -- runs this executable
+	detachProcess(cmd)
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start control master: %w", err)
+	}
+	_ = cmd.Process.Release() //nolint:errcheck // the master outlives this process
+
+	deadline := time.Now().Add(controlStartTimeout)
+	for {
+		conn, dialErr := dialControlMaster()
+		if dialErr == nil {
+			return conn, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("control master did not start: %w", dialErr)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// handleControlMaster runs the control master started by
+// startControlMaster, with args holding its --control-persist
+func handleControlMaster(args []string) error {
+	persist := DefaultControlPersist
+	for _, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--control-persist="); ok {
+			if d := firstDuration("--control-persist", value); d > 0 {
+				persist = d
+			}
+		}
+	}
+	path, err := controlSocketPath()
+	if err != nil {
+		return err
+	}
+	listener, err := listenControlSocket(path)
+	if err != nil {
+		return err
+	}
+
+	// Pooled connections live as long as the master unless configured
+	if settings, settingsErr := LoadSettings(); settingsErr != nil || settings.PoolIdleTimeout == "" {
+		sshclient.GetConnectionPool().SetMaxIdle(persist)
+	}
+	defer sshclient.GetConnectionPool().Close()
+
+	newControlMaster(listener, persist).serve()
+	return nil
+}
+
+// listenControlSocket listens on the control socket, replacing a stale
+// socket left by a master that died. Only the current user may connect.
+func listenControlSocket(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close() //nolint:errcheck // only probing
+		return nil, fmt.Errorf("a control master is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err = os.Chmod(path, 0o600); err != nil {
+		_ = listener.Close() //nolint:errcheck // already failing
+		return nil, fmt.Errorf("failed to restrict %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+// controlMaster runs the commands of CLI calls over pooled connections
+// until it has been idle for persist or is told to exit
+type controlMaster struct {
+	listener net.Listener
+	persist  time.Duration
+
+	mu     sync.Mutex
+	active int
+	idle   *time.Timer
+}
+
+func newControlMaster(listener net.Listener, persist time.Duration) *controlMaster {
+	m := &controlMaster{listener: listener, persist: persist}
+	m.idle = time.AfterFunc(persist, m.stop)
+	return m
+}
+
+// serve accepts requests until the master stops, then waits for the
+// commands still running
+func (m *controlMaster) serve() {
+	var wg sync.WaitGroup
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			break
+		}
+		m.begin()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer m.end()
+			m.handle(conn)
+		}()
+	}
+	wg.Wait()
+}
+
+// stop closes the listener, ending serve
+func (m *controlMaster) stop() {
+	_ = m.listener.Close() //nolint:errcheck // already closed when stopping twice
+}
+
+// begin holds the idle timer while a request runs
+func (m *controlMaster) begin() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active++
+	m.idle.Stop()
+}
+
+// end restarts the idle timer once no request is running
+func (m *controlMaster) end() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active--; m.active == 0 {
+		m.idle.Reset(m.persist)
+	}
+}
+
+// handle serves one request
+func (m *controlMaster) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }() //nolint:errcheck // response already written
+
+	var request controlRequest
+	if err := gob.NewDecoder(conn).Decode(&request); err != nil {
+		logger.GetLogger().Debug("invalid control request: %v", err)
+		return
+	}
+	if request.Exit {
+		m.stop()
+		_ = gob.NewEncoder(conn).Encode(&controlResponse{}) //nolint:errcheck // the caller only waits
+		return
+	}
+
+	var response controlResponse
+	var output string
+	var err error
+	if request.Warm != "" {
+		output, err = runControlWarm(&request.Config, request.Warm)
+	} else {
+		output, err = runControlCommand(&request.Config)
+	}
+	response.Output = output
+	if err != nil {
+		response.Error = err.Error()
+	}
+	if err = gob.NewEncoder(conn).Encode(&response); err != nil {
+		logger.GetLogger().Debug("failed to send control response: %v", err)
+	}
+}
+
+// runControlCommand runs a command for a CLI call over a pooled connection.
+// The connection stays pooled when the command fails, so a loop of failing
+// commands keeps reusing it.
+func runControlCommand(config *sshclient.Config) (string, error) {
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	defer func() { _ = client.Close() }() //nolint:errcheck // only releases the pooled connection
+	if err = client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+	output, err := client.ExecuteCommandWithOutput(sshclient.RequestFromConfig(config))
+	if err != nil {
+		return output, fmt.Errorf("failed to execute command: %w", err)
+	}
+	return output, nil
+}
+
+// runControlWarm connects the hosts of a --warm call into the pool of the
+// control master, where the commands handed to it later reuse them
+func runControlWarm(config *sshclient.Config, spec string) (string, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+	hosts, results, err := warmHosts(settings, spec, config)
+	if err != nil {
+		return "", err
+	}
+	output := formatWarmResults(hosts, results)
+	if failed := countWarmFailures(results); failed > 0 {
+		return output, fmt.Errorf("failed to warm %d host(s)", failed)
+	}
+	return output, nil
+}
+
+// stopControlMaster tells a running control master to exit
+func stopControlMaster() error {
+	conn, err := dialControlMaster()
+	if err != nil {
+		logger.GetLogger().Info("No control master is running")
+		return nil
+	}
+	defer func() { _ = conn.Close() }() //nolint:errcheck // response already read
+	if err = gob.NewEncoder(conn).Encode(&controlRequest{Exit: true}); err != nil {
+		return fmt.Errorf("failed to stop control master: %w", err)
+	}
+	var response controlResponse
+	if err = gob.NewDecoder(conn).Decode(&response); err != nil {
+		return fmt.Errorf("failed to stop control master: %w", err)
+	}
+	logger.GetLogger().Success("Control master stopped")
+	return nil
+}
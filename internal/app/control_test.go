@@ -0,0 +1,162 @@
+//go:build !windows
+
+package app
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+// startTestControlMaster serves a control master on the socket under a
+// temporary HOME and returns a channel closed once it stops
+func startTestControlMaster(t *testing.T, persist time.Duration) <-chan struct{} {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	path, err := controlSocketPath()
+	require.NoError(t, err)
+	listener, err := listenControlSocket(path)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newControlMaster(listener, persist).serve()
+	}()
+	t.Cleanup(func() {
+		_ = listener.Close()
+		<-done
+	})
+	return done
+}
+
+func TestControlRequestEncoding(t *testing.T) {
+	config := sshclient.Config{
+		Host:         "10.0.0.5",
+		Command:      "uptime",
+		Safety:       &sshclient.SafetyPolicy{Default: sshclient.SeverityWarn},
+		Sandbox:      &sshclient.Sandbox{Timeout: time.Minute},
+		ConfirmForce: func(*sshclient.BlockedCommandError) (string, error) { return "", nil },
+		Progress:     func(int64, int64, string) {},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(&controlRequest{Config: config}))
+
+	var decoded controlRequest
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+	assert.Equal(t, "uptime", decoded.Config.Command)
+	assert.Equal(t, sshclient.SeverityWarn, decoded.Config.Safety.Default)
+	assert.Equal(t, time.Minute, decoded.Config.Sandbox.Timeout)
+	assert.Nil(t, decoded.Config.ConfirmForce)
+}
+
+func TestUsesControlMaster(t *testing.T) {
+	assert.True(t, usesControlMaster(&sshclient.Config{Mode: "ssh", Command: "uptime"}, false))
+	assert.False(t, usesControlMaster(&sshclient.Config{Mode: "ssh", Command: "uptime"}, true))
+	assert.False(t, usesControlMaster(&sshclient.Config{Mode: "ssh", Command: "reboot", Force: true}, false))
+	assert.False(t, usesControlMaster(&sshclient.Config{Mode: "sftp"}, false))
+}
+
+func TestControlPersist(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	assert.Equal(t, time.Duration(0), controlPersist(&sshclient.Config{}))
+	assert.Equal(t, time.Minute, controlPersist(&sshclient.Config{ControlPersist: time.Minute}))
+
+	require.NoError(t, SaveSettings(&Settings{ControlPersist: "30m"}))
+	assert.Equal(t, 30*time.Minute, controlPersist(&sshclient.Config{}))
+}
+
+func TestControlMaster_RunsCommands(t *testing.T) {
+	startTestControlMaster(t, time.Minute)
+
+	// Nothing listens on the port, so the master reports the connect error
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := probe.Addr().(*net.TCPAddr).Port
+	require.NoError(t, probe.Close())
+
+	config := &sshclient.Config{
+		Host: "127.0.0.1", Port: strconv.Itoa(port), User: "nobody", Mode: "ssh", Command: "uptime",
+		DialTimeout: time.Second, MaxRetries: 1, AllowInsecureHostKey: true,
+	}
+	handled, err := executeViaControlMaster(config, time.Minute)
+	assert.True(t, handled)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to connect")
+}
+
+func TestControlMaster_Warm(t *testing.T) {
+	startTestControlMaster(t, time.Minute)
+
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := probe.Addr().(*net.TCPAddr).Port
+	require.NoError(t, probe.Close())
+	require.NoError(t, SaveSettings(&Settings{Hosts: []HostConfig{
+		{Name: "web1", Host: "127.0.0.1", Port: strconv.Itoa(port), User: "nobody", Tags: []string{"web"}},
+	}}))
+
+	config := &sshclient.Config{Mode: "pool", PoolAction: "warm", Hosts: "web", DialTimeout: time.Second, MaxRetries: 1, AllowInsecureHostKey: true}
+	handled, output, err := sendControlRequest(&controlRequest{Config: *config, Warm: config.Hosts}, time.Minute)
+	assert.True(t, handled)
+	assert.EqualError(t, err, "failed to warm 1 host(s)")
+	assert.Contains(t, output, "Pool warm-up (1 hosts)")
+	assert.Contains(t, output, "web1 (nobody@127.0.0.1:"+strconv.Itoa(port)+") failed")
+}
+
+func TestControlMaster_Exit(t *testing.T) {
+	done := startTestControlMaster(t, time.Minute)
+
+	require.NoError(t, stopControlMaster())
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("control master did not exit")
+	}
+	_, err := dialControlMaster()
+	assert.Error(t, err, "socket should be gone")
+
+	// Stopping without a master is not an error
+	assert.NoError(t, stopControlMaster())
+}
+
+func TestControlMaster_IdleTimeout(t *testing.T) {
+	done := startTestControlMaster(t, 50*time.Millisecond)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("control master did not exit when idle")
+	}
+}
+
+func TestListenControlSocket(t *testing.T) {
+	startTestControlMaster(t, time.Minute)
+	path, err := controlSocketPath()
+	require.NoError(t, err)
+
+	_, err = listenControlSocket(path)
+	assert.ErrorContains(t, err, "already listening")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// A socket left by a master that died is replaced
+	stale := filepath.Join(t.TempDir(), "stale.sock")
+	listener, err := net.Listen("unix", stale)
+	require.NoError(t, err)
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, listener.Close())
+	listener, err = listenControlSocket(stale)
+	require.NoError(t, err)
+	assert.NoError(t, listener.Close())
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package app
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess starts cmd in its own process group, so Ctrl+C in the
+// console of the CLI call that started it does not stop the control master
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
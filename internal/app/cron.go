@@ -0,0 +1,177 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros are the named schedules accepted in place of five fields
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField describes one field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // Names for min, min+1, ... (months, weekdays)
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 7 is accepted for Sunday as in most crons
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// cronSchedule is a parsed five-field cron expression (minute, hour, day of
+// month, month, day of week) evaluated in local time
+type cronSchedule struct {
+	fields [5]uint64 // Bit n is set when value n matches
+	// When both day fields are restricted a day matching either one
+	// matches, as in cron
+	anyDOM, anyDOW bool
+}
+
+// parseCron parses a cron expression: five fields of *, values, ranges
+// (1-5), lists (1,15) and steps (*/10, 0-30/5), or a macro such as @daily
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid schedule '%s': expected 5 fields (minute hour day-of-month month day-of-week) or a macro such as @daily", expr)
+	}
+
+	schedule := &cronSchedule{anyDOM: parts[2] == "*", anyDOW: parts[4] == "*"}
+	for i, part := range parts {
+		bits, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule '%s': %w", expr, err)
+		}
+		schedule.fields[i] = bits
+	}
+	// Sunday may be written as 0 or 7
+	if schedule.fields[4]&(1<<7) != 0 {
+		schedule.fields[4] |= 1
+	}
+	return schedule, nil
+}
+
+// parseCronField parses one comma-separated field into a bit set
+func parseCronField(value string, field cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(value, ",") {
+		rangePart, step := item, 1
+		if before, after, ok := strings.Cut(item, "/"); ok {
+			n, err := strconv.Atoi(after)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step '%s' in %s", after, field.name)
+			}
+			rangePart, step = before, n
+		}
+
+		low, high := field.min, field.max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = cronValue(from, field); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = cronValue(to, field); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "5/15" means from 5 to the end in steps of 15
+				high = field.max
+			}
+			if high < low {
+				return 0, fmt.Errorf("invalid range '%s' in %s", rangePart, field.name)
+			}
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// cronValue parses a number or name within the bounds of field
+func cronValue(value string, field cronField) (int, error) {
+	for i, name := range field.names {
+		if strings.EqualFold(value, name) {
+			return field.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < field.min || n > field.max {
+		return 0, fmt.Errorf("invalid %s '%s' (%d-%d)", field.name, value, field.min, field.max)
+	}
+	return n, nil
+}
+
+// matches reports whether the schedule fires in the minute of t
+func (c *cronSchedule) matches(t time.Time) bool {
+	if !c.has(0, t.Minute()) || !c.has(1, t.Hour()) || !c.has(3, int(t.Month())) {
+		return false
+	}
+	dom, dow := c.has(2, t.Day()), c.has(4, int(t.Weekday()))
+	switch {
+	case c.anyDOM && c.anyDOW:
+		return true
+	case c.anyDOM:
+		return dow
+	case c.anyDOW:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+func (c *cronSchedule) has(field, value int) bool {
+	return c.fields[field]&(1<<uint(value)) != 0
+}
+
+// next returns the first minute after t in which the schedule fires, or
+// the zero time when it never fires within five years (e.g. "0 0 31 2 *")
+func (c *cronSchedule) next(t time.Time) time.Time {
+	candidate := t.Truncate(time.Minute).Add(time.Minute)
+	limit := candidate.AddDate(5, 0, 0)
+	for candidate.Before(limit) {
+		switch {
+		case !c.has(3, int(candidate.Month())):
+			candidate = time.Date(candidate.Year(), candidate.Month()+1, 1, 0, 0, 0, 0, candidate.Location())
+		case !c.matchesDay(candidate):
+			candidate = time.Date(candidate.Year(), candidate.Month(), candidate.Day()+1, 0, 0, 0, 0, candidate.Location())
+		case !c.has(1, candidate.Hour()):
+			candidate = candidate.Truncate(time.Hour).Add(time.Hour)
+		case !c.has(0, candidate.Minute()):
+			candidate = candidate.Add(time.Minute)
+		default:
+			return candidate
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay applies the day-of-month and day-of-week fields to t
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	probe := *c
+	probe.fields[0], probe.fields[1] = ^uint64(0), ^uint64(0)
+	return probe.matches(day)
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * foo *",
+		"@reboot",
+	} {
+		_, err := parseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCronSchedule_Matches(t *testing.T) {
+	// 2026-03-02 is a Monday
+	monday := time.Date(2026, 3, 2, 3, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"* * * * *", monday, true},
+		{"30 3 * * *", monday, true},
+		{"*/15 * * * *", monday, true},
+		{"*/20 * * * *", monday, false},
+		{"0-29 * * * *", monday, false},
+		{"10,30,50 1-4 * * *", monday, true},
+		{"30 3 * * mon-fri", monday, true},
+		{"30 3 * * sat,sun", monday, false},
+		{"30 3 * mar *", monday, true},
+		{"30 3 2 * *", monday, true},
+		// Restricted day of month and day of week match either one
+		{"30 3 15 * mon", monday, true},
+		{"30 3 15 * tue", monday, false},
+		{"5/25 * * * *", monday, true},
+		{"@hourly", monday, false},
+		{"@daily", time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local), true},
+		// Sunday is 0 or 7
+		{"0 0 * * 7", time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), true},
+		{"0 0 * * 0", time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), true},
+	}
+	for _, tt := range tests {
+		schedule, err := parseCron(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, schedule.matches(tt.t), tt.expr)
+	}
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	from := time.Date(2026, 3, 2, 3, 30, 20, 0, time.Local)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 2, 3, 31, 0, 0, time.Local)},
+		{"30 3 * * *", time.Date(2026, 3, 3, 3, 30, 0, 0, time.Local)},
+		{"0 * * * *", time.Date(2026, 3, 2, 4, 0, 0, 0, time.Local)},
+		{"0 0 * * sun", time.Date(2026, 3, 8, 0, 0, 0, 0, time.Local)},
+		{"@monthly", time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)},
+		{"0 12 29 2 *", time.Date(2028, 2, 29, 12, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		schedule, err := parseCron(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, schedule.next(from), tt.expr)
+	}
+
+	never, err := parseCron("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, never.next(from).IsZero())
+}
@@ -0,0 +1,332 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// DefaultDaemonListen is the address `sshx daemon` listens on
+	DefaultDaemonListen = "127.0.0.1:7422"
+	// DaemonTokenFile holds the token generated for a daemon started without
+	// mcp_auth policies, in the settings directory
+	DaemonTokenFile = "daemon.token"
+	// maxDaemonRequestBytes bounds a request body, which may carry an upload
+	maxDaemonRequestBytes = 64 << 20
+)
+
+// errUnknownTool is returned for calls of tools the daemon does not expose
+var errUnknownTool = errors.New("unknown tool")
+
+// daemonShortcuts are the REST routes that run one tool with the request
+// body as its arguments
+var daemonShortcuts = map[string]string{
+	"/v1/exec":     "ssh_execute",
+	"/v1/upload":   "sftp_upload",
+	"/v1/download": "sftp_download",
+}
+
+// Daemon serves the MCP tools over a local REST API. Every request runs
+// through the same authorization, safety checks and audit log as an MCP
+// tool call, authenticated with a bearer token.
+type Daemon struct {
+	tools    []MCPTool
+	registry map[string]mcpToolSpec
+	// policies are the mcp_auth policies tokens are checked against
+	policies []MCPTokenPolicy
+	// token is the generated token accepted when no policies are configured
+	token string
+}
+
+// NewDaemon creates a daemon exposing the tools left by the allow and deny
+// lists of settings and args (--tools, --deny-tools). Without mcp_auth
+// policies it generates a token granting every tool and host.
+func NewDaemon(settings *Settings, args []string) (*Daemon, error) {
+	server := NewMCPServer()
+	if err := server.RestrictTools(mcpToolFilter(settings, args)); err != nil {
+		return nil, err
+	}
+	daemon := &Daemon{tools: server.tools, registry: server.registry}
+	if settings != nil && len(settings.MCPAuth) > 0 {
+		daemon.policies = settings.MCPAuth
+		return daemon, nil
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to generate daemon token: %w", err)
+	}
+	daemon.token = hex.EncodeToString(random)
+	return daemon, nil
+}
+
+// Handler returns the REST API:
+//
+//	GET  /v1/tools         tools the token may use
+//	GET  /v1/hosts         configured hosts (host_list)
+//	POST /v1/exec          run a command (ssh_execute arguments)
+//	POST /v1/upload        upload a file (sftp_upload arguments)
+//	POST /v1/download      download a file (sftp_download arguments)
+//	POST /v1/tools/{name}  run any tool with the JSON body as arguments
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/tools", d.handleTools)
+	mux.HandleFunc("GET /v1/hosts", func(w http.ResponseWriter, r *http.Request) {
+		d.callTool(w, r, "host_list", map[string]interface{}{})
+	})
+	for route, tool := range daemonShortcuts {
+		mux.HandleFunc("POST "+route, func(w http.ResponseWriter, r *http.Request) {
+			d.handleCall(w, r, tool)
+		})
+	}
+	mux.HandleFunc("POST /v1/tools/{name}", func(w http.ResponseWriter, r *http.Request) {
+		d.handleCall(w, r, r.PathValue("name"))
+	})
+	return mux
+}
+
+// authenticate returns the policy of an "Authorization: Bearer <token>"
+// value; nil with no error grants everything (the generated token)
+func (d *Daemon) authenticate(authorization string) (*MCPTokenPolicy, error) {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || token == "" {
+		return nil, errors.New("missing bearer token")
+	}
+	if d.token != "" {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(d.token)) == 1 {
+			return nil, nil
+		}
+		return nil, errors.New("invalid token")
+	}
+	policy, err := authenticateMCPToken(d.policies, token)
+	if err != nil {
+		return nil, errors.New("the token matches no mcp_auth policy")
+	}
+	return policy, nil
+}
+
+// authorize returns the tool executor for one call of tool by the holder of
+// policy, identifying as client, once the policy grants the call
+func (d *Daemon) authorize(policy *MCPTokenPolicy, client, tool string, args map[string]interface{}) (*MCPServer, error) {
+	if _, ok := d.registry[tool]; !ok {
+		return nil, fmt.Errorf("%w: %s", errUnknownTool, tool)
+	}
+	session := &MCPServer{
+		tools:    d.tools,
+		registry: d.registry,
+		caller:   policy,
+		client:   clientIdentity(client, ""),
+		source:   "daemon",
+	}
+	if err := session.authorizeToolCall(tool, args); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// run authorizes and runs one tool call
+func (d *Daemon) run(policy *MCPTokenPolicy, client, tool string, args map[string]interface{}) (string, error) {
+	session, err := d.authorize(policy, client, tool, args)
+	if err != nil {
+		return "", err
+	}
+	logger.GetLogger().Debug("daemon: %s", tool)
+	return session.executeTool(tool, args)
+}
+
+func (d *Daemon) handleTools(w http.ResponseWriter, r *http.Request) {
+	policy, err := d.authenticate(r.Header.Get("Authorization"))
+	if err != nil {
+		writeDaemonError(w, http.StatusUnauthorized, err.Error(), nil)
+		return
+	}
+	tools := make([]MCPTool, 0, len(d.tools))
+	for _, tool := range d.tools {
+		if policy.allowsTool(tool.Name) {
+			tools = append(tools, tool)
+		}
+	}
+	writeDaemonJSON(w, http.StatusOK, map[string]interface{}{"tools": tools})
+}
+
+// handleCall runs tool with the JSON object in the request body
+func (d *Daemon) handleCall(w http.ResponseWriter, r *http.Request, tool string) {
+	args := map[string]interface{}{}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDaemonRequestBytes))
+	if err != nil {
+		writeDaemonError(w, http.StatusRequestEntityTooLarge, err.Error(), nil)
+		return
+	}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err = json.Unmarshal(body, &args); err != nil {
+			writeDaemonError(w, http.StatusBadRequest, "request body must be a JSON object: "+err.Error(), nil)
+			return
+		}
+	}
+	d.callTool(w, r, tool, args)
+}
+
+// callTool authorizes and runs one tool call, answering with its result or
+// an error status: 401 without a valid token, 403 when the policy or the
+// safety check refuses it, 404 for unknown tools, 400 for invalid
+// arguments and 500 when the tool fails
+func (d *Daemon) callTool(w http.ResponseWriter, r *http.Request, tool string, args map[string]interface{}) {
+	policy, err := d.authenticate(r.Header.Get("Authorization"))
+	if err != nil {
+		writeDaemonError(w, http.StatusUnauthorized, err.Error(), nil)
+		return
+	}
+
+	result, err := d.run(policy, r.UserAgent(), tool, args)
+	var denied *MCPAuthorizationError
+	var argErr *ToolArgumentError
+	var blocked *sshclient.BlockedCommandError
+	switch {
+	case errors.Is(err, errUnknownTool):
+		writeDaemonError(w, http.StatusNotFound, err.Error(), nil)
+	case errors.As(err, &denied):
+		writeDaemonError(w, http.StatusForbidden, err.Error(), nil)
+	case errors.As(err, &argErr):
+		writeDaemonError(w, http.StatusBadRequest, argErr.Error(), map[string]interface{}{"field": argErr.Field})
+	case errors.As(err, &blocked):
+		writeDaemonError(w, http.StatusForbidden, logger.Redact(err.Error()), map[string]interface{}{"blocked": blocked})
+	case err != nil:
+		writeDaemonError(w, http.StatusInternalServerError, logger.Redact(err.Error()), nil)
+	default:
+		writeDaemonJSON(w, http.StatusOK, map[string]interface{}{"tool": tool, "result": logger.Redact(result)})
+	}
+}
+
+// writeDaemonError answers with {"error": message} plus details
+func writeDaemonError(w http.ResponseWriter, status int, message string, details map[string]interface{}) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	body := map[string]interface{}{"error": message}
+	for key, value := range details {
+		body[key] = value
+	}
+	writeDaemonJSON(w, status, body)
+}
+
+func writeDaemonJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.GetLogger().Debug("daemon: failed to write response: %v", err)
+	}
+}
+
+// checkLoopbackAddress rejects listen addresses other than loopback ones:
+// tokens and command output travel over plain HTTP
+func checkLoopbackAddress(listen string) error {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return fmt.Errorf("invalid --listen address '%s': %w", listen, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("--listen must be a loopback address such as %s, got '%s'", DefaultDaemonListen, listen)
+	}
+	return nil
+}
+
+// writeDaemonToken stores the generated token where local tools can read it
+func writeDaemonToken(token string) (string, error) {
+	dir, err := GetSettingsDir()
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, DaemonTokenFile)
+	if err = os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write daemon token: %w", err)
+	}
+	return path, nil
+}
+
+// handleDaemon runs `sshx daemon [--listen=addr] [--grpc-listen=addr]
+// [--tools=...] [--deny-tools=...]` until interrupted
+func handleDaemon(args []string) error {
+	lg := logger.GetLogger()
+	listen := DefaultDaemonListen
+	grpcListen := ""
+	for _, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--listen="); ok {
+			listen = value
+		}
+		if arg == "--grpc-listen" {
+			grpcListen = DefaultDaemonGRPCListen
+		}
+		if value, ok := strings.CutPrefix(arg, "--grpc-listen="); ok {
+			grpcListen = value
+		}
+	}
+	if err := checkLoopbackAddress(listen); err != nil {
+		return err
+	}
+
+	settings, err := LoadSettings()
+	if err != nil {
+		return err
+	}
+	daemon, err := NewDaemon(settings, args)
+	if err != nil {
+		return err
+	}
+	if daemon.token != "" {
+		path, tokenErr := writeDaemonToken(daemon.token)
+		if tokenErr != nil {
+			return tokenErr
+		}
+		defer func() { _ = os.Remove(path) }() //nolint:errcheck // best effort
+		lg.Info("No mcp_auth policies configured; the API token is in %s", path)
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+	server := &http.Server{Handler: daemon.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	stopGRPC := func() {}
+	if grpcListen != "" {
+		if stopGRPC, err = serveDaemonGRPC(daemon, grpcListen); err != nil {
+			_ = listener.Close() //nolint:errcheck // already failing
+			return err
+		}
+	}
+	removeHook := onShutdown(func() {
+		stopGRPC()
+		_ = server.Close() //nolint:errcheck // best-effort on shutdown
+	})
+	defer removeHook()
+	defer sshclient.GetConnectionPool().Close()
+	defer stopGRPC()
+	defer startEventSinks(settings)()
+	defer startInventorySync(settings)()
+	defer startExpiryPruning()()
+
+	lg.Success("sshx daemon listening on http://%s", listener.Addr())
+	if err = server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
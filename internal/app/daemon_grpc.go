@@ -0,0 +1,348 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+	"github.com/talkincode/sshmcp/pkg/sshxpb"
+)
+
+// DefaultDaemonGRPCListen is the address of --grpc-listen without a value
+const DefaultDaemonGRPCListen = "127.0.0.1:7423"
+
+// GRPCServer returns the gRPC API of the daemon (pkg/sshxpb/sshx.proto):
+// the Exec, Transfer, HostAdmin and Events services
+func (d *Daemon) GRPCServer() *grpc.Server {
+	server := grpc.NewServer(grpc.MaxRecvMsgSize(maxDaemonRequestBytes))
+	sshxpb.RegisterExecServer(server, &grpcExec{daemon: d})
+	sshxpb.RegisterTransferServer(server, &grpcTransfer{daemon: d})
+	sshxpb.RegisterHostAdminServer(server, &grpcHostAdmin{daemon: d})
+	sshxpb.RegisterEventsServer(server, &grpcEvents{daemon: d})
+	return server
+}
+
+// grpcCaller authenticates the bearer token in the call metadata and
+// returns its policy and the client's user agent
+func (d *Daemon) grpcCaller(ctx context.Context) (*MCPTokenPolicy, string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorization := ""
+	if values := md.Get("authorization"); len(values) > 0 {
+		authorization = values[0]
+	}
+	policy, err := d.authenticate(authorization)
+	if err != nil {
+		return nil, "", status.Error(codes.Unauthenticated, err.Error())
+	}
+	client := ""
+	if values := md.Get("user-agent"); len(values) > 0 {
+		client = values[0]
+	}
+	return policy, client, nil
+}
+
+// grpcRun runs a tool for a gRPC call, mapping its errors to status codes
+// as the REST API maps them to HTTP statuses
+func (d *Daemon) grpcRun(ctx context.Context, tool string, args map[string]interface{}) (string, error) {
+	policy, client, err := d.grpcCaller(ctx)
+	if err != nil {
+		return "", err
+	}
+	result, err := d.run(policy, client, tool, compactArgs(args))
+	if err != nil {
+		return "", grpcStatus(err)
+	}
+	return logger.Redact(result), nil
+}
+
+// grpcStatus converts an error of Daemon.run to a gRPC status
+func grpcStatus(err error) error {
+	var denied *MCPAuthorizationError
+	var argErr *ToolArgumentError
+	var blocked *sshclient.BlockedCommandError
+	switch {
+	case errors.Is(err, errUnknownTool):
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.As(err, &denied), errors.As(err, &blocked):
+		return status.Error(codes.PermissionDenied, logger.Redact(err.Error()))
+	case errors.As(err, &argErr):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Unknown, logger.Redact(err.Error()))
+	}
+}
+
+// compactArgs drops the zero values of unset proto3 fields, so tools apply
+// their defaults
+func compactArgs(args map[string]interface{}) map[string]interface{} {
+	for key, value := range args {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				delete(args, key)
+			}
+		case bool:
+			if !v {
+				delete(args, key)
+			}
+		case float64:
+			if v == 0 {
+				delete(args, key)
+			}
+		}
+	}
+	return args
+}
+
+type grpcExec struct {
+	sshxpb.UnimplementedExecServer
+	daemon *Daemon
+}
+
+func (s *grpcExec) Run(ctx context.Context, req *sshxpb.RunRequest) (*sshxpb.RunResponse, error) {
+	output, err := s.daemon.grpcRun(ctx, "ssh_execute", map[string]interface{}{
+		"host":         req.GetHost(),
+		"command":      req.GetCommand(),
+		"port":         float64(req.GetPort()),
+		"user":         req.GetUser(),
+		"run_as":       req.GetRunAs(),
+		"cwd":          req.GetCwd(),
+		"login_shell":  req.GetLoginShell(),
+		"force":        req.GetForce(),
+		"force_reason": req.GetForceReason(),
+		"max_output":   req.GetMaxOutput(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sshxpb.RunResponse{Output: output}, nil
+}
+
+type grpcTransfer struct {
+	sshxpb.UnimplementedTransferServer
+	daemon *Daemon
+}
+
+func (s *grpcTransfer) Upload(ctx context.Context, req *sshxpb.UploadRequest) (*sshxpb.TransferResponse, error) {
+	args := map[string]interface{}{
+		"host":        req.GetHost(),
+		"remote_path": req.GetRemotePath(),
+		"local_path":  req.GetLocalPath(),
+		"port":        float64(req.GetPort()),
+		"user":        req.GetUser(),
+		"backup":      req.GetBackup(),
+	}
+	if len(req.GetContent()) > 0 {
+		args["content_base64"] = base64.StdEncoding.EncodeToString(req.GetContent())
+		delete(args, "local_path")
+	}
+	result, err := s.daemon.grpcRun(ctx, "sftp_upload", args)
+	if err != nil {
+		return nil, err
+	}
+	return &sshxpb.TransferResponse{Result: result}, nil
+}
+
+func (s *grpcTransfer) Download(ctx context.Context, req *sshxpb.DownloadRequest) (*sshxpb.TransferResponse, error) {
+	result, err := s.daemon.grpcRun(ctx, "sftp_download", map[string]interface{}{
+		"host":        req.GetHost(),
+		"remote_path": req.GetRemotePath(),
+		"local_path":  req.GetLocalPath(),
+		"port":        float64(req.GetPort()),
+		"user":        req.GetUser(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sshxpb.TransferResponse{Result: result}, nil
+}
+
+type grpcHostAdmin struct {
+	sshxpb.UnimplementedHostAdminServer
+	daemon *Daemon
+}
+
+// List answers with the hosts the token may use, authorized as host_list
+func (s *grpcHostAdmin) List(ctx context.Context, _ *sshxpb.ListHostsRequest) (*sshxpb.ListHostsResponse, error) {
+	policy, client, err := s.daemon.grpcCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.daemon.authorize(policy, client, "host_list", map[string]interface{}{}); err != nil {
+		return nil, grpcStatus(err)
+	}
+	settings, err := LoadSettings()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	response := &sshxpb.ListHostsResponse{}
+	for _, host := range ListHosts(settings) {
+		if policy != nil && !policy.allowsHost(settings, host.Name) {
+			continue
+		}
+		port, _ := strconv.ParseUint(host.Port, 10, 32) //nolint:errcheck // validated when saved
+		response.Hosts = append(response.Hosts, &sshxpb.Host{
+			Name:        host.Name,
+			Host:        host.Host,
+			Description: host.Description,
+			Port:        uint32(port),
+			User:        host.User,
+			PasswordKey: host.PasswordKey,
+			Type:        host.Type,
+			Tags:        host.Tags,
+			Key:         host.Key,
+			Shell:       host.Shell,
+		})
+	}
+	return response, nil
+}
+
+func (s *grpcHostAdmin) Add(ctx context.Context, req *sshxpb.AddHostRequest) (*sshxpb.HostAdminResponse, error) {
+	host := req.GetHost()
+	if host == nil {
+		return nil, status.Error(codes.InvalidArgument, "host is required")
+	}
+	args := hostArgs(host)
+	args["name"] = host.GetName()
+	result, err := s.daemon.grpcRun(ctx, "host_add", args)
+	if err != nil {
+		return nil, err
+	}
+	return &sshxpb.HostAdminResponse{Result: result}, nil
+}
+
+// Update changes the fields in the update mask; unlike other calls an empty
+// value is passed on, clearing the field
+func (s *grpcHostAdmin) Update(ctx context.Context, req *sshxpb.UpdateHostRequest) (*sshxpb.HostAdminResponse, error) {
+	policy, client, err := s.daemon.grpcCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.GetUpdateMask()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "update_mask names no field")
+	}
+
+	// host_update takes the fields as strings, "" clearing them; port 0
+	// stands for the default port
+	values := hostArgs(req.GetHost())
+	args := map[string]interface{}{"name": req.GetName()}
+	for _, field := range req.GetUpdateMask() {
+		if !slices.Contains(HostUpdateFields, field) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown update_mask field '%s' (expected one of %s)", field, strings.Join(HostUpdateFields, ", "))
+		}
+		args[field] = fmt.Sprint(values[field])
+		if port, ok := values[field].(float64); ok && port == 0 {
+			args[field] = sshclient.DefaultSSHPort
+		}
+	}
+	result, err := s.daemon.run(policy, client, "host_update", args)
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	return &sshxpb.HostAdminResponse{Result: result}, nil
+}
+
+func (s *grpcHostAdmin) Remove(ctx context.Context, req *sshxpb.RemoveHostRequest) (*sshxpb.HostAdminResponse, error) {
+	result, err := s.daemon.grpcRun(ctx, "host_remove", map[string]interface{}{"name": req.GetName()})
+	if err != nil {
+		return nil, err
+	}
+	return &sshxpb.HostAdminResponse{Result: result}, nil
+}
+
+// hostArgs returns the host_add and host_update arguments of a host, keyed
+// by their JSON names
+func hostArgs(host *sshxpb.Host) map[string]interface{} {
+	return map[string]interface{}{
+		"host":         host.GetHost(),
+		"description":  host.GetDescription(),
+		"port":         float64(host.GetPort()),
+		"user":         host.GetUser(),
+		"password_key": host.GetPasswordKey(),
+		"type":         host.GetType(),
+		"tags":         strings.Join(host.GetTags(), ","),
+		"key":          host.GetKey(),
+		"shell":        host.GetShell(),
+	}
+}
+
+type grpcEvents struct {
+	sshxpb.UnimplementedEventsServer
+	daemon *Daemon
+}
+
+// Subscribe streams audit entries until the client goes away. Tokens
+// restricted to some hosts only see the entries of those hosts.
+func (s *grpcEvents) Subscribe(_ *sshxpb.SubscribeRequest, stream grpc.ServerStreamingServer[sshxpb.Event]) error {
+	policy, _, err := s.daemon.grpcCaller(stream.Context())
+	if err != nil {
+		return err
+	}
+	entries, unsubscribe := subscribeAudit()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case entry := <-entries:
+			if !policy.allowsRecord(entry.Host, entry.Address) {
+				continue
+			}
+			if err := stream.Send(auditEvent(entry)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func auditEvent(entry AuditEntry) *sshxpb.Event {
+	return &sshxpb.Event{
+		TimeUnixNano: entry.Time.UnixNano(),
+		Source:       entry.Source,
+		Caller:       entry.Caller,
+		Client:       entry.Client,
+		Tool:         entry.Tool,
+		Host:         entry.Host,
+		Address:      entry.Address,
+		Port:         entry.Port,
+		User:         entry.User,
+		Command:      entry.Command,
+		Force:        entry.Force,
+		Status:       entry.Status,
+		Error:        entry.Error,
+		DurationMs:   entry.DurationMs,
+	}
+}
+
+// serveDaemonGRPC serves the gRPC API on listen until the daemon stops
+func serveDaemonGRPC(daemon *Daemon, listen string) (stop func(), err error) {
+	if err = checkLoopbackAddress(listen); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+	server := daemon.GRPCServer()
+	go func() {
+		if serveErr := server.Serve(listener); serveErr != nil {
+			logger.GetLogger().Error("gRPC server stopped: %v", serveErr)
+		}
+	}()
+	logger.GetLogger().Success("sshx daemon gRPC API listening on %s", listener.Addr())
+	return server.Stop, nil
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/talkincode/sshmcp/pkg/sshxpb"
+)
+
+// dialTestGRPC serves the daemon's gRPC API in memory and connects to it
+func dialTestGRPC(t *testing.T, d *Daemon) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := d.GRPCServer()
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestDaemonGRPC_Exec(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	d, err := NewDaemon(&Settings{}, nil)
+	require.NoError(t, err)
+	client := sshxpb.NewExecClient(dialTestGRPC(t, d))
+
+	_, err = client.Run(context.Background(), &sshxpb.RunRequest{Host: "0.0.0.0", Command: "uptime"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	response, err := client.Run(withToken(d.token), &sshxpb.RunRequest{Host: "0.0.0.0", Command: "uptime"})
+	require.NoError(t, err)
+	assert.Contains(t, response.Output, "MCP Tool: ssh_execute")
+}
+
+func TestDaemonGRPC_HostAdmin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	d, err := NewDaemon(&Settings{}, nil)
+	require.NoError(t, err)
+	client := sshxpb.NewHostAdminClient(dialTestGRPC(t, d))
+	ctx := withToken(d.token)
+
+	_, err = client.Add(ctx, &sshxpb.AddHostRequest{Host: &sshxpb.Host{Name: "web1", Host: "10.0.0.5", Tags: []string{"prod", "web"}}})
+	require.NoError(t, err)
+	_, err = client.Update(ctx, &sshxpb.UpdateHostRequest{Name: "web1", Host: &sshxpb.Host{Port: 2222}, UpdateMask: []string{"port"}})
+	require.NoError(t, err)
+
+	list, err := client.List(ctx, &sshxpb.ListHostsRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Hosts, 1)
+	assert.Equal(t, uint32(2222), list.Hosts[0].Port)
+	assert.Equal(t, "master", list.Hosts[0].User)
+	assert.Equal(t, []string{"prod", "web"}, list.Hosts[0].Tags)
+
+	_, err = client.Update(ctx, &sshxpb.UpdateHostRequest{Name: "web1", UpdateMask: []string{"vars"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Remove(ctx, &sshxpb.RemoveHostRequest{Name: "web1"})
+	require.NoError(t, err)
+	list, err = client.List(ctx, &sshxpb.ListHostsRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, list.Hosts)
+}
+
+func TestDaemonGRPC_PolicyAndEvents(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	settings := &Settings{MCPAuth: []MCPTokenPolicy{
+		{Name: "admin", TokenSHA256: tokenHash("token-admin"), Tools: []string{"*"}, Hosts: []string{"*"}},
+		{Name: "staging", TokenSHA256: tokenHash("token-staging"), Tools: []string{"*"}, Hosts: []string{"staging"}},
+	}}
+	d, err := NewDaemon(settings, nil)
+	require.NoError(t, err)
+	conn := dialTestGRPC(t, d)
+
+	ctx, cancel := context.WithTimeout(withToken("token-admin"), 5*time.Second)
+	defer cancel()
+	events, err := sshxpb.NewEventsClient(conn).Subscribe(ctx, &sshxpb.SubscribeRequest{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		auditSubscribersMu.Lock()
+		defer auditSubscribersMu.Unlock()
+		return len(auditSubscribers) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = sshxpb.NewExecClient(conn).Run(withToken("token-staging"), &sshxpb.RunRequest{Host: "10.9.9.9", Command: "uptime"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	event, err := events.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, AuditStatusDenied, event.Status)
+	assert.Equal(t, "staging", event.Caller)
+	assert.Equal(t, "daemon", event.Source)
+	assert.Equal(t, "10.9.9.9", event.Address)
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// daemonRequest sends a request to the daemon handler and decodes the JSON
+// answer
+func daemonRequest(t *testing.T, d *Daemon, method, path, token, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("User-Agent", "deploy-script/1.0")
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, req)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decoded), rec.Body.String())
+	return rec.Code, decoded
+}
+
+func TestDaemon_Authentication(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	d, err := NewDaemon(&Settings{}, nil)
+	require.NoError(t, err)
+	require.Len(t, d.token, 64)
+
+	code, body := daemonRequest(t, d, http.MethodGet, "/v1/tools", "", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "missing bearer token", body["error"])
+
+	code, _ = daemonRequest(t, d, http.MethodGet, "/v1/tools", "wrong", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, body = daemonRequest(t, d, http.MethodGet, "/v1/tools", d.token, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, body["tools"], len(d.tools))
+}
+
+func TestDaemon_Exec(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	d, err := NewDaemon(&Settings{}, nil)
+	require.NoError(t, err)
+
+	code, body := daemonRequest(t, d, http.MethodPost, "/v1/exec", d.token, `{"host":"0.0.0.0","command":"uptime"}`)
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, "ssh_execute", body["tool"])
+	assert.Contains(t, body["result"], "MCP Tool: ssh_execute")
+
+	code, _ = daemonRequest(t, d, http.MethodPost, "/v1/exec", d.token, `[1, 2]`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, body = daemonRequest(t, d, http.MethodPost, "/v1/tools/no_such_tool", d.token, `{}`)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "unknown tool: no_such_tool", body["error"])
+}
+
+func TestDaemon_Policies(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	settings := &Settings{MCPAuth: []MCPTokenPolicy{
+		{Name: "ro", TokenSHA256: tokenHash("token-ro"), Tools: []string{"@readonly"}, Hosts: []string{"*"}},
+	}}
+	d, err := NewDaemon(settings, nil)
+	require.NoError(t, err)
+	assert.Empty(t, d.token, "mcp_auth tokens replace the generated one")
+
+	code, body := daemonRequest(t, d, http.MethodGet, "/v1/tools", "token-ro", "")
+	require.Equal(t, http.StatusOK, code)
+	for _, tool := range body["tools"].([]interface{}) {
+		assert.NotEqual(t, "ssh_execute", tool.(map[string]interface{})["name"])
+	}
+
+	code, _ = daemonRequest(t, d, http.MethodPost, "/v1/exec", "token-ro", `{"host":"0.0.0.0","command":"uptime"}`)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = daemonRequest(t, d, http.MethodGet, "/v1/tools", "token-other", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestCheckLoopbackAddress(t *testing.T) {
+	assert.NoError(t, checkLoopbackAddress("127.0.0.1:7422"))
+	assert.NoError(t, checkLoopbackAddress("[::1]:7422"))
+	assert.NoError(t, checkLoopbackAddress("localhost:0"))
+	assert.Error(t, checkLoopbackAddress("0.0.0.0:7422"))
+	assert.Error(t, checkLoopbackAddress("10.0.0.5:7422"))
+	assert.Error(t, checkLoopbackAddress("7422"))
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// DeviceConfig defines a network device profile in settings.json,
+// or changes a built-in one: fields that are set replace those of base (or
+// of the built-in profile of the same name), templates are merged
+type DeviceConfig struct {
+	Base       string            `json:"base,omitempty"`        // Built-in profile this one starts from (cisco_ios, junos, routeros, generic)
+	Prompt     string            `json:"prompt,omitempty"`      // Regular expression matching the whole prompt line (e.g. "[\\w.-]+[>#]")
+	Setup      []string          `json:"setup,omitempty"`       // Lines sent after login, e.g. to turn paging off
+	Exit       string            `json:"exit,omitempty"`        // Line that leaves the CLI
+	Errors     []string          `json:"errors,omitempty"`      // Regular expressions matching output lines of refused commands
+	PTY        *bool             `json:"pty,omitempty"`         // Request a pseudo-terminal
+	LineEnding string            `json:"line_ending,omitempty"` // "\n" (default) or "\r\n"
+	Templates  map[string]string `json:"templates,omitempty"`   // Named command sequences run with "@<name>", one command per line
+	Timeout    string            `json:"timeout,omitempty"`     // Wait for the prompt after each line (default: 60s)
+}
+
+// resolveDeviceProfile returns the device profile called name: a profile of
+// settings on top of its base, or a built-in one
+func resolveDeviceProfile(name string, settings *Settings) (*sshclient.DeviceProfile, error) {
+	var custom *DeviceConfig
+	if settings != nil {
+		custom = settings.DeviceProfiles[name]
+	}
+	builtin, isBuiltin := sshclient.DeviceProfiles[name]
+	if custom == nil {
+		if !isBuiltin {
+			return nil, fmt.Errorf("unknown device profile '%s' (use one of: %s)", name, strings.Join(deviceProfileNames(settings), ", "))
+		}
+		profile := builtin
+		return &profile, nil
+	}
+
+	var profile sshclient.DeviceProfile
+	if custom.Base != "" {
+		base, ok := sshclient.DeviceProfiles[custom.Base]
+		if !ok {
+			return nil, fmt.Errorf("device profile '%s': unknown base '%s' (use %s)", name, custom.Base, strings.Join(sshclient.DeviceProfileNames(), ", "))
+		}
+		profile = base
+	} else if isBuiltin {
+		profile = builtin
+	}
+	profile.Name = name
+	if custom.Prompt != "" {
+		profile.Prompt = custom.Prompt
+	}
+	if custom.Setup != nil {
+		profile.Setup = custom.Setup
+	}
+	if custom.Exit != "" {
+		profile.Exit = custom.Exit
+	}
+	if custom.Errors != nil {
+		profile.Errors = custom.Errors
+	}
+	if custom.PTY != nil {
+		profile.PTY = *custom.PTY
+	}
+	if custom.LineEnding != "" {
+		profile.LineEnding = custom.LineEnding
+	}
+	if len(custom.Templates) > 0 {
+		templates := maps.Clone(profile.Templates)
+		if templates == nil {
+			templates = make(map[string]string, len(custom.Templates))
+		}
+		maps.Copy(templates, custom.Templates)
+		profile.Templates = templates
+	}
+	if custom.Timeout != "" {
+		profile.Timeout = firstDuration("device_profiles."+name+".timeout", custom.Timeout)
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// validateHostDevice checks that the device profile of a host exists
+func validateHostDevice(settings *Settings, device string) error {
+	if device == "" {
+		return nil
+	}
+	_, err := resolveDeviceProfile(device, settings)
+	return err
+}
+
+// hostDevice returns the device profile of host, nil for regular hosts or
+// when the profile is broken, which is logged
+func hostDevice(host *HostConfig, settings *Settings) *sshclient.DeviceProfile {
+	if host.Device == "" {
+		return nil
+	}
+	profile, err := resolveDeviceProfile(host.Device, settings)
+	if err != nil {
+		logger.GetLogger().Warning("host '%s': %v, ignoring it", host.Name, err)
+		return nil
+	}
+	return profile
+}
+
+// deviceProfileNames lists the built-in and configured device profiles
+func deviceProfileNames(settings *Settings) []string {
+	names := sshclient.DeviceProfileNames()
+	if settings != nil {
+		for name := range settings.DeviceProfiles {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveDeviceProfile(t *testing.T) {
+	settings := &Settings{DeviceProfiles: map[string]*DeviceConfig{
+		"lab_switch": {
+			Base:      "cisco_ios",
+			Prompt:    `lab-sw\d+[>#]`,
+			Templates: map[string]string{"vlans": "show vlan brief"},
+			Timeout:   "2m",
+		},
+		"junos":  {Setup: []string{"set cli screen-length 0", "set cli screen-width 0"}},
+		"broken": {Base: "ios"},
+	}}
+
+	profile, err := resolveDeviceProfile("lab_switch", settings)
+	require.NoError(t, err)
+	assert.Equal(t, "lab_switch", profile.Name)
+	assert.Equal(t, `lab-sw\d+[>#]`, profile.Prompt)
+	assert.Equal(t, []string{"terminal length 0", "terminal width 511"}, profile.Setup)
+	assert.Equal(t, "show vlan brief", profile.Templates["vlans"])
+	assert.Equal(t, "show running-config", profile.Templates["config"], "base templates are kept")
+	assert.Equal(t, 2*time.Minute, profile.Timeout)
+
+	builtin, err := resolveDeviceProfile("cisco_ios", settings)
+	require.NoError(t, err)
+	assert.NotContains(t, builtin.Templates, "vlans", "the built-in profile must not change")
+
+	junos, err := resolveDeviceProfile("junos", settings)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"set cli screen-length 0", "set cli screen-width 0"}, junos.Setup)
+	assert.NotEmpty(t, junos.Prompt, "unset fields come from the built-in profile of the same name")
+
+	_, err = resolveDeviceProfile("broken", settings)
+	assert.ErrorContains(t, err, "unknown base 'ios'")
+	_, err = resolveDeviceProfile("nexus", settings)
+	assert.ErrorContains(t, err, "unknown device profile 'nexus'")
+	assert.ErrorContains(t, err, "lab_switch")
+}
+
+func TestHostDevice(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{{Name: "core-sw", Host: "10.0.0.2", User: "admin"}}}
+
+	_, err := ApplyHostUpdate(settings, "core-sw", map[string]string{"device": "nexus"})
+	assert.ErrorContains(t, err, "unknown device profile 'nexus'")
+	host, err := ApplyHostUpdate(settings, "core-sw", map[string]string{"device": "cisco_ios"})
+	require.NoError(t, err)
+	assert.Equal(t, "cisco_ios", host.Device)
+
+	cfg := newHostSSHConfig(host, settings, nil)
+	require.NotNil(t, cfg.Device)
+	assert.Equal(t, "cisco_ios", cfg.Device.Name)
+
+	host, err = ApplyHostUpdate(settings, "core-sw", map[string]string{"device": ""})
+	require.NoError(t, err)
+	assert.Nil(t, newHostSSHConfig(host, settings, nil).Device)
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// diagnoseIcons marks each step status in the text report
+var diagnoseIcons = map[string]string{
+	sshclient.DiagnoseOK:      "✓",
+	sshclient.DiagnoseWarning: "⚠️",
+	sshclient.DiagnoseFailed:  "❌",
+	sshclient.DiagnoseSkipped: "-",
+}
+
+// handleHostDiagnose walks through each stage of connecting to a host and
+// reports where it breaks
+func handleHostDiagnose(config *sshclient.Config) error {
+	target := config.HostName
+	if target == "" {
+		target = config.Host
+	}
+	if target == "" {
+		return fmt.Errorf("host is required (use --diagnose=<host> or --diagnose -h=<host>)")
+	}
+
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	report := sshclient.Diagnose(diagnoseConfig(settings, target, config))
+	fmt.Print(logger.Plain(formatDiagnoseReport(report)))
+
+	if report.FirstFailed != "" {
+		return fmt.Errorf("diagnosis failed at step %s", report.FirstFailed)
+	}
+	return nil
+}
+
+// diagnoseConfig builds the SSH config for a configured host name, or for a
+// literal address when no host by that name exists
+func diagnoseConfig(settings *Settings, target string, base *sshclient.Config) *sshclient.Config {
+	if host, err := GetHost(settings, target); err == nil {
+		return newHostSSHConfig(host, settings, base)
+	}
+
+	config := *base
+	config.Host = target
+	if config.UseKeyAuth && config.KeyPath == "" && settings.Key != "" {
+		config.KeyPath = settings.Key
+	}
+	applyHostKeySettings(&config, settings)
+	applyConnectionDefaults(&config, nil, settings)
+	return &config
+}
+
+// formatDiagnoseReport renders a diagnosis as a step-by-step report
+func formatDiagnoseReport(report *sshclient.DiagnoseReport) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Diagnosing %s@%s:%s\n", report.User, report.Host, report.Port))
+
+	for i, step := range report.Steps {
+		output.WriteString(fmt.Sprintf("  %d. %s %s", i+1, diagnoseIcons[step.Status], step.Name))
+		if step.Status != sshclient.DiagnoseSkipped {
+			output.WriteString(fmt.Sprintf(" (%dms)", step.DurationMs))
+		}
+		output.WriteString(": " + step.Detail + "\n")
+	}
+
+	if report.FirstFailed != "" {
+		output.WriteString(fmt.Sprintf("Result: failed at %s\n", report.FirstFailed))
+	} else {
+		output.WriteString("Result: all checks passed\n")
+	}
+	return output.String()
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestParseArgs_Diagnose(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--diagnose", "-h=web1"})
+	assert.Equal(t, "host", config.Mode)
+	assert.Equal(t, "diagnose", config.HostAction)
+	assert.Equal(t, "web1", config.Host)
+
+	config = ParseArgs([]string{"sshx", "--diagnose=db1"})
+	assert.Equal(t, "diagnose", config.HostAction)
+	assert.Equal(t, "db1", config.HostName)
+}
+
+func TestDiagnoseConfig(t *testing.T) {
+	settings := &Settings{
+		Key:   "~/.ssh/id_ed25519",
+		Hosts: []HostConfig{{Name: "web1", Host: "10.0.0.1", Port: "2222", User: "deploy"}},
+	}
+	base := &sshclient.Config{UseKeyAuth: true, Port: "22"}
+
+	config := diagnoseConfig(settings, "web1", base)
+	assert.Equal(t, "10.0.0.1", config.Host)
+	assert.Equal(t, "2222", config.Port)
+	assert.Equal(t, "deploy", config.User)
+	assert.Equal(t, "web1", config.Alias)
+	assert.Equal(t, "~/.ssh/id_ed25519", config.KeyPath)
+
+	config = diagnoseConfig(settings, "10.0.0.9", base)
+	assert.Equal(t, "10.0.0.9", config.Host)
+	assert.Equal(t, "22", config.Port)
+	assert.Equal(t, "~/.ssh/id_ed25519", config.KeyPath)
+	assert.Empty(t, base.Host, "base config must not be modified")
+}
+
+func TestFormatDiagnoseReport(t *testing.T) {
+	report := &sshclient.DiagnoseReport{
+		Host: "10.0.0.1",
+		Port: "22",
+		User: "deploy",
+		Steps: []sshclient.DiagnoseStep{
+			{Name: "dns", Status: sshclient.DiagnoseOK, Detail: "10.0.0.1 is an IP address, no lookup needed"},
+			{Name: "tcp_connect", Status: sshclient.DiagnoseFailed, DurationMs: 2000, Detail: "cannot connect to 10.0.0.1:22: i/o timeout"},
+			{Name: "ssh_banner", Status: sshclient.DiagnoseSkipped, Detail: "skipped after tcp_connect failed"},
+		},
+		FirstFailed: "tcp_connect",
+	}
+
+	output := formatDiagnoseReport(report)
+
+	assert.Contains(t, output, "Diagnosing deploy@10.0.0.1:22")
+	assert.Contains(t, output, "1. ✓ dns (0ms): 10.0.0.1 is an IP address")
+	assert.Contains(t, output, "2. ❌ tcp_connect (2000ms): cannot connect")
+	assert.Contains(t, output, "3. - ssh_banner: skipped after tcp_connect failed")
+	assert.Contains(t, output, "Result: failed at tcp_connect")
+}
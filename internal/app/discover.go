@@ -0,0 +1,168 @@
+package app
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// discoveredTag is the group tag of hosts added by --discover
+const discoveredTag = "discovered"
+
+// handleHostDiscover scans a subnet (--discover=<cidr>, default the local
+// network) and the hosts advertised over mDNS for SSH servers, lists them
+// and, at a terminal, offers to add the ones not configured yet
+func handleHostDiscover(config *sshclient.Config) error {
+	lg := logger.GetLogger()
+	cidr := config.DiscoverCIDR
+	if cidr == "" {
+		subnet, err := sshclient.LocalSubnet()
+		if err != nil {
+			return err
+		}
+		cidr = subnet
+	}
+
+	lg.Info("Scanning %s for SSH servers...", cidr)
+	found, err := sshclient.Discover(cidr, sshclient.DiscoverOptions{Port: config.Port, MDNS: true})
+	if err != nil {
+		return err
+	}
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	fmt.Print(logger.Plain(formatDiscoveredHosts(settings, found)))
+
+	if !isTerminal(os.Stdin) {
+		if len(found) > 0 {
+			fmt.Println("Run at a terminal to add them, or use sshx --host-add --host-name=<name> -h=<address>")
+		}
+		return nil
+	}
+	added, err := offerDiscoveredHosts(os.Stdin, os.Stdout, settings, found, config.User)
+	if err != nil {
+		return err
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	if err := SaveSettings(settings); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	lg.Success("Added %d host(s): %s", len(added), strings.Join(added, ", "))
+	return nil
+}
+
+// formatDiscoveredHosts renders the scan results, naming the hosts that are
+// configured already
+func formatDiscoveredHosts(settings *Settings, found []sshclient.DiscoveredHost) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("SSH servers found (%d):\n", len(found)))
+	for _, host := range found {
+		output.WriteString(fmt.Sprintf("  %s  %s", net.JoinHostPort(host.Address, host.Port), host.Banner))
+		if host.Name != "" {
+			output.WriteString(fmt.Sprintf("  (%s)", host.Name))
+		}
+		if existing := configuredHost(settings, host); existing != nil {
+			output.WriteString(fmt.Sprintf("  ✓ configured as %s", existing.Name))
+		}
+		output.WriteString("\n")
+	}
+	return output.String()
+}
+
+// configuredHost returns the configured host at the address and port of a
+// discovered host, or nil
+func configuredHost(settings *Settings, host sshclient.DiscoveredHost) *HostConfig {
+	for i := range settings.Hosts {
+		configured := &settings.Hosts[i]
+		if configured.Host == host.Address && cmp.Or(configured.Port, sshclient.DefaultSSHPort) == host.Port {
+			return configured
+		}
+	}
+	return nil
+}
+
+// offerDiscoveredHosts asks, for every discovered host that is not
+// configured, which name to add it under: Enter takes the suggested name,
+// "-" skips the host. Added hosts are tagged discoveredTag and returned by
+// name; settings are changed but not saved.
+func offerDiscoveredHosts(in io.Reader, out io.Writer, settings *Settings, found []sshclient.DiscoveredHost, user string) ([]string, error) {
+	reader := bufio.NewReader(in)
+	var added []string
+	for _, discovered := range found {
+		if configuredHost(settings, discovered) != nil {
+			continue
+		}
+		suggested := suggestHostName(settings, discovered)
+		fmt.Fprintf(out, "Add %s (%s) as [%s]? Name, Enter to accept, - to skip: ", discovered.Address, discovered.Banner, suggested)
+		answer, err := reader.ReadString('\n')
+		if err != nil && answer == "" {
+			if errors.Is(err, io.EOF) {
+				return added, nil
+			}
+			return added, fmt.Errorf("failed to read answer: %w", err)
+		}
+		name := strings.TrimSpace(answer)
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = suggested
+		}
+
+		host := HostConfig{
+			Name: name,
+			Host: discovered.Address,
+			Port: discovered.Port,
+			User: user,
+			Tags: []string{discoveredTag},
+		}
+		if strings.Contains(strings.ToLower(discovered.Banner), "windows") {
+			host.Type = "windows"
+		}
+		if err := AddHost(settings, host); err != nil {
+			fmt.Fprintf(out, "Not added: %v\n", err)
+			continue
+		}
+		added = append(added, name)
+	}
+	return added, nil
+}
+
+// suggestHostName derives an unused host name from the mDNS or reverse DNS
+// name of a discovered host, else from its address
+func suggestHostName(settings *Settings, host sshclient.DiscoveredHost) string {
+	label, _, _ := strings.Cut(host.Name, ".")
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return '-'
+		}
+	}, label)
+	base = strings.Trim(base, "-")
+	if base == "" {
+		base = "host-" + strings.NewReplacer(".", "-", ":", "-").Replace(host.Address)
+	}
+
+	name := base
+	for i := 2; ; i++ {
+		if _, err := GetHost(settings, name); err != nil {
+			return name
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestOfferDiscoveredHosts(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "router", Host: "192.168.1.1", Port: "22"},
+		{Name: "nas", Host: "192.168.1.5", Port: "22"},
+	}}
+	found := []sshclient.DiscoveredHost{
+		{Address: "192.168.1.1", Port: "22", Banner: "SSH-2.0-dropbear"},
+		{Address: "192.168.1.20", Port: "22", Banner: "SSH-2.0-OpenSSH_9.6", Name: "NAS.local"},
+		{Address: "192.168.1.21", Port: "22", Banner: "SSH-2.0-OpenSSH_for_Windows_9.5"},
+		{Address: "192.168.1.22", Port: "2222", Banner: "SSH-2.0-OpenSSH_9.2"},
+		{Address: "192.168.1.23", Port: "22", Banner: "SSH-2.0-OpenSSH_9.2"},
+	}
+
+	var out bytes.Buffer
+	added, err := offerDiscoveredHosts(strings.NewReader("\nbuild-box\n-\n"), &out, settings, found, "pi")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nas-2", "build-box"}, added)
+	assert.NotContains(t, out.String(), "192.168.1.1 ")
+	assert.Contains(t, out.String(), "Add 192.168.1.20 (SSH-2.0-OpenSSH_9.6) as [nas-2]?")
+
+	nas, err := GetHost(settings, "nas-2")
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.20", nas.Host)
+	assert.Equal(t, "pi", nas.User)
+	assert.Equal(t, []string{discoveredTag}, nas.Tags)
+	windows, err := GetHost(settings, "build-box")
+	require.NoError(t, err)
+	assert.Equal(t, "windows", windows.Type)
+	_, err = GetHost(settings, "host-192-168-1-22")
+	assert.Error(t, err, "skipped host should not be added")
+	assert.Len(t, settings.Hosts, 4)
+}
+
+func TestFormatDiscoveredHosts(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{{Name: "router", Host: "192.168.1.1"}}}
+	output := formatDiscoveredHosts(settings, []sshclient.DiscoveredHost{
+		{Address: "192.168.1.1", Port: "22", Banner: "SSH-2.0-dropbear"},
+		{Address: "192.168.1.20", Port: "2222", Banner: "SSH-2.0-OpenSSH_9.6", Name: "nas.local"},
+	})
+	assert.Contains(t, output, "SSH servers found (2)")
+	assert.Contains(t, output, "192.168.1.1:22  SSH-2.0-dropbear  ✓ configured as router")
+	assert.Contains(t, output, "192.168.1.20:2222  SSH-2.0-OpenSSH_9.6  (nas.local)\n")
+}
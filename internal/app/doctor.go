@@ -0,0 +1,328 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// doctorProbeKey is looked up to test the keyring backend; it is never stored
+const doctorProbeKey = "sshx-doctor-probe"
+
+// DoctorCheck is the outcome of one local environment check. Status uses
+// the diagnosis statuses (ok, warning, failed).
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// handleDoctor checks the local environment sshx depends on and prints
+// each problem with a suggested fix
+func handleDoctor() error {
+	checks := runDoctorChecks()
+	fmt.Print(logger.Plain(formatDoctorReport(checks)))
+
+	failed := 0
+	for _, check := range checks {
+		if check.Status == sshclient.DiagnoseFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// runDoctorChecks runs every check against the current user's environment
+func runDoctorChecks() []DoctorCheck {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return []DoctorCheck{{
+			Name:   "home directory",
+			Status: sshclient.DiagnoseFailed,
+			Detail: err.Error(),
+			Fix:    "set HOME to your home directory",
+		}}
+	}
+
+	settingsPath := filepath.Join(home, SettingsDir, SettingsFile)
+	settingsCheck, settings := checkSettingsFile(settingsPath)
+
+	keyPath := os.Getenv("SSH_KEY_PATH")
+	if keyPath == "" && settings != nil {
+		keyPath = settings.Key
+	}
+	if keyPath == "" {
+		keyPath = filepath.Join(home, ".ssh", "id_rsa")
+	}
+
+	knownHostsPath := os.Getenv("SSH_KNOWN_HOSTS")
+	if knownHostsPath == "" {
+		knownHostsPath = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	return []DoctorCheck{
+		settingsCheck,
+		checkDefaultKey(expandHome(keyPath)),
+		checkKnownHostsFile(expandHome(knownHostsPath)),
+		checkKeyring(),
+		checkSSHAgent(os.Getenv("SSH_AUTH_SOCK")),
+		checkLogDir(filepath.Join(home, SettingsDir)),
+	}
+}
+
+// checkSettingsFile validates settings.json and its hosts. The parsed
+// settings are returned when the file is readable.
+func checkSettingsFile(path string) (DoctorCheck, *Settings) {
+	check := DoctorCheck{Name: "settings.json", Status: sshclient.DiagnoseOK}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- settings path under the user's home
+	if os.IsNotExist(err) {
+		check.Detail = fmt.Sprintf("%s does not exist, defaults are used", path)
+		return check, nil
+	}
+	if err != nil {
+		check.Status = sshclient.DiagnoseFailed
+		check.Detail = fmt.Sprintf("cannot read %s: %v", path, err)
+		check.Fix = fmt.Sprintf("check the permissions of %s", path)
+		return check, nil
+	}
+
+	settings, err := decodeSettings(data)
+	if err != nil {
+		check.Status = sshclient.DiagnoseFailed
+		check.Detail = fmt.Sprintf("%s is not valid JSON: %v", path, err)
+		check.Fix = fmt.Sprintf("fix the syntax error or move %s aside to start over", path)
+		return check, nil
+	}
+
+	var problems []string
+	seen := make(map[string]bool)
+	for i := range settings.Hosts {
+		host := settings.Hosts[i]
+		if err := ValidateHostConfig(&host); err != nil {
+			problems = append(problems, fmt.Sprintf("host #%d: %v", i+1, err))
+			continue
+		}
+		if seen[host.Name] {
+			problems = append(problems, fmt.Sprintf("host %q is defined more than once", host.Name))
+		}
+		seen[host.Name] = true
+	}
+	if len(problems) > 0 {
+		check.Status = sshclient.DiagnoseWarning
+		check.Detail = strings.Join(problems, "; ")
+		check.Fix = "correct the hosts with sshx --host-update or edit " + path
+		return check, settings
+	}
+
+	check.Detail = fmt.Sprintf("%d host(s) configured", len(settings.Hosts))
+	return check, settings
+}
+
+// checkDefaultKey verifies the default private key exists, is private to
+// the user and can be used without a passphrase
+func checkDefaultKey(path string) DoctorCheck {
+	check := DoctorCheck{Name: "default SSH key", Status: sshclient.DiagnoseOK}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		check.Status = sshclient.DiagnoseWarning
+		check.Detail = fmt.Sprintf("%s not found, only password authentication is available", path)
+		check.Fix = "create a key with ssh-keygen -t ed25519, or set \"key\" in settings.json to an existing one"
+		return check
+	}
+	if info.IsDir() {
+		check.Status = sshclient.DiagnoseFailed
+		check.Detail = fmt.Sprintf("%s is a directory", path)
+		check.Fix = "point --key or \"key\" in settings.json at a private key file"
+		return check
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- the user's own key
+	if err != nil {
+		check.Status = sshclient.DiagnoseFailed
+		check.Detail = fmt.Sprintf("cannot read %s: %v", path, err)
+		check.Fix = fmt.Sprintf("chmod 600 %s", path)
+		return check
+	}
+	if _, err := ssh.ParsePrivateKey(data); err != nil {
+		var passphraseErr *ssh.PassphraseMissingError
+		check.Status = sshclient.DiagnoseFailed
+		if errors.As(err, &passphraseErr) {
+			check.Status = sshclient.DiagnoseWarning
+			check.Detail = fmt.Sprintf("%s is protected by a passphrase, which sshx cannot prompt for", path)
+			check.Fix = "store the passphrase with sshx --password-set=NAME and set key_passphrase_key on the hosts using the key, or use a dedicated key without a passphrase"
+			return check
+		}
+		check.Detail = fmt.Sprintf("%s is not a valid private key: %v", path, err)
+		check.Fix = "regenerate the key with ssh-keygen or point \"key\" in settings.json at a valid one"
+		return check
+	}
+
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		check.Status = sshclient.DiagnoseWarning
+		check.Detail = fmt.Sprintf("%s is accessible by other users (mode %04o)", path, info.Mode().Perm())
+		check.Fix = fmt.Sprintf("chmod 600 %s", path)
+		return check
+	}
+
+	check.Detail = path
+	return check
+}
+
+// checkKnownHostsFile verifies known_hosts parses and is not writable by
+// other users
+func checkKnownHostsFile(path string) DoctorCheck {
+	check := DoctorCheck{Name: "known_hosts", Status: sshclient.DiagnoseOK}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		check.Status = sshclient.DiagnoseWarning
+		check.Detail = fmt.Sprintf("%s does not exist, every host is unknown", path)
+		check.Fix = "connect once with --accept-unknown-host, or run ssh-keyscan <host> >> " + path
+		return check
+	}
+	if err != nil || info.IsDir() {
+		check.Status = sshclient.DiagnoseFailed
+		check.Detail = fmt.Sprintf("cannot use %s as known_hosts", path)
+		check.Fix = fmt.Sprintf("remove %s or point --known-hosts at a regular file", path)
+		return check
+	}
+
+	if _, err := knownhosts.New(path); err != nil {
+		check.Status = sshclient.DiagnoseFailed
+		check.Detail = fmt.Sprintf("%s cannot be parsed: %v", path, err)
+		check.Fix = "remove or fix the reported line (ssh-keygen -R <host> removes a host's entries)"
+		return check
+	}
+
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o022 != 0 {
+		check.Status = sshclient.DiagnoseWarning
+		check.Detail = fmt.Sprintf("%s is writable by other users (mode %04o)", path, info.Mode().Perm())
+		check.Fix = fmt.Sprintf("chmod 644 %s", path)
+		return check
+	}
+
+	check.Detail = path
+	return check
+}
+
+// checkKeyring looks up a key that never exists; "not found" proves the
+// backend answers
+func checkKeyring() DoctorCheck {
+	check := DoctorCheck{Name: "keyring", Status: sshclient.DiagnoseOK, Detail: "system keyring is available"}
+
+	_, err := keyring.Get(sshclient.KeyringServiceName, doctorProbeKey)
+	if err == nil || errors.Is(err, keyring.ErrNotFound) {
+		return check
+	}
+
+	check.Status = sshclient.DiagnoseFailed
+	check.Detail = fmt.Sprintf("system keyring is unavailable: %v", err)
+	switch runtime.GOOS {
+	case "linux":
+		check.Fix = "install and unlock a Secret Service provider (gnome-keyring, KeePassXC), or pass passwords with SSH_PASSWORD"
+	case "darwin":
+		check.Fix = "unlock the login keychain (security unlock-keychain), or pass passwords with SSH_PASSWORD"
+	default:
+		check.Fix = "make sure Windows Credential Manager is available, or pass passwords with SSH_PASSWORD"
+	}
+	return check
+}
+
+// checkSSHAgent reports whether an ssh-agent is reachable and how many keys
+// it holds
+func checkSSHAgent(socket string) DoctorCheck {
+	check := DoctorCheck{Name: "ssh-agent", Status: sshclient.DiagnoseOK}
+
+	if socket == "" {
+		check.Status = sshclient.DiagnoseWarning
+		check.Detail = "SSH_AUTH_SOCK is not set"
+		check.Fix = "start an agent with eval \"$(ssh-agent)\" if other ssh tools need one; sshx itself reads key files"
+		return check
+	}
+
+	conn, err := net.DialTimeout("unix", socket, 2*time.Second)
+	if err != nil {
+		check.Status = sshclient.DiagnoseWarning
+		check.Detail = fmt.Sprintf("cannot connect to %s: %v", socket, err)
+		check.Fix = "restart the agent and export the new SSH_AUTH_SOCK"
+		return check
+	}
+	defer func() { _ = conn.Close() }() //nolint:errcheck // probe connection
+
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		check.Status = sshclient.DiagnoseWarning
+		check.Detail = fmt.Sprintf("agent at %s did not answer: %v", socket, err)
+		check.Fix = "restart the agent and export the new SSH_AUTH_SOCK"
+		return check
+	}
+
+	check.Detail = fmt.Sprintf("%d key(s) loaded", len(keys))
+	return check
+}
+
+// checkLogDir verifies sshx can write its log and state files
+func checkLogDir(dir string) DoctorCheck {
+	check := DoctorCheck{Name: "log directory", Status: sshclient.DiagnoseOK, Detail: dir}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		check.Status = sshclient.DiagnoseFailed
+		check.Detail = fmt.Sprintf("cannot create %s: %v", dir, err)
+		check.Fix = fmt.Sprintf("create %s and make it writable by your user", dir)
+		return check
+	}
+
+	probe, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		check.Status = sshclient.DiagnoseFailed
+		check.Detail = fmt.Sprintf("%s is not writable: %v", dir, err)
+		check.Fix = fmt.Sprintf("chown -R $USER %s && chmod 700 %s", dir, dir)
+		return check
+	}
+	_ = probe.Close()           //nolint:errcheck // probe file
+	_ = os.Remove(probe.Name()) //nolint:errcheck // probe file
+	return check
+}
+
+// formatDoctorReport renders the checks with a fix under each problem
+func formatDoctorReport(checks []DoctorCheck) string {
+	var output strings.Builder
+	output.WriteString("sshx doctor\n")
+
+	problems := 0
+	for _, check := range checks {
+		output.WriteString(fmt.Sprintf("  %s %s: %s\n", diagnoseIcons[check.Status], check.Name, check.Detail))
+		if check.Fix != "" && check.Status != sshclient.DiagnoseOK {
+			output.WriteString(fmt.Sprintf("      fix: %s\n", check.Fix))
+		}
+		if check.Status != sshclient.DiagnoseOK {
+			problems++
+		}
+	}
+
+	if problems == 0 {
+		output.WriteString("Result: no problems found\n")
+	} else {
+		output.WriteString(fmt.Sprintf("Result: %d problem(s) found\n", problems))
+	}
+	return output.String()
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func writeDoctorKey(t *testing.T, path string, passphrase []byte, mode os.FileMode) {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var block *pem.Block
+	if passphrase != nil {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, "", passphrase)
+	} else {
+		block, err = ssh.MarshalPrivateKey(private, "")
+	}
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), mode))
+}
+
+func TestCheckSettingsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+
+	check, settings := checkSettingsFile(path)
+	assert.Equal(t, sshclient.DiagnoseOK, check.Status)
+	assert.Nil(t, settings)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"hosts": [`), 0o600))
+	check, _ = checkSettingsFile(path)
+	assert.Equal(t, sshclient.DiagnoseFailed, check.Status)
+	assert.Contains(t, check.Detail, "not valid JSON")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"key": "~/.ssh/work", "hosts": [
+		{"name": "web1", "host": "10.0.0.1"},
+		{"name": "web1", "host": "10.0.0.2"},
+		{"name": "db1"}
+	]}`), 0o600))
+	check, settings = checkSettingsFile(path)
+	assert.Equal(t, sshclient.DiagnoseWarning, check.Status)
+	assert.Contains(t, check.Detail, `host "web1" is defined more than once`)
+	assert.Contains(t, check.Detail, "host #3")
+	require.NotNil(t, settings)
+	assert.Equal(t, "~/.ssh/work", settings.Key)
+}
+
+func TestCheckDefaultKey(t *testing.T) {
+	dir := t.TempDir()
+
+	check := checkDefaultKey(filepath.Join(dir, "missing"))
+	assert.Equal(t, sshclient.DiagnoseWarning, check.Status)
+
+	good := filepath.Join(dir, "id_ed25519")
+	writeDoctorKey(t, good, nil, 0o600)
+	check = checkDefaultKey(good)
+	assert.Equal(t, sshclient.DiagnoseOK, check.Status, check.Detail)
+
+	encrypted := filepath.Join(dir, "id_encrypted")
+	writeDoctorKey(t, encrypted, []byte("secret"), 0o600)
+	check = checkDefaultKey(encrypted)
+	assert.Equal(t, sshclient.DiagnoseWarning, check.Status)
+	assert.Contains(t, check.Detail, "passphrase")
+
+	garbage := filepath.Join(dir, "id_garbage")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a key"), 0o600))
+	check = checkDefaultKey(garbage)
+	assert.Equal(t, sshclient.DiagnoseFailed, check.Status)
+
+	open := filepath.Join(dir, "id_open")
+	writeDoctorKey(t, open, nil, 0o600)
+	require.NoError(t, os.Chmod(open, 0o644))
+	check = checkDefaultKey(open)
+	assert.Equal(t, sshclient.DiagnoseWarning, check.Status)
+	assert.Equal(t, "chmod 600 "+open, check.Fix)
+}
+
+func TestCheckKnownHostsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "known_hosts")
+
+	check := checkKnownHostsFile(path)
+	assert.Equal(t, sshclient.DiagnoseWarning, check.Status)
+
+	require.NoError(t, os.WriteFile(path, []byte("web1 ssh-ed25519 not-base64!\n"), 0o600))
+	check = checkKnownHostsFile(path)
+	assert.Equal(t, sshclient.DiagnoseFailed, check.Status)
+
+	require.NoError(t, os.WriteFile(path, []byte("# empty\n"), 0o600))
+	check = checkKnownHostsFile(path)
+	assert.Equal(t, sshclient.DiagnoseOK, check.Status, check.Detail)
+}
+
+func TestCheckKeyring(t *testing.T) {
+	keyring.MockInit()
+	check := checkKeyring()
+	assert.Equal(t, sshclient.DiagnoseOK, check.Status)
+}
+
+func TestCheckSSHAgent(t *testing.T) {
+	check := checkSSHAgent("")
+	assert.Equal(t, sshclient.DiagnoseWarning, check.Status)
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	check = checkSSHAgent(socket)
+	assert.Equal(t, sshclient.DiagnoseWarning, check.Status)
+
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }() //nolint:errcheck // test cleanup
+	go func() {
+		conn, acceptErr := listener.Accept()
+		if acceptErr != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()            //nolint:errcheck // test cleanup
+		_ = agent.ServeAgent(agent.NewKeyring(), conn) //nolint:errcheck // ends when the client closes
+	}()
+
+	check = checkSSHAgent(socket)
+	assert.Equal(t, sshclient.DiagnoseOK, check.Status, check.Detail)
+	assert.Equal(t, "0 key(s) loaded", check.Detail)
+}
+
+func TestCheckLogDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".sshmcp")
+	check := checkLogDir(dir)
+	assert.Equal(t, sshclient.DiagnoseOK, check.Status, check.Detail)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "probe file was not removed")
+}
+
+func TestFormatDoctorReport(t *testing.T) {
+	report := formatDoctorReport([]DoctorCheck{
+		{Name: "keyring", Status: sshclient.DiagnoseOK, Detail: "available"},
+		{Name: "known_hosts", Status: sshclient.DiagnoseWarning, Detail: "missing", Fix: "run ssh-keyscan"},
+	})
+	assert.Contains(t, report, "✓ keyring: available")
+	assert.Contains(t, report, "⚠️ known_hosts: missing\n      fix: run ssh-keyscan\n")
+	assert.Contains(t, report, "Result: 1 problem(s) found")
+}
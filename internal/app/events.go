@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// DefaultEventsSubject is the NATS subject prefix events are published
+// under, followed by the event type (sshx.events.finished)
+const DefaultEventsSubject = "sshx.events"
+
+// EventsConfig sends the execution events of the MCP server and the daemon
+// to a webhook and/or a NATS server
+type EventsConfig struct {
+	URL     string            `json:"url,omitempty"`     // Webhook receiving each event as a JSON POST
+	Headers map[string]string `json:"headers,omitempty"` // Extra HTTP headers of the webhook (e.g. Authorization)
+	NATS    string            `json:"nats,omitempty"`    // NATS server, e.g. nats://token@127.0.0.1:4222
+	Subject string            `json:"subject,omitempty"` // NATS subject prefix (default: sshx.events)
+	Output  bool              `json:"output,omitempty"`  // Also send output events (every chunk of command output)
+}
+
+// startEventSinks delivers execution events to the sinks configured in
+// settings until the returned function is called. Delivery runs in the
+// background; failures are logged and events published meanwhile may be
+// dropped.
+func startEventSinks(settings *Settings) func() {
+	if settings == nil || settings.Events == nil || (settings.Events.URL == "" && settings.Events.NATS == "") {
+		return func() {}
+	}
+	config := *settings.Events
+
+	var publisher *natsPublisher
+	if config.NATS != "" {
+		var err error
+		if publisher, err = newNATSPublisher(config.NATS); err != nil {
+			logger.GetLogger().Warning("events: %v", err)
+			if config.URL == "" {
+				return func() {}
+			}
+		}
+	}
+	subject := config.Subject
+	if subject == "" {
+		subject = DefaultEventsSubject
+	}
+
+	events, unsubscribe := sshclient.SubscribeEvents()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for event := range events {
+			if event.Type == sshclient.EventOutput && !config.Output {
+				continue
+			}
+			if err := deliverEvent(&config, publisher, subject, event); err != nil {
+				logger.GetLogger().Debug("events: %v", err)
+			}
+		}
+	}()
+
+	return func() {
+		unsubscribe()
+		wg.Wait()
+		if publisher != nil {
+			publisher.close()
+		}
+	}
+}
+
+// deliverEvent sends one event to the webhook and the NATS server
+func deliverEvent(config *EventsConfig, publisher *natsPublisher, subject string, event sshclient.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if config.URL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+		errs = append(errs, postHook(ctx, config.URL, config.Headers, payload))
+		cancel()
+	}
+	if publisher != nil {
+		errs = append(errs, publisher.publish(subject+"."+event.Type, payload))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// natsDefaultPort is the client port of a NATS server
+	natsDefaultPort = "4222"
+	// natsTimeout bounds connecting and the handshake
+	natsTimeout = 5 * time.Second
+)
+
+// natsPublisher publishes messages to a NATS server with the plain text
+// client protocol (INFO, CONNECT, PUB, PING/PONG). It connects on first use
+// and reconnects after a failure. TLS is not supported.
+type natsPublisher struct {
+	address string
+	connect map[string]interface{}
+
+	mu   sync.Mutex
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// newNATSPublisher parses a nats://[user:password@|token@]host[:port] URL
+func newNATSPublisher(raw string) (*natsPublisher, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "nats://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL: %w", err)
+	}
+	if u.Scheme != "nats" {
+		return nil, fmt.Errorf("unsupported NATS URL scheme '%s' (only nats:// is supported)", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("NATS URL '%s' has no host", raw)
+	}
+	port := u.Port()
+	if port == "" {
+		port = natsDefaultPort
+	}
+
+	connect := map[string]interface{}{"verbose": false, "pedantic": false, "name": "sshx", "lang": "go"}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			connect["user"] = u.User.Username()
+			connect["pass"] = password
+		} else {
+			connect["auth_token"] = u.User.Username()
+		}
+	}
+	return &natsPublisher{address: net.JoinHostPort(u.Hostname(), port), connect: connect}, nil
+}
+
+// publish sends payload on subject, connecting first when needed; after a
+// failed write it reconnects once
+func (p *natsPublisher) publish(subject string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	for range 2 {
+		if p.conn == nil {
+			if err = p.dial(); err != nil {
+				return err
+			}
+		}
+		if err = p.write(subject, payload); err == nil {
+			return nil
+		}
+		p.reset()
+	}
+	return fmt.Errorf("failed to publish to NATS %s: %w", p.address, err)
+}
+
+func (p *natsPublisher) write(subject string, payload []byte) error {
+	if err := p.conn.SetWriteDeadline(time.Now().Add(natsTimeout)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(p.w, "PUB %s %d\r\n", subject, len(payload)); err != nil {
+		return err
+	}
+	if _, err := p.w.Write(payload); err != nil {
+		return err
+	}
+	if _, err := p.w.WriteString("\r\n"); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+// dial connects and completes the handshake: the server's INFO, our CONNECT
+// and a PING answered by PONG (or -ERR when authentication fails)
+func (p *natsPublisher) dial() error {
+	conn, err := net.DialTimeout("tcp", p.address, natsTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS %s: %w", p.address, err)
+	}
+	if err = conn.SetDeadline(time.Now().Add(natsTimeout)); err != nil {
+		_ = conn.Close() //nolint:errcheck // already failing
+		return err
+	}
+	r := bufio.NewReader(conn)
+	if err = natsHandshake(conn, r, p.connect); err != nil {
+		_ = conn.Close() //nolint:errcheck // already failing
+		return fmt.Errorf("NATS %s: %w", p.address, err)
+	}
+	if err = conn.SetDeadline(time.Time{}); err != nil {
+		_ = conn.Close() //nolint:errcheck // already failing
+		return err
+	}
+
+	p.conn = conn
+	p.w = bufio.NewWriter(conn)
+	go p.answerPings(conn, r)
+	return nil
+}
+
+func natsHandshake(conn net.Conn, r *bufio.Reader, connect map[string]interface{}) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("no INFO from server: %w", err)
+	}
+	info, ok := strings.CutPrefix(strings.TrimSpace(line), "INFO ")
+	if !ok {
+		return fmt.Errorf("unexpected greeting: %s", strings.TrimSpace(line))
+	}
+	var server struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	if err = json.Unmarshal([]byte(info), &server); err == nil && server.TLSRequired {
+		return errors.New("the server requires TLS, which is not supported")
+	}
+
+	options, err := json.Marshal(connect)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", options); err != nil {
+		return err
+	}
+	for {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+		}
+	}
+}
+
+// answerPings replies to the server's keepalive PINGs until the connection
+// closes, then drops it so the next publish reconnects
+func (p *natsPublisher) answerPings(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if strings.TrimSpace(line) == "PING" {
+			p.mu.Lock()
+			if p.conn == conn {
+				_, _ = p.w.WriteString("PONG\r\n") //nolint:errcheck // a failed write drops the connection
+				_ = p.w.Flush()                    //nolint:errcheck // a failed write drops the connection
+			}
+			p.mu.Unlock()
+		}
+	}
+	p.mu.Lock()
+	if p.conn == conn {
+		p.reset()
+	}
+	p.mu.Unlock()
+}
+
+// reset closes the connection; callers hold mu
+func (p *natsPublisher) reset() {
+	if p.conn != nil {
+		_ = p.conn.Close() //nolint:errcheck // dropping the connection
+	}
+	p.conn = nil
+	p.w = nil
+}
+
+func (p *natsPublisher) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reset()
+}
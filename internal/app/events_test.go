@@ -0,0 +1,138 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+// natsMessage is a PUB received by fakeNATSServer
+type natsMessage struct {
+	Subject string
+	Payload string
+}
+
+// fakeNATSServer accepts one client, answers the handshake and reports the
+// CONNECT options and every published message
+func fakeNATSServer(t *testing.T) (string, <-chan string, <-chan natsMessage) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	connects := make(chan string, 1)
+	messages := make(chan natsMessage, 16)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = io.WriteString(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 0:
+			case fields[0] == "CONNECT":
+				connects <- strings.TrimSpace(strings.TrimPrefix(line, "CONNECT"))
+			case fields[0] == "PING":
+				_, _ = io.WriteString(conn, "PONG\r\n")
+			case fields[0] == "PUB" && len(fields) == 3:
+				size, _ := strconv.Atoi(fields[2])
+				payload := make([]byte, size+2)
+				if _, err := io.ReadFull(r, payload); err != nil {
+					return
+				}
+				messages <- natsMessage{Subject: fields[1], Payload: string(payload[:size])}
+			}
+		}
+	}()
+	return listener.Addr().String(), connects, messages
+}
+
+func TestNewNATSPublisher(t *testing.T) {
+	publisher, err := newNATSPublisher("nats://secret@nats.internal")
+	require.NoError(t, err)
+	assert.Equal(t, "nats.internal:4222", publisher.address)
+	assert.Equal(t, "secret", publisher.connect["auth_token"])
+
+	publisher, err = newNATSPublisher("ops:pw@10.0.0.9:4333")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.9:4333", publisher.address)
+	assert.Equal(t, "ops", publisher.connect["user"])
+	assert.Equal(t, "pw", publisher.connect["pass"])
+
+	_, err = newNATSPublisher("tls://nats.internal:4222")
+	assert.ErrorContains(t, err, "only nats://")
+}
+
+func TestNATSPublisher_Publish(t *testing.T) {
+	address, connects, messages := fakeNATSServer(t)
+	publisher, err := newNATSPublisher("nats://token@" + address)
+	require.NoError(t, err)
+	defer publisher.close()
+
+	require.NoError(t, publisher.publish("sshx.events.finished", []byte(`{"type":"finished"}`)))
+	require.NoError(t, publisher.publish("sshx.events.started", []byte(`{}`)))
+
+	var options map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(<-connects), &options))
+	assert.Equal(t, "token", options["auth_token"])
+	assert.Equal(t, natsMessage{Subject: "sshx.events.finished", Payload: `{"type":"finished"}`}, <-messages)
+	assert.Equal(t, natsMessage{Subject: "sshx.events.started", Payload: `{}`}, <-messages)
+}
+
+func TestStartEventSinks(t *testing.T) {
+	received := make(chan sshclient.Event, 8)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event sshclient.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err == nil {
+			received <- event
+		}
+	}))
+	defer webhook.Close()
+	address, _, messages := fakeNATSServer(t)
+
+	stop := startEventSinks(&Settings{Events: &EventsConfig{URL: webhook.URL, NATS: address, Subject: "ops"}})
+	run := sshclient.Chain(func(config *sshclient.Config) (string, error) {
+		_, err := config.OutputTap.Write([]byte("output is not sent by default"))
+		return "", err
+	}, sshclient.EventsMiddleware)
+	_, err := run(&sshclient.Config{Host: "10.0.0.5", Command: "uptime"})
+	require.NoError(t, err)
+
+	for _, want := range []string{sshclient.EventStarted, sshclient.EventFinished} {
+		select {
+		case event := <-received:
+			assert.Equal(t, want, event.Type)
+			assert.Equal(t, "uptime", event.Command)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("webhook did not receive the %s event", want)
+		}
+		message := <-messages
+		assert.Equal(t, "ops."+want, message.Subject)
+	}
+	stop()
+}
+
+func TestStartEventSinks_NotConfigured(t *testing.T) {
+	startEventSinks(nil)()
+	startEventSinks(&Settings{Events: &EventsConfig{Output: true}})()
+}
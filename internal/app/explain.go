@@ -0,0 +1,69 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// handleExplain prints what the safety validator makes of the command, under
+// the safety policy of the target host when one is given, without connecting
+func handleExplain(config *sshclient.Config) error {
+	if strings.TrimSpace(config.Command) == "" {
+		return fmt.Errorf("--explain needs a command, e.g. sshx --explain \"rm -rf /tmp/build\"")
+	}
+	// Without -h no host was resolved, so the global policy applies
+	policy := config.Safety
+	if policy == nil && config.Host == "" {
+		settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+		policy = hostSafetyConfig(&HostConfig{}, settings).toSafetyPolicy()
+	}
+	explanation := sshclient.Explain(config.Command, policy)
+
+	if config.JSONOutput {
+		data, err := json.MarshalIndent(explanation, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode explanation: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Print(logger.Plain(formatExplanation(explanation)))
+	return nil
+}
+
+// formatExplanation renders an explanation as text
+func formatExplanation(explanation sshclient.Explanation) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Command:    %s\n", explanation.Command))
+	if !explanation.Flagged {
+		output.WriteString("Result:     ✓ allowed, no dangerous pattern matched\n")
+		return output.String()
+	}
+
+	switch {
+	case explanation.Blocked && explanation.Severity != sshclient.SeverityBlock:
+		output.WriteString("Result:     ❌ blocked outside the maintenance windows (use --force to run it anyway)\n")
+	case explanation.Blocked:
+		output.WriteString("Result:     ❌ blocked (use --force to run it anyway)\n")
+	case explanation.Severity == sshclient.SeverityWarn:
+		output.WriteString("Result:     ⚠️  allowed with a warning by the safety policy\n")
+	default:
+		output.WriteString("Result:     ✓ allowed by the safety policy, recorded in the audit log\n")
+	}
+	output.WriteString(fmt.Sprintf("Rule:       %s (%s)\n", explanation.Rule, explanation.Severity))
+	output.WriteString(fmt.Sprintf("Reason:     %s\n", explanation.Reason))
+	if explanation.Pattern != "" {
+		output.WriteString(fmt.Sprintf("Matched:    %s\n", explanation.Pattern))
+	}
+	if explanation.Suggestion != "" {
+		output.WriteString(fmt.Sprintf("Suggestion: %s\n", explanation.Suggestion))
+	}
+	if explanation.NextWindow != nil {
+		output.WriteString(fmt.Sprintf("Window:     the next one opens %s\n", explanation.NextWindow.Format("Mon 2006-01-02 15:04 MST")))
+	}
+	return output.String()
+}
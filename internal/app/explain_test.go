@@ -0,0 +1,37 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestFormatExplanation(t *testing.T) {
+	allowed := formatExplanation(sshclient.Explain("ls -la", nil))
+	assert.Contains(t, allowed, "allowed, no dangerous pattern matched")
+	assert.NotContains(t, allowed, "Rule:")
+
+	blocked := formatExplanation(sshclient.Explain(`cd /tmp && rm -rf "/"`, nil))
+	assert.Contains(t, blocked, "blocked")
+	assert.Contains(t, blocked, "Rule:       delete-root (block)")
+	assert.Contains(t, blocked, "Matched:    rm -rf /")
+	assert.Contains(t, blocked, "Suggestion: ")
+
+	policy := &sshclient.SafetyPolicy{Rules: map[string]sshclient.Severity{"reboot": sshclient.SeverityWarn}}
+	warned := formatExplanation(sshclient.Explain("sudo reboot", policy))
+	assert.Contains(t, warned, "allowed with a warning")
+	assert.Contains(t, warned, "Rule:       reboot (warn)")
+
+	// A window that opens in two hours
+	opens := time.Now().UTC().Add(2 * time.Hour)
+	window, err := sshclient.ParseMaintenanceWindow(nil, opens.Format("15:04"), opens.Add(time.Hour).Format("15:04"), "UTC")
+	require.NoError(t, err)
+	policy.Windows = []sshclient.MaintenanceWindow{window}
+	closed := formatExplanation(sshclient.Explain("sudo reboot", policy))
+	assert.Contains(t, closed, "blocked outside the maintenance windows")
+	assert.Contains(t, closed, "Rule:       reboot (warn)")
+	assert.Contains(t, closed, "Window:     the next one opens ")
+}
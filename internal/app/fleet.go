@@ -0,0 +1,440 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// DefaultCollectDir is where --collect stores files when --into is omitted
+const DefaultCollectDir = "collected"
+
+// HandleFleetTransfer handles file transfers that target several hosts
+// (--hosts=<names|groups> combined with --upload or --collect)
+func HandleFleetTransfer(config *sshclient.Config) error {
+	switch config.SftpAction {
+	case "upload":
+		return handleDistribute(config)
+	case "collect":
+		return handleCollect(config)
+	default:
+		return fmt.Errorf("--hosts is not supported for SFTP action '%s'", config.SftpAction)
+	}
+}
+
+// handleDistribute pushes one local file to every selected host
+func handleDistribute(config *sshclient.Config) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	if config.DeltaUpload {
+		return fmt.Errorf("--delta works with single-host --upload, not --hosts")
+	}
+
+	config.Source = "cli"
+	opts := sshclient.DistributeOptions{
+		LocalPath:  config.LocalPath,
+		RemotePath: config.RemotePath,
+		Validate:   config.Validate,
+		Rollback:   config.Rollback,
+		// A manifest vouches for the files, so they are read back first
+		Verify: config.ManifestPath != "" || config.RemoteManifest != "",
+	}
+	if config.UploadTemplate {
+		opts.Render = templateRenderer(settings, config.LocalPath)
+	}
+
+	hosts, results, status, err := distributeFile(settings, config.Hosts, config, opts, config.Rollout)
+	if err != nil {
+		return err
+	}
+
+	report := formatDistributeResults(hosts, results) + formatRolloutResult(hosts, status)
+	if opts.Verify {
+		manifest := distributeManifest(hosts, results, status, opts)
+		if report, err = keepManifest(settings, manifest, config, config.ManifestPath, config.RemoteManifest, false, report); err != nil {
+			return err
+		}
+	}
+	fmt.Print(logger.Plain(report))
+
+	if status != nil && status.Halted {
+		return fmt.Errorf("rollout halted: %s", status.Reason)
+	}
+	if failed := countDistributeFailures(results); failed > 0 {
+		return fmt.Errorf("distribution failed on %d host(s)", failed)
+	}
+	return nil
+}
+
+// distributeFile resolves a host/group spec and uploads the file to each
+// host, in the stages of rollout when it sets any
+func distributeFile(settings *Settings, spec string, baseConfig *sshclient.Config, opts sshclient.DistributeOptions, rollout sshclient.RolloutOptions) ([]HostConfig, []sshclient.DistributeResult, *sshclient.RolloutResult, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil, nil, fmt.Errorf("hosts are required (comma-separated host names or groups)")
+	}
+	if opts.LocalPath == "" || opts.RemotePath == "" {
+		return nil, nil, nil, fmt.Errorf("local and remote paths are required (--upload=<file> --to=<remote path>)")
+	}
+	if err := rollout.Validate(); err != nil {
+		return nil, nil, nil, err
+	}
+	if rollout.Expect != "" && opts.Validate == "" {
+		return nil, nil, nil, fmt.Errorf("expect matches the output of the validation command, which is missing (--validate=<cmd>)")
+	}
+
+	hosts, err := resolveBatchHosts(settings, spec, baseConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	configs := make([]*sshclient.Config, len(hosts))
+	for i := range hosts {
+		configs[i] = newHostSSHConfig(&hosts[i], settings, baseConfig)
+	}
+
+	var results []sshclient.DistributeResult
+	var status *sshclient.RolloutResult
+	if rollout.Enabled() {
+		results, status = sshclient.RolloutDistribute(configs, opts, rollout)
+	} else {
+		results = sshclient.Distribute(configs, opts)
+	}
+	recordReachable(hosts, func(i int) bool { return results[i].Err == nil })
+	return hosts, results, status, nil
+}
+
+// formatDistributeResults renders distribution results as a report
+func formatDistributeResults(hosts []HostConfig, results []sshclient.DistributeResult) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("File distribution (%d hosts):\n", len(results)))
+
+	for i, result := range results {
+		name := result.Key
+		if i < len(hosts) {
+			name = hosts[i].Name
+		}
+
+		if result.Err != nil {
+			status := "not rolled back"
+			if result.RolledBack {
+				status = "rolled back"
+			}
+			output.WriteString(fmt.Sprintf("  ❌ %s (%s) failed, %s: %v\n", name, result.Key, status, result.Err))
+			continue
+		}
+
+		line := fmt.Sprintf("  ✓ %s (%s) %d bytes in %s", name, result.Key, result.Bytes, result.Duration.Round(time.Millisecond))
+		if result.Verified {
+			line += ", verified"
+		}
+		if result.Validated {
+			line += ", validated"
+		}
+		output.WriteString(line + "\n")
+	}
+
+	succeeded := len(results) - countDistributeFailures(results)
+	output.WriteString(fmt.Sprintf("Summary: %d/%d hosts updated\n", succeeded, len(results)))
+	return output.String()
+}
+
+func countDistributeFailures(results []sshclient.DistributeResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// handleCollect gathers a file or command output from every selected host
+func handleCollect(config *sshclient.Config) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	config.Source = "cli"
+	opts := sshclient.CollectOptions{
+		RemotePath: config.RemotePath,
+		Command:    config.CollectCommand,
+		LocalDir:   config.LocalPath,
+		MaxBytes:   config.MaxBytes,
+		Rollback:   config.Rollback,
+	}
+	if opts.Command == "" && opts.LocalDir == "" {
+		opts.LocalDir = DefaultCollectDir
+	}
+	if config.RemoteManifest != "" {
+		return fmt.Errorf("--remote-manifest only works with --upload")
+	}
+	if config.ManifestPath != "" && opts.Command != "" {
+		return fmt.Errorf("--manifest lists collected files, not --collect-cmd output")
+	}
+
+	hosts, results, status, err := collectFromHosts(settings, config.Hosts, config, opts, config.Rollout)
+	if err != nil {
+		return err
+	}
+
+	if opts.Command != "" {
+		aggregated := formatCollectedOutput(hosts, results)
+		if config.AggregateOutput {
+			aggregated = formatAggregatedOutput(groupCollectedOutput(hosts, results))
+		}
+		if config.LocalPath == "" {
+			fmt.Print(aggregated)
+		} else if err := os.WriteFile(config.LocalPath, []byte(aggregated), 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", config.LocalPath, err)
+		}
+	}
+	report := formatCollectResults(hosts, results) + formatRolloutResult(hosts, status)
+	if config.ManifestPath != "" {
+		if report, err = keepManifest(settings, collectManifest(hosts, results, status, opts), config, config.ManifestPath, "", false, report); err != nil {
+			return err
+		}
+	}
+	fmt.Print(logger.Plain(report))
+
+	if status != nil && status.Halted {
+		return fmt.Errorf("rollout halted: %s", status.Reason)
+	}
+	if failed := countCollectFailures(results); failed > 0 {
+		return fmt.Errorf("collection failed on %d host(s)", failed)
+	}
+	return nil
+}
+
+// collectFromHosts resolves a host/group spec and collects from each host,
+// in the stages of rollout when it sets any
+func collectFromHosts(settings *Settings, spec string, baseConfig *sshclient.Config, opts sshclient.CollectOptions, rollout sshclient.RolloutOptions) ([]HostConfig, []sshclient.CollectResult, *sshclient.RolloutResult, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil, nil, fmt.Errorf("hosts are required (comma-separated host names or groups)")
+	}
+	if opts.RemotePath == "" && opts.Command == "" {
+		return nil, nil, nil, fmt.Errorf("a remote path or command is required (--collect=<path> or --collect-cmd=<command>)")
+	}
+	if opts.RemotePath != "" && opts.Command != "" {
+		return nil, nil, nil, fmt.Errorf("--collect and --collect-cmd cannot be combined")
+	}
+	if opts.Command == "" && opts.LocalDir == "" {
+		return nil, nil, nil, fmt.Errorf("a local directory is required (--into=<dir>)")
+	}
+	if err := rollout.Validate(); err != nil {
+		return nil, nil, nil, err
+	}
+	if rollout.Enabled() && opts.Command == "" {
+		return nil, nil, nil, fmt.Errorf("rollouts stage commands and uploads, not file collection")
+	}
+	if opts.Rollback != "" && opts.Command == "" {
+		return nil, nil, nil, fmt.Errorf("a rollback undoes commands and uploads, not file collection")
+	}
+
+	hosts, err := resolveBatchHosts(settings, spec, baseConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	configs := make([]*sshclient.Config, len(hosts))
+	for i := range hosts {
+		configs[i] = newHostSSHConfig(&hosts[i], settings, baseConfig)
+	}
+
+	var results []sshclient.CollectResult
+	var status *sshclient.RolloutResult
+	if rollout.Enabled() {
+		results, status = sshclient.RolloutCollect(configs, opts, rollout)
+	} else {
+		results = sshclient.Collect(configs, opts)
+	}
+	recordReachable(hosts, func(i int) bool { return results[i].Err == nil })
+	return hosts, results, status, nil
+}
+
+// formatRolloutResult renders the stages of a rollout (nothing without one)
+func formatRolloutResult(hosts []HostConfig, status *sshclient.RolloutResult) string {
+	if status == nil {
+		return ""
+	}
+	names := func(indexes []int) string {
+		list := make([]string, len(indexes))
+		for j, i := range indexes {
+			list[j] = hosts[i].Name
+		}
+		return strings.Join(list, ", ")
+	}
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Rollout (%d stages):\n", len(status.Stages)))
+	for _, stage := range status.Stages {
+		if len(stage.Failed) > 0 {
+			output.WriteString(fmt.Sprintf("  ❌ %s: %s (failed: %s)\n", stage.Name, names(stage.Hosts), names(stage.Failed)))
+			continue
+		}
+		output.WriteString(fmt.Sprintf("  ✓ %s: %s\n", stage.Name, names(stage.Hosts)))
+	}
+	if status.Halted {
+		output.WriteString(fmt.Sprintf("Halted: %s; %d host(s) skipped", status.Reason, len(status.Skipped)))
+		if len(status.Skipped) > 0 {
+			output.WriteString(": " + names(status.Skipped))
+		}
+		output.WriteString("\n")
+	}
+	return output.String()
+}
+
+// formatCollectedOutput aggregates command output into one document with a
+// header per host
+func formatCollectedOutput(hosts []HostConfig, results []sshclient.CollectResult) string {
+	var output strings.Builder
+	for i, result := range results {
+		name := result.Key
+		if i < len(hosts) {
+			name = hosts[i].Name
+		}
+
+		output.WriteString(fmt.Sprintf("==> %s (%s) <==\n", name, result.Key))
+		output.WriteString(result.Output)
+		if result.Output != "" && !strings.HasSuffix(result.Output, "\n") {
+			output.WriteString("\n")
+		}
+		if result.Err != nil {
+			output.WriteString(fmt.Sprintf("[error: %v]\n", result.Err))
+		}
+		output.WriteString("\n")
+	}
+	return output.String()
+}
+
+// outputGroup is a set of hosts whose command output was identical
+type outputGroup struct {
+	Hosts []string
+	// Text is the output, followed by the error if the command failed
+	Text string
+	// Diff compares the output of the largest group with Text, for the
+	// other groups
+	Diff []string
+}
+
+// collectedText is what groupCollectedOutput compares: the output followed
+// by the error, if any, without trailing line breaks
+func collectedText(result sshclient.CollectResult) string {
+	text := strings.TrimRight(result.Output, "\n")
+	if result.Err != nil {
+		if text != "" {
+			text += "\n"
+		}
+		text += "error: " + result.Err.Error()
+	}
+	return text
+}
+
+// groupCollectedOutput groups the hosts by identical output, the largest
+// group first (ties in host order), and diffs each other group against it
+func groupCollectedOutput(hosts []HostConfig, results []sshclient.CollectResult) []outputGroup {
+	var groups []outputGroup
+	index := make(map[string]int)
+	for i, result := range results {
+		name := result.Key
+		if i < len(hosts) {
+			name = hosts[i].Name
+		}
+		text := collectedText(result)
+		if g, ok := index[text]; ok {
+			groups[g].Hosts = append(groups[g].Hosts, name)
+			continue
+		}
+		index[text] = len(groups)
+		groups = append(groups, outputGroup{Hosts: []string{name}, Text: text})
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].Hosts) > len(groups[j].Hosts) })
+	for i := 1; i < len(groups); i++ {
+		groups[i].Diff = sshclient.DiffLines(groups[0].Text, groups[i].Text)
+	}
+	return groups
+}
+
+// formatAggregatedOutput renders output groups: the output of the largest
+// group in full, then the diff of each outlier group against it
+func formatAggregatedOutput(groups []outputGroup) string {
+	var output strings.Builder
+	for i, group := range groups {
+		hosts := fmt.Sprintf("%d host(s)", len(group.Hosts))
+		if i == 0 {
+			if len(groups) == 1 {
+				hosts = fmt.Sprintf("all %d host(s)", len(group.Hosts))
+			}
+			output.WriteString(fmt.Sprintf("==> %s returned this: %s <==\n", hosts, strings.Join(group.Hosts, ", ")))
+			if group.Text != "" {
+				output.WriteString(group.Text + "\n")
+			}
+		} else {
+			output.WriteString(fmt.Sprintf("==> %s differ from the %d above: %s <==\n", hosts, len(groups[0].Hosts), strings.Join(group.Hosts, ", ")))
+			for _, line := range group.Diff {
+				output.WriteString(line + "\n")
+			}
+		}
+		output.WriteString("\n")
+	}
+	summary := make([]string, len(groups))
+	for i, group := range groups {
+		summary[i] = strconv.Itoa(len(group.Hosts))
+	}
+	output.WriteString(fmt.Sprintf("Groups: %d distinct output(s) (%s hosts)\n", len(groups), strings.Join(summary, " + ")))
+	return output.String()
+}
+
+// formatCollectResults renders collection results as a report
+func formatCollectResults(hosts []HostConfig, results []sshclient.CollectResult) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Collection (%d hosts):\n", len(results)))
+
+	for i, result := range results {
+		name := result.Key
+		if i < len(hosts) {
+			name = hosts[i].Name
+		}
+
+		if result.Err != nil {
+			status := "failed"
+			if result.RolledBack {
+				status = "failed, rolled back"
+			}
+			output.WriteString(fmt.Sprintf("  ❌ %s (%s) %s: %v\n", name, result.Key, status, result.Err))
+			continue
+		}
+
+		line := fmt.Sprintf("  ✓ %s (%s) %d bytes in %s", name, result.Key, result.Bytes, result.Duration.Round(time.Millisecond))
+		if result.LocalPath != "" {
+			line += " → " + filepath.ToSlash(result.LocalPath)
+		}
+		if result.Truncated {
+			line += ", truncated"
+		}
+		output.WriteString(line + "\n")
+	}
+
+	succeeded := len(results) - countCollectFailures(results)
+	output.WriteString(fmt.Sprintf("Summary: %d/%d hosts collected\n", succeeded, len(results)))
+	return output.String()
+}
+
+func countCollectFailures(results []sshclient.CollectResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
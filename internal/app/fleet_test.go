@@ -0,0 +1,231 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestFormatDistributeResults(t *testing.T) {
+	hosts := []HostConfig{{Name: "web1"}, {Name: "web2"}, {Name: "web3"}}
+	results := []sshclient.DistributeResult{
+		{Key: "root@10.0.0.1:22", Bytes: 42, Validated: true, Duration: 120 * time.Millisecond},
+		{Key: "root@10.0.0.2:22", Err: errors.New("validation failed: exit status 1"), RolledBack: true},
+		{Key: "root@10.0.0.3:22", Err: errors.New("failed to connect")},
+	}
+
+	report := formatDistributeResults(hosts, results)
+
+	assert.Contains(t, report, "File distribution (3 hosts):")
+	assert.Contains(t, report, "✓ web1 (root@10.0.0.1:22) 42 bytes in 120ms, validated")
+	assert.Contains(t, report, "❌ web2 (root@10.0.0.2:22) failed, rolled back: validation failed")
+	assert.Contains(t, report, "❌ web3 (root@10.0.0.3:22) failed, not rolled back: failed to connect")
+	assert.Contains(t, report, "Summary: 1/3 hosts updated")
+}
+
+func TestDistributeFile_Validation(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{{Name: "web1", Host: "10.0.0.1"}}}
+
+	_, _, _, err := distributeFile(settings, "", nil, sshclient.DistributeOptions{LocalPath: "a", RemotePath: "b"}, sshclient.RolloutOptions{})
+	assert.ErrorContains(t, err, "hosts are required")
+
+	_, _, _, err = distributeFile(settings, "web1", nil, sshclient.DistributeOptions{LocalPath: "a"}, sshclient.RolloutOptions{})
+	assert.ErrorContains(t, err, "local and remote paths are required")
+
+	_, _, _, err = distributeFile(settings, "missing", nil, sshclient.DistributeOptions{LocalPath: "a", RemotePath: "b"}, sshclient.RolloutOptions{})
+	assert.ErrorContains(t, err, "no host or group named 'missing'")
+}
+
+func TestParseArgs_Distribute(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--hosts=web1,web2", "--upload=app.conf", "--to=/etc/app/app.conf", "--validate=sudo nginx -t"})
+
+	assert.Equal(t, "sftp", config.Mode)
+	assert.Equal(t, "upload", config.SftpAction)
+	assert.Equal(t, "web1,web2", config.Hosts)
+	assert.Equal(t, "app.conf", config.LocalPath)
+	assert.Equal(t, "/etc/app/app.conf", config.RemotePath)
+	assert.Equal(t, "sudo nginx -t", config.Validate)
+}
+
+func TestHandleFleetTransfer_UnsupportedAction(t *testing.T) {
+	err := HandleFleetTransfer(&sshclient.Config{SftpAction: "mkdir", Hosts: "web1"})
+	assert.ErrorContains(t, err, "not supported")
+}
+
+func TestFormatCollectedOutput(t *testing.T) {
+	hosts := []HostConfig{{Name: "web1"}, {Name: "web2"}}
+	results := []sshclient.CollectResult{
+		{Key: "root@10.0.0.1:22", Output: "error A", Bytes: 7},
+		{Key: "root@10.0.0.2:22", Output: "partial", Bytes: 7, Err: errors.New("exit status 1")},
+	}
+
+	output := formatCollectedOutput(hosts, results)
+
+	assert.Contains(t, output, "==> web1 (root@10.0.0.1:22) <==\nerror A\n")
+	assert.Contains(t, output, "==> web2 (root@10.0.0.2:22) <==\npartial\n[error: exit status 1]\n")
+}
+
+func TestGroupCollectedOutput(t *testing.T) {
+	hosts := []HostConfig{{Name: "web1"}, {Name: "web2"}, {Name: "web3"}, {Name: "web4"}}
+	results := []sshclient.CollectResult{
+		{Key: "root@10.0.0.1:22", Output: "nginx 1.24\nssl on\n"},
+		{Key: "root@10.0.0.2:22", Output: "nginx 1.22\nssl on\n"},
+		{Key: "root@10.0.0.3:22", Output: "nginx 1.24\nssl on"},
+		{Key: "root@10.0.0.4:22", Err: errors.New("failed to connect")},
+	}
+
+	groups := groupCollectedOutput(hosts, results)
+	require.Len(t, groups, 3)
+	assert.Equal(t, []string{"web1", "web3"}, groups[0].Hosts, "trailing line breaks do not count")
+	assert.Nil(t, groups[0].Diff)
+	assert.Equal(t, []string{"web2"}, groups[1].Hosts)
+	assert.Equal(t, []string{"- nginx 1.24", "+ nginx 1.22"}, groups[1].Diff)
+	assert.Equal(t, "error: failed to connect", groups[2].Text)
+
+	output := formatAggregatedOutput(groups)
+	assert.Contains(t, output, "==> 2 host(s) returned this: web1, web3 <==\nnginx 1.24\nssl on\n")
+	assert.Contains(t, output, "==> 1 host(s) differ from the 2 above: web2 <==\n- nginx 1.24\n+ nginx 1.22\n")
+	assert.Contains(t, output, "Groups: 3 distinct output(s) (2 + 1 + 1 hosts)")
+
+	same := formatAggregatedOutput(groupCollectedOutput(hosts[:1], results[:1]))
+	assert.Contains(t, same, "==> all 1 host(s) returned this: web1 <==")
+}
+
+func TestFormatCollectResults(t *testing.T) {
+	hosts := []HostConfig{{Name: "web1"}, {Name: "web2"}}
+	results := []sshclient.CollectResult{
+		{Key: "root@10.0.0.1:22", LocalPath: "collected/web1/error.log", Bytes: 42, Duration: 80 * time.Millisecond},
+		{Key: "root@10.0.0.2:22", Err: errors.New("file is 99 bytes, exceeds size cap of 10 bytes")},
+	}
+
+	report := formatCollectResults(hosts, results)
+
+	assert.Contains(t, report, "Collection (2 hosts):")
+	assert.Contains(t, report, "✓ web1 (root@10.0.0.1:22) 42 bytes in 80ms → collected/web1/error.log")
+	assert.Contains(t, report, "❌ web2 (root@10.0.0.2:22) failed: file is 99 bytes")
+	assert.Contains(t, report, "Summary: 1/2 hosts collected")
+}
+
+func TestCollectFromHosts_Validation(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{{Name: "web1", Host: "10.0.0.1"}}}
+
+	_, _, _, err := collectFromHosts(settings, "", nil, sshclient.CollectOptions{RemotePath: "/a", LocalDir: "out"}, sshclient.RolloutOptions{})
+	assert.ErrorContains(t, err, "hosts are required")
+
+	_, _, _, err = collectFromHosts(settings, "web1", nil, sshclient.CollectOptions{LocalDir: "out"}, sshclient.RolloutOptions{})
+	assert.ErrorContains(t, err, "a remote path or command is required")
+
+	_, _, _, err = collectFromHosts(settings, "web1", nil, sshclient.CollectOptions{RemotePath: "/a", Command: "uptime"}, sshclient.RolloutOptions{})
+	assert.ErrorContains(t, err, "cannot be combined")
+
+	_, _, _, err = collectFromHosts(settings, "web1", nil, sshclient.CollectOptions{RemotePath: "/a"}, sshclient.RolloutOptions{})
+	assert.ErrorContains(t, err, "local directory is required")
+
+	_, _, _, err = collectFromHosts(settings, "missing", nil, sshclient.CollectOptions{Command: "uptime"}, sshclient.RolloutOptions{})
+	assert.ErrorContains(t, err, "no host or group named 'missing'")
+
+	_, _, _, err = collectFromHosts(settings, "web1", nil, sshclient.CollectOptions{Command: "uptime"}, sshclient.RolloutOptions{Canary: -1})
+	assert.ErrorContains(t, err, "cannot be negative")
+	_, _, _, err = collectFromHosts(settings, "web1", nil, sshclient.CollectOptions{RemotePath: "/a", LocalDir: "out"}, sshclient.RolloutOptions{Canary: 1})
+	assert.ErrorContains(t, err, "not file collection")
+	_, _, _, err = collectFromHosts(settings, "web1", nil, sshclient.CollectOptions{RemotePath: "/a", LocalDir: "out", Rollback: "true"}, sshclient.RolloutOptions{})
+	assert.ErrorContains(t, err, "a rollback undoes commands and uploads")
+	_, _, _, err = distributeFile(settings, "web1", nil, sshclient.DistributeOptions{LocalPath: "a", RemotePath: "b"}, sshclient.RolloutOptions{Expect: "ok"})
+	assert.ErrorContains(t, err, "--validate")
+}
+
+func TestFormatRolloutResult(t *testing.T) {
+	assert.Empty(t, formatRolloutResult(nil, nil))
+
+	hosts := []HostConfig{{Name: "web1"}, {Name: "web2"}, {Name: "web3"}, {Name: "web4"}}
+	report := formatRolloutResult(hosts, &sshclient.RolloutResult{
+		Stages: []sshclient.RolloutStage{
+			{Name: "canary", Hosts: []int{0}, Failed: []int{}},
+			{Name: "batch 1", Hosts: []int{1, 2}, Failed: []int{2}},
+		},
+		Halted:  true,
+		Reason:  "1 host(s) failed, more than the 0 allowed",
+		Skipped: []int{3},
+	})
+	assert.Contains(t, report, "Rollout (2 stages):\n  ✓ canary: web1\n  ❌ batch 1: web2, web3 (failed: web3)\n")
+	assert.Contains(t, report, "Halted: 1 host(s) failed, more than the 0 allowed; 1 host(s) skipped: web4\n")
+}
+
+func TestParseArgs_Collect(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--hosts=prod", "--collect=/var/log/app/error.log", "--into=./collected/", "--max-size=10M"})
+
+	assert.Equal(t, "sftp", config.Mode)
+	assert.Equal(t, "collect", config.SftpAction)
+	assert.Equal(t, "prod", config.Hosts)
+	assert.Equal(t, "/var/log/app/error.log", config.RemotePath)
+	assert.Equal(t, "./collected/", config.LocalPath)
+	assert.Equal(t, int64(10<<20), config.MaxBytes)
+
+	config = ParseArgs([]string{"sshx", "--hosts=prod", "--collect-cmd=journalctl -n 100", "--into=out.log"})
+	assert.Equal(t, "collect", config.SftpAction)
+	assert.Equal(t, "journalctl -n 100", config.CollectCommand)
+	assert.Equal(t, "out.log", config.LocalPath)
+	assert.False(t, config.AggregateOutput)
+
+	config = ParseArgs([]string{"sshx", "--hosts=prod", "--collect-cmd=nginx -v", "--aggregate"})
+	assert.True(t, config.AggregateOutput)
+
+	config = ParseArgs([]string{"sshx", "--hosts=prod", "--collect-cmd=deploy", "--canary=1", "--batch-size=5", "--max-failures=two", "--expect=^done", "--check=curl -fsS localhost/health"})
+	assert.Equal(t, sshclient.RolloutOptions{Canary: 1, BatchSize: 5, MaxFailures: -1, Expect: "^done", Check: "curl -fsS localhost/health"}, config.Rollout)
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":  512,
+		"64K":  64 << 10,
+		"10m":  10 << 20,
+		"10MB": 10 << 20,
+		"1G":   1 << 30,
+		"abc":  0,
+		"-5":   0,
+	}
+	for input, expected := range tests {
+		assert.Equal(t, expected, parseSize(input), input)
+	}
+}
+
+func TestNewHostSSHConfig_ForceConfirmation(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{{Name: "web1", Host: "10.0.0.1"}, {Name: "web2", Host: "10.0.0.2"}}}
+	var out bytes.Buffer
+	base := &sshclient.Config{SafetyCheck: true, Force: true, ForceReason: "kernel update",
+		ConfirmForce: forceConfirmation(strings.NewReader("force reboot\n"), &out, true, false)}
+
+	// Every host of a forced fleet command goes through the confirmation,
+	// which asks once for all of them
+	var wg sync.WaitGroup
+	errs := make([]error, len(settings.Hosts))
+	for i := range settings.Hosts {
+		config := newHostSSHConfig(&settings.Hosts[i], settings, base)
+		require.NotNil(t, config.ConfirmForce)
+		config.Command = "sudo reboot"
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = sshclient.SafetyCheckMiddleware(func(*sshclient.Config) (string, error) { return "", nil })(config)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, strings.Count(out.String(), "Type 'force reboot'"))
+
+	base.ConfirmForce = forceConfirmation(strings.NewReader(""), &out, false, false)
+	config := newHostSSHConfig(&settings.Hosts[1], settings, base)
+	config.Command = "sudo reboot"
+	_, err := sshclient.SafetyCheckMiddleware(func(*sshclient.Config) (string, error) { return "", nil })(config)
+	var refused *sshclient.ForceRefusedError
+	assert.True(t, errors.As(err, &refused), "a reason alone does not force a fleet command")
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// forceConfirmation returns the check --force has to pass before it runs a
+// blocked command: the user types a confirmation phrase naming the rule and,
+// unless --force-reason gave one, the reason for the bypass. Without a
+// terminal there is nobody to confirm, so blocked commands stay blocked.
+// A command is confirmed once, so --watch does not ask on every run and a
+// command sent to several hosts asks only for the first of them.
+func forceConfirmation(in io.Reader, out io.Writer, interactive, askReason bool) func(*sshclient.BlockedCommandError) (string, error) {
+	reader := bufio.NewReader(in)
+	var mu sync.Mutex
+	var confirmed, confirmedReason string
+	return func(blocked *sshclient.BlockedCommandError) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if confirmed != "" && confirmed == blocked.Command {
+			return confirmedReason, nil
+		}
+		if !interactive {
+			return "", fmt.Errorf("--force has to be confirmed at a terminal")
+		}
+		phrase := "force " + blocked.Rule
+		fmt.Fprint(out, logger.Plain(fmt.Sprintf("⚠️  %s\nCommand: %s\nType '%s' to run it anyway: ", blocked.Reason, blocked.Command, phrase)))
+		answer, err := reader.ReadString('\n')
+		if err != nil && answer == "" {
+			return "", fmt.Errorf("no confirmation given")
+		}
+		if strings.TrimSpace(answer) != phrase {
+			return "", fmt.Errorf("confirmation phrase did not match")
+		}
+		reason := ""
+		if askReason {
+			fmt.Fprint(out, "Reason for the bypass (recorded in the audit log): ")
+			reason, _ = reader.ReadString('\n') //nolint:errcheck // an empty reason is refused
+			reason = strings.TrimSpace(reason)
+		}
+		confirmed, confirmedReason = blocked.Command, reason
+		return reason, nil
+	}
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestForceConfirmation(t *testing.T) {
+	var blocked *sshclient.BlockedCommandError
+	require.True(t, errors.As(sshclient.ValidateCommand("sudo reboot"), &blocked))
+
+	var out bytes.Buffer
+	_, err := forceConfirmation(strings.NewReader("force reboot\n"), &out, false, true)(blocked)
+	assert.Error(t, err, "no terminal, no confirmation")
+	assert.Empty(t, out.String())
+
+	_, err = forceConfirmation(strings.NewReader("yes\n"), &out, true, true)(blocked)
+	assert.EqualError(t, err, "confirmation phrase did not match")
+	assert.Contains(t, out.String(), "Type 'force reboot' to run it anyway")
+
+	_, err = forceConfirmation(strings.NewReader(""), &out, true, true)(blocked)
+	assert.Error(t, err)
+
+	reason, err := forceConfirmation(strings.NewReader("force reboot\n  kernel update \n"), &out, true, true)(blocked)
+	require.NoError(t, err)
+	assert.Equal(t, "kernel update", reason)
+
+	out.Reset()
+	confirm := forceConfirmation(strings.NewReader("force reboot\n"), &out, true, false)
+	reason, err = confirm(blocked)
+	require.NoError(t, err)
+	assert.Empty(t, reason)
+	assert.NotContains(t, out.String(), "Reason for the bypass")
+
+	// Watched commands are confirmed once
+	out.Reset()
+	_, err = confirm(blocked)
+	require.NoError(t, err)
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// checkForwarding validates --forward-agent and -X before connecting. The
+// agent is only forwarded to hosts forward_agent_hosts lists, since root on
+// the host can use it to log in anywhere as the user while it is connected.
+func checkForwarding(config *sshclient.Config, settings *Settings) error {
+	if !config.ForwardAgent && !config.ForwardX11 {
+		return nil
+	}
+	if config.Mode != "ssh" {
+		return fmt.Errorf("--forward-agent and -X only apply to commands")
+	}
+	if !config.ForwardAgent {
+		return nil
+	}
+	name := cmp.Or(config.Alias, config.Host)
+	if !agentForwardingAllowed(settings, config) {
+		return fmt.Errorf("agent forwarding to %s is not allowed: add its name, address or one of its tags to forward_agent_hosts in settings.json", name)
+	}
+	logger.GetLogger().Warning("Forwarding your ssh-agent to %s: while the command runs, anyone with root access there can authenticate as you with your keys", name)
+	return nil
+}
+
+// agentForwardingAllowed reports whether forward_agent_hosts lists the host
+// config connects to, by name, address or tag
+func agentForwardingAllowed(settings *Settings, config *sshclient.Config) bool {
+	if settings == nil {
+		return false
+	}
+	host := lookupHost(settings, cmp.Or(config.Alias, config.Host))
+	return slices.ContainsFunc(settings.ForwardAgentHosts, func(entry string) bool {
+		if entry == config.Host || (config.Alias != "" && entry == config.Alias) {
+			return true
+		}
+		return host != nil && (entry == host.Name || host.HasTag(entry))
+	})
+}
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestCheckForwarding(t *testing.T) {
+	settings := &Settings{
+		Hosts: []HostConfig{
+			{Name: "git-builder", Host: "10.0.0.7", Tags: []string{"ci"}},
+			{Name: "web1", Host: "10.0.0.8"},
+		},
+		ForwardAgentHosts: []string{"ci", "10.0.0.9"},
+	}
+
+	config := &sshclient.Config{Mode: "ssh", Host: "10.0.0.7", Alias: "git-builder", ForwardAgent: true}
+	assert.NoError(t, checkForwarding(config, settings), "allowed by tag")
+	config = &sshclient.Config{Mode: "ssh", Host: "10.0.0.9", ForwardAgent: true}
+	assert.NoError(t, checkForwarding(config, settings), "allowed by address")
+
+	config = &sshclient.Config{Mode: "ssh", Host: "10.0.0.8", Alias: "web1", ForwardAgent: true}
+	assert.ErrorContains(t, checkForwarding(config, settings), "agent forwarding to web1 is not allowed")
+	assert.Error(t, checkForwarding(config, nil), "nothing is allowed without settings")
+
+	config = &sshclient.Config{Mode: "ssh", Host: "10.0.0.8", ForwardX11: true}
+	assert.NoError(t, checkForwarding(config, settings), "X11 needs no allowlist")
+	config = &sshclient.Config{Mode: "sftp", Host: "10.0.0.7", Alias: "git-builder", ForwardAgent: true}
+	assert.ErrorContains(t, checkForwarding(config, settings), "only apply to commands")
+	assert.False(t, usesControlMaster(&sshclient.Config{Mode: "ssh", Command: "git pull", ForwardAgent: true}, false))
+}
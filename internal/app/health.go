@@ -0,0 +1,180 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// HealthFile is the host liveness cache under ~/.sshmcp
+	HealthFile = "health.json"
+	// defaultHealthTTL is how long a liveness result counts when
+	// health_ttl is not set
+	defaultHealthTTL = time.Hour
+)
+
+// HostHealth is the last known liveness of a configured host
+type HostHealth struct {
+	Reachable bool      `json:"reachable"`
+	Checked   time.Time `json:"checked"`
+	Error     string    `json:"error,omitempty"` // Why the host was unreachable
+}
+
+// healthMu serializes updates of the cache within this process
+var healthMu sync.Mutex
+
+// healthCachePath returns the path of the liveness cache
+func healthCachePath() (string, error) {
+	settingsDir, err := GetSettingsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(settingsDir, HealthFile), nil
+}
+
+// loadHealthCache reads the liveness cache by host name; a missing or
+// unreadable cache knows no host
+func loadHealthCache() map[string]HostHealth {
+	cache := make(map[string]HostHealth)
+	path, err := healthCachePath()
+	if err != nil {
+		return cache
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- path is under the settings directory
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.GetLogger().Warning("failed to read the host health cache: %v", err)
+		}
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		logger.GetLogger().Warning("ignoring invalid host health cache %s: %v", path, err)
+		return make(map[string]HostHealth)
+	}
+	return cache
+}
+
+// recordHealth merges updates into the liveness cache. Failures are only
+// logged: the cache never fails the operation that observed the hosts.
+func recordHealth(updates map[string]HostHealth) {
+	if len(updates) == 0 {
+		return
+	}
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	path, err := healthCachePath()
+	if err != nil {
+		return
+	}
+	cache := loadHealthCache()
+	for name, health := range updates {
+		cache[name] = health
+	}
+	if err := writeHealthCache(path, cache); err != nil {
+		logger.GetLogger().Warning("failed to update the host health cache: %v", err)
+	}
+}
+
+// writeHealthCache replaces the cache file, through a temporary file so
+// other processes never read half of it
+func writeHealthCache(path string, cache map[string]HostHealth) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(temp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// healthTTL is how long a cached result counts: health_ttl, else
+// defaultHealthTTL
+func healthTTL(settings *Settings) time.Duration {
+	if settings != nil {
+		if ttl := firstDuration("health_ttl", settings.HealthTTL); ttl > 0 {
+			return ttl
+		}
+	}
+	return defaultHealthTTL
+}
+
+// knownUnreachable reports whether the cache holds a current result saying
+// the host is down; hosts without one are not known to be unreachable
+func knownUnreachable(cache map[string]HostHealth, name string, ttl time.Duration, now time.Time) bool {
+	health, ok := cache[name]
+	return ok && !health.Reachable && now.Sub(health.Checked) <= ttl
+}
+
+// selectByHealth keeps the hosts of a batch operation selected by
+// --skip-unreachable (all but the hosts known to be down) or
+// --only-unreachable (only those); without either every host is kept
+func selectByHealth(settings *Settings, hosts []HostConfig, skipUnreachable, onlyUnreachable bool) ([]HostConfig, error) {
+	if !skipUnreachable && !onlyUnreachable {
+		return hosts, nil
+	}
+	if skipUnreachable && onlyUnreachable {
+		return nil, fmt.Errorf("--skip-unreachable and --only-unreachable cannot be combined")
+	}
+
+	cache := loadHealthCache()
+	ttl := healthTTL(settings)
+	now := time.Now()
+	var selected []HostConfig
+	var dropped []string
+	for _, host := range hosts {
+		if knownUnreachable(cache, host.Name, ttl, now) == onlyUnreachable {
+			selected = append(selected, host)
+		} else {
+			dropped = append(dropped, host.Name)
+		}
+	}
+
+	if len(selected) == 0 {
+		if skipUnreachable {
+			return nil, fmt.Errorf("all %d host(s) are known to be unreachable (check them again with --ping)", len(hosts))
+		}
+		return nil, fmt.Errorf("none of the %d host(s) is known to be unreachable in the last %s", len(hosts), ttl)
+	}
+	if skipUnreachable && len(dropped) > 0 {
+		logger.GetLogger().Warning("Skipping %d host(s) known to be unreachable: %s", len(dropped), strings.Join(dropped, ", "))
+	}
+	return selected, nil
+}
+
+// recordReachable marks the hosts an operation reached as reachable;
+// failures prove nothing about liveness, an authentication error or a
+// failed command come from a live host
+func recordReachable(hosts []HostConfig, reached func(i int) bool) {
+	now := time.Now()
+	updates := make(map[string]HostHealth)
+	for i, host := range hosts {
+		if reached(i) {
+			updates[host.Name] = HostHealth{Reachable: true, Checked: now}
+		}
+	}
+	recordHealth(updates)
+}
+
+// resolveBatchHosts resolves the host/group spec of a batch operation and
+// applies the liveness selectors of baseConfig
+func resolveBatchHosts(settings *Settings, spec string, baseConfig *sshclient.Config) ([]HostConfig, error) {
+	hosts, err := ResolveHostGroup(settings, spec)
+	if err != nil || baseConfig == nil {
+		return hosts, err
+	}
+	return selectByHealth(settings, hosts, baseConfig.SkipUnreachable, baseConfig.OnlyUnreachable)
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestSelectByHealth(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	hosts := []HostConfig{{Name: "web1"}, {Name: "web2"}, {Name: "web3"}}
+	now := time.Now()
+	recordHealth(map[string]HostHealth{
+		"web1": {Reachable: true, Checked: now},
+		"web2": {Reachable: false, Checked: now, Error: "connection refused"},
+		"web3": {Reachable: false, Checked: now.Add(-2 * time.Hour)}, // expired
+	})
+
+	selected, err := selectByHealth(nil, hosts, false, false)
+	require.NoError(t, err)
+	assert.Len(t, selected, 3)
+
+	selected, err = selectByHealth(nil, hosts, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web1", "web3"}, hostNames(selected))
+
+	selected, err = selectByHealth(nil, hosts, false, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web2"}, hostNames(selected))
+
+	// A longer health_ttl keeps web3 known to be down
+	selected, err = selectByHealth(&Settings{HealthTTL: "3h"}, hosts, false, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web2", "web3"}, hostNames(selected))
+
+	_, err = selectByHealth(nil, hosts[1:2], true, false)
+	assert.ErrorContains(t, err, "all 1 host(s) are known to be unreachable")
+	_, err = selectByHealth(nil, hosts[:1], false, true)
+	assert.ErrorContains(t, err, "none of the 1 host(s) is known to be unreachable")
+	_, err = selectByHealth(nil, hosts, true, true)
+	assert.ErrorContains(t, err, "cannot be combined")
+}
+
+func TestRecordHealth_Merges(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	assert.Empty(t, loadHealthCache())
+
+	recordHealth(map[string]HostHealth{"web1": {Reachable: false, Checked: time.Now()}})
+	recordReachable([]HostConfig{{Name: "web1"}, {Name: "web2"}}, func(i int) bool { return i == 1 })
+
+	cache := loadHealthCache()
+	require.Len(t, cache, 2)
+	assert.False(t, cache["web1"].Reachable, "a failure proves nothing")
+	assert.True(t, cache["web2"].Reachable)
+}
+
+func TestPingHosts_RecordsHealth(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	settings := &Settings{Hosts: []HostConfig{{Name: "dead", Host: "127.0.0.1", Port: "1"}}}
+
+	_, _, err := pingHosts(settings, "dead, 127.0.0.1:2", sshclient.PingOptions{Timeout: 200 * time.Millisecond})
+	require.NoError(t, err)
+
+	cache := loadHealthCache()
+	require.Len(t, cache, 1, "literal addresses are not cached")
+	assert.False(t, cache["dead"].Reachable)
+	assert.NotEmpty(t, cache["dead"].Error)
+
+	hosts, err := resolveBatchHosts(settings, "dead", &sshclient.Config{OnlyUnreachable: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dead"}, hostNames(hosts))
+	_, err = resolveBatchHosts(settings, "dead", &sshclient.Config{SkipUnreachable: true})
+	assert.Error(t, err)
+}
+
+func hostNames(hosts []HostConfig) []string {
+	names := make([]string, len(hosts))
+	for i, host := range hosts {
+		names[i] = host.Name
+	}
+	return names
+}
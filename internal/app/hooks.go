@@ -0,0 +1,221 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// Hook event names
+const (
+	HookEventCommandSuccess = "command_success"
+	HookEventCommandFailure = "command_failure"
+	HookEventPolicyBlock    = "policy_block"
+)
+
+// hookTimeout bounds how long a single hook may take
+const hookTimeout = 10 * time.Second
+
+// HooksConfig configures notifications fired on command events
+type HooksConfig struct {
+	OnCommandSuccess []Hook `json:"on_command_success,omitempty"`
+	OnCommandFailure []Hook `json:"on_command_failure,omitempty"`
+	OnPolicyBlock    []Hook `json:"on_policy_block,omitempty"`
+}
+
+// Hook is a single notification target: a URL receiving a JSON POST and/or a
+// local command receiving the JSON payload on stdin.
+type Hook struct {
+	URL     string            `json:"url,omitempty"`     // Webhook URL (JSON POST)
+	Headers map[string]string `json:"headers,omitempty"` // Extra HTTP headers (e.g. Authorization)
+	Command string            `json:"command,omitempty"` // Local shell command
+	Hosts   []string          `json:"hosts,omitempty"`   // Only fire for these host names/addresses/tags (empty = all)
+}
+
+// HookEvent is the JSON payload delivered to hooks
+type HookEvent struct {
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"` // cli or mcp
+	Host    string    `json:"host"`   // Configured host name (or address when not configured)
+	Address string    `json:"address"`
+	User    string    `json:"user"`
+	Command string    `json:"command"`
+	Error   string    `json:"error,omitempty"`
+	Reason  string    `json:"reason,omitempty"` // Block reason for policy_block
+}
+
+// newCommandEvent classifies the result of a command execution as a hook event
+func newCommandEvent(config *sshclient.Config, execErr error) HookEvent {
+	event := HookEvent{
+		Event:   HookEventCommandSuccess,
+		Time:    time.Now().UTC(),
+		Source:  config.Source,
+		Host:    config.Alias,
+		Address: config.Host,
+		User:    config.User,
+		Command: logger.Redact(config.Command),
+	}
+	if event.Host == "" {
+		event.Host = config.Host
+	}
+
+	if execErr != nil {
+		event.Event = HookEventCommandFailure
+		event.Error = logger.Redact(execErr.Error())
+
+		var blocked *sshclient.BlockedCommandError
+		if errors.As(execErr, &blocked) {
+			event.Event = HookEventPolicyBlock
+			event.Reason = blocked.Reason
+		}
+	}
+
+	return event
+}
+
+// hooksMiddleware fires the configured hooks after every command execution
+func hooksMiddleware(next sshclient.Executor) sshclient.Executor {
+	return func(config *sshclient.Config) (string, error) {
+		output, err := next(config)
+
+		settings, loadErr := LoadSettings()
+		if loadErr != nil {
+			logger.GetLogger().Debug("skipping hooks, failed to load settings: %v", loadErr)
+			return output, err
+		}
+		fireHooks(settings, newCommandEvent(config, err))
+		return output, err
+	}
+}
+
+// fireHooks delivers an event to every matching hook configured in settings.
+// Delivery failures are logged and never affect the command result.
+func fireHooks(settings *Settings, event HookEvent) {
+	if settings == nil || settings.Hooks == nil {
+		return
+	}
+
+	var hooks []Hook
+	switch event.Event {
+	case HookEventCommandSuccess:
+		hooks = settings.Hooks.OnCommandSuccess
+	case HookEventCommandFailure:
+		hooks = settings.Hooks.OnCommandFailure
+	case HookEventPolicyBlock:
+		hooks = settings.Hooks.OnPolicyBlock
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.GetLogger().Warning("failed to encode hook event: %v", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if !hook.matches(settings, event) {
+			continue
+		}
+		if err := hook.deliver(event, payload); err != nil {
+			logger.GetLogger().Warning("%s hook failed: %v", event.Event, err)
+		}
+	}
+}
+
+// matches reports whether the hook's host filter accepts the event
+func (h Hook) matches(settings *Settings, event HookEvent) bool {
+	if len(h.Hosts) == 0 {
+		return true
+	}
+
+	host, _ := GetHost(settings, event.Host) //nolint:errcheck // unknown hosts only match by name/address
+	for _, filter := range h.Hosts {
+		if filter == event.Host || filter == event.Address {
+			return true
+		}
+		if host != nil && host.HasTag(filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver sends the payload to the hook's URL and/or command
+func (h Hook) deliver(event HookEvent, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	var errs []error
+	if h.URL != "" {
+		if err := postHook(ctx, h.URL, h.Headers, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if h.Command != "" {
+		if err := runHookCommand(ctx, h.Command, event, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func postHook(ctx context.Context, url string, headers map[string]string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sshx-hooks")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	if closeErr := resp.Body.Close(); closeErr != nil {
+		logger.GetLogger().Debug("failed to close webhook response body: %v", closeErr)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+func runHookCommand(ctx context.Context, command string, event HookEvent, payload []byte) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command) // #nosec G204 -- hook command comes from user settings
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command) // #nosec G204 -- hook command comes from user settings
+	}
+
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"SSHX_EVENT="+event.Event,
+		"SSHX_HOST="+event.Host,
+		"SSHX_ADDRESS="+event.Address,
+		"SSHX_USER="+event.User,
+		"SSHX_COMMAND="+event.Command,
+	)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("hook command failed: %w (output: %s)", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
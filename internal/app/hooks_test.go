@@ -0,0 +1,125 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestNewCommandEvent(t *testing.T) {
+	config := &sshclient.Config{Host: "10.0.0.5", User: "root", Command: "rm -rf /", Source: "cli", Alias: "prod-web"}
+
+	success := newCommandEvent(config, nil)
+	assert.Equal(t, HookEventCommandSuccess, success.Event)
+	assert.Equal(t, "cli", success.Source)
+	assert.Equal(t, "prod-web", success.Host)
+	assert.Equal(t, "10.0.0.5", success.Address)
+	assert.Empty(t, success.Error)
+
+	config.Alias = ""
+	failure := newCommandEvent(config, errors.New("exit status 1"))
+	assert.Equal(t, HookEventCommandFailure, failure.Event)
+	assert.Equal(t, "10.0.0.5", failure.Host, "falls back to the address")
+	assert.Equal(t, "exit status 1", failure.Error)
+
+	blockErr := fmt.Errorf("failed to execute command: %w", sshclient.ValidateCommand("rm -rf /"))
+	blocked := newCommandEvent(config, blockErr)
+	assert.Equal(t, HookEventPolicyBlock, blocked.Event)
+	assert.NotEmpty(t, blocked.Reason)
+}
+
+func TestHookMatches(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "prod-web", Host: "10.0.0.5", Tags: []string{"production"}},
+	}}
+	event := HookEvent{Host: "prod-web", Address: "10.0.0.5"}
+
+	assert.True(t, Hook{}.matches(settings, event))
+	assert.True(t, Hook{Hosts: []string{"prod-web"}}.matches(settings, event))
+	assert.True(t, Hook{Hosts: []string{"10.0.0.5"}}.matches(settings, event))
+	assert.True(t, Hook{Hosts: []string{"production"}}.matches(settings, event))
+	assert.False(t, Hook{Hosts: []string{"staging"}}.matches(settings, event))
+}
+
+func TestFireHooks_Webhook(t *testing.T) {
+	received := make(chan HookEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		var event HookEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received <- event
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	settings := &Settings{Hooks: &HooksConfig{
+		OnPolicyBlock: []Hook{{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}}},
+	}}
+
+	fireHooks(settings, HookEvent{Event: HookEventPolicyBlock, Host: "prod-web", Command: "rm -rf /"})
+
+	select {
+	case event := <-received:
+		assert.Equal(t, HookEventPolicyBlock, event.Event)
+		assert.Equal(t, "rm -rf /", event.Command)
+	default:
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestFireHooks_OnlyMatchingEvent(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	settings := &Settings{Hooks: &HooksConfig{OnCommandFailure: []Hook{{URL: server.URL}}}}
+	fireHooks(settings, HookEvent{Event: HookEventCommandSuccess})
+
+	assert.False(t, called)
+}
+
+func TestFireHooks_Command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook command test uses sh")
+	}
+
+	out := filepath.Join(t.TempDir(), "event.json")
+	settings := &Settings{Hooks: &HooksConfig{
+		OnCommandSuccess: []Hook{{Command: "cat > " + out + " && echo $SSHX_EVENT >> " + out}},
+	}}
+
+	fireHooks(settings, HookEvent{Event: HookEventCommandSuccess, Host: "web", Command: "uptime"})
+
+	data, err := os.ReadFile(out) // #nosec G304 -- test temp file
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"command":"uptime"`)
+	assert.Contains(t, string(data), HookEventCommandSuccess+"\n")
+}
+
+func TestPostHook_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body) //nolint:errcheck
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := Hook{URL: server.URL}.deliver(HookEvent{}, []byte("{}"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 500")
+}
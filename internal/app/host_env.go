@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// parseEnv parses environment variables given as NAME=VALUE lines, one per
+// --host-env flag; values may hold commas (NO_PROXY). An empty value is
+// kept, so updates can tell it removes the variable.
+func parseEnv(spec string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, line := range strings.Split(spec, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid environment variable '%s' (use NAME=VALUE)", line)
+		}
+		if err := sshclient.ValidateEnvName(name); err != nil {
+			return nil, err
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// mergeEnv applies the NAME=VALUE lines of spec to env: an empty value
+// removes the variable, an empty spec removes every variable
+func mergeEnv(env map[string]string, spec string) (map[string]string, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	updates, err := parseEnv(spec)
+	if err != nil {
+		return nil, err
+	}
+	merged := maps.Clone(env)
+	if merged == nil {
+		merged = make(map[string]string, len(updates))
+	}
+	for name, value := range updates {
+		if value == "" {
+			delete(merged, name)
+		} else {
+			merged[name] = value
+		}
+	}
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+// validateHostEnv rejects variable names a shell cannot export
+func validateHostEnv(env map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		if err := sshclient.ValidateEnvName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostEnv is the environment exported for the commands on host. Variables
+// with invalid names, which a hand-edited settings.json may hold, are
+// ignored with a warning.
+func hostEnv(host *HostConfig) map[string]string {
+	var env map[string]string
+	for name, value := range host.Env {
+		if err := sshclient.ValidateEnvName(name); err != nil {
+			logger.GetLogger().Warning("host '%s': %v, ignoring it", host.Name, err)
+			continue
+		}
+		if env == nil {
+			env = make(map[string]string, len(host.Env))
+		}
+		env[name] = value
+	}
+	return env
+}
+
+// formatEnv lists env as NAME=VALUE pairs sorted by name
+func formatEnv(env map[string]string) string {
+	pairs := make([]string, 0, len(env))
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		pairs = append(pairs, name+"="+env[name])
+	}
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestMergeEnv(t *testing.T) {
+	env, err := mergeEnv(nil, "HTTP_PROXY=http://proxy:3128\nNO_PROXY=localhost,10.0.0.0/8\n JAVA_HOME=/opt/jdk\nEMPTY=")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"HTTP_PROXY": "http://proxy:3128", "NO_PROXY": "localhost,10.0.0.0/8", "JAVA_HOME": "/opt/jdk"}, env)
+
+	merged, err := mergeEnv(env, "JAVA_HOME=\nHTTPS_PROXY=http://proxy:3128")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"HTTP_PROXY": "http://proxy:3128", "NO_PROXY": "localhost,10.0.0.0/8", "HTTPS_PROXY": "http://proxy:3128"}, merged)
+	assert.Equal(t, "/opt/jdk", env["JAVA_HOME"], "the current environment is not modified")
+
+	env, err = mergeEnv(merged, "")
+	require.NoError(t, err)
+	assert.Nil(t, env)
+
+	for _, spec := range []string{"HTTP_PROXY", "=x", "MY-VAR=1", "1X=2"} {
+		_, err := mergeEnv(nil, spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestHostEnv(t *testing.T) {
+	host := &HostConfig{Name: "build1", Env: map[string]string{"JAVA_HOME": "/opt/jdk", "BAD NAME": "x"}}
+	assert.Equal(t, map[string]string{"JAVA_HOME": "/opt/jdk"}, hostEnv(host))
+	assert.Nil(t, hostEnv(&HostConfig{Name: "web1"}))
+	assert.Equal(t, "A=1 B=x,y", formatEnv(map[string]string{"B": "x,y", "A": "1"}))
+
+	config := &sshclient.Config{}
+	applyConnectionDefaults(config, &HostConfig{Name: "build1", Env: map[string]string{"HTTP_PROXY": "http://proxy:3128"}}, &Settings{})
+	assert.Equal(t, map[string]string{"HTTP_PROXY": "http://proxy:3128"}, config.Env)
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// hostExpiry returns when a host given a time to live of ttl at now
+// expires; an empty ttl never expires
+func hostExpiry(ttl string, now time.Time) (time.Time, error) {
+	if strings.TrimSpace(ttl) == "" {
+		return time.Time{}, nil
+	}
+	duration, err := parseDayDuration(ttl)
+	if err != nil || duration == 0 {
+		return time.Time{}, fmt.Errorf("invalid ttl '%s' (e.g. 4h or 7d)", ttl)
+	}
+	return now.Add(duration).UTC().Truncate(time.Second), nil
+}
+
+// Expired reports whether the host has an expiry that passed at now
+func (h *HostConfig) Expired(now time.Time) bool {
+	return !h.Expires.IsZero() && !now.Before(h.Expires)
+}
+
+// pruneExpiredHosts removes the hosts that expired at now from settings and
+// returns them
+func pruneExpiredHosts(settings *Settings, now time.Time) []HostConfig {
+	var expired []HostConfig
+	kept := settings.Hosts[:0]
+	for _, host := range settings.Hosts {
+		if host.Expired(now) {
+			expired = append(expired, host)
+		} else {
+			kept = append(kept, host)
+		}
+	}
+	settings.Hosts = kept
+	return expired
+}
+
+// expiryPruneInterval is how often the MCP server and daemon prune expired
+// hosts
+const expiryPruneInterval = time.Minute
+
+// dropExpiredHosts removes the expired hosts from the settings file and
+// drops their pooled connections, so short lived machines do not pile up in
+// the inventory. It runs when sshx starts, every expiryPruneInterval in the
+// MCP server and daemon, and when a pinned host expires.
+func dropExpiredHosts() {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings, err := LoadSettings()
+	if err != nil {
+		return
+	}
+	expired := pruneExpiredHosts(settings, time.Now())
+	if len(expired) == 0 {
+		return
+	}
+	names := make([]string, len(expired))
+	for i, host := range expired {
+		names[i] = host.Name
+	}
+	logger.GetLogger().Info("Removed %d expired host(s): %s", len(expired), strings.Join(names, ", "))
+	if err := SaveSettings(settings); err != nil {
+		logger.GetLogger().Warning("failed to save settings without the expired hosts: %v", err)
+	}
+	pool := sshclient.GetConnectionPool()
+	for _, host := range expired {
+		pool.Forget(host.Host)
+	}
+}
+
+// startExpiryPruning prunes expired hosts every expiryPruneInterval until
+// the returned function is called
+func startExpiryPruning() func() {
+	ticker := time.NewTicker(expiryPruneInterval)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				dropExpiredHosts()
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(stop)
+		<-done
+	}
+}
+
+// formatExpiry describes when a host expires relative to now
+func formatExpiry(expires, now time.Time) string {
+	return fmt.Sprintf("%s (in %s)", expires.Local().Format(time.RFC3339), expires.Sub(now).Round(time.Minute))
+}
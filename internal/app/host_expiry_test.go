@@ -0,0 +1,71 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	expires, err := hostExpiry("4h", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(4*time.Hour), expires)
+
+	expires, err = hostExpiry("2d", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(48*time.Hour), expires)
+
+	expires, err = hostExpiry("", now)
+	require.NoError(t, err)
+	assert.True(t, expires.IsZero())
+
+	for _, ttl := range []string{"0", "soon", "-1h"} {
+		_, err := hostExpiry(ttl, now)
+		assert.Error(t, err, ttl)
+	}
+
+	host := HostConfig{Name: "ci-runner", Expires: now}
+	assert.False(t, host.Expired(now.Add(-time.Second)))
+	assert.True(t, host.Expired(now))
+	assert.False(t, (&HostConfig{Name: "web1"}).Expired(now))
+}
+
+func TestDropExpiredHosts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, SaveSettings(&Settings{Hosts: []HostConfig{
+		{Name: "web1", Host: "10.0.0.1"},
+		{Name: "runner-old", Host: "10.0.0.2", Expires: time.Now().Add(-time.Minute)},
+		{Name: "runner-new", Host: "10.0.0.3", Expires: time.Now().Add(time.Hour)},
+	}}))
+
+	// Loading only reads
+	settings, err := LoadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web1", "runner-old", "runner-new"}, hostNames(settings.Hosts))
+
+	dropExpiredHosts()
+	settings, err = LoadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web1", "runner-new"}, hostNames(settings.Hosts))
+
+	// The pruned settings were saved, without temporary files left behind
+	dir, err := GetSettingsDir()
+	require.NoError(t, err)
+	temps, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, temps)
+	path, err := GetSettingsPath()
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	raw := string(data)
+	assert.NotContains(t, raw, "runner-old")
+	assert.Contains(t, raw, `"expires"`)
+	assert.NotContains(t, raw, `"expires": "0001`)
+}
@@ -0,0 +1,235 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// Host import sources
+const (
+	ImportSourceSSHConfig = "ssh_config"
+	ImportSourceFile      = "file"
+)
+
+// HostImportIssue explains why an entry was not imported
+type HostImportIssue struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// HostImportSummary reports the outcome of an import
+type HostImportSummary struct {
+	Source      string            `json:"source"`
+	Path        string            `json:"path"`
+	Imported    []string          `json:"imported"`
+	Overwritten []string          `json:"overwritten"`
+	Skipped     []HostImportIssue `json:"skipped"`
+	Conflicted  []HostImportIssue `json:"conflicted"`
+}
+
+// handleHostImport imports hosts from an ssh config (default ~/.ssh/config)
+// or a JSON file given with --host-import=<file.json>
+func handleHostImport(config *sshclient.Config) error {
+	source := ImportSourceSSHConfig
+	if strings.EqualFold(filepath.Ext(config.SSHConfigPath), ".json") {
+		source = ImportSourceFile
+	}
+
+	summary, err := importHosts(source, config.SSHConfigPath, config.Overwrite)
+	if err != nil {
+		return err
+	}
+	fmt.Print(logger.Plain(formatHostImportSummary(summary)))
+	return nil
+}
+
+// importHosts loads candidates from the source, merges them into the saved
+// settings and saves the result when anything changed
+func importHosts(source, path string, overwrite bool) (*HostImportSummary, error) {
+	var candidates []HostConfig
+	var err error
+	switch source {
+	case "", ImportSourceSSHConfig:
+		source = ImportSourceSSHConfig
+		if path == "" {
+			home, homeErr := os.UserHomeDir()
+			if homeErr != nil {
+				return nil, fmt.Errorf("failed to get user home directory: %w", homeErr)
+			}
+			path = filepath.Join(home, ".ssh", "config")
+		}
+		candidates, err = loadSSHConfigHosts(expandHome(path))
+	case ImportSourceFile:
+		if path == "" {
+			return nil, fmt.Errorf("path is required when importing from a file")
+		}
+		candidates, err = loadHostsFile(expandHome(path))
+	default:
+		return nil, fmt.Errorf("unknown import source '%s' (use %s or %s)", source, ImportSourceSSHConfig, ImportSourceFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings, err := LoadSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	summary := ImportHosts(settings, candidates, overwrite)
+	summary.Source = source
+	summary.Path = path
+
+	if len(summary.Imported)+len(summary.Overwritten) > 0 {
+		if err := SaveSettings(settings); err != nil {
+			return nil, fmt.Errorf("failed to save settings: %w", err)
+		}
+	}
+	return summary, nil
+}
+
+// ImportHosts merges candidates into settings. New names are added; names
+// that already exist with the same address are skipped, and ones with a
+// different address are conflicts unless overwrite is set, in which case
+// the address is replaced while tags, password keys and other sshx-only
+// fields are kept.
+func ImportHosts(settings *Settings, candidates []HostConfig, overwrite bool) *HostImportSummary {
+	summary := &HostImportSummary{
+		Imported:    []string{},
+		Overwritten: []string{},
+		Skipped:     []HostImportIssue{},
+		Conflicted:  []HostImportIssue{},
+	}
+
+	for _, candidate := range candidates {
+		if err := ValidateHostConfig(&candidate); err != nil {
+			summary.Skipped = append(summary.Skipped, HostImportIssue{Name: candidate.Name, Reason: err.Error()})
+			continue
+		}
+
+		existing, err := GetHost(settings, candidate.Name)
+		if err != nil {
+			if addErr := AddHost(settings, candidate); addErr != nil {
+				summary.Conflicted = append(summary.Conflicted, HostImportIssue{Name: candidate.Name, Reason: addErr.Error()})
+				continue
+			}
+			summary.Imported = append(summary.Imported, candidate.Name)
+			continue
+		}
+
+		if sameHostAddress(existing, &candidate) {
+			summary.Skipped = append(summary.Skipped, HostImportIssue{Name: candidate.Name, Reason: "already up to date"})
+			continue
+		}
+		if !overwrite {
+			summary.Conflicted = append(summary.Conflicted, HostImportIssue{
+				Name:   candidate.Name,
+				Reason: fmt.Sprintf("exists as %s, import has %s", hostAddress(existing), hostAddress(&candidate)),
+			})
+			continue
+		}
+
+		updated := *existing
+		updated.Host = candidate.Host
+		updated.Port = candidate.Port
+		updated.User = candidate.User
+		if candidate.Key != "" {
+			updated.Key = candidate.Key
+		}
+		if err := UpdateHost(settings, updated); err != nil {
+			summary.Conflicted = append(summary.Conflicted, HostImportIssue{Name: candidate.Name, Reason: err.Error()})
+			continue
+		}
+		summary.Overwritten = append(summary.Overwritten, candidate.Name)
+	}
+	return summary
+}
+
+// loadSSHConfigHosts converts the concrete hosts of an ssh config. Hosts
+// without a User get the local user name, as ssh would use.
+func loadSSHConfigHosts(path string) ([]HostConfig, error) {
+	entries, err := ParseSSHConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	localUser := currentUsername()
+	hosts := make([]HostConfig, 0, len(entries))
+	for _, entry := range entries {
+		host := HostConfig{Name: entry.Alias, Host: entry.HostName, Port: entry.Port, User: entry.User, Key: entry.IdentityFile}
+		if host.User == "" {
+			host.User = localUser
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// loadHostsFile reads hosts from a JSON file holding either a settings
+// object ({"hosts": [...]}) or a plain array of hosts
+func loadHostsFile(path string) ([]HostConfig, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var hosts []HostConfig
+	if err := json.Unmarshal(data, &hosts); err == nil {
+		return hosts, nil
+	}
+	var settings Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: expected a settings object or an array of hosts: %w", path, err)
+	}
+	return settings.Hosts, nil
+}
+
+// sameHostAddress compares address, port and user with defaults applied
+func sameHostAddress(a, b *HostConfig) bool {
+	return hostAddress(a) == hostAddress(b)
+}
+
+// hostAddress formats user@host:port with defaults applied
+func hostAddress(host *HostConfig) string {
+	port := host.Port
+	if port == "" {
+		port = sshclient.DefaultSSHPort
+	}
+	user := host.User
+	if user == "" {
+		user = sshclient.DefaultSSHUser
+	}
+	return fmt.Sprintf("%s@%s:%s", user, host.Host, port)
+}
+
+// formatHostImportSummary renders an import summary for the terminal
+func formatHostImportSummary(summary *HostImportSummary) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Import from %s (%s):\n", summary.Path, summary.Source))
+	for _, name := range summary.Imported {
+		output.WriteString(fmt.Sprintf("  ✓ %s imported\n", name))
+	}
+	for _, name := range summary.Overwritten {
+		output.WriteString(fmt.Sprintf("  ✓ %s overwritten\n", name))
+	}
+	for _, issue := range summary.Conflicted {
+		output.WriteString(fmt.Sprintf("  ❌ %s conflict: %s\n", issue.Name, issue.Reason))
+	}
+	for _, issue := range summary.Skipped {
+		output.WriteString(fmt.Sprintf("  - %s skipped: %s\n", issue.Name, issue.Reason))
+	}
+	output.WriteString(fmt.Sprintf("Summary: %d imported, %d overwritten, %d skipped, %d conflicted\n",
+		len(summary.Imported), len(summary.Overwritten), len(summary.Skipped), len(summary.Conflicted)))
+	if len(summary.Conflicted) > 0 {
+		output.WriteString("Use --overwrite to replace conflicting hosts.\n")
+	}
+	return output.String()
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportHosts(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "web1", Host: "10.0.0.1", Port: "22", User: "deploy", Tags: []string{"prod"}, PasswordKey: "web1"},
+		{Name: "db1", Host: "10.0.0.2", Port: "22", User: "master"},
+	}}
+	candidates := []HostConfig{
+		{Name: "web1", Host: "10.0.0.1", User: "deploy"},
+		{Name: "db1", Host: "10.0.0.20", Port: "2222", User: "dba"},
+		{Name: "cache1", Host: "10.0.0.3"},
+		{Name: "dup", Host: "10.0.0.1", User: "other"},
+		{Name: "", Host: "10.0.0.4"},
+	}
+
+	summary := ImportHosts(settings, candidates, false)
+	assert.Equal(t, []string{"cache1"}, summary.Imported)
+	assert.Empty(t, summary.Overwritten)
+	require.Len(t, summary.Skipped, 2)
+	assert.Equal(t, HostImportIssue{Name: "web1", Reason: "already up to date"}, summary.Skipped[0])
+	assert.Contains(t, summary.Skipped[1].Reason, "host name is required")
+	require.Len(t, summary.Conflicted, 2)
+	assert.Equal(t, "db1", summary.Conflicted[0].Name)
+	assert.Contains(t, summary.Conflicted[0].Reason, "exists as master@10.0.0.2:22, import has dba@10.0.0.20:2222")
+	assert.Contains(t, summary.Conflicted[1].Reason, "already exists")
+
+	summary = ImportHosts(settings, candidates[1:2], true)
+	assert.Equal(t, []string{"db1"}, summary.Overwritten)
+	db1, err := GetHost(settings, "db1")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.20", db1.Host)
+	assert.Equal(t, "2222", db1.Port)
+
+	web1, err := GetHost(settings, "web1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod"}, web1.Tags)
+}
+
+func TestImportHostsFromSSHConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeSSHConfig(t, filepath.Join(home, ".ssh", "config"), `
+Include config.d/*
+Host web1
+    HostName 10.0.0.1
+    User deploy
+`)
+	writeSSHConfig(t, filepath.Join(home, ".ssh", "config.d", "db"), "Host db1\n    HostName 10.0.0.2\n    Port 2222\n    User dba\n")
+
+	summary, err := importHosts("", "", false)
+	require.NoError(t, err)
+	assert.Equal(t, ImportSourceSSHConfig, summary.Source)
+	assert.Equal(t, []string{"db1", "web1"}, summary.Imported)
+
+	settings, err := LoadSettings()
+	require.NoError(t, err)
+	db1, err := GetHost(settings, "db1")
+	require.NoError(t, err)
+	assert.Equal(t, "2222", db1.Port)
+	assert.Equal(t, "dba", db1.User)
+
+	// Running it again changes nothing
+	summary, err = importHosts(ImportSourceSSHConfig, "", false)
+	require.NoError(t, err)
+	assert.Empty(t, summary.Imported)
+	assert.Len(t, summary.Skipped, 2)
+}
+
+func TestLoadHostsFile(t *testing.T) {
+	dir := t.TempDir()
+
+	arrayPath := filepath.Join(dir, "hosts.json")
+	data, err := json.Marshal([]HostConfig{{Name: "web1", Host: "10.0.0.1"}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(arrayPath, data, 0o600))
+
+	hosts, err := loadHostsFile(arrayPath)
+	require.NoError(t, err)
+	assert.Equal(t, "web1", hosts[0].Name)
+
+	settingsPath := filepath.Join(dir, "settings.json")
+	data, err = json.Marshal(Settings{Hosts: []HostConfig{{Name: "db1", Host: "10.0.0.2"}}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(settingsPath, data, 0o600))
+
+	hosts, err = loadHostsFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, "db1", hosts[0].Name)
+
+	badPath := filepath.Join(dir, "bad.json")
+	require.NoError(t, os.WriteFile(badPath, []byte("not json"), 0o600))
+	_, err = loadHostsFile(badPath)
+	assert.ErrorContains(t, err, "failed to parse")
+}
+
+func TestImportHosts_InvalidSource(t *testing.T) {
+	_, err := importHosts("ldap", "", false)
+	assert.ErrorContains(t, err, "unknown import source")
+
+	_, err = importHosts(ImportSourceFile, "", false)
+	assert.ErrorContains(t, err, "path is required")
+}
+
+func TestParseArgs_HostImport(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--host-import"})
+	assert.Equal(t, "host", config.Mode)
+	assert.Equal(t, "import", config.HostAction)
+
+	config = ParseArgs([]string{"sshx", "--host-import=hosts.json", "--overwrite"})
+	assert.Equal(t, "hosts.json", config.SSHConfigPath)
+	assert.True(t, config.Overwrite)
+}
@@ -2,8 +2,11 @@ package app
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
@@ -20,7 +23,7 @@ func HandleHostManagement(config *sshclient.Config) error {
 		return handleHostAdd(config)
 	case "update":
 		return handleHostUpdate(config)
-	case "list":
+	case "list", "find":
 		return handleHostList(config)
 	case "test":
 		return handleHostTest(config)
@@ -28,6 +31,18 @@ func HandleHostManagement(config *sshclient.Config) error {
 		return handleHostTestAll(config)
 	case "remove":
 		return handleHostRemove(config)
+	case "ping":
+		return handleHostPing(config)
+	case "diagnose":
+		return handleHostDiagnose(config)
+	case "import":
+		return handleHostImport(config)
+	case "sync":
+		return handleHostSync(config)
+	case "discover":
+		return handleHostDiscover(config)
+	case "export-sshconfig":
+		return handleHostExportSSHConfig(config)
 	default:
 		return fmt.Errorf("unknown host action: %s", config.HostAction)
 	}
@@ -49,10 +64,24 @@ func handleHostAdd(config *sshclient.Config) error {
 			Name:        config.HostName,
 			Description: config.HostDescription,
 			Host:        config.Host,
+			Addresses:   splitList(config.HostFields["addresses"]),
 			Port:        config.Port,
 			User:        config.User,
 			PasswordKey: config.SudoKey,
 			Type:        config.HostType,
+			Tags:        config.HostTags,
+			Key:         config.HostFields["key"],
+			Shell:       config.HostFields["shell"],
+			Device:      config.HostFields["device"],
+		}
+		if host.Meta, err = mergeMeta(nil, config.HostFields["meta"]); err != nil {
+			return err
+		}
+		if host.Env, err = mergeEnv(nil, config.HostFields["env"]); err != nil {
+			return err
+		}
+		if host.Expires, err = hostExpiry(config.HostFields["ttl"], time.Now()); err != nil {
+			return err
 		}
 	} else {
 		// Interactive mode
@@ -120,11 +149,15 @@ func handleHostAdd(config *sshclient.Config) error {
 		return fmt.Errorf("failed to save settings: %w", err)
 	}
 
+	if !host.Expires.IsZero() {
+		logger.GetLogger().Success("Host '%s' added successfully, expires %s", host.Name, formatExpiry(host.Expires, time.Now()))
+		return nil
+	}
 	logger.GetLogger().Success("Host '%s' added successfully", host.Name)
 	return nil
 }
 
-// handleHostUpdate updates an existing host in settings
+// handleHostUpdate changes only the host fields given on the command line
 func handleHostUpdate(config *sshclient.Config) error {
 	// Load settings
 	settings, err := LoadSettings()
@@ -136,69 +169,12 @@ func handleHostUpdate(config *sshclient.Config) error {
 	if config.HostName == "" {
 		return fmt.Errorf("host name is required for update (use --host-name=<name>)")
 	}
-
-	// Check if host exists
-	_, err = GetHost(settings, config.HostName)
-	if err != nil {
+	if _, err := GetHost(settings, config.HostName); err != nil {
 		return fmt.Errorf("host '%s' not found, use --host-add to create it", config.HostName)
 	}
 
-	// Build updated host config
-	host := HostConfig{
-		Name: config.HostName,
-	}
-
-	// Update fields if provided, otherwise keep existing values
-	existingHost, err := GetHost(settings, config.HostName)
+	host, err := ApplyHostUpdate(settings, config.HostName, config.HostFields)
 	if err != nil {
-		// If host doesn't exist, we'll create it with only the provided fields
-		existingHost = &HostConfig{}
-	}
-
-	if config.Host != "" {
-		host.Host = config.Host
-	} else {
-		host.Host = existingHost.Host
-	}
-
-	if config.HostDescription != "" {
-		host.Description = config.HostDescription
-	} else {
-		host.Description = existingHost.Description
-	}
-
-	if config.Port != "" && config.Port != "22" {
-		host.Port = config.Port
-	} else if existingHost.Port != "" {
-		host.Port = existingHost.Port
-	} else {
-		host.Port = "22"
-	}
-
-	if config.User != "" && config.User != "master" {
-		host.User = config.User
-	} else if existingHost.User != "" {
-		host.User = existingHost.User
-	} else {
-		host.User = "master"
-	}
-
-	if config.SudoKey != "" && config.SudoKey != sshclient.DefaultSudoKey {
-		host.PasswordKey = config.SudoKey
-	} else if existingHost.PasswordKey != "" {
-		host.PasswordKey = existingHost.PasswordKey
-	}
-
-	if config.HostType != "" {
-		host.Type = config.HostType
-	} else if existingHost.Type != "" {
-		host.Type = existingHost.Type
-	} else {
-		host.Type = "linux"
-	}
-
-	// Update host
-	if err := UpdateHost(settings, host); err != nil {
 		return fmt.Errorf("failed to update host: %w", err)
 	}
 
@@ -207,11 +183,17 @@ func handleHostUpdate(config *sshclient.Config) error {
 		return fmt.Errorf("failed to save settings: %w", err)
 	}
 
-	logger.GetLogger().Success("Host '%s' updated successfully", host.Name)
+	changed := make([]string, 0, len(config.HostFields))
+	for field := range config.HostFields {
+		changed = append(changed, field)
+	}
+	sort.Strings(changed)
+	logger.GetLogger().Success("Host '%s' updated successfully (%s)", host.Name, strings.Join(changed, ", "))
 	return nil
 }
 
-// handleHostList lists all configured hosts
+// handleHostList lists all configured hosts, or with --host-find the hosts
+// whose metadata matches, grouped by a metadata key with --group-by
 func handleHostList(config *sshclient.Config) error {
 	// Load settings
 	settings, err := LoadSettings()
@@ -220,6 +202,17 @@ func handleHostList(config *sshclient.Config) error {
 	}
 
 	hosts := ListHosts(settings)
+	title := "Configured Hosts"
+	if config.HostAction == "find" {
+		if hosts, err = FindHosts(settings, config.HostFind); err != nil {
+			return err
+		}
+		if len(hosts) == 0 {
+			fmt.Printf("No hosts match %s.\n", config.HostFind)
+			return nil
+		}
+		title = "Hosts matching " + config.HostFind
+	}
 
 	if len(hosts) == 0 {
 		fmt.Println("No hosts configured.")
@@ -229,27 +222,21 @@ func handleHostList(config *sshclient.Config) error {
 	}
 
 	// Detailed mode
-	fmt.Printf("\n=== Configured Hosts (%d) ===\n\n", len(hosts))
+	fmt.Printf("\n=== %s (%d) ===\n\n", title, len(hosts))
 
-	for i, host := range hosts {
-		fmt.Printf("[%d] %s\n", i+1, host.Name)
-		fmt.Printf("    Host:        %s\n", host.Host)
-		if host.Description != "" {
-			fmt.Printf("    Description: %s\n", host.Description)
-		}
-		if host.Port != "" && host.Port != "22" {
-			fmt.Printf("    Port:        %s\n", host.Port)
+	if config.HostGroupBy == "" {
+		for i, host := range hosts {
+			printHostEntry(i+1, host)
 		}
-		if host.User != "" {
-			fmt.Printf("    User:        %s\n", host.User)
-		}
-		if host.PasswordKey != "" {
-			fmt.Printf("    Password Key: %s\n", host.PasswordKey)
-		}
-		if host.Type != "" {
-			fmt.Printf("    Type:        %s\n", host.Type)
+	} else {
+		index := 1
+		for _, group := range groupHostsBy(hosts, config.HostGroupBy) {
+			fmt.Printf("--- %s: %s (%d) ---\n\n", config.HostGroupBy, group.Value, len(group.Hosts))
+			for _, host := range group.Hosts {
+				printHostEntry(index, host)
+				index++
+			}
 		}
-		fmt.Println()
 	}
 
 	fmt.Println("Usage:")
@@ -259,6 +246,55 @@ func handleHostList(config *sshclient.Config) error {
 	return nil
 }
 
+// printHostEntry prints one host of a listing
+func printHostEntry(index int, host HostConfig) {
+	fmt.Printf("[%d] %s\n", index, host.Name)
+	fmt.Printf("    Host:        %s\n", host.Host)
+	if len(host.Addresses) > 0 {
+		fmt.Printf("    Addresses:   %s\n", strings.Join(host.Addresses, ", "))
+	}
+	if host.Description != "" {
+		fmt.Printf("    Description: %s\n", host.Description)
+	}
+	if host.Port != "" && host.Port != "22" {
+		fmt.Printf("    Port:        %s\n", host.Port)
+	}
+	if host.User != "" {
+		fmt.Printf("    User:        %s\n", host.User)
+	}
+	if host.PasswordKey != "" {
+		fmt.Printf("    Password Key: %s\n", host.PasswordKey)
+	}
+	if host.Type != "" {
+		fmt.Printf("    Type:        %s\n", host.Type)
+	}
+	if host.Device != "" {
+		fmt.Printf("    Device:      %s\n", host.Device)
+	}
+	if len(host.Tags) > 0 {
+		fmt.Printf("    Tags:        %s\n", strings.Join(host.Tags, ", "))
+	}
+	if host.Key != "" {
+		fmt.Printf("    Key:         %s\n", host.Key)
+	}
+	if host.Shell != "" {
+		fmt.Printf("    Shell:       %s\n", host.Shell)
+	}
+	if len(host.Meta) > 0 {
+		fmt.Printf("    Meta:        %s\n", formatMeta(host.Meta))
+	}
+	if len(host.Env) > 0 {
+		fmt.Printf("    Env:         %s\n", formatEnv(host.Env))
+	}
+	if ownership, err := hostUploadOwnership(&host); err == nil && ownership != (sshclient.FileOwnership{}) {
+		fmt.Printf("    Uploads:     %s\n", ownership)
+	}
+	if !host.Expires.IsZero() {
+		fmt.Printf("    Expires:     %s\n", formatExpiry(host.Expires, time.Now()))
+	}
+	fmt.Println()
+}
+
 // handleHostTest tests host connection
 func handleHostTest(config *sshclient.Config) error {
 	// Load settings
@@ -280,6 +316,15 @@ func handleHostTest(config *sshclient.Config) error {
 	logger.GetLogger().Info("Testing connection to '%s' (%s)...", hostConfig.Name, hostConfig.Host)
 
 	result := runHostDiagnostics(hostConfig, settings, config)
+	if config.JSONOutput {
+		if err := printHostTestReports([]hostTestResult{result}, true); err != nil {
+			return err
+		}
+		if !result.Success() {
+			return fmt.Errorf("host test failed")
+		}
+		return nil
+	}
 	if !result.ConnectionSuccess {
 		if result.ConnectionError != nil {
 			logger.GetLogger().Error("Connection failed: %v", result.ConnectionError)
@@ -298,6 +343,8 @@ func handleHostTest(config *sshclient.Config) error {
 
 	logger.GetLogger().Success("Command execution successful!")
 	fmt.Printf("\nTest output: %s\n", strings.TrimSpace(result.CommandOutput))
+	fmt.Printf("Latency: connect %s, auth %s, command %s\n",
+		formatLatency(result.Stats.DialLatency), formatLatency(result.Stats.AuthLatency), formatLatency(result.CommandLatency))
 
 	return nil
 }
@@ -350,6 +397,22 @@ func handleHostTestAll(config *sshclient.Config) error {
 		results = append(results, result)
 	}
 
+	if config.JSONOutput {
+		if err := printHostTestReports(results, false); err != nil {
+			return err
+		}
+		failed := 0
+		for _, result := range results {
+			if !result.Success() {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("host test failed for %d host(s)", failed)
+		}
+		return nil
+	}
+
 	successCount := 0
 	fmt.Printf("\n=== Host Test Report (%d hosts) ===\n\n", len(results))
 	for i, result := range results {
@@ -369,17 +432,19 @@ func handleHostTestAll(config *sshclient.Config) error {
 		}
 
 		fmt.Printf("[%d] %s (%s)\n", i+1, result.Host.Name, result.Host.Host)
-		fmt.Printf("    Status: %s %s\n", statusIcon, statusMessage)
+		fmt.Printf("    Status: %s %s\n", logger.Plain(statusIcon), statusMessage)
 		fmt.Printf("    Auth: %s\n", formatAuthDescription(result.AuthMethod))
 		if !result.ConnectionSuccess && result.ConnectionError != nil {
-			fmt.Printf("    Error: %v\n", result.ConnectionError)
+			fmt.Printf("    Error: %s\n", logger.Plain(result.ConnectionError.Error()))
 		} else if result.CommandSuccess {
 			output := strings.TrimSpace(result.CommandOutput)
 			if output != "" {
 				fmt.Printf("    Output: %s\n", output)
 			}
+			fmt.Printf("    Latency: connect %s, auth %s, command %s\n",
+				formatLatency(result.Stats.DialLatency), formatLatency(result.Stats.AuthLatency), formatLatency(result.CommandLatency))
 		} else if result.CommandError != nil {
-			fmt.Printf("    Command Error: %v\n", result.CommandError)
+			fmt.Printf("    Command Error: %s\n", logger.Plain(result.CommandError.Error()))
 		}
 		fmt.Println()
 	}
@@ -393,12 +458,18 @@ func handleHostTestAll(config *sshclient.Config) error {
 }
 
 func runHostDiagnostics(hostConfig *HostConfig, settings *Settings, baseConfig *sshclient.Config) hostTestResult {
+	return testHostConnection(hostConfig, buildHostTestConfig(hostConfig, settings, baseConfig))
+}
+
+// testHostConnection connects to a host with a fresh client, outside the
+// pool, runs a test command and records how long each step took
+func testHostConnection(hostConfig *HostConfig, sshConfig *sshclient.Config) hostTestResult {
 	result := hostTestResult{
 		Host:       *hostConfig,
 		AuthMethod: sshclient.AuthMethodUnknown,
+		TestedAt:   time.Now(),
 	}
 
-	sshConfig := buildHostTestConfig(hostConfig, settings, baseConfig)
 	client, err := sshclient.NewSSHClient(sshConfig)
 	if err != nil {
 		result.ConnectionError = err
@@ -410,7 +481,9 @@ func runHostDiagnostics(hostConfig *HostConfig, settings *Settings, baseConfig *
 		}
 	}()
 
-	if err := client.ConnectDirect(); err != nil {
+	err = client.ConnectDirect()
+	result.Stats = client.ConnectStats()
+	if err != nil {
 		result.ConnectionError = err
 		return result
 	}
@@ -418,8 +491,9 @@ func runHostDiagnostics(hostConfig *HostConfig, settings *Settings, baseConfig *
 	result.ConnectionSuccess = true
 	result.AuthMethod = client.AuthMethodUsed()
 
-	sshConfig.Command = "echo 'Connection test successful'"
-	output, execErr := client.ExecuteCommandWithOutput()
+	started := time.Now()
+	output, execErr := client.ExecuteCommandWithOutput(sshclient.Request{Command: "echo 'Connection test successful'"})
+	result.CommandLatency = time.Since(started)
 	if execErr != nil {
 		result.CommandError = execErr
 		return result
@@ -431,45 +505,310 @@ func runHostDiagnostics(hostConfig *HostConfig, settings *Settings, baseConfig *
 }
 
 func buildHostTestConfig(hostConfig *HostConfig, settings *Settings, baseConfig *sshclient.Config) *sshclient.Config {
-	testConfig := &sshclient.Config{
-		Host:        hostConfig.Host,
-		Port:        hostConfig.Port,
-		User:        hostConfig.User,
-		UseKeyAuth:  true,
-		DialTimeout: hostTestDialTimeout,
+	testConfig := newHostSSHConfig(hostConfig, settings, baseConfig)
+	if testConfig.DialTimeout <= 0 {
+		testConfig.DialTimeout = hostTestDialTimeout
+	}
+	return testConfig
+}
+
+// newHostSSHConfig builds an SSH config for a configured host, applying settings
+// defaults (default key, keyring password) and optional overrides from baseConfig.
+func newHostSSHConfig(hostConfig *HostConfig, settings *Settings, baseConfig *sshclient.Config) *sshclient.Config {
+	sshConfig := &sshclient.Config{
+		Host:       hostConfig.Host,
+		Port:       hostConfig.Port,
+		User:       hostConfig.User,
+		UseKeyAuth: true,
+		Alias:      hostConfig.Name,
 	}
 
 	if baseConfig != nil {
-		testConfig.UseKeyAuth = baseConfig.UseKeyAuth
-		testConfig.KeyPath = baseConfig.KeyPath
-		testConfig.Password = baseConfig.Password
+		sshConfig.UseKeyAuth = baseConfig.UseKeyAuth
+		sshConfig.KeyPath = baseConfig.KeyPath
+		sshConfig.Password = baseConfig.Password
 		if baseConfig.DialTimeout > 0 {
-			testConfig.DialTimeout = baseConfig.DialTimeout
+			sshConfig.DialTimeout = baseConfig.DialTimeout
 		}
+		sshConfig.Keepalive = baseConfig.Keepalive
+		sshConfig.MaxRetries = baseConfig.MaxRetries
+		sshConfig.KnownHostsPath = baseConfig.KnownHostsPath
+		sshConfig.AcceptUnknownHost = baseConfig.AcceptUnknownHost
+		sshConfig.AllowInsecureHostKey = baseConfig.AllowInsecureHostKey
+		sshConfig.RevokedKeysPath = baseConfig.RevokedKeysPath
+		sshConfig.TrustTTL = baseConfig.TrustTTL
+		sshConfig.SafetyCheck = baseConfig.SafetyCheck
+		sshConfig.Force = baseConfig.Force
+		sshConfig.ForceReason = baseConfig.ForceReason
+		sshConfig.ConfirmForce = baseConfig.ConfirmForce
+		sshConfig.Source = baseConfig.Source
+		sshConfig.Client = baseConfig.Client
+		sshConfig.Caller = baseConfig.Caller
+	}
+	applyHostKeySettings(sshConfig, settings)
+
+	if sshConfig.Port == "" {
+		sshConfig.Port = sshclient.DefaultSSHPort
+	}
+	if sshConfig.User == "" {
+		sshConfig.User = sshclient.DefaultSSHUser
+	}
+
+	if !sshConfig.UseKeyAuth {
+		sshConfig.KeyPath = ""
+	} else if sshConfig.KeyPath == "" && hostConfig.Key != "" {
+		sshConfig.KeyPath = hostConfig.Key
+		sshConfig.KeyPassphraseKey = hostConfig.KeyPassphraseKey
+	} else if sshConfig.KeyPath == "" && settings != nil && settings.Key != "" {
+		sshConfig.KeyPath = settings.Key
 	}
 
-	if testConfig.Port == "" {
-		testConfig.Port = sshclient.DefaultSSHPort
-	}
-	if testConfig.User == "" {
-		testConfig.User = sshclient.DefaultSSHUser
+	if hostConfig.PasswordKey != "" {
+		if password, err := sshclient.GetSudoPassword(hostConfig.PasswordKey); err == nil {
+			sshConfig.Password = password
+		} else {
+			logger.GetLogger().Warning("failed to get password from keyring (%s): %v", hostConfig.PasswordKey, err)
+		}
 	}
+	applyConnectionDefaults(sshConfig, hostConfig, settings)
 
-	if !testConfig.UseKeyAuth {
-		testConfig.KeyPath = ""
-	} else if testConfig.KeyPath == "" && settings != nil && settings.Key != "" {
-		testConfig.KeyPath = settings.Key
+	return sshConfig
+}
+
+// applyConnectionDefaults fills in the dial timeout, keepalive interval,
+// retry count, remote temp directory, sandbox, SFTP path policy, safety
+// policy, shell, concurrency limit, dial addresses, device profile, environment and upload ownership that were not set explicitly, from the host's own settings first
+// and then the global defaults in settings.json
+func applyConnectionDefaults(config *sshclient.Config, host *HostConfig, settings *Settings) {
+	if host == nil {
+		host = &HostConfig{}
+	}
+	if settings == nil {
+		settings = &Settings{}
 	}
 
-	if hostConfig.PasswordKey != "" {
-		if password, err := sshclient.GetSudoPassword(hostConfig.PasswordKey); err == nil {
-			testConfig.Password = password
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = firstDuration("dial_timeout", host.DialTimeout, settings.DialTimeout)
+	}
+	if config.Keepalive <= 0 {
+		config.Keepalive = firstDuration("keepalive", host.Keepalive, settings.Keepalive)
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = host.MaxRetries
+		if config.MaxRetries <= 0 {
+			config.MaxRetries = settings.MaxRetries
+		}
+	}
+	if config.TempDir == "" {
+		config.TempDir = host.TempDir
+		if config.TempDir == "" {
+			config.TempDir = settings.TempDir
+		}
+	}
+	if config.Sandbox == nil {
+		sandbox := host.Sandbox
+		if sandbox == nil {
+			sandbox = settings.Sandbox
+		}
+		config.Sandbox = sandbox.toSandbox()
+	}
+	if config.PathPolicy == nil {
+		paths := host.SFTPPaths
+		if paths == nil {
+			paths = settings.SFTPPaths
+		}
+		config.PathPolicy = paths.toPathPolicy()
+	}
+	if config.Safety == nil {
+		config.Safety = hostSafetyConfig(host, settings).toSafetyPolicy()
+	}
+	if config.Shell == "" {
+		config.Shell = hostShell(host)
+	}
+	if config.MaxParallel <= 0 {
+		config.MaxParallel = hostMaxParallel(host)
+	}
+	if len(config.Addresses) == 0 {
+		config.Addresses = hostAddresses(host, settings)
+	}
+	if config.Device == nil {
+		config.Device = hostDevice(host, settings)
+	}
+	if config.Env == nil {
+		config.Env = hostEnv(host)
+	}
+	if config.UploadOwnership == (sshclient.FileOwnership{}) {
+		ownership, err := hostUploadOwnership(host)
+		if err != nil {
+			logger.GetLogger().Warning("host '%s': %v, ignoring it", host.Name, err)
 		} else {
-			logger.GetLogger().Warning("failed to get password from keyring (%s): %v", hostConfig.PasswordKey, err)
+			config.UploadOwnership = ownership
 		}
 	}
+}
 
-	return testConfig
+// hostUploadOwnership is the mode, owner and group uploads give files on
+// host
+func hostUploadOwnership(host *HostConfig) (sshclient.FileOwnership, error) {
+	ownership := sshclient.FileOwnership{Owner: strings.TrimSpace(host.UploadOwner), Group: strings.TrimSpace(host.UploadGroup)}
+	if host.UploadMode != "" {
+		mode, err := sshclient.ParseFileMode(host.UploadMode)
+		if err != nil {
+			return sshclient.FileOwnership{}, fmt.Errorf("upload_mode: %w", err)
+		}
+		ownership.Mode = mode
+	}
+	if err := ownership.Validate(); err != nil {
+		return sshclient.FileOwnership{}, fmt.Errorf("upload_owner/upload_group: %w", err)
+	}
+	return ownership, nil
+}
+
+// hostAddresses returns the addresses dialed before host.Host: its own
+// list, else the tailnet address when settings prefer it
+func hostAddresses(host *HostConfig, settings *Settings) []string {
+	if len(host.Addresses) > 0 {
+		return host.Addresses
+	}
+	if settings.PreferTailnet && host.Name != "" {
+		return []string{sshclient.TailscaleAddress}
+	}
+	return nil
+}
+
+// hostMaxParallel is how many commands may run at once on host: one when it
+// is serialized, otherwise its max_parallel (0 = no limit)
+func hostMaxParallel(host *HostConfig) int {
+	if host.Serialize {
+		return 1
+	}
+	if host.MaxParallel < 0 {
+		logger.GetLogger().Warning("host '%s': max_parallel %d is negative, ignoring it", host.Name, host.MaxParallel)
+		return 0
+	}
+	return host.MaxParallel
+}
+
+// hostShell is the shell commands run in on host: its shell field, or
+// PowerShell for Windows hosts. An invalid shell is ignored with a warning.
+func hostShell(host *HostConfig) string {
+	if host.Shell == "" {
+		if host.Type == "windows" {
+			return "powershell"
+		}
+		return ""
+	}
+	if err := sshclient.ValidateShell(host.Shell); err != nil {
+		logger.GetLogger().Warning("host '%s': %v, ignoring it", host.Name, err)
+		return ""
+	}
+	return host.Shell
+}
+
+// hostSafetyConfig picks the safety policy of a host: its own block, then
+// the safety_groups entry of its first tag that has one, then the global one
+func hostSafetyConfig(host *HostConfig, settings *Settings) *SafetyConfig {
+	if host.Safety != nil {
+		return host.Safety
+	}
+	if settings == nil {
+		return nil
+	}
+	for _, tag := range host.Tags {
+		if safety, ok := settings.SafetyGroups[tag]; ok && safety != nil {
+			return safety
+		}
+	}
+	return settings.Safety
+}
+
+// toSafetyPolicy converts the safety settings. An invalid severity is
+// reported and taken as block, and an invalid maintenance window is
+// reported and dropped; without any valid window left everything is
+// blocked, so a typo never relaxes the policy.
+func (s *SafetyConfig) toSafetyPolicy() *sshclient.SafetyPolicy {
+	if s == nil {
+		return nil
+	}
+	policy := &sshclient.SafetyPolicy{Rules: make(map[string]sshclient.Severity, len(s.Rules)), ForceTOTPKey: s.ForceTOTPKey}
+	if s.Mode != "" {
+		severity, err := sshclient.ParseSeverity(s.Mode)
+		if err != nil {
+			logger.GetLogger().Warning("invalid safety mode: %v", err)
+			severity = sshclient.SeverityBlock
+		}
+		policy.Default = severity
+	}
+	for rule, value := range s.Rules {
+		if !slices.Contains(sshclient.SafetyRules(), rule) {
+			logger.GetLogger().Warning("unknown safety rule '%s', ignoring it", rule)
+			continue
+		}
+		severity, err := sshclient.ParseSeverity(value)
+		if err != nil {
+			logger.GetLogger().Warning("invalid safety rule '%s': %v", rule, err)
+			severity = sshclient.SeverityBlock
+		}
+		policy.Rules[rule] = severity
+	}
+	for i, entry := range s.MaintenanceWindows {
+		window, err := sshclient.ParseMaintenanceWindow(entry.Days, entry.Start, entry.End, entry.Timezone)
+		if err != nil {
+			logger.GetLogger().Warning("invalid maintenance window %d: %v, ignoring it", i+1, err)
+			continue
+		}
+		policy.Windows = append(policy.Windows, window)
+	}
+	if len(s.MaintenanceWindows) > 0 && len(policy.Windows) == 0 {
+		logger.GetLogger().Warning("no valid maintenance window, blocking all flagged commands")
+		return &sshclient.SafetyPolicy{Default: sshclient.SeverityBlock, ForceTOTPKey: s.ForceTOTPKey}
+	}
+	return policy
+}
+
+// toPathPolicy converts the SFTP path settings
+func (s *SFTPPathsConfig) toPathPolicy() *sshclient.PathPolicy {
+	if s == nil {
+		return nil
+	}
+	return &sshclient.PathPolicy{Read: s.Read, Write: s.Write, Deny: s.Deny}
+}
+
+// toSandbox converts the sandbox settings; an invalid timeout is ignored
+// with a warning like the other durations in settings.json
+func (s *SandboxConfig) toSandbox() *sshclient.Sandbox {
+	if s == nil {
+		return nil
+	}
+	return &sshclient.Sandbox{
+		CleanEnv:  s.CleanEnv,
+		Path:      s.Path,
+		Timeout:   firstDuration("sandbox.timeout", s.Timeout),
+		CPUQuota:  s.CPUQuota,
+		MemoryMax: s.MemoryMax,
+		TasksMax:  s.TasksMax,
+	}
+}
+
+// firstDuration parses the first non-empty value; "0" yields zero (use the
+// built-in default, or disable keepalive), invalid values are skipped with a
+// warning
+func firstDuration(field string, values ...string) time.Duration {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if value == "0" {
+			duration, err = 0, nil
+		}
+		if err != nil || duration < 0 {
+			logger.GetLogger().Warning("invalid %s '%s', ignoring it", field, value)
+			continue
+		}
+		return duration
+	}
+	return 0
 }
 
 func formatAuthDescription(method sshclient.AuthMethod) string {
@@ -493,8 +832,87 @@ type hostTestResult struct {
 	ConnectionError   error
 	CommandError      error
 	CommandOutput     string
+	Stats             sshclient.ConnectStats
+	CommandLatency    time.Duration
+	TestedAt          time.Time
 }
 
 func (r hostTestResult) Success() bool {
 	return r.ConnectionSuccess && r.CommandSuccess
 }
+
+// HostTestReport is the JSON form of a host test, with latencies in
+// milliseconds so results can be graphed over time
+type HostTestReport struct {
+	Name          string  `json:"name"`
+	Host          string  `json:"host"`
+	Success       bool    `json:"success"`
+	FailedStage   string  `json:"failed_stage,omitempty"` // "connect" or "command"
+	AuthMethod    string  `json:"auth_method"`
+	ConnectMs     float64 `json:"connect_ms"`
+	AuthMs        float64 `json:"auth_ms"`
+	CommandMs     float64 `json:"command_ms"`
+	ServerVersion string  `json:"server_version,omitempty"`
+	Banner        string  `json:"banner,omitempty"`
+	Output        string  `json:"output,omitempty"`
+	Error         string  `json:"error,omitempty"`
+	TestedAt      string  `json:"tested_at"`
+}
+
+// Report converts the result to its JSON form
+func (r hostTestResult) Report() HostTestReport {
+	report := HostTestReport{
+		Name:          r.Host.Name,
+		Host:          r.Host.Host,
+		Success:       r.Success(),
+		AuthMethod:    string(r.AuthMethod),
+		ConnectMs:     durationMs(r.Stats.DialLatency),
+		AuthMs:        durationMs(r.Stats.AuthLatency),
+		CommandMs:     durationMs(r.CommandLatency),
+		ServerVersion: r.Stats.ServerVersion,
+		Banner:        strings.TrimSpace(r.Stats.Banner),
+		Output:        strings.TrimSpace(r.CommandOutput),
+		TestedAt:      r.TestedAt.UTC().Format(time.RFC3339),
+	}
+	switch {
+	case !r.ConnectionSuccess:
+		report.FailedStage = "connect"
+		if r.ConnectionError != nil {
+			report.Error = r.ConnectionError.Error()
+		}
+	case !r.CommandSuccess:
+		report.FailedStage = "command"
+		if r.CommandError != nil {
+			report.Error = r.CommandError.Error()
+		}
+	}
+	return report
+}
+
+// formatLatency rounds a latency for display
+func formatLatency(d time.Duration) string {
+	return d.Round(100 * time.Microsecond).String()
+}
+
+// durationMs converts d to milliseconds with microsecond precision
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// printHostTestReports writes host test results as indented JSON
+func printHostTestReports(results []hostTestResult, single bool) error {
+	reports := make([]HostTestReport, 0, len(results))
+	for _, result := range results {
+		reports = append(reports, result.Report())
+	}
+	var value interface{} = reports
+	if single && len(reports) == 1 {
+		value = reports[0]
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode host test report: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -71,3 +73,219 @@ func TestBuildHostTestConfig_DisableKeyAuth(t *testing.T) {
 		t.Fatalf("expected dial timeout override to persist (want %s, got %s)", base.DialTimeout, cfg.DialTimeout)
 	}
 }
+
+func TestBuildHostTestConfig_HostKey(t *testing.T) {
+	settings := &Settings{Key: "/default/key"}
+	host := &HostConfig{Name: "demo", Host: "demo.example.com", Key: "/host/key"}
+
+	cfg := buildHostTestConfig(host, settings, &sshclient.Config{UseKeyAuth: true})
+	if cfg.KeyPath != "/host/key" {
+		t.Fatalf("expected host key, got %s", cfg.KeyPath)
+	}
+
+	cfg = buildHostTestConfig(host, settings, &sshclient.Config{UseKeyAuth: true, KeyPath: "/explicit/key"})
+	if cfg.KeyPath != "/explicit/key" {
+		t.Fatalf("expected explicit key to win, got %s", cfg.KeyPath)
+	}
+}
+
+func TestApplyConnectionDefaults(t *testing.T) {
+	settings := &Settings{DialTimeout: "3s", Keepalive: "30s", MaxRetries: 2, TempDir: "/var/tmp"}
+	satellite := &HostConfig{Name: "sat", Host: "10.9.0.1", DialTimeout: "60s", Keepalive: "0", MaxRetries: 5, TempDir: "/home/ops/tmp"}
+
+	cfg := newHostSSHConfig(satellite, settings, nil)
+	if cfg.DialTimeout != 60*time.Second || cfg.Keepalive != 0 || cfg.MaxRetries != 5 {
+		t.Fatalf("host values not applied: timeout=%s keepalive=%s retries=%d", cfg.DialTimeout, cfg.Keepalive, cfg.MaxRetries)
+	}
+	if cfg.TempDir != "/home/ops/tmp" {
+		t.Fatalf("host temp dir not applied: %s", cfg.TempDir)
+	}
+
+	lan := &HostConfig{Name: "lan", Host: "192.168.1.10", DialTimeout: "soon"}
+	cfg = newHostSSHConfig(lan, settings, nil)
+	if cfg.DialTimeout != 3*time.Second || cfg.Keepalive != 30*time.Second || cfg.MaxRetries != 2 {
+		t.Fatalf("global defaults not applied: timeout=%s keepalive=%s retries=%d", cfg.DialTimeout, cfg.Keepalive, cfg.MaxRetries)
+	}
+	if cfg.TempDir != "/var/tmp" {
+		t.Fatalf("global temp dir not applied: %s", cfg.TempDir)
+	}
+
+	if cfg.Sandbox != nil {
+		t.Fatalf("no sandbox configured, got %+v", cfg.Sandbox)
+	}
+
+	settings.Sandbox = &SandboxConfig{Timeout: "5m", MemoryMax: "512M"}
+	cfg = newHostSSHConfig(lan, settings, nil)
+	if cfg.Sandbox == nil || cfg.Sandbox.Timeout != 5*time.Minute || cfg.Sandbox.MemoryMax != "512M" {
+		t.Fatalf("global sandbox not applied: %+v", cfg.Sandbox)
+	}
+	satellite.Sandbox = &SandboxConfig{}
+	cfg = newHostSSHConfig(satellite, settings, nil)
+	if cfg.Sandbox == nil || cfg.Sandbox.Timeout != 0 || cfg.Sandbox.MemoryMax != "" {
+		t.Fatalf("host sandbox should replace the global one: %+v", cfg.Sandbox)
+	}
+
+	if cfg.PathPolicy != nil {
+		t.Fatalf("no SFTP path policy configured, got %+v", cfg.PathPolicy)
+	}
+	settings.SFTPPaths = &SFTPPathsConfig{Write: []string{"/opt/app", "/tmp"}, Deny: []string{"/etc/ssh"}}
+	cfg = newHostSSHConfig(lan, settings, nil)
+	if cfg.PathPolicy == nil || len(cfg.PathPolicy.Write) != 2 || len(cfg.PathPolicy.Deny) != 1 {
+		t.Fatalf("global SFTP path policy not applied: %+v", cfg.PathPolicy)
+	}
+	satellite.SFTPPaths = &SFTPPathsConfig{}
+	cfg = newHostSSHConfig(satellite, settings, nil)
+	if cfg.PathPolicy == nil || len(cfg.PathPolicy.Write) != 0 || len(cfg.PathPolicy.Deny) != 0 {
+		t.Fatalf("host SFTP path policy should replace the global one: %+v", cfg.PathPolicy)
+	}
+
+	if cfg.Safety != nil {
+		t.Fatalf("no safety policy configured, got %+v", cfg.Safety)
+	}
+	settings.Safety = &SafetyConfig{Rules: map[string]string{"reboot": "warn"}}
+	settings.SafetyGroups = map[string]*SafetyConfig{"dev": {Mode: "warn"}}
+	cfg = newHostSSHConfig(lan, settings, nil)
+	if cfg.Safety.SeverityOf("reboot") != sshclient.SeverityWarn || cfg.Safety.SeverityOf("shutdown") != sshclient.SeverityBlock {
+		t.Fatalf("global safety policy not applied: %+v", cfg.Safety)
+	}
+	dev := &HostConfig{Name: "dev1", Host: "10.0.0.9", Tags: []string{"web", "dev"}}
+	cfg = newHostSSHConfig(dev, settings, nil)
+	if cfg.Safety.SeverityOf("shutdown") != sshclient.SeverityWarn {
+		t.Fatalf("group safety policy not applied: %+v", cfg.Safety)
+	}
+	dev.Safety = &SafetyConfig{Mode: "sometimes", Rules: map[string]string{"reboot": "info", "unknown": "warn"}}
+	cfg = newHostSSHConfig(dev, settings, nil)
+	if cfg.Safety.SeverityOf("shutdown") != sshclient.SeverityBlock || cfg.Safety.SeverityOf("reboot") != sshclient.SeverityInfo {
+		t.Fatalf("host safety policy should replace the others, with invalid modes blocking: %+v", cfg.Safety)
+	}
+	if _, ok := cfg.Safety.Rules["unknown"]; ok {
+		t.Fatalf("unknown safety rules should be dropped: %+v", cfg.Safety.Rules)
+	}
+	if cfg.Safety.Windows != nil {
+		t.Fatalf("no maintenance windows configured, got %+v", cfg.Safety.Windows)
+	}
+	dev.Safety.MaintenanceWindows = []MaintenanceWindowConfig{
+		{Days: []string{"sat"}, Start: "02:00", End: "05:00", Timezone: "UTC"},
+		{Start: "25:00", End: "05:00"},
+	}
+	cfg = newHostSSHConfig(dev, settings, nil)
+	if len(cfg.Safety.Windows) != 1 || cfg.Safety.Windows[0].String() != "sat 02:00-05:00 UTC" {
+		t.Fatalf("maintenance windows not converted, invalid ones dropped: %+v", cfg.Safety.Windows)
+	}
+	dev.Safety.ForceTOTPKey = "prod-totp"
+	if cfg = newHostSSHConfig(dev, settings, nil); cfg.Safety.ForceTOTPKey != "prod-totp" {
+		t.Fatalf("force_totp_key not applied: %+v", cfg.Safety)
+	}
+	dev.Safety.MaintenanceWindows = dev.Safety.MaintenanceWindows[1:]
+	cfg = newHostSSHConfig(dev, settings, nil)
+	if cfg.Safety.SeverityOf("reboot") != sshclient.SeverityBlock || cfg.Safety.ForceTOTPKey != "prod-totp" {
+		t.Fatalf("only invalid maintenance windows should block everything: %+v", cfg.Safety)
+	}
+
+	if cfg.Shell != "" {
+		t.Fatalf("no shell configured, got %q", cfg.Shell)
+	}
+	windows := &HostConfig{Name: "win1", Host: "10.0.0.50", Type: "windows"}
+	if cfg = newHostSSHConfig(windows, settings, nil); cfg.Shell != "powershell" {
+		t.Fatalf("windows hosts should default to powershell, got %q", cfg.Shell)
+	}
+	windows.Shell = "pwsh"
+	if cfg = newHostSSHConfig(windows, settings, nil); cfg.Shell != "pwsh" {
+		t.Fatalf("host shell not applied: %q", cfg.Shell)
+	}
+	dev.Shell = "tcsh"
+	if cfg = newHostSSHConfig(dev, settings, nil); cfg.Shell != "" {
+		t.Fatalf("invalid shell should be ignored, got %q", cfg.Shell)
+	}
+
+	if cfg.MaxParallel != 0 {
+		t.Fatalf("no concurrency limit configured, got %d", cfg.MaxParallel)
+	}
+	apt := &HostConfig{Name: "apt1", Host: "10.0.0.60", MaxParallel: 4}
+	if cfg = newHostSSHConfig(apt, settings, nil); cfg.MaxParallel != 4 {
+		t.Fatalf("host max_parallel not applied: %d", cfg.MaxParallel)
+	}
+	apt.Serialize = true
+	if cfg = newHostSSHConfig(apt, settings, nil); cfg.MaxParallel != 1 {
+		t.Fatalf("serialized hosts should run one command at a time, got %d", cfg.MaxParallel)
+	}
+
+	if cfg = newHostSSHConfig(lan, settings, nil); len(cfg.Addresses) != 0 {
+		t.Fatalf("no addresses configured, got %v", cfg.Addresses)
+	}
+	settings.PreferTailnet = true
+	if cfg = newHostSSHConfig(lan, settings, nil); len(cfg.Addresses) != 1 || cfg.Addresses[0] != sshclient.TailscaleAddress {
+		t.Fatalf("prefer_tailnet should dial the tailnet first, got %v", cfg.Addresses)
+	}
+	lan.Addresses = []string{"10.8.0.5"}
+	if cfg = newHostSSHConfig(lan, settings, nil); len(cfg.Addresses) != 1 || cfg.Addresses[0] != "10.8.0.5" {
+		t.Fatalf("host addresses should replace prefer_tailnet, got %v", cfg.Addresses)
+	}
+
+	if cfg.UploadOwnership != (sshclient.FileOwnership{}) {
+		t.Fatalf("no upload ownership configured, got %+v", cfg.UploadOwnership)
+	}
+	web := &HostConfig{Name: "web9", Host: "10.0.0.70", UploadMode: "0640", UploadOwner: "www-data", UploadGroup: "33"}
+	if cfg = newHostSSHConfig(web, settings, nil); cfg.UploadOwnership != (sshclient.FileOwnership{Mode: 0o640, Owner: "www-data", Group: "33"}) {
+		t.Fatalf("host upload ownership not applied: %+v", cfg.UploadOwnership)
+	}
+	web.UploadMode = "rw-r-----"
+	if cfg = newHostSSHConfig(web, settings, nil); cfg.UploadOwnership != (sshclient.FileOwnership{}) {
+		t.Fatalf("invalid upload ownership should be ignored, got %+v", cfg.UploadOwnership)
+	}
+	if err := ValidateHostConfig(web); err == nil || !strings.Contains(err.Error(), "upload_mode") {
+		t.Fatalf("invalid upload_mode should be rejected, got %v", err)
+	}
+
+	cfg = buildHostTestConfig(lan, &Settings{}, &sshclient.Config{DialTimeout: time.Second})
+	if cfg.DialTimeout != time.Second {
+		t.Fatalf("explicit timeout should win, got %s", cfg.DialTimeout)
+	}
+	cfg = buildHostTestConfig(&HostConfig{Name: "plain", Host: "10.0.0.1"}, &Settings{}, nil)
+	if cfg.DialTimeout != hostTestDialTimeout {
+		t.Fatalf("expected host test default timeout, got %s", cfg.DialTimeout)
+	}
+}
+
+func TestHostTestResultReport(t *testing.T) {
+	testedAt := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	result := hostTestResult{
+		Host:              HostConfig{Name: "web1", Host: "10.0.0.1"},
+		AuthMethod:        sshclient.AuthMethodKey,
+		ConnectionSuccess: true,
+		CommandSuccess:    true,
+		CommandOutput:     "Connection test successful\r\n",
+		Stats: sshclient.ConnectStats{
+			DialLatency:   1500 * time.Microsecond,
+			AuthLatency:   42 * time.Millisecond,
+			ServerVersion: "SSH-2.0-OpenSSH_9.6",
+			Banner:        "authorized use only\n",
+		},
+		CommandLatency: 7250 * time.Microsecond,
+		TestedAt:       testedAt,
+	}
+
+	report := result.Report()
+	if !report.Success || report.FailedStage != "" || report.Error != "" {
+		t.Fatalf("unexpected failure in report: %+v", report)
+	}
+	if report.ConnectMs != 1.5 || report.AuthMs != 42 || report.CommandMs != 7.25 {
+		t.Fatalf("latencies = %v/%v/%v ms", report.ConnectMs, report.AuthMs, report.CommandMs)
+	}
+	if report.AuthMethod != "key" || report.ServerVersion != "SSH-2.0-OpenSSH_9.6" || report.Banner != "authorized use only" {
+		t.Fatalf("unexpected details: %+v", report)
+	}
+	if report.Output != "Connection test successful" || report.TestedAt != "2026-10-16T08:00:00Z" {
+		t.Fatalf("unexpected output or time: %+v", report)
+	}
+
+	failed := hostTestResult{
+		Host:            HostConfig{Name: "db1", Host: "10.0.0.2"},
+		AuthMethod:      sshclient.AuthMethodUnknown,
+		ConnectionError: errors.New("connection refused"),
+		TestedAt:        testedAt,
+	}.Report()
+	if failed.Success || failed.FailedStage != "connect" || failed.Error != "connection refused" {
+		t.Fatalf("unexpected failed report: %+v", failed)
+	}
+}
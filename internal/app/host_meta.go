@@ -0,0 +1,165 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// noMetaGroup holds the hosts without the key a listing is grouped by
+const noMetaGroup = "(none)"
+
+// parseMeta parses metadata given as key=value pairs separated by commas.
+// An empty value is kept, so updates can tell it removes the key.
+func parseMeta(spec string) (map[string]string, error) {
+	meta := make(map[string]string)
+	for _, pair := range splitList(spec) {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid metadata '%s' (use key=value)", pair)
+		}
+		meta[key] = strings.TrimSpace(value)
+	}
+	return meta, nil
+}
+
+// mergeMeta applies the key=value pairs of spec to meta: an empty value
+// removes the key, an empty spec removes every key
+func mergeMeta(meta map[string]string, spec string) (map[string]string, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	updates, err := parseMeta(spec)
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[string]string, len(meta)+len(updates))
+	for key, value := range meta {
+		merged[key] = value
+	}
+	for key, value := range updates {
+		if value == "" {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+// metaFilter is one condition of a host search: the host has key, with a
+// value matching the glob pattern when one is given
+type metaFilter struct {
+	Key     string
+	Pattern string
+}
+
+// parseHostFind parses a search such as "env=prod,rack=r1*,owner": every
+// condition has to hold. A bare key only requires the host to have it.
+func parseHostFind(spec string) ([]metaFilter, error) {
+	var filters []metaFilter
+	for _, condition := range splitList(spec) {
+		key, pattern, _ := strings.Cut(condition, "=")
+		filter := metaFilter{Key: strings.TrimSpace(key), Pattern: strings.TrimSpace(pattern)}
+		if filter.Key == "" {
+			return nil, fmt.Errorf("invalid search '%s' (use key=value or key)", condition)
+		}
+		if _, err := path.Match(filter.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern in search '%s': %w", condition, err)
+		}
+		filters = append(filters, filter)
+	}
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("search is empty (use key=value or key)")
+	}
+	return filters, nil
+}
+
+// matches reports whether the metadata of host satisfies the filter
+func (f metaFilter) matches(host *HostConfig) bool {
+	value, ok := host.Meta[f.Key]
+	if !ok {
+		return false
+	}
+	if f.Pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(f.Pattern, value) //nolint:errcheck // checked by parseHostFind
+	return matched
+}
+
+// FindHosts returns the configured hosts whose metadata matches every
+// condition of spec (see parseHostFind), in settings order
+func FindHosts(settings *Settings, spec string) ([]HostConfig, error) {
+	filters, err := parseHostFind(spec)
+	if err != nil {
+		return nil, err
+	}
+	var found []HostConfig
+	for _, host := range ListHosts(settings) {
+		matched := true
+		for _, filter := range filters {
+			if !filter.matches(&host) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			found = append(found, host)
+		}
+	}
+	return found, nil
+}
+
+// hostGroup is the hosts sharing one value of a metadata key
+type hostGroup struct {
+	Value string
+	Hosts []HostConfig
+}
+
+// groupHostsBy groups hosts by their value of the metadata key, sorted by
+// value; hosts without the key come last, in noMetaGroup
+func groupHostsBy(hosts []HostConfig, key string) []hostGroup {
+	byValue := make(map[string][]HostConfig)
+	for _, host := range hosts {
+		value, ok := host.Meta[key]
+		if !ok {
+			value = noMetaGroup
+		}
+		byValue[value] = append(byValue[value], host)
+	}
+	values := make([]string, 0, len(byValue))
+	for value := range byValue {
+		if value != noMetaGroup {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	if _, ok := byValue[noMetaGroup]; ok {
+		values = append(values, noMetaGroup)
+	}
+	groups := make([]hostGroup, len(values))
+	for i, value := range values {
+		groups[i] = hostGroup{Value: value, Hosts: byValue[value]}
+	}
+	return groups
+}
+
+// formatMeta renders metadata as sorted key=value pairs
+func formatMeta(meta map[string]string) string {
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + meta[key]
+	}
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeMeta(t *testing.T) {
+	meta, err := mergeMeta(nil, "rack=r12, owner = payments,env=")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"rack": "r12", "owner": "payments"}, meta)
+
+	meta, err = mergeMeta(meta, "owner=,app=checkout")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"rack": "r12", "app": "checkout"}, meta)
+
+	meta, err = mergeMeta(meta, "rack=,app=")
+	require.NoError(t, err)
+	assert.Nil(t, meta)
+
+	for _, spec := range []string{"rack", "=r12"} {
+		_, err := mergeMeta(nil, spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestFindHosts(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "web1", Meta: map[string]string{"env": "prod", "rack": "r12", "owner": "payments"}},
+		{Name: "web2", Meta: map[string]string{"env": "prod", "rack": "r20"}},
+		{Name: "db1", Meta: map[string]string{"env": "staging", "rack": "r13", "owner": "data"}},
+		{Name: "bare"},
+	}}
+
+	cases := map[string][]string{
+		"env=prod":          {"web1", "web2"},
+		"env=prod,rack=r1*": {"web1"},
+		"owner":             {"web1", "db1"},
+		"env=dev":           {},
+	}
+	for spec, want := range cases {
+		hosts, err := FindHosts(settings, spec)
+		require.NoError(t, err, spec)
+		assert.Equal(t, want, hostNames(hosts), spec)
+	}
+
+	for _, spec := range []string{"", "=prod", "rack=r[1"} {
+		_, err := FindHosts(settings, spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestGroupHostsBy(t *testing.T) {
+	hosts := []HostConfig{
+		{Name: "web1", Meta: map[string]string{"owner": "payments"}},
+		{Name: "bare"},
+		{Name: "db1", Meta: map[string]string{"owner": "data"}},
+		{Name: "web2", Meta: map[string]string{"owner": "payments"}},
+	}
+	groups := groupHostsBy(hosts, "owner")
+	require.Len(t, groups, 3)
+	assert.Equal(t, "data", groups[0].Value)
+	assert.Equal(t, []string{"db1"}, hostNames(groups[0].Hosts))
+	assert.Equal(t, "payments", groups[1].Value)
+	assert.Equal(t, []string{"web1", "web2"}, hostNames(groups[1].Hosts))
+	assert.Equal(t, noMetaGroup, groups[2].Value)
+	assert.Equal(t, []string{"bare"}, hostNames(groups[2].Hosts))
+
+	assert.Equal(t, "owner=payments, rack=r12", formatMeta(map[string]string{"rack": "r12", "owner": "payments"}))
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// loadExpectScript reads the expect script of --interact from path, or
+// from in when path is "-"
+func loadExpectScript(path string, in io.Reader) ([]sshclient.ExpectStep, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(in)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read expect script: %w", err)
+	}
+	return sshclient.ParseExpectScript(string(data))
+}
+
+// handleInteract runs the command (or a login shell) with --interact,
+// answering its prompts with the expect steps, and prints the transcript,
+// also when a step failed
+func handleInteract(client *sshclient.SSHClient, config *sshclient.Config, steps []sshclient.ExpectStep) error {
+	lg := logger.GetLogger()
+	lg.Info("Running %s on %s with %d expect step(s)...", cmp.Or(config.Command, "a login shell"), config.Host, len(steps))
+	result, err := client.Interact(steps, sshclient.RequestFromConfig(config))
+	fmt.Print(result.Output)
+	if result.Truncated {
+		lg.Warning("the transcript exceeded the output limit and was truncated")
+	}
+	if err != nil {
+		return fmt.Errorf("interaction failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestLoadExpectScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "enable.expect")
+	require.NoError(t, os.WriteFile(path, []byte("expect >$\nsend enable\nexpect Password:\nsend-key core-enable\n"), 0o600))
+	steps, err := loadExpectScript(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []sshclient.ExpectStep{
+		{Expect: ">$", Send: "enable"},
+		{Expect: "Password:", SendKey: "core-enable"},
+	}, steps)
+
+	steps, err = loadExpectScript("-", strings.NewReader("send show clock\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []sshclient.ExpectStep{{Send: "show clock"}}, steps)
+
+	_, err = loadExpectScript(filepath.Join(t.TempDir(), "missing.expect"), nil)
+	assert.ErrorContains(t, err, "failed to read expect script")
+	_, err = loadExpectScript("-", strings.NewReader("# nothing\n"))
+	assert.ErrorContains(t, err, "no steps")
+}
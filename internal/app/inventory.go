@@ -0,0 +1,393 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+	"github.com/zalando/go-keyring"
+)
+
+// inventorySyncTimeout bounds one sync of a source, API calls included
+const inventorySyncTimeout = 2 * time.Minute
+
+// InventorySourceConfig is a cloud account whose running instances
+// --host-sync keeps in settings as hosts
+type InventorySourceConfig struct {
+	Name      string            `json:"name,omitempty"`       // Source name, recorded on its hosts (default: the provider)
+	Provider  string            `json:"provider"`             // aws, gcp or hetzner
+	Region    string            `json:"region,omitempty"`     // AWS region (default: AWS_REGION)
+	Project   string            `json:"project,omitempty"`    // GCP project
+	Filter    map[string]string `json:"filter,omitempty"`     // Only instances carrying these tags (labels on GCP and Hetzner)
+	GroupTags []string          `json:"group_tags,omitempty"` // Tag keys whose values become host groups (e.g. ["Role", "Env"])
+	Prefix    string            `json:"prefix,omitempty"`     // Prepended to instance names to form host names
+	User      string            `json:"user,omitempty"`       // SSH user of the synced hosts
+	Port      string            `json:"port,omitempty"`       // SSH port of the synced hosts (default: 22)
+	Key       string            `json:"key,omitempty"`        // SSH key of the synced hosts
+	PrivateIP bool              `json:"private_ip,omitempty"` // Connect to the private address instead of the public one
+	TokenKey  string            `json:"token_key,omitempty"`  // Keyring entry of the API credentials (see the provider)
+	Endpoint  string            `json:"endpoint,omitempty"`   // API base URL (default: the public API of the provider)
+	Interval  string            `json:"interval,omitempty"`   // How often the daemon syncs the source (e.g. "15m"; empty = only on demand)
+}
+
+// sourceName is the name hosts synced from the source record
+func (c *InventorySourceConfig) sourceName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Provider
+}
+
+// cloudInstance is a running instance as a provider reports it
+type cloudInstance struct {
+	ID        string
+	Name      string
+	PublicIP  string
+	PrivateIP string
+	Platform  string // "windows" for Windows instances
+	Tags      map[string]string
+}
+
+// inventoryProvider lists the running instances of a cloud account that
+// carry the tags of source.Filter
+type inventoryProvider interface {
+	Instances(ctx context.Context, source *InventorySourceConfig) ([]cloudInstance, error)
+}
+
+// inventoryProviders are the providers sources can use, by name
+var inventoryProviders = map[string]inventoryProvider{
+	"aws":     awsProvider{},
+	"gcp":     gcpProvider{},
+	"hetzner": hetznerProvider{},
+}
+
+// inventoryHTTPClient makes the API calls of the providers
+var inventoryHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// inventoryCredential reads the credentials of a source from the keyring
+// entry token_key, else from the environment variable env
+func inventoryCredential(source *InventorySourceConfig, env string) (string, error) {
+	if source.TokenKey != "" {
+		value, err := keyring.Get(sshclient.KeyringServiceName, source.TokenKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to read '%s' from the keyring: %w", source.TokenKey, err)
+		}
+		return strings.TrimSpace(value), nil
+	}
+	if value := os.Getenv(env); value != "" {
+		return value, nil
+	}
+	return "", fmt.Errorf("no credentials for %s (set token_key or %s)", source.sourceName(), env)
+}
+
+// InventorySyncSummary reports the outcome of syncing one source
+type InventorySyncSummary struct {
+	Source  string            `json:"source"`
+	Added   []string          `json:"added"`
+	Updated []string          `json:"updated"`
+	Removed []string          `json:"removed"`
+	Skipped []HostImportIssue `json:"skipped"`
+}
+
+// inventoryGet performs a provider API request and returns the body of a
+// successful response
+func inventoryGet(req *http.Request) ([]byte, error) {
+	resp, err := inventoryHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }() //nolint:errcheck // read-only body
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		message := strings.TrimSpace(string(body))
+		if len(message) > 512 {
+			message = message[:512] + "..."
+		}
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Host, resp.Status, message)
+	}
+	return body, nil
+}
+
+// selectInventorySources returns the sources named or using the provider
+// selector; an empty selector selects every source
+func selectInventorySources(settings *Settings, selector string) ([]InventorySourceConfig, error) {
+	if len(settings.Inventory) == 0 {
+		return nil, fmt.Errorf("no inventory sources configured (add them to \"inventory\" in settings.json)")
+	}
+	var sources []InventorySourceConfig
+	for _, source := range settings.Inventory {
+		if selector == "" || source.sourceName() == selector || source.Provider == selector {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no inventory source named '%s'", selector)
+	}
+	return sources, nil
+}
+
+// syncInventorySource lists the instances of source and merges them into
+// the saved settings
+func syncInventorySource(source InventorySourceConfig) (*InventorySyncSummary, error) {
+	provider, ok := inventoryProviders[source.Provider]
+	if !ok {
+		return nil, fmt.Errorf("inventory source '%s': unknown provider '%s' (use aws, gcp or hetzner)", source.sourceName(), source.Provider)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), inventorySyncTimeout)
+	defer cancel()
+	instances, err := provider.Instances(ctx, &source)
+	if err != nil {
+		return nil, fmt.Errorf("inventory source '%s': %w", source.sourceName(), err)
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings, err := LoadSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	summary := mergeInventory(settings, &source, instances)
+	if len(summary.Added)+len(summary.Updated)+len(summary.Removed) > 0 {
+		if err := SaveSettings(settings); err != nil {
+			return nil, fmt.Errorf("failed to save settings: %w", err)
+		}
+	}
+	return summary, nil
+}
+
+// mergeInventory upserts the instances of source into settings as hosts and
+// removes the hosts synced from source whose instance is gone. Hosts that
+// were not synced from source are never touched; a synced host keeps the
+// fields the sync does not own, such as its password key or safety policy.
+func mergeInventory(settings *Settings, source *InventorySourceConfig, instances []cloudInstance) *InventorySyncSummary {
+	name := source.sourceName()
+	summary := &InventorySyncSummary{Source: name, Added: []string{}, Updated: []string{}, Removed: []string{}, Skipped: []HostImportIssue{}}
+
+	seen := make(map[string]bool)
+	for _, instance := range instances {
+		candidate, err := inventoryHost(source, instance)
+		if err != nil {
+			summary.Skipped = append(summary.Skipped, HostImportIssue{Name: instance.ID, Reason: err.Error()})
+			continue
+		}
+		seen[candidate.Name] = true
+
+		existing := lookupHostByName(settings, candidate.Name)
+		if existing == nil {
+			if err := AddHost(settings, candidate); err != nil {
+				summary.Skipped = append(summary.Skipped, HostImportIssue{Name: candidate.Name, Reason: err.Error()})
+			} else {
+				summary.Added = append(summary.Added, candidate.Name)
+			}
+			continue
+		}
+		if existing.Source != name {
+			summary.Skipped = append(summary.Skipped, HostImportIssue{Name: candidate.Name, Reason: "a host with this name was not synced from " + name})
+			continue
+		}
+		updated := *existing
+		updated.Description, updated.Host, updated.Type = candidate.Description, candidate.Host, candidate.Type
+		updated.Tags, updated.Meta = candidate.Tags, candidate.Meta
+		if source.Port != "" {
+			updated.Port = source.Port
+		}
+		if source.User != "" {
+			updated.User = source.User
+		}
+		if source.Key != "" {
+			updated.Key = source.Key
+		}
+		if reflect.DeepEqual(updated, *existing) {
+			continue
+		}
+		if err := UpdateHost(settings, updated); err != nil {
+			summary.Skipped = append(summary.Skipped, HostImportIssue{Name: candidate.Name, Reason: err.Error()})
+		} else {
+			summary.Updated = append(summary.Updated, candidate.Name)
+		}
+	}
+
+	kept := settings.Hosts[:0]
+	for _, host := range settings.Hosts {
+		if host.Source == name && !seen[host.Name] {
+			summary.Removed = append(summary.Removed, host.Name)
+			continue
+		}
+		kept = append(kept, host)
+	}
+	settings.Hosts = kept
+	return summary
+}
+
+// lookupHostByName returns the configured host with the given name, or nil
+func lookupHostByName(settings *Settings, name string) *HostConfig {
+	for i := range settings.Hosts {
+		if settings.Hosts[i].Name == name {
+			return &settings.Hosts[i]
+		}
+	}
+	return nil
+}
+
+// inventoryHost turns an instance into the host the sync keeps for it: its
+// cloud tags become metadata, and the source name and the values of the
+// group tags become its groups
+func inventoryHost(source *InventorySourceConfig, instance cloudInstance) (HostConfig, error) {
+	address := instance.PublicIP
+	if source.PrivateIP || address == "" {
+		address = instance.PrivateIP
+	}
+	if source.PrivateIP && address == "" {
+		return HostConfig{}, fmt.Errorf("instance has no private address")
+	}
+	if address == "" {
+		return HostConfig{}, fmt.Errorf("instance has no address")
+	}
+
+	instanceName := inventoryName(instance.Name)
+	if instanceName == "" {
+		instanceName = instance.ID
+	}
+	host := HostConfig{
+		Name:        source.Prefix + instanceName,
+		Description: fmt.Sprintf("%s instance %s", source.Provider, instance.ID),
+		Host:        address,
+		Port:        source.Port,
+		User:        source.User,
+		Key:         source.Key,
+		Type:        "linux",
+		Tags:        []string{source.sourceName()},
+		Meta:        map[string]string{"instance_id": instance.ID},
+		Source:      source.sourceName(),
+	}
+	if instance.Platform == "windows" {
+		host.Type = "windows"
+	}
+	for key, value := range instance.Tags {
+		host.Meta[key] = value
+	}
+	for _, key := range source.GroupTags {
+		if group := inventoryName(instance.Tags[key]); group != "" && !containsString(host.Tags, group) {
+			host.Tags = append(host.Tags, group)
+		}
+	}
+	return host, nil
+}
+
+// inventoryName makes a cloud name usable as a host name or group: spaces
+// and commas, which separate host lists, become dashes
+func inventoryName(name string) string {
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}), "-")
+}
+
+// handleHostSync syncs the inventory sources selected by --host-sync
+func handleHostSync(config *sshclient.Config) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	sources, err := selectInventorySources(settings, config.SyncSource)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, source := range sources {
+		summary, err := syncInventorySource(source)
+		if err != nil {
+			logger.GetLogger().Error("%v", err)
+			failed = append(failed, source.sourceName())
+			continue
+		}
+		fmt.Print(logger.Plain(formatInventorySummary(summary)))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to sync %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// formatInventorySummary renders the outcome of syncing one source
+func formatInventorySummary(summary *InventorySyncSummary) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Sync from %s:\n", summary.Source))
+	for _, name := range summary.Added {
+		output.WriteString(fmt.Sprintf("  ✓ %s added\n", name))
+	}
+	for _, name := range summary.Updated {
+		output.WriteString(fmt.Sprintf("  ✓ %s updated\n", name))
+	}
+	for _, name := range summary.Removed {
+		output.WriteString(fmt.Sprintf("  ✓ %s removed\n", name))
+	}
+	for _, issue := range summary.Skipped {
+		output.WriteString(fmt.Sprintf("  - %s skipped: %s\n", issue.Name, issue.Reason))
+	}
+	output.WriteString(fmt.Sprintf("Summary: %d added, %d updated, %d removed, %d skipped\n",
+		len(summary.Added), len(summary.Updated), len(summary.Removed), len(summary.Skipped)))
+	return output.String()
+}
+
+// startInventorySync syncs the sources with an interval in the background,
+// once at start and then every interval, until the returned function is
+// called
+func startInventorySync(settings *Settings) func() {
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, source := range settings.Inventory {
+		if source.Interval == "" {
+			continue
+		}
+		interval := firstDuration(fmt.Sprintf("interval of inventory source '%s'", source.sourceName()), source.Interval)
+		if interval <= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(source InventorySourceConfig) {
+			defer wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				if summary, err := syncInventorySource(source); err != nil {
+					logger.GetLogger().Warning("%v", err)
+				} else if len(summary.Added)+len(summary.Updated)+len(summary.Removed) > 0 {
+					logger.GetLogger().Info("Synced %s: %d added, %d updated, %d removed",
+						summary.Source, len(summary.Added), len(summary.Updated), len(summary.Removed))
+				}
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+			}
+		}(source)
+	}
+	return func() {
+		close(stop)
+		wg.Wait()
+	}
+}
+
+// sortedTagKeys returns the keys of a filter in a stable order, so requests
+// built from it are reproducible
+func sortedTagKeys(filter map[string]string) []string {
+	keys := make([]string, 0, len(filter))
+	for key := range filter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
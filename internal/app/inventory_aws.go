@@ -0,0 +1,234 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ec2APIVersion is the version of the EC2 query API the provider speaks
+const ec2APIVersion = "2016-11-15"
+
+// awsProvider lists EC2 instances through DescribeInstances. Credentials
+// come from token_key, a keyring entry holding "<access key id>:<secret
+// access key>", or from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN.
+type awsProvider struct{}
+
+// awsCredentials sign EC2 requests
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// ec2DescribeInstancesResponse is the part of a DescribeInstances response
+// the provider reads
+type ec2DescribeInstancesResponse struct {
+	Reservations []struct {
+		Instances []struct {
+			ID        string `xml:"instanceId"`
+			PrivateIP string `xml:"privateIpAddress"`
+			PublicIP  string `xml:"ipAddress"`
+			Platform  string `xml:"platform"`
+			Tags      []struct {
+				Key   string `xml:"key"`
+				Value string `xml:"value"`
+			} `xml:"tagSet>item"`
+		} `xml:"instancesSet>item"`
+	} `xml:"reservationSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+// Instances lists the running instances carrying the filter tags
+func (awsProvider) Instances(ctx context.Context, source *InventorySourceConfig) ([]cloudInstance, error) {
+	credentials, err := awsSourceCredentials(source)
+	if err != nil {
+		return nil, err
+	}
+	region := cmp.Or(source.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	if region == "" {
+		return nil, fmt.Errorf("no AWS region (set region or AWS_REGION)")
+	}
+	endpoint := source.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://ec2.%s.amazonaws.com/", region)
+	}
+
+	query := url.Values{}
+	query.Set("Action", "DescribeInstances")
+	query.Set("Version", ec2APIVersion)
+	query.Set("Filter.1.Name", "instance-state-name")
+	query.Set("Filter.1.Value.1", "running")
+	for i, key := range sortedTagKeys(source.Filter) {
+		query.Set(fmt.Sprintf("Filter.%d.Name", i+2), "tag:"+key)
+		query.Set(fmt.Sprintf("Filter.%d.Value.1", i+2), source.Filter[key])
+	}
+
+	var instances []cloudInstance
+	for {
+		page, err := describeEC2Instances(ctx, endpoint, query, credentials, region)
+		if err != nil {
+			return nil, err
+		}
+		for _, reservation := range page.Reservations {
+			for _, item := range reservation.Instances {
+				instance := cloudInstance{
+					ID:        item.ID,
+					PublicIP:  item.PublicIP,
+					PrivateIP: item.PrivateIP,
+					Platform:  item.Platform,
+					Tags:      make(map[string]string, len(item.Tags)),
+				}
+				for _, tag := range item.Tags {
+					instance.Tags[tag.Key] = tag.Value
+				}
+				instance.Name = instance.Tags["Name"]
+				instances = append(instances, instance)
+			}
+		}
+		if page.NextToken == "" {
+			return instances, nil
+		}
+		query.Set("NextToken", page.NextToken)
+	}
+}
+
+// describeEC2Instances requests one page of DescribeInstances
+func describeEC2Instances(ctx context.Context, endpoint string, query url.Values, credentials awsCredentials, region string) (*ec2DescribeInstancesResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	signAWSRequest(req, credentials, region, "ec2", time.Now())
+	body, err := inventoryGet(req)
+	if err != nil {
+		return nil, err
+	}
+	var page ec2DescribeInstancesResponse
+	if err := xml.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("invalid DescribeInstances response: %w", err)
+	}
+	return &page, nil
+}
+
+// awsSourceCredentials reads the credentials of an aws source
+func awsSourceCredentials(source *InventorySourceConfig) (awsCredentials, error) {
+	if source.TokenKey != "" {
+		value, err := inventoryCredential(source, "")
+		if err != nil {
+			return awsCredentials{}, err
+		}
+		accessKeyID, secret, ok := strings.Cut(value, ":")
+		if !ok {
+			return awsCredentials{}, fmt.Errorf("keyring entry '%s' must hold <access key id>:<secret access key>", source.TokenKey)
+		}
+		return awsCredentials{AccessKeyID: accessKeyID, SecretAccessKey: secret}, nil
+	}
+	credentials := awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return awsCredentials{}, fmt.Errorf("no AWS credentials (set token_key or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)")
+	}
+	return credentials, nil
+}
+
+// signAWSRequest signs a request without a body with AWS Signature
+// Version 4. The host, Content-Type and X-Amz-* headers are signed.
+func signAWSRequest(req *http.Request, credentials awsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	emptyPayload := sha256.Sum256(nil)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(emptyPayload[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// awsCanonicalQuery encodes a query string the way Signature Version 4
+// expects: sorted, with RFC 3986 escaping
+func awsCanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape escapes everything but the RFC 3986 unreserved characters
+func awsEscape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
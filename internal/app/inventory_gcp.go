@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// gcpComputeAPI is the base URL of the Compute Engine API
+const gcpComputeAPI = "https://compute.googleapis.com/compute/v1"
+
+// gcpProvider lists Compute Engine instances of a project in every zone.
+// The OAuth access token comes from token_key or GOOGLE_OAUTH_ACCESS_TOKEN
+// (e.g. from `gcloud auth print-access-token`).
+type gcpProvider struct{}
+
+// gcpAggregatedInstances is the part of an aggregated instances list the
+// provider reads
+type gcpAggregatedInstances struct {
+	Items map[string]struct {
+		Instances []struct {
+			ID                json.Number       `json:"id"`
+			Name              string            `json:"name"`
+			Labels            map[string]string `json:"labels"`
+			NetworkInterfaces []struct {
+				NetworkIP     string `json:"networkIP"`
+				AccessConfigs []struct {
+					NatIP string `json:"natIP"`
+				} `json:"accessConfigs"`
+			} `json:"networkInterfaces"`
+		} `json:"instances"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// Instances lists the running instances carrying the filter labels
+func (gcpProvider) Instances(ctx context.Context, source *InventorySourceConfig) ([]cloudInstance, error) {
+	if source.Project == "" {
+		return nil, fmt.Errorf("no GCP project (set project)")
+	}
+	token, err := inventoryCredential(source, "GOOGLE_OAUTH_ACCESS_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	endpoint := strings.TrimSuffix(source.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = gcpComputeAPI
+	}
+
+	conditions := []string{`(status = "RUNNING")`}
+	for _, key := range sortedTagKeys(source.Filter) {
+		conditions = append(conditions, fmt.Sprintf(`(labels.%s = "%s")`, key, source.Filter[key]))
+	}
+	query := url.Values{}
+	query.Set("filter", strings.Join(conditions, " "))
+
+	var instances []cloudInstance
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			fmt.Sprintf("%s/projects/%s/aggregated/instances?%s", endpoint, url.PathEscape(source.Project), query.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		body, err := inventoryGet(req)
+		if err != nil {
+			return nil, err
+		}
+		var page gcpAggregatedInstances
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("invalid instances response: %w", err)
+		}
+		for _, zone := range page.Items {
+			for _, item := range zone.Instances {
+				instance := cloudInstance{ID: item.ID.String(), Name: item.Name, Tags: item.Labels}
+				if len(item.NetworkInterfaces) > 0 {
+					nic := item.NetworkInterfaces[0]
+					instance.PrivateIP = nic.NetworkIP
+					if len(nic.AccessConfigs) > 0 {
+						instance.PublicIP = nic.AccessConfigs[0].NatIP
+					}
+				}
+				instances = append(instances, instance)
+			}
+		}
+		if page.NextPageToken == "" {
+			return instances, nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
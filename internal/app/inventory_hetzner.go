@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// hetznerCloudAPI is the base URL of the Hetzner Cloud API
+const hetznerCloudAPI = "https://api.hetzner.cloud/v1"
+
+// hetznerProvider lists Hetzner Cloud servers. The API token comes from
+// token_key or HCLOUD_TOKEN.
+type hetznerProvider struct{}
+
+// hetznerServers is the part of a servers list the provider reads
+type hetznerServers struct {
+	Servers []struct {
+		ID        int64             `json:"id"`
+		Name      string            `json:"name"`
+		Labels    map[string]string `json:"labels"`
+		PublicNet struct {
+			IPv4 *struct {
+				IP string `json:"ip"`
+			} `json:"ipv4"`
+		} `json:"public_net"`
+		PrivateNet []struct {
+			IP string `json:"ip"`
+		} `json:"private_net"`
+	} `json:"servers"`
+	Meta struct {
+		Pagination struct {
+			NextPage *int `json:"next_page"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+// Instances lists the running servers carrying the filter labels
+func (hetznerProvider) Instances(ctx context.Context, source *InventorySourceConfig) ([]cloudInstance, error) {
+	token, err := inventoryCredential(source, "HCLOUD_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	endpoint := strings.TrimSuffix(source.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = hetznerCloudAPI
+	}
+
+	query := url.Values{}
+	query.Set("status", "running")
+	query.Set("per_page", "50")
+	if len(source.Filter) > 0 {
+		selectors := make([]string, 0, len(source.Filter))
+		for _, key := range sortedTagKeys(source.Filter) {
+			selectors = append(selectors, key+"="+source.Filter[key])
+		}
+		query.Set("label_selector", strings.Join(selectors, ","))
+	}
+
+	var instances []cloudInstance
+	for page := 1; ; {
+		query.Set("page", strconv.Itoa(page))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/servers?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		body, err := inventoryGet(req)
+		if err != nil {
+			return nil, err
+		}
+		var servers hetznerServers
+		if err := json.Unmarshal(body, &servers); err != nil {
+			return nil, fmt.Errorf("invalid servers response: %w", err)
+		}
+		for _, server := range servers.Servers {
+			instance := cloudInstance{ID: strconv.FormatInt(server.ID, 10), Name: server.Name, Tags: server.Labels}
+			if server.PublicNet.IPv4 != nil {
+				instance.PublicIP = server.PublicNet.IPv4.IP
+			}
+			if len(server.PrivateNet) > 0 {
+				instance.PrivateIP = server.PrivateNet[0].IP
+			}
+			instances = append(instances, instance)
+		}
+		next := servers.Meta.Pagination.NextPage
+		if next == nil || *next <= page {
+			return instances, nil
+		}
+		page = *next
+	}
+}
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAWSRequest(t *testing.T) {
+	// The example request of the Signature Version 4 documentation
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	credentials := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWSRequest(req, credentials, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7", req.Header.Get("Authorization"))
+}
+
+func TestMergeInventory(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "db1", Host: "10.0.0.9", Port: "22"},
+		{Name: "aws-web", Host: "10.0.0.1", Port: "22", PasswordKey: "web-pw", Source: "aws", Tags: []string{"aws"}},
+		{Name: "aws-gone", Host: "10.0.0.2", Port: "22", Source: "aws"},
+		{Name: "aws-manual", Host: "10.0.0.3", Port: "22"},
+	}}
+	source := &InventorySourceConfig{Provider: "aws", Prefix: "aws-", GroupTags: []string{"Role"}, User: "ec2-user"}
+	instances := []cloudInstance{
+		{ID: "i-1", Name: "web", PublicIP: "54.0.0.1", PrivateIP: "10.0.0.1", Tags: map[string]string{"Name": "web", "Role": "front end"}},
+		{ID: "i-2", PrivateIP: "10.0.0.4", Platform: "windows", Tags: map[string]string{"Role": "ad"}},
+		{ID: "i-3", Name: "manual", PrivateIP: "10.0.0.5"},
+		{ID: "i-4", Name: "lost"},
+	}
+
+	summary := mergeInventory(settings, source, instances)
+	assert.Equal(t, []string{"aws-i-2"}, summary.Added)
+	assert.Equal(t, []string{"aws-web"}, summary.Updated)
+	assert.Equal(t, []string{"aws-gone"}, summary.Removed)
+	require.Len(t, summary.Skipped, 2)
+	assert.Equal(t, "aws-manual", summary.Skipped[0].Name)
+	assert.Equal(t, "i-4", summary.Skipped[1].Name)
+
+	web := lookupHostByName(settings, "aws-web")
+	require.NotNil(t, web)
+	assert.Equal(t, "54.0.0.1", web.Host)
+	assert.Equal(t, "ec2-user", web.User)
+	assert.Equal(t, "web-pw", web.PasswordKey, "fields the sync does not own are kept")
+	assert.Equal(t, []string{"aws", "front-end"}, web.Tags)
+	assert.Equal(t, map[string]string{"instance_id": "i-1", "Name": "web", "Role": "front end"}, web.Meta)
+
+	added := lookupHostByName(settings, "aws-i-2")
+	require.NotNil(t, added)
+	assert.Equal(t, "10.0.0.4", added.Host)
+	assert.Equal(t, "windows", added.Type)
+	assert.Equal(t, "aws", added.Source)
+	assert.Nil(t, lookupHostByName(settings, "aws-gone"))
+	assert.Equal(t, "10.0.0.3", lookupHostByName(settings, "aws-manual").Host)
+
+	// A second sync with the same instances changes nothing
+	summary = mergeInventory(settings, source, instances)
+	assert.Empty(t, summary.Added)
+	assert.Empty(t, summary.Updated)
+	assert.Empty(t, summary.Removed)
+}
+
+func TestAWSProvider_Instances(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=AKID/")
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("NextToken") == "" {
+			_, _ = w.Write([]byte(`<DescribeInstancesResponse><reservationSet><item><instancesSet><item>
+				<instanceId>i-1</instanceId><privateIpAddress>10.0.0.1</privateIpAddress><ipAddress>54.0.0.1</ipAddress>
+				<tagSet><item><key>Name</key><value>web</value></item><item><key>Env</key><value>prod</value></item></tagSet>
+				</item></instancesSet></item></reservationSet><nextToken>page2</nextToken></DescribeInstancesResponse>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<DescribeInstancesResponse><reservationSet><item><instancesSet><item>
+			<instanceId>i-2</instanceId><privateIpAddress>10.0.0.2</privateIpAddress><platform>windows</platform>
+			</item></instancesSet></item></reservationSet></DescribeInstancesResponse>`))
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	source := &InventorySourceConfig{Provider: "aws", Region: "eu-west-1", Endpoint: server.URL + "/", Filter: map[string]string{"Env": "prod"}}
+	instances, err := awsProvider{}.Instances(context.Background(), source)
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, cloudInstance{ID: "i-1", Name: "web", PublicIP: "54.0.0.1", PrivateIP: "10.0.0.1",
+		Tags: map[string]string{"Name": "web", "Env": "prod"}}, instances[0])
+	assert.Equal(t, "windows", instances[1].Platform)
+	require.Len(t, queries, 2)
+	assert.Contains(t, queries[0], "Filter.2.Name=tag%3AEnv")
+	assert.Contains(t, queries[0], "Filter.2.Value.1=prod")
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	_, err = awsProvider{}.Instances(context.Background(), source)
+	assert.ErrorContains(t, err, "no AWS credentials")
+}
+
+func TestGCPProvider_Instances(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer gcp-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "/projects/acme/aggregated/instances", r.URL.Path)
+		assert.Equal(t, `(status = "RUNNING") (labels.env = "prod")`, r.URL.Query().Get("filter"))
+		_, _ = w.Write([]byte(`{"items": {"zones/europe-west1-b": {"instances": [{"id": "4711", "name": "api-1",
+			"labels": {"env": "prod"}, "networkInterfaces": [{"networkIP": "10.1.0.2", "accessConfigs": [{"natIP": "34.0.0.2"}]}]}]},
+			"zones/us-east1-c": {"warning": {"code": "NO_RESULTS_ON_PAGE"}}}}`))
+	}))
+	defer server.Close()
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "gcp-token")
+
+	source := &InventorySourceConfig{Provider: "gcp", Project: "acme", Endpoint: server.URL, Filter: map[string]string{"env": "prod"}}
+	instances, err := gcpProvider{}.Instances(context.Background(), source)
+	require.NoError(t, err)
+	assert.Equal(t, []cloudInstance{{ID: "4711", Name: "api-1", PublicIP: "34.0.0.2", PrivateIP: "10.1.0.2",
+		Tags: map[string]string{"env": "prod"}}}, instances)
+
+	_, err = gcpProvider{}.Instances(context.Background(), &InventorySourceConfig{Provider: "gcp"})
+	assert.ErrorContains(t, err, "no GCP project")
+}
+
+func TestSyncInventorySource_Hetzner(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer hcloud-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "role=runner", r.URL.Query().Get("label_selector"))
+		if r.URL.Query().Get("page") == "1" {
+			_, _ = w.Write([]byte(`{"servers": [{"id": 1, "name": "runner-1", "labels": {"role": "runner"},
+				"public_net": {"ipv4": {"ip": "95.0.0.1"}}, "private_net": []}], "meta": {"pagination": {"next_page": 2}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"servers": [{"id": 2, "name": "runner-2", "labels": {"role": "runner"},
+			"public_net": {"ipv4": null}, "private_net": [{"ip": "10.2.0.2"}]}], "meta": {"pagination": {"next_page": null}}}`))
+	}))
+	defer server.Close()
+	t.Setenv("HCLOUD_TOKEN", "hcloud-token")
+
+	source := InventorySourceConfig{Name: "ci", Provider: "hetzner", Endpoint: server.URL, Filter: map[string]string{"role": "runner"}, GroupTags: []string{"role"}}
+	require.NoError(t, SaveSettings(&Settings{Inventory: []InventorySourceConfig{source}}))
+
+	settings, err := LoadSettings()
+	require.NoError(t, err)
+	selected, err := selectInventorySources(settings, "hetzner")
+	require.NoError(t, err)
+	require.Len(t, selected, 1)
+	_, err = selectInventorySources(settings, "aws")
+	assert.Error(t, err)
+
+	summary, err := syncInventorySource(selected[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"runner-1", "runner-2"}, summary.Added)
+
+	settings, err = LoadSettings()
+	require.NoError(t, err)
+	require.Len(t, settings.Hosts, 2)
+	assert.Equal(t, "95.0.0.1", settings.Hosts[0].Host)
+	assert.Equal(t, "10.2.0.2", settings.Hosts[1].Host)
+	assert.Equal(t, []string{"ci", "runner"}, settings.Hosts[1].Tags)
+	hosts, err := ResolveHostGroup(settings, "runner")
+	require.NoError(t, err)
+	assert.Len(t, hosts, 2)
+
+	_, err = syncInventorySource(InventorySourceConfig{Provider: "openstack"})
+	assert.ErrorContains(t, err, "unknown provider")
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	// k8sNodeTag marks the configured hosts the Kubernetes node tools may
+	// run on
+	k8sNodeTag = "k8s-node"
+	// defaultKubeletLogLines is how many lines k8s_kubelet_logs returns
+	// without lines
+	defaultKubeletLogLines = 200
+	// k8sMirrorPodAnnotation marks static pods, which drains leave alone
+	k8sMirrorPodAnnotation = "kubernetes.io/config.mirror"
+)
+
+// requireK8sNode refuses targets that are not configured hosts tagged
+// k8s-node, so the node tools never run on arbitrary addresses
+func requireK8sNode(settings *Settings, target string) error {
+	host := lookupHost(settings, target)
+	if host == nil || !host.HasTag(k8sNodeTag) {
+		return fmt.Errorf("%s is not a configured host tagged %s", target, k8sNodeTag)
+	}
+	return nil
+}
+
+// K8sNodeStatus summarizes a node and its drain progress
+type K8sNodeStatus struct {
+	Node           string             `json:"node"`
+	Ready          bool               `json:"ready"`
+	Cordoned       bool               `json:"cordoned"`
+	Drained        bool               `json:"drained"` // Cordoned with no evictable pods left
+	KubeletVersion string             `json:"kubelet_version,omitempty"`
+	Problems       []K8sNodeCondition `json:"problems,omitempty"` // Conditions reporting a problem
+	Taints         []string           `json:"taints,omitempty"`
+	Pods           int                `json:"pods"`
+	EvictablePods  []string           `json:"evictable_pods,omitempty"` // namespace/name of pods a drain would evict
+}
+
+// K8sNodeCondition is a node condition reporting a problem
+type K8sNodeCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// k8sNode is the part of a kubectl node object the summary needs
+type k8sNode struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Unschedulable bool `json:"unschedulable"`
+		Taints        []struct {
+			Key    string `json:"key"`
+			Value  string `json:"value"`
+			Effect string `json:"effect"`
+		} `json:"taints"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []K8sNodeCondition `json:"conditions"`
+		NodeInfo   struct {
+			KubeletVersion string `json:"kubeletVersion"`
+		} `json:"nodeInfo"`
+	} `json:"status"`
+}
+
+// k8sPodList is the part of a kubectl pod list the summary needs
+type k8sPodList struct {
+	Items []struct {
+		Metadata struct {
+			Namespace       string            `json:"namespace"`
+			Name            string            `json:"name"`
+			Annotations     map[string]string `json:"annotations"`
+			OwnerReferences []struct {
+				Kind string `json:"kind"`
+			} `json:"ownerReferences"`
+		} `json:"metadata"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// summarizeK8sNode builds the status of a node from the JSON printed by
+// kubectl get node and kubectl get pods. Like kubectl drain, it does not
+// count DaemonSet pods, static (mirror) pods and finished pods as evictable.
+func summarizeK8sNode(nodeJSON, podsJSON []byte) (*K8sNodeStatus, error) {
+	var node k8sNode
+	if err := json.Unmarshal(nodeJSON, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse node: %w", err)
+	}
+	var pods k8sPodList
+	if err := json.Unmarshal(podsJSON, &pods); err != nil {
+		return nil, fmt.Errorf("failed to parse pods: %w", err)
+	}
+
+	status := &K8sNodeStatus{
+		Node:           node.Metadata.Name,
+		Cordoned:       node.Spec.Unschedulable,
+		KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+		Pods:           len(pods.Items),
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == "Ready" {
+			status.Ready = condition.Status == "True"
+			if status.Ready {
+				continue
+			}
+		} else if condition.Status != "True" {
+			// Pressure and unavailability conditions report problems as True
+			continue
+		}
+		status.Problems = append(status.Problems, condition)
+	}
+	for _, taint := range node.Spec.Taints {
+		entry := taint.Key
+		if taint.Value != "" {
+			entry += "=" + taint.Value
+		}
+		status.Taints = append(status.Taints, entry+":"+taint.Effect)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+			continue
+		}
+		if _, mirror := pod.Metadata.Annotations[k8sMirrorPodAnnotation]; mirror {
+			continue
+		}
+		daemon := false
+		for _, owner := range pod.Metadata.OwnerReferences {
+			daemon = daemon || owner.Kind == "DaemonSet"
+		}
+		if !daemon {
+			status.EvictablePods = append(status.EvictablePods, pod.Metadata.Namespace+"/"+pod.Metadata.Name)
+		}
+	}
+	sort.Strings(status.EvictablePods)
+	status.Drained = status.Cordoned && len(status.EvictablePods) == 0
+	return status, nil
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNodeJSON = `{
+  "metadata": {"name": "node1"},
+  "spec": {"unschedulable": true, "taints": [
+    {"key": "node.kubernetes.io/unschedulable", "effect": "NoSchedule"},
+    {"key": "dedicated", "value": "gpu", "effect": "NoExecute"}
+  ]},
+  "status": {
+    "conditions": [
+      {"type": "MemoryPressure", "status": "False"},
+      {"type": "DiskPressure", "status": "True", "reason": "KubeletHasDiskPressure", "message": "ephemeral storage low"},
+      {"type": "Ready", "status": "True"}
+    ],
+    "nodeInfo": {"kubeletVersion": "v1.30.2"}
+  }
+}`
+
+func testPodsJSON(pods ...string) string {
+	items := ""
+	for i, pod := range pods {
+		if i > 0 {
+			items += ","
+		}
+		items += pod
+	}
+	return `{"items": [` + items + `]}`
+}
+
+func TestSummarizeK8sNode(t *testing.T) {
+	daemon := `{"metadata": {"namespace": "kube-system", "name": "kube-proxy-x", "ownerReferences": [{"kind": "DaemonSet"}]}, "status": {"phase": "Running"}}`
+	mirror := `{"metadata": {"namespace": "kube-system", "name": "etcd-node1", "annotations": {"kubernetes.io/config.mirror": "abc"}}, "status": {"phase": "Running"}}`
+	done := `{"metadata": {"namespace": "batch", "name": "job-1"}, "status": {"phase": "Succeeded"}}`
+	web := `{"metadata": {"namespace": "shop", "name": "web-5d8", "ownerReferences": [{"kind": "ReplicaSet"}]}, "status": {"phase": "Running"}}`
+
+	status, err := summarizeK8sNode([]byte(testNodeJSON), []byte(testPodsJSON(daemon, mirror, done, web)))
+	require.NoError(t, err)
+	assert.Equal(t, "node1", status.Node)
+	assert.True(t, status.Ready)
+	assert.True(t, status.Cordoned)
+	assert.False(t, status.Drained)
+	assert.Equal(t, "v1.30.2", status.KubeletVersion)
+	assert.Equal(t, []K8sNodeCondition{{Type: "DiskPressure", Status: "True", Reason: "KubeletHasDiskPressure", Message: "ephemeral storage low"}}, status.Problems)
+	assert.Equal(t, []string{"node.kubernetes.io/unschedulable:NoSchedule", "dedicated=gpu:NoExecute"}, status.Taints)
+	assert.Equal(t, 4, status.Pods)
+	assert.Equal(t, []string{"shop/web-5d8"}, status.EvictablePods)
+
+	status, err = summarizeK8sNode([]byte(testNodeJSON), []byte(testPodsJSON(daemon, mirror)))
+	require.NoError(t, err)
+	assert.True(t, status.Drained)
+
+	_, err = summarizeK8sNode([]byte("error: the server doesn't have a resource type"), []byte(testPodsJSON()))
+	assert.ErrorContains(t, err, "failed to parse node")
+}
+
+func TestRequireK8sNode(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "node1", Host: "10.0.0.11", Tags: []string{"k8s-node", "prod"}},
+		{Name: "db1", Host: "10.0.0.21"},
+	}}
+	assert.NoError(t, requireK8sNode(settings, "node1"))
+	assert.NoError(t, requireK8sNode(settings, "10.0.0.11"))
+	assert.ErrorContains(t, requireK8sNode(settings, "db1"), "not a configured host tagged k8s-node")
+	assert.Error(t, requireK8sNode(settings, "10.9.9.9"))
+}
@@ -0,0 +1,285 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zalando/go-keyring"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// keygenVerifyTimeout bounds the key-only login that checks an enrolled key
+const keygenVerifyTimeout = 15 * time.Second
+
+// keygenOptions are the options of sshx keygen
+type keygenOptions struct {
+	Type          string // One of sshclient.KeyTypes
+	Out           string // Private key path as written to settings (the public key gets .pub)
+	Comment       string
+	PassphraseKey string // Keyring entry of the passphrase (empty = no passphrase)
+	Enroll        string // Hosts or groups to install the key on
+}
+
+// enrollResult is the outcome of enrolling the key on one host
+type enrollResult struct {
+	Host     string
+	Added    bool // The key was new on the host
+	Verified bool // A login with only the new key worked
+	Err      error
+}
+
+// parseKeygenArgs reads the options following sshx keygen
+func parseKeygenArgs(args []string) (keygenOptions, error) {
+	opts := keygenOptions{Type: sshclient.KeyTypeEd25519}
+	for _, arg := range args {
+		name, value, _ := strings.Cut(arg, "=")
+		switch name {
+		case "--type":
+			opts.Type = value
+		case "--out", "-f":
+			opts.Out = value
+		case "--comment", "-C":
+			opts.Comment = value
+		case "--passphrase-key":
+			opts.PassphraseKey = value
+		case "--enroll":
+			opts.Enroll = value
+		default:
+			return opts, fmt.Errorf("unknown keygen option '%s' (use --type, --out, --comment, --passphrase-key or --enroll)", arg)
+		}
+	}
+	if !slices.Contains(sshclient.KeyTypes, opts.Type) {
+		return opts, fmt.Errorf("unsupported key type '%s' (use %s)", opts.Type, strings.Join(sshclient.KeyTypes, ", "))
+	}
+	if opts.Out == "" {
+		opts.Out = "~/.ssh/sshx_" + opts.Type
+	}
+	if opts.Comment == "" {
+		opts.Comment = defaultKeyComment()
+	}
+	return opts, nil
+}
+
+// defaultKeyComment is user@hostname, like ssh-keygen
+func defaultKeyComment() string {
+	user := os.Getenv("USER")
+	if user == "" {
+		user = os.Getenv("USERNAME")
+	}
+	hostname, _ := os.Hostname() //nolint:errcheck // the comment is informational
+	return strings.Trim(user+"@"+hostname, "@")
+}
+
+// handleKeygen generates a keypair (or reuses the one at --out) and, with
+// --enroll, installs it on each host with its current credentials, checks
+// that the key alone logs in and then makes it the host's key in settings
+func handleKeygen(args []string) error {
+	opts, err := parseKeygenArgs(args)
+	if err != nil {
+		return err
+	}
+	passphrase, err := keyPassphrase(opts.PassphraseKey)
+	if err != nil {
+		return err
+	}
+	publicKey, created, err := ensureKeypair(opts, passphrase)
+	if err != nil {
+		return err
+	}
+	lg := logger.GetLogger()
+	if created {
+		lg.Success("Generated %s key %s", opts.Type, opts.Out)
+	} else {
+		lg.Info("Using existing key %s", opts.Out)
+	}
+	fmt.Println(publicKey)
+
+	if opts.Enroll == "" {
+		return nil
+	}
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	results, err := enrollKey(settings, opts, publicKey)
+	if err != nil {
+		return err
+	}
+	fmt.Print(logger.Plain(formatEnrollResults(opts.Out, results)))
+	for _, result := range results {
+		if result.Err != nil {
+			return fmt.Errorf("enrollment failed on %d host(s)", countEnrollFailures(results))
+		}
+	}
+	return nil
+}
+
+// keyPassphrase returns the passphrase stored under name in the keyring,
+// generating and storing a random one the first time (empty name = none)
+func keyPassphrase(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	passphrase, err := keyring.Get(sshclient.KeyringServiceName, name)
+	if err == nil && passphrase != "" {
+		return passphrase, nil
+	}
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("failed to read passphrase from keyring: %w", err)
+	}
+
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate passphrase: %w", err)
+	}
+	passphrase = base64.RawStdEncoding.EncodeToString(random)
+	if err := keyring.Set(sshclient.KeyringServiceName, name, passphrase); err != nil {
+		return "", fmt.Errorf("failed to store passphrase in keyring: %w", err)
+	}
+	sshclient.ForgetSudoPassword(name)
+	logger.GetLogger().Success("Stored a new key passphrase in the keyring as '%s'", name)
+	return passphrase, nil
+}
+
+// ensureKeypair writes a new keypair to opts.Out, or returns the public key
+// of the one already there so enrollment can be re-run
+func ensureKeypair(opts keygenOptions, passphrase string) (publicKey string, created bool, err error) {
+	path := expandHome(opts.Out)
+	if existing, readErr := os.ReadFile(path); readErr == nil { // #nosec G304 -- key path chosen by the user
+		if data, pubErr := os.ReadFile(path + ".pub"); pubErr == nil { // #nosec G304 -- next to the private key
+			return strings.TrimSpace(string(data)), false, nil
+		}
+		publicKey, err = sshclient.PublicKeyOf(existing, opts.PassphraseKey, opts.Comment)
+		if err != nil {
+			return "", false, fmt.Errorf("existing key %s: %w", opts.Out, err)
+		}
+		return publicKey, false, nil
+	} else if !os.IsNotExist(readErr) {
+		return "", false, fmt.Errorf("failed to read %s: %w", opts.Out, readErr)
+	}
+
+	privateKey, publicKey, err := sshclient.GenerateKey(opts.Type, opts.Comment, passphrase)
+	if err != nil {
+		return "", false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", false, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	if err := os.WriteFile(path, privateKey, 0o600); err != nil {
+		return "", false, fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(path+".pub", []byte(publicKey+"\n"), 0o644); err != nil { // #nosec G306 -- public key
+		return "", false, fmt.Errorf("failed to write public key: %w", err)
+	}
+	return publicKey, true, nil
+}
+
+// enrollKey installs publicKey on the hosts of opts.Enroll with their
+// current credentials, verifies a login with only the new key and points
+// the verified hosts at it in settings
+func enrollKey(settings *Settings, opts keygenOptions, publicKey string) ([]enrollResult, error) {
+	hosts, err := ResolveHostGroup(settings, opts.Enroll)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]enrollResult, len(hosts))
+	updated := false
+	for i := range hosts {
+		host := &hosts[i]
+		results[i] = enrollHost(settings, host, opts, publicKey)
+		if results[i].Err != nil {
+			continue
+		}
+		host.Key = opts.Out
+		host.KeyPassphraseKey = opts.PassphraseKey
+		if err := UpdateHost(settings, *host); err != nil {
+			results[i].Err = fmt.Errorf("failed to update settings: %w", err)
+			continue
+		}
+		updated = true
+	}
+	if updated {
+		if err := SaveSettings(settings); err != nil {
+			return results, fmt.Errorf("failed to save settings: %w", err)
+		}
+	}
+	return results, nil
+}
+
+// enrollHost adds the key for the login user of host and logs in with it
+func enrollHost(settings *Settings, host *HostConfig, opts keygenOptions, publicKey string) (result enrollResult) {
+	result.Host = host.Name
+	config := newHostSSHConfig(host, settings, &sshclient.Config{UseKeyAuth: true, SafetyCheck: true, Source: "cli"})
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if result.Err = client.ConnectDirect(); result.Err != nil {
+		return result
+	}
+	_, result.Added, result.Err = client.AddAuthorizedKey("", publicKey, sshclient.RequestFromConfig(config))
+	_ = client.ForceClose() //nolint:errcheck // the next login uses a new connection
+	if result.Err != nil {
+		return result
+	}
+
+	// Log in again offering only the new key
+	verify := *config
+	verify.KeyPath = opts.Out
+	verify.KeyPassphraseKey = opts.PassphraseKey
+	verify.Password = ""
+	verify.DialTimeout = keygenVerifyTimeout
+	client, err = sshclient.NewSSHClient(&verify)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer func() { _ = client.ForceClose() }() //nolint:errcheck // verification only
+	if err := client.ConnectDirect(); err != nil {
+		result.Err = fmt.Errorf("key was installed but login with it failed: %w", err)
+		return result
+	}
+	result.Verified = true
+	return result
+}
+
+// formatEnrollResults renders the enrollment of key as a report
+func formatEnrollResults(key string, results []enrollResult) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Key enrollment of %s (%d hosts):\n", key, len(results)))
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			output.WriteString(fmt.Sprintf("  ❌ %s failed: %v\n", result.Host, result.Err))
+		case result.Added:
+			output.WriteString(fmt.Sprintf("  ✓ %s key added and verified\n", result.Host))
+		default:
+			output.WriteString(fmt.Sprintf("  ✓ %s key already present, verified\n", result.Host))
+		}
+	}
+	enrolled := len(results) - countEnrollFailures(results)
+	output.WriteString(fmt.Sprintf("Summary: %d/%d hosts now use %s\n", enrolled, len(results), key))
+	return output.String()
+}
+
+func countEnrollFailures(results []enrollResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
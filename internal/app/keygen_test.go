@@ -0,0 +1,211 @@
+package app
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestParseKeygenArgs(t *testing.T) {
+	opts, err := parseKeygenArgs(nil)
+	require.NoError(t, err)
+	assert.Equal(t, sshclient.KeyTypeEd25519, opts.Type)
+	assert.Equal(t, "~/.ssh/sshx_ed25519", opts.Out)
+	assert.NotEmpty(t, opts.Comment)
+
+	opts, err = parseKeygenArgs([]string{"--type=ecdsa", "--out=/keys/ops", "--comment=ops", "--passphrase-key=ops-key", "--enroll=web1,db"})
+	require.NoError(t, err)
+	assert.Equal(t, keygenOptions{Type: "ecdsa", Out: "/keys/ops", Comment: "ops", PassphraseKey: "ops-key", Enroll: "web1,db"}, opts)
+
+	_, err = parseKeygenArgs([]string{"--type=dsa"})
+	assert.ErrorContains(t, err, "unsupported key type 'dsa'")
+	_, err = parseKeygenArgs([]string{"--bits=4096"})
+	assert.ErrorContains(t, err, "unknown keygen option")
+}
+
+func TestEnsureKeypair(t *testing.T) {
+	keyring.MockInit()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	opts := keygenOptions{Type: sshclient.KeyTypeEd25519, Out: "~/.ssh/sshx_ed25519", Comment: "ops@laptop", PassphraseKey: "sshx-key"}
+
+	passphrase, err := keyPassphrase(opts.PassphraseKey)
+	require.NoError(t, err)
+	require.NotEmpty(t, passphrase)
+	again, err := keyPassphrase(opts.PassphraseKey)
+	require.NoError(t, err)
+	assert.Equal(t, passphrase, again, "the stored passphrase is reused")
+
+	publicKey, created, err := ensureKeypair(opts, passphrase)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.True(t, strings.HasPrefix(publicKey, "ssh-ed25519 "))
+	assert.True(t, strings.HasSuffix(publicKey, " ops@laptop"))
+
+	path := filepath.Join(home, ".ssh", "sshx_ed25519")
+	for file, mode := range map[string]os.FileMode{path: 0o600, path + ".pub": 0o644, filepath.Dir(path): 0o700} {
+		info, err := os.Stat(file)
+		require.NoError(t, err)
+		assert.Equal(t, mode, info.Mode().Perm(), file)
+	}
+
+	// The private key needs the passphrase from the keyring
+	data, err := os.ReadFile(path) // #nosec G304 -- test file
+	require.NoError(t, err)
+	_, err = sshclient.ParsePrivateKey(data, "")
+	assert.ErrorContains(t, err, "passphrase")
+	_, err = sshclient.ParsePrivateKey(data, opts.PassphraseKey)
+	require.NoError(t, err)
+
+	// Re-running reuses the key, also without its .pub file
+	require.NoError(t, os.Remove(path+".pub"))
+	reused, created, err := ensureKeypair(opts, passphrase)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, publicKey, reused)
+}
+
+// startEnrollServer runs an SSH server that accepts the password "secret"
+// and the public keys installed by AddAuthorizedKeyCommand, and trusts its
+// host key in ~/.ssh/known_hosts
+func startEnrollServer(t *testing.T, home string) (port string, authorized func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	keys := map[string]bool{}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hostKey, err := ssh.NewSignerFromKey(privateKey)
+	require.NoError(t, err)
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if keys[string(key.Marshal())] {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	serverConfig.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					channel, requests, err := newChannel.Accept()
+					if err != nil {
+						return
+					}
+					for req := range requests {
+						if req.Type != "exec" {
+							_ = req.Reply(false, nil)
+							continue
+						}
+						_ = req.Reply(true, nil)
+						command := string(req.Payload[4:])
+						if start := strings.Index(command, "'ssh-ed25519 "); start >= 0 {
+							line := command[start+1:]
+							line = line[:strings.Index(line, "'")]
+							key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+							if err == nil {
+								mu.Lock()
+								keys[string(key.Marshal())] = true
+								mu.Unlock()
+							}
+						}
+						_, _ = channel.Write([]byte("added\n"))
+						_, _ = channel.SendRequest("exit-status", false, []byte{0, 0, 0, 0})
+						_ = channel.Close()
+					}
+				}
+			}()
+		}
+	}()
+
+	address := listener.Addr().String()
+	_, port, err = net.SplitHostPort(address)
+	require.NoError(t, err)
+	line := knownhosts.Line([]string{knownhosts.Normalize(address)}, hostKey.PublicKey())
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".ssh"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), []byte(line+"\n"), 0o600))
+	return port, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(keys)
+	}
+}
+
+func TestEnrollKey(t *testing.T) {
+	keyring.MockInit()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, keyring.Set(sshclient.KeyringServiceName, "web1-login", "secret"))
+	port, authorized := startEnrollServer(t, home)
+
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "web1", Host: "127.0.0.1", Port: port, User: "deploy", PasswordKey: "web1-login"},
+		{Name: "web2", Host: "127.0.0.2", Port: port, User: "deploy"},
+	}}
+	require.NoError(t, SaveSettings(settings))
+	opts := keygenOptions{Type: sshclient.KeyTypeEd25519, Out: "~/.ssh/sshx_ed25519", Comment: "ops", PassphraseKey: "sshx-key"}
+	passphrase, err := keyPassphrase(opts.PassphraseKey)
+	require.NoError(t, err)
+	publicKey, _, err := ensureKeypair(opts, passphrase)
+	require.NoError(t, err)
+
+	results, err := enrollKey(settings, keygenOptions{Out: opts.Out, PassphraseKey: opts.PassphraseKey, Enroll: "web1"}, publicKey)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	assert.True(t, results[0].Added)
+	assert.True(t, results[0].Verified)
+	assert.Equal(t, 1, authorized())
+
+	// Only the verified host now prefers the new key
+	saved, err := LoadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, "~/.ssh/sshx_ed25519", saved.Hosts[0].Key)
+	assert.Equal(t, "sshx-key", saved.Hosts[0].KeyPassphraseKey)
+	assert.Empty(t, saved.Hosts[1].Key)
+
+	report := formatEnrollResults(opts.Out, append(results, enrollResult{Host: "web2", Err: assert.AnError}))
+	assert.Contains(t, report, "✓ web1 key added and verified")
+	assert.Contains(t, report, "❌ web2 failed")
+	assert.Contains(t, report, "Summary: 1/2 hosts now use ~/.ssh/sshx_ed25519")
+
+	_, err = enrollKey(settings, keygenOptions{Out: opts.Out, Enroll: "nope"}, publicKey)
+	assert.Error(t, err)
+}
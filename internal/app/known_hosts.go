@@ -0,0 +1,153 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// MCPKnownHostsFile stores host keys accepted automatically by the MCP server,
+// kept apart from the user's ~/.ssh/known_hosts.
+const MCPKnownHostsFile = "known_hosts_mcp"
+
+// applyHostKeySettings fills host key verification options from settings
+// unless they were already set by flags or environment variables.
+func applyHostKeySettings(config *sshclient.Config, settings *Settings) {
+	if settings == nil {
+		return
+	}
+	if config.RevokedKeysPath == "" && settings.RevokedKeys != "" {
+		config.RevokedKeysPath = expandHome(settings.RevokedKeys)
+	}
+	if config.TrustTTL == 0 && settings.TrustTTL != "" {
+		config.TrustTTL = parseTrustTTL(settings.TrustTTL)
+	}
+}
+
+// mcpKnownHostsPath returns the MCP trust store path
+func mcpKnownHostsPath() (string, error) {
+	settingsDir, err := GetSettingsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(settingsDir, MCPKnownHostsFile), nil
+}
+
+// userKnownHostsPath returns the known_hosts file used by the CLI
+func userKnownHostsPath(config *sshclient.Config) (string, error) {
+	if config != nil && config.KnownHostsPath != "" {
+		return config.KnownHostsPath, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ssh", "known_hosts"), nil
+}
+
+// applyMCPTrust routes host keys accepted by the MCP server into the MCP
+// trust store. Unknown hosts are only accepted when mcp_accept_unknown_host is
+// set in settings or SSH_ACCEPT_UNKNOWN_HOST is set for the server process.
+func applyMCPTrust(config *sshclient.Config, settings *Settings) {
+	if path, err := mcpKnownHostsPath(); err == nil {
+		config.AcceptedHostsPath = path
+	}
+
+	accept := os.Getenv("SSH_ACCEPT_UNKNOWN_HOST")
+	config.AcceptUnknownHost = strings.EqualFold(accept, "true") || accept == "1" ||
+		(settings != nil && settings.MCPAcceptUnknownHost)
+}
+
+// HandleKnownHostsManagement handles the MCP trust store commands
+func HandleKnownHostsManagement(config *sshclient.Config) error {
+	mcpPath, err := mcpKnownHostsPath()
+	if err != nil {
+		return fmt.Errorf("failed to locate MCP known_hosts: %w", err)
+	}
+
+	switch config.KnownHostsAction {
+	case "list":
+		output, listErr := listMCPKnownHosts(mcpPath)
+		if listErr != nil {
+			return listErr
+		}
+		fmt.Print(output)
+		return nil
+	case "promote":
+		userPath, pathErr := userKnownHostsPath(config)
+		if pathErr != nil {
+			return fmt.Errorf("failed to locate known_hosts: %w", pathErr)
+		}
+		message, promoteErr := promoteMCPKnownHost(mcpPath, userPath, config.HostName)
+		if promoteErr != nil {
+			return promoteErr
+		}
+		logger.GetLogger().Success("%s", message)
+		return nil
+	case "deny":
+		message, denyErr := denyMCPKnownHost(mcpPath, config.HostName)
+		if denyErr != nil {
+			return denyErr
+		}
+		logger.GetLogger().Success("%s", message)
+		return nil
+	default:
+		return fmt.Errorf("unknown known_hosts action: %s (use: list, promote, deny)", config.KnownHostsAction)
+	}
+}
+
+// listMCPKnownHosts formats the entries of the MCP trust store
+func listMCPKnownHosts(path string) (string, error) {
+	entries, err := sshclient.ListKnownHosts(path)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if len(entries) == 0 {
+		sb.WriteString(fmt.Sprintf("No host keys accepted by the MCP server (%s).\n", path))
+		return sb.String(), nil
+	}
+
+	sb.WriteString(fmt.Sprintf("MCP-accepted host keys (%d) in %s:\n\n", len(entries), path))
+	for _, entry := range entries {
+		status := "trusted"
+		if entry.Revoked {
+			status = "denied"
+		}
+		sb.WriteString(fmt.Sprintf("  %s [%s]\n", strings.Join(entry.Hosts, ", "), status))
+		sb.WriteString(fmt.Sprintf("    Key:     %s %s\n", entry.KeyType, entry.Fingerprint))
+		if !entry.TrustedAt.IsZero() {
+			sb.WriteString(fmt.Sprintf("    Trusted: %s\n", entry.TrustedAt.Local().Format(time.RFC3339)))
+		}
+	}
+	sb.WriteString("\nPromote with --known-hosts-promote=<host>, deny with --known-hosts-deny=<host>\n")
+	return sb.String(), nil
+}
+
+func promoteMCPKnownHost(mcpPath, userPath, host string) (string, error) {
+	if host == "" {
+		return "", fmt.Errorf("host is required")
+	}
+	count, err := sshclient.PromoteKnownHost(mcpPath, userPath, host)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Promoted %d key(s) for %s to %s", count, host, userPath), nil
+}
+
+func denyMCPKnownHost(mcpPath, host string) (string, error) {
+	if host == "" {
+		return "", fmt.Errorf("host is required")
+	}
+	count, err := sshclient.DenyKnownHost(mcpPath, host)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Denied %d key(s) for %s; connections presenting them will be refused", count, host), nil
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestApplyMCPTrust(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_ACCEPT_UNKNOWN_HOST", "")
+
+	config := &sshclient.Config{}
+	applyMCPTrust(config, &Settings{})
+	assert.Equal(t, filepath.Join(home, SettingsDir, MCPKnownHostsFile), config.AcceptedHostsPath)
+	assert.False(t, config.AcceptUnknownHost)
+
+	applyMCPTrust(config, &Settings{MCPAcceptUnknownHost: true})
+	assert.True(t, config.AcceptUnknownHost)
+
+	t.Setenv("SSH_ACCEPT_UNKNOWN_HOST", "1")
+	applyMCPTrust(config, nil)
+	assert.True(t, config.AcceptUnknownHost)
+}
+
+func TestMCPKnownHosts_PromoteAndDeny(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	mcpPath, err := mcpKnownHostsPath()
+	require.NoError(t, err)
+	userPath := filepath.Join(home, ".ssh", "known_hosts")
+
+	webKey := newTestHostKey(t)
+	dbKey := newTestHostKey(t)
+	content := knownhosts.Line([]string{"web1"}, webKey) + " sshx-trusted@2026-01-02T03:04:05Z\n" +
+		knownhosts.Line([]string{"db1"}, dbKey) + "\n"
+	require.NoError(t, os.MkdirAll(filepath.Dir(mcpPath), 0o700))
+	require.NoError(t, os.WriteFile(mcpPath, []byte(content), 0o600))
+
+	listing, err := listMCPKnownHosts(mcpPath)
+	require.NoError(t, err)
+	assert.Contains(t, listing, "web1 [trusted]")
+	assert.Contains(t, listing, ssh.FingerprintSHA256(webKey))
+
+	message, err := promoteMCPKnownHost(mcpPath, userPath, "web1")
+	require.NoError(t, err)
+	assert.Contains(t, message, "Promoted 1 key(s)")
+
+	userData, err := os.ReadFile(userPath) // #nosec G304 -- test temp file
+	require.NoError(t, err)
+	assert.Contains(t, string(userData), "web1")
+	assert.NotContains(t, string(userData), "sshx-trusted@", "promoted keys are trusted permanently")
+
+	_, err = denyMCPKnownHost(mcpPath, "db1")
+	require.NoError(t, err)
+
+	entries, err := sshclient.ListKnownHosts(mcpPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].Revoked)
+	assert.True(t, entries[0].MatchesHost("db1"))
+
+	_, err = promoteMCPKnownHost(mcpPath, userPath, "db1")
+	assert.Error(t, err, "denied keys cannot be promoted")
+}
+
+func TestParseArgs_KnownHosts(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--known-hosts-promote=web1"})
+	assert.Equal(t, "knownhosts", config.Mode)
+	assert.Equal(t, "promote", config.KnownHostsAction)
+	assert.Equal(t, "web1", config.HostName)
+
+	config = ParseArgs([]string{"sshx", "--known-hosts-list"})
+	assert.Equal(t, "list", config.KnownHostsAction)
+}
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+	return signer.PublicKey()
+}
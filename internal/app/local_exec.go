@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+// localExecTool is the MCP tool that runs commands on the machine of the
+// MCP server; it is only exposed when enabled explicitly
+const localExecTool = "local_exec"
+
+// allowLocalExecFlag enables local_exec for one MCP server or daemon
+const allowLocalExecFlag = "--allow-local-exec"
+
+// defaultLocalExecTimeout is how long a local command may run when
+// local_exec.timeout is not set
+const defaultLocalExecTimeout = 5 * time.Minute
+
+// LocalExecConfig configures the local_exec MCP tool, which runs commands on
+// the machine of the MCP server (building an artifact, reading terraform
+// output) instead of a host
+type LocalExecConfig struct {
+	Enabled bool          `json:"enabled,omitempty"`  // Expose local_exec (default: off; --allow-local-exec also enables it)
+	Safety  *SafetyConfig `json:"safety,omitempty"`   // Severity of flagged local commands (default: block them all); host policies never apply
+	WorkDir string        `json:"work_dir,omitempty"` // Directory local commands run in (default: that of the server)
+	Timeout string        `json:"timeout,omitempty"`  // Kill local commands running longer than this (default: 5m)
+}
+
+// localExecEnabled reports whether local_exec was opted into, in settings
+// or with --allow-local-exec
+func localExecEnabled(settings *Settings, args []string) bool {
+	for _, arg := range args {
+		if arg == allowLocalExecFlag {
+			return true
+		}
+	}
+	return settings != nil && settings.LocalExec != nil && settings.LocalExec.Enabled
+}
+
+// executeLocalExec 在 MCP 服务器所在的本机运行命令（不是远程主机），使用独立的安全策略并写入审计日志
+func (s *MCPServer) executeLocalExec(args map[string]interface{}) (string, error) {
+	command := stringArg(args, "command")
+	if command == "" {
+		return "", fmt.Errorf("command is required")
+	}
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	options := &LocalExecConfig{}
+	if settings != nil && settings.LocalExec != nil {
+		options = settings.LocalExec
+	}
+
+	config := localExecConfig(command, options)
+	config.Source = s.auditSource()
+	s.identify(config)
+	applyOutputLimit(config, settings, args)
+
+	started := time.Now()
+	output, err := runLocalExec(config, options, durationArg(args, "timeout"))
+	recordAuditEntry(settings, newAuditEntry(config, started, err))
+	if err != nil {
+		var blocked *sshclient.BlockedCommandError
+		if errors.As(err, &blocked) || output == "" {
+			return "", err
+		}
+		return "", fmt.Errorf("%w\n%s", err, output)
+	}
+	return output, nil
+}
+
+// localExecConfig describes a local command the way the audit log and the
+// safety check expect a command: the host is "local", the user the one
+// running the server
+func localExecConfig(command string, options *LocalExecConfig) *sshclient.Config {
+	config := &sshclient.Config{
+		Host:        "localhost",
+		Alias:       "local",
+		Command:     command,
+		SafetyCheck: true,
+		Safety:      options.Safety.toSafetyPolicy(),
+	}
+	if current, err := user.Current(); err == nil {
+		config.User = current.Username
+	}
+	return config
+}
+
+// runLocalExec checks a local command against the local_exec safety policy
+// and runs it through the local shell, returning its combined output capped
+// like the output of remote commands. timeout, when set, replaces
+// local_exec.timeout.
+func runLocalExec(config *sshclient.Config, options *LocalExecConfig, timeout time.Duration) (string, error) {
+	finding, err := sshclient.CheckCommand(config.Command, config.Safety)
+	if err != nil {
+		return "", err
+	}
+	config.SafetyFinding = finding
+
+	if timeout <= 0 {
+		timeout = firstDuration("local_exec.timeout", options.Timeout)
+	}
+	if timeout <= 0 {
+		timeout = defaultLocalExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", config.Command) // #nosec G204 -- local_exec is opted into and safety checked
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", config.Command) // #nosec G204 -- local_exec is opted into and safety checked
+	}
+	cmd.Dir = expandHome(options.WorkDir)
+	// Children of the shell may keep the output open after it was killed
+	cmd.WaitDelay = time.Second
+	cmd.Env = os.Environ()
+	if identity := config.Identity(); identity != "" {
+		cmd.Env = append(cmd.Env, sshclient.CallerEnv+"="+identity)
+	}
+
+	raw, err := cmd.CombinedOutput()
+	output, _ := sshclient.CapOutput(raw, config.MaxOutputBytes)
+	if ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("local command timed out after %s", timeout)
+	}
+	if err != nil {
+		return output, fmt.Errorf("local command failed: %w", err)
+	}
+	return output, nil
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestRunLocalExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	options := &LocalExecConfig{WorkDir: t.TempDir()}
+
+	config := localExecConfig("echo built && pwd", options)
+	assert.Equal(t, "local", config.Alias)
+	output, err := runLocalExec(config, options, 0)
+	require.NoError(t, err)
+	assert.Contains(t, output, "built\n")
+	assert.Contains(t, output, options.WorkDir)
+
+	output, err = runLocalExec(localExecConfig("echo oops; exit 3", options), options, 0)
+	assert.ErrorContains(t, err, "local command failed")
+	assert.Equal(t, "oops\n", output)
+
+	_, err = runLocalExec(localExecConfig("sleep 5", options), options, 50*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out after 50ms")
+
+	// The capped output keeps its head and tail
+	config = localExecConfig("printf '%0100d' 0", options)
+	config.MaxOutputBytes = 20
+	output, err = runLocalExec(config, options, 0)
+	require.NoError(t, err)
+	assert.Contains(t, output, "output truncated")
+}
+
+func TestRunLocalExec_Safety(t *testing.T) {
+	options := &LocalExecConfig{}
+	_, err := runLocalExec(localExecConfig("rm -rf /", options), options, 0)
+	var blocked *sshclient.BlockedCommandError
+	require.True(t, errors.As(err, &blocked))
+
+	// Relaxing one rule leaves the others blocked
+	options = &LocalExecConfig{Safety: &SafetyConfig{Rules: map[string]string{"reboot": "warn"}}}
+	config := localExecConfig("dd if=/dev/zero of=/dev/sda", options)
+	_, err = runLocalExec(config, options, 0)
+	assert.True(t, errors.As(err, &blocked))
+	assert.Nil(t, config.SafetyFinding)
+}
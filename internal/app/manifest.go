@@ -0,0 +1,191 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+// Statuses of the entries of a transfer manifest
+const (
+	manifestOK         = "ok"
+	manifestFailed     = "failed"
+	manifestRolledBack = "rolled_back"
+	manifestSkipped    = "skipped"
+)
+
+// TransferManifest records what a multi-host transfer shipped, file by
+// file, as an artifact a deploy pipeline can keep
+type TransferManifest struct {
+	Operation string          `json:"operation"` // distribute or collect
+	Source    string          `json:"source"`    // Local file distributed, or remote path collected
+	Target    string          `json:"target"`    // Remote path written, or local directory collected into
+	Created   time.Time       `json:"created"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Entries   []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry is the file transferred for one host
+type ManifestEntry struct {
+	Host     string `json:"host"`               // Host name
+	Address  string `json:"address,omitempty"`  // Pool key (user@host:port)
+	Path     string `json:"path,omitempty"`     // Remote file written, or local file saved
+	Size     int64  `json:"size"`               // Bytes transferred
+	SHA256   string `json:"sha256,omitempty"`   // Hex SHA-256 of the content transferred
+	Verified bool   `json:"verified,omitempty"` // The file read back from the host matched sha256
+	Status   string `json:"status"`             // ok, failed, rolled_back or skipped
+	Error    string `json:"error,omitempty"`
+}
+
+// distributeManifest lists the outcome of a distribution per host
+func distributeManifest(hosts []HostConfig, results []sshclient.DistributeResult, status *sshclient.RolloutResult, opts sshclient.DistributeOptions) *TransferManifest {
+	manifest := &TransferManifest{Operation: "distribute", Source: opts.LocalPath, Target: opts.RemotePath, Created: time.Now().UTC()}
+	for i, result := range results {
+		entry := ManifestEntry{Host: manifestHostName(hosts, i, result.Key), Address: result.Key, Path: opts.RemotePath, Size: result.Bytes, SHA256: result.SHA256, Verified: result.Verified}
+		entry.Status, entry.Error = manifestStatus(i, result.Err, result.RolledBack, status)
+		manifest.add(entry)
+	}
+	return manifest
+}
+
+// collectManifest lists the files a collection saved per host
+func collectManifest(hosts []HostConfig, results []sshclient.CollectResult, status *sshclient.RolloutResult, opts sshclient.CollectOptions) *TransferManifest {
+	manifest := &TransferManifest{Operation: "collect", Source: opts.RemotePath, Target: opts.LocalDir, Created: time.Now().UTC()}
+	for i, result := range results {
+		entry := ManifestEntry{Host: manifestHostName(hosts, i, result.Key), Address: result.Key, Path: result.LocalPath, Size: result.Bytes, SHA256: result.SHA256}
+		entry.Status, entry.Error = manifestStatus(i, result.Err, result.RolledBack, status)
+		if entry.Status != manifestOK {
+			entry.SHA256 = ""
+		}
+		manifest.add(entry)
+	}
+	return manifest
+}
+
+func (m *TransferManifest) add(entry ManifestEntry) {
+	if entry.Status == manifestOK {
+		m.Succeeded++
+	} else {
+		m.Failed++
+	}
+	m.Entries = append(m.Entries, entry)
+}
+
+func manifestHostName(hosts []HostConfig, i int, key string) string {
+	if i < len(hosts) {
+		return hosts[i].Name
+	}
+	return key
+}
+
+// manifestStatus is the status and error of the host at index i
+func manifestStatus(i int, err error, rolledBack bool, status *sshclient.RolloutResult) (string, string) {
+	switch {
+	case err == nil:
+		return manifestOK, ""
+	case status != nil && slices.Contains(status.Skipped, i):
+		return manifestSkipped, err.Error()
+	case rolledBack:
+		return manifestRolledBack, err.Error()
+	default:
+		return manifestFailed, err.Error()
+	}
+}
+
+// JSON encodes the manifest for files and tool results
+func (m *TransferManifest) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// saveManifest writes the manifest to a local file
+func saveManifest(manifest *TransferManifest, path string) error {
+	data, err := manifest.JSON()
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+// pushManifest uploads the manifest to remotePath on every host the
+// distribution succeeded on, so each host keeps a record of what it got
+func pushManifest(settings *Settings, manifest *TransferManifest, baseConfig *sshclient.Config, remotePath string) error {
+	var names []string
+	for _, entry := range manifest.Entries {
+		if entry.Status == manifestOK {
+			names = append(names, entry.Host)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	data, err := manifest.JSON()
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp("", "sshx-manifest-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create manifest file: %w", err)
+	}
+	defer func() { _ = os.Remove(file.Name()) }() //nolint:errcheck // temporary file
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close() //nolint:errcheck // the write error is reported
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+
+	hosts, results, _, err := distributeFile(settings, strings.Join(names, ","), baseConfig, sshclient.DistributeOptions{LocalPath: file.Name(), RemotePath: remotePath}, sshclient.RolloutOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to upload manifest: %w", err)
+	}
+	var failed []string
+	for i, result := range results {
+		if result.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", manifestHostName(hosts, i, result.Key), result.Err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to upload manifest to %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// keepManifest saves the manifest to path and uploads it to remotePath on
+// the updated hosts, each when set. The result is the manifest JSON with
+// asJSON, report otherwise.
+func keepManifest(settings *Settings, manifest *TransferManifest, baseConfig *sshclient.Config, path, remotePath string, asJSON bool, report string) (string, error) {
+	if path != "" {
+		if err := saveManifest(manifest, path); err != nil {
+			return "", err
+		}
+		report += fmt.Sprintf("Manifest: %s\n", path)
+	}
+	if remotePath != "" {
+		if err := pushManifest(settings, manifest, baseConfig, remotePath); err != nil {
+			return "", err
+		}
+		report += fmt.Sprintf("Manifest uploaded to %s on %d host(s)\n", remotePath, manifest.Succeeded)
+	}
+	if !asJSON {
+		return report, nil
+	}
+	data, err := manifest.JSON()
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
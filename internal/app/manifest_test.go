@@ -0,0 +1,73 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestDistributeManifest(t *testing.T) {
+	hosts := []HostConfig{{Name: "web1"}, {Name: "web2"}, {Name: "web3"}, {Name: "web4"}}
+	results := []sshclient.DistributeResult{
+		{Key: "deploy@10.0.0.1:22", Bytes: 13, SHA256: "42e8f2", Verified: true},
+		{Key: "deploy@10.0.0.2:22", Bytes: 13, SHA256: "42e8f2", RolledBack: true, Err: errors.New("validation failed")},
+		{Key: "deploy@10.0.0.3:22", Err: errors.New("failed to connect: timeout")},
+		{Key: "deploy@10.0.0.4:22", Err: errors.New("skipped: the rollout halted")},
+	}
+	status := &sshclient.RolloutResult{Halted: true, Skipped: []int{3}}
+	opts := sshclient.DistributeOptions{LocalPath: "dist/app.jar", RemotePath: "/opt/app/app.jar"}
+
+	manifest := distributeManifest(hosts, results, status, opts)
+	assert.Equal(t, "distribute", manifest.Operation)
+	assert.Equal(t, "dist/app.jar", manifest.Source)
+	assert.Equal(t, "/opt/app/app.jar", manifest.Target)
+	assert.Equal(t, 1, manifest.Succeeded)
+	assert.Equal(t, 3, manifest.Failed)
+	require.Len(t, manifest.Entries, 4)
+	assert.Equal(t, ManifestEntry{Host: "web1", Address: "deploy@10.0.0.1:22", Path: "/opt/app/app.jar", Size: 13, SHA256: "42e8f2", Verified: true, Status: "ok"}, manifest.Entries[0])
+	assert.Equal(t, "rolled_back", manifest.Entries[1].Status)
+	assert.Equal(t, "validation failed", manifest.Entries[1].Error)
+	assert.Equal(t, "failed", manifest.Entries[2].Status)
+	assert.Equal(t, "skipped", manifest.Entries[3].Status)
+}
+
+func TestCollectManifest(t *testing.T) {
+	hosts := []HostConfig{{Name: "db1"}, {Name: "db2"}}
+	results := []sshclient.CollectResult{
+		{Key: "root@10.0.1.1:22", LocalPath: "out/db1/my.cnf", Bytes: 120, SHA256: "9f86d0"},
+		{Key: "root@10.0.1.2:22", LocalPath: "out/db2/my.cnf", Bytes: 60, SHA256: "partial", Err: errors.New("file grew past size cap")},
+	}
+	manifest := collectManifest(hosts, results, nil, sshclient.CollectOptions{RemotePath: "/etc/mysql/my.cnf", LocalDir: "out"})
+	assert.Equal(t, "collect", manifest.Operation)
+	assert.Equal(t, ManifestEntry{Host: "db1", Address: "root@10.0.1.1:22", Path: "out/db1/my.cnf", Size: 120, SHA256: "9f86d0", Status: "ok"}, manifest.Entries[0])
+	assert.Empty(t, manifest.Entries[1].SHA256, "no checksum for a file that did not arrive whole")
+	assert.Equal(t, "failed", manifest.Entries[1].Status)
+}
+
+func TestKeepManifest(t *testing.T) {
+	manifest := distributeManifest([]HostConfig{{Name: "web1"}}, []sshclient.DistributeResult{{Key: "deploy@10.0.0.1:22", Bytes: 13, SHA256: "42e8f2", Verified: true}}, nil,
+		sshclient.DistributeOptions{LocalPath: "app.conf", RemotePath: "/etc/app.conf"})
+	path := filepath.Join(t.TempDir(), "manifest.json")
+
+	report, err := keepManifest(&Settings{}, manifest, &sshclient.Config{}, path, "", false, "Summary: 1/1 hosts updated\n")
+	require.NoError(t, err)
+	assert.Equal(t, "Summary: 1/1 hosts updated\nManifest: "+path+"\n", report)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var saved TransferManifest
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, manifest.Entries, saved.Entries)
+	assert.True(t, manifest.Created.Equal(saved.Created))
+
+	result, err := keepManifest(&Settings{}, manifest, &sshclient.Config{}, "", "", true, "ignored")
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), result)
+}
@@ -3,10 +3,15 @@ package app
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/talkincode/sshmcp/internal/sshclient"
 	"github.com/talkincode/sshmcp/pkg/logger"
@@ -35,9 +40,10 @@ type MCPError struct {
 
 // MCP Tool definitions
 type MCPTool struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	InputSchema interface{} `json:"inputSchema"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	InputSchema interface{}      `json:"inputSchema"`
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
 }
 
 type ToolSchema struct {
@@ -47,342 +53,2602 @@ type ToolSchema struct {
 }
 
 type Property struct {
-	Type        string   `json:"type"`
-	Description string   `json:"description"`
-	Enum        []string `json:"enum,omitempty"`
-	Default     string   `json:"default,omitempty"`
+	Type        string      `json:"type"`
+	Description string      `json:"description"`
+	Enum        []string    `json:"enum,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Items       *Property   `json:"items,omitempty"`
+}
+
+// ToolAnnotations are the behavior hints MCP clients use to decide how
+// carefully to treat a tool
+type ToolAnnotations struct {
+	ReadOnlyHint bool `json:"readOnlyHint,omitempty"`
+}
+
+// mcpToolSpec declares a tool once: the schema listed to clients, the
+// handler executing it and policy metadata
+type mcpToolSpec struct {
+	MCPTool
+	// Handler runs a tool that works locally: settings, the connection pool,
+	// known hosts or many hosts resolved by the tool itself
+	Handler func(s *MCPServer, args map[string]interface{}) (string, error)
+	// RemoteHandler runs a tool on one host, with the connection config
+	// built from the common host, port, user and key arguments
+	RemoteHandler func(s *MCPServer, config *sshclient.Config, args map[string]interface{}) (string, error)
+	// ReadOnly marks tools that change nothing on hosts, in local files,
+	// in settings or in the connection pool
+	ReadOnly bool
 }
 
 // MCPServer represents an MCP server instance
 type MCPServer struct {
-	stdin  *bufio.Reader
-	stdout io.Writer
-	tools  []MCPTool
+	stdin    *bufio.Reader
+	stdout   io.Writer
+	writeMu  sync.Mutex // 日志通知可能来自其他 goroutine
+	tools    []MCPTool
+	registry map[string]mcpToolSpec
+	// progress 报告当前工具调用的进度；工具调用在读取循环中依次执行
+	progress sshclient.ProgressFunc
+	// caller 是客户端令牌对应的授权策略，nil 表示不限制
+	caller *MCPTokenPolicy
+	// client 是客户端在 initialize 中通过 clientInfo 声明的名称和版本
+	client string
+	// scheduler 运行配置中的计划作业，nil 表示未启动
+	scheduler *Scheduler
+	// source 是审计日志中记录的来源，空表示 "mcp"
+	source string
+	// stopEvents 结束 sshx://events 资源的订阅，nil 表示未订阅
+	stopEvents func()
 }
 
 // NewMCPServer creates a new MCP server instance
 func NewMCPServer() *MCPServer {
+	specs := mcpToolSpecs()
+	registry := make(map[string]mcpToolSpec, len(specs))
+	for _, spec := range specs {
+		registry[spec.Name] = spec
+	}
 	return &MCPServer{
-		stdin:  bufio.NewReader(os.Stdin),
-		stdout: os.Stdout,
-		tools:  defineMCPTools(),
+		stdin:    bufio.NewReader(os.Stdin),
+		stdout:   os.Stdout,
+		tools:    defineMCPTools(),
+		registry: registry,
 	}
 }
 
-// defineMCPTools defines all available MCP tools
+// defineMCPTools lists the tools advertised to clients, in declaration order
 func defineMCPTools() []MCPTool {
-	return []MCPTool{
+	specs := mcpToolSpecs()
+	tools := make([]MCPTool, len(specs))
+	for i, spec := range specs {
+		tools[i] = spec.MCPTool
+		if spec.ReadOnly {
+			tools[i].Annotations = &ToolAnnotations{ReadOnlyHint: true}
+		}
+	}
+	return tools
+}
+
+// mcpToolSpecs declares every MCP tool: its schema, the handler that runs
+// it and its policy metadata. A new tool is added here and nowhere else.
+func mcpToolSpecs() []mcpToolSpec {
+	return []mcpToolSpec{
+		{
+			MCPTool: MCPTool{
+				Name:        "ssh_execute",
+				Description: "Execute a command on remote server via SSH. Supports sudo with automatic password handling.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"command": {
+							Type:        "string",
+							Description: "Command to execute on remote server",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for sudo password",
+							Default:     "master",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the command as this remote user via sudo -u after logging in as user (uses the sudo password)",
+						},
+						"cwd": {
+							Type:        "string",
+							Description: "Remote directory to run the command in; it fails if the directory cannot be entered",
+						},
+						"login_shell": {
+							Type:        "boolean",
+							Description: "Run the command through bash -lc so the login profile sets up PATH and other variables",
+							Default:     false,
+						},
+						"force": {
+							Type:        "boolean",
+							Description: "Run a command the safety check blocks (requires force_reason; use with caution!)",
+							Default:     false,
+						},
+						"force_reason": {
+							Type:        "string",
+							Description: "Why the blocked command has to run; required with force and recorded in the audit log",
+						},
+						"confirm_token": {
+							Type:        "string",
+							Description: "Current TOTP code required with force on hosts whose safety policy sets force_totp_key",
+						},
+						"max_output": {
+							Type:        "string",
+							Description: "Maximum output size to return (e.g. 64K, 1M or a byte count; default: 1M). Larger output keeps its head and tail with a truncation marker",
+						},
+						"spill_output": {
+							Type:        "boolean",
+							Description: "Save the full output to a local file referenced in the response when it is truncated",
+							Default:     false,
+						},
+						"binary": {
+							Type:        "boolean",
+							Description: "Capture stdout byte for byte (no PTY, stderr returned separately) and return it base64 encoded as JSON with size and sha256. Use for tar, gzip or other binary output; fails instead of truncating",
+							Default:     false,
+						},
+						"encoding": {
+							Type:        "string",
+							Description: "Encoding of the returned output: text, or base64 (JSON with content, size and sha256)",
+							Enum:        []string{"text", "base64"},
+							Default:     "text",
+						},
+					},
+					Required: []string{"host", "command"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeSSH,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "ssh_watch",
+				Description: "Run a command repeatedly on a remote server and report how its output changes, until the output matches a pattern or max_runs runs have been made. Use to wait for a deployment, a service or a file.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"command": {
+							Type:        "string",
+							Description: "Command to run on every iteration",
+						},
+						"interval": {
+							Type:        "string",
+							Description: "Pause between runs (e.g. 10s, 1m or a number of seconds)",
+							Default:     "5s",
+						},
+						"until": {
+							Type:        "string",
+							Description: "Regular expression; the watch ends as soon as the output matches (e.g. \"successfully rolled out\")",
+						},
+						"max_runs": {
+							Type:        "integer",
+							Description: fmt.Sprintf("Maximum number of runs (at most %d)", maxWatchRuns),
+							Default:     defaultWatchRuns,
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for sudo password",
+							Default:     "master",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the command as this remote user via sudo -u after logging in as user (uses the sudo password)",
+						},
+						"cwd": {
+							Type:        "string",
+							Description: "Remote directory to run the command in; it fails if the directory cannot be entered",
+						},
+						"login_shell": {
+							Type:        "boolean",
+							Description: "Run the command through bash -lc so the login profile sets up PATH and other variables",
+							Default:     false,
+						},
+						"max_output": {
+							Type:        "string",
+							Description: "Maximum output size kept from each run (e.g. 64K, 1M or a byte count; default: 1M)",
+						},
+					},
+					Required: []string{"host", "command"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeSSHWatch,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "wait_for",
+				Description: "Block until a condition holds on a remote server: a TCP port accepts connections, a file exists, a systemd unit is active or a URL answers 200. The check is polled on the remote host until the timeout.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"condition": {
+							Type:        "string",
+							Description: "Kind of condition",
+							Enum:        sshclient.WaitKinds,
+						},
+						"target": {
+							Type:        "string",
+							Description: "What to wait for: [host:]port for port (host defaults to 127.0.0.1 on the remote side), a path for file, a unit for service, a URL for http",
+						},
+						"timeout": {
+							Type:        "string",
+							Description: fmt.Sprintf("How long to wait (e.g. 30s, 5m or a number of seconds; at most %s)", maxWaitTimeout),
+							Default:     "60s",
+						},
+						"interval": {
+							Type:        "string",
+							Description: "Pause between checks (e.g. 5s or a number of seconds)",
+							Default:     "2s",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
+					},
+					Required: []string{"host", "condition", "target"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeWaitFor,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "ssh_interact",
+				Description: "Run a command (or a login shell) on a pseudo-terminal and answer its prompts with an expect script, for installer confirmations, passphrase prompts or a switch's enable mode that a one-shot command cannot answer. Returns the transcript",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"command": {
+							Type:        "string",
+							Description: "Command to start; omit to drive the login shell (left with exit after the last step)",
+						},
+						"script": {
+							Type:        "string",
+							Description: "Expect script, one instruction per line: 'expect <regex>' waits for output matching the regular expression, 'send <line>' types a line (a quoted line is unquoted, e.g. \"\\x03\"), 'send-key <key>' types a keyring password without revealing it, 'timeout <duration>' sets the wait of the following expects (default 30s, at most 30m)",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Start the command as this remote user via sudo -u; answer sudo's password prompt in the script",
+						},
+						"cwd": {
+							Type:        "string",
+							Description: "Remote directory to start the command in",
+						},
+						"max_output": {
+							Type:        "string",
+							Description: "Maximum transcript size to return (e.g. 64K, 1M or a byte count; default: 1M)",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
+					},
+					Required: []string{"host", "script"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeInteract,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "sftp_upload",
+				Description: "Upload a file to remote server via SFTP, from a local path or from content passed inline",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"local_path": {
+							Type:        "string",
+							Description: "Local file path to upload",
+						},
+						"content": {
+							Type:        "string",
+							Description: "Text to write to remote_path instead of uploading local_path",
+						},
+						"content_base64": {
+							Type:        "string",
+							Description: "Base64 encoded bytes to write to remote_path instead of uploading local_path",
+						},
+						"remote_path": {
+							Type:        "string",
+							Description: "Remote destination path",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"template": {
+							Type:        "boolean",
+							Description: "Render local_path or content as a Go template with the host's fields, tags and vars before upload",
+							Default:     false,
+						},
+						"backup": {
+							Type:        "boolean",
+							Description: "Keep the file being replaced as remote_path.bak",
+							Default:     false,
+						},
+						"delta": {
+							Type:        "boolean",
+							Description: "With local_path, send only the blocks that differ from the existing remote file (rsync-style delta transfer for large, frequently updated files such as VM images or SQLite databases; needs python3 on the host, otherwise the whole file is sent)",
+							Default:     false,
+						},
+					},
+					Required: []string{"host", "remote_path"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeSftpUpload,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "sftp_append",
+				Description: "Append content to a remote file via SFTP, creating it if needed, without a shell command",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"remote_path": {
+							Type:        "string",
+							Description: "Remote file to append to",
+						},
+						"content": {
+							Type:        "string",
+							Description: "Text to append (add the trailing newline yourself)",
+						},
+						"content_base64": {
+							Type:        "string",
+							Description: "Base64 encoded bytes to append",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+					},
+					Required: []string{"host", "remote_path"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeSftpAppend,
+		},
 		{
-			Name:        "ssh_execute",
-			Description: "Execute a command on remote server via SSH. Supports sudo with automatic password handling.",
-			InputSchema: ToolSchema{
-				Type: "object",
-				Properties: map[string]Property{
-					"host": {
-						Type:        "string",
-						Description: "Remote host address (IP or hostname)",
+			MCPTool: MCPTool{
+				Name:        "sftp_download",
+				Description: "Download a file from remote server via SFTP, to a local path or, for small files, directly into the result",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"remote_path": {
+							Type:        "string",
+							Description: "Remote file path to download",
+						},
+						"local_path": {
+							Type:        "string",
+							Description: "Local destination path. Without it the file is returned inline (text, or base64 JSON with size and sha256 for binary content)",
+						},
+						"max_inline_bytes": {
+							Type:        "integer",
+							Description: fmt.Sprintf("Return files up to this size inline instead of writing local_path (default without local_path: %d, at most %d)", defaultInlineBytes, maxInlineBytes),
+						},
+						"offset": {
+							Type:        "integer",
+							Description: "Return only a byte range starting here, as JSON with the file size; negative counts from the end (-65536 reads the last 64 KB)",
+						},
+						"length": {
+							Type:        "integer",
+							Description: fmt.Sprintf("Bytes to return from offset (default %d, at most %d)", defaultInlineBytes, maxInlineBytes),
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
 					},
-					"command": {
-						Type:        "string",
-						Description: "Command to execute on remote server",
+					Required: []string{"host", "remote_path"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeSftpDownload,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "sftp_list",
+				Description: "List directory contents on remote server via SFTP as JSON entries (path, type, size, mode, modified), with filters, sorting and pagination",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"remote_path": {
+							Type:        "string",
+							Description: "Remote directory path to list",
+							Default:     ".",
+						},
+						"recursive": {
+							Type:        "boolean",
+							Description: "Include the contents of subdirectories (symlinks are not followed)",
+							Default:     false,
+						},
+						"pattern": {
+							Type:        "string",
+							Description: "Only entries whose name matches this glob (e.g. *.log)",
+						},
+						"type": {
+							Type:        "string",
+							Description: "Only entries of this type",
+							Enum:        []string{"file", "dir", "symlink"},
+						},
+						"sort": {
+							Type:        "string",
+							Description: "Sort key (name sorts by relative path)",
+							Enum:        sshclient.ListSortKeys,
+							Default:     "name",
+						},
+						"reverse": {
+							Type:        "boolean",
+							Description: "Sort in descending order (e.g. largest or newest first)",
+							Default:     false,
+						},
+						"offset": {
+							Type:        "integer",
+							Description: "Number of matching entries to skip (next_offset of the previous page)",
+							Default:     0,
+						},
+						"limit": {
+							Type:        "integer",
+							Description: fmt.Sprintf("Maximum number of entries to return (at most %d)", maxListLimit),
+							Default:     sshclient.DefaultListLimit,
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
 					},
-					"port": {
-						Type:        "string",
-						Description: "SSH port",
-						Default:     "22",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeSftpList,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "sftp_mkdir",
+				Description: "Create a directory on remote server via SFTP",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"remote_path": {
+							Type:        "string",
+							Description: "Remote directory path to create",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
 					},
-					"user": {
-						Type:        "string",
-						Description: "SSH username",
-						Default:     "master",
+					Required: []string{"host", "remote_path"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeSftpMkdir,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "sftp_remove",
+				Description: "Remove a file or directory on remote server via SFTP, or move it to ~/.sshx-trash with backup",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"remote_path": {
+							Type:        "string",
+							Description: "Remote file or directory path to remove",
+						},
+						"backup": {
+							Type:        "boolean",
+							Description: "Move the target to a timestamped directory below ~/.sshx-trash instead of deleting it (always on when mcp_remove_backup is set)",
+							Default:     false,
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
 					},
-					"key_path": {
-						Type:        "string",
-						Description: "Path to SSH private key",
+					Required: []string{"host", "remote_path"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeSftpRemove,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "trash_purge",
+				Description: "Permanently delete files that sftp_remove moved to ~/.sshx-trash on a remote host, optionally only removals older than a given age",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"older_than": {
+							Type:        "string",
+							Description: "Only purge removals older than this (e.g. 12h or 7d; default: everything)",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
 					},
-					"sudo_key": {
-						Type:        "string",
-						Description: "Key name for sudo password",
-						Default:     "master",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeTrashPurge,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "script_execute",
+				Description: "Upload and execute a local script file on remote server. Automatically detects script type (bash/python/perl/ruby) and cleans up after execution.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"script_path": {
+							Type:        "string",
+							Description: "Local script file path to upload and execute",
+						},
+						"args": {
+							Type:        "string",
+							Description: "Optional arguments to pass to the script (space-separated)",
+						},
+						"timeout": {
+							Type:        "string",
+							Description: "Kill the script if it runs longer than this (e.g. 30s, 10m or a number of seconds; default: 30m, 0 disables). Output produced before the kill is returned",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the script as this remote user via sudo -u after logging in as user (uses the sudo password)",
+						},
+						"cwd": {
+							Type:        "string",
+							Description: "Remote directory to run the script in; it fails if the directory cannot be entered",
+						},
+						"login_shell": {
+							Type:        "boolean",
+							Description: "Run the script through bash -lc so the login profile sets up PATH and other variables",
+							Default:     false,
+						},
+						"check_syntax": {
+							Type:        "boolean",
+							Description: "Parse the script with its interpreter (bash -n, perl -c, ruby -c, python ast) first and only run it if that succeeds",
+							Default:     false,
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"max_output": {
+							Type:        "string",
+							Description: "Maximum output size to return (e.g. 64K, 1M or a byte count; default: 1M). Larger output keeps its head and tail with a truncation marker",
+						},
+						"spill_output": {
+							Type:        "boolean",
+							Description: "Save the full output to a local file referenced in the response when it is truncated",
+							Default:     false,
+						},
 					},
-					"force": {
-						Type:        "string",
-						Description: "Force execution, bypass safety checks (use with caution!)",
-						Enum:        []string{"true", "false"},
-						Default:     "false",
+					Required: []string{"host", "script_path"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeScript,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "container_exec",
+				Description: "Execute a command inside a container on a remote host with docker, podman or nerdctl exec. The command is checked by the same safety rules as ssh_execute.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"container": {
+							Type:        "string",
+							Description: "Container name or ID",
+						},
+						"command": {
+							Type:        "string",
+							Description: "Command to run inside the container (through sh -c)",
+						},
+						"force": {
+							Type:        "boolean",
+							Description: "Run a command the safety check blocks (requires force_reason; use with caution!)",
+							Default:     false,
+						},
+						"force_reason": {
+							Type:        "string",
+							Description: "Why the blocked command has to run; required with force and recorded in the audit log",
+						},
+						"confirm_token": {
+							Type:        "string",
+							Description: "Current TOTP code required with force on hosts whose safety policy sets force_totp_key",
+						},
+						"runtime": {
+							Type:        "string",
+							Description: "Container runtime (default: the first of docker, podman and nerdctl installed on the host)",
+							Enum:        []string{"docker", "podman", "nerdctl"},
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the container runtime as this remote user via sudo -u, e.g. root when the user cannot reach the Docker socket",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"max_output": {
+							Type:        "string",
+							Description: "Maximum output size to return (e.g. 64K, 1M or a byte count; default: 1M). Larger output keeps its head and tail with a truncation marker",
+						},
 					},
+					Required: []string{"host", "container", "command"},
 				},
-				Required: []string{"host", "command"},
 			},
+			RemoteHandler: (*MCPServer).executeContainerExec,
 		},
 		{
-			Name:        "sftp_upload",
-			Description: "Upload a file to remote server via SFTP",
-			InputSchema: ToolSchema{
-				Type: "object",
-				Properties: map[string]Property{
-					"host": {
-						Type:        "string",
-						Description: "Remote host address",
+			MCPTool: MCPTool{
+				Name:        "container_list",
+				Description: "List the containers on a remote host (docker, podman or nerdctl ps)",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"all": {
+							Type:        "boolean",
+							Description: "Include stopped containers",
+							Default:     false,
+						},
+						"runtime": {
+							Type:        "string",
+							Description: "Container runtime (default: the first of docker, podman and nerdctl installed on the host)",
+							Enum:        []string{"docker", "podman", "nerdctl"},
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the container runtime as this remote user via sudo -u, e.g. root when the user cannot reach the Docker socket",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
 					},
-					"local_path": {
-						Type:        "string",
-						Description: "Local file path to upload",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeContainerList,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "container_logs",
+				Description: "Get the logs of a container on a remote host (docker, podman or nerdctl logs)",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"container": {
+							Type:        "string",
+							Description: "Container name or ID",
+						},
+						"tail": {
+							Type:        "integer",
+							Description: "Number of lines from the end of the logs (0 for all)",
+							Default:     defaultContainerLogLines,
+						},
+						"since": {
+							Type:        "string",
+							Description: "Only logs newer than this (e.g. 10m, 2h or an RFC 3339 timestamp)",
+						},
+						"runtime": {
+							Type:        "string",
+							Description: "Container runtime (default: the first of docker, podman and nerdctl installed on the host)",
+							Enum:        []string{"docker", "podman", "nerdctl"},
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the container runtime as this remote user via sudo -u, e.g. root when the user cannot reach the Docker socket",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"max_output": {
+							Type:        "string",
+							Description: "Maximum output size to return (e.g. 64K, 1M or a byte count; default: 1M). Larger output keeps its head and tail with a truncation marker",
+						},
 					},
-					"remote_path": {
-						Type:        "string",
-						Description: "Remote destination path",
+					Required: []string{"host", "container"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeContainerLogs,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "k8s_node_status",
+				Description: "Report a Kubernetes node's readiness, cordon and drain status as JSON, using kubectl on a host tagged k8s-node. Lists the pods a drain would still evict.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name tagged k8s-node",
+						},
+						"node": {
+							Type:        "string",
+							Description: "Node name (default: the host's hostname)",
+						},
+						"kubeconfig": {
+							Type:        "string",
+							Description: "Kubeconfig on the host used by kubectl",
+							Default:     sshclient.DefaultKubeconfig,
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the command as this remote user via sudo -u, e.g. root",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
 					},
-					"port": {
-						Type:        "string",
-						Description: "SSH port",
-						Default:     "22",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeK8sNodeStatus,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "k8s_kubelet_logs",
+				Description: "Get the kubelet journal of a host tagged k8s-node",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name tagged k8s-node",
+						},
+						"lines": {
+							Type:        "integer",
+							Description: "Number of lines from the end of the journal (0 for all)",
+							Default:     defaultKubeletLogLines,
+						},
+						"since": {
+							Type:        "string",
+							Description: "Only entries newer than this (journalctl time, e.g. \"10 min ago\" or \"2026-01-02 15:04\")",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the command as this remote user via sudo -u, e.g. root",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"max_output": {
+							Type:        "string",
+							Description: "Maximum output size to return (e.g. 64K, 1M or a byte count; default: 1M). Larger output keeps its head and tail with a truncation marker",
+						},
 					},
-					"user": {
-						Type:        "string",
-						Description: "SSH username",
-						Default:     "master",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeK8sKubeletLogs,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "k8s_crictl_ps",
+				Description: "List the containers of the container runtime on a host tagged k8s-node (crictl ps)",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name tagged k8s-node",
+						},
+						"all": {
+							Type:        "boolean",
+							Description: "Include exited containers",
+							Default:     false,
+						},
+						"container": {
+							Type:        "string",
+							Description: "Only containers whose name matches this regular expression",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the command as this remote user via sudo -u, e.g. root",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
 					},
+					Required: []string{"host"},
 				},
-				Required: []string{"host", "local_path", "remote_path"},
 			},
+			RemoteHandler: (*MCPServer).executeK8sCrictlPs,
+			ReadOnly:      true,
 		},
 		{
-			Name:        "sftp_download",
-			Description: "Download a file from remote server via SFTP",
-			InputSchema: ToolSchema{
-				Type: "object",
-				Properties: map[string]Property{
-					"host": {
-						Type:        "string",
-						Description: "Remote host address",
+			MCPTool: MCPTool{
+				Name:        "package_manage",
+				Description: "Install, remove, update or list packages on a remote host with its own package manager (apt, dnf, yum, zypper or apk, detected automatically), so no distro-specific syntax is needed. Runs as a dry run that reports what would change unless dry_run is false. Real changes need root: pass run_as \"root\" unless the SSH user is root. Essential packages (openssh-server, sudo, systemd, the kernel, the package manager) cannot be removed.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"action": {
+							Type:        "string",
+							Description: "install, remove, update (all packages when none are given) or list (installed packages with versions)",
+							Enum:        sshclient.PackageActions,
+						},
+						"packages": {
+							Type:        "string",
+							Description: "Comma-separated package names, optionally pinned to a version as the package manager expects (e.g. nginx,curl or nginx=1.24.0-1 for apt)",
+						},
+						"dry_run": {
+							Type:        "boolean",
+							Description: "Only simulate install, remove and update and report what would change",
+							Default:     true,
+						},
+						"manager": {
+							Type:        "string",
+							Description: "Package manager to use instead of detecting it",
+							Enum:        sshclient.PackageManagers,
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the package manager as this remote user via sudo -u, e.g. root",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"max_output": {
+							Type:        "string",
+							Description: "Maximum output size to return (e.g. 64K, 1M or a byte count; default: 1M). Larger output keeps its head and tail with a truncation marker",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
-					"remote_path": {
-						Type:        "string",
-						Description: "Remote file path to download",
+					Required: []string{"host", "action"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executePackageManage,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "user_create",
+				Description: "Create a user with a home directory on a remote host unless it already exists (useradd, or adduser on BusyBox), optionally authorizing a public key for it. Idempotent: an existing user is left unchanged. Needs root: pass run_as \"root\" unless the SSH user is root.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"name": {
+							Type:        "string",
+							Description: "Name of the user to create",
+						},
+						"shell": {
+							Type:        "string",
+							Description: "Login shell of a new user, e.g. /bin/bash (default: the system default)",
+						},
+						"public_key": {
+							Type:        "string",
+							Description: "Public key in authorized_keys format (the content of an id_*.pub file)",
+						},
+						"public_key_path": {
+							Type:        "string",
+							Description: "Local public key file to read instead of public_key",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run as this remote user via sudo -u, e.g. root (needed to manage other users)",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
-					"local_path": {
-						Type:        "string",
-						Description: "Local destination path",
+					Required: []string{"host", "name"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeUserCreate,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "authorized_keys_add",
+				Description: "Authorize a public key for a remote user by adding it to ~/.ssh/authorized_keys, creating the directory and file with the permissions sshd expects. Idempotent: a key already present is not added twice. Returns JSON with the key fingerprint and whether it was added.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"account": {
+							Type:        "string",
+							Description: "Remote user whose authorized_keys to manage (default: the SSH user)",
+						},
+						"public_key": {
+							Type:        "string",
+							Description: "Public key in authorized_keys format (the content of an id_*.pub file)",
+						},
+						"public_key_path": {
+							Type:        "string",
+							Description: "Local public key file to read instead of public_key",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run as this remote user via sudo -u, e.g. root (needed to manage other users)",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
-					"port": {
-						Type:        "string",
-						Description: "SSH port",
-						Default:     "22",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeAuthorizedKeysAdd,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "authorized_keys_list",
+				Description: "List the public keys a remote user accepts (~/.ssh/authorized_keys) as JSON: type, SHA256 fingerprint, comment and options.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"account": {
+							Type:        "string",
+							Description: "Remote user whose authorized_keys to manage (default: the SSH user)",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run as this remote user via sudo -u, e.g. root (needed to manage other users)",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
-					"user": {
-						Type:        "string",
-						Description: "SSH username",
-						Default:     "master",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeAuthorizedKeysList,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "authorized_keys_revoke",
+				Description: "Remove the public keys whose SHA256 fingerprint or comment matches from a remote user's ~/.ssh/authorized_keys and return them as JSON. Idempotent: nothing matching is not an error.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"account": {
+							Type:        "string",
+							Description: "Remote user whose authorized_keys to manage (default: the SSH user)",
+						},
+						"key": {
+							Type:        "string",
+							Description: "SHA256 fingerprint (SHA256:...) or comment of the keys to remove",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run as this remote user via sudo -u, e.g. root (needed to manage other users)",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
+					Required: []string{"host", "key"},
 				},
-				Required: []string{"host", "remote_path", "local_path"},
 			},
+			RemoteHandler: (*MCPServer).executeAuthorizedKeysRevoke,
 		},
 		{
-			Name:        "sftp_list",
-			Description: "List directory contents on remote server via SFTP",
-			InputSchema: ToolSchema{
-				Type: "object",
-				Properties: map[string]Property{
-					"host": {
-						Type:        "string",
-						Description: "Remote host address",
+			MCPTool: MCPTool{
+				Name:        "port_check",
+				Description: "Report the listening TCP/UDP ports of a remote host (ss, or netstat) with their processes and the firewall status (ufw, firewalld or the iptables INPUT chain) as JSON. With check_port, also tells whether that port is listening, allowed by the firewall and therefore open. Only reads state; process names need run_as \"root\".",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"check_port": {
+							Type:        "integer",
+							Description: "Port to check (e.g. 443)",
+						},
+						"protocol": {
+							Type:        "string",
+							Description: "Protocol of check_port",
+							Enum:        []string{"tcp", "udp"},
+							Default:     "tcp",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the commands as this remote user via sudo -u, e.g. root",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
-					"remote_path": {
-						Type:        "string",
-						Description: "Remote directory path to list",
-						Default:     ".",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executePortCheck,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "host_metrics",
+				Description: "Sample the CPU, memory, swap and load of a remote host from /proc over a short window (default 5 samples, 2s apart) and report disk usage from df, as JSON numbers with averages and maximums instead of top output. The call blocks for samples × interval (at most 60s).",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"samples": {
+							Type:        "integer",
+							Description: "Number of samples (1-30)",
+							Default:     sshclient.DefaultMetricsSamples,
+						},
+						"interval": {
+							Type:        "string",
+							Description: "Time between samples in whole seconds (e.g. 2s or 5)",
+							Default:     "2s",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
-					"port": {
-						Type:        "string",
-						Description: "SSH port",
-						Default:     "22",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeHostMetrics,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "process_list",
+				Description: "List the processes of a remote host as JSON (PID, parent, owner, state, CPU and memory use, running time, name and command line), filtered by name and owner and sorted by CPU use. Use it to find the PID for process_signal instead of composing ps or pgrep pipelines.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"name": {
+							Type:        "string",
+							Description: "Only processes whose name or command line matches this regular expression",
+						},
+						"process_user": {
+							Type:        "string",
+							Description: "Only processes owned by this user",
+						},
+						"sort": {
+							Type:        "string",
+							Description: "Order: cpu (highest first), memory (largest resident size first) or pid",
+							Enum:        sshclient.ProcessSorts,
+							Default:     sshclient.ProcessSortCPU,
+						},
+						"limit": {
+							Type:        "integer",
+							Description: "Maximum number of processes to return (0 = all)",
+							Default:     defaultProcessLimit,
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
-					"user": {
-						Type:        "string",
-						Description: "SSH username",
-						Default:     "master",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeProcessList,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "process_signal",
+				Description: "Send TERM, KILL, HUP, INT, QUIT, USR1 or USR2 to one process of a remote host by PID. By default only a dry run that reports the process; to send the signal pass dry_run false and expect_name set to the process name from that report, which is checked again on the host right before the signal. After TERM, KILL, INT or QUIT it waits up to 5s and reports whether the process exited. PID 1, kernel threads and essential daemons (sshd, systemd, ...) are refused.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"pid": {
+							Type:        "integer",
+							Description: "Process ID (from process_list)",
+						},
+						"signal": {
+							Type:        "string",
+							Description: "Signal to send",
+							Enum:        sshclient.Signals,
+							Default:     "TERM",
+						},
+						"expect_name": {
+							Type:        "string",
+							Description: "Name the process must have, as reported by process_list or the dry run; required unless dry_run is true",
+						},
+						"dry_run": {
+							Type:        "boolean",
+							Description: "Only look the process up and report what would be signaled",
+							Default:     true,
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Send the signal as this remote user via sudo -u, e.g. root for processes of other users",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
+					Required: []string{"host", "pid"},
 				},
-				Required: []string{"host"},
 			},
+			RemoteHandler: (*MCPServer).executeProcessSignal,
 		},
 		{
-			Name:        "sftp_mkdir",
-			Description: "Create a directory on remote server via SFTP",
-			InputSchema: ToolSchema{
-				Type: "object",
-				Properties: map[string]Property{
-					"host": {
-						Type:        "string",
-						Description: "Remote host address",
+			MCPTool: MCPTool{
+				Name:        "journal_query",
+				Description: "Query the system journal of a remote host (journalctl -o json) by unit, priority, time range and message pattern, returning the most recent matching entries as JSON (time, priority, unit, identifier, PID, message). Hosts without journald fall back to /var/log/syslog or /var/log/messages, where only unit and grep apply. Reading other users' and system logs usually needs run_as \"root\" or membership in the adm or systemd-journal group.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"unit": {
+							Type:        "string",
+							Description: "Comma-separated systemd units, e.g. nginx.service,php-fpm.service",
+						},
+						"priority": {
+							Type:        "string",
+							Description: "Only entries of this priority or more severe",
+							Enum:        sshclient.JournalPriorities,
+						},
+						"since": {
+							Type:        "string",
+							Description: "Only entries newer than this (journalctl time, e.g. \"1 hour ago\", \"today\" or \"2026-01-02 15:04\")",
+						},
+						"until": {
+							Type:        "string",
+							Description: "Only entries older than this (journalctl time)",
+						},
+						"grep": {
+							Type:        "string",
+							Description: "Only entries whose message matches this regular expression (journalctl --grep)",
+						},
+						"lines": {
+							Type:        "integer",
+							Description: fmt.Sprintf("Most recent matching entries to return (at most %d)", sshclient.MaxJournalLines),
+							Default:     sshclient.DefaultJournalLines,
+						},
+						"max_output": {
+							Type:        "string",
+							Description: "Maximum size of the journal output read from the host (e.g. 64K, 1M or a byte count; default: 1M)",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Read the journal as this remote user via sudo -u, e.g. root",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
-					"remote_path": {
-						Type:        "string",
-						Description: "Remote directory path to create",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeJournalQuery,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "cron_list",
+				Description: "List the crontab of a remote user and the systemd timers of the host as JSON: the raw crontab, its jobs (line, schedule, command) and each timer with its schedule, activated unit and next and last run.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"account": {
+							Type:        "string",
+							Description: "User whose crontab to list (default: the SSH user, or run_as when set); other users' crontabs need run_as \"root\"",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Read the crontab as this remote user via sudo -u, e.g. root",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
-					"port": {
-						Type:        "string",
-						Description: "SSH port",
-						Default:     "22",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeCronList,
+			ReadOnly:      true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "cron_add",
+				Description: "Add a job to the crontab of a remote user. By default only a dry run that returns the diff; with dry_run false the previous crontab is saved under ~/.sshx/crontab-backups on the host before the new one is installed, and the install is refused if the crontab changed after it was read. The job's command goes through the safety check. Adding a job that is already there changes nothing.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"entry": {
+							Type:        "string",
+							Description: "Crontab line: five time fields or an @ schedule followed by the command, e.g. \"30 2 * * * /srv/app/bin/report\" or \"@daily /srv/app/bin/rotate\"",
+						},
+						"account": {
+							Type:        "string",
+							Description: "User whose crontab to edit (default: the SSH user, or run_as when set); other users' crontabs need run_as \"root\"",
+						},
+						"dry_run": {
+							Type:        "boolean",
+							Description: "Only show the diff without installing the crontab",
+							Default:     true,
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Edit the crontab as this remote user via sudo -u, e.g. root",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
-					"user": {
-						Type:        "string",
-						Description: "SSH username",
-						Default:     "master",
+					Required: []string{"host", "entry"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeCronAdd,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "cron_remove",
+				Description: "Remove the jobs containing a text from the crontab of a remote user; comments and variable assignments are never removed. By default only a dry run that returns the diff; with dry_run false the previous crontab is saved under ~/.sshx/crontab-backups on the host before the new one is installed, and the install is refused if the crontab changed after it was read.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"match": {
+							Type:        "string",
+							Description: "Text the job lines to remove contain, e.g. the script path",
+						},
+						"account": {
+							Type:        "string",
+							Description: "User whose crontab to edit (default: the SSH user, or run_as when set); other users' crontabs need run_as \"root\"",
+						},
+						"dry_run": {
+							Type:        "boolean",
+							Description: "Only show the diff without installing the crontab",
+							Default:     true,
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Edit the crontab as this remote user via sudo -u, e.g. root",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
+					Required: []string{"host", "match"},
 				},
-				Required: []string{"host", "remote_path"},
 			},
+			RemoteHandler: (*MCPServer).executeCronRemove,
 		},
 		{
-			Name:        "sftp_remove",
-			Description: "Remove a file or directory on remote server via SFTP",
-			InputSchema: ToolSchema{
-				Type: "object",
-				Properties: map[string]Property{
-					"host": {
-						Type:        "string",
-						Description: "Remote host address",
+			MCPTool: MCPTool{
+				Name:        "host_reboot",
+				Description: "Reboot a remote host and wait for it to come back. By default only a dry run of the pre-checks: the command must run as root, the host must have been up for min_uptime and nobody may be logged in (unless allow_sessions). To reboot pass dry_run false and confirm set to the host argument. It then reconnects until the boot ID changes, runs the verify command and returns the boot state before and after, the time the host took to return and the verification output. The reboot itself bypasses the safety check, which blocks reboot commands, but is audited; outside the maintenance windows of the host's safety policy it is refused, with the error saying when the next window opens, unless forced with a reason.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address (IP or hostname)",
+						},
+						"confirm": {
+							Type:        "string",
+							Description: "The host argument repeated, confirming which host to reboot; required unless dry_run is true",
+						},
+						"dry_run": {
+							Type:        "boolean",
+							Description: "Only run the pre-checks",
+							Default:     true,
+						},
+						"min_uptime": {
+							Type:        "string",
+							Description: "Refuse when the host booted less than this long ago, guarding against reboot loops (e.g. 30m; 0 disables the check)",
+							Default:     "10m",
+						},
+						"allow_sessions": {
+							Type:        "boolean",
+							Description: "Reboot even when users are logged in",
+							Default:     false,
+						},
+						"verify": {
+							Type:        "string",
+							Description: "Command run once the host is back, e.g. \"systemctl is-active nginx\"; the result reports whether it succeeded",
+						},
+						"force": {
+							Type:        "boolean",
+							Description: "Reboot outside the maintenance windows of the host (requires force_reason)",
+							Default:     false,
+						},
+						"force_reason": {
+							Type:        "string",
+							Description: "Why the reboot cannot wait for the maintenance window; required with force and recorded in the audit log",
+						},
+						"confirm_token": {
+							Type:        "string",
+							Description: "Current TOTP code required with force on hosts whose safety policy sets force_totp_key",
+						},
+						"timeout": {
+							Type:        "string",
+							Description: "How long to wait for the host to return (e.g. 5m)",
+							Default:     "10m",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Reboot as this remote user via sudo -u; rebooting needs root, so this is root unless the SSH user is",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"key_path": {
+							Type:        "string",
+							Description: "Path to SSH private key",
+						},
 					},
-					"remote_path": {
-						Type:        "string",
-						Description: "Remote file or directory path to remove",
+					Required: []string{"host"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeHostReboot,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "db_dump",
+				Description: "Dump a PostgreSQL (pg_dump) or MySQL (mysqldump) database on a remote host and download it over SFTP, verifying its sha256. A local_path ending in .gz is compressed on the host first.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"engine": {
+							Type:        "string",
+							Description: "Database engine",
+							Enum:        []string{sshclient.DBEnginePostgres, sshclient.DBEngineMySQL},
+						},
+						"database": {
+							Type:        "string",
+							Description: "Database name",
+						},
+						"local_path": {
+							Type:        "string",
+							Description: "Local file the dump is written to (.gz for a gzip compressed dump)",
+						},
+						"db_user": {
+							Type:        "string",
+							Description: "Database user (default: the client tool's default)",
+						},
+						"db_host": {
+							Type:        "string",
+							Description: "Database server as seen from the SSH host (default: local socket)",
+						},
+						"db_port": {
+							Type:        "integer",
+							Description: "Database port (default: the engine's default)",
+						},
+						"db_password_key": {
+							Type:        "string",
+							Description: "Keyring key holding the database password (omit for peer or .pgpass/.my.cnf authentication)",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the database client as this remote user via sudo -u, e.g. postgres for peer authentication",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
 					},
-					"port": {
-						Type:        "string",
-						Description: "SSH port",
-						Default:     "22",
+					Required: []string{"host", "engine", "database", "local_path"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeDBDump,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "db_restore",
+				Description: "Upload a local SQL dump over SFTP, verify its sha256 on the remote host and load it with psql or mysql. Dumps ending in .gz are decompressed on the host.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or remote address",
+						},
+						"engine": {
+							Type:        "string",
+							Description: "Database engine",
+							Enum:        []string{sshclient.DBEnginePostgres, sshclient.DBEngineMySQL},
+						},
+						"database": {
+							Type:        "string",
+							Description: "Database name",
+						},
+						"local_path": {
+							Type:        "string",
+							Description: "Local SQL dump to restore (.gz for a gzip compressed dump)",
+						},
+						"db_user": {
+							Type:        "string",
+							Description: "Database user (default: the client tool's default)",
+						},
+						"db_host": {
+							Type:        "string",
+							Description: "Database server as seen from the SSH host (default: local socket)",
+						},
+						"db_port": {
+							Type:        "integer",
+							Description: "Database port (default: the engine's default)",
+						},
+						"db_password_key": {
+							Type:        "string",
+							Description: "Keyring key holding the database password (omit for peer or .pgpass/.my.cnf authentication)",
+						},
+						"run_as": {
+							Type:        "string",
+							Description: "Run the database client as this remote user via sudo -u, e.g. postgres for peer authentication",
+						},
+						"sudo_key": {
+							Type:        "string",
+							Description: "Key name for the sudo password used with run_as",
+							Default:     "master",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
 					},
-					"user": {
-						Type:        "string",
-						Description: "SSH username",
-						Default:     "master",
+					Required: []string{"host", "engine", "database", "local_path"},
+				},
+			},
+			RemoteHandler: (*MCPServer).executeDBRestore,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "pool_stats",
+				Description: "Get SSH connection pool statistics (active/idle connections, health check interval, etc.)",
+				InputSchema: ToolSchema{
+					Type:       "object",
+					Properties: map[string]Property{},
+					Required:   []string{},
+				},
+			},
+			Handler:  (*MCPServer).getPoolStats,
+			ReadOnly: true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "pool_connections",
+				Description: "List every pooled SSH connection as JSON (pool key, host, in-use, age, last used, idle time, retry count, auth method) together with the pool totals",
+				InputSchema: ToolSchema{
+					Type:       "object",
+					Properties: map[string]Property{},
+					Required:   []string{},
+				},
+			},
+			Handler:  (*MCPServer).executePoolConnections,
+			ReadOnly: true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "server_info",
+				Description: "Describe this server as JSON: version, transports, tools, enabled features (audit log, hooks, host key trust, secrets backend), configured host count and groups, and effective limits",
+				InputSchema: ToolSchema{
+					Type:       "object",
+					Properties: map[string]Property{},
+					Required:   []string{},
+				},
+			},
+			Handler:  (*MCPServer).executeServerInfo,
+			ReadOnly: true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "pool_evict",
+				Description: "Close and drop pooled connections for one host, or all hosts, so the next command reconnects (after rotating keys or rebooting a server)",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name, address or pool key (user@host:port); omit or use \"all\" to flush the whole pool",
+						},
 					},
+					Required: []string{},
 				},
-				Required: []string{"host", "remote_path"},
 			},
+			Handler: (*MCPServer).executePoolEvict,
 		},
 		{
-			Name:        "script_execute",
-			Description: "Upload and execute a local script file on remote server. Automatically detects script type (bash/python/perl/ruby) and cleans up after execution.",
-			InputSchema: ToolSchema{
-				Type: "object",
-				Properties: map[string]Property{
-					"host": {
-						Type:        "string",
-						Description: "Remote host address",
+			MCPTool: MCPTool{
+				Name:        "pool_warm",
+				Description: "Pre-connect to a set of configured hosts in parallel so the first command on each host is fast",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"hosts": {
+							Type:        "string",
+							Description: "Comma-separated host names or group tags (e.g. web1,web2 or prod)",
+						},
+						"skip_unreachable": {
+							Type:        "boolean",
+							Description: "Leave out the hosts a recent host_ping found unreachable, so a dead host does not stall the run",
+							Default:     false,
+						},
+						"only_unreachable": {
+							Type:        "boolean",
+							Description: "Select only the hosts a recent host_ping found unreachable (e.g. to remediate them)",
+							Default:     false,
+						},
 					},
-					"script_path": {
-						Type:        "string",
-						Description: "Local script file path to upload and execute",
+					Required: []string{"hosts"},
+				},
+			},
+			Handler: (*MCPServer).executePoolWarm,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "sftp_distribute",
+				Description: "Upload one local file to multiple configured hosts in parallel. The existing remote file is backed up, an optional validation command runs on each host, and failed hosts are rolled back. With canary or batch_size the upload is rolled out in stages that halt once more than max_failures hosts failed.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"hosts": {
+							Type:        "string",
+							Description: "Comma-separated host names or group tags (e.g. web1,web2 or prod)",
+						},
+						"local_path": {
+							Type:        "string",
+							Description: "Local file path",
+						},
+						"remote_path": {
+							Type:        "string",
+							Description: "Remote file path on every host",
+						},
+						"validate": {
+							Type:        "string",
+							Description: "Optional command run after the copy (e.g. 'sudo nginx -t'); a failure restores the previous file",
+						},
+						"rollback": {
+							Type:        "string",
+							Description: "Optional command run on a host after its previous file was restored, because the upload, validate, expect or check failed (e.g. 'sudo systemctl reload nginx')",
+						},
+						"template": {
+							Type:        "boolean",
+							Description: "Render local_path as a Go template with the host's fields, tags and vars before upload",
+							Default:     false,
+						},
+						"manifest": {
+							Type:        "boolean",
+							Description: "Read every uploaded file back to verify its SHA-256 and return the transfer manifest as JSON (per host: path, size, sha256, verified, status, error) instead of the text report",
+							Default:     false,
+						},
+						"manifest_path": {
+							Type:        "string",
+							Description: "Local file the JSON transfer manifest is saved to (verifies the uploads like manifest)",
+						},
+						"remote_manifest": {
+							Type:        "string",
+							Description: "Remote path the JSON transfer manifest is uploaded to on every updated host (verifies the uploads like manifest)",
+						},
+						"canary": {
+							Type:        "integer",
+							Description: "Run on this many hosts first and halt if any of them fails (0 = no canary stage)",
+						},
+						"batch_size": {
+							Type:        "integer",
+							Description: "After the canary, run on this many hosts at a time (0 = all the rest at once)",
+						},
+						"max_failures": {
+							Type:        "integer",
+							Description: "Failed hosts tolerated before the rollout halts and skips the remaining hosts (default: 0)",
+						},
+						"expect": {
+							Type:        "string",
+							Description: "Regular expression the output of validate must match on each host",
+						},
+						"check": {
+							Type:        "string",
+							Description: "Command run on each host afterwards (e.g. 'curl -fsS localhost/health'); a failure counts the host as failed",
+						},
+						"skip_unreachable": {
+							Type:        "boolean",
+							Description: "Leave out the hosts a recent host_ping found unreachable, so a dead host does not stall the run",
+							Default:     false,
+						},
+						"only_unreachable": {
+							Type:        "boolean",
+							Description: "Select only the hosts a recent host_ping found unreachable (e.g. to remediate them)",
+							Default:     false,
+						},
 					},
-					"args": {
-						Type:        "string",
-						Description: "Optional arguments to pass to the script (space-separated)",
+					Required: []string{"hosts", "local_path", "remote_path"},
+				},
+			},
+			Handler: (*MCPServer).executeSftpDistribute,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "sftp_collect",
+				Description: "Collect the same file or command output from multiple configured hosts in parallel. Files are saved to <local_dir>/<host>/; command output is returned with a header per host, or with aggregate grouped by identical output, the outliers shown as diffs against the most common output. With canary or batch_size a command is rolled out in stages that halt once more than max_failures hosts failed.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"hosts": {
+							Type:        "string",
+							Description: "Comma-separated host names or group tags (e.g. web1,web2 or prod)",
+						},
+						"remote_path": {
+							Type:        "string",
+							Description: "Remote file to download from every host (requires local_dir)",
+						},
+						"command": {
+							Type:        "string",
+							Description: "Command whose output is collected from every host (instead of remote_path)",
+						},
+						"rollback": {
+							Type:        "string",
+							Description: "Optional command run on each host where command, expect or check failed, to undo what command changed",
+						},
+						"local_dir": {
+							Type:        "string",
+							Description: "Local directory receiving one subdirectory per host",
+						},
+						"manifest": {
+							Type:        "boolean",
+							Description: "With remote_path, return the transfer manifest as JSON (per host: local path, size, sha256, status, error) instead of the text report",
+							Default:     false,
+						},
+						"manifest_path": {
+							Type:        "string",
+							Description: "With remote_path, local file the JSON transfer manifest is saved to",
+						},
+						"max_size": {
+							Type:        "string",
+							Description: "Per-host size cap (e.g. 10M, default: 50M); larger files are skipped, output is truncated",
+						},
+						"aggregate": {
+							Type:        "boolean",
+							Description: "With command, group hosts by identical output (e.g. 18 hosts returned X, 2 returned Y) and show a diff of each outlier group",
+							Default:     false,
+						},
+						"canary": {
+							Type:        "integer",
+							Description: "Run on this many hosts first and halt if any of them fails (0 = no canary stage)",
+						},
+						"batch_size": {
+							Type:        "integer",
+							Description: "After the canary, run on this many hosts at a time (0 = all the rest at once)",
+						},
+						"max_failures": {
+							Type:        "integer",
+							Description: "Failed hosts tolerated before the rollout halts and skips the remaining hosts (default: 0)",
+						},
+						"expect": {
+							Type:        "string",
+							Description: "Regular expression the command output must match on each host",
+						},
+						"check": {
+							Type:        "string",
+							Description: "Command run on each host afterwards (e.g. 'curl -fsS localhost/health'); a failure counts the host as failed",
+						},
+						"skip_unreachable": {
+							Type:        "boolean",
+							Description: "Leave out the hosts a recent host_ping found unreachable, so a dead host does not stall the run",
+							Default:     false,
+						},
+						"only_unreachable": {
+							Type:        "boolean",
+							Description: "Select only the hosts a recent host_ping found unreachable (e.g. to remediate them)",
+							Default:     false,
+						},
 					},
-					"port": {
-						Type:        "string",
-						Description: "SSH port",
-						Default:     "22",
+					Required: []string{"hosts"},
+				},
+			},
+			Handler: (*MCPServer).executeSftpCollect,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "job_list",
+				Description: "List the scheduled jobs of the MCP server with their next run and the result of their last run",
+				InputSchema: ToolSchema{
+					Type:       "object",
+					Properties: map[string]Property{},
+				},
+			},
+			Handler:  (*MCPServer).executeJobList,
+			ReadOnly: true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "job_add",
+				Description: "Add or replace a scheduled job: a command run on configured hosts on a cron schedule while the MCP server is running. The job is saved to settings.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"job": {
+							Type:        "string",
+							Description: "Job name (unique identifier)",
+						},
+						"schedule": {
+							Type:        "string",
+							Description: "Cron expression: minute hour day-of-month month day-of-week (e.g. \"*/15 * * * *\", \"0 3 * * mon\") or @hourly, @daily, @weekly, @monthly",
+						},
+						"hosts": {
+							Type:        "string",
+							Description: "Comma-separated host names or group tags (e.g. web1,web2 or prod)",
+						},
+						"command": {
+							Type:        "string",
+							Description: "Command run on every host",
+						},
+						"disabled": {
+							Type:        "boolean",
+							Description: "Save the job without running it on schedule (job_run_now still runs it)",
+							Default:     false,
+						},
 					},
-					"user": {
-						Type:        "string",
-						Description: "SSH username",
-						Default:     "master",
+					Required: []string{"job", "schedule", "hosts", "command"},
+				},
+			},
+			Handler: (*MCPServer).executeJobAdd,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "job_run_now",
+				Description: "Run a scheduled job immediately on all of its hosts and return the output per host",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"job": {
+							Type:        "string",
+							Description: "Job name",
+						},
 					},
+					Required: []string{"job"},
 				},
-				Required: []string{"host", "script_path"},
 			},
+			Handler: (*MCPServer).executeJobRunNow,
 		},
 		{
-			Name:        "pool_stats",
-			Description: "Get SSH connection pool statistics (active/idle connections, health check interval, etc.)",
-			InputSchema: ToolSchema{
-				Type:       "object",
-				Properties: map[string]Property{},
-				Required:   []string{},
+			MCPTool: MCPTool{
+				Name:        "host_add",
+				Description: "Add a new host configuration to settings",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"name": {
+							Type:        "string",
+							Description: "Host name (unique identifier)",
+						},
+						"host": {
+							Type:        "string",
+							Description: "Host address (IP or hostname)",
+						},
+						"description": {
+							Type:        "string",
+							Description: "Host description (optional)",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port",
+							Default:     22,
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH username",
+							Default:     "master",
+						},
+						"password_key": {
+							Type:        "string",
+							Description: "Password key name (optional)",
+						},
+						"type": {
+							Type:        "string",
+							Description: "System type",
+							Enum:        []string{"linux", "windows", "macos"},
+							Default:     "linux",
+						},
+						"device": {
+							Type:        "string",
+							Description: "Network device profile for switches and routers (optional): cisco_ios, junos, routeros, generic or a device_profiles entry of settings.json. Commands are typed into an interactive shell, one per line, \"@<template>\" runs a command template; SFTP is unavailable",
+						},
+						"shell": {
+							Type:        "string",
+							Description: "Shell commands run in (default: the login shell, powershell for windows hosts)",
+							Enum:        sshclient.Shells,
+						},
+						"tags": {
+							Type:        "string",
+							Description: "Comma-separated group tags (optional, e.g. prod,web)",
+						},
+						"key": {
+							Type:        "string",
+							Description: "SSH private key path for this host (optional, overrides the default key)",
+						},
+						"meta": {
+							Type:        "string",
+							Description: "Inventory metadata as comma-separated key=value pairs (optional, e.g. rack=r12,owner=payments,env=prod)",
+						},
+						"env": {
+							Type:        "string",
+							Description: "Environment variables exported for every command on the host, as NAME=VALUE lines (optional, e.g. \"HTTP_PROXY=http://proxy:3128\\nNO_PROXY=localhost,10.0.0.0/8\")",
+						},
+						"addresses": {
+							Type:        "string",
+							Description: "Comma-separated addresses dialed in order before host (optional), e.g. a WireGuard IP, or tailscale / tailscale:<machine> for the tailnet IP of a MagicDNS name",
+						},
+						"ttl": {
+							Type:        "string",
+							Description: "Time to live for an ephemeral host such as a cloud instance or CI runner (e.g. 4h, 7d); it is removed from settings and the connection pool once it expires",
+						},
+					},
+					Required: []string{"name", "host"},
+				},
 			},
+			Handler: (*MCPServer).executeHostAdd,
 		},
 		{
-			Name:        "host_add",
-			Description: "Add a new host configuration to settings",
-			InputSchema: ToolSchema{
-				Type: "object",
-				Properties: map[string]Property{
-					"name": {
-						Type:        "string",
-						Description: "Host name (unique identifier)",
+			MCPTool: MCPTool{
+				Name:        "host_update",
+				Description: "Change fields of a configured host. Only the fields provided are changed; an empty string clears an optional field (port and user fall back to 22 and master).",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"name": {
+							Type:        "string",
+							Description: "Name of the host to update",
+						},
+						"host": {
+							Type:        "string",
+							Description: "New host address (IP or hostname)",
+						},
+						"addresses": {
+							Type:        "string",
+							Description: "Comma-separated addresses dialed in order before host, replacing the current ones",
+						},
+						"description": {
+							Type:        "string",
+							Description: "New description",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "New SSH port",
+						},
+						"user": {
+							Type:        "string",
+							Description: "New SSH username",
+						},
+						"password_key": {
+							Type:        "string",
+							Description: "New password key name",
+						},
+						"type": {
+							Type:        "string",
+							Description: "New system type",
+							Enum:        []string{"linux", "windows", "macos"},
+						},
+						"device": {
+							Type:        "string",
+							Description: "New network device profile (empty for a regular host)",
+						},
+						"shell": {
+							Type:        "string",
+							Description: "New shell commands run in (empty for the login shell)",
+						},
+						"tags": {
+							Type:        "string",
+							Description: "Comma-separated group tags, replacing the current ones",
+						},
+						"key": {
+							Type:        "string",
+							Description: "SSH private key path for this host",
+						},
+						"meta": {
+							Type:        "string",
+							Description: "Comma-separated key=value pairs merged into the metadata; an empty value removes the key, an empty string removes all metadata",
+						},
+						"env": {
+							Type:        "string",
+							Description: "NAME=VALUE lines merged into the environment variables exported for every command; an empty value removes the variable, an empty string removes all of them",
+						},
+						"ttl": {
+							Type:        "string",
+							Description: "New time to live counted from now (e.g. 4h, 7d); an empty string makes the host permanent",
+						},
 					},
-					"host": {
-						Type:        "string",
-						Description: "Host address (IP or hostname)",
+					Required: []string{"name"},
+				},
+			},
+			Handler: (*MCPServer).executeHostUpdate,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "host_list",
+				Description: "List all configured hosts, or those whose metadata matches find, optionally grouped by a metadata key",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"find": {
+							Type:        "string",
+							Description: "Comma-separated metadata conditions that all have to hold: key=value (value may be a glob, e.g. rack=r1*) or a bare key the host has to have",
+						},
+						"group_by": {
+							Type:        "string",
+							Description: "Metadata key to group the hosts by (e.g. environment)",
+						},
 					},
-					"description": {
-						Type:        "string",
-						Description: "Host description (optional)",
+					Required: []string{},
+				},
+			},
+			Handler:  (*MCPServer).executeHostList,
+			ReadOnly: true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "host_test",
+				Description: "Test connection to a configured host. Returns JSON with success, the failed stage, auth method, connect/auth/command latency in ms, server version and banner",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"name": {
+							Type:        "string",
+							Description: "Host name to test",
+						},
 					},
-					"port": {
-						Type:        "string",
-						Description: "SSH port",
-						Default:     "22",
+					Required: []string{"name"},
+				},
+			},
+			Handler: (*MCPServer).executeHostTest,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "host_ping",
+				Description: "Quickly check whether hosts accept TCP connections on their SSH port (no authentication), with latency. Use it to decide whether running a command is worth attempting.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"hosts": {
+							Type:        "string",
+							Description: "Comma-separated host names, group tags or addresses (host or host:port)",
+						},
+						"icmp": {
+							Type:        "boolean",
+							Description: "Also send an ICMP ping using the system ping command",
+							Default:     false,
+						},
 					},
-					"user": {
-						Type:        "string",
-						Description: "SSH username",
-						Default:     "master",
+					Required: []string{"hosts"},
+				},
+			},
+			Handler:  (*MCPServer).executeHostPing,
+			ReadOnly: true,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "host_import",
+				Description: "Import hosts from an OpenSSH config (Include, Match and %-tokens supported) or a JSON hosts file. Returns a JSON summary of imported, overwritten, skipped and conflicted entries.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"source": {
+							Type:        "string",
+							Description: "Where to import from",
+							Enum:        []string{"ssh_config", "file"},
+							Default:     "ssh_config",
+						},
+						"path": {
+							Type:        "string",
+							Description: "ssh config path (default: ~/.ssh/config), or the JSON file for source=file (settings object or array of hosts)",
+						},
+						"overwrite": {
+							Type:        "boolean",
+							Description: "Replace the address, port and user of existing hosts whose details differ",
+							Default:     false,
+						},
 					},
-					"password_key": {
-						Type:        "string",
-						Description: "Password key name (optional)",
+				},
+			},
+			Handler: (*MCPServer).executeHostImport,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "host_diagnose",
+				Description: "Diagnose why a host cannot be reached or logged into. Returns JSON with each step (dns, tcp_connect, ssh_banner, host_key, auth_methods, authentication), its status and timing, the auth methods offered by the server versus those configured, and the first failing step.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Configured host name or address",
+						},
+						"port": {
+							Type:        "integer",
+							Description: "SSH port when host is an address (default: 22)",
+						},
+						"user": {
+							Type:        "string",
+							Description: "SSH user when host is an address",
+						},
 					},
-					"type": {
-						Type:        "string",
-						Description: "System type",
-						Enum:        []string{"linux", "windows", "macos"},
-						Default:     "linux",
+					Required: []string{"host"},
+				},
+			},
+			Handler: (*MCPServer).executeHostDiagnose,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "host_remove",
+				Description: "Remove a host from configuration",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"name": {
+							Type:        "string",
+							Description: "Host name to remove",
+						},
 					},
+					Required: []string{"name"},
+				},
+			},
+			Handler: (*MCPServer).executeHostRemove,
+		},
+		{
+			MCPTool: MCPTool{
+				Name:        "known_hosts_list",
+				Description: "List host keys accepted by the MCP server (kept in ~/.sshmcp/known_hosts_mcp, separate from ~/.ssh/known_hosts)",
+				InputSchema: ToolSchema{
+					Type:       "object",
+					Properties: map[string]Property{},
 				},
-				Required: []string{"name", "host"},
 			},
+			Handler:  (*MCPServer).executeKnownHostsList,
+			ReadOnly: true,
 		},
 		{
-			Name:        "host_list",
-			Description: "List all configured hosts",
-			InputSchema: ToolSchema{
-				Type:       "object",
-				Properties: map[string]Property{},
-				Required:   []string{},
+			MCPTool: MCPTool{
+				Name:        "known_hosts_promote",
+				Description: "Move a host key accepted by the MCP server into the user's ~/.ssh/known_hosts",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Host name or address of the entry to promote",
+						},
+					},
+					Required: []string{"host"},
+				},
 			},
+			Handler: (*MCPServer).executeKnownHostsPromote,
 		},
 		{
-			Name:        "host_test",
-			Description: "Test connection to a configured host",
-			InputSchema: ToolSchema{
-				Type: "object",
-				Properties: map[string]Property{
-					"name": {
-						Type:        "string",
-						Description: "Host name to test",
+			MCPTool: MCPTool{
+				Name:        "known_hosts_deny",
+				Description: "Revoke a host key accepted by the MCP server so future connections are refused",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"host": {
+							Type:        "string",
+							Description: "Host name or address of the entry to deny",
+						},
 					},
+					Required: []string{"host"},
 				},
-				Required: []string{"name"},
 			},
+			Handler: (*MCPServer).executeKnownHostsDeny,
 		},
 		{
-			Name:        "host_remove",
-			Description: "Remove a host from configuration",
-			InputSchema: ToolSchema{
-				Type: "object",
-				Properties: map[string]Property{
-					"name": {
-						Type:        "string",
-						Description: "Host name to remove",
+			MCPTool: MCPTool{
+				Name:        localExecTool,
+				Description: "LOCAL: run a shell command on the machine running this MCP server, NOT on a remote host (e.g. build an artifact or read terraform output between remote steps). Only available when enabled in settings (local_exec.enabled) or with --allow-local-exec; checked against the local_exec safety policy and written to the audit log as host 'local'.",
+				InputSchema: ToolSchema{
+					Type: "object",
+					Properties: map[string]Property{
+						"command": {
+							Type:        "string",
+							Description: "Command run with sh -c (cmd /C on Windows) on the local machine",
+						},
+						"timeout": {
+							Type:        "string",
+							Description: "Kill the command after this long (e.g. 30s, 10m; default: local_exec.timeout or 5m)",
+						},
+						"max_output": {
+							Type:        "string",
+							Description: "Maximum output size to return (e.g. 64K, 1M or a byte count; default: 1M). Larger output keeps its head and tail with a truncation marker",
+						},
 					},
+					Required: []string{"command"},
 				},
-				Required: []string{"name"},
 			},
+			Handler: (*MCPServer).executeLocalExec,
 		},
 	}
 }
@@ -391,6 +2657,9 @@ func defineMCPTools() []MCPTool {
 func (s *MCPServer) Start() error {
 	// In MCP stdio mode, log output is disabled to avoid interfering with JSON-RPC communication
 	// log is set to io.Discard in main.go
+	// Log messages are forwarded as notifications once the client has initialized
+	defer logger.GetLogger().SetSink(defaultMCPLogLevel, nil)
+	defer s.unsubscribeEvents()
 
 	for {
 		line, err := s.stdin.ReadString('\n')
@@ -442,9 +2711,20 @@ func (s *MCPServer) handleRequest(req *MCPRequest) {
 		s.handleToolsList(req)
 	case "tools/call":
 		s.handleToolsCall(req)
+	case "logging/setLevel":
+		s.handleSetLogLevel(req)
+	case "resources/list":
+		s.handleResourcesList(req)
+	case "resources/read":
+		s.handleResourcesRead(req)
+	case "resources/subscribe":
+		s.handleResourcesSubscribe(req)
+	case "resources/unsubscribe":
+		s.handleResourcesUnsubscribe(req)
 	case "shutdown":
 		logger.GetLogger().Debug("MCP shutdown requested")
 		s.sendResponse(req.ID, map[string]interface{}{})
+		runShutdownHooks()
 		os.Exit(0)
 	default:
 		logger.GetLogger().Debug("MCP unknown method: %s", req.Method)
@@ -454,23 +2734,81 @@ func (s *MCPServer) handleRequest(req *MCPRequest) {
 
 // handleInitialize 处理初始化请求
 func (s *MCPServer) handleInitialize(req *MCPRequest) {
+	var params struct {
+		ClientInfo struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"clientInfo"`
+	}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			logger.GetLogger().Debug("MCP initialize - Invalid params: %v", err)
+		}
+	}
+	s.client = clientIdentity(params.ClientInfo.Name, params.ClientInfo.Version)
+	logger.GetLogger().Debug("MCP initialize - Client: %s", s.client)
+
 	result := map[string]interface{}{
 		"protocolVersion": "2024-11-05",
 		"capabilities": map[string]interface{}{
-			"tools": map[string]interface{}{},
+			"tools":     map[string]interface{}{},
+			"logging":   map[string]interface{}{},
+			"resources": map[string]interface{}{"subscribe": true},
 		},
 		"serverInfo": map[string]interface{}{
 			"name":    "sshx-mcp-server",
-			"version": "1.0.0",
+			"version": serverVersion(),
 		},
 	}
 	s.sendResponse(req.ID, result)
+
+	// 初始化之后才能发送通知
+	logger.GetLogger().SetSink(defaultMCPLogLevel, s.sendLogMessage)
+}
+
+// clientIdentity 把 clientInfo 组合成 "name/version"，去掉控制字符以免污染审计日志
+func clientIdentity(name, version string) string {
+	clean := func(s string) string {
+		return strings.TrimSpace(strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, s))
+	}
+	name, version = clean(name), clean(version)
+	if name == "" || version == "" {
+		return name
+	}
+	return name + "/" + version
+}
+
+// identify 记录发起操作的客户端和令牌身份，写入审计日志和远程的 SSHX_CALLER
+func (s *MCPServer) identify(config *sshclient.Config) {
+	config.Client = s.client
+	if s.caller != nil {
+		config.Caller = s.caller.Name
+	}
+}
+
+// auditSource 返回审计日志中的来源：MCP 服务器为 "mcp"，REST 守护进程为 "daemon"
+func (s *MCPServer) auditSource() string {
+	if s.source == "" {
+		return "mcp"
+	}
+	return s.source
 }
 
 // handleToolsList 处理工具列表请求
 func (s *MCPServer) handleToolsList(req *MCPRequest) {
+	tools := make([]MCPTool, 0, len(s.tools))
+	for _, tool := range s.tools {
+		if s.caller.allowsTool(tool.Name) {
+			tools = append(tools, tool)
+		}
+	}
 	result := map[string]interface{}{
-		"tools": s.tools,
+		"tools": tools,
 	}
 	s.sendResponse(req.ID, result)
 }
@@ -480,6 +2818,9 @@ func (s *MCPServer) handleToolsCall(req *MCPRequest) {
 	var params struct {
 		Name      string                 `json:"name"`
 		Arguments map[string]interface{} `json:"arguments"`
+		Meta      struct {
+			ProgressToken interface{} `json:"progressToken"`
+		} `json:"_meta"`
 	}
 
 	if err := json.Unmarshal(req.Params, &params); err != nil {
@@ -495,18 +2836,49 @@ func (s *MCPServer) handleToolsCall(req *MCPRequest) {
 		logger.GetLogger().Debug("MCP tools/call - Tool: %s, Arguments: %v", params.Name, params.Arguments)
 	}
 
+	if authErr := s.authorizeToolCall(params.Name, params.Arguments); authErr != nil {
+		logger.GetLogger().Debug("MCP tools/call - Denied: %v", authErr)
+		s.sendError(req.ID, -32001, authErr.Error(), map[string]interface{}{
+			"tool": params.Name,
+		})
+		return
+	}
+
+	if params.Meta.ProgressToken != nil {
+		s.progress = s.progressNotifier(params.Meta.ProgressToken)
+		defer func() { s.progress = nil }()
+	}
+
 	result, err := s.executeTool(params.Name, params.Arguments)
+	var argErr *ToolArgumentError
+	if errors.As(err, &argErr) {
+		logger.GetLogger().Debug("MCP tools/call - Invalid arguments: %v", err)
+		s.sendError(req.ID, -32602, "Invalid params: "+argErr.Error(), map[string]interface{}{
+			"tool":  argErr.Tool,
+			"field": argErr.Field,
+			"error": argErr.Reason,
+		})
+		return
+	}
 	if err != nil {
-		// 构建更详细的错误消息
-		errorMsg := fmt.Sprintf("Tool '%s' execution failed: %s", params.Name, err.Error())
+		// 构建更详细的错误消息(屏蔽已知的敏感值)
+		errText := logger.Redact(err.Error())
+		errorMsg := fmt.Sprintf("Tool '%s' execution failed: %s", params.Name, errText)
 		logger.GetLogger().Debug("MCP tools/call - Execution failed: %v", err)
-		s.sendError(req.ID, -32000, errorMsg, map[string]interface{}{
+		data := map[string]interface{}{
 			"tool":      params.Name,
 			"arguments": params.Arguments,
-			"error":     err.Error(),
-		})
+			"error":     errText,
+		}
+		// 被安全检查拦截时附带结构化说明，便于客户端改写命令而不是直接 force
+		var blocked *sshclient.BlockedCommandError
+		if errors.As(err, &blocked) {
+			data["blocked"] = blocked
+		}
+		s.sendError(req.ID, -32000, errorMsg, data)
 		return
 	}
+	result = logger.Redact(result)
 
 	// Debug log: print execution result
 	if logger.GetLogger().GetLevel() <= logger.LogLevelDebug {
@@ -542,6 +2914,21 @@ func (s *MCPServer) handleToolsCall(req *MCPRequest) {
 
 // executeTool 执行工具
 func (s *MCPServer) executeTool(name string, args map[string]interface{}) (string, error) {
+	spec, ok := s.registry[name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+	if schema, ok := spec.InputSchema.(ToolSchema); ok {
+		validated, err := validateToolArgs(name, schema, args)
+		if err != nil {
+			return "", err
+		}
+		args = validated
+	}
+	if spec.RemoteHandler == nil {
+		return spec.Handler(s, args)
+	}
+
 	// 构建配置
 	config := &sshclient.Config{UseKeyAuth: true}
 
@@ -556,7 +2943,7 @@ func (s *MCPServer) executeTool(name string, args map[string]interface{}) (strin
 		config.Host = "0.0.0.0"
 	}
 
-	if port, ok := args["port"].(string); ok {
+	if port := stringArg(args, "port"); port != "" {
 		config.Port = port
 	} else {
 		config.Port = sshclient.DefaultSSHPort
@@ -568,53 +2955,25 @@ func (s *MCPServer) executeTool(name string, args map[string]interface{}) (strin
 	}
 	if keyPath, ok := args["key_path"].(string); ok {
 		config.KeyPath = keyPath
-	} else if settingsErr == nil && settings.Key != "" {
-		// 使用 settings 中的默认密钥
-		config.KeyPath = settings.Key
-	}
-	if useKeyAuth, ok := args["use_key_auth"].(bool); ok {
-		config.UseKeyAuth = useKeyAuth
-		if !useKeyAuth {
-			config.KeyPath = ""
-		}
-	} else if useKeyAuthStr, ok := args["use_key_auth"].(string); ok {
-		config.UseKeyAuth = strings.EqualFold(useKeyAuthStr, "true") || useKeyAuthStr == "1"
-		if !config.UseKeyAuth {
-			config.KeyPath = ""
-		}
+	}
+	if _, ok := args["use_key_auth"]; ok {
+		config.UseKeyAuth = boolArg(args, "use_key_auth")
 	}
 	if !config.UseKeyAuth {
 		config.KeyPath = ""
 	}
-
-	switch name {
-	case "ssh_execute":
-		return s.executeSSH(config, args)
-	case "sftp_upload":
-		return s.executeSftpUpload(config, args)
-	case "sftp_download":
-		return s.executeSftpDownload(config, args)
-	case "sftp_list":
-		return s.executeSftpList(config, args)
-	case "sftp_mkdir":
-		return s.executeSftpMkdir(config, args)
-	case "sftp_remove":
-		return s.executeSftpRemove(config, args)
-	case "script_execute":
-		return s.executeScript(config, args)
-	case "pool_stats":
-		return s.getPoolStats()
-	case "host_add":
-		return s.executeHostAdd(args)
-	case "host_list":
-		return s.executeHostList(args)
-	case "host_test":
-		return s.executeHostTest(args)
-	case "host_remove":
-		return s.executeHostRemove(args)
-	default:
-		return "", fmt.Errorf("unknown tool: %s", name)
+	if settingsErr == nil {
+		// 与 CLI 相同的主机解析：别名、密码键、密钥和连接设置
+		if config.Host != "0.0.0.0" {
+			applyHostSettings(config, settings)
+		}
+		applyHostKeySettings(config, settings)
 	}
+	applyMCPTrust(config, settings)
+	s.identify(config)
+	config.Progress = s.progress
+
+	return spec.RemoteHandler(s, config, args)
 }
 
 // executeSSH 执行SSH命令
@@ -628,39 +2987,33 @@ func (s *MCPServer) executeSSH(config *sshclient.Config, args map[string]interfa
 	if !ok {
 		return "", fmt.Errorf("command is required")
 	}
-	config.Command = command
 
 	// 默认启用安全检查
 	config.SafetyCheck = true
 
-	// 处理 force 参数
-	if force, ok := args["force"].(string); ok {
-		config.Force = force == "true"
-	} else {
-		config.Force = false
-	}
+	applyForceArgs(config, args)
 
-	// 处理 sudo
-	if sudoKey, ok := args["sudo_key"].(string); ok {
+	// 处理 sudo：显式的 sudo_key 优先，其次是主机配置的密码键
+	if sudoKey, ok := args["sudo_key"].(string); ok && sudoKey != "" {
 		config.SudoKey = sudoKey
-	} else {
+	} else if config.SudoKey == "" {
 		config.SudoKey = sshclient.DefaultSudoKey
 	}
 
-	// 尝试从 settings 获取主机配置的密码键
-	settings, settingsErr := LoadSettings()
-	if settingsErr == nil {
-		// 尝试查找主机配置
-		for _, host := range settings.Hosts {
-			if host.Host == config.Host && host.PasswordKey != "" {
-				config.SudoKey = host.PasswordKey
-				break
-			}
-		}
+	config.Source = s.auditSource()
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	applyOutputLimit(config, settings, args)
+	encoding, err := applyOutputEncoding(config, args)
+	if err != nil {
+		return "", err
 	}
 
-	// 只有当命令包含 sudo 时才获取密码
-	if strings.Contains(command, "sudo") && config.SudoKey != "" {
+	// 以其他用户运行时通过 sudo -u 切换
+	runAs, _ := args["run_as"].(string) //nolint:errcheck // optional
+	applyShellArgs(config, args)
+
+	// 只有当命令包含 sudo 或需要切换用户时才获取密码
+	if (strings.Contains(command, "sudo") || runAs != "") && config.SudoKey != "" {
 		password, pwdErr := sshclient.GetSudoPassword(config.SudoKey)
 		if pwdErr == nil {
 			config.Password = password
@@ -681,15 +3034,18 @@ func (s *MCPServer) executeSSH(config *sshclient.Config, args map[string]interfa
 		return "", fmt.Errorf("failed to connect: %w", err)
 	}
 
-	// 使用新的 ExecuteCommandWithOutput 方法直接获取输出
-	output, err = client.ExecuteCommandWithOutput()
+	// 以独立请求执行命令，不修改共享的 config
+	result, err := client.Execute(sshclient.Request{Command: command, RunAs: runAs, Container: config.Container})
 	if err != nil {
 		// 返回详细的错误信息,包含命令和完整的错误详情
 		return "", fmt.Errorf("failed to execute command '%s' on %s@%s:%s - %w",
 			command, config.User, config.Host, config.Port, err)
 	}
 
-	return output, nil
+	if encoding == OutputEncodingBase64 {
+		return encodeOutput([]byte(result.Output), result.Stderr)
+	}
+	return annotateSafety(result.Output, result.Safety), nil
 }
 
 // executeSftpUpload 执行SFTP上传
@@ -699,19 +3055,34 @@ func (s *MCPServer) executeSftpUpload(config *sshclient.Config, args map[string]
 		return "MCP Tool: sftp_upload\nStatus: Ready\nNote: Please provide valid parameters to upload files.\nExample: {\"host\": \"192.168.1.100\", \"local_path\": \"/local/file.txt\", \"remote_path\": \"/remote/file.txt\"}", nil
 	}
 
-	localPath, ok := args["local_path"].(string)
-	if !ok {
-		return "", fmt.Errorf("local_path is required")
-	}
 	remotePath, ok := args["remote_path"].(string)
 	if !ok {
 		return "", fmt.Errorf("remote_path is required")
 	}
+	config.UploadBackup = boolArg(args, "backup")
+	config.DeltaUpload = boolArg(args, "delta")
+	content, inline, err := inlineUploadContent(args)
+	if err != nil {
+		return "", err
+	}
+	if inline {
+		return s.uploadInlineContent(config, remotePath, content, boolArg(args, "template"))
+	}
+	localPath, ok := args["local_path"].(string)
+	if !ok {
+		return "", fmt.Errorf("local_path, content or content_base64 is required")
+	}
 
-	config.Mode = "sftp"
-	config.SftpAction = "upload"
 	config.LocalPath = localPath
-	config.RemotePath = remotePath
+
+	if boolArg(args, "template") {
+		config.UploadTemplate = true
+		cleanup, tmplErr := renderUploadTemplate(config)
+		if tmplErr != nil {
+			return "", tmplErr
+		}
+		defer cleanup()
+	}
 
 	client, err := sshclient.NewSSHClient(config)
 	if err != nil {
@@ -725,13 +3096,40 @@ func (s *MCPServer) executeSftpUpload(config *sshclient.Config, args map[string]
 		return "", err
 	}
 
-	if err := client.ExecuteSftp(); err != nil {
+	if err := client.ExecuteSftp(sshclient.Request{SftpAction: "upload", LocalPath: config.LocalPath, RemotePath: remotePath}); err != nil {
 		return "", err
 	}
 
 	return fmt.Sprintf("File uploaded successfully: %s -> %s", localPath, remotePath), nil
 }
 
+// uploadInlineContent 将调用方直接提供的内容写入远程文件
+func (s *MCPServer) uploadInlineContent(config *sshclient.Config, remotePath string, content []byte, template bool) (result string, err error) {
+	if template {
+		if content, err = renderHostTemplate(config, path.Base(remotePath), content); err != nil {
+			return "", err
+		}
+	}
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+
+	if err = client.Connect(); err != nil {
+		return "", err
+	}
+
+	written, err := client.WriteFile(remotePath, content)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Content uploaded successfully: %d bytes -> %s", written, remotePath), nil
+}
+
 // executeSftpDownload 执行SFTP下载
 func (s *MCPServer) executeSftpDownload(config *sshclient.Config, args map[string]interface{}) (result string, err error) {
 	// 检查是否为测试调用
@@ -743,15 +3141,19 @@ func (s *MCPServer) executeSftpDownload(config *sshclient.Config, args map[strin
 	if !ok {
 		return "", fmt.Errorf("remote_path is required")
 	}
-	localPath, ok := args["local_path"].(string)
-	if !ok {
-		return "", fmt.Errorf("local_path is required")
+	localPath := stringArg(args, "local_path")
+	_, hasOffset := args["offset"]
+	_, hasLength := args["length"]
+	if hasOffset || hasLength {
+		return s.downloadRange(config, remotePath, args)
+	}
+	maxInline := int64(intArg(args, "max_inline_bytes", 0))
+	if localPath == "" && maxInline == 0 {
+		maxInline = defaultInlineBytes
+	}
+	if maxInline < 0 || maxInline > maxInlineBytes {
+		return "", fmt.Errorf("max_inline_bytes must be between 0 and %d", maxInlineBytes)
 	}
-
-	config.Mode = "sftp"
-	config.SftpAction = "download"
-	config.LocalPath = localPath
-	config.RemotePath = remotePath
 
 	client, err := sshclient.NewSSHClient(config)
 	if err != nil {
@@ -765,28 +3167,75 @@ func (s *MCPServer) executeSftpDownload(config *sshclient.Config, args map[strin
 		return "", err
 	}
 
-	if err := client.ExecuteSftp(); err != nil {
+	// 小文件直接返回内容，超过上限时再写入 local_path
+	if maxInline > 0 {
+		data, readErr := client.ReadFile(remotePath, maxInline)
+		var tooLarge *sshclient.FileTooLargeError
+		switch {
+		case readErr == nil:
+			return inlineFileContent(data)
+		case !errors.As(readErr, &tooLarge):
+			return "", readErr
+		case localPath == "":
+			return "", fmt.Errorf("%w; set local_path to download it", readErr)
+		}
+	}
+
+	if err := client.ExecuteSftp(sshclient.Request{SftpAction: "download", LocalPath: localPath, RemotePath: remotePath}); err != nil {
 		return "", err
 	}
 
 	return fmt.Sprintf("File downloaded successfully: %s -> %s", remotePath, localPath), nil
 }
 
-// executeSftpList 执行SFTP列表
-func (s *MCPServer) executeSftpList(config *sshclient.Config, args map[string]interface{}) (result string, err error) {
+// downloadRange 只读取文件的一段，用于查看大文件的末尾而不下载整个文件
+func (s *MCPServer) downloadRange(config *sshclient.Config, remotePath string, args map[string]interface{}) (result string, err error) {
+	if stringArg(args, "local_path") != "" {
+		return "", fmt.Errorf("offset and length cannot be combined with local_path")
+	}
+	offset := int64(intArg(args, "offset", 0))
+	length := int64(intArg(args, "length", defaultInlineBytes))
+	if length <= 0 || length > maxInlineBytes {
+		return "", fmt.Errorf("length must be between 1 and %d", maxInlineBytes)
+	}
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+
+	if err = client.Connect(); err != nil {
+		return "", err
+	}
+
+	fileRange, err := client.ReadFileRange(remotePath, offset, length)
+	if err != nil {
+		return "", err
+	}
+	return encodeFileRange(fileRange)
+}
+
+// executeSftpAppend 将内容追加到远程文件末尾
+func (s *MCPServer) executeSftpAppend(config *sshclient.Config, args map[string]interface{}) (result string, err error) {
 	// 检查是否为测试调用
 	if config.Host == "0.0.0.0" {
-		return "MCP Tool: sftp_list\nStatus: Ready\nNote: Please provide a valid 'host' parameter to list files.\nExample: {\"host\": \"192.168.1.100\", \"remote_path\": \"/var/log\"}", nil
+		return "MCP Tool: sftp_append\nStatus: Ready\nNote: Please provide valid parameters to append to a file.\nExample: {\"host\": \"192.168.1.100\", \"remote_path\": \"/var/log/deploy.log\", \"content\": \"deployed v1.2\\n\"}", nil
 	}
 
-	remotePath := "."
-	if path, ok := args["remote_path"].(string); ok {
-		remotePath = path
+	remotePath := stringArg(args, "remote_path")
+	if remotePath == "" {
+		return "", fmt.Errorf("remote_path is required")
+	}
+	content, inline, err := inlineUploadContent(args)
+	if err != nil {
+		return "", err
+	}
+	if !inline {
+		return "", fmt.Errorf("content or content_base64 is required")
 	}
-
-	config.Mode = "sftp"
-	config.SftpAction = "list"
-	config.RemotePath = remotePath
 
 	client, err := sshclient.NewSSHClient(config)
 	if err != nil {
@@ -800,39 +3249,64 @@ func (s *MCPServer) executeSftpList(config *sshclient.Config, args map[string]in
 		return "", err
 	}
 
-	// 捕获输出
-	var output strings.Builder
-	oldStdout := os.Stdout
-	r, w, pipeErr := os.Pipe()
-	if pipeErr != nil {
-		return "", fmt.Errorf("failed to create pipe: %w", pipeErr)
+	size, err := client.AppendFile(remotePath, content)
+	if err != nil {
+		return "", err
 	}
-	os.Stdout = w
+	return fmt.Sprintf("Appended %d bytes to %s (now %d bytes)", len(content), remotePath, size), nil
+}
 
-	errChan := make(chan error, 1)
-	go func() {
-		errChan <- client.ExecuteSftp()
-	}()
+// maxListLimit caps the page size of sftp_list
+const maxListLimit = 1000
 
-	go func() {
-		scanner := bufio.NewScanner(r)
-		for scanner.Scan() {
-			output.WriteString(scanner.Text() + "\n")
-		}
-	}()
+// executeSftpList 执行SFTP列表
+func (s *MCPServer) executeSftpList(config *sshclient.Config, args map[string]interface{}) (result string, err error) {
+	// 检查是否为测试调用
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: sftp_list\nStatus: Ready\nNote: Please provide a valid 'host' parameter to list files.\nExample: {\"host\": \"192.168.1.100\", \"remote_path\": \"/var/log\"}", nil
+	}
 
-	err = <-errChan
-	if closeErr := w.Close(); closeErr != nil {
-		// Log best-effort close error
-		_ = closeErr
+	remotePath := "."
+	if path, ok := args["remote_path"].(string); ok {
+		remotePath = path
+	}
+	opts := sshclient.ListOptions{
+		Recursive: boolArg(args, "recursive"),
+		Pattern:   stringArg(args, "pattern"),
+		Type:      stringArg(args, "type"),
+		Sort:      stringArg(args, "sort"),
+		Reverse:   boolArg(args, "reverse"),
+		Offset:    intArg(args, "offset", 0),
+		Limit:     intArg(args, "limit", sshclient.DefaultListLimit),
+	}
+	if opts.Limit > maxListLimit {
+		return "", fmt.Errorf("limit must be at most %d", maxListLimit)
+	}
+	if err = opts.Validate(); err != nil {
+		return "", err
 	}
-	os.Stdout = oldStdout
 
+	client, err := sshclient.NewSSHClient(config)
 	if err != nil {
 		return "", err
 	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
 
-	return output.String(), nil
+	if err = client.Connect(); err != nil {
+		return "", err
+	}
+
+	listing, err := client.List(remotePath, opts)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(listing, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode listing: %w", err)
+	}
+	return string(data), nil
 }
 
 // executeSftpMkdir 执行SFTP创建目录
@@ -847,10 +3321,6 @@ func (s *MCPServer) executeSftpMkdir(config *sshclient.Config, args map[string]i
 		return "", fmt.Errorf("remote_path is required")
 	}
 
-	config.Mode = "sftp"
-	config.SftpAction = "mkdir"
-	config.RemotePath = remotePath
-
 	client, err := sshclient.NewSSHClient(config)
 	if err != nil {
 		return "", err
@@ -863,7 +3333,7 @@ func (s *MCPServer) executeSftpMkdir(config *sshclient.Config, args map[string]i
 		return "", err
 	}
 
-	if err := client.ExecuteSftp(); err != nil {
+	if err := client.ExecuteSftp(sshclient.Request{SftpAction: "mkdir", RemotePath: remotePath}); err != nil {
 		return "", err
 	}
 
@@ -882,10 +3352,6 @@ func (s *MCPServer) executeSftpRemove(config *sshclient.Config, args map[string]
 		return "", fmt.Errorf("remote_path is required")
 	}
 
-	config.Mode = "sftp"
-	config.SftpAction = "remove"
-	config.RemotePath = remotePath
-
 	client, err := sshclient.NewSSHClient(config)
 	if err != nil {
 		return "", err
@@ -898,13 +3364,58 @@ func (s *MCPServer) executeSftpRemove(config *sshclient.Config, args map[string]
 		return "", err
 	}
 
-	if err := client.ExecuteSftp(); err != nil {
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	if boolArg(args, "backup") || (settings != nil && settings.MCPRemoveBackup) {
+		trashed, trashErr := client.Trash(remotePath)
+		if trashErr != nil {
+			return "", trashErr
+		}
+		return fmt.Sprintf("Moved to trash: %s -> %s", remotePath, trashed), nil
+	}
+
+	if err := client.ExecuteSftp(sshclient.Request{SftpAction: "remove", RemotePath: remotePath}); err != nil {
 		return "", err
 	}
 
 	return fmt.Sprintf("Removed: %s", remotePath), nil
 }
 
+// executeTrashPurge 清空远程主机上的回收站
+func (s *MCPServer) executeTrashPurge(config *sshclient.Config, args map[string]interface{}) (result string, err error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: trash_purge\nStatus: Ready\nNote: Please provide a valid 'host' parameter to purge the trash.\nExample: {\"host\": \"192.168.1.100\", \"older_than\": \"7d\"}", nil
+	}
+
+	var olderThan time.Duration
+	if value := stringArg(args, "older_than"); value != "" {
+		if olderThan, err = parseDayDuration(value); err != nil {
+			return "", err
+		}
+	}
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+
+	if err = client.Connect(); err != nil {
+		return "", err
+	}
+
+	purged, err := client.PurgeTrash(olderThan)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(purged, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result: %w", err)
+	}
+	return string(data), nil
+}
+
 // sendResponse 发送响应
 func (s *MCPServer) sendResponse(id interface{}, result interface{}) {
 	resp := MCPResponse{
@@ -952,6 +3463,8 @@ func (s *MCPServer) writeJSON(v interface{}) {
 		// 静默忽略，MCP 模式下不输出日志
 		return
 	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 	if _, writeErr := fmt.Fprintf(s.stdout, "%s\n", data); writeErr != nil {
 		// Best-effort write, ignore error
 		_ = writeErr
@@ -970,6 +3483,31 @@ func (s *MCPServer) executeScript(config *sshclient.Config, args map[string]inte
 		return "", fmt.Errorf("script_path is required")
 	}
 
+	// 限制返回的输出大小
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	applyOutputLimit(config, settings, args)
+	// 超时后终止远程脚本并返回已有输出
+	applyScriptTimeout(config, settings, args)
+
+	// 先检查语法，通过后再执行
+	if boolArg(args, "check_syntax") {
+		config.CheckSyntax = true
+	}
+	applyShellArgs(config, args)
+
+	// 以其他用户运行时通过 sudo -u 切换，并获取 sudo 密码
+	config.RunAs, _ = args["run_as"].(string) //nolint:errcheck // optional
+	if config.RunAs != "" {
+		if sudoKey, ok := args["sudo_key"].(string); ok && sudoKey != "" {
+			config.SudoKey = sudoKey
+		} else if config.SudoKey == "" {
+			config.SudoKey = sshclient.DefaultSudoKey
+		}
+		if password, pwdErr := sshclient.GetSudoPassword(config.SudoKey); pwdErr == nil {
+			config.Password = password
+		}
+	}
+
 	client, err := sshclient.NewSSHClient(config)
 	if err != nil {
 		return "", fmt.Errorf("failed to create SSH client: %w", err)
@@ -982,14 +3520,12 @@ func (s *MCPServer) executeScript(config *sshclient.Config, args map[string]inte
 		return "", fmt.Errorf("failed to connect: %w", err)
 	}
 
-	// 检查是否有参数
+	// 参数以空白分割
+	request := sshclient.Request{RunAs: config.RunAs}
 	if argsStr, ok := args["args"].(string); ok && argsStr != "" {
-		// 分割参数
-		scriptArgs := strings.Fields(argsStr)
-		output, err = client.ExecuteScriptWithArgs(scriptPath, scriptArgs)
-	} else {
-		output, err = client.ExecuteScript(scriptPath)
+		request.ScriptArgs = strings.Fields(argsStr)
 	}
+	output, err = client.ExecuteScript(scriptPath, request)
 
 	if err != nil {
 		return "", fmt.Errorf("script execution failed: %w\nOutput: %s", err, output)
@@ -998,157 +3534,1479 @@ func (s *MCPServer) executeScript(config *sshclient.Config, args map[string]inte
 	return output, nil
 }
 
-// getPoolStats 获取连接池统计
-func (s *MCPServer) getPoolStats() (string, error) {
-	pool := sshclient.GetConnectionPool()
-	stats := pool.Stats()
-
-	// 格式化输出
-	var output strings.Builder
-	output.WriteString("SSH Connection Pool Statistics:\n")
-	output.WriteString("================================\n")
-	output.WriteString(fmt.Sprintf("Total Connections:      %v\n", stats["total_connections"]))
-	output.WriteString(fmt.Sprintf("Recently Used:          %v\n", stats["recently_used_connections"]))
-	output.WriteString(fmt.Sprintf("Idle Connections:       %v\n", stats["idle_connections"]))
-	output.WriteString(fmt.Sprintf("Max Idle Duration:      %v\n", stats["max_idle_duration"]))
-	output.WriteString(fmt.Sprintf("Health Check Interval:  %v\n", stats["health_check_interval"]))
-
-	return output.String(), nil
-}
-
-// executeHostAdd 执行添加主机配置
-func (s *MCPServer) executeHostAdd(args map[string]interface{}) (string, error) {
-	// Load settings
-	settings, err := LoadSettings()
-	if err != nil {
-		return "", fmt.Errorf("failed to load settings: %w", err)
+// executeContainerExec 在容器内执行命令：与 ssh_execute 相同的安全检查和 sudo 处理
+func (s *MCPServer) executeContainerExec(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: container_exec\nStatus: Ready\nNote: Please provide valid parameters to execute commands in a container.\nExample: {\"host\": \"192.168.1.100\", \"container\": \"web1\", \"command\": \"ls /app\"}", nil
 	}
-
-	// Extract parameters
-	name, ok := args["name"].(string)
-	if !ok || name == "" {
-		return "", fmt.Errorf("name is required")
+	if err := applyContainerArgs(config, args); err != nil {
+		return "", err
 	}
+	return s.executeSSH(config, args)
+}
 
-	host, ok := args["host"].(string)
-	if !ok || host == "" {
-		return "", fmt.Errorf("host address is required")
+// executeContainerList 列出主机上的容器
+func (s *MCPServer) executeContainerList(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: container_list\nStatus: Ready\nNote: Please provide a valid 'host' parameter to list containers.\nExample: {\"host\": \"192.168.1.100\", \"all\": true}", nil
 	}
-
-	// Build host config
-	hostConfig := HostConfig{
-		Name: name,
-		Host: host,
+	runtime := stringArg(args, "runtime")
+	if err := sshclient.ValidateContainerRuntime(runtime); err != nil {
+		return "", err
 	}
+	return s.runHelperCommand(config, args, sshclient.ContainerListCommand(runtime, boolArg(args, "all")))
+}
 
-	// Optional parameters
-	if description, ok := args["description"].(string); ok {
-		hostConfig.Description = description
-	}
+// defaultContainerLogLines is how many lines container_logs returns without tail
+const defaultContainerLogLines = 100
 
-	if port, ok := args["port"].(string); ok && port != "" {
-		hostConfig.Port = port
-	} else {
-		hostConfig.Port = "22"
+// executeContainerLogs 返回容器日志的最后若干行
+func (s *MCPServer) executeContainerLogs(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: container_logs\nStatus: Ready\nNote: Please provide valid parameters to read container logs.\nExample: {\"host\": \"192.168.1.100\", \"container\": \"web1\", \"tail\": 100}", nil
 	}
-
-	if user, ok := args["user"].(string); ok && user != "" {
-		hostConfig.User = user
-	} else {
-		hostConfig.User = "master"
+	if err := applyContainerArgs(config, args); err != nil {
+		return "", err
 	}
+	command := sshclient.ContainerLogsCommand(config.ContainerRuntime, config.Container,
+		intArg(args, "tail", defaultContainerLogLines), stringArg(args, "since"))
+	config.Container = ""
+	return s.runHelperCommand(config, args, command)
+}
 
-	if passwordKey, ok := args["password_key"].(string); ok {
-		hostConfig.PasswordKey = passwordKey
+// applyForceArgs 处理 force 参数：绕过拦截必须给出原因，原因写入审计日志；
+// 安全策略设置了 force_totp_key 的主机还需要 confirm_token 中的 TOTP 验证码，
+// 在服务端校验，客户端单方面无法强制执行
+func applyForceArgs(config *sshclient.Config, args map[string]interface{}) {
+	config.Force = boolArg(args, "force")
+	config.ForceReason = stringArg(args, "force_reason")
+	if config.Force && config.Safety != nil && config.Safety.ForceTOTPKey != "" {
+		config.ConfirmForce = sshclient.TOTPConfirmation(config.Safety.ForceTOTPKey, stringArg(args, "confirm_token"))
 	}
+}
 
-	if hostType, ok := args["type"].(string); ok && hostType != "" {
-		hostConfig.Type = hostType
-	} else {
-		hostConfig.Type = "linux"
+// applyContainerArgs 校验并设置 container 和 runtime 参数
+func applyContainerArgs(config *sshclient.Config, args map[string]interface{}) error {
+	config.Container = stringArg(args, "container")
+	if err := sshclient.ValidateContainerName(config.Container); err != nil {
+		return err
 	}
+	config.ContainerRuntime = stringArg(args, "runtime")
+	return sshclient.ValidateContainerRuntime(config.ContainerRuntime)
+}
 
-	// Add host
-	if err := AddHost(settings, hostConfig); err != nil {
-		return "", fmt.Errorf("failed to add host: %w", err)
-	}
+// applyShellArgs 读取 cwd 和 login_shell：在指定目录、通过登录 shell 运行命令
+func applyShellArgs(config *sshclient.Config, args map[string]interface{}) {
+	config.WorkDir = stringArg(args, "cwd")
+	config.LoginShell = boolArg(args, "login_shell")
+}
 
-	// Save settings
-	if err := SaveSettings(settings); err != nil {
-		return "", fmt.Errorf("failed to save settings: %w", err)
-	}
+// runHelperCommands 在同一连接上依次运行 sshx 生成的固定命令（容器、k8s 节点工具），
+// 这些命令不经过安全检查
+func (s *MCPServer) runHelperCommands(config *sshclient.Config, args map[string]interface{}, commands ...string) (results []sshclient.Result, err error) {
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	applyOutputLimit(config, settings, args)
+	config.Source = s.auditSource()
 
-	return fmt.Sprintf("Host '%s' (%s) added successfully", name, host), nil
-}
+	// docker、crictl 等通常需要 root
+	applyRunAsArg(config, args)
 
-// executeHostList 执行列出主机配置
-func (s *MCPServer) executeHostList(args map[string]interface{}) (string, error) {
-	// Load settings
-	settings, err := LoadSettings()
+	client, err := sshclient.NewSSHClient(config)
 	if err != nil {
-		return "", fmt.Errorf("failed to load settings: %w", err)
+		return nil, fmt.Errorf("failed to create SSH client: %w", err)
 	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
 
-	hosts := ListHosts(settings)
-
-	if len(hosts) == 0 {
-		return "No hosts configured.\n\nTo add hosts, use the host_add tool.", nil
+	if err = client.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
 
-	// Build output
-	var output strings.Builder
-	output.WriteString(fmt.Sprintf("=== Configured Hosts (%d) ===\n\n", len(hosts)))
-
-	for i, host := range hosts {
-		output.WriteString(fmt.Sprintf("[%d] %s\n", i+1, host.Name))
-		output.WriteString(fmt.Sprintf("    Host:        %s\n", host.Host))
-		if host.Description != "" {
-			output.WriteString(fmt.Sprintf("    Description: %s\n", host.Description))
-		}
-		if host.Port != "" && host.Port != "22" {
-			output.WriteString(fmt.Sprintf("    Port:        %s\n", host.Port))
+	for _, command := range commands {
+		var result sshclient.Result
+		if result, err = client.Execute(sshclient.Request{Command: command, RunAs: config.RunAs}); err != nil {
+			return nil, fmt.Errorf("command failed on %s: %w\nOutput: %s%s", config.Host, err, result.Output, result.Stderr)
 		}
-		if host.User != "" {
-			output.WriteString(fmt.Sprintf("    User:        %s\n", host.User))
-		}
-		if host.PasswordKey != "" {
-			output.WriteString(fmt.Sprintf("    Password Key: %s\n", host.PasswordKey))
-		}
-		if host.Type != "" {
-			output.WriteString(fmt.Sprintf("    Type:        %s\n", host.Type))
-		}
-		output.WriteString("\n")
+		results = append(results, result)
 	}
+	return results, nil
+}
 
-	return output.String(), nil
+// applyRunAsArg 读取 run_as 参数：以其他用户运行时通过 sudo -u 切换，并获取 sudo 密码
+func applyRunAsArg(config *sshclient.Config, args map[string]interface{}) {
+	config.RunAs = stringArg(args, "run_as")
+	if config.RunAs == "" {
+		return
+	}
+	if sudoKey := stringArg(args, "sudo_key"); sudoKey != "" {
+		config.SudoKey = sudoKey
+	} else if config.SudoKey == "" {
+		config.SudoKey = sshclient.DefaultSudoKey
+	}
+	if password, pwdErr := sshclient.GetSudoPassword(config.SudoKey); pwdErr == nil {
+		config.Password = password
+	}
 }
 
-// executeHostTest 执行测试主机连接
-func (s *MCPServer) executeHostTest(args map[string]interface{}) (string, error) {
-	// Load settings
-	settings, err := LoadSettings()
+// runHelperCommand 运行一条固定命令并返回其输出
+func (s *MCPServer) runHelperCommand(config *sshclient.Config, args map[string]interface{}, command string) (string, error) {
+	results, err := s.runHelperCommands(config, args, command)
 	if err != nil {
-		return "", fmt.Errorf("failed to load settings: %w", err)
+		return "", err
 	}
+	return results[0].Output, nil
+}
 
-	// Extract name parameter
-	name, ok := args["name"].(string)
-	if !ok || name == "" {
-		return "", fmt.Errorf("host name is required")
+// executeK8sNodeStatus 返回节点的就绪、cordon 和 drain 状态（JSON）
+func (s *MCPServer) executeK8sNodeStatus(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: k8s_node_status\nStatus: Ready\nNote: Please provide a host tagged k8s-node.\nExample: {\"host\": \"node1\"}", nil
 	}
-
+	if err := s.requireK8sNodeArgs(args); err != nil {
+		return "", err
+	}
+	node := stringArg(args, "node")
+	if err := sshclient.ValidateK8sNodeName(node); err != nil {
+		return "", err
+	}
+	kubeconfig := stringArg(args, "kubeconfig")
+
+	// kubectl 的 JSON 只取 stdout，警告不混入
+	config.BinaryOutput = true
+	results, err := s.runHelperCommands(config, args,
+		sshclient.K8sNodeCommand(kubeconfig, node),
+		sshclient.K8sNodePodsCommand(kubeconfig, node))
+	if err != nil {
+		return "", err
+	}
+	status, err := summarizeK8sNode([]byte(results[0].Output), []byte(results[1].Output))
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// executeK8sKubeletLogs 返回 kubelet 日志的最后若干行
+func (s *MCPServer) executeK8sKubeletLogs(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: k8s_kubelet_logs\nStatus: Ready\nNote: Please provide a host tagged k8s-node.\nExample: {\"host\": \"node1\", \"lines\": 200}", nil
+	}
+	if err := s.requireK8sNodeArgs(args); err != nil {
+		return "", err
+	}
+	command := sshclient.KubeletLogsCommand(intArg(args, "lines", defaultKubeletLogLines), stringArg(args, "since"))
+	return s.runHelperCommand(config, args, command)
+}
+
+// executeK8sCrictlPs 列出节点容器运行时中的容器
+func (s *MCPServer) executeK8sCrictlPs(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: k8s_crictl_ps\nStatus: Ready\nNote: Please provide a host tagged k8s-node.\nExample: {\"host\": \"node1\", \"all\": true}", nil
+	}
+	if err := s.requireK8sNodeArgs(args); err != nil {
+		return "", err
+	}
+	return s.runHelperCommand(config, args, sshclient.CrictlPsCommand(boolArg(args, "all"), stringArg(args, "container")))
+}
+
+// executePackageManage 用主机自己的包管理器安装、删除、更新或列出软件包，默认只模拟执行
+func (s *MCPServer) executePackageManage(config *sshclient.Config, args map[string]interface{}) (output string, err error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: package_manage\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"action\": \"install\", \"packages\": \"nginx\", \"run_as\": \"root\"}", nil
+	}
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	opts := sshclient.PackageOptions{
+		Manager:  stringArg(args, "manager"),
+		Action:   stringArg(args, "action"),
+		Packages: strings.FieldsFunc(stringArg(args, "packages"), func(r rune) bool { return r == ',' || unicode.IsSpace(r) }),
+		DryRun:   true,
+	}
+	if _, ok := args["dry_run"]; ok {
+		opts.DryRun = boolArg(args, "dry_run")
+	}
+	if settings != nil && len(settings.ProtectedPackages) > 0 {
+		opts.Protected = append(append([]string{}, sshclient.ProtectedPackages...), settings.ProtectedPackages...)
+	}
+	if err = opts.Validate(); err != nil {
+		return "", err
+	}
+
+	// 生成的命令同样经过安全检查、审计和主机并发限制
+	config.SafetyCheck = true
+	config.Source = s.auditSource()
+	applyRunAsArg(config, args)
+	applyOutputLimit(config, settings, args)
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	if err = client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	result, err := client.ManagePackages(opts, sshclient.Request{RunAs: config.RunAs})
+	if err != nil {
+		if result != nil {
+			return "", fmt.Errorf("%s failed: %w\nCommand: %s", result.Manager, err, result.Command)
+		}
+		return "", err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// publicKeyArg 读取 public_key 参数，或 public_key_path 指向的本地公钥文件，并检查其格式
+func publicKeyArg(args map[string]interface{}) (string, error) {
+	publicKey := stringArg(args, "public_key")
+	if path := stringArg(args, "public_key_path"); publicKey == "" && path != "" {
+		data, err := os.ReadFile(expandHome(path)) // #nosec G304 -- public key chosen by the caller
+		if err != nil {
+			return "", fmt.Errorf("failed to read public key: %w", err)
+		}
+		publicKey = string(data)
+	}
+	if publicKey == "" {
+		return "", nil
+	}
+	if _, _, err := sshclient.ParsePublicKey(publicKey); err != nil {
+		return "", err
+	}
+	return publicKey, nil
+}
+
+// accountArg 返回 account 参数（默认 SSH 用户）并检查用户名
+func accountArg(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	account := stringArg(args, "account")
+	if account == "" {
+		account = config.User
+	}
+	return account, sshclient.ValidateAccountName(account)
+}
+
+// runAccountTool 连接主机运行账号管理操作，并以 JSON 返回其结果；
+// 生成的命令经过安全检查和审计
+func (s *MCPServer) runAccountTool(config *sshclient.Config, args map[string]interface{},
+	action func(client *sshclient.SSHClient, req sshclient.Request) (interface{}, error)) (output string, err error) {
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	config.SafetyCheck = true
+	config.Source = s.auditSource()
+	applyRunAsArg(config, args)
+	applyOutputLimit(config, settings, args)
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	if err = client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	result, err := action(client, sshclient.Request{RunAs: config.RunAs})
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// executeUserCreate 创建用户（已存在则不变），可同时为其授权公钥
+func (s *MCPServer) executeUserCreate(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: user_create\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"name\": \"deploy\", \"shell\": \"/bin/bash\", \"run_as\": \"root\"}", nil
+	}
+	name := stringArg(args, "name")
+	if err := sshclient.ValidateAccountName(name); err != nil {
+		return "", err
+	}
+	publicKey, err := publicKeyArg(args)
+	if err != nil {
+		return "", err
+	}
+
+	return s.runAccountTool(config, args, func(client *sshclient.SSHClient, req sshclient.Request) (interface{}, error) {
+		created, err := client.CreateUser(name, stringArg(args, "shell"), req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user %s: %w", name, err)
+		}
+		result := map[string]interface{}{"name": name, "created": created}
+		if publicKey != "" {
+			key, added, err := client.AddAuthorizedKey(name, publicKey, req)
+			if err != nil {
+				return nil, fmt.Errorf("failed to add key for %s: %w", name, err)
+			}
+			result["key"] = key
+			result["key_added"] = added
+		}
+		return result, nil
+	})
+}
+
+// executeAuthorizedKeysAdd 将公钥加入用户的 authorized_keys（已存在则不重复添加）
+func (s *MCPServer) executeAuthorizedKeysAdd(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: authorized_keys_add\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"account\": \"deploy\", \"public_key_path\": \"~/.ssh/id_ed25519.pub\", \"run_as\": \"root\"}", nil
+	}
+	account, err := accountArg(config, args)
+	if err != nil {
+		return "", err
+	}
+	publicKey, err := publicKeyArg(args)
+	if err != nil {
+		return "", err
+	}
+	if publicKey == "" {
+		return "", fmt.Errorf("public_key or public_key_path is required")
+	}
+
+	return s.runAccountTool(config, args, func(client *sshclient.SSHClient, req sshclient.Request) (interface{}, error) {
+		key, added, err := client.AddAuthorizedKey(account, publicKey, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add key for %s: %w", account, err)
+		}
+		return map[string]interface{}{"account": account, "key": key, "added": added}, nil
+	})
+}
+
+// executeAuthorizedKeysList 列出用户 authorized_keys 中的公钥
+func (s *MCPServer) executeAuthorizedKeysList(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: authorized_keys_list\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"account\": \"deploy\"}", nil
+	}
+	account, err := accountArg(config, args)
+	if err != nil {
+		return "", err
+	}
+
+	return s.runAccountTool(config, args, func(client *sshclient.SSHClient, req sshclient.Request) (interface{}, error) {
+		keys, err := client.AuthorizedKeys(account, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the keys of %s: %w", account, err)
+		}
+		return map[string]interface{}{"account": account, "keys": keys}, nil
+	})
+}
+
+// executeAuthorizedKeysRevoke 按指纹或注释从用户的 authorized_keys 删除公钥
+func (s *MCPServer) executeAuthorizedKeysRevoke(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: authorized_keys_revoke\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"account\": \"deploy\", \"key\": \"SHA256:...\"}", nil
+	}
+	account, err := accountArg(config, args)
+	if err != nil {
+		return "", err
+	}
+	match := stringArg(args, "key")
+	if strings.TrimSpace(match) == "" {
+		return "", fmt.Errorf("key is required (a SHA256 fingerprint or comment)")
+	}
+
+	return s.runAccountTool(config, args, func(client *sshclient.SSHClient, req sshclient.Request) (interface{}, error) {
+		revoked, err := client.RevokeAuthorizedKeys(account, match, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to revoke keys of %s: %w", account, err)
+		}
+		return map[string]interface{}{"account": account, "revoked": revoked}, nil
+	})
+}
+
+// executePortCheck 返回监听端口和防火墙状态（JSON），可选检查某个端口是否开放
+func (s *MCPServer) executePortCheck(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: port_check\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"check_port\": 443}", nil
+	}
+	port := intArg(args, "check_port", 0)
+	if _, ok := args["check_port"]; ok && (port < 1 || port > 65535) {
+		return "", fmt.Errorf("check_port must be between 1 and 65535")
+	}
+	protocol := stringArg(args, "protocol")
+	if protocol == "" {
+		protocol = "tcp"
+	}
+
+	output, err := s.runHelperCommand(config, args, sshclient.PortCheckCommand())
+	if err != nil {
+		return "", err
+	}
+	report := sshclient.ParsePortCheck(output)
+	if port > 0 {
+		report.Port = report.CheckPort(port, protocol)
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// executeHostMetrics 在短时间窗口内采样 CPU、内存和负载并返回磁盘用量（JSON）
+func (s *MCPServer) executeHostMetrics(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: host_metrics\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"samples\": 5, \"interval\": \"2s\"}", nil
+	}
+	samples := intArg(args, "samples", sshclient.DefaultMetricsSamples)
+	interval := sshclient.DefaultMetricsInterval
+	if stringArg(args, "interval") != "" {
+		interval = durationArg(args, "interval")
+	}
+	if err := sshclient.ValidateMetricsWindow(samples, interval); err != nil {
+		return "", err
+	}
+
+	output, err := s.runHelperCommand(config, args, sshclient.MetricsCommand(samples, interval))
+	if err != nil {
+		return "", err
+	}
+	metrics, err := sshclient.ParseMetrics(output, interval)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// executeJournalQuery 按单元、优先级、时间范围和模式查询系统日志并返回 JSON
+func (s *MCPServer) executeJournalQuery(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: journal_query\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"unit\": \"nginx.service\", \"priority\": \"err\", \"since\": \"1 hour ago\"}", nil
+	}
+	query := sshclient.JournalQuery{
+		Units:    strings.FieldsFunc(stringArg(args, "unit"), func(r rune) bool { return r == ',' || unicode.IsSpace(r) }),
+		Priority: stringArg(args, "priority"),
+		Since:    stringArg(args, "since"),
+		Until:    stringArg(args, "until"),
+		Grep:     stringArg(args, "grep"),
+		Lines:    intArg(args, "lines", sshclient.DefaultJournalLines),
+	}
+	if err := query.Validate(); err != nil {
+		return "", err
+	}
+
+	// 只解析 stdout 中的 JSON，stderr 作为警告返回
+	config.BinaryOutput = true
+	results, err := s.runHelperCommands(config, args, sshclient.JournalCommand(query))
+	if err != nil {
+		return "", err
+	}
+	journal := sshclient.ParseJournal(results[0].Output, query)
+	for _, line := range strings.Split(strings.TrimSpace(results[0].Stderr), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			journal.Warnings = append(journal.Warnings, line)
+		}
+	}
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// defaultProcessLimit is how many processes process_list returns without limit
+const defaultProcessLimit = 50
+
+// executeProcessList 按名称和所有者筛选进程并返回 JSON
+func (s *MCPServer) executeProcessList(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: process_list\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"name\": \"celery\"}", nil
+	}
+	filter := sshclient.ProcessFilter{
+		Name:  stringArg(args, "name"),
+		User:  stringArg(args, "process_user"),
+		Sort:  stringArg(args, "sort"),
+		Limit: intArg(args, "limit", defaultProcessLimit),
+	}
+	// 先检查筛选条件再连接
+	if _, _, err := sshclient.FilterProcesses(nil, filter); err != nil {
+		return "", err
+	}
+
+	output, err := s.runHelperCommand(config, args, sshclient.ProcessListCommand(0))
+	if err != nil {
+		return "", err
+	}
+	processes, total, err := sshclient.FilterProcesses(sshclient.ParseProcesses(output), filter)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{"total": total, "processes": processes}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// executeProcessSignal 向一个进程发送信号，默认只模拟执行，实际发送需要 expect_name 确认
+func (s *MCPServer) executeProcessSignal(config *sshclient.Config, args map[string]interface{}) (output string, err error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: process_signal\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"pid\": 5001, \"signal\": \"TERM\", \"expect_name\": \"celery\", \"dry_run\": false}", nil
+	}
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	opts := sshclient.SignalOptions{
+		PID:        intArg(args, "pid", 0),
+		Signal:     stringArg(args, "signal"),
+		ExpectName: stringArg(args, "expect_name"),
+		DryRun:     true,
+	}
+	if _, ok := args["dry_run"]; ok {
+		opts.DryRun = boolArg(args, "dry_run")
+	}
+	if settings != nil && len(settings.ProtectedProcesses) > 0 {
+		opts.Protected = append(append([]string{}, sshclient.ProtectedProcesses...), settings.ProtectedProcesses...)
+	}
+	if err = opts.Validate(); err != nil {
+		return "", err
+	}
+
+	// 查找和发送信号的命令同样经过安全检查和审计
+	config.SafetyCheck = true
+	config.Source = s.auditSource()
+	applyRunAsArg(config, args)
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	if err = client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	result, err := client.SignalProcess(opts, sshclient.Request{RunAs: config.RunAs})
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// executeCronList 以 JSON 返回用户的 crontab 及主机上的 systemd 定时器
+func (s *MCPServer) executeCronList(config *sshclient.Config, args map[string]interface{}) (output string, err error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: cron_list\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"account\": \"deploy\"}", nil
+	}
+	account := stringArg(args, "account")
+	if account != "" {
+		if err = sshclient.ValidateAccountName(account); err != nil {
+			return "", err
+		}
+	}
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	applyOutputLimit(config, settings, args)
+	config.Source = s.auditSource()
+	applyRunAsArg(config, args)
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	if err = client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	req := sshclient.Request{RunAs: config.RunAs}
+	crontab, err := client.Crontab(account, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the crontab: %w", err)
+	}
+	timers, err := client.Timers(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to list the timers: %w", err)
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"account": account,
+		"crontab": crontab,
+		"entries": sshclient.ParseCrontab(crontab),
+		"timers":  timers,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// executeCronAdd 向 crontab 添加一行，默认只预览差异
+func (s *MCPServer) executeCronAdd(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: cron_add\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"entry\": \"30 2 * * * /srv/app/bin/report\", \"dry_run\": false}", nil
+	}
+	entry := stringArg(args, "entry")
+	if err := sshclient.ValidateCronEntry(entry); err != nil {
+		return "", err
+	}
+	return s.editCrontab(config, args, sshclient.CronEdit{Add: entry})
+}
+
+// executeCronRemove 从 crontab 删除包含指定文本的任务行，默认只预览差异
+func (s *MCPServer) executeCronRemove(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: cron_remove\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"match\": \"/srv/app/bin/report\", \"dry_run\": false}", nil
+	}
+	match := stringArg(args, "match")
+	if strings.TrimSpace(match) == "" {
+		return "", fmt.Errorf("match must not be empty")
+	}
+	return s.editCrontab(config, args, sshclient.CronEdit{Remove: match})
+}
+
+// editCrontab 读取 account 和 dry_run 参数修改 crontab，并以 JSON 返回差异和备份位置
+func (s *MCPServer) editCrontab(config *sshclient.Config, args map[string]interface{}, edit sshclient.CronEdit) (output string, err error) {
+	edit.Account = stringArg(args, "account")
+	edit.DryRun = true
+	if _, ok := args["dry_run"]; ok {
+		edit.DryRun = boolArg(args, "dry_run")
+	}
+	if edit.Account != "" {
+		if err = sshclient.ValidateAccountName(edit.Account); err != nil {
+			return "", err
+		}
+	}
+
+	// 读取和安装 crontab 的命令经过审计，新任务的命令经过安全检查
+	config.SafetyCheck = true
+	config.Source = s.auditSource()
+	applyRunAsArg(config, args)
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	if err = client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	result, err := client.EditCrontab(edit, sshclient.Request{RunAs: config.RunAs})
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// executeHostReboot 检查后重启主机，等待其重新上线并运行验证命令；默认只做预检
+func (s *MCPServer) executeHostReboot(config *sshclient.Config, args map[string]interface{}) (output string, err error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: host_reboot\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"confirm\": \"web1\", \"dry_run\": false, \"verify\": \"systemctl is-active nginx\"}", nil
+	}
+	opts := sshclient.RebootOptions{
+		AllowSessions: boolArg(args, "allow_sessions"),
+		Verify:        stringArg(args, "verify"),
+		Timeout:       durationArg(args, "timeout"),
+		DryRun:        true,
+	}
+	if value := stringArg(args, "min_uptime"); value != "" {
+		// 无效的值不能悄悄关闭检查
+		if opts.MinUptime = durationArg(args, "min_uptime"); opts.MinUptime == 0 && value != "0" && value != "0s" {
+			return "", fmt.Errorf("invalid min_uptime '%s' (e.g. 30m, or 0 to skip the check)", value)
+		}
+		if opts.MinUptime == 0 {
+			opts.MinUptime = -1
+		}
+	}
+	if _, ok := args["dry_run"]; ok {
+		opts.DryRun = boolArg(args, "dry_run")
+	}
+	if host := stringArg(args, "host"); !opts.DryRun && stringArg(args, "confirm") != host {
+		return "", fmt.Errorf("confirm must repeat the host argument ('%s') to reboot it: run with dry_run first", host)
+	}
+	if progress := s.progress; progress != nil {
+		step := int64(0)
+		opts.Progress = func(message string) {
+			step++
+			progress(step, 2, message)
+		}
+	}
+
+	// 预检和验证命令经过安全检查，重启命令本身只记录审计
+	config.SafetyCheck = true
+	config.Source = s.auditSource()
+	applyRunAsArg(config, args)
+
+	// 维护窗口之外重启需要 force 和原因
+	applyForceArgs(config, args)
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	if err = client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	result, err := client.Reboot(opts, sshclient.Request{RunAs: config.RunAs})
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if result.Verify != nil && !result.Verify.Passed {
+		return "", fmt.Errorf("%s is back but the verification failed:\n%s", stringArg(args, "host"), data)
+	}
+	return string(data), nil
+}
+
+// requireK8sNodeArgs 只允许在标记为 k8s-node 的已配置主机上运行节点工具
+func (s *MCPServer) requireK8sNodeArgs(args map[string]interface{}) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	return requireK8sNode(settings, stringArg(args, "host"))
+}
+
+// executeDBDump 在主机上导出数据库，经 SFTP 下载并校验 sha256
+func (s *MCPServer) executeDBDump(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: db_dump\nStatus: Ready\nNote: Please provide valid parameters to dump a database.\nExample: {\"host\": \"db1\", \"engine\": \"postgres\", \"database\": \"shop\", \"local_path\": \"/backups/shop.sql.gz\", \"run_as\": \"postgres\"}", nil
+	}
+	return s.transferDatabase(config, args, (*sshclient.SSHClient).DumpDatabase)
+}
+
+// executeDBRestore 上传数据库转储，校验 sha256 后导入
+func (s *MCPServer) executeDBRestore(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: db_restore\nStatus: Ready\nNote: Please provide valid parameters to restore a database.\nExample: {\"host\": \"db1\", \"engine\": \"postgres\", \"database\": \"shop\", \"local_path\": \"/backups/shop.sql.gz\", \"run_as\": \"postgres\"}", nil
+	}
+	return s.transferDatabase(config, args, (*sshclient.SSHClient).RestoreDatabase)
+}
+
+// transferDatabase 读取数据库参数和密钥环中的密码，执行导出或导入并以 JSON 返回结果
+func (s *MCPServer) transferDatabase(config *sshclient.Config, args map[string]interface{},
+	transfer func(*sshclient.SSHClient, sshclient.DBOptions, sshclient.Request) (*sshclient.DBTransferResult, error)) (output string, err error) {
+	opts := sshclient.DBOptions{
+		Engine:    stringArg(args, "engine"),
+		Database:  stringArg(args, "database"),
+		DBUser:    stringArg(args, "db_user"),
+		DBHost:    stringArg(args, "db_host"),
+		DBPort:    stringArg(args, "db_port"),
+		LocalPath: stringArg(args, "local_path"),
+	}
+	if err = opts.Validate(); err != nil {
+		return "", err
+	}
+	if key := stringArg(args, "db_password_key"); key != "" {
+		if opts.Password, err = sshclient.GetSudoPassword(key); err != nil {
+			return "", fmt.Errorf("failed to get the database password (%s): %w", key, err)
+		}
+	}
+	config.Source = s.auditSource()
+	applyRunAsArg(config, args)
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	if err = client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	result, err := transfer(client, opts, sshclient.Request{RunAs: config.RunAs})
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(struct {
+		*sshclient.DBTransferResult
+		Duration string `json:"duration"`
+	}{result, result.Duration.Round(time.Millisecond).String()}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result: %w", err)
+	}
+	return string(data), nil
+}
+
+// executePoolConnections 以 JSON 返回连接池中每个连接的详情
+func (s *MCPServer) executePoolConnections(_ map[string]interface{}) (string, error) {
+	return formatPoolStatsJSON(sshclient.GetConnectionPool().Stats())
+}
+
+// getPoolStats 获取连接池统计
+func (s *MCPServer) getPoolStats(_ map[string]interface{}) (string, error) {
+	return formatPoolStats(sshclient.GetConnectionPool().Stats()), nil
+}
+
+// executeServerInfo 返回服务器版本、功能和限制的 JSON 描述
+func (s *MCPServer) executeServerInfo(_ map[string]interface{}) (string, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+	return formatServerInfo(buildServerInfo(settings, s.tools))
+}
+
+// executePoolWarm 预热主机连接
+func (s *MCPServer) executePoolWarm(args map[string]interface{}) (string, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	spec, ok := args["hosts"].(string)
+	if !ok || spec == "" {
+		return "", fmt.Errorf("hosts is required")
+	}
+
+	base := &sshclient.Config{UseKeyAuth: true}
+	applyHostKeySettings(base, settings)
+	applyMCPTrust(base, settings)
+	applyHealthArgs(base, args)
+
+	hosts, results, err := warmHosts(settings, spec, base)
+	if err != nil {
+		return "", err
+	}
+
+	return formatWarmResults(hosts, results), nil
+}
+
+// executePoolEvict 关闭并移除连接池中指定主机（或全部）的连接
+func (s *MCPServer) executePoolEvict(args map[string]interface{}) (string, error) {
+	target, _ := args["host"].(string) //nolint:errcheck // optional
+	settings, _ := LoadSettings()      //nolint:errcheck // names resolve only with settings
+
+	evicted := evictPool(sshclient.GetConnectionPool(), settings, target)
+	return formatEvicted(target, evicted), nil
+}
+
+// executeSftpDistribute 将同一文件分发到多台主机
+func (s *MCPServer) executeSftpDistribute(args map[string]interface{}) (string, error) {
+	spec, ok := args["hosts"].(string)
+	if !ok || spec == "" {
+		return "", fmt.Errorf("hosts is required")
+	}
+	localPath, ok := args["local_path"].(string)
+	if !ok || localPath == "" {
+		return "", fmt.Errorf("local_path is required")
+	}
+	remotePath, ok := args["remote_path"].(string)
+	if !ok || remotePath == "" {
+		return "", fmt.Errorf("remote_path is required")
+	}
+	validate, _ := args["validate"].(string) //nolint:errcheck // optional
+	rollback, _ := args["rollback"].(string) //nolint:errcheck // optional
+
+	settings, err := LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	base := &sshclient.Config{UseKeyAuth: true, SafetyCheck: true, Source: s.auditSource()}
+	applyHostKeySettings(base, settings)
+	applyMCPTrust(base, settings)
+	s.identify(base)
+	applyHealthArgs(base, args)
+
+	opts := sshclient.DistributeOptions{
+		LocalPath:  localPath,
+		RemotePath: remotePath,
+		Validate:   validate,
+		Rollback:   rollback,
+		Progress:   s.progress,
+	}
+	if boolArg(args, "template") {
+		opts.Render = templateRenderer(settings, localPath)
+	}
+	asJSON, manifestPath, remoteManifest := boolArg(args, "manifest"), stringArg(args, "manifest_path"), stringArg(args, "remote_manifest")
+	opts.Verify = asJSON || manifestPath != "" || remoteManifest != ""
+
+	hosts, results, status, err := distributeFile(settings, spec, base, opts, rolloutArgs(args))
+	if err != nil {
+		return "", err
+	}
+
+	report := formatDistributeResults(hosts, results) + formatRolloutResult(hosts, status)
+	if opts.Verify {
+		manifest := distributeManifest(hosts, results, status, opts)
+		if report, err = keepManifest(settings, manifest, base, manifestPath, remoteManifest, asJSON, report); err != nil {
+			return "", err
+		}
+	}
+	if status != nil && status.Halted {
+		return "", fmt.Errorf("rollout halted: %s\n%s", status.Reason, report)
+	}
+	if failed := countDistributeFailures(results); failed > 0 {
+		return "", fmt.Errorf("distribution failed on %d host(s):\n%s", failed, report)
+	}
+	return report, nil
+}
+
+// rolloutArgs 读取分阶段执行的参数：canary、batch_size、max_failures、expect 和 check
+func rolloutArgs(args map[string]interface{}) sshclient.RolloutOptions {
+	return sshclient.RolloutOptions{
+		Canary:      intArg(args, "canary", 0),
+		BatchSize:   intArg(args, "batch_size", 0),
+		MaxFailures: intArg(args, "max_failures", 0),
+		Expect:      stringArg(args, "expect"),
+		Check:       stringArg(args, "check"),
+	}
+}
+
+// applyHealthArgs 读取按存活缓存筛选主机的参数：skip_unreachable 和 only_unreachable
+func applyHealthArgs(config *sshclient.Config, args map[string]interface{}) {
+	config.SkipUnreachable = boolArg(args, "skip_unreachable")
+	config.OnlyUnreachable = boolArg(args, "only_unreachable")
+}
+
+// executeSftpCollect 从多台主机收集同一文件或命令输出
+func (s *MCPServer) executeSftpCollect(args map[string]interface{}) (string, error) {
+	spec, ok := args["hosts"].(string)
+	if !ok || spec == "" {
+		return "", fmt.Errorf("hosts is required")
+	}
+	remotePath, _ := args["remote_path"].(string) //nolint:errcheck // optional
+	command, _ := args["command"].(string)        //nolint:errcheck // optional
+	localDir, _ := args["local_dir"].(string)     //nolint:errcheck // optional
+	maxSize, _ := args["max_size"].(string)       //nolint:errcheck // optional
+
+	opts := sshclient.CollectOptions{RemotePath: remotePath, Command: command, LocalDir: localDir, Rollback: stringArg(args, "rollback"), Progress: s.progress}
+	if maxSize != "" {
+		opts.MaxBytes = parseSize(maxSize)
+	}
+
+	settings, err := LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	base := &sshclient.Config{UseKeyAuth: true, SafetyCheck: true, Source: s.auditSource()}
+	applyHostKeySettings(base, settings)
+	applyMCPTrust(base, settings)
+	s.identify(base)
+	applyHealthArgs(base, args)
+
+	asJSON, manifestPath := boolArg(args, "manifest"), stringArg(args, "manifest_path")
+	if (asJSON || manifestPath != "") && opts.Command != "" {
+		return "", fmt.Errorf("manifest lists collected files, not command output")
+	}
+
+	hosts, results, status, err := collectFromHosts(settings, spec, base, opts, rolloutArgs(args))
+	if err != nil {
+		return "", err
+	}
+
+	report := formatCollectResults(hosts, results) + formatRolloutResult(hosts, status)
+	switch {
+	case opts.Command != "" && boolArg(args, "aggregate"):
+		report = formatAggregatedOutput(groupCollectedOutput(hosts, results)) + report
+	case opts.Command != "":
+		report = formatCollectedOutput(hosts, results) + report
+	case asJSON || manifestPath != "":
+		if report, err = keepManifest(settings, collectManifest(hosts, results, status, opts), base, manifestPath, "", asJSON, report); err != nil {
+			return "", err
+		}
+	}
+	if status != nil && status.Halted {
+		return "", fmt.Errorf("rollout halted: %s\n%s", status.Reason, report)
+	}
+	if failed := countCollectFailures(results); failed > 0 {
+		return "", fmt.Errorf("collection failed on %d host(s):\n%s", failed, report)
+	}
+	return report, nil
+}
+
+// executeSSHWatch 重复运行命令并报告输出变化，直到匹配条件或达到次数上限
+func (s *MCPServer) executeSSHWatch(config *sshclient.Config, args map[string]interface{}) (output string, err error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: ssh_watch\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"command\": \"systemctl is-active app\", \"until\": \"^active\"}", nil
+	}
+	command := stringArg(args, "command")
+	if command == "" {
+		return "", fmt.Errorf("command is required")
+	}
+
+	maxRuns := intArg(args, "max_runs", defaultWatchRuns)
+	if maxRuns <= 0 || maxRuns > maxWatchRuns {
+		return "", fmt.Errorf("max_runs must be between 1 and %d", maxWatchRuns)
+	}
+	opts, err := watchOptions(durationArg(args, "interval"), stringArg(args, "until"), maxRuns)
+	if err != nil {
+		return "", err
+	}
+
+	config.SafetyCheck = true
+	config.Source = s.auditSource()
+	if config.SudoKey == "" {
+		config.SudoKey = sshclient.DefaultSudoKey
+	}
+	if sudoKey := stringArg(args, "sudo_key"); sudoKey != "" {
+		config.SudoKey = sudoKey
+	}
+	if strings.Contains(command, "sudo") {
+		if password, pwdErr := sshclient.GetSudoPassword(config.SudoKey); pwdErr == nil {
+			config.Password = password
+		}
+	}
+	applyRunAsArg(config, args)
+	applyShellArgs(config, args)
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	applyOutputLimit(config, settings, args)
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	if err = client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	var report strings.Builder
+	progress := s.progress
+	opts.OnRun = func(run sshclient.WatchRun) {
+		report.WriteString(formatWatchRun(run))
+		if progress != nil {
+			progress(int64(run.Run), int64(maxRuns), fmt.Sprintf("run %d", run.Run))
+		}
+	}
+	result := client.Watch(sshclient.Request{Command: command, RunAs: config.RunAs, Container: config.Container}, opts)
+	report.WriteString(fmt.Sprintf("Runs: %d, changed: %d, matched: %t\n", result.Runs, result.Changes, result.Matched))
+
+	if err = watchOutcome(result, opts); err != nil {
+		return "", fmt.Errorf("%w\n%s", err, report.String())
+	}
+	return report.String(), nil
+}
+
+// executeWaitFor 在远程主机上轮询条件，直到满足或超时
+func (s *MCPServer) executeWaitFor(config *sshclient.Config, args map[string]interface{}) (output string, err error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: wait_for\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"db1\", \"condition\": \"port\", \"target\": \"5432\"}", nil
+	}
+	condition := sshclient.WaitCondition{Kind: stringArg(args, "condition"), Target: stringArg(args, "target")}
+	if err = condition.Validate(); err != nil {
+		return "", err
+	}
+	timeout := durationArg(args, "timeout")
+	if timeout > maxWaitTimeout {
+		return "", fmt.Errorf("timeout must be at most %s", maxWaitTimeout)
+	}
+
+	config.SafetyCheck = true
+	config.Source = s.auditSource()
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	if err = client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	waited, err := client.WaitFor(condition, timeout, durationArg(args, "interval"), sshclient.Request{})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s ready on %s after %s", condition, config.Host, waited), nil
+}
+
+// executeInteract 在伪终端中运行命令，并按 expect 脚本回答其提示
+func (s *MCPServer) executeInteract(config *sshclient.Config, args map[string]interface{}) (output string, err error) {
+	if config.Host == "0.0.0.0" {
+		return "MCP Tool: ssh_interact\nStatus: Ready\nNote: Please provide a valid 'host' parameter.\nExample: {\"host\": \"web1\", \"command\": \"sudo apt-get install nginx\", \"script\": \"expect continue\\nsend y\"}", nil
+	}
+	steps, err := sshclient.ParseExpectScript(stringArg(args, "script"))
+	if err != nil {
+		return "", err
+	}
+	for _, step := range steps {
+		if step.Timeout > maxWaitTimeout {
+			return "", fmt.Errorf("timeout must be at most %s", maxWaitTimeout)
+		}
+	}
+
+	// 输入的每一行同样经过安全检查
+	config.SafetyCheck = true
+	config.Source = s.auditSource()
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	applyOutputLimit(config, settings, args)
+	applyShellArgs(config, args)
+
+	client, err := sshclient.NewSSHClient(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	if err = client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	result, err := client.Interact(steps, sshclient.Request{Command: stringArg(args, "command"), RunAs: stringArg(args, "run_as")})
+	if err != nil {
+		// 失败时附上已有的交互记录，便于看出程序停在哪个提示
+		return "", fmt.Errorf("%w\n%s", err, result.Output)
+	}
+	return annotateSafety(result.Output, result.Safety), nil
+}
+
+// jobScheduler 返回服务器的作业调度器
+func (s *MCPServer) jobScheduler() (*Scheduler, error) {
+	if s.scheduler == nil {
+		return nil, fmt.Errorf("the job scheduler is not running")
+	}
+	return s.scheduler, nil
+}
+
+// executeJobList 列出计划作业
+func (s *MCPServer) executeJobList(args map[string]interface{}) (string, error) {
+	scheduler, err := s.jobScheduler()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(scheduler.List(), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode jobs: %w", err)
+	}
+	return string(data), nil
+}
+
+// executeJobAdd 添加或替换计划作业并保存到配置
+func (s *MCPServer) executeJobAdd(args map[string]interface{}) (string, error) {
+	scheduler, err := s.jobScheduler()
+	if err != nil {
+		return "", err
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings, err := LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	job := JobConfig{
+		Name:     stringArg(args, "job"),
+		Schedule: stringArg(args, "schedule"),
+		Hosts:    stringArg(args, "hosts"),
+		Command:  stringArg(args, "command"),
+		Disabled: boolArg(args, "disabled"),
+	}
+	if err := validateJob(settings, &job); err != nil {
+		return "", err
+	}
+
+	action := "added"
+	if existing := findJob(settings, job.Name); existing != nil {
+		*existing = job
+		action = "updated"
+	} else {
+		settings.Jobs = append(settings.Jobs, job)
+	}
+	if err := SaveSettings(settings); err != nil {
+		return "", fmt.Errorf("failed to save settings: %w", err)
+	}
+	if err := scheduler.Add(settings, job); err != nil {
+		return "", err
+	}
+
+	message := fmt.Sprintf("Job '%s' %s: %s on %s", job.Name, action, job.Schedule, job.Hosts)
+	if job.Disabled {
+		return message + " (disabled)", nil
+	}
+	schedule, _ := parseCron(job.Schedule) //nolint:errcheck // validated above
+	if next := schedule.next(time.Now()); !next.IsZero() {
+		message += fmt.Sprintf(", next run at %s", next.Format(time.RFC3339))
+	}
+	return message, nil
+}
+
+// executeJobRunNow 立即运行计划作业
+func (s *MCPServer) executeJobRunNow(args map[string]interface{}) (string, error) {
+	scheduler, err := s.jobScheduler()
+	if err != nil {
+		return "", err
+	}
+
+	name := stringArg(args, "job")
+	if name == "" {
+		return "", fmt.Errorf("job is required")
+	}
+	run, err := scheduler.RunNow(name)
+	if err != nil {
+		return "", err
+	}
+	if run.Error != "" {
+		return "", fmt.Errorf("job '%s' %s\n%s", name, run.Error, run.Report)
+	}
+	return run.Report, nil
+}
+
+// executeHostAdd 执行添加主机配置
+func (s *MCPServer) executeHostAdd(args map[string]interface{}) (string, error) {
+	// Load settings
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings, err := LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	// Extract parameters
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+
+	host, ok := args["host"].(string)
+	if !ok || host == "" {
+		return "", fmt.Errorf("host address is required")
+	}
+
+	// Build host config
+	hostConfig := HostConfig{
+		Name: name,
+		Host: host,
+	}
+
+	// Optional parameters
+	if description, ok := args["description"].(string); ok {
+		hostConfig.Description = description
+	}
+
+	if port := stringArg(args, "port"); port != "" {
+		hostConfig.Port = port
+	} else {
+		hostConfig.Port = "22"
+	}
+
+	if user, ok := args["user"].(string); ok && user != "" {
+		hostConfig.User = user
+	} else {
+		hostConfig.User = "master"
+	}
+
+	if passwordKey, ok := args["password_key"].(string); ok {
+		hostConfig.PasswordKey = passwordKey
+	}
+
+	if hostType, ok := args["type"].(string); ok && hostType != "" {
+		hostConfig.Type = hostType
+	} else {
+		hostConfig.Type = "linux"
+	}
+
+	if tags, ok := args["tags"].(string); ok {
+		hostConfig.Tags = splitList(tags)
+	}
+
+	if key, ok := args["key"].(string); ok {
+		hostConfig.Key = key
+	}
+	hostConfig.Shell = stringArg(args, "shell")
+	hostConfig.Device = stringArg(args, "device")
+	hostConfig.Addresses = splitList(stringArg(args, "addresses"))
+	if hostConfig.Meta, err = mergeMeta(nil, stringArg(args, "meta")); err != nil {
+		return "", err
+	}
+	if hostConfig.Env, err = mergeEnv(nil, stringArg(args, "env")); err != nil {
+		return "", err
+	}
+	if hostConfig.Expires, err = hostExpiry(stringArg(args, "ttl"), time.Now()); err != nil {
+		return "", err
+	}
+
+	// Add host
+	if err := AddHost(settings, hostConfig); err != nil {
+		return "", fmt.Errorf("failed to add host: %w", err)
+	}
+
+	// Save settings
+	if err := SaveSettings(settings); err != nil {
+		return "", fmt.Errorf("failed to save settings: %w", err)
+	}
+
+	return fmt.Sprintf("Host '%s' (%s) added successfully", name, host), nil
+}
+
+// executeHostUpdate 只修改提供的主机字段
+func (s *MCPServer) executeHostUpdate(args map[string]interface{}) (string, error) {
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+
+	fields := make(map[string]string)
+	var changed []string
+	for _, field := range HostUpdateFields {
+		// port arrives as a number once validated against the schema
+		if _, ok := args[field]; ok {
+			fields[field] = stringArg(args, field)
+			changed = append(changed, field)
+		}
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings, err := LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	host, err := ApplyHostUpdate(settings, name, fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to update host: %w", err)
+	}
+	if err := SaveSettings(settings); err != nil {
+		return "", fmt.Errorf("failed to save settings: %w", err)
+	}
+
+	return fmt.Sprintf("Host '%s' updated (%s): %s", host.Name, strings.Join(changed, ", "), hostAddress(host)), nil
+}
+
+// executeHostList 执行列出主机配置，可按元数据筛选和分组
+func (s *MCPServer) executeHostList(args map[string]interface{}) (string, error) {
+	// Load settings
+	settings, err := LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	hosts := ListHosts(settings)
+	title := "Configured Hosts"
+	if find := stringArg(args, "find"); find != "" {
+		if hosts, err = FindHosts(settings, find); err != nil {
+			return "", err
+		}
+		if len(hosts) == 0 {
+			return fmt.Sprintf("No hosts match %s.", find), nil
+		}
+		title = "Hosts matching " + find
+	}
+
+	if len(hosts) == 0 {
+		return "No hosts configured.\n\nTo add hosts, use the host_add tool.", nil
+	}
+
+	// Build output
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("=== %s (%d) ===\n\n", title, len(hosts)))
+
+	groupBy := stringArg(args, "group_by")
+	if groupBy == "" {
+		for i, host := range hosts {
+			writeHostEntry(&output, i+1, host)
+		}
+		return output.String(), nil
+	}
+	index := 1
+	for _, group := range groupHostsBy(hosts, groupBy) {
+		output.WriteString(fmt.Sprintf("--- %s: %s (%d) ---\n\n", groupBy, group.Value, len(group.Hosts)))
+		for _, host := range group.Hosts {
+			writeHostEntry(&output, index, host)
+			index++
+		}
+	}
+	return output.String(), nil
+}
+
+// writeHostEntry 写入主机列表中的一个主机
+func writeHostEntry(output *strings.Builder, index int, host HostConfig) {
+	output.WriteString(fmt.Sprintf("[%d] %s\n", index, host.Name))
+	output.WriteString(fmt.Sprintf("    Host:        %s\n", host.Host))
+	if len(host.Addresses) > 0 {
+		output.WriteString(fmt.Sprintf("    Addresses:   %s\n", strings.Join(host.Addresses, ", ")))
+	}
+	if host.Description != "" {
+		output.WriteString(fmt.Sprintf("    Description: %s\n", host.Description))
+	}
+	if host.Port != "" && host.Port != "22" {
+		output.WriteString(fmt.Sprintf("    Port:        %s\n", host.Port))
+	}
+	if host.User != "" {
+		output.WriteString(fmt.Sprintf("    User:        %s\n", host.User))
+	}
+	if host.PasswordKey != "" {
+		output.WriteString(fmt.Sprintf("    Password Key: %s\n", host.PasswordKey))
+	}
+	if host.Type != "" {
+		output.WriteString(fmt.Sprintf("    Type:        %s\n", host.Type))
+	}
+	if host.Device != "" {
+		output.WriteString(fmt.Sprintf("    Device:      %s\n", host.Device))
+	}
+	if len(host.Tags) > 0 {
+		output.WriteString(fmt.Sprintf("    Tags:        %s\n", strings.Join(host.Tags, ", ")))
+	}
+	if len(host.Meta) > 0 {
+		output.WriteString(fmt.Sprintf("    Meta:        %s\n", formatMeta(host.Meta)))
+	}
+	if !host.Expires.IsZero() {
+		output.WriteString(fmt.Sprintf("    Expires:     %s\n", formatExpiry(host.Expires, time.Now())))
+	}
+	output.WriteString("\n")
+}
+
+// executeHostTest 执行测试主机连接
+func (s *MCPServer) executeHostTest(args map[string]interface{}) (string, error) {
+	// Load settings
+	settings, err := LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	// Extract name parameter
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return "", fmt.Errorf("host name is required")
+	}
+
 	// Get host configuration
 	hostConfig, err := GetHost(settings, name)
 	if err != nil {
 		return "", fmt.Errorf("host '%s' not found: %w", name, err)
 	}
 
-	// Create SSH config for testing with command
+	// Create SSH config for testing
 	testConfig := &sshclient.Config{
 		Host:       hostConfig.Host,
 		Port:       hostConfig.Port,
 		User:       hostConfig.User,
-		Command:    "echo 'Connection test successful'",
 		UseKeyAuth: true,
 	}
 
@@ -1156,6 +5014,12 @@ func (s *MCPServer) executeHostTest(args map[string]interface{}) (string, error)
 	if settings.Key != "" {
 		testConfig.KeyPath = settings.Key
 	}
+	applyHostKeySettings(testConfig, settings)
+	applyMCPTrust(testConfig, settings)
+	applyConnectionDefaults(testConfig, hostConfig, settings)
+	if testConfig.DialTimeout <= 0 {
+		testConfig.DialTimeout = hostTestDialTimeout
+	}
 
 	// Try to get password if password key is configured
 	if hostConfig.PasswordKey != "" {
@@ -1165,36 +5029,86 @@ func (s *MCPServer) executeHostTest(args map[string]interface{}) (string, error)
 		}
 	}
 
-	// Create single SSH client for testing (avoid connection pool reuse issues)
-	client, err := sshclient.NewSSHClient(testConfig)
+	// 使用独立连接测试（不经过连接池），并记录各阶段耗时
+	result := testHostConnection(hostConfig, testConfig)
+	data, err := json.MarshalIndent(result.Report(), "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to create SSH client: %w", err)
+		return "", fmt.Errorf("failed to encode host test report: %w", err)
 	}
-	defer func() {
-		// Force close to avoid returning to pool
-		_ = client.ForceClose() //nolint:errcheck
-	}()
+	return string(data), nil
+}
+
+// executeHostPing 检测主机 SSH 端口是否可达
+func (s *MCPServer) executeHostPing(args map[string]interface{}) (string, error) {
+	spec, ok := args["hosts"].(string)
+	if !ok || spec == "" {
+		return "", fmt.Errorf("hosts is required")
+	}
+
+	settings, err := LoadSettings()
+	if err != nil {
+		return "", fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	names, results, err := pingHosts(settings, spec, sshclient.PingOptions{ICMP: boolArg(args, "icmp"), Progress: s.progress})
+	if err != nil {
+		return "", err
+	}
+	return formatPingResults(names, results), nil
+}
+
+// executeHostImport 从 ssh_config 或 JSON 文件导入主机
+func (s *MCPServer) executeHostImport(args map[string]interface{}) (string, error) {
+	source, _ := args["source"].(string) //nolint:errcheck // optional
+	path, _ := args["path"].(string)     //nolint:errcheck // optional
 
-	// Test connection - use direct connection to bypass pool
-	if connectErr := client.ConnectDirect(); connectErr != nil {
-		return "", fmt.Errorf("connection failed: %w", connectErr)
+	summary, err := importHosts(source, path, boolArg(args, "overwrite"))
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode summary: %w", err)
 	}
+	return string(data), nil
+}
+
+// executeHostDiagnose 逐步诊断主机连接并返回 JSON 报告
+func (s *MCPServer) executeHostDiagnose(args map[string]interface{}) (string, error) {
+	target, ok := args["host"].(string)
+	if !ok || target == "" {
+		return "", fmt.Errorf("host is required")
+	}
+	port := stringArg(args, "port")
+	user, _ := args["user"].(string) //nolint:errcheck // optional
 
-	// Test command execution
-	output, err := client.ExecuteCommandWithOutput()
+	settings, err := LoadSettings()
 	if err != nil {
-		return "", fmt.Errorf("command execution failed: %w", err)
+		return "", fmt.Errorf("failed to load settings: %w", err)
 	}
 
-	result := fmt.Sprintf("✓ Connection to '%s' (%s) successful!\n✓ Command execution successful!\n\nTest output: %s",
-		name, hostConfig.Host, strings.TrimSpace(output))
+	base := &sshclient.Config{Port: port, User: user, UseKeyAuth: true, Source: s.auditSource()}
+	applyHostKeySettings(base, settings)
+	applyMCPTrust(base, settings)
+	s.identify(base)
 
-	return result, nil
+	config := diagnoseConfig(settings, target, base)
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = hostTestDialTimeout
+	}
+	report := sshclient.Diagnose(config)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode report: %w", err)
+	}
+	return string(data), nil
 }
 
 // executeHostRemove 执行删除主机配置
 func (s *MCPServer) executeHostRemove(args map[string]interface{}) (string, error) {
 	// Load settings
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
 	settings, err := LoadSettings()
 	if err != nil {
 		return "", fmt.Errorf("failed to load settings: %w", err)
@@ -1218,3 +5132,42 @@ func (s *MCPServer) executeHostRemove(args map[string]interface{}) (string, erro
 
 	return fmt.Sprintf("Host '%s' removed successfully", name), nil
 }
+
+// executeKnownHostsList 列出 MCP 自动信任的主机密钥
+func (s *MCPServer) executeKnownHostsList(_ map[string]interface{}) (string, error) {
+	path, err := mcpKnownHostsPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate MCP known_hosts: %w", err)
+	}
+	return listMCPKnownHosts(path)
+}
+
+// executeKnownHostsPromote 将 MCP 信任的主机密钥提升到用户的 known_hosts
+func (s *MCPServer) executeKnownHostsPromote(args map[string]interface{}) (string, error) {
+	host, ok := args["host"].(string)
+	if !ok || host == "" {
+		return "", fmt.Errorf("host is required")
+	}
+	mcpPath, err := mcpKnownHostsPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate MCP known_hosts: %w", err)
+	}
+	userPath, err := userKnownHostsPath(nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate known_hosts: %w", err)
+	}
+	return promoteMCPKnownHost(mcpPath, userPath, host)
+}
+
+// executeKnownHostsDeny 拒绝 MCP 信任的主机密钥
+func (s *MCPServer) executeKnownHostsDeny(args map[string]interface{}) (string, error) {
+	host, ok := args["host"].(string)
+	if !ok || host == "" {
+		return "", fmt.Errorf("host is required")
+	}
+	mcpPath, err := mcpKnownHostsPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate MCP known_hosts: %w", err)
+	}
+	return denyMCPKnownHost(mcpPath, host)
+}
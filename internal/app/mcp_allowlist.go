@@ -0,0 +1,99 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// readOnlyToolsSelector selects every tool declared ReadOnly in an allow or
+// deny list
+const readOnlyToolsSelector = "@readonly"
+
+// MCPToolsConfig limits the tools the MCP server registers and advertises.
+// Entries are tool names, glob patterns such as "sftp_*" or "@readonly".
+type MCPToolsConfig struct {
+	Allow []string `json:"allow,omitempty"` // Only these tools are exposed (default: all)
+	Deny  []string `json:"deny,omitempty"`  // These tools are never exposed, even when allowed
+}
+
+// mcpToolFilter resolves the allow and deny lists for the MCP server: the
+// --tools and --deny-tools flags replace the lists from settings.mcp_tools.
+// local_exec is denied unless it was opted into.
+func mcpToolFilter(settings *Settings, args []string) (allow, deny []string) {
+	if settings != nil && settings.MCPTools != nil {
+		allow, deny = settings.MCPTools.Allow, settings.MCPTools.Deny
+	}
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--tools="):
+			allow = splitList(strings.SplitN(arg, "=", 2)[1])
+		case strings.HasPrefix(arg, "--deny-tools="):
+			deny = splitList(strings.SplitN(arg, "=", 2)[1])
+		}
+	}
+	if !localExecEnabled(settings, args) {
+		deny = append(slices.Clone(deny), localExecTool)
+	}
+	return allow, deny
+}
+
+// RestrictTools removes every tool not selected by allow (all tools when
+// empty) or selected by deny from the registry and the advertised list.
+// Entries that select no tool are rejected so typos do not silently expose
+// or hide the wrong tools.
+func (s *MCPServer) RestrictTools(allow, deny []string) error {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil
+	}
+
+	specs := mcpToolSpecs()
+	allowed, err := selectTools(specs, allow, "--tools")
+	if err != nil {
+		return err
+	}
+	denied, err := selectTools(specs, deny, "--deny-tools")
+	if err != nil {
+		return err
+	}
+
+	tools := make([]MCPTool, 0, len(s.tools))
+	for _, tool := range s.tools {
+		if (len(allow) > 0 && !allowed[tool.Name]) || denied[tool.Name] {
+			delete(s.registry, tool.Name)
+			continue
+		}
+		tools = append(tools, tool)
+	}
+	if len(tools) == 0 {
+		return fmt.Errorf("no MCP tools left to expose after applying the allow and deny lists")
+	}
+	s.tools = tools
+	return nil
+}
+
+// selectTools returns the names of the tools matched by entries
+func selectTools(specs []mcpToolSpec, entries []string, option string) (map[string]bool, error) {
+	selected := make(map[string]bool)
+	for _, entry := range entries {
+		matched := false
+		for _, spec := range specs {
+			ok := spec.ReadOnly && entry == readOnlyToolsSelector
+			if !ok {
+				var err error
+				if ok, err = path.Match(entry, spec.Name); err != nil {
+					return nil, fmt.Errorf("invalid pattern '%s' in %s: %w", entry, option, err)
+				}
+			}
+			if ok {
+				selected[spec.Name] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("'%s' in %s does not match any MCP tool", entry, option)
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func toolNames(tools []MCPTool) []string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+func TestRestrictTools(t *testing.T) {
+	server := NewMCPServer()
+	require.NoError(t, server.RestrictTools([]string{"sftp_download", "sftp_l*", "host_list"}, nil))
+	assert.Equal(t, []string{"sftp_download", "sftp_list", "host_list"}, toolNames(server.tools))
+	assert.Len(t, server.registry, 3)
+
+	_, err := server.executeTool("ssh_execute", map[string]interface{}{"host": "web1", "command": "id"})
+	assert.ErrorContains(t, err, "unknown tool")
+
+	server = NewMCPServer()
+	require.NoError(t, server.RestrictTools([]string{readOnlyToolsSelector}, []string{"host_ping"}))
+	for _, tool := range server.tools {
+		require.NotNil(t, tool.Annotations, tool.Name)
+		assert.True(t, tool.Annotations.ReadOnlyHint, tool.Name)
+		assert.NotEqual(t, "host_ping", tool.Name)
+	}
+
+	server = NewMCPServer()
+	require.NoError(t, server.RestrictTools(nil, []string{"ssh_execute", "script_execute"}))
+	assert.NotContains(t, toolNames(server.tools), "ssh_execute")
+	assert.Contains(t, toolNames(server.tools), "sftp_upload")
+}
+
+func TestRestrictTools_Errors(t *testing.T) {
+	err := NewMCPServer().RestrictTools([]string{"ssh_exec"}, nil)
+	assert.ErrorContains(t, err, "'ssh_exec' in --tools does not match any MCP tool")
+
+	err = NewMCPServer().RestrictTools([]string{"[sftp"}, nil)
+	assert.ErrorContains(t, err, "invalid pattern")
+
+	err = NewMCPServer().RestrictTools([]string{"sftp_list"}, []string{"sftp_*"})
+	assert.ErrorContains(t, err, "no MCP tools left")
+}
+
+func TestMCPToolFilter(t *testing.T) {
+	settings := &Settings{MCPTools: &MCPToolsConfig{Allow: []string{"@readonly"}, Deny: []string{"host_ping"}}}
+
+	allow, deny := mcpToolFilter(settings, nil)
+	assert.Equal(t, []string{"@readonly"}, allow)
+	assert.Equal(t, []string{"host_ping", localExecTool}, deny)
+	assert.Equal(t, []string{"host_ping"}, settings.MCPTools.Deny, "settings are not changed")
+
+	allow, deny = mcpToolFilter(settings, []string{"--debug", "--tools=sftp_list, host_list"})
+	assert.Equal(t, []string{"sftp_list", "host_list"}, allow)
+	assert.Equal(t, []string{"host_ping", localExecTool}, deny)
+
+	allow, deny = mcpToolFilter(nil, []string{"--deny-tools=ssh_execute"})
+	assert.Empty(t, allow)
+	assert.Equal(t, []string{"ssh_execute", localExecTool}, deny)
+
+	// local_exec is only exposed when opted into
+	_, deny = mcpToolFilter(nil, []string{allowLocalExecFlag})
+	assert.Empty(t, deny)
+	_, deny = mcpToolFilter(&Settings{LocalExec: &LocalExecConfig{Enabled: true}}, nil)
+	assert.Empty(t, deny)
+	_, deny = mcpToolFilter(&Settings{LocalExec: &LocalExecConfig{WorkDir: "/tmp"}}, nil)
+	assert.Equal(t, []string{localExecTool}, deny)
+}
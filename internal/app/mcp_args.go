@@ -0,0 +1,228 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ToolArgumentError reports a tools/call argument that does not match the
+// tool's input schema. It is answered with a JSON-RPC invalid params error
+// naming the field instead of a tool execution failure.
+type ToolArgumentError struct {
+	Tool   string
+	Field  string
+	Reason string
+}
+
+func (e *ToolArgumentError) Error() string {
+	return fmt.Sprintf("invalid argument '%s' for %s: %s", e.Field, e.Tool, e.Reason)
+}
+
+// validateToolArgs checks args against a tool's input schema and returns a
+// copy with every declared argument coerced to its schema type, so a port
+// sent as the string "22" reaches the handler as the integer 22 and a force
+// flag sent as "true" as a boolean. Null arguments
+// count as absent; arguments the schema does not declare are passed through
+// unchanged, as JSON Schema allows additional properties by default.
+func validateToolArgs(tool string, schema ToolSchema, args map[string]interface{}) (map[string]interface{}, error) {
+	validated := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		if value == nil {
+			continue
+		}
+		property, declared := schema.Properties[name]
+		if !declared {
+			validated[name] = value
+			continue
+		}
+
+		coerced, err := coerceToolArg(property.Type, value)
+		if err != nil {
+			return nil, &ToolArgumentError{Tool: tool, Field: name, Reason: err.Error()}
+		}
+		if len(property.Enum) > 0 && !containsString(property.Enum, fmt.Sprint(coerced)) {
+			return nil, &ToolArgumentError{
+				Tool:   tool,
+				Field:  name,
+				Reason: fmt.Sprintf("must be one of %s, got %q", strings.Join(property.Enum, ", "), fmt.Sprint(coerced)),
+			}
+		}
+		validated[name] = coerced
+	}
+
+	for _, name := range schema.Required {
+		if value, ok := validated[name]; !ok || value == "" {
+			return nil, &ToolArgumentError{Tool: tool, Field: name, Reason: "is required"}
+		}
+	}
+	return validated, nil
+}
+
+// coerceToolArg converts a decoded JSON value to a schema type. Scalars are
+// converted between strings, numbers and booleans when the conversion is
+// lossless; anything else is rejected with the type that was received.
+func coerceToolArg(schemaType string, value interface{}) (interface{}, error) {
+	switch schemaType {
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case json.Number:
+			return v.String(), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+	case "integer":
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				return int64(v), nil
+			}
+			return nil, fmt.Errorf("expected an integer, got %v", v)
+		case string:
+			n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("expected an integer, got %q", v)
+			}
+			return n, nil
+		case json.Number:
+			n, err := v.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("expected an integer, got %s", v)
+			}
+			return n, nil
+		}
+	case "number":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("expected a number, got %q", v)
+			}
+			return n, nil
+		case json.Number:
+			return v.Float64()
+		}
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("expected a boolean, got %q", v)
+			}
+			return b, nil
+		}
+	case "array":
+		if v, ok := value.([]interface{}); ok {
+			return v, nil
+		}
+	case "object":
+		if v, ok := value.(map[string]interface{}); ok {
+			return v, nil
+		}
+	default:
+		return value, nil
+	}
+	return nil, fmt.Errorf("expected %s %s, got %s", article(schemaType), schemaType, jsonTypeName(value))
+}
+
+// jsonTypeName names the JSON type of a decoded value for error messages
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// article picks "a" or "an" for a schema type name
+func article(word string) string {
+	if word != "" && strings.ContainsRune("aeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// stringArg reads an argument as a string, formatting numbers such as an
+// integer port the way older clients sent them
+func stringArg(args map[string]interface{}, name string) string {
+	switch v := args[name].(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// intArg reads an integer argument, or fallback when it is absent or not a
+// number
+func intArg(args map[string]interface{}, name string, fallback int) int {
+	switch v := args[name].(type) {
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+// durationArg reads a duration argument such as "30s" or "5m"; a bare
+// number is a count of seconds. It is 0 when absent or invalid.
+func durationArg(args map[string]interface{}, name string) time.Duration {
+	value := stringArg(args, name)
+	if _, err := strconv.Atoi(value); err == nil {
+		value += "s"
+	}
+	return firstDuration(name, value)
+}
+
+// boolArg reads a boolean argument. The strings "true" and "1" sent by
+// clients predating boolean schemas still count as true.
+func boolArg(args map[string]interface{}, name string) bool {
+	switch v := args[name].(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v) //nolint:errcheck // anything else is false
+		return b
+	}
+	return false
+}
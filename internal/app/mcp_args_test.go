@@ -0,0 +1,124 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateToolArgs(t *testing.T) {
+	schema := ToolSchema{
+		Type: "object",
+		Properties: map[string]Property{
+			"host":  {Type: "string"},
+			"port":  {Type: "string"},
+			"force": {Type: "string", Enum: []string{"true", "false"}},
+			"count": {Type: "integer"},
+			"dry":   {Type: "boolean"},
+		},
+		Required: []string{"host"},
+	}
+
+	args, err := validateToolArgs("ssh_execute", schema, map[string]interface{}{
+		"host":  "web1",
+		"port":  float64(2222),
+		"force": true,
+		"count": "3",
+		"dry":   "true",
+		"extra": float64(1),
+		"user":  nil,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2222", args["port"])
+	assert.Equal(t, "true", args["force"])
+	assert.Equal(t, int64(3), args["count"])
+	assert.Equal(t, true, args["dry"])
+	assert.Equal(t, float64(1), args["extra"], "undeclared arguments pass through")
+	assert.NotContains(t, args, "user", "null counts as absent")
+
+	tests := []struct {
+		name  string
+		args  map[string]interface{}
+		field string
+		want  string
+	}{
+		{"missing required", map[string]interface{}{}, "host", "is required"},
+		{"empty required", map[string]interface{}{"host": ""}, "host", "is required"},
+		{"wrong type", map[string]interface{}{"host": []interface{}{"a"}}, "host", "expected a string, got array"},
+		{"enum", map[string]interface{}{"host": "web1", "force": "yes"}, "force", "must be one of true, false"},
+		{"fractional integer", map[string]interface{}{"host": "web1", "count": 1.5}, "count", "expected an integer"},
+		{"bad boolean", map[string]interface{}{"host": "web1", "dry": "maybe"}, "dry", "expected a boolean"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateToolArgs("ssh_execute", schema, tt.args)
+			var argErr *ToolArgumentError
+			require.True(t, errors.As(err, &argErr), "got %v", err)
+			assert.Equal(t, tt.field, argErr.Field)
+			assert.Contains(t, argErr.Reason, tt.want)
+		})
+	}
+}
+
+func TestTypedArgs(t *testing.T) {
+	args := map[string]interface{}{
+		"force":    true,
+		"legacy":   "true",
+		"one":      "1",
+		"off":      "no",
+		"port":     int64(2222),
+		"fraction": 1.5,
+	}
+	assert.True(t, boolArg(args, "force"))
+	assert.True(t, boolArg(args, "legacy"))
+	assert.True(t, boolArg(args, "one"))
+	assert.False(t, boolArg(args, "off"))
+	assert.False(t, boolArg(args, "missing"))
+	assert.Equal(t, "2222", stringArg(args, "port"))
+	assert.Equal(t, "1.5", stringArg(args, "fraction"))
+	assert.Equal(t, "", stringArg(args, "missing"))
+}
+
+func TestToolSchemaTypes(t *testing.T) {
+	for _, spec := range mcpToolSpecs() {
+		schema, ok := spec.InputSchema.(ToolSchema)
+		require.True(t, ok, spec.Name)
+		for name, property := range schema.Properties {
+			assert.NotEqual(t, []string{"true", "false"}, property.Enum, "%s.%s should be a boolean", spec.Name, name)
+			if name == "port" {
+				assert.Equal(t, "integer", property.Type, "%s.port", spec.Name)
+			}
+		}
+	}
+
+	args, err := validateToolArgs("ssh_execute", mcpToolSpecs()[0].InputSchema.(ToolSchema), map[string]interface{}{
+		"host": "web1", "command": "uptime", "port": "2222", "force": "true",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2222), args["port"], "string ports from older clients are coerced")
+	assert.Equal(t, true, args["force"], "string booleans from older clients are coerced")
+}
+
+func TestExecuteTool_ValidatesArguments(t *testing.T) {
+	server := NewMCPServer()
+
+	_, err := server.executeTool("host_test", map[string]interface{}{})
+	var argErr *ToolArgumentError
+	require.True(t, errors.As(err, &argErr), "got %v", err)
+	assert.Equal(t, "name", argErr.Field)
+
+	_, err = server.executeTool("host_ping", map[string]interface{}{"hosts": "web1", "icmp": "sometimes"})
+	require.True(t, errors.As(err, &argErr), "got %v", err)
+	assert.Equal(t, "icmp", argErr.Field)
+}
+
+func TestDurationArg(t *testing.T) {
+	assert.Equal(t, 10*time.Second, durationArg(map[string]interface{}{"timeout": "10"}, "timeout"))
+	assert.Equal(t, 10*time.Second, durationArg(map[string]interface{}{"timeout": int64(10)}, "timeout"))
+	assert.Equal(t, 5*time.Minute, durationArg(map[string]interface{}{"timeout": "5m"}, "timeout"))
+	assert.Zero(t, durationArg(map[string]interface{}{}, "timeout"))
+	assert.Zero(t, durationArg(map[string]interface{}{"timeout": "soon"}, "timeout"))
+}
@@ -0,0 +1,194 @@
+package app
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// MCPTokenEnv carries the API token an MCP client presents to a server with
+// mcp_auth policies
+const MCPTokenEnv = "SSHX_MCP_TOKEN"
+
+// anyHost grants a token every host, including unconfigured addresses
+const anyHost = "*"
+
+// MCPTokenPolicy grants the holder of an API token a set of tools on a set
+// of hosts. The token itself is never stored, only its sha256.
+type MCPTokenPolicy struct {
+	Name        string   `json:"name"`         // Caller name recorded in the audit log
+	TokenSHA256 string   `json:"token_sha256"` // Hex sha256 of the token
+	Tools       []string `json:"tools"`        // Tool names, globs or @readonly ("*" for every tool)
+	Hosts       []string `json:"hosts"`        // Configured host names or groups ("*" for any host)
+}
+
+// authenticateMCPToken returns the policy matching token. With no policies
+// configured every caller is unrestricted and nil is returned; otherwise an
+// empty or unknown token is an error.
+func authenticateMCPToken(policies []MCPTokenPolicy, token string) (*MCPTokenPolicy, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	if token == "" {
+		return nil, fmt.Errorf("mcp_auth is configured: set %s to an API token", MCPTokenEnv)
+	}
+	sum := sha256.Sum256([]byte(token))
+	presented := hex.EncodeToString(sum[:])
+	for i := range policies {
+		expected := strings.ToLower(strings.TrimSpace(policies[i].TokenSHA256))
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(expected)) == 1 {
+			return &policies[i], nil
+		}
+	}
+	return nil, fmt.Errorf("the token in %s matches no mcp_auth policy", MCPTokenEnv)
+}
+
+// mcpCallerFromEnv authenticates the stdio client by the token in
+// SSHX_MCP_TOKEN against settings.mcp_auth
+func mcpCallerFromEnv(settings *Settings) (*MCPTokenPolicy, error) {
+	if settings == nil {
+		return nil, nil
+	}
+	return authenticateMCPToken(settings.MCPAuth, os.Getenv(MCPTokenEnv))
+}
+
+// allowsTool reports whether the policy grants tool
+func (p *MCPTokenPolicy) allowsTool(tool string) bool {
+	if p == nil {
+		return true
+	}
+	for _, entry := range p.Tools {
+		selected, err := selectTools(mcpToolSpecs(), []string{entry}, "mcp_auth")
+		if err == nil && selected[tool] {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHost reports whether the policy grants target, a configured host
+// name or address. A configured host is granted by its name or one of its
+// groups; unconfigured addresses only by "*".
+func (p *MCPTokenPolicy) allowsHost(settings *Settings, target string) bool {
+	if p == nil || containsString(p.Hosts, anyHost) {
+		return true
+	}
+	host := lookupHost(settings, target)
+	if host == nil {
+		return false
+	}
+	for _, entry := range p.Hosts {
+		if entry == host.Name || host.HasTag(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsRecord reports whether the policy may see a record (audit entry or
+// execution event) of a command on a configured host or address. Records
+// without a target are only shown to tokens granted every host.
+func (p *MCPTokenPolicy) allowsRecord(host, address string) bool {
+	if p == nil || containsString(p.Hosts, anyHost) {
+		return true
+	}
+	target := host
+	if target == "" {
+		target = address
+	}
+	if target == "" {
+		return false
+	}
+	settings, err := LoadSettings()
+	if err != nil {
+		settings = &Settings{}
+	}
+	return p.allowsHost(settings, target)
+}
+
+// mcpCallTargets lists the hosts a tool call acts on: the host argument,
+// every host of the hosts argument, the host named by name and the hosts of
+// the job named by job
+func mcpCallTargets(settings *Settings, args map[string]interface{}) []string {
+	var targets []string
+	for _, field := range []string{"host", "name"} {
+		if target := stringArg(args, field); target != "" {
+			targets = append(targets, target)
+		}
+	}
+	specs := []string{stringArg(args, "hosts")}
+	if job := findJob(settings, stringArg(args, "job")); job != nil {
+		// Running a job runs its command on the job's hosts
+		specs = append(specs, job.Hosts)
+	}
+	for _, spec := range specs {
+		for _, item := range splitList(spec) {
+			hosts, err := ResolveHostGroup(settings, item)
+			if err != nil {
+				// Unknown names are only granted by "*"
+				targets = append(targets, item)
+				continue
+			}
+			for _, host := range hosts {
+				targets = append(targets, host.Name)
+			}
+		}
+	}
+	return targets
+}
+
+// authorizeToolCall checks a tools/call against the caller's policy. A
+// denial is recorded in the audit log and returned as an error.
+func (s *MCPServer) authorizeToolCall(name string, args map[string]interface{}) error {
+	if s.caller == nil {
+		return nil
+	}
+
+	settings, err := LoadSettings()
+	if err != nil {
+		settings = &Settings{}
+	}
+
+	reason := ""
+	target := ""
+	if !s.caller.allowsTool(name) {
+		reason = fmt.Sprintf("token '%s' may not use %s", s.caller.Name, name)
+	} else {
+		for _, candidate := range mcpCallTargets(settings, args) {
+			if !s.caller.allowsHost(settings, candidate) {
+				target = candidate
+				reason = fmt.Sprintf("token '%s' may not use %s on %s", s.caller.Name, name, candidate)
+				break
+			}
+		}
+	}
+	if reason == "" {
+		return nil
+	}
+
+	recordAuditEntry(settings, AuditEntry{
+		Time:    time.Now().UTC(),
+		Source:  s.auditSource(),
+		Caller:  s.caller.Name,
+		Client:  s.client,
+		Tool:    name,
+		Address: target,
+		Status:  AuditStatusDenied,
+		Error:   reason,
+	})
+	return &MCPAuthorizationError{Reason: reason}
+}
+
+// MCPAuthorizationError is returned for tool calls the caller's token does
+// not grant
+type MCPAuthorizationError struct {
+	Reason string
+}
+
+func (e *MCPAuthorizationError) Error() string {
+	return "permission denied: " + e.Reason
+}
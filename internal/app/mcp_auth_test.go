@@ -0,0 +1,141 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestAuthenticateMCPToken(t *testing.T) {
+	policies := []MCPTokenPolicy{
+		{Name: "staging-ro", TokenSHA256: tokenHash("token-a")},
+		{Name: "admin", TokenSHA256: tokenHash("token-b")},
+	}
+
+	caller, err := authenticateMCPToken(nil, "")
+	require.NoError(t, err)
+	assert.Nil(t, caller, "no policies means no restrictions")
+
+	caller, err = authenticateMCPToken(policies, "token-b")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", caller.Name)
+
+	_, err = authenticateMCPToken(policies, "")
+	assert.ErrorContains(t, err, MCPTokenEnv)
+
+	_, err = authenticateMCPToken(policies, "token-c")
+	assert.ErrorContains(t, err, "matches no mcp_auth policy")
+}
+
+func TestMCPTokenPolicy(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "stage1", Host: "10.0.1.1", Tags: []string{"staging"}},
+		{Name: "prod1", Host: "10.0.2.1", Tags: []string{"prod"}},
+	}}
+	policy := &MCPTokenPolicy{Name: "staging-ro", Tools: []string{"@readonly"}, Hosts: []string{"staging"}}
+
+	assert.True(t, policy.allowsTool("sftp_list"))
+	assert.False(t, policy.allowsTool("ssh_execute"))
+	assert.True(t, policy.allowsHost(settings, "stage1"))
+	assert.True(t, policy.allowsHost(settings, "10.0.1.1"), "addresses of configured hosts resolve to the host")
+	assert.False(t, policy.allowsHost(settings, "prod1"))
+	assert.False(t, policy.allowsHost(settings, "192.168.0.9"))
+
+	admin := &MCPTokenPolicy{Name: "admin", Tools: []string{"*"}, Hosts: []string{"*"}}
+	assert.True(t, admin.allowsTool("ssh_execute"))
+	assert.True(t, admin.allowsHost(settings, "192.168.0.9"))
+
+	var unrestricted *MCPTokenPolicy
+	assert.True(t, unrestricted.allowsTool("ssh_execute"))
+	assert.True(t, unrestricted.allowsHost(settings, "192.168.0.9"))
+
+	assert.ElementsMatch(t, []string{"stage1", "prod1", "stage1"},
+		mcpCallTargets(settings, map[string]interface{}{"host": "stage1", "hosts": "prod,stage1"}))
+}
+
+func TestMCPTokenPolicy_AllowsRecord(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, SaveSettings(&Settings{Hosts: []HostConfig{
+		{Name: "stage1", Host: "10.0.1.1", Tags: []string{"staging"}},
+	}}))
+	staging := &MCPTokenPolicy{Name: "staging", Hosts: []string{"staging"}}
+	admin := &MCPTokenPolicy{Name: "admin", Hosts: []string{"*"}}
+	var unrestricted *MCPTokenPolicy
+
+	assert.True(t, unrestricted.allowsRecord("", ""))
+	assert.True(t, admin.allowsRecord("", ""))
+	assert.False(t, staging.allowsRecord("", ""), "records without a target need every host")
+	assert.True(t, staging.allowsRecord("stage1", "10.0.1.1"))
+	assert.True(t, staging.allowsRecord("", "10.0.1.1"))
+	assert.False(t, staging.allowsRecord("", "10.0.2.1"))
+}
+
+func TestToolsCall_Authorization(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, SaveSettings(&Settings{Hosts: []HostConfig{
+		{Name: "stage1", Host: "10.0.1.1", Port: "22", User: "root", Tags: []string{"staging"}},
+		{Name: "prod1", Host: "10.0.2.1", Port: "22", User: "root", Tags: []string{"prod"}},
+	}}))
+
+	var out bytes.Buffer
+	server := NewMCPServer()
+	server.stdout = &out
+	server.caller = &MCPTokenPolicy{Name: "staging-ro", Tools: []string{"@readonly"}, Hosts: []string{"staging"}}
+	server.client = "cursor/1.4.2"
+
+	server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/list"})
+	messages := mcpMessages(t, &out)
+	require.Len(t, messages, 1)
+	var listed struct {
+		Result struct {
+			Tools []MCPTool `json:"tools"`
+		} `json:"result"`
+	}
+	data, err := json.Marshal(messages[0])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &listed))
+	assert.Contains(t, toolNames(listed.Result.Tools), "sftp_list")
+	assert.NotContains(t, toolNames(listed.Result.Tools), "ssh_execute")
+
+	call := func(params string) map[string]interface{} {
+		server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 2, Method: "tools/call", Params: json.RawMessage(params)})
+		messages := mcpMessages(t, &out)
+		require.Len(t, messages, 1)
+		return messages[0]
+	}
+
+	denied := call(`{"name": "ssh_execute", "arguments": {"host": "stage1", "command": "id"}}`)
+	assert.Equal(t, float64(-32001), denied["error"].(map[string]interface{})["code"])
+	assert.Contains(t, denied["error"].(map[string]interface{})["message"], "may not use ssh_execute")
+
+	denied = call(`{"name": "host_ping", "arguments": {"hosts": "prod1"}}`)
+	assert.Contains(t, denied["error"].(map[string]interface{})["message"], "on prod1")
+
+	allowed := call(`{"name": "host_list", "arguments": {}}`)
+	assert.Contains(t, allowed, "result")
+
+	audit, err := os.ReadFile(filepath.Join(home, SettingsDir, AuditLogFile))
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(audit), []byte("\n"))
+	require.Len(t, lines, 2)
+	var entry AuditEntry
+	require.NoError(t, json.Unmarshal(lines[1], &entry))
+	assert.Equal(t, AuditStatusDenied, entry.Status)
+	assert.Equal(t, "staging-ro", entry.Caller)
+	assert.Equal(t, "cursor/1.4.2", entry.Client)
+	assert.Equal(t, "host_ping", entry.Tool)
+	assert.Equal(t, "prod1", entry.Address)
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// defaultMCPLogLevel is forwarded to clients that never call
+// logging/setLevel: connection progress and warnings, but no debug output
+const defaultMCPLogLevel = logger.LogLevelInfo
+
+// MCPNotification is a JSON-RPC message without an id
+type MCPNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// mcpLogLevels maps the syslog levels of the MCP logging capability to
+// sshx log levels; sshx has no levels above error
+var mcpLogLevels = map[string]logger.LogLevel{
+	"debug":     logger.LogLevelDebug,
+	"info":      logger.LogLevelInfo,
+	"notice":    logger.LogLevelInfo,
+	"warning":   logger.LogLevelWarning,
+	"error":     logger.LogLevelError,
+	"critical":  logger.LogLevelError,
+	"alert":     logger.LogLevelError,
+	"emergency": logger.LogLevelError,
+}
+
+// mcpLogLevelName is the MCP level a sshx log level is reported as
+func mcpLogLevelName(level logger.LogLevel) string {
+	switch level {
+	case logger.LogLevelTrace, logger.LogLevelDebug:
+		return "debug"
+	case logger.LogLevelWarning:
+		return "warning"
+	case logger.LogLevelError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// handleSetLogLevel 处理 logging/setLevel：调整转发给客户端的最低日志级别
+func (s *MCPServer) handleSetLogLevel(req *MCPRequest) {
+	var params struct {
+		Level string `json:"level"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		s.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+	level, ok := mcpLogLevels[params.Level]
+	if !ok {
+		s.sendError(req.ID, -32602, fmt.Sprintf("Invalid params: unknown log level '%s'", params.Level), map[string]interface{}{
+			"field": "level",
+		})
+		return
+	}
+
+	logger.GetLogger().SetSink(level, s.sendLogMessage)
+	s.sendResponse(req.ID, map[string]interface{}{})
+}
+
+// sendLogMessage 将一条日志作为 notifications/message 发送给客户端。
+// 它作为日志 sink 运行，因此不能再写日志。
+func (s *MCPServer) sendLogMessage(level logger.LogLevel, message string) {
+	s.writeJSON(MCPNotification{
+		JSONRPC: "2.0",
+		Method:  "notifications/message",
+		Params: map[string]interface{}{
+			"level":  mcpLogLevelName(level),
+			"logger": "sshx",
+			"data":   message,
+		},
+	})
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// mcpMessages decodes the JSON-RPC messages written to out
+func mcpMessages(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var messages []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var message map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &message), line)
+		messages = append(messages, message)
+	}
+	out.Reset()
+	return messages
+}
+
+func TestMCPLogging(t *testing.T) {
+	var out bytes.Buffer
+	server := NewMCPServer()
+	server.stdout = &out
+	t.Cleanup(func() { logger.GetLogger().SetSink(defaultMCPLogLevel, nil) })
+
+	server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "initialize"})
+	messages := mcpMessages(t, &out)
+	require.Len(t, messages, 1)
+	capabilities := messages[0]["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+	assert.Contains(t, capabilities, "logging")
+
+	logger.GetLogger().Debug("not forwarded by default")
+	logger.GetLogger().Info("connecting to %s", "web1")
+	messages = mcpMessages(t, &out)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "notifications/message", messages[0]["method"])
+	assert.NotContains(t, messages[0], "id")
+	params := messages[0]["params"].(map[string]interface{})
+	assert.Equal(t, "info", params["level"])
+	assert.Equal(t, "sshx", params["logger"])
+	assert.Equal(t, "connecting to web1", params["data"])
+
+	server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 2, Method: "logging/setLevel", Params: json.RawMessage(`{"level":"warning"}`)})
+	messages = mcpMessages(t, &out)
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "result")
+
+	logger.GetLogger().Info("quiet now")
+	logger.GetLogger().Warning("retrying web1")
+	messages = mcpMessages(t, &out)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "warning", messages[0]["params"].(map[string]interface{})["level"])
+
+	server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 3, Method: "logging/setLevel", Params: json.RawMessage(`{"level":"verbose"}`)})
+	messages = mcpMessages(t, &out)
+	require.Len(t, messages, 1)
+	assert.Equal(t, float64(-32602), messages[0]["error"].(map[string]interface{})["code"])
+}
+
+func TestMCPInitialize_ClientIdentity(t *testing.T) {
+	var out bytes.Buffer
+	server := NewMCPServer()
+	server.stdout = &out
+	server.caller = &MCPTokenPolicy{Name: "ops"}
+	t.Cleanup(func() { logger.GetLogger().SetSink(defaultMCPLogLevel, nil) })
+
+	server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "initialize",
+		Params: json.RawMessage(`{"protocolVersion": "2024-11-05", "clientInfo": {"name": "claude-desktop", "version": "0.9.2"}}`)})
+	require.Len(t, mcpMessages(t, &out), 1)
+	assert.Equal(t, "claude-desktop/0.9.2", server.client)
+
+	config := &sshclient.Config{}
+	server.identify(config)
+	assert.Equal(t, "claude-desktop/0.9.2", config.Client)
+	assert.Equal(t, "ops", config.Caller)
+	assert.Equal(t, "claude-desktop/0.9.2 (ops)", config.Identity())
+}
+
+func TestClientIdentity(t *testing.T) {
+	assert.Equal(t, "cursor/1.4.2", clientIdentity("cursor", "1.4.2"))
+	assert.Equal(t, "cursor", clientIdentity(" cursor ", ""))
+	assert.Empty(t, clientIdentity("", "1.0"))
+	assert.Equal(t, "evil/1", clientIdentity("evil\n", "1\x1b"))
+}
@@ -0,0 +1,21 @@
+package app
+
+import "github.com/talkincode/sshmcp/internal/sshclient"
+
+// progressNotifier returns a progress callback that sends
+// notifications/progress for the request that supplied token
+func (s *MCPServer) progressNotifier(token interface{}) sshclient.ProgressFunc {
+	return func(done, total int64, item string) {
+		params := map[string]interface{}{
+			"progressToken": token,
+			"progress":      done,
+		}
+		if total > 0 {
+			params["total"] = total
+		}
+		if item != "" {
+			params["message"] = item
+		}
+		s.writeJSON(MCPNotification{JSONRPC: "2.0", Method: "notifications/progress", Params: params})
+	}
+}
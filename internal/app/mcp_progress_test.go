@@ -0,0 +1,52 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressNotifier(t *testing.T) {
+	var out bytes.Buffer
+	server := NewMCPServer()
+	server.stdout = &out
+
+	notify := server.progressNotifier("upload-1")
+	notify(512, 2048, "/srv/app.tar")
+	notify(3, 0, "")
+
+	messages := mcpMessages(t, &out)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "notifications/progress", messages[0]["method"])
+	assert.Equal(t, map[string]interface{}{
+		"progressToken": "upload-1",
+		"progress":      float64(512),
+		"total":         float64(2048),
+		"message":       "/srv/app.tar",
+	}, messages[0]["params"])
+	assert.Equal(t, map[string]interface{}{
+		"progressToken": "upload-1",
+		"progress":      float64(3),
+	}, messages[1]["params"])
+}
+
+func TestToolsCall_ProgressToken(t *testing.T) {
+	var out bytes.Buffer
+	server := NewMCPServer()
+	server.stdout = &out
+
+	server.handleRequest(&MCPRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"name": "pool_stats", "arguments": {}, "_meta": {"progressToken": 7}}`),
+	})
+
+	messages := mcpMessages(t, &out)
+	require.NotEmpty(t, messages)
+	assert.Contains(t, messages[len(messages)-1], "result")
+	assert.Nil(t, server.progress, "the progress callback only lives for its tool call")
+}
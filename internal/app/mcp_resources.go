@@ -0,0 +1,186 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+const (
+	// eventsResourceURI is the MCP resource holding the latest execution events
+	eventsResourceURI = "sshx://events"
+	// hostsResourceURI is the MCP resource holding the host inventory
+	hostsResourceURI = "sshx://hosts"
+)
+
+// hostInventoryEntry is a configured host as the hosts resource shows it,
+// without credentials
+type hostInventoryEntry struct {
+	Name        string            `json:"name"`
+	Host        string            `json:"host"`
+	Addresses   []string          `json:"addresses,omitempty"`
+	Port        string            `json:"port,omitempty"`
+	User        string            `json:"user,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	Device      string            `json:"device,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Meta        map[string]string `json:"meta,omitempty"`
+	Expires     time.Time         `json:"expires,omitzero"`
+}
+
+// MCPResource describes a resource in resources/list
+type MCPResource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// handleResourcesList 处理资源列表请求
+func (s *MCPServer) handleResourcesList(req *MCPRequest) {
+	s.sendResponse(req.ID, map[string]interface{}{
+		"resources": []MCPResource{{
+			URI:         eventsResourceURI,
+			Name:        "Execution events",
+			Description: "The latest commands run by sshx: started, finished and blocked events as a JSON array, oldest first. Subscribe to be notified when commands start and end.",
+			MimeType:    "application/json",
+		}, {
+			URI:         hostsResourceURI,
+			Name:        "Host inventory",
+			Description: "The configured hosts as a JSON array: name, address, port, user, type, description, tags, metadata (e.g. rack, owner, environment, app) and the expiry of ephemeral hosts. Use it to choose the hosts a task should target.",
+			MimeType:    "application/json",
+		}},
+	})
+}
+
+// resourceURI 读取 resources/read、subscribe 和 unsubscribe 请求中的 uri，
+// 不在 known 中的资源返回错误响应并报告 false
+func (s *MCPServer) resourceURI(req *MCPRequest, known ...string) (string, bool) {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		s.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return "", false
+	}
+	if !containsString(known, params.URI) {
+		s.sendError(req.ID, -32002, "Resource not found", map[string]interface{}{"uri": params.URI})
+		return "", false
+	}
+	return params.URI, true
+}
+
+// handleResourcesRead 返回令牌可见的最近执行事件或主机清单
+func (s *MCPServer) handleResourcesRead(req *MCPRequest) {
+	uri, ok := s.resourceURI(req, eventsResourceURI, hostsResourceURI)
+	if !ok {
+		return
+	}
+	var visible interface{}
+	if uri == hostsResourceURI {
+		hosts, err := s.visibleHosts()
+		if err != nil {
+			s.sendError(req.ID, -32603, "Internal error", err.Error())
+			return
+		}
+		visible = hosts
+	} else {
+		events := []sshclient.Event{}
+		for _, event := range sshclient.RecentEvents() {
+			if s.caller.allowsRecord(event.Host, event.Address) {
+				events = append(events, event)
+			}
+		}
+		visible = events
+	}
+	text, err := json.Marshal(visible)
+	if err != nil {
+		s.sendError(req.ID, -32603, "Internal error", err.Error())
+		return
+	}
+	s.sendResponse(req.ID, map[string]interface{}{
+		"contents": []map[string]interface{}{{
+			"uri":      uri,
+			"mimeType": "application/json",
+			"text":     string(text),
+		}},
+	})
+}
+
+// visibleHosts 返回令牌可访问的已配置主机
+func (s *MCPServer) visibleHosts() ([]hostInventoryEntry, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	entries := []hostInventoryEntry{}
+	for _, host := range ListHosts(settings) {
+		if !s.caller.allowsHost(settings, host.Name) {
+			continue
+		}
+		entries = append(entries, hostInventoryEntry{
+			Name:        host.Name,
+			Host:        host.Host,
+			Addresses:   host.Addresses,
+			Port:        host.Port,
+			User:        host.User,
+			Type:        host.Type,
+			Device:      host.Device,
+			Description: host.Description,
+			Tags:        host.Tags,
+			Meta:        host.Meta,
+			Expires:     host.Expires,
+		})
+	}
+	return entries, nil
+}
+
+// handleResourcesSubscribe 订阅执行事件：命令开始和结束时发送
+// notifications/resources/updated，输出片段不发送通知
+func (s *MCPServer) handleResourcesSubscribe(req *MCPRequest) {
+	if _, ok := s.resourceURI(req, eventsResourceURI); !ok {
+		return
+	}
+	if s.stopEvents == nil {
+		events, unsubscribe := sshclient.SubscribeEvents()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for event := range events {
+				if event.Type == sshclient.EventOutput || !s.caller.allowsRecord(event.Host, event.Address) {
+					continue
+				}
+				s.writeJSON(MCPNotification{
+					JSONRPC: "2.0",
+					Method:  "notifications/resources/updated",
+					Params:  map[string]interface{}{"uri": eventsResourceURI},
+				})
+			}
+		}()
+		s.stopEvents = func() {
+			unsubscribe()
+			<-done
+		}
+	}
+	s.sendResponse(req.ID, map[string]interface{}{})
+}
+
+// handleResourcesUnsubscribe 取消执行事件订阅
+func (s *MCPServer) handleResourcesUnsubscribe(req *MCPRequest) {
+	if _, ok := s.resourceURI(req, eventsResourceURI); !ok {
+		return
+	}
+	s.unsubscribeEvents()
+	s.sendResponse(req.ID, map[string]interface{}{})
+}
+
+// unsubscribeEvents 结束执行事件订阅（如果有）
+func (s *MCPServer) unsubscribeEvents() {
+	if s.stopEvents != nil {
+		s.stopEvents()
+		s.stopEvents = nil
+	}
+}
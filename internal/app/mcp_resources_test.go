@@ -0,0 +1,106 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestMCPResources_Events(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var out bytes.Buffer
+	server := NewMCPServer()
+	server.stdout = &out
+	defer server.unsubscribeEvents()
+
+	server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "resources/list"})
+	messages := mcpMessages(t, &out)
+	require.Len(t, messages, 1)
+	resources := messages[0]["result"].(map[string]interface{})["resources"].([]interface{})
+	require.Len(t, resources, 2)
+	assert.Equal(t, eventsResourceURI, resources[0].(map[string]interface{})["uri"])
+	assert.Equal(t, hostsResourceURI, resources[1].(map[string]interface{})["uri"])
+
+	server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 2, Method: "resources/subscribe", Params: json.RawMessage(`{"uri":"sshx://events"}`)})
+	require.Len(t, mcpMessages(t, &out), 1)
+
+	run := sshclient.Chain(func(*sshclient.Config) (string, error) { return "", nil }, sshclient.EventsMiddleware)
+	_, err := run(&sshclient.Config{Host: "10.0.0.5", Command: "hostname"})
+	require.NoError(t, err)
+
+	// started and finished each notify the subscriber
+	require.Eventually(t, func() bool {
+		server.writeMu.Lock()
+		defer server.writeMu.Unlock()
+		return bytes.Count(out.Bytes(), []byte("notifications/resources/updated")) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	server.unsubscribeEvents()
+	out.Reset()
+
+	server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 3, Method: "resources/read", Params: json.RawMessage(`{"uri":"sshx://events"}`)})
+	messages = mcpMessages(t, &out)
+	require.Len(t, messages, 1)
+	contents := messages[0]["result"].(map[string]interface{})["contents"].([]interface{})
+	var events []sshclient.Event
+	require.NoError(t, json.Unmarshal([]byte(contents[0].(map[string]interface{})["text"].(string)), &events))
+	require.NotEmpty(t, events)
+	last := events[len(events)-1]
+	assert.Equal(t, sshclient.EventFinished, last.Type)
+	assert.Equal(t, "hostname", last.Command)
+
+	// A token restricted to some hosts does not see other hosts
+	server.caller = &MCPTokenPolicy{Name: "staging", Hosts: []string{"staging"}}
+	server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 4, Method: "resources/read", Params: json.RawMessage(`{"uri":"sshx://events"}`)})
+	messages = mcpMessages(t, &out)
+	contents = messages[0]["result"].(map[string]interface{})["contents"].([]interface{})
+	assert.Equal(t, "[]", contents[0].(map[string]interface{})["text"])
+
+	server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 5, Method: "resources/read", Params: json.RawMessage(`{"uri":"sshx://nope"}`)})
+	messages = mcpMessages(t, &out)
+	assert.Equal(t, float64(-32002), messages[0]["error"].(map[string]interface{})["code"])
+}
+
+func TestMCPResources_Hosts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, SaveSettings(&Settings{Hosts: []HostConfig{
+		{Name: "web1", Host: "10.0.0.1", PasswordKey: "web1-pw", Tags: []string{"prod"}, Meta: map[string]string{"rack": "r12"}},
+		{Name: "staging", Host: "10.0.0.2", Tags: []string{"staging"}},
+	}}))
+	var out bytes.Buffer
+	server := NewMCPServer()
+	server.stdout = &out
+
+	read := func() []hostInventoryEntry {
+		server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "resources/read", Params: json.RawMessage(`{"uri":"sshx://hosts"}`)})
+		messages := mcpMessages(t, &out)
+		require.Len(t, messages, 1)
+		contents := messages[0]["result"].(map[string]interface{})["contents"].([]interface{})
+		text := contents[0].(map[string]interface{})["text"].(string)
+		assert.NotContains(t, text, "web1-pw")
+		var hosts []hostInventoryEntry
+		require.NoError(t, json.Unmarshal([]byte(text), &hosts))
+		return hosts
+	}
+
+	hosts := read()
+	require.Len(t, hosts, 2)
+	assert.Equal(t, "web1", hosts[0].Name)
+	assert.Equal(t, map[string]string{"rack": "r12"}, hosts[0].Meta)
+
+	// A token restricted to some hosts only sees those
+	server.caller = &MCPTokenPolicy{Name: "staging", Hosts: []string{"staging"}}
+	hosts = read()
+	require.Len(t, hosts, 1)
+	assert.Equal(t, "staging", hosts[0].Name)
+
+	// Only the events resource can be subscribed to
+	server.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 2, Method: "resources/subscribe", Params: json.RawMessage(`{"uri":"sshx://hosts"}`)})
+	messages := mcpMessages(t, &out)
+	assert.Equal(t, float64(-32002), messages[0]["error"].(map[string]interface{})["code"])
+}
@@ -124,7 +124,7 @@ func TestExecuteSSH_ForceParameter(t *testing.T) {
 func TestGetPoolStats(t *testing.T) {
 	server := NewMCPServer()
 
-	result, err := server.getPoolStats()
+	result, err := server.getPoolStats(nil)
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, result)
@@ -280,17 +280,60 @@ func TestMCPToolDefinitions(t *testing.T) {
 
 	expectedTools := []string{
 		"ssh_execute",
+		"ssh_watch",
+		"wait_for",
+		"ssh_interact",
 		"sftp_upload",
 		"sftp_download",
+		"sftp_append",
 		"sftp_list",
 		"sftp_mkdir",
 		"sftp_remove",
+		"trash_purge",
 		"script_execute",
 		"pool_stats",
+		"pool_connections",
+		"server_info",
+		"pool_evict",
+		"pool_warm",
+		"sftp_distribute",
+		"sftp_collect",
 		"host_add",
+		"host_update",
 		"host_list",
 		"host_test",
+		"host_ping",
+		"host_import",
+		"host_diagnose",
 		"host_remove",
+		"known_hosts_list",
+		"known_hosts_promote",
+		"known_hosts_deny",
+		"container_exec",
+		"container_list",
+		"container_logs",
+		"k8s_node_status",
+		"k8s_kubelet_logs",
+		"k8s_crictl_ps",
+		"package_manage",
+		"user_create",
+		"authorized_keys_add",
+		"authorized_keys_list",
+		"authorized_keys_revoke",
+		"port_check",
+		"host_metrics",
+		"process_list",
+		"process_signal",
+		"journal_query",
+		"cron_list",
+		"cron_add",
+		"cron_remove",
+		"host_reboot",
+		"db_dump",
+		"db_restore",
+		"job_list",
+		"job_add",
+		"job_run_now",
 	}
 
 	for _, expected := range expectedTools {
@@ -298,6 +341,30 @@ func TestMCPToolDefinitions(t *testing.T) {
 	}
 }
 
+func TestMCPToolSpecs(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, spec := range mcpToolSpecs() {
+		assert.False(t, seen[spec.Name], "tool %s is declared twice", spec.Name)
+		seen[spec.Name] = true
+		assert.True(t, (spec.Handler == nil) != (spec.RemoteHandler == nil),
+			"tool %s must have exactly one of Handler and RemoteHandler", spec.Name)
+	}
+
+	for _, tool := range defineMCPTools() {
+		if tool.Name == "sftp_list" {
+			require.NotNil(t, tool.Annotations)
+			assert.True(t, tool.Annotations.ReadOnlyHint)
+		}
+		if tool.Name == "ssh_execute" {
+			assert.Nil(t, tool.Annotations)
+		}
+	}
+
+	server := NewMCPServer()
+	_, err := server.executeTool("no_such_tool", map[string]interface{}{})
+	assert.ErrorContains(t, err, "unknown tool")
+}
+
 func TestMCPRequest_Unmarshal(t *testing.T) {
 	jsonData := `{
 		"jsonrpc": "2.0",
@@ -334,3 +401,288 @@ func TestMCPResponse_Marshal(t *testing.T) {
 	assert.Contains(t, string(data), "jsonrpc")
 	assert.Contains(t, string(data), "success")
 }
+
+func TestExecutePoolWarm_MissingHosts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := NewMCPServer()
+
+	result, err := server.executePoolWarm(map[string]interface{}{})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "hosts is required")
+	assert.Empty(t, result)
+}
+
+func TestExecuteKnownHostsTools(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := NewMCPServer()
+
+	result, err := server.executeKnownHostsList(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, result, "No host keys accepted")
+
+	_, err = server.executeKnownHostsPromote(map[string]interface{}{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "host is required")
+
+	_, err = server.executeKnownHostsDeny(map[string]interface{}{"host": "unknown"})
+	assert.Error(t, err)
+}
+
+func TestExecuteSftpAppend_Args(t *testing.T) {
+	server := NewMCPServer()
+	config := &sshclient.Config{Host: "10.0.0.5"}
+
+	_, err := server.executeSftpAppend(config, map[string]interface{}{})
+	assert.ErrorContains(t, err, "remote_path is required")
+
+	_, err = server.executeSftpAppend(config, map[string]interface{}{"remote_path": "/var/log/deploy.log"})
+	assert.ErrorContains(t, err, "content or content_base64 is required")
+
+	_, err = server.executeSftpAppend(config, map[string]interface{}{"remote_path": "/var/log/deploy.log", "content": "a", "content_base64": "YQ=="})
+	assert.ErrorContains(t, err, "mutually exclusive")
+}
+
+func TestExecuteSftpDownload_RangeArgs(t *testing.T) {
+	server := NewMCPServer()
+	config := &sshclient.Config{Host: "10.0.0.5"}
+
+	_, err := server.executeSftpDownload(config, map[string]interface{}{"remote_path": "/var/log/syslog", "offset": int64(-100), "local_path": "/tmp/syslog"})
+	assert.ErrorContains(t, err, "cannot be combined with local_path")
+
+	_, err = server.executeSftpDownload(config, map[string]interface{}{"remote_path": "/var/log/syslog", "length": int64(maxInlineBytes + 1)})
+	assert.ErrorContains(t, err, "length must be between 1 and")
+}
+
+func TestExecuteSftpDistribute_MissingArgs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := NewMCPServer()
+
+	_, err := server.executeSftpDistribute(map[string]interface{}{})
+	assert.ErrorContains(t, err, "hosts is required")
+
+	_, err = server.executeSftpDistribute(map[string]interface{}{"hosts": "web1"})
+	assert.ErrorContains(t, err, "local_path is required")
+
+	_, err = server.executeSftpDistribute(map[string]interface{}{"hosts": "web1", "local_path": "a"})
+	assert.ErrorContains(t, err, "remote_path is required")
+}
+
+func TestExecuteSftpCollect_MissingArgs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := NewMCPServer()
+
+	_, err := server.executeSftpCollect(map[string]interface{}{})
+	assert.ErrorContains(t, err, "hosts is required")
+
+	_, err = server.executeSftpCollect(map[string]interface{}{"hosts": "web1"})
+	assert.ErrorContains(t, err, "a remote path or command is required")
+}
+
+func TestApplyContainerArgs(t *testing.T) {
+	config := &sshclient.Config{}
+	require.NoError(t, applyContainerArgs(config, map[string]interface{}{"container": "web1", "runtime": "podman"}))
+	assert.Equal(t, "web1", config.Container)
+	assert.Equal(t, "podman", config.ContainerRuntime)
+
+	assert.ErrorContains(t, applyContainerArgs(&sshclient.Config{}, map[string]interface{}{"container": "-it"}), "invalid container name")
+	assert.ErrorContains(t, applyContainerArgs(&sshclient.Config{}, map[string]interface{}{"container": "web1", "runtime": "lxc"}), "unsupported container runtime")
+
+	assert.Equal(t, 20, intArg(map[string]interface{}{"tail": int64(20)}, "tail", defaultContainerLogLines))
+	assert.Equal(t, defaultContainerLogLines, intArg(map[string]interface{}{}, "tail", defaultContainerLogLines))
+}
+
+func TestExecuteHostPing_MissingHosts(t *testing.T) {
+	server := NewMCPServer()
+
+	_, err := server.executeHostPing(map[string]interface{}{})
+	assert.ErrorContains(t, err, "hosts is required")
+}
+
+func TestExecuteHostUpdate_MissingName(t *testing.T) {
+	server := NewMCPServer()
+
+	_, err := server.executeHostUpdate(map[string]interface{}{"port": "22"})
+	assert.ErrorContains(t, err, "name is required")
+}
+
+func TestExecuteHostUpdate_NumericPort(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, SaveSettings(&Settings{Hosts: []HostConfig{{Name: "web1", Host: "10.0.0.5", Port: "22"}}}))
+	server := NewMCPServer()
+
+	// The schema turns port into a number before the handler sees it
+	_, err := server.executeTool("host_update", map[string]interface{}{"name": "web1", "port": float64(2222)})
+	require.NoError(t, err)
+	settings, err := LoadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, "2222", settings.Hosts[0].Port)
+}
+
+func TestAccountTools_Args(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := NewMCPServer()
+
+	// Names and keys are checked before connecting
+	_, err := server.executeTool("user_create", map[string]interface{}{"host": "10.0.0.5", "name": "deploy;id"})
+	assert.ErrorContains(t, err, "invalid user name")
+	_, err = server.executeTool("user_create", map[string]interface{}{"host": "10.0.0.5", "name": "deploy", "public_key": "not a key"})
+	assert.ErrorContains(t, err, "invalid public key")
+	_, err = server.executeTool("authorized_keys_add", map[string]interface{}{"host": "10.0.0.5", "account": "deploy"})
+	assert.ErrorContains(t, err, "public_key or public_key_path is required")
+	_, err = server.executeTool("authorized_keys_add", map[string]interface{}{"host": "10.0.0.5", "public_key_path": "~/missing.pub"})
+	assert.ErrorContains(t, err, "failed to read public key")
+	_, err = server.executeTool("authorized_keys_list", map[string]interface{}{"host": "10.0.0.5", "account": "Root"})
+	assert.ErrorContains(t, err, "invalid user name")
+	_, err = server.executeTool("authorized_keys_revoke", map[string]interface{}{"host": "10.0.0.5", "key": " "})
+	assert.ErrorContains(t, err, "key is required")
+
+	output, err := server.executeTool("authorized_keys_list", map[string]interface{}{"host": "0.0.0.0"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "Status: Ready")
+}
+
+func TestExecutePortCheck_Args(t *testing.T) {
+	server := NewMCPServer()
+
+	_, err := server.executeTool("port_check", map[string]interface{}{"host": "10.0.0.5", "check_port": float64(70000)})
+	assert.ErrorContains(t, err, "check_port must be between 1 and 65535")
+	_, err = server.executeTool("port_check", map[string]interface{}{"host": "10.0.0.5", "check_port": float64(53), "protocol": "sctp"})
+	assert.ErrorContains(t, err, "must be one of tcp, udp")
+
+	output, err := server.executeTool("port_check", map[string]interface{}{"host": "0.0.0.0"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "Status: Ready")
+}
+
+func TestExecuteHostMetrics_Args(t *testing.T) {
+	server := NewMCPServer()
+
+	_, err := server.executeTool("host_metrics", map[string]interface{}{"host": "10.0.0.5", "samples": float64(50)})
+	assert.ErrorContains(t, err, "samples must be between 1 and 30")
+	_, err = server.executeTool("host_metrics", map[string]interface{}{"host": "10.0.0.5", "samples": float64(20), "interval": "5s"})
+	assert.ErrorContains(t, err, "at most 1m0s")
+	_, err = server.executeTool("host_metrics", map[string]interface{}{"host": "10.0.0.5", "interval": "1.5s"})
+	assert.ErrorContains(t, err, "whole number of seconds")
+
+	output, err := server.executeTool("host_metrics", map[string]interface{}{"host": "0.0.0.0"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "Status: Ready")
+}
+
+func TestProcessTools_Args(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := NewMCPServer()
+
+	// Filters and signals are checked before connecting
+	_, err := server.executeTool("process_list", map[string]interface{}{"host": "10.0.0.5", "name": "worker("})
+	assert.ErrorContains(t, err, "invalid name pattern")
+	_, err = server.executeTool("process_signal", map[string]interface{}{"host": "10.0.0.5", "pid": float64(0)})
+	assert.ErrorContains(t, err, "pid must be a positive number")
+	_, err = server.executeTool("process_signal", map[string]interface{}{"host": "10.0.0.5", "pid": float64(4242), "dry_run": false})
+	assert.ErrorContains(t, err, "expect_name is required")
+
+	output, err := server.executeTool("process_signal", map[string]interface{}{"host": "0.0.0.0", "pid": float64(4242)})
+	require.NoError(t, err)
+	assert.Contains(t, output, "Status: Ready")
+}
+
+func TestExecuteJournalQuery_Args(t *testing.T) {
+	server := NewMCPServer()
+
+	// Units and limits are checked before connecting
+	_, err := server.executeTool("journal_query", map[string]interface{}{"host": "10.0.0.5", "unit": "nginx.service,$(reboot)"})
+	assert.ErrorContains(t, err, "invalid unit name '$(reboot)'")
+	_, err = server.executeTool("journal_query", map[string]interface{}{"host": "10.0.0.5", "lines": float64(5000)})
+	assert.ErrorContains(t, err, "lines must be between 1 and 2000")
+
+	output, err := server.executeTool("journal_query", map[string]interface{}{"host": "0.0.0.0"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "Status: Ready")
+}
+
+func TestExecuteCronTools_Args(t *testing.T) {
+	server := NewMCPServer()
+
+	// Entries and accounts are checked before connecting
+	_, err := server.executeTool("cron_add", map[string]interface{}{"host": "10.0.0.5", "entry": "daily /srv/app/bin/rotate"})
+	assert.ErrorContains(t, err, "invalid cron entry")
+	_, err = server.executeTool("cron_add", map[string]interface{}{"host": "10.0.0.5", "entry": "@daily /bin/true\n@reboot /bin/true"})
+	assert.ErrorContains(t, err, "a cron entry must be a single line")
+	_, err = server.executeTool("cron_remove", map[string]interface{}{"host": "10.0.0.5", "match": "  "})
+	assert.ErrorContains(t, err, "match must not be empty")
+	_, err = server.executeTool("cron_list", map[string]interface{}{"host": "10.0.0.5", "account": "-u root"})
+	assert.ErrorContains(t, err, "invalid user name")
+	_, err = server.executeTool("cron_remove", map[string]interface{}{"host": "10.0.0.5", "match": "report", "account": "Bad User"})
+	assert.ErrorContains(t, err, "invalid user name")
+
+	for _, tool := range []string{"cron_list", "cron_add", "cron_remove"} {
+		output, err := server.executeTool(tool, map[string]interface{}{"host": "0.0.0.0", "entry": "@daily /bin/true", "match": "x"})
+		require.NoError(t, err)
+		assert.Contains(t, output, "MCP Tool: "+tool)
+	}
+}
+
+func TestExecuteHostReboot_Args(t *testing.T) {
+	server := NewMCPServer()
+
+	// Rebooting needs the host repeated in confirm, checked before connecting
+	_, err := server.executeTool("host_reboot", map[string]interface{}{"host": "10.0.0.5", "dry_run": false})
+	assert.ErrorContains(t, err, "confirm must repeat the host argument ('10.0.0.5')")
+	_, err = server.executeTool("host_reboot", map[string]interface{}{"host": "10.0.0.5", "dry_run": false, "confirm": "10.0.0.6"})
+	assert.ErrorContains(t, err, "confirm must repeat the host argument")
+	_, err = server.executeTool("host_reboot", map[string]interface{}{"host": "10.0.0.5", "min_uptime": "soon"})
+	assert.ErrorContains(t, err, "invalid min_uptime 'soon'")
+
+	output, err := server.executeTool("host_reboot", map[string]interface{}{"host": "0.0.0.0"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "Status: Ready")
+}
+
+func TestApplyForceArgs(t *testing.T) {
+	config := &sshclient.Config{Safety: &sshclient.SafetyPolicy{ForceTOTPKey: "prod-totp"}}
+	applyForceArgs(config, map[string]interface{}{"force": true, "force_reason": "hotfix"})
+	assert.True(t, config.Force)
+	assert.Equal(t, "hotfix", config.ForceReason)
+	require.NotNil(t, config.ConfirmForce)
+	_, err := config.ConfirmForce(&sshclient.BlockedCommandError{Rule: "reboot"})
+	assert.ErrorContains(t, err, "requires a TOTP code as confirm_token")
+
+	plain := &sshclient.Config{Safety: &sshclient.SafetyPolicy{}}
+	applyForceArgs(plain, map[string]interface{}{"force": true, "force_reason": "hotfix"})
+	assert.Nil(t, plain.ConfirmForce, "no TOTP without force_totp_key")
+}
+
+func TestExecutePackageManage_Args(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, SaveSettings(&Settings{ProtectedPackages: []string{"postgresql*"}}))
+	server := NewMCPServer()
+
+	// Arguments and protected packages are checked before connecting
+	_, err := server.executeTool("package_manage", map[string]interface{}{"host": "10.0.0.5", "action": "remove", "packages": "nginx, openssh-server"})
+	assert.ErrorContains(t, err, "'openssh-server' is protected")
+	_, err = server.executeTool("package_manage", map[string]interface{}{"host": "10.0.0.5", "action": "remove", "packages": "postgresql-16", "dry_run": false})
+	assert.ErrorContains(t, err, "'postgresql-16' is protected (postgresql*)")
+	_, err = server.executeTool("package_manage", map[string]interface{}{"host": "10.0.0.5", "action": "install", "packages": "nginx;reboot"})
+	assert.ErrorContains(t, err, "invalid package name")
+	_, err = server.executeTool("package_manage", map[string]interface{}{"host": "10.0.0.5", "action": "purge"})
+	assert.Error(t, err)
+}
+
+func TestExecuteHostDiagnose_MissingHost(t *testing.T) {
+	server := NewMCPServer()
+
+	_, err := server.executeHostDiagnose(map[string]interface{}{})
+	assert.ErrorContains(t, err, "host is required")
+}
+
+func TestApplyShellArgs(t *testing.T) {
+	config := &sshclient.Config{}
+	applyShellArgs(config, map[string]interface{}{"cwd": "/opt/app", "login_shell": true})
+	assert.Equal(t, "/opt/app", config.WorkDir)
+	assert.True(t, config.LoginShell)
+
+	applyShellArgs(config, map[string]interface{}{})
+	assert.Empty(t, config.WorkDir)
+	assert.False(t, config.LoginShell)
+}
@@ -0,0 +1,17 @@
+package app
+
+import (
+	"sync"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+var registerMiddlewareOnce sync.Once
+
+// registerMiddleware installs the built-in execution middleware (audit
+// logging, notification hooks and execution events) exactly once per process.
+func registerMiddleware() {
+	registerMiddlewareOnce.Do(func() {
+		sshclient.Use(auditMiddleware, hooksMiddleware, sshclient.EventsMiddleware)
+	})
+}
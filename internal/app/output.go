@@ -0,0 +1,212 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+// OutputDir is the directory below ~/.sshmcp holding full outputs of
+// truncated commands
+const OutputDir = "output"
+
+// defaultScriptTimeout bounds MCP scripts when no timeout is configured, so
+// a hanging script cannot block a tool call forever
+const defaultScriptTimeout = 30 * time.Minute
+
+// applyOutputLimit sets the output cap for an MCP command from the
+// max_output argument or the max_output setting, and the spill file when
+// spill_output is set
+func applyOutputLimit(config *sshclient.Config, settings *Settings, args map[string]interface{}) {
+	limit := ""
+	if settings != nil {
+		limit = settings.MaxOutput
+	}
+	if value := stringArg(args, "max_output"); value != "" {
+		limit = value
+	}
+	if limit != "" {
+		config.MaxOutputBytes = parseSize(limit)
+	}
+
+	if boolArg(args, "spill_output") {
+		config.OutputSpillPath = outputSpillPath(config)
+	}
+}
+
+// applyScriptTimeout sets how long an MCP script may run before it is
+// killed: the timeout argument, else settings.script_timeout, else
+// defaultScriptTimeout. A bare number is a count of seconds and "0"
+// disables the timeout.
+func applyScriptTimeout(config *sshclient.Config, settings *Settings, args map[string]interface{}) {
+	configured := ""
+	if settings != nil {
+		configured = settings.ScriptTimeout
+	}
+	value := stringArg(args, "timeout")
+	if _, err := strconv.Atoi(value); err == nil && value != "0" {
+		value += "s"
+	}
+	if value == "" && configured == "" {
+		config.ScriptTimeout = defaultScriptTimeout
+		return
+	}
+	config.ScriptTimeout = firstDuration("script timeout", value, configured)
+}
+
+// outputSpillPath returns a fresh file below ~/.sshmcp/output for the full
+// output of a command
+func outputSpillPath(config *sshclient.Config) string {
+	settingsDir, err := GetSettingsDir()
+	if err != nil {
+		return ""
+	}
+
+	name := config.Alias
+	if name == "" {
+		name = config.Host
+	}
+	fileName := fmt.Sprintf("%s-%s.log", sshclient.CollectDirName(&sshclient.Config{Host: name}), time.Now().Format("20060102-150405.000"))
+	return filepath.Join(settingsDir, OutputDir, fileName)
+}
+
+// Encodings for command output returned by MCP tools
+const (
+	OutputEncodingText   = "text"
+	OutputEncodingBase64 = "base64"
+)
+
+// EncodedOutput is returned instead of plain text when output is base64
+// encoded, so binary payloads survive the JSON-RPC round trip
+type EncodedOutput struct {
+	Encoding string `json:"encoding"`
+	Size     int    `json:"size"`
+	SHA256   string `json:"sha256"`
+	Content  string `json:"content"`
+	Stderr   string `json:"stderr,omitempty"`
+}
+
+// FileRangeOutput is the sftp_download result of a ranged read. Content is
+// text, or base64 with Encoding set when the slice is not valid UTF-8.
+type FileRangeOutput struct {
+	sshclient.FileRange
+	Length   int    `json:"length"`
+	Encoding string `json:"encoding,omitempty"`
+	Content  string `json:"content"`
+}
+
+// applyOutputEncoding reads the encoding and binary arguments. Binary output
+// is captured byte for byte (no PTY, stderr kept apart) and always base64
+// encoded; encoding "base64" alone encodes the usual text capture.
+func applyOutputEncoding(config *sshclient.Config, args map[string]interface{}) (string, error) {
+	encoding, _ := args["encoding"].(string) //nolint:errcheck // optional
+
+	switch encoding {
+	case "", OutputEncodingText:
+		encoding = OutputEncodingText
+	case OutputEncodingBase64:
+	default:
+		return "", fmt.Errorf("unknown encoding '%s' (use %s or %s)", encoding, OutputEncodingText, OutputEncodingBase64)
+	}
+
+	if boolArg(args, "binary") {
+		config.BinaryOutput = true
+		encoding = OutputEncodingBase64
+	}
+	return encoding, nil
+}
+
+const (
+	// defaultInlineBytes is the largest file sftp_download returns inline
+	// when no local_path is given
+	defaultInlineBytes = 256 << 10
+	// maxInlineBytes caps max_inline_bytes and inline upload content
+	maxInlineBytes = 1 << 20
+)
+
+// inlineFileContent returns file content as text when it is valid UTF-8
+// without NUL bytes, and as an EncodedOutput JSON document otherwise
+func inlineFileContent(data []byte) (string, error) {
+	if utf8.Valid(data) && !bytes.Contains(data, []byte{0}) {
+		return string(data), nil
+	}
+	return encodeOutput(data, "")
+}
+
+// inlineUploadContent reads the content or content_base64 argument of an
+// upload. It reports false when neither is given; both at once, or either
+// together with local_path, is an error.
+func inlineUploadContent(args map[string]interface{}) ([]byte, bool, error) {
+	text, hasText := args["content"].(string)
+	encoded, hasEncoded := args["content_base64"].(string)
+	if !hasText && !hasEncoded {
+		return nil, false, nil
+	}
+	if hasText && hasEncoded {
+		return nil, false, fmt.Errorf("content and content_base64 are mutually exclusive")
+	}
+	if stringArg(args, "local_path") != "" {
+		return nil, false, fmt.Errorf("local_path cannot be combined with inline content")
+	}
+
+	content := []byte(text)
+	if hasEncoded {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid content_base64: %w", err)
+		}
+		content = decoded
+	}
+	if len(content) > maxInlineBytes {
+		return nil, false, fmt.Errorf("inline content is %d bytes, more than the limit of %d bytes (upload a local_path instead)", len(content), maxInlineBytes)
+	}
+	return content, true, nil
+}
+
+// encodeFileRange renders a ranged read as a FileRangeOutput JSON document
+func encodeFileRange(fileRange sshclient.FileRange) (string, error) {
+	output := FileRangeOutput{FileRange: fileRange, Length: len(fileRange.Data), Content: string(fileRange.Data)}
+	if !utf8.Valid(fileRange.Data) || bytes.Contains(fileRange.Data, []byte{0}) {
+		output.Encoding = OutputEncodingBase64
+		output.Content = base64.StdEncoding.EncodeToString(fileRange.Data)
+	}
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode file range: %w", err)
+	}
+	return string(data), nil
+}
+
+// encodeOutput renders output as an EncodedOutput JSON document
+func encodeOutput(output []byte, stderr string) (string, error) {
+	sum := sha256.Sum256(output)
+	data, err := json.MarshalIndent(EncodedOutput{
+		Encoding: OutputEncodingBase64,
+		Size:     len(output),
+		SHA256:   hex.EncodeToString(sum[:]),
+		Content:  base64.StdEncoding.EncodeToString(output),
+		Stderr:   stderr,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode output: %w", err)
+	}
+	return string(data), nil
+}
+
+// annotateSafety prefixes output with a note when the safety policy let a
+// flagged command run with severity warn
+func annotateSafety(output string, finding *sshclient.SafetyFinding) string {
+	if finding == nil || finding.Severity != sshclient.SeverityWarn {
+		return output
+	}
+	return fmt.Sprintf("⚠️  Safety warning (%s): %s. The command ran because the safety policy of this host only warns about it.\n\n%s", finding.Rule, finding.Reason, output)
+}
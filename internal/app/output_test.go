@@ -0,0 +1,175 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestApplyOutputLimit(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	config := &sshclient.Config{Host: "10.0.0.1"}
+	applyOutputLimit(config, &Settings{MaxOutput: "256K"}, map[string]interface{}{})
+	assert.Equal(t, int64(256<<10), config.MaxOutputBytes)
+	assert.Empty(t, config.OutputSpillPath)
+
+	config = &sshclient.Config{Host: "10.0.0.1", Alias: "web1"}
+	applyOutputLimit(config, &Settings{MaxOutput: "256K"}, map[string]interface{}{"max_output": "64K", "spill_output": "true"})
+	assert.Equal(t, int64(64<<10), config.MaxOutputBytes)
+	assert.True(t, strings.HasPrefix(config.OutputSpillPath, filepath.Join(home, ".sshmcp", OutputDir, "web1-")), config.OutputSpillPath)
+
+	config = &sshclient.Config{Host: "10.0.0.1"}
+	applyOutputLimit(config, nil, map[string]interface{}{})
+	assert.Zero(t, config.MaxOutputBytes)
+}
+
+func TestApplyScriptTimeout(t *testing.T) {
+	config := &sshclient.Config{}
+	applyScriptTimeout(config, nil, map[string]interface{}{})
+	assert.Equal(t, defaultScriptTimeout, config.ScriptTimeout)
+
+	applyScriptTimeout(config, &Settings{ScriptTimeout: "2h"}, map[string]interface{}{})
+	assert.Equal(t, 2*time.Hour, config.ScriptTimeout)
+
+	applyScriptTimeout(config, &Settings{ScriptTimeout: "2h"}, map[string]interface{}{"timeout": "45s"})
+	assert.Equal(t, 45*time.Second, config.ScriptTimeout)
+
+	applyScriptTimeout(config, &Settings{ScriptTimeout: "2h"}, map[string]interface{}{"timeout": "0"})
+	assert.Zero(t, config.ScriptTimeout)
+
+	applyScriptTimeout(config, &Settings{ScriptTimeout: "2h"}, map[string]interface{}{"timeout": "90"})
+	assert.Equal(t, 90*time.Second, config.ScriptTimeout)
+
+	applyScriptTimeout(config, &Settings{ScriptTimeout: "2h"}, map[string]interface{}{"timeout": "soon"})
+	assert.Equal(t, 2*time.Hour, config.ScriptTimeout)
+}
+
+func TestApplyOutputEncoding(t *testing.T) {
+	config := &sshclient.Config{}
+	encoding, err := applyOutputEncoding(config, map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, OutputEncodingText, encoding)
+	assert.False(t, config.BinaryOutput)
+
+	encoding, err = applyOutputEncoding(config, map[string]interface{}{"encoding": "base64"})
+	assert.NoError(t, err)
+	assert.Equal(t, OutputEncodingBase64, encoding)
+	assert.False(t, config.BinaryOutput)
+
+	encoding, err = applyOutputEncoding(config, map[string]interface{}{"binary": "true"})
+	assert.NoError(t, err)
+	assert.Equal(t, OutputEncodingBase64, encoding)
+	assert.True(t, config.BinaryOutput)
+
+	_, err = applyOutputEncoding(&sshclient.Config{}, map[string]interface{}{"encoding": "hex"})
+	assert.ErrorContains(t, err, "unknown encoding 'hex'")
+}
+
+func TestEncodeOutput(t *testing.T) {
+	payload := []byte{0x00, 0xff, 0xfe, 'a', '\n'}
+	result, err := encodeOutput(payload, "warning")
+	assert.NoError(t, err)
+
+	var decoded EncodedOutput
+	assert.NoError(t, json.Unmarshal([]byte(result), &decoded))
+	assert.Equal(t, OutputEncodingBase64, decoded.Encoding)
+	assert.Equal(t, len(payload), decoded.Size)
+	assert.Equal(t, "warning", decoded.Stderr)
+
+	content, err := base64.StdEncoding.DecodeString(decoded.Content)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, content)
+	sum := sha256.Sum256(payload)
+	assert.Equal(t, hex.EncodeToString(sum[:]), decoded.SHA256)
+}
+
+func TestEncodeFileRange(t *testing.T) {
+	result, err := encodeFileRange(sshclient.FileRange{Path: "/var/log/app.log", Offset: 90, Size: 100, EOF: true, Data: []byte("tail line\n")})
+	require.NoError(t, err)
+	var decoded FileRangeOutput
+	require.NoError(t, json.Unmarshal([]byte(result), &decoded))
+	assert.Equal(t, "/var/log/app.log", decoded.Path)
+	assert.EqualValues(t, 90, decoded.Offset)
+	assert.EqualValues(t, 100, decoded.Size)
+	assert.True(t, decoded.EOF)
+	assert.Equal(t, 10, decoded.Length)
+	assert.Empty(t, decoded.Encoding)
+	assert.Equal(t, "tail line\n", decoded.Content)
+	assert.NotContains(t, result, "Data")
+
+	result, err = encodeFileRange(sshclient.FileRange{Path: "/bin/ls", Size: 4, Data: []byte{0x7f, 'E', 0x00, 0xff}})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(result), &decoded))
+	assert.Equal(t, OutputEncodingBase64, decoded.Encoding)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{0x7f, 'E', 0x00, 0xff}), decoded.Content)
+}
+
+func TestInlineFileContent(t *testing.T) {
+	result, err := inlineFileContent([]byte("server {\n  listen 80;\n}\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "server {\n  listen 80;\n}\n", result)
+
+	for _, payload := range [][]byte{{0x1f, 0x8b, 0x08, 0xff}, []byte("a\x00b")} {
+		result, err = inlineFileContent(payload)
+		assert.NoError(t, err)
+
+		var decoded EncodedOutput
+		assert.NoError(t, json.Unmarshal([]byte(result), &decoded))
+		content, err := base64.StdEncoding.DecodeString(decoded.Content)
+		assert.NoError(t, err)
+		assert.Equal(t, payload, content)
+	}
+}
+
+func TestInlineUploadContent(t *testing.T) {
+	content, inline, err := inlineUploadContent(map[string]interface{}{"local_path": "/tmp/app.conf"})
+	assert.NoError(t, err)
+	assert.False(t, inline)
+	assert.Nil(t, content)
+
+	content, inline, err = inlineUploadContent(map[string]interface{}{"content": "PORT=8080\n"})
+	assert.NoError(t, err)
+	assert.True(t, inline)
+	assert.Equal(t, "PORT=8080\n", string(content))
+
+	content, inline, err = inlineUploadContent(map[string]interface{}{"content": ""})
+	assert.NoError(t, err)
+	assert.True(t, inline, "an empty file is still inline content")
+	assert.Empty(t, content)
+
+	content, _, err = inlineUploadContent(map[string]interface{}{"content_base64": base64.StdEncoding.EncodeToString([]byte{0x00, 0xff})})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0xff}, content)
+
+	_, _, err = inlineUploadContent(map[string]interface{}{"content": "a", "content_base64": "YQ=="})
+	assert.ErrorContains(t, err, "mutually exclusive")
+	_, _, err = inlineUploadContent(map[string]interface{}{"content": "a", "local_path": "/tmp/a"})
+	assert.ErrorContains(t, err, "cannot be combined")
+	_, _, err = inlineUploadContent(map[string]interface{}{"content_base64": "not base64!"})
+	assert.ErrorContains(t, err, "invalid content_base64")
+	_, _, err = inlineUploadContent(map[string]interface{}{"content": strings.Repeat("x", maxInlineBytes+1)})
+	assert.ErrorContains(t, err, "more than the limit")
+}
+
+func TestAnnotateSafety(t *testing.T) {
+	assert.Equal(t, "up 3 days\n", annotateSafety("up 3 days\n", nil))
+
+	info := &sshclient.SafetyFinding{Rule: "reboot", Reason: "System reboot operation", Severity: sshclient.SeverityInfo}
+	assert.Equal(t, "", annotateSafety("", info))
+
+	warn := &sshclient.SafetyFinding{Rule: "reboot", Reason: "System reboot operation", Severity: sshclient.SeverityWarn}
+	result := annotateSafety("Rebooting\n", warn)
+	assert.True(t, strings.HasPrefix(result, "⚠️  Safety warning (reboot): System reboot operation."))
+	assert.True(t, strings.HasSuffix(result, "\n\nRebooting\n"))
+}
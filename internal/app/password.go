@@ -3,7 +3,9 @@ package app
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/zalando/go-keyring"
 
@@ -24,8 +26,10 @@ func HandlePasswordManagement(config *sshclient.Config) error {
 		return listPasswords()
 	case "check", "exists":
 		return checkPassword(sshclient.KeyringServiceName, config.PasswordKey)
+	case "lock":
+		return lockSecrets()
 	default:
-		return fmt.Errorf("unknown password action: %s (use: set, get, delete, list, check)", config.PasswordAction)
+		return fmt.Errorf("unknown password action: %s (use: set, get, delete, list, check, lock)", config.PasswordAction)
 	}
 }
 
@@ -45,6 +49,7 @@ func setPassword(serviceName, key, value string) error {
 	if err := keyring.Set(serviceName, key, value); err != nil {
 		return fmt.Errorf("failed to set password: %w", err)
 	}
+	sshclient.ForgetSudoPassword(key)
 
 	logger.GetLogger().Success("Password saved to system keyring")
 	logger.GetLogger().Info("  Service: %s", serviceName)
@@ -100,6 +105,7 @@ func deletePassword(serviceName, key string) error {
 	if err := keyring.Delete(serviceName, key); err != nil {
 		return fmt.Errorf("failed to delete password: %w", err)
 	}
+	sshclient.ForgetSudoPassword(key)
 
 	logger.GetLogger().Success("Password deleted from system keyring")
 	logger.GetLogger().Info("  Service: %s", serviceName)
@@ -150,7 +156,7 @@ func listPasswords() error {
 		_, err := keyring.Get(sshclient.KeyringServiceName, key)
 		switch err {
 		case nil:
-			fmt.Printf("  ✓ %s (exists)\n", key)
+			fmt.Print(logger.Plain(fmt.Sprintf("  ✓ %s (exists)\n", key)))
 			found = true
 		case keyring.ErrNotFound:
 			fmt.Printf("    %s (not set)\n", key)
@@ -200,3 +206,53 @@ func isMacOS() bool {
 	}
 	return false
 }
+
+// secretLockPath returns the file touched by `sshx --lock`
+func secretLockPath() (string, error) {
+	settingsDir, err := GetSettingsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(settingsDir, SecretLockFile), nil
+}
+
+// configureSecretCache applies the sudo password cache TTL from settings and
+// wires up the lock file so `sshx --lock` purges caches in running servers.
+func configureSecretCache() {
+	if lockPath, err := secretLockPath(); err == nil {
+		sshclient.SetSecretLockFile(lockPath)
+	}
+
+	settings, err := LoadSettings()
+	if err != nil || settings.SudoCacheTTL == "" {
+		return
+	}
+
+	ttl, err := time.ParseDuration(settings.SudoCacheTTL)
+	if err != nil {
+		logger.GetLogger().Warning("invalid sudo_cache_ttl '%s': %v (using default %v)",
+			settings.SudoCacheTTL, err, sshclient.DefaultSudoPasswordTTL)
+		return
+	}
+	sshclient.SetSudoPasswordTTL(ttl)
+}
+
+// lockSecrets purges cached sudo passwords in this and every other running
+// sshx process by touching the lock file.
+func lockSecrets() error {
+	sshclient.PurgeSecrets()
+
+	lockPath, err := secretLockPath()
+	if err != nil {
+		return fmt.Errorf("failed to locate lock file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o700); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+	if err := os.WriteFile(lockPath, []byte(time.Now().UTC().Format(time.RFC3339Nano)+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	logger.GetLogger().Success("Cached sudo passwords purged; the next sudo command will read the keyring again")
+	return nil
+}
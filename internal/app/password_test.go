@@ -2,6 +2,7 @@ package app
 
 import (
 	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 )
@@ -230,3 +231,20 @@ func TestMockKeyring(t *testing.T) {
 		t.Error("Expected error after deletion")
 	}
 }
+
+func TestLockSecrets_WritesLockFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if err := lockSecrets(); err != nil {
+		t.Fatalf("lockSecrets() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(home, SettingsDir, SecretLockFile))
+	if err != nil {
+		t.Fatalf("lock file not created: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("lock file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
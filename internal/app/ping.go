@@ -0,0 +1,127 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// handleHostPing checks whether the SSH port of each target accepts TCP
+// connections, without authenticating
+func handleHostPing(config *sshclient.Config) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	spec := config.Hosts
+	if spec == "" {
+		spec = config.Host
+	}
+
+	names, results, err := pingHosts(settings, spec, sshclient.PingOptions{ICMP: config.PingICMP})
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(logger.Plain(formatPingResults(names, results)))
+
+	if unreachable := countUnreachable(results); unreachable > 0 {
+		return fmt.Errorf("%d host(s) unreachable", unreachable)
+	}
+	return nil
+}
+
+// pingHosts resolves a comma-separated list of host names, group tags or
+// literal addresses (host or host:port) and probes each of them
+func pingHosts(settings *Settings, spec string, opts sshclient.PingOptions) ([]string, []sshclient.PingResult, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil, fmt.Errorf("hosts are required (comma-separated host names, groups or addresses)")
+	}
+
+	var names []string
+	var configs []*sshclient.Config
+	for _, item := range splitList(spec) {
+		if hosts, err := ResolveHostGroup(settings, item); err == nil {
+			for _, host := range hosts {
+				names = append(names, host.Name)
+				configs = append(configs, &sshclient.Config{Host: host.Host, Port: host.Port, Alias: host.Name, Addresses: hostAddresses(&host, settings)})
+			}
+			continue
+		}
+
+		host, port := item, ""
+		if h, p, err := net.SplitHostPort(item); err == nil {
+			host, port = h, p
+		}
+		names = append(names, item)
+		configs = append(configs, &sshclient.Config{Host: host, Port: port})
+	}
+
+	results := sshclient.Ping(configs, opts)
+	recordPingHealth(configs, results)
+	return names, results, nil
+}
+
+// recordPingHealth stores the reachability of the configured hosts among
+// configs in the liveness cache; literal addresses are not cached
+func recordPingHealth(configs []*sshclient.Config, results []sshclient.PingResult) {
+	now := time.Now()
+	updates := make(map[string]HostHealth)
+	for i, config := range configs {
+		if config.Alias == "" {
+			continue
+		}
+		health := HostHealth{Reachable: results[i].Reachable(), Checked: now}
+		if results[i].TCPErr != nil {
+			health.Error = results[i].TCPErr.Error()
+		}
+		updates[config.Alias] = health
+	}
+	recordHealth(updates)
+}
+
+// formatPingResults renders reachability results as a report
+func formatPingResults(names []string, results []sshclient.PingResult) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Reachability (%d hosts):\n", len(results)))
+
+	for i, result := range results {
+		name := result.Address
+		if i < len(names) {
+			name = names[i]
+		}
+
+		if !result.Reachable() {
+			output.WriteString(fmt.Sprintf("  ❌ %s (%s) unreachable: %v", name, result.Address, result.TCPErr))
+		} else {
+			output.WriteString(fmt.Sprintf("  ✓ %s (%s) tcp %s", name, result.Address, result.TCPLatency.Round(time.Millisecond)))
+		}
+		if result.ICMPChecked {
+			if result.ICMPErr != nil {
+				output.WriteString(fmt.Sprintf(", icmp failed: %v", result.ICMPErr))
+			} else {
+				output.WriteString(fmt.Sprintf(", icmp %s", result.ICMPLatency.Round(100*time.Microsecond)))
+			}
+		}
+		output.WriteString("\n")
+	}
+
+	reachable := len(results) - countUnreachable(results)
+	output.WriteString(fmt.Sprintf("Summary: %d/%d hosts reachable\n", reachable, len(results)))
+	return output.String()
+}
+
+func countUnreachable(results []sshclient.PingResult) int {
+	unreachable := 0
+	for _, result := range results {
+		if !result.Reachable() {
+			unreachable++
+		}
+	}
+	return unreachable
+}
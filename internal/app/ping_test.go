@@ -0,0 +1,55 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestPingHosts_ResolvesGroupsAndAddresses(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "web1", Host: "127.0.0.1", Port: "1", Tags: []string{"web"}},
+	}}
+
+	names, results, err := pingHosts(settings, "web, 127.0.0.1:2", sshclient.PingOptions{Timeout: 200 * time.Millisecond})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web1", "127.0.0.1:2"}, names)
+	require.Len(t, results, 2)
+	assert.Equal(t, "127.0.0.1:1", results[0].Address)
+	assert.Equal(t, "127.0.0.1:2", results[1].Address)
+
+	_, _, err = pingHosts(settings, " ", sshclient.PingOptions{})
+	assert.ErrorContains(t, err, "hosts are required")
+}
+
+func TestFormatPingResults(t *testing.T) {
+	results := []sshclient.PingResult{
+		{Address: "10.0.0.1:22", TCPLatency: 12 * time.Millisecond, ICMPChecked: true, ICMPLatency: 9500 * time.Microsecond},
+		{Address: "10.0.0.2:22", TCPErr: errors.New("i/o timeout")},
+	}
+
+	report := formatPingResults([]string{"web1", "web2"}, results)
+
+	assert.Contains(t, report, "Reachability (2 hosts):")
+	assert.Contains(t, report, "✓ web1 (10.0.0.1:22) tcp 12ms, icmp 9.5ms")
+	assert.Contains(t, report, "❌ web2 (10.0.0.2:22) unreachable: i/o timeout")
+	assert.Contains(t, report, "Summary: 1/2 hosts reachable")
+}
+
+func TestParseArgs_Ping(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--ping=prod,10.0.0.9", "--icmp"})
+	assert.Equal(t, "host", config.Mode)
+	assert.Equal(t, "ping", config.HostAction)
+	assert.Equal(t, "prod,10.0.0.9", config.Hosts)
+	assert.True(t, config.PingICMP)
+
+	config = ParseArgs([]string{"sshx", "-h=web1", "--ping"})
+	assert.Equal(t, "ping", config.HostAction)
+	assert.Equal(t, "web1", config.Host)
+}
@@ -0,0 +1,286 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// HandlePoolManagement handles connection pool commands
+func HandlePoolManagement(config *sshclient.Config) error {
+	switch config.PoolAction {
+	case "warm":
+		return handlePoolWarm(config)
+	case "flush":
+		return handlePoolFlush(config)
+	case "stats":
+		return handlePoolStats(config)
+	case "control-exit":
+		return stopControlMaster()
+	default:
+		return fmt.Errorf("unknown pool action: %s", config.PoolAction)
+	}
+}
+
+// configurePool applies connection lifetime and sudo reset settings to the
+// global connection pool and client
+func configurePool() {
+	settings, err := LoadSettings()
+	if err != nil {
+		return
+	}
+	applyPoolSettings(sshclient.GetConnectionPool(), settings)
+}
+
+// pinHosts pins the connections of hosts marked "pinned" so the pool keeps
+// them open and reconnects them after failures. Only the long-running MCP
+// server pins hosts; a one-shot CLI command would just dial them needlessly.
+func pinHosts(pool *sshclient.ConnectionPool, settings *Settings) []string {
+	base := &sshclient.Config{UseKeyAuth: true}
+	applyHostKeySettings(base, settings)
+	applyMCPTrust(base, settings)
+
+	var configs []*sshclient.Config
+	for i := range settings.Hosts {
+		if settings.Hosts[i].Pinned {
+			configs = append(configs, newHostSSHConfig(&settings.Hosts[i], settings, base))
+			if expires := settings.Hosts[i].Expires; !expires.IsZero() {
+				// Pruning once it expires drops the host and its pin
+				time.AfterFunc(time.Until(expires), dropExpiredHosts)
+			}
+		}
+	}
+	if len(configs) == 0 {
+		return nil
+	}
+	return pool.Pin(configs...)
+}
+
+// applyPoolSettings configures pool timeouts and sudo reset from settings
+func applyPoolSettings(pool *sshclient.ConnectionPool, settings *Settings) {
+	sshclient.SetSudoReset(settings.SudoReset)
+
+	if settings.PoolIdleTimeout != "" {
+		if idle, err := time.ParseDuration(settings.PoolIdleTimeout); err != nil || idle <= 0 {
+			logger.GetLogger().Warning("invalid pool_idle_timeout '%s', keeping the default", settings.PoolIdleTimeout)
+		} else {
+			pool.SetMaxIdle(idle)
+		}
+	}
+	if settings.PoolMaxLifetime != "" {
+		if lifetime, err := time.ParseDuration(settings.PoolMaxLifetime); err != nil || lifetime < 0 {
+			logger.GetLogger().Warning("invalid pool_max_lifetime '%s', keeping the default %v",
+				settings.PoolMaxLifetime, sshclient.DefaultPoolMaxLifetime)
+		} else {
+			pool.SetMaxLifetime(lifetime)
+		}
+	}
+}
+
+// handlePoolWarm pre-connects every host in the requested hosts/groups.
+// With a control persist the connections are made by the control master,
+// so later CLI calls reuse them; otherwise they are made by this process,
+// which exits right away, and the report only tells which hosts are
+// reachable.
+func handlePoolWarm(config *sshclient.Config) error {
+	if persist := controlPersist(config); persist > 0 {
+		handled, output, err := sendControlRequest(&controlRequest{Config: *config, Warm: config.Hosts}, persist)
+		if handled {
+			fmt.Print(logger.Plain(output))
+			return err
+		}
+	}
+
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	hosts, results, err := warmHosts(settings, config.Hosts, config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(logger.Plain(formatWarmResults(hosts, results)))
+
+	if failed := countWarmFailures(results); failed > 0 {
+		return fmt.Errorf("failed to warm %d host(s)", failed)
+	}
+	return nil
+}
+
+// warmHosts resolves a host/group spec and establishes pooled connections to each host
+func warmHosts(settings *Settings, spec string, baseConfig *sshclient.Config) ([]HostConfig, []sshclient.WarmResult, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil, fmt.Errorf("hosts are required (comma-separated host names or groups)")
+	}
+
+	hosts, err := resolveBatchHosts(settings, spec, baseConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	configs := make([]*sshclient.Config, len(hosts))
+	for i := range hosts {
+		configs[i] = newHostSSHConfig(&hosts[i], settings, baseConfig)
+	}
+
+	results := sshclient.GetConnectionPool().Warm(configs...)
+	recordReachable(hosts, func(i int) bool { return results[i].Err == nil })
+	return hosts, results, nil
+}
+
+// formatWarmResults renders warm-up results as a human readable report
+func formatWarmResults(hosts []HostConfig, results []sshclient.WarmResult) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Pool warm-up (%d hosts):\n", len(results)))
+
+	for i, result := range results {
+		name := result.Key
+		if i < len(hosts) {
+			name = hosts[i].Name
+		}
+
+		switch {
+		case result.Err != nil:
+			output.WriteString(fmt.Sprintf("  ❌ %s (%s) failed: %v\n", name, result.Key, result.Err))
+		case result.Reused:
+			output.WriteString(fmt.Sprintf("  ✓ %s (%s) already connected\n", name, result.Key))
+		default:
+			output.WriteString(fmt.Sprintf("  ✓ %s (%s) connected in %s\n", name, result.Key, result.Duration.Round(time.Millisecond)))
+		}
+	}
+
+	ready := len(results) - countWarmFailures(results)
+	output.WriteString(fmt.Sprintf("Summary: %d/%d hosts ready\n", ready, len(results)))
+	return output.String()
+}
+
+func countWarmFailures(results []sshclient.WarmResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// handlePoolFlush drops pooled connections to one host, or all of them.
+// Connections are pooled per process, so this acts on the pool of the
+// current sshx process.
+func handlePoolFlush(config *sshclient.Config) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	evicted := evictPool(sshclient.GetConnectionPool(), settings, config.Hosts)
+	fmt.Print(logger.Plain(formatEvicted(config.Hosts, evicted)))
+	return nil
+}
+
+// evictPool drops pooled connections to target: a configured host name,
+// an address, a pool key (user@host:port), or every host when empty
+func evictPool(pool *sshclient.ConnectionPool, settings *Settings, target string) []string {
+	target = strings.TrimSpace(target)
+	if target == "" || target == "all" {
+		return pool.Flush()
+	}
+	if settings != nil {
+		if host, err := GetHost(settings, target); err == nil {
+			return pool.Evict(host.Host)
+		}
+	}
+	return pool.Evict(target)
+}
+
+// formatEvicted reports which pooled connections were dropped
+func formatEvicted(target string, evicted []string) string {
+	if target == "" || target == "all" {
+		target = "all hosts"
+	}
+	if len(evicted) == 0 {
+		return fmt.Sprintf("No pooled connections for %s\n", target)
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("✓ Evicted %d pooled connection(s) for %s:\n", len(evicted), target))
+	for _, key := range evicted {
+		output.WriteString("  - " + key + "\n")
+	}
+	return output.String()
+}
+
+// handlePoolStats prints pool totals and every pooled connection, as JSON
+// with --json. Like --pool-flush it reports the pool of this sshx process.
+func handlePoolStats(config *sshclient.Config) error {
+	stats := sshclient.GetConnectionPool().Stats()
+	if !config.JSONOutput {
+		fmt.Print(logger.Plain(formatPoolStats(stats)))
+		return nil
+	}
+
+	output, err := formatPoolStatsJSON(stats)
+	if err != nil {
+		return err
+	}
+	fmt.Println(output)
+	return nil
+}
+
+// formatPoolStats renders pool statistics and per-connection detail as text
+func formatPoolStats(stats map[string]interface{}) string {
+	var output strings.Builder
+	output.WriteString("SSH Connection Pool Statistics:\n")
+	output.WriteString("================================\n")
+	output.WriteString(fmt.Sprintf("Total Connections:      %v\n", stats["total_connections"]))
+	output.WriteString(fmt.Sprintf("Recently Used:          %v\n", stats["recently_used_connections"]))
+	output.WriteString(fmt.Sprintf("Idle Connections:       %v\n", stats["idle_connections"]))
+	output.WriteString(fmt.Sprintf("Max Idle Duration:      %v\n", stats["max_idle_duration"]))
+	output.WriteString(fmt.Sprintf("Max Lifetime:           %v\n", stats["max_lifetime"]))
+	output.WriteString(fmt.Sprintf("Health Check Interval:  %v\n", stats["health_check_interval"]))
+
+	connections, _ := stats["connections"].([]sshclient.PoolConnectionInfo) //nolint:errcheck // absent when empty
+	if len(connections) > 0 {
+		output.WriteString("\nConnections:\n")
+		for _, conn := range connections {
+			state := "idle"
+			if conn.InUse {
+				state = "in use"
+			}
+			output.WriteString(fmt.Sprintf("  %s  %s, age %s, idle %s, auth %s, retries %d\n",
+				conn.Key, state, conn.Age, conn.Idle, conn.AuthMethod, conn.RetryCount))
+		}
+	}
+
+	pinned, _ := stats["pinned_connections"].([]sshclient.PinnedConnectionInfo) //nolint:errcheck // absent when empty
+	if len(pinned) > 0 {
+		output.WriteString("\nPinned:\n")
+		for _, pin := range pinned {
+			switch {
+			case pin.Connected:
+				output.WriteString(fmt.Sprintf("  ✓ %s connected\n", pin.Key))
+			case pin.Failures > 0 && pin.NextAttempt != nil:
+				output.WriteString(fmt.Sprintf("  ❌ %s down after %d attempt(s), retry at %s: %s\n",
+					pin.Key, pin.Failures, pin.NextAttempt.Format(time.TimeOnly), pin.LastError))
+			default:
+				output.WriteString(fmt.Sprintf("  … %s connecting\n", pin.Key))
+			}
+		}
+	}
+	return output.String()
+}
+
+// formatPoolStatsJSON renders pool statistics and per-connection detail as JSON
+func formatPoolStatsJSON(stats map[string]interface{}) (string, error) {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode pool stats: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestFormatWarmResults(t *testing.T) {
+	hosts := []HostConfig{{Name: "web1"}, {Name: "web2"}, {Name: "web3"}}
+	results := []sshclient.WarmResult{
+		{Key: "root@10.0.0.1:22", Duration: 120 * time.Millisecond},
+		{Key: "root@10.0.0.2:22", Reused: true},
+		{Key: "root@10.0.0.3:22", Err: errors.New("connection refused")},
+	}
+
+	output := formatWarmResults(hosts, results)
+
+	assert.Contains(t, output, "Pool warm-up (3 hosts)")
+	assert.Contains(t, output, "web1 (root@10.0.0.1:22) connected in 120ms")
+	assert.Contains(t, output, "web2 (root@10.0.0.2:22) already connected")
+	assert.Contains(t, output, "web3 (root@10.0.0.3:22) failed: connection refused")
+	assert.Contains(t, output, "Summary: 2/3 hosts ready")
+	assert.Equal(t, 1, countWarmFailures(results))
+}
+
+func TestWarmHosts_RequiresSpec(t *testing.T) {
+	_, _, err := warmHosts(&Settings{}, "  ", nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "hosts are required")
+}
+
+func TestWarmHosts_UnknownGroup(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{{Name: "web1", Host: "10.0.0.1"}}}
+
+	_, _, err := warmHosts(settings, "prod", nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no host or group named 'prod'")
+}
+
+func TestApplyPoolSettings(t *testing.T) {
+	t.Cleanup(func() { sshclient.SetSudoReset(false) })
+	pool := sshclient.NewConnectionPool()
+
+	applyPoolSettings(pool, &Settings{PoolIdleTimeout: "2m", PoolMaxLifetime: "0", SudoReset: true})
+
+	stats := pool.Stats()
+	assert.Equal(t, "2m0s", stats["max_idle_duration"])
+	assert.Equal(t, "0s", stats["max_lifetime"])
+
+	applyPoolSettings(pool, &Settings{PoolIdleTimeout: "soon", PoolMaxLifetime: "-1h"})
+
+	stats = pool.Stats()
+	assert.Equal(t, "2m0s", stats["max_idle_duration"])
+	assert.Equal(t, "0s", stats["max_lifetime"])
+}
+
+func TestFormatEvicted(t *testing.T) {
+	assert.Equal(t, "No pooled connections for web1\n", formatEvicted("web1", nil))
+
+	output := formatEvicted("", []string{"root@10.0.0.1:22", "deploy@10.0.0.1:2222"})
+	assert.Contains(t, output, "Evicted 2 pooled connection(s) for all hosts")
+	assert.Contains(t, output, "  - deploy@10.0.0.1:2222\n")
+}
+
+func TestFormatPoolStats(t *testing.T) {
+	next := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	stats := map[string]interface{}{
+		"total_connections": 1,
+		"connections": []sshclient.PoolConnectionInfo{
+			{Key: "root@10.0.0.1:22", Host: "10.0.0.1", InUse: true, Age: "2m0s", Idle: "5s", AuthMethod: sshclient.AuthMethodKey},
+		},
+		"pinned_connections": []sshclient.PinnedConnectionInfo{
+			{Key: "root@10.0.0.1:22", Host: "10.0.0.1", Connected: true},
+			{Key: "root@10.0.0.2:22", Host: "10.0.0.2", Failures: 2, LastError: "connection refused", NextAttempt: &next},
+		},
+	}
+	output := formatPoolStats(stats)
+	assert.Contains(t, output, "Total Connections:      1\n")
+	assert.Contains(t, output, "  root@10.0.0.1:22  in use, age 2m0s, idle 5s, auth key, retries 0\n")
+	assert.Contains(t, output, "  ✓ root@10.0.0.1:22 connected\n")
+	assert.Contains(t, output, "  ❌ root@10.0.0.2:22 down after 2 attempt(s), retry at 15:04:05: connection refused\n")
+
+	data, err := formatPoolStatsJSON(stats)
+	require.NoError(t, err)
+	assert.Contains(t, data, `"key": "root@10.0.0.1:22"`)
+	assert.Contains(t, data, `"auth_method": "key"`)
+	assert.Contains(t, data, `"in_use": true`)
+}
+
+func TestPinHosts(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "web1", Host: "127.0.0.1", Port: "1", User: "deploy", Pinned: true, DialTimeout: "100ms", MaxRetries: 1},
+		{Name: "web2", Host: "127.0.0.2", Port: "1"},
+	}}
+	pool := sshclient.NewConnectionPool()
+	defer pool.Close()
+
+	assert.Equal(t, []string{"deploy@127.0.0.1:1"}, pinHosts(pool, settings))
+	assert.Nil(t, pinHosts(pool, &Settings{}))
+}
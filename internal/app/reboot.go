@@ -0,0 +1,85 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// handleReboot reboots the host with --reboot: it runs the pre-checks,
+// shows the boot state, has the user confirm the host name (or takes it
+// from --confirm), then reboots and waits for the host to return. A failed
+// --verify command is returned as an error, so scripts can check the exit
+// status.
+func handleReboot(client *sshclient.SSHClient, config *sshclient.Config) error {
+	lg := logger.GetLogger()
+	opts := config.Reboot
+	opts.Timeout = config.WaitTimeout
+	opts.Interval = config.WaitInterval
+	req := sshclient.RequestFromConfig(config)
+
+	checkOpts := opts
+	checkOpts.DryRun = true
+	checked, err := client.Reboot(checkOpts, req)
+	if err != nil {
+		return err
+	}
+	lg.Info("%s has been up for %s, boot ID %s", config.Host, time.Duration(checked.Before.Uptime)*time.Second, checked.Before.BootID)
+	for _, session := range checked.Before.Sessions {
+		lg.Warning("Logged in: %s", session)
+	}
+	if opts.DryRun {
+		lg.Success("Pre-checks passed; run without --dry-run to reboot %s", config.Host)
+		return nil
+	}
+
+	name := config.HostFields["host"]
+	if err = confirmReboot(os.Stdin, os.Stderr, isTerminal(os.Stdin), name, config.RebootConfirm); err != nil {
+		return err
+	}
+	opts.Progress = func(message string) { lg.Info("%s", message) }
+	result, err := client.Reboot(opts, req)
+	if err != nil {
+		return err
+	}
+	lg.Success("%s rebooted, new boot ID %s", config.Host, result.After.BootID)
+	if result.Verify == nil {
+		return nil
+	}
+	fmt.Print(result.Verify.Output)
+	if !result.Verify.Passed {
+		return fmt.Errorf("%s is back but the verification failed", config.Host)
+	}
+	lg.Success("Verification passed: %s", result.Verify.Command)
+	return nil
+}
+
+// confirmReboot checks that the user named the host to reboot: confirmed
+// is the name given with --confirm, otherwise it is asked for on a
+// terminal. Without a terminal there is nobody to confirm.
+func confirmReboot(in io.Reader, out io.Writer, interactive bool, name, confirmed string) error {
+	if confirmed != "" {
+		if confirmed != name {
+			return fmt.Errorf("--confirm=%s does not match the host %s", confirmed, name)
+		}
+		return nil
+	}
+	if !interactive {
+		return fmt.Errorf("--reboot has to be confirmed at a terminal or with --confirm=%s", name)
+	}
+	fmt.Fprint(out, logger.Plain(fmt.Sprintf("⚠️  Type the host name '%s' to reboot it: ", name)))
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return fmt.Errorf("no confirmation given")
+	}
+	if strings.TrimSpace(answer) != name {
+		return fmt.Errorf("host name did not match")
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirmReboot(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, confirmReboot(strings.NewReader(""), &out, false, "web1", "web1"))
+	assert.ErrorContains(t, confirmReboot(strings.NewReader(""), &out, true, "web1", "web2"), "--confirm=web2 does not match the host web1")
+	assert.ErrorContains(t, confirmReboot(strings.NewReader("web1\n"), &out, false, "web1", ""), "has to be confirmed at a terminal or with --confirm=web1")
+	assert.Empty(t, out.String(), "nothing is asked without a terminal")
+
+	assert.NoError(t, confirmReboot(strings.NewReader("web1\n"), &out, true, "web1", ""))
+	assert.Contains(t, out.String(), "Type the host name 'web1' to reboot it")
+	assert.ErrorContains(t, confirmReboot(strings.NewReader("web2\n"), &out, true, "web1", ""), "host name did not match")
+	assert.ErrorContains(t, confirmReboot(strings.NewReader(""), &out, true, "web1", ""), "no confirmation given")
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/errutil"
+)
+
+const (
+	// RecordingsDir is the default directory for automatic session recordings
+	RecordingsDir = "recordings"
+	// playbackMaxIdle caps pauses between events during playback
+	playbackMaxIdle = 2 * time.Second
+)
+
+// autoRecordPath returns the recording file for a host that has automatic
+// recording enabled, or an empty string when recording is not required.
+func autoRecordPath(settings *Settings, host *HostConfig) string {
+	if host == nil || !host.Record {
+		return ""
+	}
+
+	dir := ""
+	if settings != nil {
+		dir = expandHome(settings.RecordDir)
+	}
+	if dir == "" {
+		settingsDir, err := GetSettingsDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(settingsDir, RecordingsDir)
+	}
+
+	name := host.Name
+	if name == "" {
+		name = host.Host
+	}
+	fileName := fmt.Sprintf("%s-%s.cast", name, time.Now().Format("20060102-150405.000"))
+	return filepath.Join(dir, fileName)
+}
+
+// playRecording replays an asciinema recording to stdout
+func playRecording(path string) (err error) {
+	if path == "" {
+		return fmt.Errorf("recording path is required (use --play=<file.cast>)")
+	}
+
+	file, err := os.Open(expandHome(path)) // #nosec G304 -- user-provided recording path
+	if err != nil {
+		return fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer errutil.HandleCloseError(&err, file)
+
+	return sshclient.PlayRecording(file, os.Stdout, 1, playbackMaxIdle)
+}
+
+// expandHome expands a leading ~/ to the user's home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
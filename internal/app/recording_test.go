@@ -0,0 +1,31 @@
+package app
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoRecordPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	assert.Empty(t, autoRecordPath(&Settings{}, &HostConfig{Name: "web1"}))
+	assert.Empty(t, autoRecordPath(&Settings{}, nil))
+
+	path := autoRecordPath(&Settings{}, &HostConfig{Name: "web1", Record: true})
+	assert.Equal(t, filepath.Join(home, SettingsDir, RecordingsDir), filepath.Dir(path))
+	assert.True(t, strings.HasPrefix(filepath.Base(path), "web1-"))
+	assert.True(t, strings.HasSuffix(path, ".cast"))
+
+	custom := autoRecordPath(&Settings{RecordDir: "~/casts"}, &HostConfig{Host: "10.0.0.1", Record: true})
+	assert.Equal(t, filepath.Join(home, "casts"), filepath.Dir(custom))
+	assert.True(t, strings.HasPrefix(filepath.Base(custom), "10.0.0.1-"))
+}
+
+func TestPlayRecording_MissingFile(t *testing.T) {
+	assert.ErrorContains(t, playRecording(""), "recording path is required")
+	assert.ErrorContains(t, playRecording(filepath.Join(t.TempDir(), "missing.cast")), "failed to open recording")
+}
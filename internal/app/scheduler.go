@@ -0,0 +1,291 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// JobConfig is a command the MCP server runs on a schedule
+type JobConfig struct {
+	Name     string `json:"name"`               // Job name (unique identifier)
+	Schedule string `json:"schedule"`           // Cron expression (e.g. "*/15 * * * *" or "@daily")
+	Hosts    string `json:"hosts"`              // Comma-separated host names or groups
+	Command  string `json:"command"`            // Command run on each host
+	Disabled bool   `json:"disabled,omitempty"` // Keep the job without running it on schedule
+}
+
+// validateJob checks the schedule, command and hosts of a job before it is
+// saved or scheduled
+func validateJob(settings *Settings, job *JobConfig) error {
+	if job.Name == "" {
+		return fmt.Errorf("job name is required")
+	}
+	if _, err := parseCron(job.Schedule); err != nil {
+		return err
+	}
+	if strings.TrimSpace(job.Command) == "" {
+		return fmt.Errorf("job '%s' has no command", job.Name)
+	}
+	hosts, err := ResolveHostGroup(settings, job.Hosts)
+	if err != nil {
+		return fmt.Errorf("job '%s': %w", job.Name, err)
+	}
+	// Jobs run unattended, so commands the safety policy of any of their
+	// hosts blocks are refused up front rather than failing on every run.
+	// Maintenance windows are left to the runs: a job may be scheduled in one.
+	for i := range hosts {
+		policy := hostSafetyConfig(&hosts[i], settings).toSafetyPolicy()
+		if policy != nil {
+			policy.Windows = nil
+		}
+		if _, err := sshclient.CheckCommand(job.Command, policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findJob returns the job with the given name, or nil
+func findJob(settings *Settings, name string) *JobConfig {
+	if settings == nil || name == "" {
+		return nil
+	}
+	for i := range settings.Jobs {
+		if settings.Jobs[i].Name == name {
+			return &settings.Jobs[i]
+		}
+	}
+	return nil
+}
+
+// JobRun is the outcome of one run of a job
+type JobRun struct {
+	Started    time.Time `json:"started"`
+	DurationMs int64     `json:"duration_ms"`
+	Hosts      int       `json:"hosts"`
+	Failed     int       `json:"failed"`
+	Error      string    `json:"error,omitempty"`
+	Report     string    `json:"-"` // Output and per-host results
+}
+
+// JobStatus describes a scheduled job for job_list
+type JobStatus struct {
+	JobConfig
+	NextRun *time.Time `json:"next_run,omitempty"`
+	Running bool       `json:"running,omitempty"`
+	LastRun *JobRun    `json:"last_run,omitempty"`
+}
+
+// jobRunner runs a job once on all of its hosts
+type jobRunner func(job JobConfig) *JobRun
+
+// scheduledJob is a job with its parsed schedule and state
+type scheduledJob struct {
+	config   JobConfig
+	schedule *cronSchedule
+	running  bool
+	lastRun  *JobRun
+}
+
+// Scheduler runs the jobs from settings while the MCP server is up. Each run
+// goes through the normal command path, so the safety check, sandbox, hooks
+// and audit log apply to it; a job is skipped while its previous run is
+// still going.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]*scheduledJob
+	run     jobRunner
+	now     func() time.Time
+	stop    chan struct{}
+	stopped sync.Once
+	wg      sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler for the valid jobs; invalid jobs are
+// logged and left out
+func NewScheduler(settings *Settings) *Scheduler {
+	s := &Scheduler{
+		jobs: make(map[string]*scheduledJob),
+		run:  runJob,
+		now:  time.Now,
+		stop: make(chan struct{}),
+	}
+	if settings == nil {
+		return s
+	}
+	for _, job := range settings.Jobs {
+		if err := s.Add(settings, job); err != nil {
+			logger.GetLogger().Warning("Skipping job: %v", err)
+		}
+	}
+	return s
+}
+
+// Add validates and schedules a job, replacing one with the same name
+func (s *Scheduler) Add(settings *Settings, job JobConfig) error {
+	if err := validateJob(settings, &job); err != nil {
+		return err
+	}
+	schedule, err := parseCron(job.Schedule)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := &scheduledJob{config: job, schedule: schedule}
+	if previous, ok := s.jobs[job.Name]; ok {
+		entry.running, entry.lastRun = previous.running, previous.lastRun
+	}
+	s.jobs[job.Name] = entry
+	return nil
+}
+
+// Start runs due jobs at the start of every minute until Stop is called
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			now := s.now()
+			timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+			select {
+			case <-s.stop:
+				timer.Stop()
+				return
+			case fired := <-timer.C:
+				s.tick(fired)
+			}
+		}
+	}()
+}
+
+// Stop stops scheduling and waits for running jobs to finish
+func (s *Scheduler) Stop() {
+	s.stopped.Do(func() { close(s.stop) })
+	s.wg.Wait()
+}
+
+// tick starts the enabled jobs due in the minute of t
+func (s *Scheduler) tick(t time.Time) {
+	s.mu.Lock()
+	var due []JobConfig
+	for name, job := range s.jobs {
+		if job.config.Disabled || !job.schedule.matches(t) {
+			continue
+		}
+		if job.running {
+			logger.GetLogger().Warning("Job '%s' is still running, skipping this run", name)
+			continue
+		}
+		job.running = true
+		due = append(due, job.config)
+	}
+	s.mu.Unlock()
+
+	for _, job := range due {
+		s.wg.Add(1)
+		go func(job JobConfig) {
+			defer s.wg.Done()
+			s.execute(job)
+		}(job)
+	}
+}
+
+// RunNow runs a job immediately and waits for the result. It fails when
+// the job is unknown or already running.
+func (s *Scheduler) RunNow(name string) (*JobRun, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("job '%s' not found", name)
+	}
+	if job.running {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("job '%s' is already running", name)
+	}
+	job.running = true
+	config := job.config
+	s.mu.Unlock()
+
+	return s.execute(config), nil
+}
+
+// execute runs a job that has been marked running and records the result
+func (s *Scheduler) execute(job JobConfig) *JobRun {
+	logger.GetLogger().Info("Running job '%s' on %s", job.Name, job.Hosts)
+	run := s.run(job)
+	if run.Error != "" {
+		logger.GetLogger().Warning("Job '%s' failed: %s", job.Name, run.Error)
+	}
+
+	s.mu.Lock()
+	if entry, ok := s.jobs[job.Name]; ok {
+		entry.running = false
+		entry.lastRun = run
+	}
+	s.mu.Unlock()
+	return run
+}
+
+// List returns the status of all jobs, sorted by name
+func (s *Scheduler) List() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := JobStatus{JobConfig: job.config, Running: job.running, LastRun: job.lastRun}
+		if !job.config.Disabled {
+			if next := job.schedule.next(now); !next.IsZero() {
+				status.NextRun = &next
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// runJob runs the job's command on its hosts with the settings current at
+// the time of the run
+func runJob(job JobConfig) *JobRun {
+	run := &JobRun{Started: time.Now()}
+	defer func() { run.DurationMs = time.Since(run.Started).Milliseconds() }()
+
+	settings, err := LoadSettings()
+	if err != nil {
+		run.Error = fmt.Sprintf("failed to load settings: %v", err)
+		return run
+	}
+
+	base := &sshclient.Config{UseKeyAuth: true, SafetyCheck: true, Source: "job:" + job.Name}
+	applyHostKeySettings(base, settings)
+	applyMCPTrust(base, settings)
+
+	opts := sshclient.CollectOptions{Command: job.Command, MaxBytes: sshclient.DefaultMaxOutputBytes}
+	if settings.MaxOutput != "" {
+		opts.MaxBytes = parseSize(settings.MaxOutput)
+	}
+	hosts, results, _, err := collectFromHosts(settings, job.Hosts, base, opts, sshclient.RolloutOptions{})
+	if err != nil {
+		run.Error = err.Error()
+		return run
+	}
+
+	run.Hosts = len(results)
+	run.Failed = countCollectFailures(results)
+	run.Report = formatCollectedOutput(hosts, results) + formatCollectResults(hosts, results)
+	if run.Failed > 0 {
+		run.Error = fmt.Sprintf("failed on %d host(s)", run.Failed)
+	}
+	return run
+}
@@ -0,0 +1,186 @@
+package app
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testJobSettings() *Settings {
+	return &Settings{
+		Hosts: []HostConfig{
+			{Name: "web1", Host: "10.0.0.1", Tags: []string{"web"}},
+			{Name: "web2", Host: "10.0.0.2", Tags: []string{"web"}},
+			{Name: "db1", Host: "10.0.0.3"},
+		},
+	}
+}
+
+func TestValidateJob(t *testing.T) {
+	settings := testJobSettings()
+	valid := JobConfig{Name: "disk", Schedule: "@hourly", Hosts: "web", Command: "df -h"}
+	require.NoError(t, validateJob(settings, &valid))
+
+	for _, job := range []JobConfig{
+		{Schedule: "@hourly", Hosts: "web", Command: "df -h"},
+		{Name: "disk", Schedule: "every hour", Hosts: "web", Command: "df -h"},
+		{Name: "disk", Schedule: "@hourly", Hosts: "web", Command: " "},
+		{Name: "disk", Schedule: "@hourly", Hosts: "", Command: "df -h"},
+		{Name: "disk", Schedule: "@hourly", Hosts: "cache", Command: "df -h"},
+		{Name: "wipe", Schedule: "@hourly", Hosts: "web", Command: "rm -rf /"},
+	} {
+		assert.Error(t, validateJob(settings, &job), job)
+	}
+
+	// A command blocked on any of the job's hosts is refused
+	reboot := JobConfig{Name: "reboot", Schedule: "@weekly", Hosts: "web", Command: "sudo reboot"}
+	assert.Error(t, validateJob(settings, &reboot))
+	settings.SafetyGroups = map[string]*SafetyConfig{"web": {Rules: map[string]string{"reboot": "warn"}}}
+	assert.NoError(t, validateJob(settings, &reboot))
+	reboot.Hosts = "web,db1"
+	assert.Error(t, validateJob(settings, &reboot))
+}
+
+// newTestScheduler returns a scheduler whose runs are recorded instead of
+// executed
+func newTestScheduler(t *testing.T, settings *Settings) (*Scheduler, func() []string) {
+	t.Helper()
+	scheduler := NewScheduler(settings)
+
+	var mu sync.Mutex
+	var ran []string
+	scheduler.run = func(job JobConfig) *JobRun {
+		mu.Lock()
+		ran = append(ran, job.Name)
+		mu.Unlock()
+		return &JobRun{Started: time.Now(), Hosts: 2, Report: "ok from " + job.Name}
+	}
+	return scheduler, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ran...)
+	}
+}
+
+func TestScheduler_Tick(t *testing.T) {
+	settings := testJobSettings()
+	settings.Jobs = []JobConfig{
+		{Name: "hourly", Schedule: "0 * * * *", Hosts: "web", Command: "uptime"},
+		{Name: "quarter", Schedule: "*/15 * * * *", Hosts: "db1", Command: "uptime"},
+		{Name: "off", Schedule: "* * * * *", Hosts: "web", Command: "uptime", Disabled: true},
+		{Name: "broken", Schedule: "61 * * * *", Hosts: "web", Command: "uptime"},
+	}
+	scheduler, ran := newTestScheduler(t, settings)
+
+	scheduler.tick(time.Date(2026, 3, 2, 4, 15, 0, 0, time.Local))
+	scheduler.Stop()
+	assert.Equal(t, []string{"quarter"}, ran())
+
+	statuses := scheduler.List()
+	require.Len(t, statuses, 3, "invalid jobs are not scheduled")
+	assert.Equal(t, "hourly", statuses[0].Name)
+	assert.Nil(t, statuses[0].LastRun)
+	assert.NotNil(t, statuses[0].NextRun)
+	assert.Equal(t, "off", statuses[1].Name)
+	assert.Nil(t, statuses[1].NextRun, "disabled jobs have no next run")
+	require.NotNil(t, statuses[2].LastRun)
+	assert.Equal(t, 2, statuses[2].LastRun.Hosts)
+}
+
+func TestScheduler_SkipsRunningJob(t *testing.T) {
+	settings := testJobSettings()
+	settings.Jobs = []JobConfig{{Name: "slow", Schedule: "* * * * *", Hosts: "web", Command: "sleep 100"}}
+	scheduler := NewScheduler(settings)
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	runs := 0
+	scheduler.run = func(job JobConfig) *JobRun {
+		mu.Lock()
+		runs++
+		mu.Unlock()
+		<-release
+		return &JobRun{}
+	}
+
+	scheduler.tick(time.Now())
+	require.Eventually(t, func() bool { return scheduler.List()[0].Running }, time.Second, time.Millisecond)
+	scheduler.tick(time.Now())
+	_, err := scheduler.RunNow("slow")
+	assert.ErrorContains(t, err, "already running")
+
+	close(release)
+	scheduler.Stop()
+	assert.Equal(t, 1, runs)
+	assert.False(t, scheduler.List()[0].Running)
+}
+
+func TestScheduler_RunNow(t *testing.T) {
+	settings := testJobSettings()
+	settings.Jobs = []JobConfig{{Name: "disk", Schedule: "@daily", Hosts: "web", Command: "df -h", Disabled: true}}
+	scheduler, ran := newTestScheduler(t, settings)
+
+	run, err := scheduler.RunNow("disk")
+	require.NoError(t, err)
+	assert.Equal(t, "ok from disk", run.Report)
+	assert.Equal(t, []string{"disk"}, ran(), "disabled jobs can still be run by hand")
+
+	_, err = scheduler.RunNow("missing")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestExecuteJobAdd(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	require.NoError(t, os.Setenv("HOME", tmpDir))
+	t.Cleanup(func() {
+		require.NoError(t, os.Setenv("HOME", originalHome))
+	})
+	require.NoError(t, SaveSettings(testJobSettings()))
+
+	server := NewMCPServer()
+	_, err := server.executeJobAdd(map[string]interface{}{"job": "disk", "schedule": "@daily", "hosts": "web", "command": "df -h"})
+	assert.ErrorContains(t, err, "not running")
+
+	scheduler, ran := newTestScheduler(t, testJobSettings())
+	server.scheduler = scheduler
+
+	result, err := server.executeJobAdd(map[string]interface{}{"job": "disk", "schedule": "@daily", "hosts": "web", "command": "df -h"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "Job 'disk' added")
+	assert.Contains(t, result, "next run at")
+
+	_, err = server.executeJobAdd(map[string]interface{}{"job": "bad", "schedule": "@daily", "hosts": "nowhere", "command": "df -h"})
+	assert.Error(t, err)
+
+	result, err = server.executeJobAdd(map[string]interface{}{"job": "disk", "schedule": "0 6 * * *", "hosts": "db1", "command": "df -h", "disabled": true})
+	require.NoError(t, err)
+	assert.Contains(t, result, "updated")
+
+	settings, err := LoadSettings()
+	require.NoError(t, err)
+	require.Len(t, settings.Jobs, 1)
+	assert.Equal(t, JobConfig{Name: "disk", Schedule: "0 6 * * *", Hosts: "db1", Command: "df -h", Disabled: true}, settings.Jobs[0])
+
+	output, err := server.executeJobRunNow(map[string]interface{}{"job": "disk"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok from disk", output)
+	assert.Equal(t, []string{"disk"}, ran())
+
+	list, err := server.executeJobList(nil)
+	require.NoError(t, err)
+	assert.Contains(t, list, `"schedule": "0 6 * * *"`)
+	assert.Contains(t, list, `"last_run"`)
+}
+
+func TestMCPCallTargets_Job(t *testing.T) {
+	settings := testJobSettings()
+	settings.Jobs = []JobConfig{{Name: "disk", Schedule: "@daily", Hosts: "web", Command: "df -h"}}
+
+	assert.Equal(t, []string{"web1", "web2"}, mcpCallTargets(settings, map[string]interface{}{"job": "disk"}))
+	assert.Empty(t, mcpCallTargets(settings, map[string]interface{}{"job": "unknown"}))
+}
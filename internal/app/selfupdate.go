@@ -0,0 +1,313 @@
+package app
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// releaseAPIURL is the GitHub API endpoint describing the latest release
+var releaseAPIURL = "https://api.github.com/repos/talkincode/sshmcp/releases/latest"
+
+const (
+	// checksumsAsset is the release asset listing the sha256 of every archive
+	checksumsAsset = "checksums.txt"
+	// maxReleaseArchiveSize bounds downloaded and extracted release files
+	maxReleaseArchiveSize = 200 << 20
+	// selfUpdateTimeout bounds each HTTP request of an update
+	selfUpdateTimeout = 5 * time.Minute
+)
+
+// githubRelease is the subset of the GitHub release API used for updates
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+// githubAsset is a file attached to a release
+type githubAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// handleSelfUpdate replaces the running binary with the latest release.
+// --check only reports whether an update is available; --force reinstalls
+// even when the current version is newer or not a release build.
+func handleSelfUpdate(args []string) error {
+	checkOnly, force := false, false
+	for _, arg := range args[2:] {
+		switch arg {
+		case "--check":
+			checkOnly = true
+		case "--force", "-f":
+			force = true
+		default:
+			return fmt.Errorf("unknown self-update option '%s' (use --check or --force)", arg)
+		}
+	}
+
+	client := &http.Client{Timeout: selfUpdateTimeout}
+	release, err := fetchLatestRelease(client)
+	if err != nil {
+		return err
+	}
+
+	current := GetBuildInfo().Version
+	cmp, comparable := compareVersions(current, release.TagName)
+	switch {
+	case comparable && cmp >= 0 && !force:
+		fmt.Printf("sshx %s is up to date (latest release %s)\n", current, release.TagName)
+		return nil
+	case !comparable && !force && !checkOnly:
+		return fmt.Errorf("sshx %s is not a release build; use --force to replace it with %s", current, release.TagName)
+	case checkOnly:
+		fmt.Printf("sshx %s is available (current %s); run sshx self-update to install it\n", release.TagName, current)
+		return nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the running binary: %w", err)
+	}
+	if resolved, resolveErr := filepath.EvalSymlinks(executable); resolveErr == nil {
+		executable = resolved
+	}
+
+	if err := installRelease(client, release, executable, runtime.GOOS, runtime.GOARCH); err != nil {
+		return err
+	}
+	fmt.Print(logger.Plain(fmt.Sprintf("✓ Updated %s from %s to %s\n", executable, current, release.TagName)))
+	return nil
+}
+
+// fetchLatestRelease queries the GitHub API for the latest release
+func fetchLatestRelease(client *http.Client) (*githubRelease, error) {
+	req, err := http.NewRequest(http.MethodGet, releaseAPIURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create release request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the latest release: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }() //nolint:errcheck // read-only response
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to check the latest release: %s returned %s", releaseAPIURL, resp.Status)
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse the latest release: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("latest release has no tag")
+	}
+	return &release, nil
+}
+
+// installRelease downloads the archive for goos/goarch, verifies it against
+// checksums.txt and replaces target with the binary it contains
+func installRelease(client *http.Client, release *githubRelease, target, goos, goarch string) error {
+	archiveName := releaseArchiveName(goos, goarch)
+	var archiveURL, checksumsURL string
+	for _, asset := range release.Assets {
+		switch asset.Name {
+		case archiveName:
+			archiveURL = asset.URL
+		case checksumsAsset:
+			checksumsURL = asset.URL
+		}
+	}
+	if archiveURL == "" {
+		return fmt.Errorf("release %s has no build for %s/%s (%s)", release.TagName, goos, goarch, archiveName)
+	}
+	if checksumsURL == "" {
+		return fmt.Errorf("release %s has no %s, refusing to install an unverified binary", release.TagName, checksumsAsset)
+	}
+
+	checksums, err := downloadReleaseFile(client, checksumsURL, 1<<20)
+	if err != nil {
+		return err
+	}
+	expected, err := releaseChecksum(checksums, archiveName)
+	if err != nil {
+		return err
+	}
+
+	archive, err := downloadReleaseFile(client, archiveURL, maxReleaseArchiveSize)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(archive)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", archiveName, expected, actual)
+	}
+
+	binary, err := extractReleaseBinary(archive, archiveName, releaseBinaryName(goos, goarch))
+	if err != nil {
+		return err
+	}
+	return replaceExecutable(target, binary)
+}
+
+// releaseBinaryName is the name of the binary inside a release archive
+func releaseBinaryName(goos, goarch string) string {
+	name := fmt.Sprintf("sshx-%s-%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// releaseArchiveName is the release asset holding the binary for a platform
+func releaseArchiveName(goos, goarch string) string {
+	if goos == "windows" {
+		return fmt.Sprintf("sshx-%s-%s.zip", goos, goarch)
+	}
+	return fmt.Sprintf("sshx-%s-%s.tar.gz", goos, goarch)
+}
+
+// downloadReleaseFile fetches a release asset of at most limit bytes
+func downloadReleaseFile(client *http.Client, url string, limit int64) ([]byte, error) {
+	resp, err := client.Get(url) // #nosec G107 -- URL comes from the release API
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }() //nolint:errcheck // read-only response
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("download %s exceeds %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// releaseChecksum finds the sha256 of name in sha256sum output
+func releaseChecksum(checksums []byte, name string) (string, error) {
+	for _, line := range strings.Split(string(checksums), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s does not list %s", checksumsAsset, name)
+}
+
+// extractReleaseBinary returns the binary named binaryName from a .tar.gz
+// or .zip release archive
+func extractReleaseBinary(archive []byte, archiveName, binaryName string) ([]byte, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", archiveName, err)
+		}
+		for _, file := range reader.File {
+			if path.Base(file.Name) != binaryName {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to extract %s: %w", binaryName, err)
+			}
+			defer func() { _ = rc.Close() }() //nolint:errcheck // read-only entry
+			return readReleaseBinary(rc, binaryName)
+		}
+		return nil, fmt.Errorf("%s does not contain %s", archiveName, binaryName)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", archiveName, err)
+	}
+	defer func() { _ = gz.Close() }() //nolint:errcheck // read-only stream
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s does not contain %s", archiveName, binaryName)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", archiveName, err)
+		}
+		if header.Typeflag == tar.TypeReg && path.Base(header.Name) == binaryName {
+			return readReleaseBinary(reader, binaryName)
+		}
+	}
+}
+
+// readReleaseBinary reads an archive entry, bounded by the archive limit
+func readReleaseBinary(r io.Reader, binaryName string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxReleaseArchiveSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", binaryName, err)
+	}
+	if len(data) > maxReleaseArchiveSize {
+		return nil, fmt.Errorf("%s exceeds %d bytes", binaryName, maxReleaseArchiveSize)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s is empty", binaryName)
+	}
+	return data, nil
+}
+
+// replaceExecutable swaps target for binary. The new file is written next
+// to target first so the final rename is atomic; the running binary is
+// moved aside because Windows cannot overwrite an executable in use.
+func replaceExecutable(target string, binary []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".sshx-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %w (re-run with permission to modify it)", filepath.Dir(target), err)
+	}
+	tmpPath := tmp.Name()
+	_, writeErr := tmp.Write(binary)
+	closeErr := tmp.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil {
+		writeErr = os.Chmod(tmpPath, 0o755) // #nosec G302 -- executables must be executable
+	}
+	if writeErr != nil {
+		_ = os.Remove(tmpPath) //nolint:errcheck // cleanup on error path
+		return fmt.Errorf("failed to write new binary: %w", writeErr)
+	}
+
+	old := target + ".old"
+	_ = os.Remove(old) //nolint:errcheck // left over from a previous update
+	if err := os.Rename(target, old); err != nil {
+		_ = os.Remove(tmpPath) //nolint:errcheck // cleanup on error path
+		return fmt.Errorf("failed to move %s aside: %w", target, err)
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		_ = os.Rename(old, target) //nolint:errcheck // restore the previous binary
+		_ = os.Remove(tmpPath)     //nolint:errcheck // cleanup on error path
+		return fmt.Errorf("failed to install new binary at %s: %w", target, err)
+	}
+	// Windows keeps the running binary locked; the next update removes it
+	_ = os.Remove(old) //nolint:errcheck // best effort
+	return nil
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tarGzRelease(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// serveRelease serves a release API document, checksums.txt and one archive
+func serveRelease(t *testing.T, tag, archiveName string, archive []byte, checksum string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(githubRelease{ //nolint:errcheck // test server
+			TagName: tag,
+			Assets: []githubAsset{
+				{Name: archiveName, URL: server.URL + "/" + archiveName},
+				{Name: checksumsAsset, URL: server.URL + "/" + checksumsAsset},
+			},
+		})
+	})
+	mux.HandleFunc("/"+checksumsAsset, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s  %s\n", checksum, archiveName) //nolint:errcheck // test server
+	})
+	mux.HandleFunc("/"+archiveName, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive) //nolint:errcheck // test server
+	})
+
+	original := releaseAPIURL
+	releaseAPIURL = server.URL + "/latest"
+	t.Cleanup(func() { releaseAPIURL = original })
+	return server
+}
+
+func TestInstallRelease(t *testing.T) {
+	archiveName := releaseArchiveName("linux", "amd64")
+	archive := tarGzRelease(t, releaseBinaryName("linux", "amd64"), []byte("new binary"))
+	sum := sha256.Sum256(archive)
+	server := serveRelease(t, "v9.9.9", archiveName, archive, hex.EncodeToString(sum[:]))
+
+	release, err := fetchLatestRelease(server.Client())
+	require.NoError(t, err)
+	assert.Equal(t, "v9.9.9", release.TagName)
+
+	target := filepath.Join(t.TempDir(), "sshx")
+	require.NoError(t, os.WriteFile(target, []byte("old binary"), 0o755))
+	require.NoError(t, installRelease(server.Client(), release, target, "linux", "amd64"))
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "new binary", string(data))
+	_, err = os.Stat(target + ".old")
+	assert.True(t, os.IsNotExist(err), "previous binary was not removed")
+
+	err = installRelease(server.Client(), release, target, "freebsd", "amd64")
+	assert.ErrorContains(t, err, "has no build for freebsd/amd64")
+}
+
+func TestInstallRelease_ChecksumMismatch(t *testing.T) {
+	archiveName := releaseArchiveName("linux", "amd64")
+	archive := tarGzRelease(t, releaseBinaryName("linux", "amd64"), []byte("tampered"))
+	server := serveRelease(t, "v9.9.9", archiveName, archive, "deadbeef")
+
+	release, err := fetchLatestRelease(server.Client())
+	require.NoError(t, err)
+
+	target := filepath.Join(t.TempDir(), "sshx")
+	require.NoError(t, os.WriteFile(target, []byte("old binary"), 0o755))
+	err = installRelease(server.Client(), release, target, "linux", "amd64")
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "old binary", string(data))
+}
+
+func TestExtractReleaseBinary_Zip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("dist/sshx-windows-amd64.exe")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("MZ"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	binary, err := extractReleaseBinary(buf.Bytes(), "sshx-windows-amd64.zip", releaseBinaryName("windows", "amd64"))
+	require.NoError(t, err)
+	assert.Equal(t, "MZ", string(binary))
+
+	_, err = extractReleaseBinary(buf.Bytes(), "sshx-windows-amd64.zip", "sshx-windows-arm64.exe")
+	assert.ErrorContains(t, err, "does not contain")
+}
+
+func TestReleaseChecksum(t *testing.T) {
+	checksums := []byte("abc123  sshx-linux-amd64.tar.gz\nDEF456 *sshx-darwin-arm64.tar.gz\n")
+	sum, err := releaseChecksum(checksums, "sshx-darwin-arm64.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, "def456", sum)
+
+	_, err = releaseChecksum(checksums, "sshx-windows-amd64.zip")
+	assert.ErrorContains(t, err, "does not list")
+}
+
+func TestHandleSelfUpdate_UpToDate(t *testing.T) {
+	original := buildInfo
+	t.Cleanup(func() { buildInfo = original })
+	SetBuildInfo("v9.9.9", "", "")
+
+	serveRelease(t, "v9.9.9", "unused.tar.gz", nil, "")
+	assert.NoError(t, handleSelfUpdate([]string{"sshx", "self-update"}))
+	assert.ErrorContains(t, handleSelfUpdate([]string{"sshx", "self-update", "--bogus"}), "unknown self-update option")
+
+	SetBuildInfo("dev", "", "")
+	assert.ErrorContains(t, handleSelfUpdate([]string{"sshx", "self-update"}), "not a release build")
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+// ServerInfo describes what this MCP server offers, for clients and scripts
+// that adapt to it without parsing usage text
+type ServerInfo struct {
+	Name       string         `json:"name"`
+	Build      BuildInfo      `json:"build"`
+	Transports []string       `json:"transports"`
+	Tools      []string       `json:"tools"`
+	Features   ServerFeatures `json:"features"`
+	Hosts      ServerHosts    `json:"hosts"`
+	Limits     ServerLimits   `json:"limits"`
+}
+
+// ServerFeatures reports optional behavior and whether it is enabled
+type ServerFeatures struct {
+	SafetyCheck        bool   `json:"safety_check"`
+	AcceptUnknownHosts bool   `json:"accept_unknown_hosts"`
+	AuditLog           string `json:"audit_log,omitempty"`
+	Hooks              bool   `json:"hooks"`
+	SudoReset          bool   `json:"sudo_reset"`
+	RevokedKeys        string `json:"revoked_keys,omitempty"`
+	TrustTTL           string `json:"trust_ttl,omitempty"`
+	SecretsBackend     string `json:"secrets_backend"`
+}
+
+// ServerHosts summarizes the configured hosts
+type ServerHosts struct {
+	Count  int      `json:"count"`
+	Groups []string `json:"groups"`
+}
+
+// ServerLimits reports the effective limits applied to commands and
+// connections
+type ServerLimits struct {
+	MaxOutputBytes  int64  `json:"max_output_bytes"`
+	DialTimeout     string `json:"dial_timeout"`
+	Keepalive       string `json:"keepalive"`
+	MaxRetries      int    `json:"max_retries"`
+	PoolIdleTimeout string `json:"pool_idle_timeout"`
+	PoolMaxLifetime string `json:"pool_max_lifetime"`
+	SudoCacheTTL    string `json:"sudo_cache_ttl"`
+}
+
+// defaultPoolRetries mirrors the connection pool's built-in retry count
+const defaultPoolRetries = 3
+
+// buildServerInfo collects the server description from the settings and
+// the tools the server exposes
+func buildServerInfo(settings *Settings, tools []MCPTool) ServerInfo {
+	if settings == nil {
+		settings = &Settings{}
+	}
+
+	toolNames := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		toolNames = append(toolNames, tool.Name)
+	}
+
+	groups := []string{}
+	seen := make(map[string]bool)
+	for _, host := range settings.Hosts {
+		for _, tag := range host.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				groups = append(groups, tag)
+			}
+		}
+	}
+	sort.Strings(groups)
+
+	limits := ServerLimits{MaxOutputBytes: sshclient.DefaultMaxOutputBytes}
+	if settings.MaxOutput != "" {
+		limits.MaxOutputBytes = parseSize(settings.MaxOutput)
+	}
+	connection := &sshclient.Config{}
+	applyConnectionDefaults(connection, nil, settings)
+	if connection.DialTimeout <= 0 {
+		connection.DialTimeout = sshclient.DefaultTimeout
+	}
+	if connection.MaxRetries <= 0 {
+		connection.MaxRetries = defaultPoolRetries
+	}
+	limits.DialTimeout = connection.DialTimeout.String()
+	limits.Keepalive = connection.Keepalive.String()
+	limits.MaxRetries = connection.MaxRetries
+	poolStats := sshclient.GetConnectionPool().Stats()
+	limits.PoolIdleTimeout = fmt.Sprint(poolStats["max_idle_duration"])
+	limits.PoolMaxLifetime = fmt.Sprint(poolStats["max_lifetime"])
+	limits.SudoCacheTTL = sshclient.SudoPasswordTTL().String()
+
+	return ServerInfo{
+		Name:       "sshx-mcp-server",
+		Build:      GetBuildInfo(),
+		Transports: []string{"stdio"},
+		Tools:      toolNames,
+		Features: ServerFeatures{
+			SafetyCheck:        true,
+			AcceptUnknownHosts: settings.MCPAcceptUnknownHost,
+			AuditLog:           auditLogPath(settings),
+			Hooks:              settings.Hooks != nil,
+			SudoReset:          settings.SudoReset,
+			RevokedKeys:        settings.RevokedKeys,
+			TrustTTL:           settings.TrustTTL,
+			SecretsBackend:     secretsBackend(runtime.GOOS),
+		},
+		Hosts:  ServerHosts{Count: len(settings.Hosts), Groups: groups},
+		Limits: limits,
+	}
+}
+
+// secretsBackend names the keyring implementation used on an OS
+func secretsBackend(goos string) string {
+	switch goos {
+	case "darwin":
+		return "macos-keychain"
+	case "windows":
+		return "windows-credential-manager"
+	default:
+		return "secret-service"
+	}
+}
+
+// formatServerInfo renders the server description as indented JSON
+func formatServerInfo(info ServerInfo) (string, error) {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode server info: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildServerInfo(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	settings := &Settings{
+		Hosts: []HostConfig{
+			{Name: "web1", Host: "10.0.0.1", Tags: []string{"web", "prod"}},
+			{Name: "db1", Host: "10.0.0.2", Tags: []string{"prod"}},
+		},
+		MaxOutput:            "256K",
+		DialTimeout:          "10s",
+		MaxRetries:           5,
+		MCPAcceptUnknownHost: true,
+		SudoReset:            true,
+	}
+	info := buildServerInfo(settings, defineMCPTools())
+
+	assert.Equal(t, "sshx-mcp-server", info.Name)
+	assert.Equal(t, []string{"stdio"}, info.Transports)
+	assert.Contains(t, info.Tools, "server_info")
+	assert.Equal(t, 2, info.Hosts.Count)
+	assert.Equal(t, []string{"prod", "web"}, info.Hosts.Groups)
+
+	assert.True(t, info.Features.SafetyCheck)
+	assert.True(t, info.Features.AcceptUnknownHosts)
+	assert.True(t, info.Features.SudoReset)
+	assert.False(t, info.Features.Hooks)
+	assert.Equal(t, filepath.Join(home, SettingsDir, AuditLogFile), info.Features.AuditLog)
+
+	assert.Equal(t, int64(256<<10), info.Limits.MaxOutputBytes)
+	assert.Equal(t, "10s", info.Limits.DialTimeout)
+	assert.Equal(t, "0s", info.Limits.Keepalive)
+	assert.Equal(t, 5, info.Limits.MaxRetries)
+}
+
+func TestBuildServerInfo_Defaults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	info := buildServerInfo(&Settings{AuditLog: AuditLogDisabled}, nil)
+	assert.Equal(t, "30s", info.Limits.DialTimeout)
+	assert.Equal(t, defaultPoolRetries, info.Limits.MaxRetries)
+	assert.Empty(t, info.Features.AuditLog)
+	assert.Equal(t, []string{}, info.Hosts.Groups)
+
+	text, err := formatServerInfo(info)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(text), &decoded))
+	assert.Contains(t, decoded, "limits")
+	assert.Contains(t, decoded, "features")
+}
@@ -5,6 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
 )
 
 const (
@@ -12,23 +18,125 @@ const (
 	SettingsDir = ".sshmcp"
 	// SettingsFile is the name of the settings file
 	SettingsFile = "settings.json"
+	// SecretLockFile is touched by `sshx --lock` to purge cached secrets
+	SecretLockFile = "lock"
 )
 
 // HostConfig represents a configured host
 type HostConfig struct {
-	Name        string `json:"name"`                   // Host name (unique identifier)
-	Description string `json:"description,omitempty"`  // Description
-	Host        string `json:"host"`                   // IP or hostname
-	Port        string `json:"port,omitempty"`         // Port (default: 22)
-	User        string `json:"user,omitempty"`         // Username (default: master)
-	PasswordKey string `json:"password_key,omitempty"` // Password key name (optional)
-	Type        string `json:"type,omitempty"`         // System type (linux/windows/macos)
+	Name             string            `json:"name"`                         // Host name (unique identifier)
+	Description      string            `json:"description,omitempty"`        // Description
+	Host             string            `json:"host"`                         // IP or hostname
+	Addresses        []string          `json:"addresses,omitempty"`          // Addresses dialed in order before host (e.g. a WireGuard IP, "tailscale" or "tailscale:<machine>")
+	Port             string            `json:"port,omitempty"`               // Port (default: 22)
+	User             string            `json:"user,omitempty"`               // Username (default: master)
+	PasswordKey      string            `json:"password_key,omitempty"`       // Password key name (optional)
+	Type             string            `json:"type,omitempty"`               // System type (linux/windows/macos)
+	Device           string            `json:"device,omitempty"`             // Network device profile (cisco_ios, junos, routeros, generic or one of device_profiles): commands go through an interactive shell, no SFTP
+	Tags             []string          `json:"tags,omitempty"`               // Group tags (e.g. prod, web)
+	Record           bool              `json:"record,omitempty"`             // Automatically record command sessions
+	Vars             map[string]string `json:"vars,omitempty"`               // Variables for upload templates
+	Env              map[string]string `json:"env,omitempty"`                // Environment variables exported for every command and script (e.g. HTTP_PROXY, JAVA_HOME)
+	Meta             map[string]string `json:"meta,omitempty"`               // Inventory metadata searched by --host-find (e.g. rack, owner, environment, app)
+	Expires          time.Time         `json:"expires,omitzero"`             // When the host is pruned from settings and the pool (ephemeral hosts, set by --ttl)
+	Source           string            `json:"source,omitempty"`             // Inventory source the host is synced from; --host-sync updates and removes it
+	Key              string            `json:"key,omitempty"`                // SSH key for this host (overrides the default key)
+	DialTimeout      string            `json:"dial_timeout,omitempty"`       // Connection timeout (e.g. "60s", overrides the global default)
+	Keepalive        string            `json:"keepalive,omitempty"`          // Keepalive interval (e.g. "15s", "0" disables)
+	MaxRetries       int               `json:"max_retries,omitempty"`        // Connection attempts made by the pool
+	TempDir          string            `json:"temp_dir,omitempty"`           // Remote directory for uploaded scripts (e.g. when /tmp is noexec)
+	Pinned           bool              `json:"pinned,omitempty"`             // Keep a pooled connection open and reconnect it in the background (MCP server)
+	Sandbox          *SandboxConfig    `json:"sandbox,omitempty"`            // Limits for commands run on this host (replaces the global sandbox, {} disables it)
+	SFTPPaths        *SFTPPathsConfig  `json:"sftp_paths,omitempty"`         // Remote paths SFTP may use on this host (replaces the global policy, {} disables it)
+	Safety           *SafetyConfig     `json:"safety,omitempty"`             // Severity of flagged commands on this host (replaces group and global policies)
+	Shell            string            `json:"shell,omitempty"`              // Shell commands run in: bash, sh, zsh, fish, powershell or pwsh (windows hosts default to powershell)
+	Serialize        bool              `json:"serialize,omitempty"`          // Run one command at a time on this host, queueing the others (same as max_parallel 1)
+	MaxParallel      int               `json:"max_parallel,omitempty"`       // Commands run at once on this host, the others wait (0 = no limit)
+	SnapshotFiles    []string          `json:"snapshot_files,omitempty"`     // Files hashed by --snapshot on this host, besides the global list
+	UploadMode       string            `json:"upload_mode,omitempty"`        // Permissions of uploaded files in octal (e.g. "0640"; default: those of the replaced file)
+	UploadOwner      string            `json:"upload_owner,omitempty"`       // Owner of uploaded files, a user name or uid, set with chown through sudo unless the user is root
+	UploadGroup      string            `json:"upload_group,omitempty"`       // Group of uploaded files, a group name or gid
+	KeyPassphraseKey string            `json:"key_passphrase_key,omitempty"` // Keyring entry holding the passphrase of key (set by sshx keygen)
+}
+
+// SafetyConfig sets what happens to commands the safety validator flags:
+// block refuses them unless forced, warn runs them with a warning in the log
+// and the MCP result, info runs them and only records the finding. With
+// maintenance windows, the commands warn and info let run are blocked
+// outside of them.
+type SafetyConfig struct {
+	Mode               string                    `json:"mode,omitempty"`                // Severity of rules not listed in rules: block (default), warn or info
+	Rules              map[string]string         `json:"rules,omitempty"`               // Severity per rule (e.g. {"reboot": "warn"})
+	MaintenanceWindows []MaintenanceWindowConfig `json:"maintenance_windows,omitempty"` // When flagged commands and reboots may run (empty = any time)
+	ForceTOTPKey       string                    `json:"force_totp_key,omitempty"`      // Keyring entry of the TOTP secret MCP force overrides need a code of (confirm_token)
+}
+
+// MaintenanceWindowConfig is a weekly maintenance window
+type MaintenanceWindowConfig struct {
+	Days     []string `json:"days,omitempty"`     // Weekdays the window opens on (e.g. ["sat", "sun"]; empty = every day)
+	Start    string   `json:"start"`              // Opening time (HH:MM)
+	End      string   `json:"end"`                // Closing time (HH:MM); before start closes the window the next day
+	Timezone string   `json:"timezone,omitempty"` // IANA time zone of start and end (e.g. "Europe/Berlin"; empty = local time)
+}
+
+// SFTPPathsConfig restricts the remote paths of SFTP operations (uploads,
+// downloads, listings, mkdir and removals) to path prefixes
+type SFTPPathsConfig struct {
+	Read  []string `json:"read,omitempty"`  // Prefixes downloads and listings are limited to (empty = anywhere)
+	Write []string `json:"write,omitempty"` // Prefixes uploads, mkdir and removals are limited to (empty = anywhere)
+	Deny  []string `json:"deny,omitempty"`  // Prefixes no SFTP operation may use
+}
+
+// SandboxConfig confines the commands and scripts run on a host. The limits
+// are enforced on the remote host with env, timeout and systemd-run.
+type SandboxConfig struct {
+	CleanEnv  bool   `json:"clean_env,omitempty"`  // Run with only PATH, HOME and USER set
+	Path      string `json:"path,omitempty"`       // PATH in the clean environment
+	Timeout   string `json:"timeout,omitempty"`    // Kill commands running longer than this (e.g. "300s")
+	CPUQuota  string `json:"cpu_quota,omitempty"`  // systemd CPUQuota (e.g. "50%")
+	MemoryMax string `json:"memory_max,omitempty"` // systemd MemoryMax (e.g. "512M")
+	TasksMax  int    `json:"tasks_max,omitempty"`  // systemd TasksMax (processes and threads)
 }
 
 // Settings represents the user-level configuration
 type Settings struct {
-	Key   string       `json:"key,omitempty"` // Default SSH key path (e.g., ~/.ssh/id_rsa)
-	Hosts []HostConfig `json:"hosts"`         // List of configured hosts
+	Key                  string                   `json:"key,omitempty"`                     // Default SSH key path (e.g., ~/.ssh/id_rsa)
+	Hosts                []HostConfig             `json:"hosts"`                             // List of configured hosts
+	RecordDir            string                   `json:"record_dir,omitempty"`              // Directory for automatic recordings (default: ~/.sshmcp/recordings)
+	Hooks                *HooksConfig             `json:"hooks,omitempty"`                   // Notifications for command events
+	Events               *EventsConfig            `json:"events,omitempty"`                  // Webhook and NATS sinks of execution events (MCP server and daemon)
+	AuditLog             string                   `json:"audit_log,omitempty"`               // Audit log path (default: ~/.sshmcp/audit.log, "off" to disable)
+	SudoCacheTTL         string                   `json:"sudo_cache_ttl,omitempty"`          // How long sudo passwords stay in memory (e.g. "5m", "0" disables)
+	RevokedKeys          string                   `json:"revoked_keys,omitempty"`            // Revoked host keys file (authorized_keys format)
+	TrustTTL             string                   `json:"trust_ttl,omitempty"`               // Expire automatically trusted host keys after this period (e.g. "30d")
+	MCPAcceptUnknownHost bool                     `json:"mcp_accept_unknown_host,omitempty"` // Let the MCP server trust unknown hosts (stored in ~/.sshmcp/known_hosts_mcp)
+	MaxOutput            string                   `json:"max_output,omitempty"`              // Cap on command output returned by MCP tools (e.g. "256K", default: 1M)
+	PoolIdleTimeout      string                   `json:"pool_idle_timeout,omitempty"`       // Close pooled connections unused for this long (default: 5m)
+	PoolMaxLifetime      string                   `json:"pool_max_lifetime,omitempty"`       // Replace pooled connections older than this (default: 1h, "0" disables)
+	SudoReset            bool                     `json:"sudo_reset,omitempty"`              // Run `sudo -k` after every command that used sudo
+	DialTimeout          string                   `json:"dial_timeout,omitempty"`            // Default connection timeout for configured hosts (default: 30s)
+	Keepalive            string                   `json:"keepalive,omitempty"`               // Default keepalive interval for configured hosts (default: off)
+	MaxRetries           int                      `json:"max_retries,omitempty"`             // Default connection attempts made by the pool (default: 3)
+	ScriptTimeout        string                   `json:"script_timeout,omitempty"`          // Kill scripts running longer than this (default: 30m, "0" disables)
+	TempDir              string                   `json:"temp_dir,omitempty"`                // Default remote directory for uploaded scripts (default: /tmp)
+	MCPTools             *MCPToolsConfig          `json:"mcp_tools,omitempty"`               // Tools exposed by the MCP server (allow and deny lists)
+	MCPAuth              []MCPTokenPolicy         `json:"mcp_auth,omitempty"`                // Tools and hosts granted to each MCP API token
+	Sandbox              *SandboxConfig           `json:"sandbox,omitempty"`                 // Default limits for commands run on configured hosts
+	SFTPPaths            *SFTPPathsConfig         `json:"sftp_paths,omitempty"`              // Default remote paths SFTP may use on configured hosts
+	Safety               *SafetyConfig            `json:"safety,omitempty"`                  // Default severity of flagged commands
+	SafetyGroups         map[string]*SafetyConfig `json:"safety_groups,omitempty"`           // Severity of flagged commands on hosts with a group tag
+	Jobs                 []JobConfig              `json:"jobs,omitempty"`                    // Commands the MCP server runs on a schedule
+	MCPRemoveBackup      bool                     `json:"mcp_remove_backup,omitempty"`       // Make sftp_remove move files to ~/.sshx-trash instead of deleting them
+	ControlPersist       string                   `json:"control_persist,omitempty"`         // Run CLI commands through a control master kept up this long without use (e.g. "10m")
+	SnapshotFiles        []string                 `json:"snapshot_files,omitempty"`          // Files hashed by --snapshot (default: passwd, group, sudoers, sshd_config, hosts, fstab, resolv.conf, crontab)
+	ProtectedPackages    []string                 `json:"protected_packages,omitempty"`      // Packages package_manage never removes, besides the built-in list (globs allowed)
+	ProtectedProcesses   []string                 `json:"protected_processes,omitempty"`     // Process names process_signal never signals, besides the built-in list (globs allowed)
+	LocalExec            *LocalExecConfig         `json:"local_exec,omitempty"`              // Commands the MCP server may run on its own machine (local_exec, disabled by default)
+	HealthTTL            string                   `json:"health_ttl,omitempty"`              // How long ping and fleet results count for --skip-unreachable/--only-unreachable (default: 1h)
+	Inventory            []InventorySourceConfig  `json:"inventory,omitempty"`               // Cloud accounts whose instances --host-sync keeps as hosts
+	PreferTailnet        bool                     `json:"prefer_tailnet,omitempty"`          // Dial the tailnet address of hosts without addresses first, falling back to host
+	DeviceProfiles       map[string]*DeviceConfig `json:"device_profiles,omitempty"`         // Network device profiles hosts name in device, besides the built-in ones
+	ForwardAgentHosts    []string                 `json:"forward_agent_hosts,omitempty"`     // Hosts (names, addresses or tags) --forward-agent may forward the ssh-agent to
 }
 
 // GetSettingsPath returns the path to the settings file
@@ -49,7 +157,12 @@ func GetSettingsDir() (string, error) {
 	return filepath.Join(home, SettingsDir), nil
 }
 
-// LoadSettings loads settings from the settings file
+// settingsMu serializes the load, change and save of the settings by the
+// MCP server and daemon, whose requests and timers run concurrently
+var settingsMu sync.Mutex
+
+// LoadSettings loads settings from the settings file. It only reads: expired
+// hosts are pruned by dropExpiredHosts.
 func LoadSettings() (*Settings, error) {
 	settingsPath, err := GetSettingsPath()
 	if err != nil {
@@ -68,9 +181,18 @@ func LoadSettings() (*Settings, error) {
 		return nil, fmt.Errorf("failed to read settings file: %w", err)
 	}
 
+	settings, err := decodeSettings(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse settings file: %w", err)
+	}
+	return settings, nil
+}
+
+// decodeSettings parses the contents of a settings file
+func decodeSettings(data []byte) (*Settings, error) {
 	var settings Settings
 	if err := json.Unmarshal(data, &settings); err != nil {
-		return nil, fmt.Errorf("failed to parse settings file: %w", err)
+		return nil, err
 	}
 
 	// Initialize Hosts slice if nil
@@ -81,7 +203,8 @@ func LoadSettings() (*Settings, error) {
 	return &settings, nil
 }
 
-// SaveSettings saves settings to the settings file
+// SaveSettings saves settings to the settings file, through a temporary file
+// renamed over it so readers never see half of it
 func SaveSettings(settings *Settings) error {
 	settingsDir, err := GetSettingsDir()
 	if err != nil {
@@ -105,7 +228,19 @@ func SaveSettings(settings *Settings) error {
 	}
 
 	// Write settings file with secure permissions
-	if err := os.WriteFile(settingsPath, data, 0600); err != nil {
+	temp, err := os.CreateTemp(settingsDir, SettingsFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write settings file: %w", err)
+	}
+	defer func() { _ = os.Remove(temp.Name()) }() //nolint:errcheck // gone once renamed
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write settings file: %w", err)
+	}
+	if err := os.Rename(temp.Name(), settingsPath); err != nil {
 		return fmt.Errorf("failed to write settings file: %w", err)
 	}
 
@@ -120,7 +255,13 @@ func ValidateHostConfig(host *HostConfig) error {
 	if host.Host == "" {
 		return fmt.Errorf("host address is required")
 	}
-	return nil
+	if err := sshclient.ValidateShell(host.Shell); err != nil {
+		return err
+	}
+	if _, err := hostUploadOwnership(host); err != nil {
+		return err
+	}
+	return validateHostEnv(host.Env)
 }
 
 // AddHost adds a new host to settings
@@ -129,6 +270,9 @@ func AddHost(settings *Settings, host HostConfig) error {
 	if err := ValidateHostConfig(&host); err != nil {
 		return err
 	}
+	if err := validateHostDevice(settings, host.Device); err != nil {
+		return err
+	}
 
 	// Set default values before checking duplicates
 	if host.Port == "" {
@@ -187,6 +331,9 @@ func UpdateHost(settings *Settings, host HostConfig) error {
 	if err := ValidateHostConfig(&host); err != nil {
 		return err
 	}
+	if err := validateHostDevice(settings, host.Device); err != nil {
+		return err
+	}
 
 	// Set default values before checking duplicates
 	if host.Port == "" {
@@ -224,7 +371,139 @@ func UpdateHost(settings *Settings, host HostConfig) error {
 	return fmt.Errorf("host '%s' not found", host.Name)
 }
 
+// HostUpdateFields are the host fields ApplyHostUpdate accepts, by JSON name
+var HostUpdateFields = []string{"host", "addresses", "description", "port", "user", "password_key", "type", "device", "tags", "key", "shell", "meta", "env", "ttl"}
+
+// ApplyHostUpdate changes only the given fields of a configured host, keyed
+// by their JSON names, and validates the result. Fields that are not present
+// keep their current value; an empty value clears optional fields and
+// resets port and user to their defaults. meta takes key=value pairs merged
+// into the current metadata, where an empty value removes the key, and env
+// NAME=VALUE lines merged the same way; ttl sets the expiry from now, an
+// empty ttl makes the host permanent.
+func ApplyHostUpdate(settings *Settings, name string, fields map[string]string) (*HostConfig, error) {
+	existing, err := GetHost(settings, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields to update (use one of: %s)", strings.Join(HostUpdateFields, ", "))
+	}
+
+	host := *existing
+	for field, value := range fields {
+		value = strings.TrimSpace(value)
+		switch field {
+		case "host":
+			host.Host = value
+		case "addresses":
+			host.Addresses = splitList(value)
+		case "description":
+			host.Description = value
+		case "port":
+			host.Port = value
+		case "user":
+			host.User = value
+		case "password_key":
+			host.PasswordKey = value
+		case "type":
+			host.Type = value
+		case "device":
+			host.Device = value
+		case "tags":
+			host.Tags = splitList(value)
+		case "key":
+			host.Key = value
+		case "shell":
+			host.Shell = value
+		case "meta":
+			if host.Meta, err = mergeMeta(host.Meta, value); err != nil {
+				return nil, err
+			}
+		case "env":
+			if host.Env, err = mergeEnv(host.Env, value); err != nil {
+				return nil, err
+			}
+		case "ttl":
+			if host.Expires, err = hostExpiry(value, time.Now()); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown host field '%s' (use one of: %s)", field, strings.Join(HostUpdateFields, ", "))
+		}
+	}
+
+	if host.Port != "" {
+		if port, err := strconv.Atoi(host.Port); err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port '%s'", host.Port)
+		}
+	}
+	switch host.Type {
+	case "", "linux", "windows", "macos":
+	default:
+		return nil, fmt.Errorf("invalid type '%s' (use linux, windows or macos)", host.Type)
+	}
+
+	if err := UpdateHost(settings, host); err != nil {
+		return nil, err
+	}
+	return GetHost(settings, name)
+}
+
 // ListHosts returns all configured hosts
 func ListHosts(settings *Settings) []HostConfig {
 	return settings.Hosts
 }
+
+// HasTag reports whether the host carries the given tag
+func (h *HostConfig) HasTag(tag string) bool {
+	for _, t := range h.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveHostGroup expands a comma-separated list of host names and group tags
+// into the matching host configurations. Each item is first looked up as a host
+// name and then as a tag; duplicates are removed while preserving order.
+func ResolveHostGroup(settings *Settings, spec string) ([]HostConfig, error) {
+	var hosts []HostConfig
+	seen := make(map[string]bool)
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if host, err := GetHost(settings, item); err == nil {
+			if !seen[host.Name] {
+				seen[host.Name] = true
+				hosts = append(hosts, *host)
+			}
+			continue
+		}
+
+		matched := false
+		for _, h := range settings.Hosts {
+			if h.HasTag(item) {
+				matched = true
+				if !seen[h.Name] {
+					seen[h.Name] = true
+					hosts = append(hosts, h)
+				}
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no host or group named '%s'", item)
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no hosts specified")
+	}
+
+	return hosts, nil
+}
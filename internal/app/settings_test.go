@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -141,6 +142,16 @@ func TestValidateHostConfig(t *testing.T) {
 			host:    HostConfig{},
 			wantErr: true,
 		},
+		{
+			name:    "supported shell",
+			host:    HostConfig{Name: "test", Host: "192.168.1.100", Shell: "fish"},
+			wantErr: false,
+		},
+		{
+			name:    "unsupported shell",
+			host:    HostConfig{Name: "test", Host: "192.168.1.100", Shell: "tcsh"},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -400,3 +411,126 @@ func TestSettingsPath(t *testing.T) {
 		t.Errorf("GetSettingsDir() = %s, want %s", settingsDir, expectedDir)
 	}
 }
+
+func TestResolveHostGroup(t *testing.T) {
+	settings := &Settings{
+		Hosts: []HostConfig{
+			{Name: "web1", Host: "10.0.0.1", Tags: []string{"prod", "web"}},
+			{Name: "web2", Host: "10.0.0.2", Tags: []string{"prod", "web"}},
+			{Name: "db1", Host: "10.0.0.3", Tags: []string{"prod"}},
+			{Name: "dev1", Host: "10.0.1.1", Tags: []string{"dev"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "single host", spec: "db1", want: []string{"db1"}},
+		{name: "group tag", spec: "web", want: []string{"web1", "web2"}},
+		{name: "mixed and deduplicated", spec: "web1, prod", want: []string{"web1", "web2", "db1"}},
+		{name: "unknown item", spec: "web,missing", wantErr: true},
+		{name: "empty spec", spec: " , ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts, err := ResolveHostGroup(settings, tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ResolveHostGroup(%q) expected error", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveHostGroup(%q) error = %v", tt.spec, err)
+			}
+			var names []string
+			for _, h := range hosts {
+				names = append(names, h.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ResolveHostGroup(%q) = %v, want %v", tt.spec, names, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyHostUpdate(t *testing.T) {
+	settings := &Settings{
+		Hosts: []HostConfig{
+			{
+				Name: "web1", Host: "10.0.0.1", Port: "2222", User: "deploy", Description: "Web",
+				Tags: []string{"prod"}, Record: true, Vars: map[string]string{"role": "web"},
+			},
+			{Name: "web2", Host: "10.0.0.2", Port: "22"},
+		},
+	}
+
+	// Setting the port back to 22 must not be mistaken for "not provided"
+	host, err := ApplyHostUpdate(settings, "web1", map[string]string{"port": "22", "tags": "", "key": "~/.ssh/web"})
+	if err != nil {
+		t.Fatalf("ApplyHostUpdate() error = %v", err)
+	}
+	if host.Port != "22" || len(host.Tags) != 0 || host.Key != "~/.ssh/web" {
+		t.Errorf("fields not applied: %+v", host)
+	}
+	if host.User != "deploy" || host.Description != "Web" || !host.Record || host.Vars["role"] != "web" {
+		t.Errorf("untouched fields changed: %+v", host)
+	}
+	if host, err = ApplyHostUpdate(settings, "web1", map[string]string{"shell": "sh"}); err != nil || host.Shell != "sh" {
+		t.Errorf("shell not applied: %+v, %v", host, err)
+	}
+	host, err = ApplyHostUpdate(settings, "web1", map[string]string{"addresses": "tailscale, 10.8.0.1"})
+	if err != nil || len(host.Addresses) != 2 || host.Addresses[0] != "tailscale" || host.Addresses[1] != "10.8.0.1" {
+		t.Errorf("addresses not applied: %v, %v", host.Addresses, err)
+	}
+
+	// Metadata is merged, an empty value removes a key and "" removes all
+	if _, err = ApplyHostUpdate(settings, "web1", map[string]string{"meta": "rack=r12,owner=ops"}); err != nil {
+		t.Fatalf("ApplyHostUpdate(meta) error = %v", err)
+	}
+	host, err = ApplyHostUpdate(settings, "web1", map[string]string{"meta": "owner=,env=prod"})
+	if err != nil || len(host.Meta) != 2 || host.Meta["rack"] != "r12" || host.Meta["env"] != "prod" {
+		t.Errorf("meta not merged: %v, %v", host.Meta, err)
+	}
+	if host, err = ApplyHostUpdate(settings, "web1", map[string]string{"meta": ""}); err != nil || host.Meta != nil {
+		t.Errorf("meta not cleared: %v, %v", host.Meta, err)
+	}
+
+	// The environment is merged the same way, one variable per line
+	if _, err = ApplyHostUpdate(settings, "web1", map[string]string{"env": "HTTP_PROXY=http://proxy:3128\nNO_PROXY=localhost,.corp"}); err != nil {
+		t.Fatalf("ApplyHostUpdate(env) error = %v", err)
+	}
+	host, err = ApplyHostUpdate(settings, "web1", map[string]string{"env": "HTTP_PROXY="})
+	if err != nil || len(host.Env) != 1 || host.Env["NO_PROXY"] != "localhost,.corp" {
+		t.Errorf("env not merged: %v, %v", host.Env, err)
+	}
+
+	invalid := []map[string]string{
+		{},
+		{"port": "70000"},
+		{"type": "bsd"},
+		{"host": ""},
+		{"colour": "red"},
+		{"shell": "cmd"},
+		{"meta": "rack"},
+		{"env": "MY-VAR=1"},
+		{"ttl": "soon"},
+		{"host": "10.0.0.2", "port": "22"},
+	}
+	for _, fields := range invalid {
+		if _, err := ApplyHostUpdate(settings, "web1", fields); err == nil {
+			t.Errorf("ApplyHostUpdate(%v) should fail", fields)
+		}
+	}
+	if settings.Hosts[0].Host != "10.0.0.1" {
+		t.Errorf("failed update modified settings: %+v", settings.Hosts[0])
+	}
+
+	if _, err := ApplyHostUpdate(settings, "missing", map[string]string{"port": "22"}); err == nil {
+		t.Error("ApplyHostUpdate() should fail for unknown host")
+	}
+}
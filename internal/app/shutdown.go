@@ -0,0 +1,113 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// shutdownGracePeriod is how long Run waits for the interrupted operation to
+// unwind after its connections have been closed.
+const shutdownGracePeriod = 5 * time.Second
+
+// shutdownSignals are the signals that trigger a graceful shutdown
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// InterruptError is returned by Run when the process was interrupted by a signal.
+type InterruptError struct {
+	Signal os.Signal
+}
+
+func (e *InterruptError) Error() string {
+	return fmt.Sprintf("interrupted by signal: %v", e.Signal)
+}
+
+// ExitCode returns the conventional shell exit code (128 + signal number).
+func (e *InterruptError) ExitCode() int {
+	if sig, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+	return 1
+}
+
+var (
+	shutdownMu     sync.Mutex
+	shutdownHooks  = make(map[int]func())
+	nextShutdownID int
+)
+
+// onShutdown registers fn to run when the process shuts down and returns a
+// function that unregisters it once the resource has been released normally.
+func onShutdown(fn func()) func() {
+	shutdownMu.Lock()
+	id := nextShutdownID
+	nextShutdownID++
+	shutdownHooks[id] = fn
+	shutdownMu.Unlock()
+
+	return func() {
+		shutdownMu.Lock()
+		delete(shutdownHooks, id)
+		shutdownMu.Unlock()
+	}
+}
+
+// runShutdownHooks runs registered hooks (most recent first), drains the
+// connection pool and flushes log output.
+func runShutdownHooks() {
+	shutdownMu.Lock()
+	ids := make([]int, 0, len(shutdownHooks))
+	for id := range shutdownHooks {
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	hooks := make([]func(), 0, len(ids))
+	for _, id := range ids {
+		hooks = append(hooks, shutdownHooks[id])
+	}
+	shutdownHooks = make(map[int]func())
+	shutdownMu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+
+	sshclient.GetConnectionPool().Close()
+	sshclient.PurgeSecrets()
+
+	if err := logger.GetLogger().Sync(); err != nil {
+		logger.GetLogger().Debug("failed to flush log file: %v", err)
+	}
+}
+
+// runUntilSignal runs fn and returns its error, unless a signal arrives first.
+// On signal all connections are closed so the operation unblocks; a second
+// signal skips the grace period.
+func runUntilSignal(fn func() error, sigCh <-chan os.Signal) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case sig := <-sigCh:
+		logger.GetLogger().Warning("Received %v, shutting down...", sig)
+		runShutdownHooks()
+
+		select {
+		case <-done:
+		case <-sigCh:
+		case <-time.After(shutdownGracePeriod):
+			logger.GetLogger().Warning("Operation did not stop within %s", shutdownGracePeriod)
+		}
+		return &InterruptError{Signal: sig}
+	}
+}
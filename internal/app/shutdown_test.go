@@ -0,0 +1,70 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunUntilSignal_ReturnsResult(t *testing.T) {
+	sigCh := make(chan os.Signal, 1)
+	expected := errors.New("boom")
+
+	err := runUntilSignal(func() error { return expected }, sigCh)
+
+	assert.ErrorIs(t, err, expected)
+}
+
+func TestRunUntilSignal_InterruptRunsHooks(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	release := make(chan struct{})
+	var order []string
+	removeFirst := onShutdown(func() { order = append(order, "first") })
+	defer removeFirst()
+	removeSecond := onShutdown(func() {
+		order = append(order, "second")
+		close(release)
+	})
+	defer removeSecond()
+
+	sigCh := make(chan os.Signal, 1)
+	sigCh <- syscall.SIGINT
+
+	err := runUntilSignal(func() error {
+		<-release
+		return errors.New("connection closed")
+	}, sigCh)
+
+	var interruptErr *InterruptError
+	require.ErrorAs(t, err, &interruptErr)
+	assert.Equal(t, 130, interruptErr.ExitCode())
+	assert.Equal(t, []string{"second", "first"}, order)
+}
+
+func TestOnShutdown_Remove(t *testing.T) {
+	called := false
+	remove := onShutdown(func() { called = true })
+	remove()
+
+	runShutdownHooks()
+
+	assert.False(t, called)
+}
+
+func TestInterruptError(t *testing.T) {
+	err := &InterruptError{Signal: syscall.SIGTERM}
+
+	assert.Equal(t, 143, err.ExitCode())
+	assert.Contains(t, err.Error(), "interrupted by signal")
+	assert.Equal(t, 1, (&InterruptError{Signal: fakeSignal{}}).ExitCode())
+}
+
+type fakeSignal struct{}
+
+func (fakeSignal) String() string { return "fake" }
+func (fakeSignal) Signal()        {}
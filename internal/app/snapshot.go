@@ -0,0 +1,207 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// SnapshotsDir holds one directory of snapshots per host
+	SnapshotsDir = "snapshots"
+	// snapshotTimeFormat names snapshot files, so they sort by time
+	snapshotTimeFormat = "20060102-150405.000"
+	// snapshotHashWidth is how much of a file hash drift reports show
+	snapshotHashWidth = 12
+)
+
+// snapshotFiles lists the files hashed on host: the global snapshot_files
+// (or the defaults) followed by the host's own, without duplicates
+func snapshotFiles(settings *Settings, host *HostConfig) []string {
+	files := sshclient.DefaultSnapshotFiles
+	if settings != nil && len(settings.SnapshotFiles) > 0 {
+		files = settings.SnapshotFiles
+	}
+	if host != nil {
+		files = append(append([]string{}, files...), host.SnapshotFiles...)
+	}
+
+	seen := make(map[string]bool, len(files))
+	unique := make([]string, 0, len(files))
+	for _, file := range files {
+		if !seen[file] {
+			seen[file] = true
+			unique = append(unique, file)
+		}
+	}
+	return unique
+}
+
+// snapshotDir is the directory keeping the snapshots of the host of config
+func snapshotDir(config *sshclient.Config) (string, error) {
+	settingsDir, err := GetSettingsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(settingsDir, SnapshotsDir, sshclient.CollectDirName(config)), nil
+}
+
+// saveSnapshot writes snapshot to a new file in dir named after its time
+func saveSnapshot(dir string, snapshot *sshclient.Snapshot) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	path := filepath.Join(dir, snapshot.Time.Format(snapshotTimeFormat)+".json")
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return "", fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return path, nil
+}
+
+// latestSnapshot returns the newest snapshot file in dir
+func latestSnapshot(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read snapshots: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no snapshot in %s yet (take one with --snapshot)", dir)
+	}
+	sort.Strings(names)
+	return filepath.Join(dir, names[len(names)-1]), nil
+}
+
+// loadSnapshot reads a snapshot file
+func loadSnapshot(path string) (*sshclient.Snapshot, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- snapshot path chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var snapshot sshclient.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	return &snapshot, nil
+}
+
+// configSnapshotFiles returns the files to hash on the host of config
+func configSnapshotFiles(config *sshclient.Config) []string {
+	settings, _ := LoadSettings() //nolint:errcheck // defaults apply without settings
+	target := config.Alias
+	if target == "" {
+		target = config.Host
+	}
+	return snapshotFiles(settings, lookupHost(settings, target))
+}
+
+// handleSnapshot records the state of the host and saves it as its latest
+// snapshot
+func handleSnapshot(client *sshclient.SSHClient, config *sshclient.Config) error {
+	snapshot, err := client.TakeSnapshot(configSnapshotFiles(config), sshclient.RequestFromConfig(config))
+	if err != nil {
+		return fmt.Errorf("snapshot failed: %w", err)
+	}
+	dir, err := snapshotDir(config)
+	if err != nil {
+		return err
+	}
+	path, err := saveSnapshot(dir, snapshot)
+	if err != nil {
+		return err
+	}
+	fmt.Println(path)
+	logger.GetLogger().Success("Snapshot of %s saved: %d packages, %d enabled services, %d files",
+		snapshot.Host, len(snapshot.Packages), len(snapshot.Services), len(snapshot.Files))
+	return nil
+}
+
+// handleDiffSnapshot compares the host with its latest snapshot (or the
+// snapshot file given with --diff-snapshot=FILE) and reports the drift.
+// Drift is returned as an error, so scripts can check the exit status.
+func handleDiffSnapshot(client *sshclient.SSHClient, config *sshclient.Config) error {
+	path := config.LocalPath
+	if path == "" {
+		dir, err := snapshotDir(config)
+		if err != nil {
+			return err
+		}
+		if path, err = latestSnapshot(dir); err != nil {
+			return err
+		}
+	}
+	baseline, err := loadSnapshot(expandHome(path))
+	if err != nil {
+		return err
+	}
+	current, err := client.TakeSnapshot(configSnapshotFiles(config), sshclient.RequestFromConfig(config))
+	if err != nil {
+		return fmt.Errorf("snapshot failed: %w", err)
+	}
+	changes := sshclient.DiffSnapshots(baseline, current)
+
+	if config.JSONOutput {
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode drift: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(logger.Plain(formatSnapshotDrift(baseline, changes)))
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("%d change(s) on %s since %s", len(changes), current.Host, baseline.Time.Local().Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+// formatSnapshotDrift renders the changes since baseline as text
+func formatSnapshotDrift(baseline *sshclient.Snapshot, changes []sshclient.SnapshotChange) string {
+	since := baseline.Time.Local().Format("2006-01-02 15:04:05")
+	if len(changes) == 0 {
+		return fmt.Sprintf("✓ No drift on %s since %s\n", baseline.Host, since)
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Drift on %s since %s:\n", baseline.Host, since))
+	for _, change := range changes {
+		before, after := change.Before, change.After
+		if change.Section == sshclient.SnapshotFiles {
+			before, after = shortHash(before), shortHash(after)
+		}
+		var detail string
+		switch {
+		case change.Before == "":
+			detail = "+ " + after
+		case change.After == "":
+			detail = "- " + before
+		default:
+			detail = before + " → " + after
+		}
+		output.WriteString(fmt.Sprintf("  %-9s %s: %s\n", change.Section, change.Name, detail))
+	}
+	return output.String()
+}
+
+// shortHash shortens a sha256 for display, leaving file states as they are
+func shortHash(value string) string {
+	if len(value) > snapshotHashWidth {
+		return value[:snapshotHashWidth]
+	}
+	return value
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestSnapshotFiles(t *testing.T) {
+	assert.Equal(t, sshclient.DefaultSnapshotFiles, snapshotFiles(nil, nil))
+
+	settings := &Settings{SnapshotFiles: []string{"/etc/hosts", "/etc/app.conf"}}
+	host := &HostConfig{Name: "web1", SnapshotFiles: []string{"/etc/nginx/nginx.conf", "/etc/hosts"}}
+	assert.Equal(t, []string{"/etc/hosts", "/etc/app.conf", "/etc/nginx/nginx.conf"}, snapshotFiles(settings, host))
+	assert.Equal(t, []string{"/etc/hosts", "/etc/app.conf"}, settings.SnapshotFiles, "the global list is not modified")
+}
+
+func TestSnapshotStore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir, err := snapshotDir(&sshclient.Config{Host: "10.0.0.5", Alias: "web1"})
+	require.NoError(t, err)
+	assert.Equal(t, "web1", filepath.Base(dir))
+
+	_, err = latestSnapshot(dir)
+	assert.ErrorContains(t, err, "take one with --snapshot")
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, version := range []string{"1.18.0", "1.24.0"} {
+		snapshot := &sshclient.Snapshot{Host: "web1", Time: start.Add(time.Duration(i) * time.Hour), Packages: map[string]string{"nginx": version}}
+		_, err = saveSnapshot(dir, snapshot)
+		require.NoError(t, err)
+	}
+
+	path, err := latestSnapshot(dir)
+	require.NoError(t, err)
+	snapshot, err := loadSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, "1.24.0", snapshot.Packages["nginx"])
+	assert.True(t, snapshot.Time.Equal(start.Add(time.Hour)))
+}
+
+func TestFormatSnapshotDrift(t *testing.T) {
+	baseline := &sshclient.Snapshot{Host: "web1", Time: time.Now()}
+	assert.Contains(t, formatSnapshotDrift(baseline, nil), "No drift on web1")
+
+	drift := formatSnapshotDrift(baseline, []sshclient.SnapshotChange{
+		{Section: sshclient.SnapshotPackages, Name: "nginx", Before: "1.18.0", After: "1.24.0"},
+		{Section: sshclient.SnapshotServices, Name: "cron.service", Before: "enabled"},
+		{Section: sshclient.SnapshotFiles, Name: "/etc/hosts", Before: "0123456789abcdef0123", After: sshclient.SnapshotFileMissing},
+	})
+	assert.Contains(t, drift, "Drift on web1 since")
+	assert.Contains(t, drift, "packages  nginx: 1.18.0 → 1.24.0")
+	assert.Contains(t, drift, "services  cron.service: - enabled")
+	assert.Contains(t, drift, "files     /etc/hosts: 0123456789ab → missing")
+}
@@ -0,0 +1,434 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// sshConfigMaxIncludeDepth matches OpenSSH's limit on nested Include files
+const sshConfigMaxIncludeDepth = 16
+
+// SSHConfigHost is a concrete host alias resolved from an OpenSSH config file
+type SSHConfigHost struct {
+	Alias        string // Name used on the Host line
+	HostName     string // Address to connect to (defaults to Alias)
+	User         string
+	Port         string
+	IdentityFile string // First IdentityFile, tokens expanded
+}
+
+// sshConfigDirective is one keyword line
+type sshConfigDirective struct {
+	keyword string // lower-cased
+	args    []string
+}
+
+// sshConfigBlock is a Host or Match section; a block with neither applies to
+// every host
+type sshConfigBlock struct {
+	hosts     []string             // Host patterns
+	match     []sshMatchCriterion  // Match criteria, all of which must hold
+	directive []sshConfigDirective // Keywords in file order
+}
+
+// sshMatchCriterion is one criterion of a Match line, e.g. "host *.prod"
+type sshMatchCriterion struct {
+	name     string // all, host, originalhost, user, localuser, or unsupported ones
+	patterns string // comma-separated pattern list
+	negate   bool
+}
+
+// ParseSSHConfig reads an OpenSSH client config and returns one entry per
+// concrete Host alias (patterns with wildcards or negation are skipped).
+// Each alias is evaluated the way ssh does: the first value for a keyword
+// wins across all matching Host and Match blocks. Include globs are followed
+// (relative paths are resolved against the directory of path), Match
+// supports all, host, originalhost, user and localuser, and %-tokens are
+// expanded in HostName and IdentityFile.
+func ParseSSHConfig(path string) ([]SSHConfigHost, error) {
+	parser := &sshConfigParser{baseDir: filepath.Dir(path)}
+	if err := parser.parseFile(path, &sshConfigBlock{}, 0); err != nil {
+		return nil, err
+	}
+
+	var hosts []SSHConfigHost
+	for _, alias := range parser.aliases() {
+		hosts = append(hosts, parser.resolve(alias))
+	}
+	return hosts, nil
+}
+
+type sshConfigParser struct {
+	baseDir string
+	blocks  []*sshConfigBlock
+}
+
+// parseFile appends the blocks of one file. Lines before the file's first
+// Host or Match belong to the block that contained the Include.
+func (p *sshConfigParser) parseFile(path string, current *sshConfigBlock, depth int) error {
+	if depth > sshConfigMaxIncludeDepth {
+		return fmt.Errorf("%s: too many nested Include directives", path)
+	}
+
+	file, err := os.Open(path) // #nosec G304 -- user's own ssh config
+	if err != nil {
+		return fmt.Errorf("failed to open ssh config: %w", err)
+	}
+	defer func() {
+		_ = file.Close() //nolint:errcheck // read-only
+	}()
+
+	block := p.continueBlock(current)
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		keyword, args, err := splitSSHConfigLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		if keyword == "" {
+			continue
+		}
+
+		switch keyword {
+		case "host":
+			block = &sshConfigBlock{hosts: append([]string{}, args...)}
+			p.blocks = append(p.blocks, block)
+		case "match":
+			criteria, err := parseMatchCriteria(args)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+			block = &sshConfigBlock{match: criteria}
+			p.blocks = append(p.blocks, block)
+		case "include":
+			for _, pattern := range args {
+				if err := p.include(pattern, block, depth); err != nil {
+					return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+				}
+			}
+			// Keywords after the Include still belong to the enclosing block
+			block = p.continueBlock(block)
+		default:
+			block.directive = append(block.directive, sshConfigDirective{keyword: keyword, args: args})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// continueBlock starts a new block with the same condition as block, so that
+// keywords keep their file order relative to included blocks
+func (p *sshConfigParser) continueBlock(block *sshConfigBlock) *sshConfigBlock {
+	next := &sshConfigBlock{hosts: block.hosts, match: block.match}
+	p.blocks = append(p.blocks, next)
+	return next
+}
+
+// include parses every file matching pattern in lexical order
+func (p *sshConfigParser) include(pattern string, current *sshConfigBlock, depth int) error {
+	pattern = expandHome(pattern)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(p.baseDir, pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid Include pattern %q: %w", pattern, err)
+	}
+	for _, match := range matches {
+		if info, statErr := os.Stat(match); statErr == nil && info.IsDir() {
+			continue
+		}
+		if err := p.parseFile(match, current, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aliases returns the concrete names listed on Host lines, in file order
+func (p *sshConfigParser) aliases() []string {
+	seen := make(map[string]bool)
+	var aliases []string
+	for _, block := range p.blocks {
+		for _, pattern := range block.hosts {
+			if strings.ContainsAny(pattern, "*?!") || seen[pattern] {
+				continue
+			}
+			seen[pattern] = true
+			aliases = append(aliases, pattern)
+		}
+	}
+	return aliases
+}
+
+// resolve evaluates the config for one alias
+func (p *sshConfigParser) resolve(alias string) SSHConfigHost {
+	host := SSHConfigHost{Alias: alias}
+	localUser := currentUsername()
+
+	for _, block := range p.blocks {
+		hostname := host.HostName
+		if hostname == "" {
+			hostname = alias
+		}
+		remoteUser := host.User
+		if remoteUser == "" {
+			remoteUser = localUser
+		}
+		if !block.applies(alias, hostname, remoteUser, localUser) {
+			continue
+		}
+
+		for _, d := range block.directive {
+			if len(d.args) == 0 {
+				continue
+			}
+			switch d.keyword {
+			case "hostname":
+				if host.HostName == "" {
+					host.HostName = expandSSHTokens(d.args[0], map[byte]string{'h': alias})
+				}
+			case "user":
+				if host.User == "" {
+					host.User = d.args[0]
+				}
+			case "port":
+				if host.Port == "" {
+					host.Port = d.args[0]
+				}
+			case "identityfile":
+				if host.IdentityFile == "" {
+					host.IdentityFile = d.args[0]
+				}
+			}
+		}
+	}
+
+	if host.HostName == "" {
+		host.HostName = alias
+	}
+	if host.IdentityFile != "" {
+		host.IdentityFile = expandSSHTokens(host.IdentityFile, identityFileTokens(host, localUser))
+	}
+	return host
+}
+
+// applies reports whether a block's condition holds for the host
+func (b *sshConfigBlock) applies(alias, hostname, remoteUser, localUser string) bool {
+	if b.hosts != nil {
+		return matchHostPatterns(b.hosts, alias)
+	}
+	for _, criterion := range b.match {
+		var ok bool
+		switch criterion.name {
+		case "all":
+			ok = true
+		case "host":
+			ok = matchPatternList(criterion.patterns, hostname)
+		case "originalhost":
+			ok = matchPatternList(criterion.patterns, alias)
+		case "user":
+			ok = matchPatternList(criterion.patterns, remoteUser)
+		case "localuser":
+			ok = matchPatternList(criterion.patterns, localUser)
+		default:
+			// exec, canonical, final and others cannot be evaluated offline
+			return false
+		}
+		if ok == criterion.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// supportedMatchCriteria can be evaluated without connecting
+var supportedMatchCriteria = map[string]bool{"all": true, "host": true, "originalhost": true, "user": true, "localuser": true}
+
+// parseMatchCriteria parses the arguments of a Match line
+func parseMatchCriteria(args []string) ([]sshMatchCriterion, error) {
+	var criteria []sshMatchCriterion
+	for i := 0; i < len(args); i++ {
+		criterion := sshMatchCriterion{name: strings.ToLower(args[i])}
+		if strings.HasPrefix(criterion.name, "!") {
+			criterion.negate = true
+			criterion.name = criterion.name[1:]
+		}
+
+		if criterion.name != "all" && criterion.name != "canonical" && criterion.name != "final" {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("match %s requires an argument", criterion.name)
+			}
+			i++
+			criterion.patterns = args[i]
+		}
+		if !supportedMatchCriteria[criterion.name] {
+			logger.GetLogger().Warning("ssh config: Match %s is not supported, block ignored", criterion.name)
+		}
+		criteria = append(criteria, criterion)
+	}
+	if len(criteria) == 0 {
+		return nil, fmt.Errorf("match requires criteria")
+	}
+	return criteria, nil
+}
+
+// matchHostPatterns implements Host line matching: at least one pattern must
+// match and no negated pattern may match
+func matchHostPatterns(patterns []string, host string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
+			if matchWildcard(strings.ToLower(pattern[1:]), strings.ToLower(host)) {
+				return false
+			}
+			continue
+		}
+		if matchWildcard(strings.ToLower(pattern), strings.ToLower(host)) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchPatternList matches a comma-separated pattern list as used by Match
+func matchPatternList(list, value string) bool {
+	return matchHostPatterns(strings.Split(list, ","), value)
+}
+
+// matchWildcard matches OpenSSH patterns where * matches any run of
+// characters and ? matches exactly one
+func matchWildcard(pattern, value string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(value); i >= 0; i-- {
+				if matchWildcard(pattern[1:], value[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if value == "" {
+				return false
+			}
+		default:
+			if value == "" || value[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, value = pattern[1:], value[1:]
+	}
+	return value == ""
+}
+
+// identityFileTokens returns the tokens ssh expands in IdentityFile
+func identityFileTokens(host SSHConfigHost, localUser string) map[byte]string {
+	port := host.Port
+	if port == "" {
+		port = "22"
+	}
+	remoteUser := host.User
+	if remoteUser == "" {
+		remoteUser = localUser
+	}
+	home, _ := os.UserHomeDir()   //nolint:errcheck // %d expands to "" without a home directory
+	localHost, _ := os.Hostname() //nolint:errcheck // %l expands to "" when unknown
+
+	return map[byte]string{
+		'd': home,
+		'h': host.HostName,
+		'i': strconv.Itoa(os.Getuid()),
+		'l': localHost,
+		'L': strings.SplitN(localHost, ".", 2)[0],
+		'n': host.Alias,
+		'p': port,
+		'r': remoteUser,
+		'u': localUser,
+	}
+}
+
+// expandSSHTokens replaces %x tokens; %% is a literal percent sign and
+// unknown tokens are left untouched
+func expandSSHTokens(value string, tokens map[byte]string) string {
+	if !strings.Contains(value, "%") {
+		return value
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '%' || i+1 >= len(value) {
+			out.WriteByte(value[i])
+			continue
+		}
+		i++
+		if value[i] == '%' {
+			out.WriteByte('%')
+		} else if replacement, ok := tokens[value[i]]; ok {
+			out.WriteString(replacement)
+		} else {
+			out.WriteByte('%')
+			out.WriteByte(value[i])
+		}
+	}
+	return out.String()
+}
+
+// splitSSHConfigLine returns the lower-cased keyword and its arguments.
+// Keywords may be separated from their value by whitespace or "=", and
+// arguments may be double-quoted.
+func splitSSHConfigLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil, nil
+	}
+	keyword := strings.ToLower(line[:end])
+	rest := strings.TrimLeft(line[end:], " \t")
+	rest = strings.TrimLeft(strings.TrimPrefix(rest, "="), " \t")
+
+	var args []string
+	for rest != "" {
+		if rest[0] == '"' {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing < 0 {
+				return "", nil, fmt.Errorf("unterminated quote")
+			}
+			args = append(args, rest[1:closing+1])
+			rest = rest[closing+2:]
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			args = append(args, rest[:end])
+			rest = rest[end:]
+		}
+		rest = strings.TrimLeft(rest, " \t")
+	}
+	return keyword, args, nil
+}
+
+// currentUsername returns the local user name used for %u and Match localuser
+func currentUsername() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,181 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// Markers delimiting the section of ~/.ssh/config owned by sshx
+const (
+	sshConfigBlockBegin = "# BEGIN sshx managed hosts (generated by sshx --host-export-sshconfig, do not edit)"
+	sshConfigBlockEnd   = "# END sshx managed hosts"
+)
+
+// SSHConfigExportResult summarizes an export
+type SSHConfigExportResult struct {
+	Path     string
+	Exported []string
+	Skipped  map[string]string // host name -> reason
+}
+
+// handleHostExportSSHConfig writes configured hosts into the managed block
+// of ~/.ssh/config (or the file given with --host-export-sshconfig=<path>)
+func handleHostExportSSHConfig(config *sshclient.Config) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	path := config.SSHConfigPath
+	if path == "" {
+		home, homeErr := os.UserHomeDir()
+		if homeErr != nil {
+			return fmt.Errorf("failed to get user home directory: %w", homeErr)
+		}
+		path = filepath.Join(home, ".ssh", "config")
+	}
+
+	result, err := ExportSSHConfig(settings, expandHome(path))
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(logger.Plain(fmt.Sprintf("✓ Exported %d host(s) to %s\n", len(result.Exported), result.Path)))
+	skipped := make([]string, 0, len(result.Skipped))
+	for name := range result.Skipped {
+		skipped = append(skipped, name)
+	}
+	sort.Strings(skipped)
+	for _, name := range skipped {
+		fmt.Printf("  - skipped %s: %s\n", name, result.Skipped[name])
+	}
+	return nil
+}
+
+// ExportSSHConfig replaces the sshx managed block in the ssh config at path
+// with one Host entry per configured host, creating the file if needed.
+// Content outside the block is left untouched. A new block is inserted at
+// the top of the file so its values take precedence over "Host *" defaults;
+// hosts whose name is already declared outside the block are skipped.
+func ExportSSHConfig(settings *Settings, path string) (*SSHConfigExportResult, error) {
+	existing, err := os.ReadFile(path) // #nosec G304 -- user's own ssh config
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	before, after, found, err := splitManagedBlock(string(existing))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	userAliases := declaredHostAliases(before + after)
+
+	result := &SSHConfigExportResult{Path: path, Skipped: make(map[string]string)}
+	var block strings.Builder
+	block.WriteString(sshConfigBlockBegin + "\n")
+	for _, host := range ListHosts(settings) {
+		switch {
+		case strings.ContainsAny(host.Name, " \t*?!\"") || host.Name == "":
+			result.Skipped[host.Name] = "name cannot be used as an ssh_config Host pattern"
+			continue
+		case userAliases[host.Name]:
+			result.Skipped[host.Name] = "already defined outside the sshx block"
+			continue
+		}
+
+		key := host.Key
+		if key == "" {
+			key = settings.Key
+		}
+		block.WriteString(formatSSHConfigHost(host, key))
+		result.Exported = append(result.Exported, host.Name)
+	}
+	// Keywords following the block must apply to every host again
+	block.WriteString("Match all\n")
+	block.WriteString(sshConfigBlockEnd + "\n")
+
+	var content string
+	if found {
+		content = before + block.String() + after
+	} else if before == "" {
+		content = block.String()
+	} else {
+		content = block.String() + "\n" + before
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// formatSSHConfigHost renders one Host entry
+func formatSSHConfigHost(host HostConfig, key string) string {
+	var entry strings.Builder
+	if host.Description != "" {
+		entry.WriteString("# " + strings.ReplaceAll(host.Description, "\n", " ") + "\n")
+	}
+	entry.WriteString("Host " + host.Name + "\n")
+	entry.WriteString("    HostName " + host.Host + "\n")
+	if host.User != "" {
+		entry.WriteString("    User " + host.User + "\n")
+	}
+	if host.Port != "" && host.Port != sshclient.DefaultSSHPort {
+		entry.WriteString("    Port " + host.Port + "\n")
+	}
+	if key != "" {
+		entry.WriteString("    IdentityFile " + quoteSSHConfigArg(key) + "\n")
+	}
+	return entry.String()
+}
+
+// quoteSSHConfigArg double-quotes values containing whitespace
+func quoteSSHConfigArg(value string) string {
+	if strings.ContainsAny(value, " \t") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// splitManagedBlock returns the content before and after the sshx managed
+// block, and whether the block was present
+func splitManagedBlock(content string) (before, after string, found bool, err error) {
+	start := strings.Index(content, sshConfigBlockBegin)
+	if start < 0 {
+		return content, "", false, nil
+	}
+	end := strings.Index(content[start:], sshConfigBlockEnd)
+	if end < 0 {
+		return "", "", false, fmt.Errorf("sshx managed block is not terminated by %q", sshConfigBlockEnd)
+	}
+	end += start + len(sshConfigBlockEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	return content[:start], content[end:], true, nil
+}
+
+// declaredHostAliases returns the concrete names on Host lines of content
+func declaredHostAliases(content string) map[string]bool {
+	aliases := make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		keyword, args, err := splitSSHConfigLine(line)
+		if err != nil || keyword != "host" {
+			continue
+		}
+		for _, pattern := range args {
+			if !strings.ContainsAny(pattern, "*?!") {
+				aliases[pattern] = true
+			}
+		}
+	}
+	return aliases
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportSSHConfig_CreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".ssh", "config")
+	settings := &Settings{
+		Key: "~/.ssh/id_ed25519",
+		Hosts: []HostConfig{
+			{Name: "web1", Host: "10.0.0.1", User: "deploy", Port: "2222", Description: "Web server"},
+			{Name: "db1", Host: "10.0.0.2", Port: "22"},
+		},
+	}
+
+	result, err := ExportSSHConfig(settings, path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web1", "db1"}, result.Exported)
+
+	hosts, err := ParseSSHConfig(path)
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+	assert.Equal(t, SSHConfigHost{Alias: "web1", HostName: "10.0.0.1", User: "deploy", Port: "2222", IdentityFile: "~/.ssh/id_ed25519"}, hosts[0])
+	assert.Empty(t, hosts[1].Port)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestExportSSHConfig_PreservesUserContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	userContent := "ServerAliveInterval 60\n\nHost bastion\n    HostName 192.168.1.1\n"
+	require.NoError(t, os.WriteFile(path, []byte(userContent), 0o600))
+
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "web1", Host: "10.0.0.1"},
+		{Name: "bastion", Host: "10.9.9.9"},
+		{Name: "bad name", Host: "10.0.0.3"},
+	}}
+
+	result, err := ExportSSHConfig(settings, path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web1"}, result.Exported)
+	assert.Contains(t, result.Skipped["bastion"], "already defined")
+	assert.Contains(t, result.Skipped["bad name"], "cannot be used")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	content := string(data)
+	assert.True(t, strings.HasPrefix(content, sshConfigBlockBegin), "new block goes first")
+	assert.True(t, strings.HasSuffix(content, userContent))
+
+	// Re-exporting replaces the block in place
+	settings.Hosts[0].Host = "10.0.0.10"
+	_, err = ExportSSHConfig(settings, path)
+	require.NoError(t, err)
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), sshConfigBlockBegin))
+	assert.Contains(t, string(data), "HostName 10.0.0.10")
+	assert.NotContains(t, string(data), "HostName 10.0.0.1\n")
+	assert.True(t, strings.HasSuffix(string(data), userContent))
+}
+
+func TestExportSSHConfig_UnterminatedBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(sshConfigBlockBegin+"\nHost x\n"), 0o600))
+
+	_, err := ExportSSHConfig(&Settings{}, path)
+	assert.ErrorContains(t, err, "not terminated")
+}
+
+func TestParseArgs_HostExportSSHConfig(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "--host-export-sshconfig"})
+	assert.Equal(t, "host", config.Mode)
+	assert.Equal(t, "export-sshconfig", config.HostAction)
+	assert.Empty(t, config.SSHConfigPath)
+
+	config = ParseArgs([]string{"sshx", "--host-export-sshconfig=/tmp/ssh_config"})
+	assert.Equal(t, "/tmp/ssh_config", config.SSHConfigPath)
+}
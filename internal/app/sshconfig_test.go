@@ -0,0 +1,135 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSSHConfig(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestParseSSHConfig_FirstValueWins(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	writeSSHConfig(t, path, `
+# comment
+Host web1 web2
+    HostName 10.0.0.1
+    User deploy
+
+Host web2
+    HostName=10.0.0.2
+    Port 2222
+
+Host *
+    User fallback
+    Port 22
+    IdentityFile "~/.ssh/id ed25519"
+
+Host !bastion *.internal
+    User ignored
+`)
+
+	hosts, err := ParseSSHConfig(path)
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+
+	assert.Equal(t, SSHConfigHost{Alias: "web1", HostName: "10.0.0.1", User: "deploy", Port: "22", IdentityFile: "~/.ssh/id ed25519"}, hosts[0])
+	assert.Equal(t, "10.0.0.1", hosts[1].HostName, "first HostName wins")
+	assert.Equal(t, "2222", hosts[1].Port)
+}
+
+func TestParseSSHConfig_IncludeGlob(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	writeSSHConfig(t, path, `
+Include config.d/*
+Host main
+    HostName 10.0.0.9
+`)
+	writeSSHConfig(t, filepath.Join(dir, "config.d", "10-db"), `
+Host db1
+    HostName 10.0.1.1
+    Include nested.conf
+`)
+	writeSSHConfig(t, filepath.Join(dir, "config.d", "20-cache"), "Host cache1\n  HostName 10.0.2.1\n")
+	writeSSHConfig(t, filepath.Join(dir, "nested.conf"), "User dbadmin\n")
+
+	hosts, err := ParseSSHConfig(path)
+	require.NoError(t, err)
+
+	var aliases []string
+	for _, host := range hosts {
+		aliases = append(aliases, host.Alias)
+	}
+	assert.Equal(t, []string{"db1", "cache1", "main"}, aliases)
+	assert.Equal(t, "dbadmin", hosts[0].User, "included lines inherit the enclosing Host block")
+	assert.Empty(t, hosts[1].User)
+}
+
+func TestParseSSHConfig_IncludeDepthLimit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	writeSSHConfig(t, path, "Include config\n")
+
+	_, err := ParseSSHConfig(path)
+	assert.ErrorContains(t, err, "too many nested Include")
+}
+
+func TestParseSSHConfig_MatchAndTokens(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	writeSSHConfig(t, path, `
+Host app1 app2 legacy
+    HostName %h.prod.example.com
+
+Match host *.prod.example.com !originalhost app2
+    User ops
+    IdentityFile ~/.ssh/%n-%r@%h:%p%%
+
+Match originalhost legacy
+    Port 2200
+
+Match exec "test -f /tmp/x"
+    User never
+`)
+
+	hosts, err := ParseSSHConfig(path)
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+
+	assert.Equal(t, "app1.prod.example.com", hosts[0].HostName)
+	assert.Equal(t, "ops", hosts[0].User)
+	assert.Equal(t, "~/.ssh/app1-ops@app1.prod.example.com:22%", hosts[0].IdentityFile)
+
+	assert.Equal(t, "app2.prod.example.com", hosts[1].HostName)
+	assert.NotEqual(t, "ops", hosts[1].User, "negated originalhost excludes app2")
+
+	assert.Equal(t, "2200", hosts[2].Port)
+	assert.Equal(t, "ops", hosts[2].User)
+}
+
+func TestMatchWildcard(t *testing.T) {
+	assert.True(t, matchWildcard("*.prod", "web.prod"))
+	assert.True(t, matchWildcard("web?", "web1"))
+	assert.False(t, matchWildcard("web?", "web12"))
+	assert.True(t, matchWildcard("*", ""))
+	assert.False(t, matchHostPatterns([]string{"*", "!bastion"}, "bastion"))
+}
+
+func TestSplitSSHConfigLine(t *testing.T) {
+	keyword, args, err := splitSSHConfigLine(`  IdentityFile = "/path/with space" other`)
+	require.NoError(t, err)
+	assert.Equal(t, "identityfile", keyword)
+	assert.Equal(t, []string{"/path/with space", "other"}, args)
+
+	_, _, err = splitSSHConfigLine(`HostName "unterminated`)
+	assert.ErrorContains(t, err, "unterminated quote")
+}
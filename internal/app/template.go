@@ -0,0 +1,149 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/template"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+// TemplateData is the root object available to upload templates, e.g.
+// {{ .Name }}, {{ .Host }}, {{ .Vars.worker_count }} or {{ if hasTag "prod" }}
+type TemplateData struct {
+	Name        string
+	Description string
+	Host        string
+	Port        string
+	User        string
+	Type        string
+	Tags        []string
+	Vars        map[string]string
+}
+
+// newTemplateData builds template data from a configured host, preferring
+// the effective connection values of config where they are set
+func newTemplateData(host *HostConfig, config *sshclient.Config) TemplateData {
+	data := TemplateData{Vars: map[string]string{}}
+	if host != nil {
+		data.Name = host.Name
+		data.Description = host.Description
+		data.Host = host.Host
+		data.Port = host.Port
+		data.User = host.User
+		data.Type = host.Type
+		data.Tags = host.Tags
+		for key, value := range host.Vars {
+			data.Vars[key] = value
+		}
+	}
+	if config != nil {
+		if config.Host != "" {
+			data.Host = config.Host
+		}
+		if config.Port != "" {
+			data.Port = config.Port
+		}
+		if config.User != "" {
+			data.User = config.User
+		}
+		if data.Name == "" {
+			data.Name = data.Host
+		}
+	}
+	if data.Port == "" {
+		data.Port = "22"
+	}
+	return data
+}
+
+// renderTemplate renders template content for one host. Missing variables
+// are reported as errors rather than rendered as "<no value>".
+func renderTemplate(name string, content []byte, data TemplateData) ([]byte, error) {
+	tmpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{
+			"hasTag": func(tag string) bool { return slices.Contains(data.Tags, tag) },
+		}).
+		Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return nil, fmt.Errorf("failed to render template for %s: %w", data.Name, err)
+	}
+	return rendered.Bytes(), nil
+}
+
+// lookupTemplateHost finds the configured host a connection targets, by alias
+// first and then by address. It returns nil for ad-hoc hosts.
+func lookupTemplateHost(settings *Settings, config *sshclient.Config) *HostConfig {
+	if settings == nil {
+		return nil
+	}
+	for i := range settings.Hosts {
+		if config.Alias != "" && settings.Hosts[i].Name == config.Alias {
+			return &settings.Hosts[i]
+		}
+	}
+	for i := range settings.Hosts {
+		if settings.Hosts[i].Host == config.Host {
+			return &settings.Hosts[i]
+		}
+	}
+	return nil
+}
+
+// templateRenderer returns a per-host render function for multi-host uploads
+func templateRenderer(settings *Settings, path string) func(*sshclient.Config, []byte) ([]byte, error) {
+	return func(config *sshclient.Config, content []byte) ([]byte, error) {
+		return renderTemplate(filepath.Base(path), content, newTemplateData(lookupTemplateHost(settings, config), config))
+	}
+}
+
+// renderHostTemplate renders content for the target host of config
+func renderHostTemplate(config *sshclient.Config, name string, content []byte) ([]byte, error) {
+	settings, _ := LoadSettings() //nolint:errcheck // ad-hoc hosts render without settings
+	return renderTemplate(name, content, newTemplateData(lookupTemplateHost(settings, config), config))
+}
+
+// renderUploadTemplate renders config.LocalPath for the target host into a
+// temporary file and points config.LocalPath at it. The returned function
+// removes the temporary file.
+func renderUploadTemplate(config *sshclient.Config) (func(), error) {
+	content, err := os.ReadFile(config.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	rendered, err := renderHostTemplate(config, filepath.Base(config.LocalPath), content)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpFile, err := os.CreateTemp("", "sshx-template-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	cleanup := func() {
+		_ = os.Remove(tmpFile.Name()) //nolint:errcheck // best-effort cleanup
+	}
+
+	if _, err := tmpFile.Write(rendered); err != nil {
+		_ = tmpFile.Close() //nolint:errcheck // already failing
+		cleanup()
+		return nil, fmt.Errorf("failed to write rendered template: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to write rendered template: %w", err)
+	}
+
+	config.LocalPath = tmpFile.Name()
+	return cleanup, nil
+}
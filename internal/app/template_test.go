@@ -0,0 +1,89 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	host := &HostConfig{
+		Name: "web1",
+		Host: "10.0.0.1",
+		Tags: []string{"prod", "web"},
+		Vars: map[string]string{"workers": "8"},
+	}
+	content := []byte("server_name {{ .Name }};\nlisten {{ .Host }}:{{ .Port }};\nworkers {{ .Vars.workers }};\n{{ if hasTag \"prod\" }}gzip on;{{ end }}\n")
+
+	rendered, err := renderTemplate("nginx.conf.tmpl", content, newTemplateData(host, nil))
+
+	require.NoError(t, err)
+	assert.Equal(t, "server_name web1;\nlisten 10.0.0.1:22;\nworkers 8;\ngzip on;\n", string(rendered))
+}
+
+func TestRenderTemplate_MissingVar(t *testing.T) {
+	_, err := renderTemplate("app.tmpl", []byte("{{ .Vars.missing }}"), newTemplateData(&HostConfig{Name: "web1"}, nil))
+
+	assert.ErrorContains(t, err, "failed to render template for web1")
+}
+
+func TestNewTemplateData_PrefersEffectiveConfig(t *testing.T) {
+	host := &HostConfig{Name: "web1", Host: "10.0.0.1", User: "deploy"}
+	data := newTemplateData(host, &sshclient.Config{Host: "10.0.0.1", Port: "2222", User: "root"})
+
+	assert.Equal(t, "web1", data.Name)
+	assert.Equal(t, "2222", data.Port)
+	assert.Equal(t, "root", data.User)
+
+	adhoc := newTemplateData(nil, &sshclient.Config{Host: "192.168.1.5"})
+	assert.Equal(t, "192.168.1.5", adhoc.Name)
+	assert.Empty(t, adhoc.Vars)
+}
+
+func TestLookupTemplateHost(t *testing.T) {
+	settings := &Settings{Hosts: []HostConfig{
+		{Name: "web1", Host: "10.0.0.1"},
+		{Name: "web1-admin", Host: "10.0.0.1"},
+	}}
+
+	assert.Equal(t, "web1-admin", lookupTemplateHost(settings, &sshclient.Config{Host: "10.0.0.1", Alias: "web1-admin"}).Name)
+	assert.Equal(t, "web1", lookupTemplateHost(settings, &sshclient.Config{Host: "10.0.0.1"}).Name)
+	assert.Nil(t, lookupTemplateHost(settings, &sshclient.Config{Host: "10.0.0.9"}))
+}
+
+func TestRenderUploadTemplate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, SaveSettings(&Settings{Hosts: []HostConfig{
+		{Name: "db1", Host: "10.0.0.7", Vars: map[string]string{"role": "primary"}},
+	}}))
+
+	tmplPath := filepath.Join(t.TempDir(), "role.tmpl")
+	require.NoError(t, os.WriteFile(tmplPath, []byte("{{ .Name }}={{ .Vars.role }}"), 0o600))
+
+	config := &sshclient.Config{Host: "10.0.0.7", Alias: "db1", LocalPath: tmplPath}
+	cleanup, err := renderUploadTemplate(config)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, tmplPath, config.LocalPath)
+	data, err := os.ReadFile(config.LocalPath)
+	require.NoError(t, err)
+	assert.Equal(t, "db1=primary", string(data))
+
+	cleanup()
+	_, err = os.Stat(config.LocalPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestParseArgs_UploadTemplate(t *testing.T) {
+	config := ParseArgs([]string{"sshx", "-h=web1", "--upload-template=nginx.conf.tmpl", "--to=/etc/nginx/nginx.conf"})
+
+	assert.Equal(t, "sftp", config.Mode)
+	assert.Equal(t, "upload", config.SftpAction)
+	assert.True(t, config.UploadTemplate)
+	assert.Equal(t, "nginx.conf.tmpl", config.LocalPath)
+	assert.Equal(t, "/etc/nginx/nginx.conf", config.RemotePath)
+}
@@ -9,6 +9,12 @@ SSH & SFTP Remote Tool with Password Manager (Cross-Platform)
 
 Usage:
   sshx mcp-stdio                                  # MCP stdio mode (for AI assistants)
+  sshx daemon [--listen=127.0.0.1:7422]           # Serve the tools over a local REST API
+  sshx daemon --grpc-listen[=127.0.0.1:7423]      # Also serve the gRPC API
+  sshx doctor                                     # Check the local environment
+  sshx --version                                  # Show version, commit and build date
+  sshx self-update [--check] [--force]            # Install the latest release
+  sshx keygen [--type=ed25519] [--enroll=<hosts|groups>] # Generate a key and switch hosts to it
   sshx -h=<host> [options] <command>              # SSH mode
   sshx -h=<host> [options] --upload=<file>        # SFTP upload
   sshx -h=<host> [options] --download=<file>      # SFTP download
@@ -17,23 +23,91 @@ Usage:
   sshx --password-delete=<key>                    # Delete password from keyring
   sshx --password-list                            # List common password keys
   sshx --host-add                                 # Add host configuration
+  sshx --host-import[=<path>] [--overwrite]       # Import hosts from ~/.ssh/config or JSON
+  sshx --host-sync[=<source>]                     # Sync hosts from cloud inventory (aws, gcp, hetzner)
+  sshx --discover[=<cidr>]                        # Find SSH servers on the LAN and add them
   sshx --host-update                              # Update host configuration
   sshx --host-list                                # List configured hosts
+  sshx --host-export-sshconfig                    # Write hosts to ~/.ssh/config
   sshx --host-test=<name>                         # Test host connection
   sshx --host-test-all                            # Test all host connections
+  sshx --ping=<hosts|groups|addresses>            # Check SSH port reachability
+  sshx --diagnose -h=<host>                       # Step-by-step connection diagnosis
   sshx --host-remove=<name>                       # Remove host configuration
+  sshx --warm=<hosts|groups>                      # Pre-connect hosts in parallel
+  sshx --pool-flush[-host=<name>]                 # Drop pooled connections (all, or one host)
+  sshx --pool-stats [--json]                      # Show pooled connections
+  sshx --control-exit                             # Stop the background control master
+  sshx --hosts=<hosts|groups> --upload=<file> --to=<path>  # Upload to many hosts
+  sshx --hosts=<hosts|groups> --collect=<path> --into=<dir> # Download from many hosts
+  sshx --play=<file.cast>                         # Replay a recorded session
+  sshx -h=<host> --script=<file> [args...]        # Upload and run a local script, streaming output
+  sshx -h=<host> --cleanup-temp[=<age>]           # Remove leftover script files (default: older than 1h)
+  sshx -h=<host> --trash-purge[=<age>]            # Empty ~/.sshx-trash (or only removals older than e.g. 7d)
+  sshx [-h=<host>] --explain "<command>"          # Explain why the safety check blocks a command
+  sshx -h=<host> --snapshot                       # Save packages, enabled services and config file hashes
+  sshx -h=<host> --diff-snapshot[=<file>] [--json] # Report drift since the last snapshot (exit 1 on drift)
+  sshx -h=<host> --push-key --pubkey=<file> [--account=<user>] # Authorize a public key (default: the login user)
+  sshx -h=<host> --add-user=<name> [--user-shell=<path>] [--pubkey=<file>] # Create a user, optionally with a key
+  sshx -h=<host> --list-keys [--account=<user>] [--json] # List authorized keys with fingerprints
+  sshx -h=<host> --revoke-key=<fingerprint|comment> [--account=<user>] # Remove matching authorized keys
 
 MCP Mode:
   sshx mcp-stdio            Start MCP server in stdio mode
   sshx --mcp-stdio          Alternative MCP mode flag
+    --tools=<list>          Only expose these tools (names, globs like sftp_*, @readonly)
+    --deny-tools=<list>     Never expose these tools
+    --allow-local-exec      Expose local_exec, which runs commands on this machine (off by default)
+
+Daemon Mode:
+  sshx daemon               Serve the MCP tools over HTTP on 127.0.0.1:7422
+    --listen=<addr>         Loopback address to listen on
+    --grpc-listen[=<addr>]  Also serve the gRPC API (pkg/sshxpb/sshx.proto)
+    --tools, --deny-tools   Restrict the tools as in MCP mode (also --allow-local-exec)
+  Requests carry "Authorization: Bearer <token>": an mcp_auth token, or the
+  token written to ~/.sshmcp/daemon.token when no mcp_auth is configured.
+  Inventory sources with an "interval" are synced in the background.
 
   MCP Tools Available:
     - ssh_execute           Execute SSH commands with sudo support
+    - ssh_watch             Re-run a command until its output matches, reporting changes
+    - wait_for              Block until a port, file, systemd unit or URL is ready
+    - ssh_interact          Answer the prompts of a command or login shell with an expect script
+    - package_manage        Install, remove, update or list packages (dry run by default)
+    - user_create           Create a remote user unless it exists, optionally with a public key
+    - authorized_keys_add   Authorize a public key for a remote user (idempotent)
+    - authorized_keys_list  List a remote user's authorized keys with fingerprints
+    - authorized_keys_revoke Remove authorized keys by fingerprint or comment
+    - port_check            Listening ports, firewall status and whether a port is open
+    - host_metrics          Sample CPU, memory, load and disk usage as JSON
+    - process_list          List processes filtered by name and owner as JSON
+    - process_signal        Send TERM/KILL to a PID after a dry run and name check
+    - journal_query         Query journald/syslog by unit, priority, time range and pattern
+    - cron_list             List a user's crontab and the systemd timers
+    - cron_add              Add a crontab job (dry run diff by default, backs up the crontab)
+    - cron_remove           Remove crontab jobs containing a text (dry run diff by default)
+    - host_reboot           Reboot after pre-checks and confirmation, wait for the host to return
     - sftp_upload           Upload files via SFTP
     - sftp_download         Download files via SFTP
-    - sftp_list             List directory contents
+    - sftp_append           Append content to a remote file
+    - sftp_list             List directory contents as paginated JSON
     - sftp_mkdir            Create remote directory
-    - sftp_remove           Remove files/directories
+    - sftp_remove           Remove files/directories (backup moves them to ~/.sshx-trash)
+    - trash_purge           Delete files moved to ~/.sshx-trash
+    - sftp_distribute       Upload a file to many hosts with validation and rollback
+    - sftp_collect          Collect a file or command output from many hosts
+    - server_info           Version, enabled features, host count and limits as JSON
+    - pool_warm             Pre-connect hosts/groups to the connection pool
+    - pool_evict            Drop pooled connections for one host or all hosts
+    - pool_connections      List pooled connections as JSON
+    - host_ping             Check SSH port reachability and latency without logging in
+    - host_diagnose         Step-by-step connection diagnosis as JSON
+    - host_import           Import hosts from ~/.ssh/config or a JSON file
+    - host_update           Change only the given fields of a configured host
+    - known_hosts_list      List host keys accepted by the MCP server
+    - known_hosts_promote   Promote an MCP-accepted host key to ~/.ssh/known_hosts
+    - known_hosts_deny      Revoke an MCP-accepted host key
+    - local_exec            LOCAL command on the server machine (opt-in, own safety policy)
     - password_set          Store password in system keyring
     - password_get          Retrieve password from keyring
     - password_delete       Delete password from keyring
@@ -45,11 +119,47 @@ SSH Options:
   -u, --user=USER          SSH username (default: master)
   -i, --key=PATH           SSH private key path (default: ~/.ssh/id_rsa)
   -pk, --password-key=KEY  Sudo password keyring key name (default: master)
+  --record=FILE            Record the session to an asciinema v2 file
+  --play=FILE              Replay a recording (idle pauses capped at 2s)
+  --script=FILE            Upload and run a local script; positional arguments are passed to it
+  --script-timeout=DUR     Kill a script running longer than DUR (e.g. 10m; default: script_timeout setting)
+  --check-syntax           Parse a --script with its interpreter first and run it only if that succeeds
+  --run-as=USER            Run the command or script as USER via sudo -u (uses the sudo password)
+  --container=NAME         Run the command inside container NAME (docker, podman or nerdctl exec)
+  --container-runtime=RT   Container runtime for --container: docker, podman or nerdctl (default: autodetect)
+  --cwd=DIR                Run the command or script in remote directory DIR
+  --login-shell            Run the command or script through bash -lc (login profile environment)
+  --watch[=DUR]            Re-run the command every DUR (default: 2s) and print what changed
+  --until=REGEX            Stop watching once the output matches REGEX (implies --watch)
+  --watch-count=N          Stop watching after N runs
+  --wait-for=COND          Block until COND holds: port:[host:]N, file:PATH, service:UNIT or http:URL
+  --wait-timeout=DUR       Give up on --wait-for after DUR (default: 1m)
+  --wait-interval=DUR      Pause between --wait-for checks (default: 2s)
+  --interact=FILE          Answer the command's prompts (no command: a login shell) with an expect script (- reads stdin)
+  --forward-agent          Forward the local ssh-agent to the command (host must be in forward_agent_hosts)
+  -X, --forward-x11        Forward X11 connections of the command to the local DISPLAY
+  --reboot                 Reboot the host after pre-checks and wait up to --wait-timeout (default: 10m) for it
+  --confirm=HOST           Confirm --reboot without the prompt; must repeat the -h value
+  --min-uptime=DUR         Refuse --reboot when the host booted less than DUR ago (default: 10m; 0 skips)
+  --allow-sessions         Reboot even when users are logged in
+  --verify=CMD             Run CMD once the rebooted host is back; fail when it fails
+  --dry-run                Only run the --reboot pre-checks
+  --temp-dir=DIR           Remote directory for uploaded scripts (default: temp_dir setting or /tmp)
+  --revoked-keys=FILE      Refuse hosts presenting a key listed in FILE
+  --trust-ttl=DURATION     Re-verify automatically trusted host keys after DURATION (e.g. 30d, 720h)
+  --known-hosts-list       List host keys accepted by the MCP server (~/.sshmcp/known_hosts_mcp)
+  --known-hosts-promote=H  Move an MCP-accepted key for host H into ~/.ssh/known_hosts
+  --known-hosts-deny=H     Revoke an MCP-accepted key for host H
+  -q, --quiet              Print only remote output; warnings and errors still go to stderr
+  -v, --verbose            Show debug details (-vv adds host keys and protocol versions)
+  --no-color               Replace emoji with ASCII markers such as [OK] and [WARN] (also NO_COLOR=1)
   --help                   Show this help message
 
 Safety Options:
-  -f, --force           Force execution, bypass safety checks (use with caution!)
+  -f, --force           Run a blocked command after typing 'force <rule>' and a reason at the terminal
+  --force-reason=TEXT   Reason for --force, recorded in the audit log (skips the reason prompt)
   --no-safety-check     Disable safety checks completely (not recommended)
+  --explain "<cmd>"     Show the rule, match and safer alternative for a command, without running it ([-h=<host>] [--json])
 
   Safety checks protect against:
     - Destructive operations (rm -rf /, mkfs, dd)
@@ -60,11 +170,32 @@ Safety Options:
 
 SFTP Options:
   --upload=<local>      Upload file (use with --to=<remote>)
+  --upload-template=<f> Render a Go template per host (fields, tags, vars) and upload it
   --download=<remote>   Download file (use with --to=<local>)
   --to=<path>           Target path for upload/download
   --list=<path>         List directory contents (alias: --ls)
   --mkdir=<path>        Create remote directory
   --rm=<path>           Remove remote file or directory
+  --backup              With --rm, move the target to ~/.sshx-trash/<time>/ instead of deleting it;
+                        with --upload, keep the replaced file as <path>.bak
+  --delta               With --upload, send only the blocks that differ from the file on the host
+                        (rsync-style; needs python3 there, else the whole file is sent)
+  --hosts=<hosts>       Upload to several hosts/groups in parallel (with --upload)
+  --validate=<cmd>      Run on each host after upload; restore the old file if it fails
+  --collect=<remote>    Download a file from every --hosts host into <dir>/<host>/
+  --collect-cmd=<cmd>   Gather command output from every --hosts host into one file
+  --aggregate           With --collect-cmd, group hosts by identical output and diff the outliers
+  --into=<path>         Target directory (--collect) or file (--collect-cmd, default: stdout)
+  --max-size=<size>     Per-host cap for --collect/--collect-cmd (e.g. 10M, default: 50M)
+  --canary=<n>          Roll --upload/--collect-cmd out to n hosts first; halt if any fails
+  --batch-size=<n>      Then continue n hosts at a time (default: all the rest)
+  --max-failures=<n>    Failed hosts tolerated before the rollout halts (default: 0)
+  --expect=<regex>      Output (--collect-cmd) or --validate output (--upload) each host must match
+  --check=<cmd>         Command run on each host afterwards; a failure counts the host as failed
+  --rollback=<cmd>      Run on each host where --upload/--collect-cmd, --expect or --check failed
+  --manifest=<file>     Save a JSON manifest (size, SHA-256, status per host) of --upload/--collect;
+                        uploads are read back and verified first
+  --remote-manifest=<p> With --upload, also write the manifest to <p> on every updated host
 
 Password Management (Cross-Platform):
   --password-set=<key>[:<password>]   Set password in system keyring
@@ -73,6 +204,7 @@ Password Management (Cross-Platform):
   --password-check=<key>              Check if password exists (alias: --password-exists)
   --password-delete=<key>             Delete password from keyring (alias: --password-del)
   --password-list                     List common password keys (alias: --password-ls)
+  --lock                              Purge sudo passwords cached in memory (all running sshx processes)
 
   Platform Support:
     macOS:   Uses Keychain
@@ -81,20 +213,60 @@ Password Management (Cross-Platform):
 
 Host Management:
   --host-add                          Add new host (interactive or with options)
+  --host-import[=<path>]              Import hosts from ~/.ssh/config (Include/Match aware) or a .json hosts file
+  --overwrite                         Replace existing hosts whose address differs during --host-import
+  --host-sync[=<source>]              Upsert the running instances of the "inventory" sources in settings.json
+                                      (by source name or provider) and remove hosts whose instance is gone
+  --discover[=<cidr>]                 Scan a subnet (default: the local /24) and mDNS _ssh._tcp services for
+                                      SSH servers, show their banners and offer to add them (-p, -u apply)
   --host-update                       Update existing host configuration
   --host-list                         List all configured hosts (alias: --host-ls)
+  --host-find=<k=v,...>               List hosts whose metadata matches every condition (values may be globs,
+                                      a bare key only has to be set)
+  --group-by=<key>                    Group --host-list/--host-find output by a metadata key
+  --host-export-sshconfig[=<path>]    Write hosts into a managed block of ~/.ssh/config (or <path>)
   --host-test=<name>                  Test connection to configured host
   --host-test-all                     Test connections for all configured hosts
+  --json                              Print --host-test/--host-test-all/--pool-stats results as JSON
+  --ping=<hosts>                      TCP check of the SSH port (names, groups or host[:port]), 2s timeout
+  --icmp                              Also run the system ping command with --ping
+  --skip-unreachable                  Leave hosts a recent --ping found down out of --hosts/--warm runs
+  --only-unreachable                  Run --hosts/--warm only on the hosts a recent --ping found down
+  --diagnose[=<host>]                 Check DNS, TCP, SSH banner, host key and auth step by step (host from -h)
   --host-remove=<name>                Remove host from configuration (alias: --host-rm)
 
   Host Add/Update Options:
     --host-name=<name>                Host name (unique identifier, required for update)
     --host-desc=<description>         Host description
     -h=<address>                      Host address (IP or hostname)
+    --host-addresses=<a,b>            Addresses dialed in order before -h, e.g. a WireGuard IP or
+                                      tailscale[:<machine>] for the tailnet IP (MagicDNS name)
+    --host-device=<profile>           Drive the host as a network device through its CLI: cisco_ios,
+                                      junos, routeros, generic or a device_profiles entry (no SFTP)
     -p=<port>                         SSH port
     -u=<user>                         SSH username
     -pk=<key>                         Password key name
     --host-type=<type>                System type (linux/windows/macos)
+    --host-shell=<shell>              Shell commands run in: bash, sh, zsh, fish, powershell or pwsh
+                                      (default: the login shell; powershell for windows hosts)
+    --host-tags=<a,b>                 Group tags (usable wherever a host group is accepted)
+    --host-meta=<k=v,...>             Inventory metadata (e.g. rack=r12,owner=payments,env=prod);
+                                      on update the pairs are merged and k= removes a key
+    --host-env=<NAME=VALUE>           Environment variable exported for every command (e.g. HTTP_PROXY);
+                                      repeatable, on update NAME= removes one
+    --ttl=<duration>                  Ephemeral host: removed from settings and the pool once the TTL
+                                      (e.g. 4h, 7d) passes; --ttl= on update makes it permanent
+    -i=<path>                         SSH key for this host (overrides the default key)
+
+    --host-update changes only the options given; an empty value (e.g. --host-tags=) clears it.
+
+Connection Pool:
+  --warm=<hosts>                      Pre-connect hosts in parallel and report latency
+                                      (comma-separated host names or group tags); the connections are
+                                      kept by the control master with --control-persist, else this only
+                                      checks reachability
+  --control-persist[=<duration>]      Run the command through a background control master that
+                                      keeps the connection open for later calls (default: 10m)
 
   Configuration file: ~/.sshmcp/settings.json
 
@@ -104,6 +276,9 @@ Environment Variables (.env):
   SSH_SUDO_KEY          Sudo password keyring key name (default: master)
   SSH_NO_SAFETY_CHECK   Disable safety checks (true/false)
   SSH_FORCE             Force execution mode (true/false)
+  SSH_REVOKED_KEYS      Revoked host keys file
+  SSH_TRUST_TTL         Expiry for automatically trusted host keys (e.g. 30d)
+  SSH_CONTROL_PERSIST   Run commands through the control master (e.g. 10m, like --control-persist)
 
 SSH Examples:
   # Execute simple command (default user: master)
@@ -123,9 +298,15 @@ SSH Examples:
   sshx -h=192.168.1.100 "sudo rm -rf /tmp/*"  # Safe
   sshx -h=192.168.1.100 "sudo rm -rf /"       # ⚠️ BLOCKED!
 
-  # Force execute (bypass safety check - use with caution!)
+  # Force execute (confirm at the prompt - use with caution!)
   sshx -h=192.168.1.100 --force "sudo reboot"
-  sshx -h=192.168.1.100 -f "sudo systemctl reboot"
+  sshx -h=192.168.1.100 -f --force-reason="kernel update" "sudo systemctl reboot"
+
+  # Reboot with pre-checks, wait for the host and verify a service
+  sshx -h=web1 --run-as=root --reboot --verify="systemctl is-active nginx"
+
+  # Answer an installer's confirmation and a passphrase prompt
+  sshx -h=web1 --interact=install.expect "sudo apt-get install app"
 
 SFTP Examples:
   # Upload file
@@ -143,6 +324,15 @@ SFTP Examples:
   # Remove file
   sshx -h=192.168.1.100 --rm=/tmp/oldfile.txt
 
+  # Distribute a config file and roll back hosts where validation fails
+  sshx --hosts=web1,web2,web3 --upload=app.conf --to=/etc/nginx/conf.d/app.conf --validate="sudo nginx -t"
+
+  # Render a per-host config from a template (uses "vars" in settings.json)
+  sshx --hosts=web --upload-template=nginx.conf.tmpl --to=/etc/nginx/nginx.conf
+
+  # Collect an error log from every production host
+  sshx --hosts=prod --collect=/var/log/app/error.log --into=./collected/
+
   # Batch upload
   for file in *.txt; do
     sshx -h=192.168.1.100 --upload=$file --to=/backup/$file
@@ -206,6 +396,23 @@ Host Management Examples:
   # Test all configured hosts and get a report with auth methods
   sshx --host-test-all
 
+  # Find the SSH servers of a lab network and add them as hosts
+  sshx --discover=192.168.1.0/24 -u=pi
+
+  # Add a switch and back up its configuration with a device template
+  sshx --host-add --host-name=core-sw -h=10.0.0.2 -u=admin --host-device=cisco_ios
+  sshx -h=core-sw "@config"
+
+  # Check whether production hosts are reachable before running anything
+  sshx --ping=prod --icmp
+
+  # Then roll out to the live ones and remediate the dead ones
+  sshx --hosts=prod --upload=app.conf --to=/etc/app/app.conf --skip-unreachable
+  sshx --hosts=prod --collect-cmd="sudo systemctl restart sshd" --only-unreachable
+
+  # Find out why a host refuses connections or logins
+  sshx --diagnose -h=web1
+
   # Remove a host from configuration
   sshx --host-remove=prod-web
 
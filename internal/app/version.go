@@ -0,0 +1,138 @@
+package app
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// devVersion marks a binary built without release ldflags
+const devVersion = "dev"
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// buildInfo holds the values injected into main with -ldflags
+var buildInfo = BuildInfo{Version: devVersion}
+
+// SetBuildInfo records the version, commit and build date injected at link
+// time (-X main.Version=... -X main.Commit=... -X main.BuildDate=...)
+func SetBuildInfo(version, commit, buildDate string) {
+	if version != "" {
+		buildInfo.Version = version
+	}
+	buildInfo.Commit = commit
+	buildInfo.BuildDate = buildDate
+}
+
+// GetBuildInfo returns the build information of the running binary. Fields
+// not injected at link time are taken from the module and VCS data Go
+// embeds, so `go install` builds still report something useful.
+func GetBuildInfo() BuildInfo {
+	info := buildInfo
+	info.GoVersion = runtime.Version()
+	info.Platform = runtime.GOOS + "/" + runtime.GOARCH
+
+	embedded, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if info.Version == devVersion && embedded.Main.Version != "" && embedded.Main.Version != "(devel)" {
+		info.Version = embedded.Main.Version
+	}
+	for _, setting := range embedded.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && info.Commit == "":
+			info.Commit = setting.Value
+		case setting.Key == "vcs.time" && info.BuildDate == "":
+			info.BuildDate = setting.Value
+		}
+	}
+	return info
+}
+
+// String formats the build information for `sshx --version`
+func (b BuildInfo) String() string {
+	details := []string{}
+	if b.Commit != "" {
+		commit := b.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		details = append(details, "commit "+commit)
+	}
+	if b.BuildDate != "" {
+		details = append(details, "built "+b.BuildDate)
+	}
+	details = append(details, b.GoVersion, b.Platform)
+	return fmt.Sprintf("sshx %s (%s)", b.Version, strings.Join(details, ", "))
+}
+
+// serverVersion is the version reported in the MCP serverInfo, without the
+// tag's "v" prefix
+func serverVersion() string {
+	return strings.TrimPrefix(GetBuildInfo().Version, "v")
+}
+
+// compareVersions compares two semantic versions ("v1.2.3", "1.2.3-rc1").
+// It returns -1, 0 or 1, and ok is false when either is not a version.
+// A pre-release sorts before the release it precedes.
+func compareVersions(a, b string) (result int, ok bool) {
+	aCore, aPre, aOK := parseVersion(a)
+	bCore, bPre, bOK := parseVersion(b)
+	if !aOK || !bOK {
+		return 0, false
+	}
+	for i := range aCore {
+		if aCore[i] != bCore[i] {
+			if aCore[i] < bCore[i] {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0, true
+	case aPre == "":
+		return 1, true
+	case bPre == "":
+		return -1, true
+	case aPre < bPre:
+		return -1, true
+	default:
+		return 1, true
+	}
+}
+
+// parseVersion splits a version into major, minor, patch and pre-release
+func parseVersion(version string) (core [3]int, pre string, ok bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexByte(version, '+'); i >= 0 {
+		version = version[:i]
+	}
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		version, pre = version[:i], version[i+1:]
+	}
+
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return core, "", false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return core, "", false
+		}
+		core[i] = n
+	}
+	return core, pre, true
+}
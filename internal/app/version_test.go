@@ -0,0 +1,54 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+		ok       bool
+	}{
+		{"v1.2.3", "v1.2.3", 0, true},
+		{"1.2.3", "v1.2.4", -1, true},
+		{"v1.10.0", "v1.9.9", 1, true},
+		{"v2.0.0-rc1", "v2.0.0", -1, true},
+		{"v2.0.0", "v2.0.0-rc1", 1, true},
+		{"v2.0.0-rc1", "v2.0.0-rc2", -1, true},
+		{"v1.2.3+build5", "v1.2.3", 0, true},
+		{"dev", "v1.0.0", 0, false},
+		{"v1.2", "v1.2.0", 0, false},
+	}
+	for _, tt := range tests {
+		result, ok := compareVersions(tt.a, tt.b)
+		if ok != tt.ok || result != tt.expected {
+			t.Errorf("compareVersions(%q, %q) = %d, %v; want %d, %v", tt.a, tt.b, result, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	original := buildInfo
+	t.Cleanup(func() { buildInfo = original })
+
+	SetBuildInfo("v1.4.0", "0123456789abcdef0123", "2026-01-02T03:04:05Z")
+	info := GetBuildInfo()
+	if info.Version != "v1.4.0" || info.Commit != "0123456789abcdef0123" || info.BuildDate != "2026-01-02T03:04:05Z" {
+		t.Fatalf("GetBuildInfo() = %+v", info)
+	}
+	if serverVersion() != "1.4.0" {
+		t.Errorf("serverVersion() = %q", serverVersion())
+	}
+
+	text := info.String()
+	if !strings.HasPrefix(text, "sshx v1.4.0 (commit 0123456789ab, built 2026-01-02T03:04:05Z, go") {
+		t.Errorf("String() = %q", text)
+	}
+
+	SetBuildInfo("", "", "")
+	if GetBuildInfo().Version != "v1.4.0" {
+		t.Errorf("empty version replaced the injected one: %q", GetBuildInfo().Version)
+	}
+}
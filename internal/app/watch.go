@@ -0,0 +1,106 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// defaultWatchRuns is how many runs ssh_watch makes without max_runs
+	defaultWatchRuns = 10
+	// maxWatchRuns bounds ssh_watch, so a tool call cannot run forever
+	maxWatchRuns = 120
+	// maxWaitTimeout bounds wait_for, so a tool call cannot block forever
+	maxWaitTimeout = 30 * time.Minute
+)
+
+// watchCommand re-runs the command of config at its watch interval and
+// prints what changed between runs, until --until matches, --watch-count
+// runs have been made or the process is interrupted
+func watchCommand(client *sshclient.SSHClient, config *sshclient.Config) error {
+	opts, err := watchOptions(config.WatchInterval, config.WatchUntil, config.WatchCount)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	removeHook := onShutdown(func() { close(stop) })
+	defer removeHook()
+	opts.Stop = stop
+	opts.OnRun = func(run sshclient.WatchRun) {
+		fmt.Print(formatWatchRun(run))
+	}
+
+	fmt.Printf("Every %s: %s\n\n", opts.Interval, config.Command)
+	result := client.Watch(sshclient.RequestFromConfig(config), opts)
+	return watchOutcome(result, opts)
+}
+
+// watchOptions builds the options of a watch, validating the condition
+func watchOptions(interval time.Duration, until string, maxRuns int) (sshclient.WatchOptions, error) {
+	opts := sshclient.WatchOptions{Interval: interval, MaxRuns: maxRuns}
+	if opts.Interval <= 0 {
+		opts.Interval = sshclient.DefaultWatchInterval
+	}
+	if until != "" {
+		pattern, err := regexp.Compile(until)
+		if err != nil {
+			return opts, fmt.Errorf("invalid until pattern '%s': %w", until, err)
+		}
+		opts.Until = pattern
+	}
+	return opts, nil
+}
+
+// watchOutcome turns the end of a watch into an error when the condition
+// never matched or the command was blocked
+func watchOutcome(result sshclient.WatchResult, opts sshclient.WatchOptions) error {
+	var blocked *sshclient.BlockedCommandError
+	if errors.As(result.Last.Err, &blocked) {
+		return blocked
+	}
+	if result.Matched {
+		logger.GetLogger().Success("Condition matched on run %d", result.Runs)
+		return nil
+	}
+	if opts.Until != nil {
+		return fmt.Errorf("condition '%s' not matched after %d run(s)", opts.Until, result.Runs)
+	}
+	return nil
+}
+
+// formatWatchRun renders a run of a watch: the full output of the first run
+// and the changed lines of later runs. Unchanged runs render nothing.
+func formatWatchRun(run sshclient.WatchRun) string {
+	if !run.Changed {
+		return ""
+	}
+
+	var output strings.Builder
+	stamp := run.Time.Format("15:04:05")
+	if run.Run == 1 {
+		output.WriteString(fmt.Sprintf("[%s] run 1:\n", stamp))
+		output.WriteString(run.Output)
+		if run.Output != "" && !strings.HasSuffix(run.Output, "\n") {
+			output.WriteString("\n")
+		}
+		if run.Err != nil {
+			output.WriteString(fmt.Sprintf("error: %v\n", run.Err))
+		}
+	} else {
+		output.WriteString(fmt.Sprintf("[%s] run %d changed:\n", stamp, run.Run))
+		for _, line := range run.Diff {
+			output.WriteString(line + "\n")
+		}
+	}
+	if run.Matched {
+		output.WriteString("(condition matched)\n")
+	}
+	return output.String()
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+func TestWatchOptions(t *testing.T) {
+	opts, err := watchOptions(0, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, sshclient.DefaultWatchInterval, opts.Interval)
+	assert.Nil(t, opts.Until)
+
+	opts, err = watchOptions(time.Minute, `^active$`, 3)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, opts.Interval)
+	assert.Equal(t, 3, opts.MaxRuns)
+	assert.True(t, opts.Until.MatchString("active"))
+
+	_, err = watchOptions(time.Second, "(unclosed", 0)
+	assert.ErrorContains(t, err, "invalid until pattern")
+}
+
+func TestWatchOutcome(t *testing.T) {
+	opts, err := watchOptions(time.Second, "ready", 5)
+	require.NoError(t, err)
+
+	assert.NoError(t, watchOutcome(sshclient.WatchResult{Runs: 2, Matched: true}, opts))
+	assert.ErrorContains(t, watchOutcome(sshclient.WatchResult{Runs: 5}, opts), "not matched after 5 run(s)")
+
+	blocked := &sshclient.BlockedCommandError{Command: "rm -rf /", Reason: "Delete root directory"}
+	err = watchOutcome(sshclient.WatchResult{Runs: 1, Last: sshclient.WatchRun{Err: blocked}}, opts)
+	assert.Equal(t, blocked, err)
+
+	opts.Until = nil
+	assert.NoError(t, watchOutcome(sshclient.WatchResult{Runs: 5}, opts))
+}
+
+func TestFormatWatchRun(t *testing.T) {
+	at := time.Date(2026, 3, 2, 15, 4, 5, 0, time.Local)
+
+	first := formatWatchRun(sshclient.WatchRun{Run: 1, Time: at, Output: "used 10%", Changed: true})
+	assert.Equal(t, "[15:04:05] run 1:\nused 10%\n", first)
+
+	failed := formatWatchRun(sshclient.WatchRun{Run: 1, Time: at, Err: errors.New("exit 1"), Changed: true})
+	assert.Equal(t, "[15:04:05] run 1:\nerror: exit 1\n", failed)
+
+	assert.Empty(t, formatWatchRun(sshclient.WatchRun{Run: 2, Time: at, Output: "used 10%"}))
+
+	changed := formatWatchRun(sshclient.WatchRun{Run: 3, Time: at, Changed: true, Matched: true, Diff: []string{"- used 10%", "+ used 20%"}})
+	assert.Equal(t, "[15:04:05] run 3 changed:\n- used 10%\n+ used 20%\n(condition matched)\n", changed)
+}
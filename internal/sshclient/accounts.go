@@ -0,0 +1,288 @@
+package sshclient
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Outcomes printed by the account commands as their last line
+const (
+	accountCreated = "created"
+	accountExists  = "exists"
+	keyAdded       = "added"
+	keyPresent     = "present"
+)
+
+// accountNamePattern matches the user names useradd and adduser accept by
+// default
+var accountNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_.-]{0,31}$`)
+
+// ValidateAccountName rejects names that are not plain user names
+func ValidateAccountName(name string) error {
+	if !accountNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid user name '%s'", name)
+	}
+	return nil
+}
+
+// AuthorizedKey is one key of an authorized_keys file
+type AuthorizedKey struct {
+	Type        string   `json:"type"`
+	Fingerprint string   `json:"fingerprint"` // SHA256:...
+	Comment     string   `json:"comment,omitempty"`
+	Options     []string `json:"options,omitempty"`
+	// blob is the base64 key as written in the file
+	blob string
+}
+
+// Matches reports whether the key has the fingerprint or comment match
+func (k AuthorizedKey) Matches(match string) bool {
+	return match != "" && (k.Fingerprint == match || k.Comment == match)
+}
+
+// parseAuthorizedKeyLine reads one authorized_keys line
+func parseAuthorizedKeyLine(line string) (AuthorizedKey, bool) {
+	key, comment, options, _, err := ssh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		return AuthorizedKey{}, false
+	}
+	// MarshalAuthorizedKey prints "type base64"
+	fields := strings.Fields(string(ssh.MarshalAuthorizedKey(key)))
+	return AuthorizedKey{
+		Type:        key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		Comment:     comment,
+		Options:     options,
+		blob:        fields[len(fields)-1],
+	}, true
+}
+
+// ParseAuthorizedKeys reads the keys of an authorized_keys file, skipping
+// blank lines, comments and lines that are not keys
+func ParseAuthorizedKeys(content string) []AuthorizedKey {
+	keys := []AuthorizedKey{}
+	for _, line := range splitLines(content) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, ok := parseAuthorizedKeyLine(line); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ParsePublicKey reads a single public key in authorized_keys format (the
+// content of an id_*.pub file) and returns it with the line to install
+func ParsePublicKey(data string) (AuthorizedKey, string, error) {
+	var lines []string
+	for _, line := range splitLines(data) {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 1 {
+		return AuthorizedKey{}, "", fmt.Errorf("expected exactly one public key, found %d lines", len(lines))
+	}
+	key, ok := parseAuthorizedKeyLine(lines[0])
+	if !ok {
+		return AuthorizedKey{}, "", fmt.Errorf("invalid public key (use the content of an id_*.pub file)")
+	}
+	if len(key.Options) > 0 {
+		return AuthorizedKey{}, "", fmt.Errorf("public key must not carry authorized_keys options")
+	}
+	line := key.Type + " " + key.blob
+	if key.Comment != "" {
+		line += " " + key.Comment
+	}
+	return key, line, nil
+}
+
+// authorizedKeysFileCommand sets $f to the authorized_keys file of account,
+// failing when the account does not exist
+func authorizedKeysFileCommand(account string) string {
+	return strings.Join([]string{
+		`home=$(getent passwd ` + account + ` 2>/dev/null | cut -d: -f6)`,
+		`[ -n "$home" ] || home=~` + account,
+		`[ -d "$home" ] || { echo "user ` + account + ` does not exist" >&2; exit 1; }`,
+		`f="$home/.ssh/authorized_keys"`,
+	}, "\n")
+}
+
+// CreateUserCommand creates account with a home directory unless it already
+// exists, using useradd or BusyBox adduser. shell is the login shell (empty
+// = the system default).
+func CreateUserCommand(account, shell string) string {
+	useradd, adduser := "useradd -m", "adduser -D"
+	if shell != "" {
+		useradd += " -s " + ShellQuote(shell)
+		adduser += " -s " + ShellQuote(shell)
+	}
+	return strings.Join([]string{
+		`if id ` + account + ` >/dev/null 2>&1; then echo ` + accountExists,
+		`elif command -v useradd >/dev/null 2>&1; then ` + useradd + ` ` + account + ` && echo ` + accountCreated,
+		`elif command -v adduser >/dev/null 2>&1; then ` + adduser + ` ` + account + ` && echo ` + accountCreated,
+		`else echo "neither useradd nor adduser found" >&2; exit 1; fi`,
+	}, "\n")
+}
+
+// ListAuthorizedKeysCommand prints the authorized_keys file of account
+// (nothing when it has none)
+func ListAuthorizedKeysCommand(account string) string {
+	return authorizedKeysFileCommand(account) + "\n" + `[ ! -f "$f" ] || cat "$f"`
+}
+
+// AddAuthorizedKeyCommand appends line to the authorized_keys file of
+// account unless a line with the same key is already there, creating
+// ~/.ssh with the permissions sshd expects
+func AddAuthorizedKeyCommand(account string, key AuthorizedKey, line string) string {
+	return strings.Join([]string{
+		authorizedKeysFileCommand(account),
+		`mkdir -p "$home/.ssh" && chmod 700 "$home/.ssh" && touch "$f" && chmod 600 "$f" || exit 1`,
+		`if [ "$(id -u)" = 0 ]; then chown ` + account + ` "$home/.ssh" "$f" || exit 1; fi`,
+		`if grep -qF ` + ShellQuote(key.blob) + ` "$f"; then echo ` + keyPresent + `; exit 0; fi`,
+		`if [ -s "$f" ] && [ -n "$(tail -c 1 "$f")" ]; then echo >> "$f"; fi`,
+		`printf '%s\n' ` + ShellQuote(line) + ` >> "$f" && echo ` + keyAdded,
+	}, "\n")
+}
+
+// RemoveAuthorizedKeysCommand removes the lines holding any of keys from the
+// authorized_keys file of account, keeping its owner and permissions
+func RemoveAuthorizedKeysCommand(account string, keys []AuthorizedKey) string {
+	patterns := make([]string, len(keys))
+	for i, key := range keys {
+		patterns[i] = "-e " + ShellQuote(key.blob)
+	}
+	return strings.Join([]string{
+		authorizedKeysFileCommand(account),
+		`[ -f "$f" ] || exit 0`,
+		`t=$(mktemp "$f.XXXXXX") || exit 1`,
+		`grep -vF ` + strings.Join(patterns, " ") + ` "$f" > "$t"; s=$?`,
+		// grep exits 1 when no line is left
+		`if [ $s -gt 1 ]; then rm -f "$t"; exit $s; fi`,
+		`cat "$t" > "$f"; s=$?; rm -f "$t"; exit $s`,
+	}, "\n")
+}
+
+// lastLine is the last non-empty line of output
+func lastLine(output string) string {
+	lines := splitLines(strings.TrimSpace(output))
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// accountOrUser is account, or the login user when it is empty
+func (c *SSHClient) accountOrUser(account string) (string, error) {
+	if account == "" {
+		account = c.config.User
+	}
+	if err := ValidateAccountName(account); err != nil {
+		return "", err
+	}
+	if isPowerShell(c.config.Shell) {
+		return "", fmt.Errorf("account management is not supported with shell %s", c.config.Shell)
+	}
+	return account, nil
+}
+
+// CreateUser creates account on the host unless it exists and reports
+// whether it did. Like the other account methods it runs through Execute,
+// so the command is validated and audited; creating users needs root.
+func (c *SSHClient) CreateUser(account, shell string, req Request) (bool, error) {
+	if account == "" {
+		return false, fmt.Errorf("a user name is required")
+	}
+	if _, err := c.accountOrUser(account); err != nil {
+		return false, err
+	}
+	if shell != "" && (!strings.HasPrefix(shell, "/") || strings.ContainsAny(shell, " \t\r\n")) {
+		return false, fmt.Errorf("invalid login shell '%s' (use an absolute path)", shell)
+	}
+	req.Command = CreateUserCommand(account, shell)
+	result, err := c.Execute(req)
+	if err != nil {
+		return false, err
+	}
+	return lastLine(result.Output) == accountCreated, nil
+}
+
+// AuthorizedKeys lists the keys account (the login user when empty) accepts
+func (c *SSHClient) AuthorizedKeys(account string, req Request) ([]AuthorizedKey, error) {
+	account, err := c.accountOrUser(account)
+	if err != nil {
+		return nil, err
+	}
+	req.Command = ListAuthorizedKeysCommand(account)
+	result, err := c.Execute(req)
+	if err != nil {
+		return nil, err
+	}
+	if result.Truncated {
+		return nil, fmt.Errorf("authorized_keys exceeded the output limit")
+	}
+	return ParseAuthorizedKeys(result.Output), nil
+}
+
+// AddAuthorizedKey installs publicKey for account (the login user when
+// empty) and reports whether it was added; a key already present is left
+// alone
+func (c *SSHClient) AddAuthorizedKey(account, publicKey string, req Request) (AuthorizedKey, bool, error) {
+	account, err := c.accountOrUser(account)
+	if err != nil {
+		return AuthorizedKey{}, false, err
+	}
+	key, line, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return AuthorizedKey{}, false, err
+	}
+	req.Command = AddAuthorizedKeyCommand(account, key, line)
+	result, err := c.Execute(req)
+	if err != nil {
+		return key, false, err
+	}
+	switch lastLine(result.Output) {
+	case keyAdded:
+		return key, true, nil
+	case keyPresent:
+		return key, false, nil
+	}
+	return key, false, fmt.Errorf("unexpected output: %s", strings.TrimSpace(result.Output))
+}
+
+// RevokeAuthorizedKeys removes the keys of account (the login user when
+// empty) whose SHA256 fingerprint or comment is match and returns them.
+// Nothing matching is not an error, so revoking twice is harmless.
+func (c *SSHClient) RevokeAuthorizedKeys(account, match string, req Request) ([]AuthorizedKey, error) {
+	if strings.TrimSpace(match) == "" {
+		return nil, fmt.Errorf("a key fingerprint or comment is required")
+	}
+	account, err := c.accountOrUser(account)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := c.AuthorizedKeys(account, req)
+	if err != nil {
+		return nil, err
+	}
+	revoked := []AuthorizedKey{}
+	for _, key := range keys {
+		if key.Matches(match) {
+			revoked = append(revoked, key)
+		}
+	}
+	if len(revoked) == 0 {
+		return revoked, nil
+	}
+
+	req.Command = RemoveAuthorizedKeysCommand(account, revoked)
+	if _, err := c.Execute(req); err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
@@ -0,0 +1,195 @@
+package sshclient
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	alicePublicKey   = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIJUZR92WqbYB6FZpQ7ujn+yNkw0PbBmlfEOBuczEGIa/ alice@laptop"
+	aliceFingerprint = "SHA256:z2pzaesWkS75Fs9LUFWN85hgRe1ax9S3kGx+lBuBfgo"
+	ciPublicKey      = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAINgMZBTnA2saq4h6eTy7mfSZRBew4bYHiP26ee2Pjh+I ci"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	key, line, err := ParsePublicKey("\n" + alicePublicKey + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, alicePublicKey, line)
+	assert.Equal(t, "ssh-ed25519", key.Type)
+	assert.Equal(t, aliceFingerprint, key.Fingerprint)
+	assert.Equal(t, "alice@laptop", key.Comment)
+
+	_, _, err = ParsePublicKey(alicePublicKey + "\n" + ciPublicKey)
+	assert.ErrorContains(t, err, "exactly one public key")
+	_, _, err = ParsePublicKey("ssh-ed25519 not-base64")
+	assert.ErrorContains(t, err, "invalid public key")
+	_, _, err = ParsePublicKey(`command="/bin/true" ` + ciPublicKey)
+	assert.ErrorContains(t, err, "options")
+}
+
+func TestParseAuthorizedKeys(t *testing.T) {
+	keys := ParseAuthorizedKeys("# deploy keys\n" + alicePublicKey + "\r\n\nnot a key\n" + `from="10.0.0.0/8" ` + ciPublicKey + "\n")
+	require.Len(t, keys, 2)
+	assert.Equal(t, aliceFingerprint, keys[0].Fingerprint)
+	assert.Equal(t, []string{`from="10.0.0.0/8"`}, keys[1].Options)
+	assert.True(t, keys[1].Matches("ci"))
+	assert.False(t, keys[1].Matches(""))
+	assert.Empty(t, ParseAuthorizedKeys(""))
+}
+
+func TestValidateAccountName(t *testing.T) {
+	for _, name := range []string{"deploy", "_svc", "app-user.1"} {
+		assert.NoError(t, ValidateAccountName(name), name)
+	}
+	for _, name := range []string{"", "Deploy", "-x", "a b", "x;id", "~root", strings.Repeat("a", 33)} {
+		assert.Error(t, ValidateAccountName(name), name)
+	}
+}
+
+// fakeAccountHost runs the account commands with sh against a temporary home
+// directory: getent resolves deploy to it and id -u reports an ordinary user
+func fakeAccountHost(t *testing.T) (home string, run func(command string) string) {
+	t.Helper()
+	dir := t.TempDir()
+	home = filepath.Join(dir, "home")
+	bin := filepath.Join(dir, "bin")
+	require.NoError(t, os.MkdirAll(home, 0o700))
+	require.NoError(t, os.MkdirAll(bin, 0o700))
+	getent := "#!/bin/sh\n[ \"$2\" = deploy ] && echo \"deploy:x:1000:1000::" + home + ":/bin/sh\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "getent"), []byte(getent), 0o700)) // #nosec G306 -- test helper
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "id"), []byte("#!/bin/sh\necho 1000\n"), 0o700))
+	return home, func(command string) string {
+		t.Helper()
+		assert.False(t, Explain(command, nil).Flagged, "%s must pass the safety check", command)
+		cmd := exec.Command("sh", "-c", command) // #nosec G204 -- test command
+		cmd.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"))
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return string(output)
+	}
+}
+
+func TestAuthorizedKeysCommands(t *testing.T) {
+	home, run := fakeAccountHost(t)
+	file := filepath.Join(home, ".ssh", "authorized_keys")
+	alice, aliceLine, err := ParsePublicKey(alicePublicKey)
+	require.NoError(t, err)
+	ci, ciLine, err := ParsePublicKey(ciPublicKey)
+	require.NoError(t, err)
+
+	assert.Empty(t, run(ListAuthorizedKeysCommand("deploy")))
+	assert.Equal(t, "added", lastLine(run(AddAuthorizedKeyCommand("deploy", alice, aliceLine))))
+	assert.Equal(t, "present", lastLine(run(AddAuthorizedKeyCommand("deploy", alice, aliceLine))))
+
+	// A file without a trailing newline gets one before the next key
+	require.NoError(t, os.WriteFile(file, []byte("# keep\n"+alicePublicKey), 0o600))
+	assert.Equal(t, "added", lastLine(run(AddAuthorizedKeyCommand("deploy", ci, ciLine))))
+	keys := ParseAuthorizedKeys(run(ListAuthorizedKeysCommand("deploy")))
+	require.Len(t, keys, 2)
+
+	info, err := os.Stat(filepath.Dir(file))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	run(RemoveAuthorizedKeysCommand("deploy", []AuthorizedKey{alice}))
+	data, err := os.ReadFile(file) // #nosec G304 -- test file
+	require.NoError(t, err)
+	assert.Equal(t, "# keep\n"+ciPublicKey+"\n", string(data))
+	info, err = os.Stat(file)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Removing the last key leaves the comments
+	run(RemoveAuthorizedKeysCommand("deploy", []AuthorizedKey{ci}))
+	data, err = os.ReadFile(file) // #nosec G304 -- test file
+	require.NoError(t, err)
+	assert.Equal(t, "# keep\n", string(data))
+
+	cmd := exec.Command("sh", "-c", ListAuthorizedKeysCommand("nobody_here")) // #nosec G204 -- test command
+	output, err := cmd.CombinedOutput()
+	require.Error(t, err)
+	assert.Contains(t, string(output), "user nobody_here does not exist")
+}
+
+func TestCreateUserCommand(t *testing.T) {
+	command := CreateUserCommand("deploy", "/bin/bash")
+	assert.Contains(t, command, "useradd -m -s '/bin/bash' deploy && echo created")
+	assert.Contains(t, command, "adduser -D -s '/bin/bash' deploy && echo created")
+	assert.False(t, Explain(command, nil).Flagged)
+
+	_, run := fakeAccountHost(t)
+	assert.Equal(t, "exists", lastLine(run(CreateUserCommand(currentUser(t), ""))))
+}
+
+// currentUser is the name of the user running the tests
+func currentUser(t *testing.T) string {
+	t.Helper()
+	output, err := exec.Command("sh", "-c", "id -un").Output()
+	if err != nil {
+		t.Skip("id -un not available")
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestSSHClient_AuthorizedKeys(t *testing.T) {
+	var ran []string
+	conn := startExecServer(t, func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		ran = append(ran, command)
+		switch {
+		case strings.Contains(command, "cat \"$f\""):
+			_, _ = channel.Write([]byte(alicePublicKey + "\n" + ciPublicKey + "\n"))
+		case strings.Contains(command, "grep -qF"):
+			_, _ = channel.Write([]byte("present\n"))
+		case strings.Contains(command, "useradd"):
+			_, _ = channel.Write([]byte("created\n"))
+		}
+		return 0
+	})
+	client := &SSHClient{config: &Config{Host: "10.0.0.5", User: "deploy"}, client: conn}
+
+	keys, err := client.AuthorizedKeys("", Request{})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Contains(t, ran[0], "getent passwd deploy")
+
+	key, added, err := client.AddAuthorizedKey("ops", alicePublicKey, Request{})
+	require.NoError(t, err)
+	assert.False(t, added)
+	assert.Equal(t, aliceFingerprint, key.Fingerprint)
+	assert.Contains(t, ran[1], "getent passwd ops")
+
+	created, err := client.CreateUser("ops", "/bin/bash", Request{})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	// Revoking lists the keys first and only rewrites the file on a match
+	ran = nil
+	revoked, err := client.RevokeAuthorizedKeys("", aliceFingerprint, Request{})
+	require.NoError(t, err)
+	require.Len(t, revoked, 1)
+	assert.Equal(t, "alice@laptop", revoked[0].Comment)
+	require.Len(t, ran, 2)
+	assert.Contains(t, ran[1], "grep -vF -e 'AAAAC3NzaC1lZDI1NTE5AAAAIJUZR92WqbYB6FZpQ7ujn+yNkw0PbBmlfEOBuczEGIa/'")
+
+	ran = nil
+	revoked, err = client.RevokeAuthorizedKeys("", "nobody@nowhere", Request{})
+	require.NoError(t, err)
+	assert.Empty(t, revoked)
+	assert.Len(t, ran, 1)
+
+	_, err = client.RevokeAuthorizedKeys("", " ", Request{})
+	assert.ErrorContains(t, err, "fingerprint or comment is required")
+	_, err = client.CreateUser("", "", Request{})
+	assert.ErrorContains(t, err, "user name is required")
+	_, err = client.CreateUser("ops", "bash", Request{})
+	assert.ErrorContains(t, err, "invalid login shell")
+	_, _, err = client.AddAuthorizedKey("Bad Name", alicePublicKey, Request{})
+	assert.ErrorContains(t, err, "invalid user name")
+}
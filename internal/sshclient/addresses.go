@@ -0,0 +1,189 @@
+package sshclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// TailscaleAddress is the Config.Addresses entry that dials the tailnet
+	// IP of the host, looked up by its MagicDNS or machine name through the
+	// local Tailscale daemon. "tailscale:<name>" names the machine; plain
+	// "tailscale" uses the host alias, else its address.
+	TailscaleAddress = "tailscale"
+	// fallbackDialTimeout caps the dial of every address but the last, so a
+	// dead mesh route does not use up the whole timeout
+	fallbackDialTimeout = 5 * time.Second
+	// tailscaleStatusTTL is how long a tailnet status is reused
+	tailscaleStatusTTL = 30 * time.Second
+)
+
+// dialTargets returns the host:port addresses the dialer tries for config,
+// in order: Config.Addresses, then Host unless it was listed. Tailnet
+// entries that cannot be resolved are left out.
+func dialTargets(config *Config) []string {
+	port := config.Port
+	if port == "" {
+		port = DefaultSSHPort
+	}
+	var targets []string
+	add := func(address string) {
+		if address != "" && !slices.Contains(targets, address) {
+			targets = append(targets, address)
+		}
+	}
+	for _, entry := range config.Addresses {
+		address := strings.TrimSpace(entry)
+		if machine, ok := tailscaleMachine(address, config); ok {
+			ip, err := resolveTailscale(machine)
+			if err != nil {
+				logger.GetLogger().Debug("Skipping the tailnet address of %s: %v", machine, err)
+				continue
+			}
+			address = ip
+		}
+		if address != "" {
+			add(withPort(address, port))
+		}
+	}
+	add(net.JoinHostPort(config.Host, port))
+	return targets
+}
+
+// withPort adds port to address unless it carries one
+func withPort(address, port string) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(strings.Trim(address, "[]"), port)
+}
+
+// dialFirst connects to the first of targets that answers and returns the
+// connection with the address it reached
+func dialFirst(targets []string, timeout time.Duration) (net.Conn, string, error) {
+	var errs []error
+	for i, target := range targets {
+		attempt := timeout
+		if i < len(targets)-1 && attempt > fallbackDialTimeout {
+			attempt = fallbackDialTimeout
+		}
+		conn, err := net.DialTimeout("tcp", target, attempt)
+		if err == nil {
+			return conn, target, nil
+		}
+		if len(targets) == 1 {
+			return nil, "", err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", target, err))
+	}
+	return nil, "", errors.Join(errs...)
+}
+
+// tailscaleMachine reports whether an address entry is a tailnet entry and
+// which machine it names
+func tailscaleMachine(entry string, config *Config) (string, bool) {
+	if entry == TailscaleAddress {
+		if config.Alias != "" {
+			return config.Alias, true
+		}
+		return config.Host, true
+	}
+	machine, ok := strings.CutPrefix(entry, TailscaleAddress+":")
+	return machine, ok && machine != ""
+}
+
+// tailscalePeer is a machine of the tailnet as `tailscale status --json`
+// reports it
+type tailscalePeer struct {
+	HostName     string
+	DNSName      string
+	TailscaleIPs []string
+	Online       bool
+}
+
+// tailscaleStatusOutput runs `tailscale status --json`; tests replace it
+var tailscaleStatusOutput = func(ctx context.Context) ([]byte, error) {
+	return exec.CommandContext(ctx, "tailscale", "status", "--json").Output()
+}
+
+// tailscaleCache holds the machines of the last tailnet status
+var tailscaleCache struct {
+	sync.Mutex
+	peers   []tailscalePeer
+	fetched time.Time
+}
+
+// tailscalePeers returns the machines of the tailnet, this one included
+func tailscalePeers() ([]tailscalePeer, error) {
+	tailscaleCache.Lock()
+	defer tailscaleCache.Unlock()
+	if tailscaleCache.peers != nil && time.Since(tailscaleCache.fetched) < tailscaleStatusTTL {
+		return tailscaleCache.peers, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fallbackDialTimeout)
+	defer cancel()
+	output, err := tailscaleStatusOutput(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("tailscale status failed: %w", err)
+	}
+	var status struct {
+		Self *tailscalePeer
+		Peer map[string]*tailscalePeer
+	}
+	if err := json.Unmarshal(output, &status); err != nil {
+		return nil, fmt.Errorf("invalid tailscale status: %w", err)
+	}
+	peers := []tailscalePeer{}
+	if status.Self != nil {
+		self := *status.Self
+		self.Online = true
+		peers = append(peers, self)
+	}
+	for _, peer := range status.Peer {
+		if peer != nil {
+			peers = append(peers, *peer)
+		}
+	}
+	tailscaleCache.peers, tailscaleCache.fetched = peers, time.Now()
+	return peers, nil
+}
+
+// resolveTailscale returns the tailnet IP of the machine with the given
+// machine name, MagicDNS short name or MagicDNS name, IPv4 first
+func resolveTailscale(machine string) (string, error) {
+	peers, err := tailscalePeers()
+	if err != nil {
+		return "", err
+	}
+	for _, peer := range peers {
+		dnsName := strings.TrimSuffix(peer.DNSName, ".")
+		shortName, _, _ := strings.Cut(dnsName, ".")
+		if !strings.EqualFold(peer.HostName, machine) && !strings.EqualFold(dnsName, machine) && !strings.EqualFold(shortName, machine) {
+			continue
+		}
+		if !peer.Online {
+			return "", fmt.Errorf("%s is offline", machine)
+		}
+		for _, ip := range peer.TailscaleIPs {
+			if !strings.Contains(ip, ":") {
+				return ip, nil
+			}
+		}
+		if len(peer.TailscaleIPs) > 0 {
+			return peer.TailscaleIPs[0], nil
+		}
+		return "", fmt.Errorf("%s has no tailnet address", machine)
+	}
+	return "", fmt.Errorf("%s is not in the tailnet", machine)
+}
@@ -0,0 +1,164 @@
+package sshclient
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+const testTailscaleStatus = `{
+	"Self": {"HostName": "laptop", "DNSName": "laptop.tail1234.ts.net.", "TailscaleIPs": ["100.64.0.1", "fd7a:115c:a1e0::1"]},
+	"Peer": {
+		"nodekey:a": {"HostName": "web-01", "DNSName": "web-01.tail1234.ts.net.", "TailscaleIPs": ["fd7a:115c:a1e0::2", "100.64.0.2"], "Online": true},
+		"nodekey:b": {"HostName": "db-01", "DNSName": "db-01.tail1234.ts.net.", "TailscaleIPs": ["100.64.0.3"], "Online": false}
+	}
+}`
+
+// stubTailscaleStatus makes tailscale status report output for the test
+func stubTailscaleStatus(t *testing.T, output string) {
+	t.Helper()
+	previous := tailscaleStatusOutput
+	tailscaleStatusOutput = func(context.Context) ([]byte, error) { return []byte(output), nil }
+	resetCache := func() {
+		tailscaleCache.Lock()
+		tailscaleCache.peers = nil
+		tailscaleCache.Unlock()
+	}
+	resetCache()
+	t.Cleanup(func() {
+		tailscaleStatusOutput = previous
+		resetCache()
+	})
+}
+
+func TestResolveTailscale(t *testing.T) {
+	stubTailscaleStatus(t, testTailscaleStatus)
+
+	for _, machine := range []string{"web-01", "WEB-01", "web-01.tail1234.ts.net"} {
+		ip, err := resolveTailscale(machine)
+		require.NoError(t, err, machine)
+		assert.Equal(t, "100.64.0.2", ip, machine)
+	}
+
+	ip, err := resolveTailscale("laptop")
+	require.NoError(t, err)
+	assert.Equal(t, "100.64.0.1", ip)
+
+	_, err = resolveTailscale("db-01")
+	assert.ErrorContains(t, err, "offline")
+	_, err = resolveTailscale("mail")
+	assert.ErrorContains(t, err, "not in the tailnet")
+}
+
+func TestDialTargets(t *testing.T) {
+	stubTailscaleStatus(t, testTailscaleStatus)
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{
+			name:     "host only",
+			config:   Config{Host: "203.0.113.10"},
+			expected: []string{"203.0.113.10:22"},
+		},
+		{
+			name:     "addresses first, host last",
+			config:   Config{Host: "203.0.113.10", Port: "2222", Addresses: []string{"10.8.0.5", "10.9.0.5:22", "fd00::5"}},
+			expected: []string{"10.8.0.5:2222", "10.9.0.5:22", "[fd00::5]:2222", "203.0.113.10:2222"},
+		},
+		{
+			name:     "duplicates dropped",
+			config:   Config{Host: "203.0.113.10", Addresses: []string{"203.0.113.10", " 10.8.0.5 ", "10.8.0.5"}},
+			expected: []string{"203.0.113.10:22", "10.8.0.5:22"},
+		},
+		{
+			name:     "tailscale by alias",
+			config:   Config{Host: "203.0.113.10", Alias: "web-01", Addresses: []string{TailscaleAddress}},
+			expected: []string{"100.64.0.2:22", "203.0.113.10:22"},
+		},
+		{
+			name:     "tailscale by machine name",
+			config:   Config{Host: "203.0.113.10", Alias: "web", Addresses: []string{"tailscale:web-01.tail1234.ts.net"}},
+			expected: []string{"100.64.0.2:22", "203.0.113.10:22"},
+		},
+		{
+			name:     "offline machine skipped",
+			config:   Config{Host: "203.0.113.11", Alias: "db-01", Addresses: []string{TailscaleAddress, "10.8.0.6"}},
+			expected: []string{"10.8.0.6:22", "203.0.113.11:22"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, dialTargets(&tt.config))
+		})
+	}
+}
+
+func TestDialFirst_FallsBack(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	closed := closedAddress(t)
+
+	conn, dialed, err := dialFirst([]string{closed, listener.Addr().String()}, time.Second)
+	require.NoError(t, err)
+	_ = conn.Close()
+	assert.Equal(t, listener.Addr().String(), dialed)
+
+	_, _, err = dialFirst([]string{closed, closedAddress(t)}, time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), closed)
+}
+
+func TestConnectDirect_DialsAddresses(t *testing.T) {
+	addr := listenExecServer(t, func(string, ssh.Channel, <-chan struct{}) uint32 { return 0 })
+	_, closedPort, err := net.SplitHostPort(closedAddress(t))
+	require.NoError(t, err)
+
+	client, err := NewSSHClient(&Config{
+		Host:              "127.0.0.1",
+		Port:              closedPort,
+		Addresses:         []string{addr},
+		User:              "test",
+		Password:          "unused",
+		KnownHostsPath:    filepath.Join(t.TempDir(), "known_hosts"),
+		AcceptUnknownHost: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.ConnectDirect())
+	_ = client.ForceClose()
+}
+
+func TestPing_Addresses(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	_, closedPort, err := net.SplitHostPort(closedAddress(t))
+	require.NoError(t, err)
+
+	results := Ping([]*Config{
+		{Host: "127.0.0.1", Port: closedPort, Addresses: []string{listener.Addr().String()}},
+	}, PingOptions{Timeout: time.Second})
+
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Reachable())
+	assert.Equal(t, listener.Addr().String(), results[0].Address)
+}
+
+// closedAddress returns a local address nothing listens on
+func closedAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return addr
+}
@@ -0,0 +1,157 @@
+package sshclient
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/pkg/sftp"
+	"github.com/talkincode/sshmcp/pkg/errutil"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// AtomicTempSuffix names the sibling file an upload is written to before
+	// it is renamed over the destination
+	AtomicTempSuffix = ".sshx-tmp"
+	// BackupSuffix names the copy of the replaced file kept with
+	// Config.UploadBackup
+	BackupSuffix = ".bak"
+	// maxSymlinkDepth bounds how many symlinks are followed to find the file
+	// an upload replaces
+	maxSymlinkDepth = 40
+)
+
+// atomicWriteRemote writes a remote file so that readers see either the old
+// or the complete new content, never a truncated file: write fills a
+// temporary sibling, which is synced and then renamed over remotePath. The
+// new file takes mode, when it is set, or the permissions of the file it
+// replaces and, where allowed, that file's owner. A symlink at remotePath is
+// kept and the file it points to is replaced. With keepBackup the previous
+// content is copied to BackupSuffix first.
+func atomicWriteRemote(sftpClient *sftp.Client, remotePath string, keepBackup bool, mode os.FileMode, write func(io.Writer) (int64, error)) (int64, error) {
+	return atomicReplaceRemote(sftpClient, remotePath, keepBackup, func(_, tempPath string, previous os.FileInfo) (int64, error) {
+		return writeTempFile(sftpClient, tempPath, previous, mode, write)
+	})
+}
+
+// atomicReplaceRemote replaces remotePath like atomicWriteRemote, with fill
+// creating the temporary file. fill is given the file being replaced (the
+// target of a symlink at remotePath) and its info, nil when there is none.
+func atomicReplaceRemote(sftpClient *sftp.Client, remotePath string, keepBackup bool, fill func(target, tempPath string, previous os.FileInfo) (int64, error)) (written int64, err error) {
+	target, err := resolveRemoteSymlinks(sftpClient, remotePath)
+	if err != nil {
+		return 0, err
+	}
+	previous, err := sftpClient.Stat(target)
+	switch {
+	case err == nil:
+		if previous.IsDir() {
+			return 0, fmt.Errorf("%s is a directory", remotePath)
+		}
+	case os.IsNotExist(err):
+		previous = nil
+	default:
+		return 0, fmt.Errorf("failed to stat remote file: %w", err)
+	}
+
+	tempPath := target + AtomicTempSuffix
+	defer func() {
+		if err == nil {
+			return
+		}
+		if removeErr := sftpClient.Remove(tempPath); removeErr != nil && !os.IsNotExist(removeErr) {
+			logger.GetLogger().Warning("failed to remove %s: %v", tempPath, removeErr)
+		}
+	}()
+
+	if written, err = fill(target, tempPath, previous); err != nil {
+		return written, err
+	}
+	if keepBackup && previous != nil {
+		if err = copyRemoteFile(sftpClient, target, target+BackupSuffix); err != nil {
+			return written, fmt.Errorf("failed to back up %s: %w", target, err)
+		}
+	}
+	if err = replaceRemoteFile(sftpClient, tempPath, target); err != nil {
+		return written, fmt.Errorf("failed to move %s into place: %w", tempPath, err)
+	}
+	return written, nil
+}
+
+// writeTempFile creates tempPath with mode or the permissions of previous,
+// fills it with write and syncs it to disk when the server supports fsync
+func writeTempFile(sftpClient *sftp.Client, tempPath string, previous os.FileInfo, mode os.FileMode, write func(io.Writer) (int64, error)) (written int64, err error) {
+	file, err := sftpClient.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create remote file: %w", err)
+	}
+	defer errutil.HandleCloseError(&err, file)
+
+	// Restrict the permissions before any content is written
+	if mode == 0 && previous != nil {
+		mode = previous.Mode().Perm()
+	}
+	if mode != 0 {
+		if err = file.Chmod(mode); err != nil {
+			return 0, fmt.Errorf("failed to chmod %s: %w", tempPath, err)
+		}
+	}
+	if previous != nil {
+		if stat, ok := previous.Sys().(*sftp.FileStat); ok {
+			if chownErr := file.Chown(int(stat.UID), int(stat.GID)); chownErr != nil {
+				logger.GetLogger().Debug("keeping the owner of %s failed: %v", tempPath, chownErr)
+			}
+		}
+	}
+
+	if written, err = write(file); err != nil {
+		return written, fmt.Errorf("failed to upload file: %w", err)
+	}
+	if _, ok := sftpClient.HasExtension("fsync@openssh.com"); ok {
+		if err = file.Sync(); err != nil {
+			return written, fmt.Errorf("failed to sync %s: %w", tempPath, err)
+		}
+	}
+	return written, nil
+}
+
+// replaceRemoteFile renames from over to. Plain SFTP rename refuses to
+// overwrite, so servers without posix-rename briefly have no file at to.
+func replaceRemoteFile(sftpClient *sftp.Client, from, to string) error {
+	if _, ok := sftpClient.HasExtension("posix-rename@openssh.com"); ok {
+		return sftpClient.PosixRename(from, to)
+	}
+	if err := sftpClient.Remove(to); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return sftpClient.Rename(from, to)
+}
+
+// resolveRemoteSymlinks follows symlinks at remotePath to the file they point
+// to, so replacing it does not replace the link with a regular file
+func resolveRemoteSymlinks(sftpClient *sftp.Client, remotePath string) (string, error) {
+	target := remotePath
+	for range maxSymlinkDepth {
+		info, err := sftpClient.Lstat(target)
+		if os.IsNotExist(err) {
+			return target, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to stat remote file: %w", err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return target, nil
+		}
+		link, err := sftpClient.ReadLink(target)
+		if err != nil {
+			return "", fmt.Errorf("failed to read symlink %s: %w", target, err)
+		}
+		if !path.IsAbs(link) {
+			link = path.Join(path.Dir(target), link)
+		}
+		target = link
+	}
+	return "", fmt.Errorf("too many levels of symbolic links: %s", remotePath)
+}
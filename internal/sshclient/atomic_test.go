@@ -0,0 +1,78 @@
+package sshclient
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRemoteFile_Atomic(t *testing.T) {
+	client := startLocalSFTP(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.conf")
+
+	written, err := writeRemoteFile(client, path, []byte("port=80\n"), false, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 8, written)
+
+	require.NoError(t, os.Chmod(path, 0o640))
+	_, err = writeRemoteFile(client, path, []byte("port=8080\n"), true, 0)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "port=8080\n", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm(), "permissions of the replaced file are kept")
+
+	backup, err := os.ReadFile(path + BackupSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, "port=80\n", string(backup))
+	assert.NoFileExists(t, path+AtomicTempSuffix)
+}
+
+func TestAtomicWriteRemote_FailureKeepsOriginal(t *testing.T) {
+	client := startLocalSFTP(t)
+	path := filepath.Join(t.TempDir(), "app.conf")
+	require.NoError(t, os.WriteFile(path, []byte("port=80\n"), 0o600))
+
+	_, err := atomicWriteRemote(client, path, false, 0, func(w io.Writer) (int64, error) {
+		n, _ := w.Write([]byte("po")) //nolint:errcheck // the transfer fails anyway
+		return int64(n), errors.New("connection lost")
+	})
+	assert.ErrorContains(t, err, "connection lost")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "port=80\n", string(data))
+	assert.NoFileExists(t, path+AtomicTempSuffix)
+}
+
+func TestAtomicWriteRemote_Symlink(t *testing.T) {
+	client := startLocalSFTP(t)
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "available"), 0o755))
+	target := filepath.Join(dir, "available", "site")
+	link := filepath.Join(dir, "site")
+	require.NoError(t, os.WriteFile(target, []byte("old"), 0o644))
+	require.NoError(t, os.Symlink("available/site", link))
+
+	_, err := writeRemoteFile(client, link, []byte("new"), false, 0)
+	require.NoError(t, err)
+
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink, "the symlink is kept")
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	_, err = writeRemoteFile(client, dir, []byte("x"), false, 0)
+	assert.ErrorContains(t, err, "is a directory")
+}
@@ -13,7 +13,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/pkg/sftp"
 	"github.com/talkincode/sshmcp/pkg/errutil"
 	"github.com/talkincode/sshmcp/pkg/logger"
 	"golang.org/x/crypto/ssh"
@@ -27,6 +26,8 @@ const (
 	DefaultTimeout    = 30 * time.Second
 	SudoPrompt        = "[sudo] password"
 	PasswordPromptEnd = ": "
+	// CallerEnv names who requested a command in its remote environment
+	CallerEnv = "SSHX_CALLER"
 )
 
 // AuthMethod indicates which authentication mechanism was used for the SSH connection.
@@ -51,9 +52,32 @@ type Config struct {
 	Command     string
 	Mode        string
 	DialTimeout time.Duration
+	// Addresses are dialed in order before Host, e.g. tailnet or WireGuard
+	// addresses ("addr" or "addr:port", TailscaleAddress for the tailnet IP)
+	Addresses []string
+	// Keepalive sends keepalive@openssh.com requests at this interval and
+	// closes the connection after keepaliveMaxMissed unanswered ones (0 = off)
+	Keepalive time.Duration
+	// MaxRetries is the number of connection attempts made by the pool
+	// (0 = pool default)
+	MaxRetries int
+	// KeyPassphraseKey names the keyring entry holding the passphrase of
+	// KeyPath, for keys protected by one
+	KeyPassphraseKey string
 
 	SafetyCheck bool
 	Force       bool
+	// Safety sets the severity of flagged commands (nil blocks them all)
+	Safety *SafetyPolicy
+	// SafetyFinding is set by SafetyCheckMiddleware when a flagged command
+	// runs because Safety rates it below block or Force overrides the block
+	SafetyFinding *SafetyFinding
+	// ForceReason explains why Force overrides a blocked command. Without
+	// one the command stays blocked; it is written to the audit log.
+	ForceReason string
+	// ConfirmForce, when set, must approve each blocked command Force
+	// overrides (the CLI asks at the terminal) and may supply the reason
+	ConfirmForce func(blocked *BlockedCommandError) (reason string, err error)
 	// AcceptUnknownHost controls whether sshx will automatically add
 	// previously unseen host keys to the user's known_hosts file.
 	AcceptUnknownHost bool
@@ -62,10 +86,39 @@ type Config struct {
 	AllowInsecureHostKey bool
 	// KnownHostsPath allows overriding the path to the known_hosts file.
 	KnownHostsPath string
+	// RevokedKeysPath points to a file of revoked host keys (authorized_keys
+	// format, like OpenSSH RevokedHostKeys). Servers presenting them are refused.
+	RevokedKeysPath string
+	// TrustTTL, when non-zero, expires host keys that sshx trusted
+	// automatically so they must be re-verified after this period.
+	TrustTTL time.Duration
+	// AcceptedHostsPath, when set, receives automatically accepted host keys
+	// instead of KnownHostsPath. Both files are consulted for verification.
+	AcceptedHostsPath string
+	// RecordPath, when set, records command output to an asciinema v2 file.
+	RecordPath string
+	// Alias is the configured host name the target was resolved from, if any.
+	Alias string
+	// Source identifies the caller (e.g. "cli" or "mcp") for middleware.
+	Source string
+	// Client is the name/version an MCP client declared in initialize.
+	Client string
+	// Caller is the authenticated principal (the MCP token policy name).
+	Caller string
 
 	SftpAction string
 	LocalPath  string
 	RemotePath string
+	// UploadTemplate renders LocalPath as a Go template per host before upload
+	UploadTemplate bool
+	// RemoveBackup makes the remove action move files to ~/.sshx-trash
+	// instead of deleting them
+	RemoveBackup bool
+	// UploadBackup keeps the file an upload replaces as <path>.bak
+	UploadBackup bool
+	// DeltaUpload sends only the blocks of an upload that differ from the
+	// file it replaces (see uploadDelta)
+	DeltaUpload bool
 
 	PasswordAction string
 	PasswordKey    string
@@ -76,14 +129,216 @@ type Config struct {
 	HostName        string
 	HostDescription string
 	HostType        string
+	HostTags        []string
+	// HostFind searches hosts by metadata, e.g. "env=prod,rack=r1*"
+	HostFind string
+	// HostGroupBy groups host listings by a metadata key
+	HostGroupBy string
+	// SyncSource selects the inventory sources of --host-sync by name or
+	// provider (empty = all)
+	SyncSource string
+	// DiscoverCIDR is the subnet --discover scans (empty = the local network)
+	DiscoverCIDR string
+	// HostFields holds host fields given explicitly on the command line, by
+	// settings JSON name, so updates can tell "not provided" from defaults
+	HostFields map[string]string
+	// SSHConfigPath is the OpenSSH config file used by host import/export
+	SSHConfigPath string
+	// Overwrite replaces existing hosts on import
+	Overwrite bool
+
+	// Connection pool management fields
+	PoolAction string
+	// Hosts is a comma-separated list of configured host names or group tags
+	Hosts string
+	// PingICMP adds an ICMP ping to reachability checks
+	PingICMP bool
+	// SkipUnreachable leaves the hosts the liveness cache knows to be down
+	// out of batch operations; OnlyUnreachable selects only those
+	SkipUnreachable bool
+	OnlyUnreachable bool
+
+	// Known hosts management fields
+	KnownHostsAction string
+
+	// Remote account management fields (--add-user, --push-key, --list-keys
+	// and --revoke-key)
+	AccountAction string
+	// Account is the remote user to create or whose authorized_keys are
+	// managed (empty = User)
+	Account string
+	// AccountShell is the login shell of a user created with --add-user
+	AccountShell string
+	// PublicKeyPath is the local public key file --push-key installs
+	PublicKeyPath string
+	// KeyMatch is the SHA256 fingerprint or comment of the keys --revoke-key
+	// removes
+	KeyMatch string
+
+	// Validate is a command run on each host after a multi-host upload
+	Validate string
+	// CollectCommand is a command whose output is gathered from many hosts
+	CollectCommand string
+	// AggregateOutput groups the hosts of CollectCommand by identical output
+	// and shows the outliers as diffs against the most common output
+	AggregateOutput bool
+	// Rollout stages multi-host uploads and CollectCommand runs
+	Rollout RolloutOptions
+	// Rollback is a command run on each host where a multi-host upload or
+	// CollectCommand failed, to undo it
+	Rollback string
+	// ManifestPath is the local file a multi-host upload or --collect
+	// writes its transfer manifest to
+	ManifestPath string
+	// RemoteManifest is the path a multi-host upload writes its transfer
+	// manifest to on every host it updated
+	RemoteManifest string
+	// RollbackFor marks the command as the rollback of this failed step;
+	// it is written to the audit log
+	RollbackFor string
+	// MaxBytes caps the data collected from each host (0 uses the default)
+	MaxBytes int64
+	// MaxOutputBytes caps captured command output; 0 uses
+	// DefaultMaxOutputBytes and a negative value disables the limit.
+	MaxOutputBytes int64
+	// OutputSpillPath, when set, receives the full output of a command whose
+	// captured output was truncated.
+	OutputSpillPath string
+	// BinaryOutput captures stdout byte for byte: no PTY is requested and
+	// stderr is kept apart (see SSHClient.Stderr) instead of being appended
+	BinaryOutput bool
+	// ScriptTimeout kills a script that runs longer than this, returning the
+	// output captured so far (0 = no limit)
+	ScriptTimeout time.Duration
+	// CheckSyntax parses a script with its interpreter (bash -n, perl -c,
+	// ...) after upload and refuses to run it when that fails
+	CheckSyntax bool
+	// RunAs runs commands and scripts as this remote user through sudo -u,
+	// after logging in as User
+	RunAs string
+	// TempDir is the remote directory scripts are uploaded to (default /tmp)
+	TempDir string
+	// Container, when set, runs commands inside this container with
+	// docker, podman or nerdctl exec
+	Container string
+	// WorkDir, when set, is the remote directory commands and scripts run
+	// in (inside Container when that is set)
+	WorkDir string
+	// LoginShell runs commands and scripts through a login shell (bash -lc
+	// unless Shell is set), so the environment set up by the login profile
+	// is available
+	LoginShell bool
+	// Shell, when set, is the shell commands run in (see Shells) instead of
+	// the login shell of the remote user; powershell and pwsh run them as
+	// encoded PowerShell scripts for Windows hosts
+	Shell string
+	// Device, when set, drives the host as a network device: commands are
+	// typed into a shell channel and SFTP is unavailable
+	Device *DeviceProfile
+	// Env holds environment variables exported for every command and
+	// script (e.g. HTTP_PROXY); the names must pass ValidateEnvName
+	Env map[string]string
+	// UploadOwnership is the mode, owner and group given to the files
+	// uploads write (zero: the permissions of a replaced file are kept)
+	UploadOwnership FileOwnership
+	// ForwardAgent forwards the local ssh-agent to commands run by
+	// ExecuteCommand (--forward-agent)
+	ForwardAgent bool
+	// ForwardX11 forwards the X11 connections of those commands to the
+	// local display (-X)
+	ForwardX11 bool
+	// ContainerRuntime is the container CLI used for Container (default:
+	// the first of docker, podman and nerdctl found on the remote host)
+	ContainerRuntime string
+	// Sandbox, when set, confines commands and scripts with a clean
+	// environment, a time limit and resource limits
+	Sandbox *Sandbox
+	// MaxParallel caps how many commands, scripts and database transfers
+	// this process runs on the host at once; others wait for a free slot.
+	// 1 serializes them (0 = no limit).
+	MaxParallel int
+	// PathPolicy, when set, restricts the remote paths of SFTP operations
+	PathPolicy *PathPolicy
+	// WatchInterval, when set, re-runs the CLI command at this interval and
+	// prints what changed (--watch)
+	WatchInterval time.Duration
+	// WatchUntil ends a watch once the output matches this regular
+	// expression (--until)
+	WatchUntil string
+	// WatchCount ends a watch after this many runs (0 = until interrupted)
+	WatchCount int
+	// WaitFor is the condition --wait-for blocks on (e.g. "port:5432")
+	WaitFor string
+	// WaitTimeout bounds --wait-for (0 = DefaultWaitTimeout)
+	WaitTimeout time.Duration
+	// WaitInterval is the pause between --wait-for checks (0 =
+	// DefaultWaitInterval)
+	WaitInterval time.Duration
+	// InteractScript is the expect script file --interact answers the
+	// command's prompts with ("-" reads it from stdin)
+	InteractScript string
+	// Reboot holds the options of --reboot; the wait for the host to return
+	// takes WaitTimeout and WaitInterval
+	Reboot RebootOptions
+	// RebootConfirm is the host name given with --confirm, which --reboot
+	// asks for at the terminal otherwise
+	RebootConfirm string
+	// CleanupAge is how old leftover temp files must be for --cleanup-temp
+	CleanupAge time.Duration
+	// TrashPurgeAge is how old removals must be for --trash-purge, e.g.
+	// "7d" (empty = all)
+	TrashPurgeAge string
+	// ScriptArgs are the arguments passed to a script run from the CLI
+	ScriptArgs []string
+	// StreamOutput, when set, receives script output as it is produced
+	StreamOutput io.Writer
+	// OutputTap, when set, also receives the output of commands as it
+	// arrives; middleware sets it to observe output (see EventsMiddleware)
+	OutputTap io.Writer
+	// Progress, when set, receives the progress of SFTP uploads and
+	// downloads (bytes) and of scripts (upload, run, latest output line)
+	Progress ProgressFunc
+	// JSONOutput prints CLI reports (host tests) as JSON
+	JSONOutput bool
+	// Verbosity is the CLI log verbosity: -1 for --quiet, 1 for -v and 2
+	// for -vv (connection protocol details)
+	Verbosity int
+	// ControlPersist, when set, runs CLI commands through a background
+	// control master that keeps connections open for reuse by later calls,
+	// exiting after this long without a request (--control-persist)
+	ControlPersist time.Duration
 }
 
 // SSHClient wraps an ssh.Client with optional pooled and sftp helpers.
+// Once connected it is safe for concurrent use: each Request runs on its own
+// copy of the Config over the shared connection.
 type SSHClient struct {
 	config         *Config
 	client         *ssh.Client
-	sftpClient     *sftp.Client
 	authMethodUsed AuthMethod
+	connectStats   ConnectStats
+	forwarding     *forwarding
+	// secret is the password registered for redaction until Close
+	secret string
+}
+
+// ConnectStats describes the connection made by ConnectDirect
+type ConnectStats struct {
+	// DialLatency is the time taken to open the TCP connection
+	DialLatency time.Duration
+	// AuthLatency covers the SSH handshake, host key check and
+	// authentication
+	AuthLatency time.Duration
+	// ServerVersion is the identification string the server sent
+	ServerVersion string
+	// Banner is the pre-authentication banner, if the server sent one
+	Banner string
+}
+
+// ConnectStats returns the latencies and server details of the last
+// ConnectDirect. With a password fallback they describe the final attempt.
+func (c *SSHClient) ConnectStats() ConnectStats {
+	return c.connectStats
 }
 
 // AuthMethodUsed returns the authentication method used for the current connection.
@@ -131,7 +386,18 @@ func getHostKeyCallback(cfg *Config) (ssh.HostKeyCallback, error) {
 		return nil, err
 	}
 
-	hostKeyCallback, err := knownhosts.New(knownHostsPath)
+	// Keys accepted automatically go to a separate trust store when configured
+	trustFiles := []string{knownHostsPath}
+	acceptPath := knownHostsPath
+	if cfg.AcceptedHostsPath != "" && cfg.AcceptedHostsPath != knownHostsPath {
+		if err := ensureKnownHostsFile(cfg.AcceptedHostsPath); err != nil {
+			return nil, err
+		}
+		trustFiles = append(trustFiles, cfg.AcceptedHostsPath)
+		acceptPath = cfg.AcceptedHostsPath
+	}
+
+	hostKeyCallback, err := knownhosts.New(trustFiles...)
 	if err != nil {
 		if cfg.AllowInsecureHostKey {
 			lg.Warning("Failed to load known_hosts from %s: %v", knownHostsPath, err)
@@ -142,6 +408,11 @@ func getHostKeyCallback(cfg *Config) (ssh.HostKeyCallback, error) {
 		return nil, fmt.Errorf("failed to load known_hosts from %s: %w", knownHostsPath, err)
 	}
 
+	revokedKeys, err := loadRevokedKeys(cfg.RevokedKeysPath)
+	if err != nil {
+		return nil, err
+	}
+
 	var callbackMu sync.Mutex
 
 	// Wrap the callback to handle key verification errors gracefully
@@ -149,11 +420,28 @@ func getHostKeyCallback(cfg *Config) (ssh.HostKeyCallback, error) {
 		callbackMu.Lock()
 		defer callbackMu.Unlock()
 
+		if isRevoked(revokedKeys, key) {
+			return &HostKeyRevokedError{Host: hostname, Source: cfg.RevokedKeysPath}
+		}
+
 		err := hostKeyCallback(hostname, remote, key)
 		if err == nil {
+			if cfg.TrustTTL > 0 {
+				patterns := normalizeHostPatterns(hostname, remote)
+				for _, path := range trustFiles {
+					if since, ok := trustedAt(path, patterns, key); ok && time.Since(since) > cfg.TrustTTL {
+						return &HostKeyTrustExpiredError{Host: hostname, TrustedAt: since, Path: path}
+					}
+				}
+			}
 			return nil
 		}
 
+		var revokedErr *knownhosts.RevokedError
+		if errors.As(err, &revokedErr) {
+			return &HostKeyRevokedError{Host: hostname, Source: revokedErr.Revoked.Filename}
+		}
+
 		var keyErr *knownhosts.KeyError
 		if !errors.As(err, &keyErr) {
 			return err
@@ -173,11 +461,11 @@ func getHostKeyCallback(cfg *Config) (ssh.HostKeyCallback, error) {
 			if len(hostPatterns) == 0 {
 				hostPatterns = []string{hostname}
 			}
-			if appendErr := appendHostKey(knownHostsPath, hostPatterns, key); appendErr != nil {
+			if appendErr := appendHostKey(acceptPath, hostPatterns, key); appendErr != nil {
 				return fmt.Errorf("failed to record new host key for %s: %w", hostname, appendErr)
 			}
-			lg.Success("Trusted new host %s and saved its key to %s", hostname, knownHostsPath)
-			freshCallback, reloadErr := knownhosts.New(knownHostsPath)
+			lg.Success("Trusted new host %s and saved its key to %s", hostname, acceptPath)
+			freshCallback, reloadErr := knownhosts.New(trustFiles...)
 			if reloadErr != nil {
 				return fmt.Errorf("failed to reload known_hosts after adding %s: %w", hostname, reloadErr)
 			}
@@ -223,7 +511,12 @@ func appendHostKey(path string, hostnames []string, key ssh.PublicKey) (err erro
 	if len(hostnames) == 0 {
 		return fmt.Errorf("no hostnames provided for known_hosts entry")
 	}
-	line := knownhosts.Line(hostnames, key)
+	// Tag the entry so its trust can expire (see Config.TrustTTL)
+	return appendKnownHostLine(path, knownhosts.Line(hostnames, key)+" "+trustedMarker+time.Now().UTC().Format(time.RFC3339))
+}
+
+// appendKnownHostLine appends a raw line to a known_hosts file
+func appendKnownHostLine(path, line string) (err error) {
 	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600) // #nosec G304 -- caller controls path and permissions
 	if os.IsNotExist(err) {
 		if ensureErr := ensureKnownHostsFile(path); ensureErr != nil {
@@ -282,6 +575,9 @@ func NewSSHClient(config *Config) (*SSHClient, error) {
 	if config.User == "" {
 		config.User = DefaultSSHUser
 	}
+	// Never let the password show up in logs while the client is in use
+	logger.RegisterSecret(config.Password)
+
 	// Default to key authentication unless explicitly disabled
 	if !config.UseKeyAuth {
 		config.KeyPath = ""
@@ -293,7 +589,7 @@ func NewSSHClient(config *Config) (*SSHClient, error) {
 		}
 	}
 
-	return &SSHClient{config: config, authMethodUsed: AuthMethodUnknown}, nil
+	return &SSHClient{config: config, authMethodUsed: AuthMethodUnknown, secret: config.Password}, nil
 }
 
 // Connect establishes an SSH connection (prefers using connection pool)
@@ -331,7 +627,7 @@ func (c *SSHClient) ConnectDirect() error {
 		}
 
 		if key, err := os.ReadFile(keyPath); err == nil { //nolint:gosec // G304: key path is provided by user
-			signer, signerErr := ssh.ParsePrivateKey(key)
+			signer, signerErr := ParsePrivateKey(key, c.config.KeyPassphraseKey)
 			if signerErr == nil {
 				keyAuthMethods = append(keyAuthMethods, ssh.PublicKeys(signer))
 				lg.Debug("Using SSH key: %s", keyPath)
@@ -358,28 +654,53 @@ func (c *SSHClient) ConnectDirect() error {
 	}
 
 	dialWithAuth := func(methods []ssh.AuthMethod) (*ssh.Client, error) {
+		c.connectStats = ConnectStats{}
 		sshConfig := &ssh.ClientConfig{
 			User:            c.config.User,
 			Auth:            methods,
-			HostKeyCallback: hostKeyCallback,
-			Timeout:         timeout,
+			HostKeyCallback: traceHostKey(hostKeyCallback),
+			BannerCallback: func(message string) error {
+				c.connectStats.Banner = message
+				return nil
+			},
+			Timeout: timeout,
 		}
 
 		addr := net.JoinHostPort(c.config.Host, c.config.Port)
 		lg.Debug("Connecting to %s@%s...", c.config.User, addr)
+		lg.Trace("Dial timeout %s, keepalive %s, %d auth method(s)", timeout, c.config.Keepalive, len(methods))
 
-		conn, err := net.DialTimeout("tcp", addr, timeout)
+		// The host key is verified against the configured address whichever
+		// address answers, so every route has to reach the same machine
+		started := time.Now()
+		conn, dialed, err := dialFirst(dialTargets(c.config), timeout)
 		if err != nil {
 			return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
 		}
+		c.connectStats.DialLatency = time.Since(started)
+		if dialed != addr {
+			lg.Debug("Reached %s through %s", addr, dialed)
+		}
 
+		started = time.Now()
 		sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
 		if err != nil {
 			_ = conn.Close() //nolint:errcheck
 			return nil, err
 		}
+		c.connectStats.AuthLatency = time.Since(started)
+		c.connectStats.ServerVersion = string(sshConn.ServerVersion())
+		lg.Trace("Server version %s, client version %s", sshConn.ServerVersion(), sshConn.ClientVersion())
 
-		return ssh.NewClient(sshConn, chans, reqs), nil
+		client := ssh.NewClient(sshConn, chans, reqs)
+		if c.forwarding, err = startForwarding(client, c.config); err != nil {
+			_ = client.Close() //nolint:errcheck
+			return nil, err
+		}
+		if c.config.Keepalive > 0 {
+			go keepAlive(client, c.config.Keepalive)
+		}
+		return client, nil
 	}
 
 	if len(keyAuthMethods) > 0 {
@@ -425,17 +746,39 @@ func shouldFallbackToPassword(err error, hadKeyAuth bool, hasPassword bool) bool
 	return errors.As(err, &serverErr)
 }
 
-// ExecuteCommand executes a command
-func (c *SSHClient) ExecuteCommand() (err error) {
-	lg := logger.GetLogger()
-
-	if c.config.SafetyCheck && !c.config.Force {
-		if validateErr := ValidateCommand(c.config.Command); validateErr != nil {
-			return validateErr
+// ExecuteCommand executes a command, streaming its output to the terminal
+func (c *SSHClient) ExecuteCommand(req Request) error {
+	op := c.newOperation(req)
+	_, err := registeredChain(func(*Config) (string, error) {
+		defer acquireHostSlot(op.config)()
+		err := op.executeCommand()
+		// EOF is a normal session close signal, not an error
+		if err != nil && errutil.IsEOFError(err) {
+			return "", nil
 		}
-	} else if c.config.Force {
-		lg.Warning("Safety check skipped (--force mode)")
+		return "", err
+	})(op.config)
+	return err
+}
+
+// executeCommand runs the command with a PTY once middleware has approved it
+func (c *operation) executeCommand() (err error) {
+	if err = checkShell(c.config); err != nil {
+		return err
+	}
+	if err = checkDevice(c.config); err != nil {
+		return err
 	}
+	if err = c.startRecording(); err != nil {
+		return err
+	}
+	defer c.stopRecording()
+	if c.config.Device != nil {
+		output, err := c.executeOnDevice()
+		fmt.Print(output)
+		return err
+	}
+	defer c.invalidateSudo()
 
 	session, err := c.client.NewSession()
 	if err != nil {
@@ -443,21 +786,53 @@ func (c *SSHClient) ExecuteCommand() (err error) {
 	}
 	// Use new error handling mechanism that automatically ignores common errors like EOF
 	defer errutil.HandleCloseError(&err, session)
+	if err = c.forwarding.request(session); err != nil {
+		return err
+	}
 
-	if c.config.Password != "" && strings.Contains(c.config.Command, "sudo") {
+	if c.config.Password != "" && c.usesSudo() {
 		return c.executeInteractive(session)
 	}
 
 	return c.executeWithPTY(session)
-} // ExecuteCommandWithOutput executes a command and returns the output
-func (c *SSHClient) ExecuteCommandWithOutput() (output string, err error) {
+}
+
+// ExecuteCommandWithOutput executes a command and returns the output
+func (c *SSHClient) ExecuteCommandWithOutput(req Request) (string, error) {
+	result, err := c.Execute(req)
+	return result.Output, err
+}
+
+// Execute executes a command and returns its output along with the separate
+// stderr of binary output and whether the output was truncated
+func (c *SSHClient) Execute(req Request) (Result, error) {
+	op := c.newOperation(req)
+	output, err := registeredChain(func(*Config) (string, error) {
+		defer acquireHostSlot(op.config)()
+		return op.executeCommandWithOutput()
+	})(op.config)
+	return Result{Output: output, Stderr: op.stderr, Truncated: op.outputTruncated, Safety: op.config.SafetyFinding}, err
+}
+
+// executeCommandWithOutput runs the command and captures its output once
+// middleware has approved it
+func (c *operation) executeCommandWithOutput() (output string, err error) {
 	lg := logger.GetLogger()
 
-	if c.config.SafetyCheck && !c.config.Force {
-		if validateErr := ValidateCommand(c.config.Command); validateErr != nil {
-			return "", validateErr
-		}
+	if err = checkShell(c.config); err != nil {
+		return "", err
+	}
+	if err = checkDevice(c.config); err != nil {
+		return "", err
+	}
+	if err = c.startRecording(); err != nil {
+		return "", err
+	}
+	defer c.stopRecording()
+	if c.config.Device != nil {
+		return c.executeOnDevice()
 	}
+	defer c.invalidateSudo()
 
 	session, err := c.client.NewSession()
 	if err != nil {
@@ -473,29 +848,38 @@ func (c *SSHClient) ExecuteCommandWithOutput() (output string, err error) {
 		ssh.TTY_OP_OSPEED: 14400,
 	}
 
-	if ptyErr := session.RequestPty("xterm", 80, 40, modes); ptyErr != nil {
-		// PTY request failed, try without it
-		lg.Warning("failed to request PTY: %v", ptyErr)
+	// A PTY would translate line endings and merge stderr into stdout
+	if !c.config.BinaryOutput {
+		if ptyErr := session.RequestPty("xterm", 80, 40, modes); ptyErr != nil {
+			// PTY request failed, try without it
+			lg.Warning("failed to request PTY: %v", ptyErr)
+		}
 	}
 
-	var stdout, stderr bytes.Buffer
-	session.Stdout = &stdout
-	session.Stderr = &stderr
+	capture := newOutputCapture(c.config)
+	stdoutWriter, stderrWriter := capture.writers()
+	session.Stdout = c.tee(stdoutWriter)
+	session.Stderr = c.tee(stderrWriter)
 
 	var execErr error
-	if c.config.Password != "" && strings.Contains(c.config.Command, "sudo") {
-		actualCmd := strings.TrimPrefix(c.config.Command, "sudo ")
-		actualCmd = strings.TrimSpace(actualCmd)
-		finalCmd := fmt.Sprintf(`printf '%%s\n' '%s' | sudo -S %s`, c.config.Password, actualCmd)
-
-		execErr = session.Run(finalCmd)
+	if c.config.Password != "" && c.usesSudo() {
+		if execErr = feedPassword(session, c.config.Password); execErr == nil {
+			execErr = session.Run(c.sandboxed(c.sudoCommandLine()))
+		}
 	} else {
-		execErr = session.Run(c.config.Command)
+		execErr = session.Run(c.sandboxed(c.commandLine()))
 	}
 
 	// Build output
-	output = stdout.String()
-	stderrStr := stderr.String()
+	output, stderrStr := capture.finish()
+	c.outputTruncated = capture.truncated()
+	if c.outputTruncated {
+		lg.Warning("output of '%s' exceeded the output limit and was truncated", c.config.Command)
+	}
+
+	if c.config.BinaryOutput {
+		return c.binaryResult(output, stderrStr, execErr)
+	}
 
 	// Use enhanced error handling
 	if execErr != nil {
@@ -514,8 +898,130 @@ func (c *SSHClient) ExecuteCommandWithOutput() (output string, err error) {
 	return output, nil
 }
 
+// binaryResult returns raw stdout, refusing output that was truncated since
+// the truncation marker would corrupt it
+func (c *operation) binaryResult(stdout, stderr string, execErr error) (string, error) {
+	c.stderr = stderr
+	if c.outputTruncated {
+		limit := c.config.MaxOutputBytes
+		if limit == 0 {
+			limit = DefaultMaxOutputBytes
+		}
+		message := fmt.Sprintf("binary output exceeds the output limit of %d bytes", limit)
+		if c.config.OutputSpillPath != "" {
+			message += "; full output saved to " + c.config.OutputSpillPath
+		}
+		return "", errors.New(message)
+	}
+	if execErr != nil {
+		// Binary stdout is useless in an error message, so only stderr is shown
+		if enhancedErr := errutil.EnhanceError(execErr, "", stderr); enhancedErr != nil {
+			return "", enhancedErr
+		}
+	}
+	return stdout, nil
+}
+
+// invalidateSudo runs `sudo -k` after a privileged command when sudo reset
+// is enabled, so cached sudo credentials do not outlive the command
+func (c *operation) invalidateSudo() {
+	if !sudoReset.Load() || !c.usesSudo() || c.client == nil {
+		return
+	}
+
+	session, err := c.client.NewSession()
+	if err != nil {
+		logger.GetLogger().Debug("failed to open session for sudo -k: %v", err)
+		return
+	}
+	defer func() {
+		_ = errutil.SafeClose(session) //nolint:errcheck
+	}()
+
+	if err := session.Run("sudo -k"); err != nil {
+		logger.GetLogger().Debug("sudo -k failed on %s: %v", c.config.Host, err)
+	}
+}
+
+// startRecording starts an asciinema recording when RecordPath is configured
+func (c *operation) startRecording() error {
+	if c.config.RecordPath == "" || c.recorder != nil {
+		return nil
+	}
+
+	recorder, err := CreateRecording(c.config.RecordPath, CastHeader{
+		Command: c.config.Command,
+		Title:   fmt.Sprintf("%s@%s", c.config.User, c.config.Host),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start recording: %w", err)
+	}
+	c.recorder = recorder
+	logger.GetLogger().Debug("Recording session to %s", c.config.RecordPath)
+	return nil
+}
+
+// stopRecording finishes the active recording, if any
+func (c *operation) stopRecording() {
+	if c.recorder == nil {
+		return
+	}
+	if err := c.recorder.Close(); err != nil {
+		logger.GetLogger().Warning("failed to close recording %s: %v", c.config.RecordPath, err)
+	}
+	c.recorder = nil
+}
+
+// tee duplicates session output into the active recording and OutputTap
+func (c *operation) tee(w io.Writer) io.Writer {
+	writers := []io.Writer{w}
+	if c.recorder != nil {
+		writers = append(writers, c.recorder)
+	}
+	if c.config.OutputTap != nil {
+		writers = append(writers, c.config.OutputTap)
+	}
+	if len(writers) == 1 {
+		return w
+	}
+	return io.MultiWriter(writers...)
+}
+
+// recordInput duplicates what is typed into a shell's stdin into the active
+// recording as input events
+func (c *operation) recordInput(w io.Writer) io.Writer {
+	if c.recorder == nil {
+		return w
+	}
+	return &inputRecorder{w: w, recorder: c.recorder}
+}
+
+// inputRecorder records the bytes written through it as input events
+type inputRecorder struct {
+	w        io.Writer
+	recorder *Recorder
+}
+
+func (r *inputRecorder) Write(p []byte) (int, error) {
+	n, err := r.w.Write(p)
+	if n > 0 {
+		if recErr := r.recorder.Input(p[:n]); recErr != nil {
+			logger.GetLogger().Warning("failed to record input: %v", recErr)
+		}
+	}
+	return n, err
+}
+
+// traceHostKey logs the key presented by the server before verifying it
+func traceHostKey(callback ssh.HostKeyCallback) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		logger.GetLogger().Trace("Host key for %s (%s): %s %s", hostname, remote, key.Type(), ssh.FingerprintSHA256(key))
+		return callback(hostname, remote, key)
+	}
+}
+
 // executeWithPTY executes a command using PTY
-func (c *SSHClient) executeWithPTY(session *ssh.Session) error {
+func (c *operation) executeWithPTY(session *ssh.Session) error {
 	lg := logger.GetLogger()
 	modes := ssh.TerminalModes{
 		ssh.ECHO:          0,
@@ -529,12 +1035,13 @@ func (c *SSHClient) executeWithPTY(session *ssh.Session) error {
 	}
 
 	var stdout, stderr bytes.Buffer
-	session.Stdout = &stdout
-	session.Stderr = &stderr
+	session.Stdout = c.tee(&stdout)
+	session.Stderr = c.tee(&stderr)
 
-	lg.Debug("Executing (with PTY): %s", c.config.Command)
+	command := c.sandboxed(c.commandLine())
+	lg.Debug("Executing (with PTY): %s", command)
 
-	if err := session.Run(c.config.Command); err != nil && !errutil.IsEOFError(err) {
+	if err := session.Run(command); err != nil && !errutil.IsEOFError(err) {
 		// Only report non-EOF errors
 		if stderr.Len() > 0 {
 			fmt.Fprintf(os.Stderr, "STDERR:\n%s", stderr.String())
@@ -553,15 +1060,16 @@ func (c *SSHClient) executeWithPTY(session *ssh.Session) error {
 }
 
 // executeNormal executes a normal command (without PTY)
-func (c *SSHClient) executeNormal(session *ssh.Session) error {
+func (c *operation) executeNormal(session *ssh.Session) error {
 	lg := logger.GetLogger()
 	var stdout, stderr bytes.Buffer
-	session.Stdout = &stdout
-	session.Stderr = &stderr
+	session.Stdout = c.tee(&stdout)
+	session.Stderr = c.tee(&stderr)
 
-	lg.Debug("Executing: %s", c.config.Command)
+	command := c.sandboxed(c.commandLine())
+	lg.Debug("Executing: %s", command)
 
-	if err := session.Run(c.config.Command); err != nil {
+	if err := session.Run(command); err != nil {
 		if stderr.Len() > 0 {
 			fmt.Fprintf(os.Stderr, "STDERR:\n%s", stderr.String())
 		}
@@ -579,23 +1087,23 @@ func (c *SSHClient) executeNormal(session *ssh.Session) error {
 }
 
 // executeInteractive executes an interactive command (supports auto sudo password input)
-func (c *SSHClient) executeInteractive(session *ssh.Session) error {
+func (c *operation) executeInteractive(session *ssh.Session) error {
 	lg := logger.GetLogger()
-	var finalCmd string
+	finalCmd := c.commandLine()
 	if c.config.Password != "" {
 		lg.Info("Auto-filling sudo password...")
-		actualCmd := strings.TrimPrefix(c.config.Command, "sudo ")
-		actualCmd = strings.TrimSpace(actualCmd)
-		finalCmd = fmt.Sprintf(`printf '%%s\n' '%s' | sudo -S %s`, c.config.Password, actualCmd)
-	} else {
-		finalCmd = c.config.Command
+		if err := feedPassword(session, c.config.Password); err != nil {
+			return err
+		}
+		finalCmd = c.sudoCommandLine()
 	}
+	finalCmd = c.sandboxed(finalCmd)
 
 	var stdout, stderr bytes.Buffer
-	session.Stdout = &stdout
-	session.Stderr = &stderr
+	session.Stdout = c.tee(&stdout)
+	session.Stderr = c.tee(&stderr)
 
-	lg.Debug("Executing (no PTY): %s", "sudo command")
+	lg.Debug("Executing (no PTY): %s", finalCmd)
 
 	if err := session.Run(finalCmd); err != nil {
 		if stderr.Len() > 0 {
@@ -614,15 +1122,121 @@ func (c *SSHClient) executeInteractive(session *ssh.Session) error {
 	return nil
 }
 
+// commandLine is the command sent to the remote shell: Command in WorkDir
+// and a login shell, run inside Container and through sudo as RunAs when set
+func (c *operation) commandLine() string {
+	return runAsCommand(c.config.RunAs, containerCommandLine(c.config, shellCommandLine(c.config, c.config.Command)))
+}
+
+// sudoCommandLine is commandLine with its outermost sudo reading the
+// password from stdin. Without RunAs or Container that is the command's own
+// leading sudo, which sits inside the cd and login shell.
+func (c *operation) sudoCommandLine() string {
+	if c.config.RunAs != "" || c.config.Container != "" {
+		return sudoStdinCommand(c.commandLine())
+	}
+	return shellCommandLine(c.config, sudoStdinCommand(c.config.Command))
+}
+
+// sandboxed confines command with the configured sandbox. It wraps the
+// final command line, so a sudo reading its password from stdin runs inside
+// the sandbox too.
+func (c *operation) sandboxed(command string) string {
+	// PowerShell scripts set SSHX_CALLER themselves and cannot be sandboxed
+	if isPowerShell(c.config.Shell) {
+		return command
+	}
+	return c.config.Sandbox.Wrap(callerCommand(c.config.Identity(), command), c.config.User)
+}
+
+// Identity describes who requested the operation, e.g.
+// "claude-desktop/0.9.2 (ops)": the MCP client and, in parentheses, the
+// authenticated caller. It is empty when neither is known.
+func (c *Config) Identity() string {
+	switch {
+	case c.Client == "":
+		return c.Caller
+	case c.Caller == "":
+		return c.Client
+	default:
+		return fmt.Sprintf("%s (%s)", c.Client, c.Caller)
+	}
+}
+
+// callerCommand exports identity as CallerEnv for command, inside the
+// sandbox so a clean environment keeps it. An empty identity leaves the
+// command unchanged.
+func callerCommand(identity, command string) string {
+	if identity == "" {
+		return command
+	}
+	return fmt.Sprintf("export %s=%s; %s", CallerEnv, ShellQuote(identity), command)
+}
+
+// usesSudo reports whether the command needs the sudo password
+func (c *operation) usesSudo() bool {
+	return c.config.RunAs != "" || strings.Contains(c.config.Command, "sudo")
+}
+
+// runAsCommand wraps command so sudo runs it as user through a shell, with
+// that user's home directory. An empty user leaves the command unchanged.
+func runAsCommand(user, command string) string {
+	if user == "" {
+		return command
+	}
+	return BuildCommand("sudo", "-H", "-u", user, "--", "sh", "-c", command)
+}
+
+// sudoStdinCommand rewrites a leading sudo so it reads the password from stdin
+// without printing a prompt. Other commands are returned unchanged.
+func sudoStdinCommand(command string) string {
+	trimmed := strings.TrimSpace(command)
+	if !strings.HasPrefix(trimmed, "sudo ") {
+		return command
+	}
+	return "sudo -S -p '' " + strings.TrimSpace(strings.TrimPrefix(trimmed, "sudo "))
+}
+
+// feedPassword writes the sudo password to the session's stdin, so it never
+// appears in the remote process list. A command that exits without reading
+// it (NOPASSWD, cached credentials) closes the channel under the write,
+// which is no failure of its own: the exit status tells how the command went.
+func feedPassword(session *ssh.Session, password string) error {
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdin: %w", err)
+	}
+	go func() {
+		_, _ = io.WriteString(stdin, password+"\n") //nolint:errcheck // the exit status decides
+		_ = stdin.Close()                           //nolint:errcheck // the exit status decides
+	}()
+	return nil
+}
+
 // ExecuteSftp executes SFTP operations
-func (c *SSHClient) ExecuteSftp() (err error) {
-	sftpClient, err := sftp.NewClient(c.client)
+func (c *SSHClient) ExecuteSftp(req Request) error {
+	return c.newOperation(req).executeSftp()
+}
+
+// executeSftp runs the SFTP action over a new SFTP session
+func (c *operation) executeSftp() (err error) {
+	sftpClient, err := newSFTPClient(c.client, c.config)
 	if err != nil {
 		return fmt.Errorf("failed to create SFTP client: %w", err)
 	}
 	defer errutil.HandleCloseError(&err, sftpClient)
 	c.sftpClient = sftpClient
 
+	switch c.config.SftpAction {
+	case "upload", "mkdir", "remove", "rm":
+		err = c.checkPath(c.config.RemotePath, true)
+	case "download", "list", "ls":
+		err = c.checkPath(c.config.RemotePath, false)
+	}
+	if err != nil {
+		return err
+	}
+
 	switch c.config.SftpAction {
 	case "upload":
 		return c.uploadFile()
@@ -633,13 +1247,16 @@ func (c *SSHClient) ExecuteSftp() (err error) {
 	case "mkdir":
 		return c.makeDirectory()
 	case "remove", "rm":
+		if c.config.RemoveBackup {
+			return c.trashRemotePath()
+		}
 		return c.removeFile()
 	default:
 		return fmt.Errorf("unknown SFTP action: %s", c.config.SftpAction)
 	}
 }
 
-func (c *SSHClient) uploadFile() (err error) {
+func (c *operation) uploadFile() (err error) {
 	lg := logger.GetLogger()
 	localFile, err := os.Open(c.config.LocalPath)
 	if err != nil {
@@ -647,24 +1264,51 @@ func (c *SSHClient) uploadFile() (err error) {
 	}
 	defer errutil.HandleCloseError(&err, localFile)
 
-	remoteFile, err := c.sftpClient.Create(c.config.RemotePath)
-	if err != nil {
-		return fmt.Errorf("failed to create remote file: %w", err)
+	if c.config.DeltaUpload {
+		lg.Info("Uploading by delta transfer: %s → %s", c.config.LocalPath, c.config.RemotePath)
+		stats, deltaErr := c.uploadDelta(localFile)
+		switch {
+		case deltaErr == nil:
+			if err = applyOwnership(c.client, c.sftpClient, c.config, c.config.RemotePath); err != nil {
+				return err
+			}
+			lg.Success("Uploaded %d bytes by delta transfer: sent %d, reused %d from the previous file", stats.Size, stats.Sent, stats.Reused)
+			return nil
+		case errors.Is(deltaErr, errDeltaUnavailable):
+			lg.Info("%v, uploading the whole file", deltaErr)
+			if _, err = localFile.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to rewind local file: %w", err)
+			}
+		default:
+			return deltaErr
+		}
 	}
-	defer errutil.HandleCloseError(&err, remoteFile)
 
 	lg.Info("Uploading: %s → %s", c.config.LocalPath, c.config.RemotePath)
 
-	written, err := io.Copy(remoteFile, localFile)
+	written, err := atomicWriteRemote(c.sftpClient, c.config.RemotePath, c.config.UploadBackup, c.config.UploadOwnership.Mode, func(remoteFile io.Writer) (int64, error) {
+		dst := remoteFile
+		if c.config.Progress != nil {
+			var size int64
+			if info, statErr := localFile.Stat(); statErr == nil {
+				size = info.Size()
+			}
+			dst = newProgressWriter(remoteFile, c.config.Progress, size, c.config.RemotePath)
+		}
+		return io.Copy(dst, localFile)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to upload file: %w", err)
+		return err
+	}
+	if err = applyOwnership(c.client, c.sftpClient, c.config, c.config.RemotePath); err != nil {
+		return err
 	}
 
 	lg.Success("Uploaded %d bytes successfully", written)
 	return nil
 }
 
-func (c *SSHClient) downloadFile() (err error) {
+func (c *operation) downloadFile() (err error) {
 	lg := logger.GetLogger()
 	remoteFile, err := c.sftpClient.Open(c.config.RemotePath)
 	if err != nil {
@@ -680,7 +1324,15 @@ func (c *SSHClient) downloadFile() (err error) {
 
 	lg.Info("Downloading: %s → %s", c.config.RemotePath, c.config.LocalPath)
 
-	written, err := io.Copy(localFile, remoteFile)
+	var dst io.Writer = localFile
+	if c.config.Progress != nil {
+		var size int64
+		if info, statErr := remoteFile.Stat(); statErr == nil {
+			size = info.Size()
+		}
+		dst = newProgressWriter(localFile, c.config.Progress, size, c.config.RemotePath)
+	}
+	written, err := io.Copy(dst, remoteFile)
 	if err != nil {
 		return fmt.Errorf("failed to download file: %w", err)
 	}
@@ -689,7 +1341,7 @@ func (c *SSHClient) downloadFile() (err error) {
 	return nil
 }
 
-func (c *SSHClient) listFiles() error {
+func (c *operation) listFiles() error {
 	lg := logger.GetLogger()
 	remotePath := c.config.RemotePath
 	if remotePath == "" {
@@ -717,7 +1369,7 @@ func (c *SSHClient) listFiles() error {
 	return nil
 }
 
-func (c *SSHClient) makeDirectory() error {
+func (c *operation) makeDirectory() error {
 	lg := logger.GetLogger()
 	if err := c.sftpClient.MkdirAll(c.config.RemotePath); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
@@ -727,7 +1379,7 @@ func (c *SSHClient) makeDirectory() error {
 	return nil
 }
 
-func (c *SSHClient) removeFile() error {
+func (c *operation) removeFile() error {
 	lg := logger.GetLogger()
 	stat, err := c.sftpClient.Stat(c.config.RemotePath)
 	if err != nil {
@@ -749,7 +1401,16 @@ func (c *SSHClient) removeFile() error {
 	return nil
 }
 
-func (c *SSHClient) removeDirectory(path string) error {
+func (c *operation) trashRemotePath() error {
+	dest, err := c.trashFile(c.config.RemotePath, time.Now())
+	if err != nil {
+		return err
+	}
+	logger.GetLogger().Success("Moved to trash: %s → %s", c.config.RemotePath, dest)
+	return nil
+}
+
+func (c *operation) removeDirectory(path string) error {
 	files, err := c.sftpClient.ReadDir(path)
 	if err != nil {
 		return fmt.Errorf("failed to read directory: %w", err)
@@ -773,6 +1434,7 @@ func (c *SSHClient) removeDirectory(path string) error {
 
 // Close closes the connection (releases back to connection pool)
 func (c *SSHClient) Close() error {
+	c.forgetSecret()
 	if c.config != nil {
 		pool := GetConnectionPool()
 		pool.ReleaseConnection(c.config)
@@ -780,9 +1442,18 @@ func (c *SSHClient) Close() error {
 	return nil
 }
 
+// forgetSecret drops the redaction NewSSHClient registered, once
+func (c *SSHClient) forgetSecret() {
+	if c.secret != "" {
+		logger.UnregisterSecret(c.secret)
+		c.secret = ""
+	}
+}
+
 // CloseWithError closes the connection and removes it from pool if there's an error
 func (c *SSHClient) CloseWithError(err error) error {
 	if err != nil && c.config != nil {
+		c.forgetSecret()
 		pool := GetConnectionPool()
 		pool.RemoveConnection(c.config)
 		return err
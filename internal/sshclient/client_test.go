@@ -7,10 +7,12 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/pkg/logger"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -120,6 +122,21 @@ func TestNewSSHClient_DefaultKeyPath(t *testing.T) {
 	}
 }
 
+func TestNewSSHClient_RedactsPasswordUntilClose(t *testing.T) {
+	first, err := NewSSHClient(&Config{Host: "a.test", Password: "client-pass"})
+	require.NoError(t, err)
+	second, err := NewSSHClient(&Config{Host: "b.test", Password: "client-pass"})
+	require.NoError(t, err)
+	assert.Equal(t, logger.RedactedMask, logger.Redact("client-pass"))
+
+	require.NoError(t, first.Close())
+	require.NoError(t, first.Close())
+	assert.Equal(t, logger.RedactedMask, logger.Redact("client-pass"), "still used by the second client")
+
+	assert.Error(t, second.CloseWithError(assert.AnError))
+	assert.Equal(t, "client-pass", logger.Redact("client-pass"))
+}
+
 func TestNewSSHClient_KeyAuthDisabled(t *testing.T) {
 	config := &Config{
 		Host:       "nokey.test",
@@ -252,7 +269,6 @@ func TestSSHClient_InitialState(t *testing.T) {
 	// 验证初始状态
 	assert.NotNil(t, client.config)
 	assert.Nil(t, client.client) // 未连接
-	assert.Nil(t, client.sftpClient)
 }
 
 func TestConfig_MultipleHosts(t *testing.T) {
@@ -334,3 +350,112 @@ func generateTestPublicKey(t *testing.T) ssh.PublicKey {
 	require.NoError(t, err)
 	return signer.PublicKey()
 }
+
+func TestSudoStdinCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"sudo systemctl restart nginx", "sudo -S -p '' systemctl restart nginx"},
+		{"  sudo  id", "sudo -S -p '' id"},
+		{"ls -la", "ls -la"},
+		{"echo sudo", "echo sudo"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, sudoStdinCommand(tt.command), tt.command)
+	}
+}
+
+func TestRunAsCommand(t *testing.T) {
+	assert.Equal(t, "uptime", runAsCommand("", "uptime"))
+	assert.Equal(t, `sudo -H -u app -- sh -c 'cd /srv && echo '\''ok'\'''`, runAsCommand("app", "cd /srv && echo 'ok'"))
+	assert.Equal(t, "sudo -S -p '' -H -u app -- sh -c id", sudoStdinCommand(runAsCommand("app", "id")))
+
+	c := &operation{config: &Config{Command: "id", RunAs: "app"}}
+	assert.True(t, c.usesSudo())
+	assert.Equal(t, "sudo -H -u app -- sh -c id", c.commandLine())
+	c.config.RunAs = ""
+	assert.False(t, c.usesSudo())
+	assert.Equal(t, "id", c.commandLine())
+}
+
+func TestCommandLine_WorkDirAndLoginShell(t *testing.T) {
+	c := &operation{config: &Config{Command: "./deploy.sh && echo done", WorkDir: "/opt/my app"}}
+	assert.Equal(t, `cd '/opt/my app' || exit 1; ./deploy.sh && echo done`, c.commandLine())
+
+	c.config.LoginShell = true
+	assert.Equal(t, `bash -lc 'cd '\''/opt/my app'\'' || exit 1; ./deploy.sh && echo done'`, c.commandLine())
+
+	// The command's own sudo still reads the password from stdin
+	c.config.Command = "sudo systemctl restart app"
+	c.config.LoginShell = false
+	assert.Equal(t, `cd '/opt/my app' || exit 1; sudo -S -p '' systemctl restart app`, c.sudoCommandLine())
+
+	// With RunAs the directory and login shell belong to the target user
+	c.config.Command = "bundle exec rake"
+	c.config.WorkDir = "/srv/app"
+	c.config.LoginShell = true
+	c.config.RunAs = "app"
+	inner := "bash -lc " + ShellQuote("cd /srv/app || exit 1; bundle exec rake")
+	assert.Equal(t, "sudo -S -p '' -H -u app -- sh -c "+ShellQuote(inner), c.sudoCommandLine())
+}
+
+func TestNewSSHClient_RegistersPasswordForRedaction(t *testing.T) {
+	_, err := NewSSHClient(&Config{Host: "example.com", Password: "pa55-redact-me"})
+	require.NoError(t, err)
+	defer logger.UnregisterSecret("pa55-redact-me")
+
+	assert.Equal(t, "password="+logger.RedactedMask, logger.Redact("password=pa55-redact-me"))
+}
+
+func TestConnectDirect_RecordsConnectStats(t *testing.T) {
+	addr := listenExecServer(t, func(string, ssh.Channel, <-chan struct{}) uint32 { return 0 })
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	client, err := NewSSHClient(&Config{
+		Host:              host,
+		Port:              port,
+		User:              "test",
+		Password:          "unused",
+		KnownHostsPath:    filepath.Join(t.TempDir(), "known_hosts"),
+		AcceptUnknownHost: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.ConnectDirect())
+	defer func() { _ = client.ForceClose() }()
+
+	stats := client.ConnectStats()
+	assert.Positive(t, stats.DialLatency)
+	assert.Positive(t, stats.AuthLatency)
+	assert.Contains(t, stats.ServerVersion, "SSH-2.0-")
+	assert.Equal(t, testServerBanner, stats.Banner)
+}
+
+func TestSSHClient_ConcurrentRequests(t *testing.T) {
+	conn := startExecServer(t, func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		_, _ = channel.Write([]byte("ran: " + command))
+		return 0
+	})
+	client := &SSHClient{config: &Config{Host: "test", Command: "shared"}, client: conn}
+
+	const requests = 8
+	var wg sync.WaitGroup
+	outputs := make([]string, requests)
+	errs := make([]error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			outputs[i], errs[i] = client.ExecuteCommandWithOutput(Request{Command: fmt.Sprintf("echo %d", i)})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < requests; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, fmt.Sprintf("ran: echo %d", i), outputs[i])
+	}
+	assert.Equal(t, "shared", client.config.Command, "requests must not modify the client's Config")
+}
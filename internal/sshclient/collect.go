@@ -0,0 +1,177 @@
+package sshclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/talkincode/sshmcp/pkg/errutil"
+)
+
+// DefaultCollectMaxBytes caps how much data is collected from a single host
+const DefaultCollectMaxBytes int64 = 50 * 1024 * 1024
+
+// CollectOptions describes an artifact gathered from many hosts. Either
+// RemotePath (a file downloaded into LocalDir/<host>/) or Command (output
+// returned in CollectResult.Output) must be set.
+type CollectOptions struct {
+	RemotePath  string
+	Command     string
+	LocalDir    string
+	MaxBytes    int64 // Per-host size cap (default: DefaultCollectMaxBytes)
+	Concurrency int   // Hosts processed in parallel (default: DefaultFleetConcurrency)
+	// Rollback is an optional command run on a host where Command failed,
+	// to undo what it changed (command collection only)
+	Rollback string
+	// Progress, when set, is called as each host finishes
+	Progress ProgressFunc
+}
+
+// CollectResult describes what was collected from one host
+type CollectResult struct {
+	Key        string        // Pool key (user@host:port)
+	LocalPath  string        // Where the file was saved (file collection only)
+	Output     string        // Command output (command collection only)
+	Bytes      int64         // Bytes collected
+	SHA256     string        // Hex SHA-256 of the file saved (file collection only)
+	Truncated  bool          // Command output exceeded MaxBytes and was cut
+	RolledBack bool          // Rollback ran after Command failed
+	Duration   time.Duration // Total time spent on this host
+	Err        error         // nil on success
+}
+
+// Collect gathers the same file or command output from every host in
+// parallel. Files larger than MaxBytes are skipped with an error; command
+// output keeps its first and last MaxBytes/2 bytes. Results are returned in
+// the same order as configs.
+func Collect(configs []*Config, opts CollectOptions) []CollectResult {
+	results := make([]CollectResult, len(configs))
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultCollectMaxBytes
+	}
+
+	forEachHost(configs, opts.Concurrency, opts.Progress, func(i int, config *Config) {
+		results[i] = collectOne(config, opts)
+	})
+	return results
+}
+
+// CollectDirName returns the per-host subdirectory name used by Collect: the
+// configured alias when present, otherwise the host address, with path
+// separators replaced.
+func CollectDirName(config *Config) string {
+	name := config.Alias
+	if name == "" {
+		name = config.Host
+	}
+	return strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(name)
+}
+
+// collectOne gathers the artifact from a single host
+func collectOne(config *Config, opts CollectOptions) (result CollectResult) {
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	if config == nil || config.Host == "" {
+		result.Err = fmt.Errorf("host is required")
+		return result
+	}
+
+	client, err := NewSSHClient(config)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Key = GetConnectionPool().makeKey(config)
+
+	if err := client.Connect(); err != nil {
+		result.Err = fmt.Errorf("failed to connect: %w", err)
+		return result
+	}
+	defer func() {
+		_ = client.CloseWithError(result.Err) //nolint:errcheck
+	}()
+
+	if opts.Command != "" {
+		commandConfig := *config
+		commandConfig.MaxOutputBytes = opts.MaxBytes
+		commandClient := &SSHClient{config: &commandConfig, client: client.client, authMethodUsed: client.authMethodUsed}
+		var output Result
+		output, result.Err = commandClient.Execute(Request{Command: opts.Command, RunAs: config.RunAs})
+		result.Output = output.Output
+		result.Bytes = int64(len(result.Output))
+		result.Truncated = output.Truncated
+		if opts.Rollback != "" && rollbackApplies(result.Err) {
+			if rollbackErr := runRollback(client, opts.Rollback, opts.Command); rollbackErr != nil {
+				result.Err = fmt.Errorf("%w (rollback failed: %v)", result.Err, rollbackErr)
+			} else {
+				result.RolledBack = true
+			}
+		}
+		return result
+	}
+
+	sftpClient, err := newSFTPClient(client.client, client.config)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to create SFTP client: %w", err)
+		return result
+	}
+	defer errutil.HandleCloseError(&result.Err, sftpClient)
+
+	if err := checkRemotePath(sftpClient, config.PathPolicy, opts.RemotePath, false); err != nil {
+		result.Err = err
+		return result
+	}
+
+	localPath := filepath.Join(opts.LocalDir, CollectDirName(config), filepath.Base(opts.RemotePath))
+	result.LocalPath = localPath
+	result.Bytes, result.SHA256, result.Err = downloadCapped(sftpClient, opts.RemotePath, localPath, opts.MaxBytes)
+	return result
+}
+
+// downloadCapped downloads remotePath to localPath, refusing files larger
+// than maxBytes, and returns the size and hex SHA-256 of the copy
+func downloadCapped(sftpClient *sftp.Client, remotePath, localPath string, maxBytes int64) (written int64, sum string, err error) {
+	remoteFile, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer errutil.HandleCloseError(&err, remoteFile)
+
+	info, err := remoteFile.Stat()
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to stat remote file: %w", err)
+	}
+	if info.IsDir() {
+		return 0, "", fmt.Errorf("%s is a directory", remotePath)
+	}
+	if info.Size() > maxBytes {
+		return 0, "", fmt.Errorf("file is %d bytes, exceeds size cap of %d bytes", info.Size(), maxBytes)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o750); err != nil {
+		return 0, "", fmt.Errorf("failed to create local directory: %w", err)
+	}
+	localFile, err := os.Create(localPath) // #nosec G304 -- path built from the collection directory
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create local file: %w", err)
+	}
+	defer errutil.HandleCloseError(&err, localFile)
+
+	// Read one byte past the cap so growth during the copy is detected
+	hash := sha256.New()
+	written, err = io.Copy(io.MultiWriter(localFile, hash), io.LimitReader(remoteFile, maxBytes+1))
+	if err != nil {
+		return written, "", fmt.Errorf("failed to download file: %w", err)
+	}
+	if written > maxBytes {
+		return maxBytes, "", fmt.Errorf("file grew past size cap of %d bytes during download", maxBytes)
+	}
+	return written, hex.EncodeToString(hash.Sum(nil)), nil
+}
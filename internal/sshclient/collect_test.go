@@ -0,0 +1,66 @@
+package sshclient
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCollect_InvalidConfigs(t *testing.T) {
+	results := Collect([]*Config{nil, {}}, CollectOptions{RemotePath: "/var/log/app.log", LocalDir: t.TempDir()})
+
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.ErrorContains(t, result.Err, "host is required")
+		assert.Empty(t, result.LocalPath)
+	}
+}
+
+func TestCollectDirName(t *testing.T) {
+	assert.Equal(t, "web1", CollectDirName(&Config{Host: "10.0.0.1", Alias: "web1"}))
+	assert.Equal(t, "10.0.0.1", CollectDirName(&Config{Host: "10.0.0.1"}))
+	assert.Equal(t, "fe80__1", CollectDirName(&Config{Host: "fe80::1"}))
+	assert.Equal(t, "a_b", CollectDirName(&Config{Host: "x", Alias: "a/b"}))
+}
+
+func TestCollect_Rollback(t *testing.T) {
+	var mu sync.Mutex
+	var ran []string
+	handler := func(command string, _ ssh.Channel, _ <-chan struct{}) uint32 {
+		mu.Lock()
+		ran = append(ran, command)
+		mu.Unlock()
+		if strings.HasPrefix(command, "deploy") || command == "broken rollback" {
+			return 1
+		}
+		return 0
+	}
+	address, port, err := net.SplitHostPort(listenExecServer(t, handler))
+	require.NoError(t, err)
+	config := &Config{
+		Host: address, Port: port, User: "test", Password: "unused", SafetyCheck: true,
+		KnownHostsPath: filepath.Join(t.TempDir(), "known_hosts"), AcceptUnknownHost: true,
+	}
+
+	results := Collect([]*Config{config}, CollectOptions{Command: "deploy v2", Rollback: "undo deploy"})
+	assert.Error(t, results[0].Err)
+	assert.True(t, results[0].RolledBack)
+	assert.Equal(t, []string{"deploy v2", "undo deploy"}, ran)
+
+	results = Collect([]*Config{config}, CollectOptions{Command: "deploy v3", Rollback: "broken rollback"})
+	assert.ErrorContains(t, results[0].Err, "rollback failed")
+	assert.False(t, results[0].RolledBack)
+
+	// A command the safety check refused never ran
+	ran = nil
+	results = Collect([]*Config{config}, CollectOptions{Command: "rm -rf /", Rollback: "undo deploy"})
+	assert.Error(t, results[0].Err)
+	assert.False(t, results[0].RolledBack)
+	assert.Empty(t, ran)
+}
@@ -0,0 +1,81 @@
+package sshclient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContainerRuntimes are the container CLIs tried, in order, when no runtime
+// is configured
+var ContainerRuntimes = []string{"docker", "podman", "nerdctl"}
+
+// ValidateContainerName rejects container names the runtime would take for
+// an option. Quoting already keeps names from reaching the shell.
+func ValidateContainerName(name string) error {
+	if name == "" {
+		return fmt.Errorf("container name is empty")
+	}
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid container name '%s'", name)
+	}
+	return nil
+}
+
+// ValidateContainerRuntime rejects runtimes other than ContainerRuntimes;
+// an empty runtime means autodetect
+func ValidateContainerRuntime(runtime string) error {
+	if runtime == "" {
+		return nil
+	}
+	for _, known := range ContainerRuntimes {
+		if runtime == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported container runtime '%s' (use %s)", runtime, strings.Join(ContainerRuntimes, ", "))
+}
+
+// ContainerExecCommand runs command through a shell inside container
+func ContainerExecCommand(runtime, container, command string) string {
+	return containerCommand(runtime, BuildCommand("exec", container, "sh", "-c", command))
+}
+
+// ContainerListCommand lists running containers, or all of them
+func ContainerListCommand(runtime string, all bool) string {
+	if all {
+		return containerCommand(runtime, "ps -a")
+	}
+	return containerCommand(runtime, "ps")
+}
+
+// ContainerLogsCommand prints the last tail lines (all when 0) of the logs
+// of container, optionally only those newer than since (e.g. "10m")
+func ContainerLogsCommand(runtime, container string, tail int, since string) string {
+	args := "logs"
+	if tail > 0 {
+		args += fmt.Sprintf(" --tail %d", tail)
+	}
+	if since != "" {
+		args += " " + BuildCommand("--since", since)
+	}
+	return containerCommand(runtime, args+" "+BuildCommand(container))
+}
+
+// containerCommand runs the runtime with args. Without a runtime the first
+// of ContainerRuntimes installed on the remote host is used.
+func containerCommand(runtime, args string) string {
+	if runtime != "" {
+		return BuildCommand(runtime) + " " + args
+	}
+	return fmt.Sprintf(`for rt in %s; do if command -v "$rt" >/dev/null 2>&1; then exec "$rt" %s; fi; done; echo 'no container runtime found (%s)' >&2; exit 127`,
+		strings.Join(ContainerRuntimes, " "), args, strings.Join(ContainerRuntimes, ", "))
+}
+
+// containerCommandLine wraps command in a container exec when Container is
+// set
+func containerCommandLine(config *Config, command string) string {
+	if config.Container == "" {
+		return command
+	}
+	return ContainerExecCommand(config.ContainerRuntime, config.Container, command)
+}
@@ -0,0 +1,42 @@
+package sshclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerCommands(t *testing.T) {
+	assert.Equal(t, `docker exec web1 sh -c 'ls /app'`, ContainerExecCommand("docker", "web1", "ls /app"))
+	assert.Equal(t, `podman exec web1 sh -c 'echo '\''hi'\'''`, ContainerExecCommand("podman", "web1", "echo 'hi'"))
+	assert.Equal(t, `nerdctl ps -a`, ContainerListCommand("nerdctl", true))
+	assert.Equal(t, `docker ps`, ContainerListCommand("docker", false))
+	assert.Equal(t, `docker logs --tail 50 --since 10m web1`, ContainerLogsCommand("docker", "web1", 50, "10m"))
+	assert.Equal(t, `docker logs web1`, ContainerLogsCommand("docker", "web1", 0, ""))
+
+	detected := ContainerExecCommand("", "web1", "ls")
+	assert.Contains(t, detected, "for rt in docker podman nerdctl; do")
+	assert.Contains(t, detected, `exec "$rt" exec web1 sh -c ls`)
+	assert.Contains(t, detected, "exit 127")
+}
+
+func TestValidateContainer(t *testing.T) {
+	assert.NoError(t, ValidateContainerName("web1"))
+	assert.NoError(t, ValidateContainerName("3f2a9c1b"))
+	assert.Error(t, ValidateContainerName(""))
+	assert.Error(t, ValidateContainerName("--privileged"))
+
+	assert.NoError(t, ValidateContainerRuntime(""))
+	assert.NoError(t, ValidateContainerRuntime("podman"))
+	assert.ErrorContains(t, ValidateContainerRuntime("lxc"), "unsupported container runtime")
+}
+
+func TestCommandLine_Container(t *testing.T) {
+	c := &operation{config: &Config{Command: "id", Container: "web1", ContainerRuntime: "docker"}}
+	assert.Equal(t, `docker exec web1 sh -c id`, c.commandLine())
+
+	// sudo -u runs the runtime, so a user without socket access can use run_as root
+	c.config.RunAs = "root"
+	assert.Equal(t, `sudo -H -u root -- sh -c 'docker exec web1 sh -c id'`, c.commandLine())
+	assert.True(t, c.usesSudo())
+}
@@ -0,0 +1,323 @@
+package sshclient
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// cronBackupDir is where EditCrontab keeps the replaced crontabs, below the
+// home directory of the user the command runs as
+const cronBackupDir = ".sshx/crontab-backups"
+
+// cronSpecials are the @ schedules cron understands
+var cronSpecials = []string{"@reboot", "@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+
+// cronFieldPattern matches one time field of a crontab line
+var cronFieldPattern = regexp.MustCompile(`^[0-9A-Za-z*/,-]+$`)
+
+// CronEntry is a job of a crontab
+type CronEntry struct {
+	Line     int    `json:"line"` // 1-based line number in the crontab
+	Schedule string `json:"schedule"`
+	Command  string `json:"command"`
+}
+
+// Timer is a systemd timer
+type Timer struct {
+	Name      string   `json:"name"`
+	Activates string   `json:"activates"`
+	State     string   `json:"state"`
+	Schedule  []string `json:"schedule,omitempty"` // OnCalendar= and monotonic triggers
+	Next      string   `json:"next,omitempty"`
+	Last      string   `json:"last,omitempty"`
+}
+
+// CronEdit describes a change to a crontab
+type CronEdit struct {
+	Account string // User whose crontab to edit (empty = the user the command runs as)
+	Add     string // Line to append unless it is already there
+	Remove  string // Remove the job lines containing this text
+	// DryRun only computes the diff
+	DryRun bool
+}
+
+// CronEditResult is the outcome of EditCrontab
+type CronEditResult struct {
+	Account string   `json:"account,omitempty"`
+	Changed bool     `json:"changed"`
+	DryRun  bool     `json:"dry_run"`
+	Diff    []string `json:"diff"` // "- " removed and "+ " added lines
+	// Backup is the file on the host holding the previous crontab
+	Backup string `json:"backup,omitempty"`
+}
+
+// ValidateCronEntry checks that line is a single crontab job: five time
+// fields or an @ schedule, followed by a command
+func ValidateCronEntry(line string) error {
+	if strings.ContainsAny(line, "\r\n") {
+		return fmt.Errorf("a cron entry must be a single line")
+	}
+	if _, ok := parseCronLine(line); !ok {
+		return fmt.Errorf("invalid cron entry '%s' (use \"MIN HOUR DOM MON DOW command\" or \"@daily command\")", line)
+	}
+	return nil
+}
+
+// parseCronLine reads a job line; comments, blank lines and environment
+// assignments are not jobs
+func parseCronLine(line string) (CronEntry, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return CronEntry{}, false
+	}
+	if slices.Contains(cronSpecials, fields[0]) {
+		if len(fields) < 2 {
+			return CronEntry{}, false
+		}
+		return CronEntry{Schedule: fields[0], Command: strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), fields[0]))}, true
+	}
+	if len(fields) < 6 {
+		return CronEntry{}, false
+	}
+	for _, field := range fields[:5] {
+		if !cronFieldPattern.MatchString(field) {
+			return CronEntry{}, false
+		}
+	}
+	// The command keeps its own spacing
+	rest := strings.TrimSpace(line)
+	for range 5 {
+		rest = strings.TrimLeft(rest[strings.IndexAny(rest, " \t"):], " \t")
+	}
+	return CronEntry{Schedule: strings.Join(fields[:5], " "), Command: rest}, true
+}
+
+// ParseCrontab returns the jobs of a crontab
+func ParseCrontab(crontab string) []CronEntry {
+	entries := []CronEntry{}
+	for i, line := range splitLines(crontab) {
+		if entry, ok := parseCronLine(line); ok {
+			entry.Line = i + 1
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// readCrontabFunction defines read_crontab, which prints the crontab of
+// account (the current user when empty) and nothing when there is none
+func readCrontabFunction(account string) string {
+	crontab := "crontab -l"
+	if account != "" {
+		crontab = "crontab -u " + ShellQuote(account) + " -l"
+	}
+	return `read_crontab() { if out=$(` + crontab + ` 2>&1); then printf '%s\n' "$out"; ` +
+		`elif printf '%s\n' "$out" | grep -qi '^no crontab'; then :; ` +
+		`else printf '%s\n' "$out" >&2; return 1; fi; }`
+}
+
+// CrontabCommand prints the crontab of account (the current user when
+// empty), or nothing when it has none
+func CrontabCommand(account string) string {
+	return readCrontabFunction(account) + "\nread_crontab"
+}
+
+// TimersCommand prints the properties of every systemd timer, in blocks
+// separated by blank lines, or nothing on hosts without systemd
+func TimersCommand() string {
+	return strings.Join([]string{
+		"if command -v systemctl >/dev/null 2>&1; then",
+		`  timers=$(systemctl list-units --type=timer --all --no-legend --plain 2>/dev/null | awk '{for (i = 1; i <= NF; i++) if ($i ~ /\.timer$/) { print $i; break }}')`,
+		`  if [ -n "$timers" ]; then systemctl show -p Id,Unit,ActiveState,NextElapseUSecRealtime,LastTriggerUSec,TimersCalendar,TimersMonotonic $timers; fi`,
+		"fi",
+	}, "\n")
+}
+
+// timerSchedulePattern finds the triggers in TimersCalendar and
+// TimersMonotonic values, e.g. "{ OnCalendar=*-*-* 00:00:00 ; next_elapse=... }"
+var timerSchedulePattern = regexp.MustCompile(`\{ (On[A-Za-z]+)=([^;]*?) ;`)
+
+// ParseTimers reads the output of TimersCommand
+func ParseTimers(output string) []Timer {
+	timers := []Timer{}
+	var timer *Timer
+	for _, line := range splitLines(output) {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			timer = nil
+			continue
+		}
+		if timer == nil {
+			timers = append(timers, Timer{})
+			timer = &timers[len(timers)-1]
+		}
+		switch name {
+		case "Id":
+			timer.Name = value
+		case "Unit":
+			timer.Activates = value
+		case "ActiveState":
+			timer.State = value
+		case "NextElapseUSecRealtime":
+			timer.Next = value
+		case "LastTriggerUSec":
+			if value != "n/a" {
+				timer.Last = value
+			}
+		case "TimersCalendar", "TimersMonotonic":
+			for _, match := range timerSchedulePattern.FindAllStringSubmatch(value, -1) {
+				timer.Schedule = append(timer.Schedule, match[1]+"="+strings.TrimSpace(match[2]))
+			}
+		}
+	}
+	return slices.DeleteFunc(timers, func(timer Timer) bool { return timer.Name == "" })
+}
+
+// EditCrontabText applies edit to crontab and returns the new crontab
+func EditCrontabText(crontab string, edit CronEdit) (string, error) {
+	lines := splitLines(strings.TrimRight(crontab, "\n"))
+	if strings.TrimSpace(crontab) == "" {
+		lines = nil
+	}
+	if edit.Remove != "" {
+		lines = slices.DeleteFunc(lines, func(line string) bool {
+			_, job := parseCronLine(line)
+			return job && strings.Contains(line, edit.Remove)
+		})
+	}
+	if edit.Add != "" {
+		if err := ValidateCronEntry(edit.Add); err != nil {
+			return "", err
+		}
+		if !slices.ContainsFunc(lines, func(line string) bool { return strings.TrimSpace(line) == strings.TrimSpace(edit.Add) }) {
+			lines = append(lines, edit.Add)
+		}
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// InstallCrontabCommand replaces the crontab of account with crontab if it
+// still is previous, keeping a copy of previous under cronBackupDir, and
+// prints the path of the copy. Both crontabs travel base64 encoded: jobs
+// already installed, such as @reboot ones, would otherwise trip the safety
+// validator on every edit (EditCrontab checks the added job itself).
+func InstallCrontabCommand(account, previous, crontab string) string {
+	install := "crontab -"
+	owner := `"$(id -un)"`
+	if account != "" {
+		install = "crontab -u " + ShellQuote(account) + " -"
+		owner = ShellQuote(account)
+	}
+	return strings.Join([]string{
+		readCrontabFunction(account),
+		"current=$(read_crontab) || exit 1",
+		// $(...) drops trailing newlines, printf adds one back
+		`if [ "$(printf '%s\n' "$current" | base64 | tr -d '\n')" != ` + ShellQuote(base64.StdEncoding.EncodeToString([]byte(strings.TrimRight(previous, "\n")+"\n"))) + ` ]; then`,
+		`  echo 'the crontab changed since it was read; run again' >&2; exit 3`,
+		`fi`,
+		`backup="$HOME/` + cronBackupDir + `/"` + owner + `"-$(date +%Y%m%d-%H%M%S)"`,
+		`mkdir -p "$HOME/` + cronBackupDir + `" && chmod 700 "$HOME/` + cronBackupDir + `"`,
+		`printf '%s\n' "$current" > "$backup" && chmod 600 "$backup"`,
+		"printf '%s' " + ShellQuote(base64.StdEncoding.EncodeToString([]byte(crontab))) + " | base64 -d | " + install,
+		`echo "$backup"`,
+	}, "\n")
+}
+
+// cronAccount is the -u argument for account: empty when the crontab is
+// the one of the user the command runs as
+func (c *SSHClient) cronAccount(account string, req Request) (string, error) {
+	if account == "" {
+		return "", nil
+	}
+	if err := ValidateAccountName(account); err != nil {
+		return "", err
+	}
+	// crontab -u needs root, even for one's own crontab
+	if req.RunAs == "" && account == c.config.User {
+		return "", nil
+	}
+	return account, nil
+}
+
+// Crontab returns the crontab of account (empty = the user the command runs
+// as), which is empty when the user has none
+func (c *SSHClient) Crontab(account string, req Request) (string, error) {
+	account, err := c.cronAccount(account, req)
+	if err != nil {
+		return "", err
+	}
+	req.Command = CrontabCommand(account)
+	result, err := c.Execute(req)
+	if err != nil {
+		return "", err
+	}
+	if result.Truncated {
+		return "", fmt.Errorf("the crontab exceeded the output limit")
+	}
+	return result.Output, nil
+}
+
+// Timers lists the systemd timers of the host
+func (c *SSHClient) Timers(req Request) ([]Timer, error) {
+	req.Command = TimersCommand()
+	result, err := c.Execute(req)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTimers(result.Output), nil
+}
+
+// EditCrontab adds or removes crontab lines. It reads the crontab, computes
+// the diff and, unless edit.DryRun or nothing changes, installs the new
+// crontab after saving the previous one on the host. The install is refused
+// if the crontab changed after it was read. All commands run through
+// Execute, so they are validated and audited; with SafetyCheck set, the
+// command of the added job is validated like one run directly.
+func (c *SSHClient) EditCrontab(edit CronEdit, req Request) (*CronEditResult, error) {
+	if edit.Add == "" && strings.TrimSpace(edit.Remove) == "" {
+		return nil, fmt.Errorf("nothing to change: give an entry to add or text to remove")
+	}
+	account, err := c.cronAccount(edit.Account, req)
+	if err != nil {
+		return nil, err
+	}
+	if isPowerShell(c.config.Shell) {
+		return nil, fmt.Errorf("crontab editing is not supported with shell %s", c.config.Shell)
+	}
+
+	previous, err := c.Crontab(account, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the crontab: %w", err)
+	}
+	crontab, err := EditCrontabText(previous, edit)
+	if err != nil {
+		return nil, err
+	}
+	if entry, ok := parseCronLine(edit.Add); ok && c.config.SafetyCheck {
+		if _, err := CheckCommand(entry.Command, c.config.Safety); err != nil {
+			return nil, err
+		}
+	}
+	result := &CronEditResult{Account: edit.Account, DryRun: edit.DryRun, Diff: DiffLines(previous, crontab)}
+	if result.Diff == nil {
+		result.Diff = []string{}
+	}
+	result.Changed = len(result.Diff) > 0
+	if edit.DryRun || !result.Changed {
+		return result, nil
+	}
+
+	req.Command = InstallCrontabCommand(account, previous, crontab)
+	installed, err := c.Execute(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to install the crontab: %w", err)
+	}
+	result.Backup = lastLine(installed.Output)
+	return result, nil
+}
@@ -0,0 +1,192 @@
+package sshclient
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCrontab = `# m h dom mon dow command
+MAILTO=ops@example.com
+*/5 * * * *  /usr/local/bin/backup.sh --quiet
+@reboot /srv/app/bin/start
+0 3 * * 1-5 find /tmp -mtime +7 -delete
+`
+
+func TestParseCrontab(t *testing.T) {
+	entries := ParseCrontab(testCrontab)
+	require.Len(t, entries, 3)
+	assert.Equal(t, CronEntry{Line: 3, Schedule: "*/5 * * * *", Command: "/usr/local/bin/backup.sh --quiet"}, entries[0])
+	assert.Equal(t, CronEntry{Line: 4, Schedule: "@reboot", Command: "/srv/app/bin/start"}, entries[1])
+	assert.Equal(t, "0 3 * * 1-5", entries[2].Schedule)
+	assert.Empty(t, ParseCrontab(""))
+}
+
+func TestValidateCronEntry(t *testing.T) {
+	for _, line := range []string{"0 3 * * * /usr/bin/true", "@daily /srv/app/bin/rotate", "*/10 8-18 * jan,feb mon-fri cd /srv && make"} {
+		assert.NoError(t, ValidateCronEntry(line), line)
+	}
+	for _, line := range []string{"", "0 3 * * *", "@daily", "@often /bin/true", "FOO=bar", "# comment", "0 3 * * * a\n* * * * * b", "0 3 ? * * /bin/true"} {
+		assert.Error(t, ValidateCronEntry(line), line)
+	}
+}
+
+func TestEditCrontabText(t *testing.T) {
+	added, err := EditCrontabText(testCrontab, CronEdit{Add: "30 2 * * * /srv/app/bin/report"})
+	require.NoError(t, err)
+	assert.Equal(t, testCrontab+"30 2 * * * /srv/app/bin/report\n", added)
+	assert.Equal(t, []string{"+ 30 2 * * * /srv/app/bin/report"}, DiffLines(testCrontab, added))
+
+	// Adding an entry that is there changes nothing
+	again, err := EditCrontabText(added, CronEdit{Add: "30 2 * * * /srv/app/bin/report"})
+	require.NoError(t, err)
+	assert.Equal(t, added, again)
+
+	// Only job lines are removed, never comments or variables
+	removed, err := EditCrontabText(testCrontab, CronEdit{Remove: "backup"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"- */5 * * * *  /usr/local/bin/backup.sh --quiet"}, DiffLines(testCrontab, removed))
+	removed, err = EditCrontabText(testCrontab, CronEdit{Remove: "m h dom"})
+	require.NoError(t, err)
+	assert.Equal(t, testCrontab, removed)
+
+	created, err := EditCrontabText("", CronEdit{Add: "@hourly /bin/true"})
+	require.NoError(t, err)
+	assert.Equal(t, "@hourly /bin/true\n", created)
+	emptied, err := EditCrontabText("@hourly /bin/true\n", CronEdit{Remove: "/bin/true"})
+	require.NoError(t, err)
+	assert.Empty(t, emptied)
+
+	_, err = EditCrontabText(testCrontab, CronEdit{Add: "daily backup"})
+	assert.ErrorContains(t, err, "invalid cron entry")
+}
+
+func TestParseTimers(t *testing.T) {
+	output := `Id=logrotate.timer
+Unit=logrotate.service
+ActiveState=active
+NextElapseUSecRealtime=Sat 2026-10-17 00:00:00 UTC
+LastTriggerUSec=Fri 2026-10-16 00:00:01 UTC
+TimersCalendar={ OnCalendar=*-*-* 00:00:00 ; next_elapse=Sat 2026-10-17 00:00:00 UTC }
+TimersMonotonic=
+
+Id=fstrim.timer
+Unit=fstrim.service
+ActiveState=inactive
+NextElapseUSecRealtime=
+LastTriggerUSec=n/a
+TimersCalendar=
+TimersMonotonic={ OnBootUSec=15min ; next_elapse=0 } { OnUnitActiveUSec=1w ; next_elapse=0 }
+`
+	timers := ParseTimers(output)
+	require.Len(t, timers, 2)
+	assert.Equal(t, Timer{
+		Name: "logrotate.timer", Activates: "logrotate.service", State: "active",
+		Schedule: []string{"OnCalendar=*-*-* 00:00:00"},
+		Next:     "Sat 2026-10-17 00:00:00 UTC", Last: "Fri 2026-10-16 00:00:01 UTC",
+	}, timers[0])
+	assert.Equal(t, []string{"OnBootUSec=15min", "OnUnitActiveUSec=1w"}, timers[1].Schedule)
+	assert.Empty(t, timers[1].Last)
+	assert.Empty(t, ParseTimers(""))
+}
+
+func TestCronCommands(t *testing.T) {
+	for _, command := range []string{CrontabCommand(""), CrontabCommand("deploy"), TimersCommand(), InstallCrontabCommand("deploy", testCrontab, testCrontab+"@daily /bin/true\n")} {
+		assert.False(t, Explain(command, nil).Flagged, "%s must pass the safety check", command)
+	}
+	assert.Contains(t, CrontabCommand("deploy"), "crontab -u 'deploy' -l")
+	assert.NotContains(t, CrontabCommand(""), "-u")
+}
+
+// fakeCrontab puts a crontab command in PATH that keeps the crontab in a
+// file, and points HOME at a temporary directory
+func fakeCrontab(t *testing.T) (file, home string) {
+	t.Helper()
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	home = filepath.Join(dir, "home")
+	file = filepath.Join(dir, "crontab")
+	require.NoError(t, os.MkdirAll(bin, 0o700))
+	require.NoError(t, os.MkdirAll(home, 0o700))
+	script := "#!/bin/sh\ncase \"$*\" in\n" +
+		"*-l) if [ -f '" + file + "' ]; then cat '" + file + "'; else echo 'no crontab for test' >&2; exit 1; fi ;;\n" +
+		"*-) cat > '" + file + "' ;;\nesac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "crontab"), []byte(script), 0o700)) // #nosec G306 -- test helper
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+	t.Setenv("HOME", home)
+	return file, home
+}
+
+func TestSSHClient_EditCrontab(t *testing.T) {
+	file, home := fakeCrontab(t)
+	conn := startExecServer(t, shellExecHandler)
+	client := &SSHClient{config: &Config{Host: "10.0.0.5", User: "deploy"}, client: conn}
+
+	crontab, err := client.Crontab("", Request{})
+	require.NoError(t, err)
+	assert.Empty(t, crontab, "no crontab yet")
+
+	// A dry run only shows the diff
+	result, err := client.EditCrontab(CronEdit{Add: "@daily /srv/app/bin/rotate", DryRun: true}, Request{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"+ @daily /srv/app/bin/rotate"}, result.Diff)
+	assert.True(t, result.Changed)
+	assert.NoFileExists(t, file)
+
+	result, err = client.EditCrontab(CronEdit{Add: "@daily /srv/app/bin/rotate"}, Request{})
+	require.NoError(t, err)
+	assert.True(t, result.Changed)
+	assert.True(t, strings.HasPrefix(result.Backup, filepath.Join(home, ".sshx", "crontab-backups")+"/"), result.Backup)
+	data, err := os.ReadFile(file) // #nosec G304 -- test file
+	require.NoError(t, err)
+	assert.Equal(t, "@daily /srv/app/bin/rotate\n", string(data))
+
+	// The previous crontab is kept
+	require.NoError(t, os.WriteFile(file, []byte(testCrontab), 0o600))
+	result, err = client.EditCrontab(CronEdit{Remove: "backup.sh"}, Request{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"- */5 * * * *  /usr/local/bin/backup.sh --quiet"}, result.Diff)
+	backup, err := os.ReadFile(result.Backup) // #nosec G304 -- test file
+	require.NoError(t, err)
+	assert.Equal(t, testCrontab, string(backup))
+	info, err := os.Stat(result.Backup)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Nothing to do is not an error and installs nothing
+	result, err = client.EditCrontab(CronEdit{Remove: "backup.sh"}, Request{})
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+	assert.Empty(t, result.Backup)
+
+	// The added job is validated like a command run directly
+	client.config.SafetyCheck = true
+	_, err = client.EditCrontab(CronEdit{Add: "@daily rm -rf /"}, Request{})
+	assert.ErrorContains(t, err, "Dangerous command blocked")
+	result, err = client.EditCrontab(CronEdit{Add: "@daily /srv/app/bin/rotate"}, Request{})
+	require.NoError(t, err, "the @reboot job already installed is not flagged")
+	assert.True(t, result.Changed)
+
+	_, err = client.EditCrontab(CronEdit{}, Request{})
+	assert.ErrorContains(t, err, "nothing to change")
+	_, err = client.EditCrontab(CronEdit{Account: "Bad User", Remove: "x"}, Request{})
+	assert.ErrorContains(t, err, "invalid user name")
+}
+
+func TestInstallCrontabCommand_Changed(t *testing.T) {
+	file, _ := fakeCrontab(t)
+	require.NoError(t, os.WriteFile(file, []byte(testCrontab), 0o600))
+	conn := startExecServer(t, shellExecHandler)
+	client := &SSHClient{config: &Config{Host: "10.0.0.5", User: "deploy"}, client: conn}
+
+	// Someone edited the crontab after it was read
+	_, err := client.Execute(Request{Command: InstallCrontabCommand("", "@daily /bin/old\n", "@daily /bin/new\n")})
+	assert.ErrorContains(t, err, "the crontab changed since it was read")
+	data, err := os.ReadFile(file) // #nosec G304 -- test file
+	require.NoError(t, err)
+	assert.Equal(t, testCrontab, string(data))
+}
@@ -0,0 +1,389 @@
+package sshclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/pkg/errutil"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// Database engines supported by DumpDatabase and RestoreDatabase
+const (
+	DBEnginePostgres = "postgres"
+	DBEngineMySQL    = "mysql"
+)
+
+// DBOptions describes a database dump or restore. The password is sent on
+// the session's stdin and handed to the client tool through its
+// environment (PGPASSWORD, MYSQL_PWD), so it never appears in a remote
+// command line.
+type DBOptions struct {
+	Engine   string // DBEnginePostgres or DBEngineMySQL
+	Database string
+	DBUser   string // Database user (default: the tool's default)
+	DBHost   string // Database server as seen from the SSH host (default: local socket)
+	DBPort   string
+	Password string // Database password (optional)
+	// LocalPath is where DumpDatabase writes the dump, or the dump
+	// RestoreDatabase loads. A path ending in .gz is gzip compressed.
+	LocalPath string
+}
+
+// DBTransferResult describes a verified dump or restore
+type DBTransferResult struct {
+	Engine     string        `json:"engine"`
+	Database   string        `json:"database"`
+	LocalPath  string        `json:"local_path"`
+	Size       int64         `json:"size"`
+	SHA256     string        `json:"sha256"`
+	Compressed bool          `json:"compressed"`
+	Duration   time.Duration `json:"-"`
+}
+
+// Validate checks the engine and rejects names the client tools would take
+// for options
+func (o DBOptions) Validate() error {
+	if o.Engine != DBEnginePostgres && o.Engine != DBEngineMySQL {
+		return fmt.Errorf("unsupported database engine '%s' (use %s or %s)", o.Engine, DBEnginePostgres, DBEngineMySQL)
+	}
+	if o.Database == "" {
+		return fmt.Errorf("database is required")
+	}
+	if o.LocalPath == "" {
+		return fmt.Errorf("local path is required")
+	}
+	for field, value := range map[string]string{"database": o.Database, "db_user": o.DBUser, "db_host": o.DBHost, "db_port": o.DBPort} {
+		if strings.HasPrefix(value, "-") {
+			return fmt.Errorf("invalid %s '%s'", field, value)
+		}
+	}
+	return nil
+}
+
+// compressed reports whether the local dump is gzip compressed
+func (o DBOptions) compressed() bool {
+	return strings.HasSuffix(o.LocalPath, ".gz")
+}
+
+// DumpDatabase runs pg_dump or mysqldump on the host into a private temp
+// file, compresses it when LocalPath ends in .gz, downloads it over SFTP
+// and checks that the local sha256 matches the remote one. The remote file
+// is removed afterwards. The dump tool runs as req.RunAs when set, e.g.
+// postgres for peer authentication.
+func (c *SSHClient) DumpDatabase(opts DBOptions, req Request) (*DBTransferResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	op := c.newOperation(req)
+	defer acquireHostSlot(op.config)()
+	return op.dumpDatabase(opts)
+}
+
+// RestoreDatabase uploads a dump over SFTP to a private temp file, checks
+// its sha256 on the host and loads it with psql or mysql, decompressing
+// dumps ending in .gz. The remote file is removed afterwards.
+func (c *SSHClient) RestoreDatabase(opts DBOptions, req Request) (*DBTransferResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	op := c.newOperation(req)
+	defer acquireHostSlot(op.config)()
+	return op.restoreDatabase(opts)
+}
+
+func (c *operation) dumpDatabase(opts DBOptions) (result *DBTransferResult, err error) {
+	start := time.Now()
+	remotePath, err := remoteScriptPath(tempDir(c.config), "dump.sql")
+	if err != nil {
+		return nil, err
+	}
+	transferred := remotePath
+	if opts.compressed() {
+		transferred += ".gz"
+	}
+	defer c.removeRemoteFiles(remotePath, transferred)
+
+	logger.GetLogger().Info("Dumping %s database %s", opts.Engine, opts.Database)
+	command := "umask 077; " + c.dbCommand(opts, dbDumpCommand(opts), "") + " > " + ShellQuote(remotePath)
+	if opts.compressed() {
+		command += " && gzip -f " + ShellQuote(remotePath)
+	}
+	command += " && " + sha256Command(transferred)
+	output, err := c.runDBCommand(opts, command)
+	if err != nil {
+		return nil, fmt.Errorf("dump of %s failed: %w", opts.Database, err)
+	}
+	remoteSum, err := parseSHA256(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.withSFTP(func() error {
+		c.config.RemotePath, c.config.LocalPath = transferred, opts.LocalPath
+		return c.downloadFile()
+	}); err != nil {
+		return nil, err
+	}
+
+	size, localSum, err := fileSHA256(opts.LocalPath)
+	if err != nil {
+		return nil, err
+	}
+	if localSum != remoteSum {
+		_ = os.Remove(opts.LocalPath) //nolint:errcheck // the corrupt copy is useless either way
+		return nil, fmt.Errorf("checksum mismatch for %s: remote %s, local %s", opts.LocalPath, remoteSum, localSum)
+	}
+	return &DBTransferResult{
+		Engine:     opts.Engine,
+		Database:   opts.Database,
+		LocalPath:  opts.LocalPath,
+		Size:       size,
+		SHA256:     localSum,
+		Compressed: opts.compressed(),
+		Duration:   time.Since(start),
+	}, nil
+}
+
+func (c *operation) restoreDatabase(opts DBOptions) (result *DBTransferResult, err error) {
+	start := time.Now()
+	size, localSum, err := fileSHA256(opts.LocalPath)
+	if err != nil {
+		return nil, err
+	}
+	name := "dump.sql"
+	if opts.compressed() {
+		name += ".gz"
+	}
+	remotePath, err := remoteScriptPath(tempDir(c.config), name)
+	if err != nil {
+		return nil, err
+	}
+	defer c.removeRemoteFiles(remotePath)
+
+	if err = c.withSFTP(func() error {
+		// Create the file private before any content is written
+		file, createErr := c.sftpClient.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+		if createErr != nil {
+			return fmt.Errorf("failed to create remote file in %s: %w", tempDir(c.config), createErr)
+		}
+		if chmodErr := file.Chmod(0o600); chmodErr != nil {
+			_ = file.Close() //nolint:errcheck // chmod already failed
+			return fmt.Errorf("failed to chmod %s: %w", remotePath, chmodErr)
+		}
+		if closeErr := file.Close(); closeErr != nil {
+			return closeErr
+		}
+		c.config.RemotePath, c.config.LocalPath = remotePath, opts.LocalPath
+		return c.uploadFile()
+	}); err != nil {
+		return nil, err
+	}
+
+	output, err := c.runDBCommand(opts, sha256Command(remotePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to checksum %s: %w", remotePath, err)
+	}
+	remoteSum, err := parseSHA256(output)
+	if err != nil {
+		return nil, err
+	}
+	if remoteSum != localSum {
+		return nil, fmt.Errorf("checksum mismatch after upload: local %s, remote %s", localSum, remoteSum)
+	}
+
+	logger.GetLogger().Info("Restoring %s database %s", opts.Engine, opts.Database)
+	input := "cat " + ShellQuote(remotePath)
+	if opts.compressed() {
+		input = "gunzip -c " + ShellQuote(remotePath)
+	}
+	if _, err = c.runDBCommand(opts, c.dbCommand(opts, dbRestoreCommand(opts), input)); err != nil {
+		return nil, fmt.Errorf("restore of %s failed: %w", opts.Database, err)
+	}
+	return &DBTransferResult{
+		Engine:     opts.Engine,
+		Database:   opts.Database,
+		LocalPath:  opts.LocalPath,
+		Size:       size,
+		SHA256:     localSum,
+		Compressed: opts.compressed(),
+		Duration:   time.Since(start),
+	}, nil
+}
+
+// dbDumpCommand is the pg_dump or mysqldump invocation writing to stdout
+func dbDumpCommand(opts DBOptions) string {
+	if opts.Engine == DBEngineMySQL {
+		return "mysqldump --single-transaction --routines --triggers" + dbConnectionArgs(opts) + " " + ShellQuote(opts.Database)
+	}
+	return "pg_dump --no-password" + dbConnectionArgs(opts) + " -d " + ShellQuote(opts.Database)
+}
+
+// dbRestoreCommand is the psql or mysql invocation reading SQL from stdin
+func dbRestoreCommand(opts DBOptions) string {
+	if opts.Engine == DBEngineMySQL {
+		return "mysql" + dbConnectionArgs(opts) + " " + ShellQuote(opts.Database)
+	}
+	return "psql --no-password -X -q -v ON_ERROR_STOP=1" + dbConnectionArgs(opts) + " -d " + ShellQuote(opts.Database)
+}
+
+// dbConnectionArgs are the host, port and user options of the client tool
+func dbConnectionArgs(opts DBOptions) string {
+	portFlag, userFlag := "-p", "-U"
+	if opts.Engine == DBEngineMySQL {
+		portFlag, userFlag = "-P", "-u"
+	}
+	args := ""
+	if opts.DBHost != "" {
+		args += " -h " + ShellQuote(opts.DBHost)
+	}
+	if opts.DBPort != "" {
+		args += " " + portFlag + " " + ShellQuote(opts.DBPort)
+	}
+	if opts.DBUser != "" {
+		args += " " + userFlag + " " + ShellQuote(opts.DBUser)
+	}
+	return args
+}
+
+// dbCommand runs tool through a shell, as RunAs when set, with input piped
+// to it. The sudo and database passwords arrive as the first lines of the
+// session's stdin (see dbStdin); the login shell reads them and writes them
+// ahead of input, so sudo -S and the tool's shell read them in turn
+// wherever sudo resets the environment.
+func (c *operation) dbCommand(opts DBOptions, tool, input string) string {
+	inner := tool
+	if opts.Password != "" {
+		envVar := "PGPASSWORD"
+		if opts.Engine == DBEngineMySQL {
+			envVar = "MYSQL_PWD"
+		}
+		inner = fmt.Sprintf("IFS= read -r %[1]s; export %[1]s; exec %[2]s", envVar, tool)
+	}
+
+	var prelude, secrets []string
+	command := "sh -c " + ShellQuote(inner)
+	if c.config.RunAs != "" {
+		command = runAsCommand(c.config.RunAs, inner)
+		if c.config.Password != "" {
+			command = sudoStdinCommand(command)
+			prelude = append(prelude, "IFS= read -r sshx_sudo_pw")
+			secrets = append(secrets, `"$sshx_sudo_pw"`)
+		}
+	}
+	if opts.Password != "" {
+		prelude = append(prelude, "IFS= read -r sshx_db_pw")
+		secrets = append(secrets, `"$sshx_db_pw"`)
+	}
+
+	var feed []string
+	if len(secrets) > 0 {
+		feed = append(feed, "printf '%s\\n' "+strings.Join(secrets, " "))
+	}
+	if input != "" {
+		feed = append(feed, input)
+	}
+	if len(feed) > 0 {
+		command = "{ " + strings.Join(feed, "; ") + "; } | " + command
+	}
+	if len(prelude) > 0 {
+		command = strings.Join(prelude, "; ") + "; " + command
+	}
+	return command
+}
+
+// dbStdin is the session input read by the prelude of dbCommand
+func (c *operation) dbStdin(opts DBOptions) string {
+	stdin := ""
+	if c.config.RunAs != "" && c.config.Password != "" {
+		stdin += c.config.Password + "\n"
+	}
+	if opts.Password != "" {
+		stdin += opts.Password + "\n"
+	}
+	return stdin
+}
+
+// runDBCommand runs command with the passwords on stdin and returns its
+// stdout; stderr is included in the error when it fails
+func (c *operation) runDBCommand(opts DBOptions, command string) (output string, err error) {
+	session, err := c.client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	defer CloseIgnore(&err, session, io.EOF)
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = strings.NewReader(c.dbStdin(opts))
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err = session.Run(c.sandboxed(command)); err != nil {
+		if enhancedErr := errutil.EnhanceError(err, "", stderr.String()); enhancedErr != nil {
+			return "", enhancedErr
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// withSFTP runs fn with an SFTP session open on the operation
+func (c *operation) withSFTP(fn func() error) (err error) {
+	sftpClient, err := newSFTPClient(c.client, c.config)
+	if err != nil {
+		return fmt.Errorf("failed to create SFTP client: %w", err)
+	}
+	c.sftpClient = sftpClient
+	defer func() {
+		CloseIgnore(&err, sftpClient, io.EOF)
+		c.sftpClient = nil
+	}()
+	return fn()
+}
+
+// removeRemoteFiles deletes temp files, best effort
+func (c *operation) removeRemoteFiles(paths ...string) {
+	if err := c.executeSimpleCommand(BuildCommand(append([]string{"rm", "-f", "--"}, paths...)...)); err != nil {
+		logger.GetLogger().Debug("failed to remove %s: %v", strings.Join(paths, ", "), err)
+	}
+}
+
+// sha256Command prints the sha256 of path with sha256sum, or shasum where
+// coreutils are missing
+func sha256Command(path string) string {
+	return fmt.Sprintf("{ sha256sum %[1]s 2>/dev/null || shasum -a 256 %[1]s; }", ShellQuote(path))
+}
+
+// parseSHA256 extracts the checksum printed by sha256Command
+func parseSHA256(output string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("unexpected checksum output: %q", strings.TrimSpace(output))
+	}
+	if _, err := hex.DecodeString(fields[0]); err != nil {
+		return "", fmt.Errorf("unexpected checksum output: %q", strings.TrimSpace(output))
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// fileSHA256 returns the size and sha256 of a local file
+func fileSHA256(path string) (size int64, sum string, err error) {
+	file, err := os.Open(path) //nolint:gosec // G304: path is provided by the user
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer errutil.HandleCloseError(&err, file)
+
+	hash := sha256.New()
+	size, err = io.Copy(hash, file)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,94 @@
+package sshclient
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDBOptionsValidate(t *testing.T) {
+	valid := DBOptions{Engine: DBEnginePostgres, Database: "shop", LocalPath: "shop.sql"}
+	assert.NoError(t, valid.Validate())
+
+	for _, opts := range []DBOptions{
+		{Engine: "oracle", Database: "shop", LocalPath: "shop.sql"},
+		{Engine: DBEngineMySQL, LocalPath: "shop.sql"},
+		{Engine: DBEngineMySQL, Database: "shop"},
+		{Engine: DBEngineMySQL, Database: "--all-databases", LocalPath: "all.sql"},
+		{Engine: DBEnginePostgres, Database: "shop", DBHost: "-oProxyCommand", LocalPath: "shop.sql"},
+	} {
+		assert.Error(t, opts.Validate(), "%+v", opts)
+	}
+}
+
+func TestDBToolCommands(t *testing.T) {
+	pg := DBOptions{Engine: DBEnginePostgres, Database: "shop", DBUser: "app", DBHost: "db.internal", DBPort: "5433"}
+	assert.Equal(t, "pg_dump --no-password -h 'db.internal' -p '5433' -U 'app' -d 'shop'", dbDumpCommand(pg))
+	assert.Equal(t, "psql --no-password -X -q -v ON_ERROR_STOP=1 -h 'db.internal' -p '5433' -U 'app' -d 'shop'", dbRestoreCommand(pg))
+
+	my := DBOptions{Engine: DBEngineMySQL, Database: "shop", DBUser: "app", DBPort: "3307"}
+	assert.Equal(t, "mysqldump --single-transaction --routines --triggers -P '3307' -u 'app' 'shop'", dbDumpCommand(my))
+	assert.Equal(t, "mysql -P '3307' -u 'app' 'shop'", dbRestoreCommand(my))
+}
+
+func TestDBCommand(t *testing.T) {
+	c := &operation{config: &Config{}}
+	opts := DBOptions{Engine: DBEnginePostgres, Database: "shop"}
+	assert.Equal(t, `sh -c 'pg_dump'`, c.dbCommand(opts, "pg_dump", ""))
+	assert.Equal(t, "", c.dbStdin(opts))
+
+	// The database password reaches the tool through its environment
+	opts.Password = "s3cret"
+	assert.Equal(t, `IFS= read -r sshx_db_pw; { printf '%s\n' "$sshx_db_pw"; cat 'dump.sql'; } | sh -c 'IFS= read -r PGPASSWORD; export PGPASSWORD; exec psql'`,
+		c.dbCommand(opts, "psql", "cat 'dump.sql'"))
+	assert.Equal(t, "s3cret\n", c.dbStdin(opts))
+	assert.NotContains(t, c.dbCommand(opts, "psql", ""), "s3cret")
+
+	// sudo reads its password first, then the tool's shell reads the database password
+	c.config.RunAs, c.config.Password = "postgres", "sudo-pw"
+	opts.Engine = DBEngineMySQL
+	assert.Equal(t, `IFS= read -r sshx_sudo_pw; IFS= read -r sshx_db_pw; { printf '%s\n' "$sshx_sudo_pw" "$sshx_db_pw"; } | `+
+		`sudo -S -p '' -H -u postgres -- sh -c 'IFS= read -r MYSQL_PWD; export MYSQL_PWD; exec mysqldump'`,
+		c.dbCommand(opts, "mysqldump", ""))
+	assert.Equal(t, "sudo-pw\ns3cret\n", c.dbStdin(opts))
+}
+
+func TestRunDBCommand_SendsPasswordsOnStdin(t *testing.T) {
+	var command, line string
+	client := startExecServer(t, func(cmd string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		command = cmd
+		line, _ = bufio.NewReader(channel).ReadString('\n')
+		_, _ = channel.Write([]byte("ok\n"))
+		return 0
+	})
+
+	c := &operation{client: client, config: &Config{}}
+	output, err := c.runDBCommand(DBOptions{Engine: DBEnginePostgres, Password: "s3cret"}, "true")
+	require.NoError(t, err)
+	assert.Equal(t, "ok\n", output)
+	assert.Equal(t, "true", command)
+	assert.Equal(t, "s3cret\n", line)
+}
+
+func TestSHA256Helpers(t *testing.T) {
+	const sum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	parsed, err := parseSHA256(sum + "  /tmp/sshx-script-ab-dump.sql.gz\n")
+	require.NoError(t, err)
+	assert.Equal(t, sum, parsed)
+	_, err = parseSHA256("sha256sum: command not found")
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0o600))
+	size, local, err := fileSHA256(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), size)
+	assert.Equal(t, sum, local)
+
+	assert.Equal(t, `{ sha256sum '/tmp/a b' 2>/dev/null || shasum -a 256 '/tmp/a b'; }`, sha256Command("/tmp/a b"))
+}
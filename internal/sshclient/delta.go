@@ -0,0 +1,461 @@
+package sshclient
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/talkincode/sshmcp/pkg/errutil"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// deltaMinBlock and deltaMaxBlock bound the block size of delta
+	// uploads; sizes are multiples of deltaMinBlock, so the in-place page
+	// writes of databases and disk images change whole blocks
+	deltaMinBlock = 4 << 10
+	deltaMaxBlock = 128 << 10
+	// deltaMaxLiteral is the most unmatched data held before it is sent
+	deltaMaxLiteral = 1 << 20
+	// adlerMod is the modulus of the Adler-32 weak checksum
+	adlerMod = 65521
+)
+
+// errDeltaUnavailable is returned when a delta upload cannot be done on a
+// host; the file is then uploaded whole
+var errDeltaUnavailable = errors.New("delta transfer unavailable")
+
+// deltaHelper is run on the host with python3 by delta uploads. "sig <file>
+// <block size>" prints the Adler-32, SHA-256 and length of each block of
+// the file; "patch <old> <new>" builds <new> from the instructions on
+// stdin (C: copy a range of <old>, L: literal data, E: end) and prints its
+// SHA-256 and size.
+const deltaHelper = `import hashlib, os, struct, sys, zlib
+def sig(path, size):
+    with open(path, "rb") as f:
+        while True:
+            block = f.read(size)
+            if not block:
+                break
+            sys.stdout.write("%08x %s %d\n" % (zlib.adler32(block) & 0xffffffff, hashlib.sha256(block).hexdigest(), len(block)))
+def read(f, n):
+    data = f.read(n)
+    if len(data) != n:
+        sys.exit("unexpected end of data")
+    return data
+def patch(old, new):
+    ops, digest, total = sys.stdin.buffer, hashlib.sha256(), 0
+    fd = os.open(new, os.O_WRONLY | os.O_CREAT | os.O_TRUNC, 0o600)
+    with open(old, "rb") as src, os.fdopen(fd, "wb") as dst:
+        while True:
+            op = read(ops, 1)
+            if op == b"E":
+                break
+            if op == b"C":
+                offset, length = struct.unpack(">QQ", read(ops, 16))
+                src.seek(offset)
+                reader = src
+            elif op == b"L":
+                length, = struct.unpack(">Q", read(ops, 8))
+                reader = ops
+            else:
+                sys.exit("unknown instruction %r" % op)
+            while length > 0:
+                data = read(reader, min(length, 1 << 20))
+                dst.write(data)
+                digest.update(data)
+                total += len(data)
+                length -= len(data)
+        dst.flush()
+        os.fsync(dst.fileno())
+    print("%s %d" % (digest.hexdigest(), total))
+if sys.argv[1] == "sig":
+    sig(sys.argv[2], int(sys.argv[3]))
+else:
+    patch(sys.argv[2], sys.argv[3])
+`
+
+// DeltaStats tells how much of a delta upload was sent and how much was
+// reused from the file it replaced
+type DeltaStats struct {
+	Size   int64 // Size of the new file
+	Sent   int64 // Bytes sent as literal data
+	Reused int64 // Bytes copied from the previous file on the host
+}
+
+// deltaBlock is the signature of one block of the remote file
+type deltaBlock struct {
+	weak   uint32
+	strong [sha256.Size]byte
+	length int
+}
+
+// deltaSignature lists the blocks of the remote file, indexed by weak
+// checksum for the rolling search
+type deltaSignature struct {
+	blockSize int
+	blocks    []deltaBlock
+	index     map[uint32][]int
+}
+
+// deltaBlockSize picks the block size for a file of size bytes like rsync
+// does, about its square root, so both the signature and the data sent
+// for a small change stay small
+func deltaBlockSize(size int64) int {
+	blockSize := int(math.Sqrt(float64(size)))
+	blockSize = (blockSize + deltaMinBlock - 1) / deltaMinBlock * deltaMinBlock
+	return min(max(blockSize, deltaMinBlock), deltaMaxBlock)
+}
+
+// parseDeltaSignature reads the output of the helper's sig command
+func parseDeltaSignature(output string, blockSize int) (*deltaSignature, error) {
+	sig := &deltaSignature{blockSize: blockSize, index: make(map[uint32][]int)}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid block signature '%s'", line)
+		}
+		weak, err := strconv.ParseUint(fields[0], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block signature '%s'", line)
+		}
+		strong, err := hex.DecodeString(fields[1])
+		if err != nil || len(strong) != sha256.Size {
+			return nil, fmt.Errorf("invalid block signature '%s'", line)
+		}
+		length, err := strconv.Atoi(fields[2])
+		if err != nil || length <= 0 || length > blockSize {
+			return nil, fmt.Errorf("invalid block signature '%s'", line)
+		}
+		block := deltaBlock{weak: uint32(weak), length: length}
+		copy(block.strong[:], strong)
+		// Only whole blocks are searched for; a short last block can
+		// only match the end of the file
+		if length == blockSize {
+			sig.index[block.weak] = append(sig.index[block.weak], len(sig.blocks))
+		}
+		sig.blocks = append(sig.blocks, block)
+	}
+	return sig, nil
+}
+
+// find returns the block whose content is window, preferring block next
+// so consecutive blocks coalesce into one copy. The strong checksum is
+// only computed when the weak one matched.
+func (s *deltaSignature) find(weak uint32, window []byte, next int) (int, bool) {
+	candidates := s.index[weak]
+	if len(candidates) == 0 {
+		return 0, false
+	}
+	strong := sha256.Sum256(window)
+	if slices.Contains(candidates, next) && s.blocks[next].strong == strong {
+		return next, true
+	}
+	for _, i := range candidates {
+		if s.blocks[i].strong == strong {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// rollingAdler is an Adler-32 checksum of a window that slides over the
+// data one byte at a time
+type rollingAdler struct {
+	a, b   uint32
+	length uint32
+}
+
+func newRollingAdler(window []byte) rollingAdler {
+	sum := adler32.Checksum(window)
+	return rollingAdler{a: sum & 0xffff, b: sum >> 16, length: uint32(len(window))}
+}
+
+func (r *rollingAdler) sum() uint32 {
+	return r.b<<16 | r.a
+}
+
+// roll moves the window one byte on: out leaves it, in enters it
+func (r *rollingAdler) roll(out, in byte) {
+	r.a = (r.a + adlerMod - uint32(out) + uint32(in)) % adlerMod
+	r.b = (r.b + 2*adlerMod - uint32(uint64(r.length)*uint64(out)%adlerMod) + r.a - 1) % adlerMod
+}
+
+// deltaWriter encodes the instructions of the helper's patch command
+type deltaWriter struct {
+	w     *bufio.Writer
+	hash  hash.Hash
+	stats DeltaStats
+	// copyOffset and copyLength are the pending copy, extended while the
+	// blocks found follow each other
+	copyOffset, copyLength int64
+}
+
+func (d *deltaWriter) literal(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := d.flushCopy(); err != nil {
+		return err
+	}
+	header := [9]byte{'L'}
+	binary.BigEndian.PutUint64(header[1:], uint64(len(data)))
+	if _, err := d.w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := d.w.Write(data); err != nil {
+		return err
+	}
+	d.hash.Write(data)
+	d.stats.Sent += int64(len(data))
+	return nil
+}
+
+func (d *deltaWriter) copyBlock(offset int64, data []byte) error {
+	if d.copyLength > 0 && offset != d.copyOffset+d.copyLength {
+		if err := d.flushCopy(); err != nil {
+			return err
+		}
+	}
+	if d.copyLength == 0 {
+		d.copyOffset = offset
+	}
+	d.copyLength += int64(len(data))
+	d.hash.Write(data)
+	d.stats.Reused += int64(len(data))
+	return nil
+}
+
+func (d *deltaWriter) flushCopy() error {
+	if d.copyLength == 0 {
+		return nil
+	}
+	op := [17]byte{'C'}
+	binary.BigEndian.PutUint64(op[1:], uint64(d.copyOffset))
+	binary.BigEndian.PutUint64(op[9:], uint64(d.copyLength))
+	d.copyLength = 0
+	_, err := d.w.Write(op[:])
+	return err
+}
+
+// next is the block that would extend the pending copy
+func (d *deltaWriter) next(blockSize int) int {
+	if d.copyLength == 0 {
+		return -1
+	}
+	return int((d.copyOffset + d.copyLength) / int64(blockSize))
+}
+
+func (d *deltaWriter) finish() error {
+	if err := d.flushCopy(); err != nil {
+		return err
+	}
+	if err := d.w.WriteByte('E'); err != nil {
+		return err
+	}
+	return d.w.Flush()
+}
+
+// writeDelta encodes local as instructions that rebuild it from the blocks
+// of sig, the rsync way: a weak checksum rolls over every byte offset, and
+// a window whose weak and strong checksums match a block is copied from
+// the remote file instead of sent. It returns the stats and the SHA-256 of
+// local.
+func writeDelta(w io.Writer, local io.Reader, sig *deltaSignature) (DeltaStats, string, error) {
+	d := &deltaWriter{w: bufio.NewWriterSize(w, 256<<10), hash: sha256.New()}
+	blockSize := sig.blockSize
+	var buf []byte
+	// start is where unsent data begins in buf, p where the window does
+	start, p := 0, 0
+	eof := false
+	var weak rollingAdler
+	rolled := false
+
+	for {
+		if len(buf) < p+blockSize+1 && !eof {
+			// Drop what was sent and read on
+			buf = buf[:copy(buf, buf[start:])]
+			p -= start
+			start = 0
+			for len(buf) < p+blockSize+1 && !eof {
+				buf = slices.Grow(buf, max(blockSize, 64<<10))
+				n, err := local.Read(buf[len(buf):cap(buf)])
+				buf = buf[:len(buf)+n]
+				if errors.Is(err, io.EOF) {
+					eof = true
+				} else if err != nil {
+					return d.stats, "", fmt.Errorf("failed to read local file: %w", err)
+				}
+			}
+		}
+		if len(buf)-p < blockSize {
+			break
+		}
+
+		window := buf[p : p+blockSize]
+		if !rolled {
+			weak = newRollingAdler(window)
+			rolled = true
+		}
+		if i, ok := sig.find(weak.sum(), window, d.next(blockSize)); ok {
+			if err := d.literal(buf[start:p]); err != nil {
+				return d.stats, "", err
+			}
+			if err := d.copyBlock(int64(i)*int64(blockSize), window); err != nil {
+				return d.stats, "", err
+			}
+			p += blockSize
+			start = p
+			rolled = false
+			continue
+		}
+		if p-start >= deltaMaxLiteral {
+			if err := d.literal(buf[start:p]); err != nil {
+				return d.stats, "", err
+			}
+			start = p
+		}
+		if len(buf) > p+blockSize {
+			weak.roll(buf[p], buf[p+blockSize])
+		} else {
+			rolled = false
+		}
+		p++
+	}
+
+	// The rest is shorter than a block: it is the last block of the
+	// remote file or sent as it is
+	tail := buf[p:]
+	if n := len(sig.blocks); len(tail) > 0 && n > 0 && sig.blocks[n-1].length == len(tail) && sig.blocks[n-1].strong == sha256.Sum256(tail) {
+		if err := d.literal(buf[start:p]); err != nil {
+			return d.stats, "", err
+		}
+		if err := d.copyBlock(int64(n-1)*int64(blockSize), tail); err != nil {
+			return d.stats, "", err
+		}
+	} else if err := d.literal(buf[start:]); err != nil {
+		return d.stats, "", err
+	}
+	if err := d.finish(); err != nil {
+		return d.stats, "", err
+	}
+	d.stats.Size = d.stats.Sent + d.stats.Reused
+	return d.stats, hex.EncodeToString(d.hash.Sum(nil)), nil
+}
+
+// uploadDelta replaces RemotePath with local like an atomic upload, but
+// sends only what differs from the file it replaces: the host returns the
+// checksums of that file's blocks and builds the new file from them and
+// the data sent. Both ends run deltaHelper, which needs python3 on the
+// host; errDeltaUnavailable is returned without it, and when there is no
+// previous file.
+func (c *operation) uploadDelta(local *os.File) (stats DeltaStats, err error) {
+	if isPowerShell(c.config.Shell) {
+		return stats, fmt.Errorf("%w on PowerShell hosts", errDeltaUnavailable)
+	}
+	_, err = atomicReplaceRemote(c.sftpClient, c.config.RemotePath, c.config.UploadBackup, func(target, tempPath string, previous os.FileInfo) (int64, error) {
+		if previous == nil || previous.Size() == 0 {
+			return 0, fmt.Errorf("%w: there is no previous file to reuse", errDeltaUnavailable)
+		}
+		blockSize := deltaBlockSize(previous.Size())
+		output, helperErr := runDeltaHelper(c.client, nil, "sig", target, strconv.Itoa(blockSize))
+		if helperErr != nil {
+			return 0, helperErr
+		}
+		sig, helperErr := parseDeltaSignature(output, blockSize)
+		if helperErr != nil {
+			return 0, helperErr
+		}
+
+		var sum string
+		output, helperErr = runDeltaHelper(c.client, func(w io.Writer) error {
+			var writeErr error
+			stats, sum, writeErr = writeDelta(w, local, sig)
+			return writeErr
+		}, "patch", target, tempPath)
+		if helperErr != nil {
+			return 0, helperErr
+		}
+		if result := strings.Fields(output); len(result) != 2 || result[0] != sum || result[1] != strconv.FormatInt(stats.Size, 10) {
+			return 0, fmt.Errorf("delta transfer verification failed: the host built %s, expected SHA-256 %s and %d bytes", strings.TrimSpace(output), sum, stats.Size)
+		}
+		return stats.Size, keepRemoteAttributes(c.sftpClient, tempPath, previous, c.config.UploadOwnership.Mode)
+	})
+	return stats, err
+}
+
+// keepRemoteAttributes gives tempPath mode, or the permissions of previous,
+// and where allowed the owner of previous
+func keepRemoteAttributes(sftpClient *sftp.Client, tempPath string, previous os.FileInfo, mode os.FileMode) error {
+	if mode == 0 {
+		mode = previous.Mode().Perm()
+	}
+	if err := sftpClient.Chmod(tempPath, mode); err != nil {
+		return fmt.Errorf("failed to chmod %s: %w", tempPath, err)
+	}
+	if stat, ok := previous.Sys().(*sftp.FileStat); ok {
+		if err := sftpClient.Chown(tempPath, int(stat.UID), int(stat.GID)); err != nil {
+			logger.GetLogger().Debug("keeping the owner of %s failed: %v", tempPath, err)
+		}
+	}
+	return nil
+}
+
+// runDeltaHelper runs deltaHelper with args on the host, feeding its stdin
+// with feed when set, and returns its output
+func runDeltaHelper(client *ssh.Client, feed func(io.Writer) error, args ...string) (output string, err error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	defer CloseIgnore(&err, session, io.EOF)
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return "", fmt.Errorf("failed to open stdin: %w", err)
+	}
+	if err = session.Start(BuildCommand(append([]string{"python3", "-c", deltaHelper}, args...)...)); err != nil {
+		return "", fmt.Errorf("failed to start the delta helper: %w", err)
+	}
+	var feedErr error
+	if feed != nil {
+		feedErr = feed(stdin)
+	}
+	_ = stdin.Close() //nolint:errcheck // the exit status tells whether the helper got everything
+
+	// The helper's own error explains a failed write better than the write
+	if err = session.Wait(); err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitStatus() == 127 {
+			return "", fmt.Errorf("%w: python3 is not installed on the host", errDeltaUnavailable)
+		}
+		if enhancedErr := errutil.EnhanceError(err, "", stderr.String()); enhancedErr != nil {
+			err = enhancedErr
+		}
+		return "", fmt.Errorf("delta helper failed: %w", err)
+	}
+	if feedErr != nil {
+		return "", fmt.Errorf("failed to send the delta: %w", feedErr)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,165 @@
+package sshclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/adler32"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signatureOf returns the output of the helper's sig command for data
+func signatureOf(data []byte, blockSize int) string {
+	var out strings.Builder
+	for offset := 0; offset < len(data); offset += blockSize {
+		block := data[offset:min(offset+blockSize, len(data))]
+		strong := sha256.Sum256(block)
+		fmt.Fprintf(&out, "%08x %s %d\n", adler32.Checksum(block), hex.EncodeToString(strong[:]), len(block))
+	}
+	return out.String()
+}
+
+// applyDelta rebuilds a file from old and the instructions of writeDelta,
+// as the helper's patch command does
+func applyDelta(t *testing.T, old, delta []byte) []byte {
+	t.Helper()
+	var out []byte
+	for {
+		require.NotEmpty(t, delta, "instructions end without E")
+		op := delta[0]
+		switch op {
+		case 'E':
+			require.Len(t, delta, 1)
+			return out
+		case 'C':
+			offset, length := binary.BigEndian.Uint64(delta[1:]), binary.BigEndian.Uint64(delta[9:])
+			out = append(out, old[offset:offset+length]...)
+			delta = delta[17:]
+		case 'L':
+			length := binary.BigEndian.Uint64(delta[1:])
+			out = append(out, delta[9:9+length]...)
+			delta = delta[9+length:]
+		default:
+			t.Fatalf("unknown instruction %q", op)
+		}
+	}
+}
+
+func TestRollingAdler(t *testing.T) {
+	data := make([]byte, 5000)
+	rand.New(rand.NewSource(1)).Read(data)
+	const window = 1024
+	weak := newRollingAdler(data[:window])
+	for p := 1; p+window <= len(data); p++ {
+		weak.roll(data[p-1], data[p+window-1])
+		require.Equal(t, adler32.Checksum(data[p:p+window]), weak.sum(), "window at %d", p)
+	}
+}
+
+func TestDeltaBlockSize(t *testing.T) {
+	assert.Equal(t, deltaMinBlock, deltaBlockSize(100))
+	assert.Equal(t, 12<<10, deltaBlockSize(100<<20))
+	assert.Equal(t, deltaMaxBlock, deltaBlockSize(20<<30))
+}
+
+func TestWriteDelta(t *testing.T) {
+	const blockSize = deltaMinBlock
+	random := rand.New(rand.NewSource(2))
+	old := make([]byte, 40*blockSize+1234)
+	random.Read(old)
+	noise := make([]byte, 300)
+	random.Read(noise)
+
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+	changed := bytes.Clone(old)
+	copy(changed[17*blockSize+100:], noise)
+
+	tests := []struct {
+		name     string
+		local    []byte
+		maxSent  int64
+		minReuse int64
+	}{
+		{"identical", old, 0, int64(len(old))},
+		{"changed in place", changed, blockSize, int64(len(old) - blockSize)},
+		{"insertion shifts the rest", join(old[:5*blockSize+7], noise, old[5*blockSize+7:]), 2*blockSize + 300, int64(len(old) - 2*blockSize)},
+		{"appended", join(old, noise), int64(len(noise)) + 1234, 40 * blockSize},
+		{"truncated", old[:len(old)/2], blockSize, int64(len(old)/2 - blockSize)},
+		{"unrelated", noise, int64(len(noise)), 0},
+		{"empty", nil, 0, 0},
+	}
+	sig, err := parseDeltaSignature(signatureOf(old, blockSize), blockSize)
+	require.NoError(t, err)
+	require.Len(t, sig.blocks, 41)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delta bytes.Buffer
+			stats, sum, err := writeDelta(&delta, bytes.NewReader(tt.local), sig)
+			require.NoError(t, err)
+			assert.Equal(t, tt.local, applyDelta(t, old, delta.Bytes()))
+
+			expected := sha256.Sum256(tt.local)
+			assert.Equal(t, hex.EncodeToString(expected[:]), sum)
+			assert.EqualValues(t, len(tt.local), stats.Size)
+			assert.Equal(t, stats.Size, stats.Sent+stats.Reused)
+			assert.LessOrEqual(t, stats.Sent, tt.maxSent)
+			assert.GreaterOrEqual(t, stats.Reused, tt.minReuse)
+		})
+	}
+
+	_, err = parseDeltaSignature("0000abcd tooshort 4096\n", blockSize)
+	assert.ErrorContains(t, err, "invalid block signature")
+}
+
+// TestDeltaHelper runs the helper that delta uploads start on the host
+// against local files
+func TestDeltaHelper(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not installed")
+	}
+	dir := t.TempDir()
+	random := rand.New(rand.NewSource(3))
+	old := make([]byte, 30*deltaMinBlock+99)
+	random.Read(old)
+	oldPath := filepath.Join(dir, "app.db")
+	require.NoError(t, os.WriteFile(oldPath, old, 0o600))
+
+	run := func(stdin io.Reader, args ...string) string {
+		cmd := exec.Command("sh", "-c", BuildCommand(append([]string{"python3", "-c", deltaHelper}, args...)...)) // #nosec G204 -- test command
+		cmd.Stdin = stdin
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return string(output)
+	}
+
+	output := run(nil, "sig", oldPath, fmt.Sprint(deltaMinBlock))
+	assert.Equal(t, signatureOf(old, deltaMinBlock), output)
+	sig, err := parseDeltaSignature(output, deltaMinBlock)
+	require.NoError(t, err)
+
+	local := bytes.Clone(old)
+	random.Read(local[12*deltaMinBlock : 12*deltaMinBlock+500])
+	local = append(local, "appended"...)
+	var delta bytes.Buffer
+	stats, sum, err := writeDelta(&delta, bytes.NewReader(local), sig)
+	require.NoError(t, err)
+
+	newPath := filepath.Join(dir, "app.db"+AtomicTempSuffix)
+	output = run(&delta, "patch", oldPath, newPath)
+	assert.Equal(t, fmt.Sprintf("%s %d\n", sum, stats.Size), output)
+	rebuilt, err := os.ReadFile(newPath)
+	require.NoError(t, err)
+	assert.Equal(t, local, rebuilt)
+}
@@ -0,0 +1,402 @@
+package sshclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/talkincode/sshmcp/pkg/errutil"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// DefaultDeviceTimeout bounds the wait for the prompt after each line
+	// sent to a network device
+	DefaultDeviceTimeout = 60 * time.Second
+	// deviceExitWait is how long the device may take to close the session
+	// after the exit command
+	deviceExitWait = 2 * time.Second
+	// deviceTemplatePrefix starts a command line that runs a template of
+	// the device profile, e.g. "@backup"
+	deviceTemplatePrefix = "@"
+)
+
+// DeviceProfile describes how to drive the CLI of a network device (switch,
+// router, firewall) that rejects exec requests or has no SFTP: commands are
+// typed into an interactive shell channel one line at a time and the
+// output of each ends when the prompt shows up again. Patterns are kept as
+// strings so a Config holding a profile can be encoded.
+type DeviceProfile struct {
+	Name string
+	// Prompt matches the whole last line of output while the device waits
+	// for input, e.g. `[\w.\-/()]+[>#]` for "switch1#"
+	Prompt string
+	// Setup lines are sent once after login, e.g. to turn paging off
+	Setup []string
+	// Exit is sent to leave the CLI after the last command
+	Exit string
+	// Errors match output lines that mean the command was refused
+	Errors []string
+	// PTY requests a pseudo-terminal, which some CLIs need to show a prompt
+	PTY bool
+	// LineEnding ends every line sent (default "\n")
+	LineEnding string
+	// Templates are named command sequences, one command per line, run
+	// with "@<name>"
+	Templates map[string]string
+	// Timeout bounds the wait for the prompt (0 = DefaultDeviceTimeout)
+	Timeout time.Duration
+}
+
+// DeviceProfiles are the built-in device profiles, by name
+var DeviceProfiles = map[string]DeviceProfile{
+	"cisco_ios": {
+		Name:   "cisco_ios",
+		Prompt: `[\w.\-/():]+[>#]`,
+		Setup:  []string{"terminal length 0", "terminal width 511"},
+		Exit:   "exit",
+		Errors: []string{`^\s*% ?(Invalid|Incomplete|Ambiguous|Unknown|Bad)`},
+		Templates: map[string]string{
+			"version":    "show version",
+			"config":     "show running-config",
+			"interfaces": "show ip interface brief",
+			"save":       "write memory",
+		},
+	},
+	"junos": {
+		Name:   "junos",
+		Prompt: `[\w.\-]+@[\w.\-]+[>#%]`,
+		Setup:  []string{"set cli screen-length 0", "set cli screen-width 0"},
+		Exit:   "exit",
+		Errors: []string{`^\s*(error:|syntax error|unknown command)`},
+		Templates: map[string]string{
+			"version":    "show version",
+			"config":     "show configuration | display set",
+			"interfaces": "show interfaces terse",
+		},
+	},
+	"routeros": {
+		Name:       "routeros",
+		Prompt:     `\[[^\]\r\n]+\]\s*(/[^>\r\n]*)?>`,
+		Exit:       "/quit",
+		Errors:     []string{`^\s*(bad command name|syntax error|expected end of command|failure:|no such item)`},
+		LineEnding: "\r\n",
+		PTY:        true,
+		Templates: map[string]string{
+			"version":    "/system resource print",
+			"config":     "/export",
+			"interfaces": "/interface print without-paging",
+			"save":       "/system backup save",
+		},
+	},
+	"generic": {
+		Name:   "generic",
+		Prompt: `[^\r\n]*[>#$%]`,
+		Exit:   "exit",
+		PTY:    true,
+	},
+}
+
+// DeviceProfileNames lists the built-in device profiles, sorted
+func DeviceProfileNames() []string {
+	return slices.Sorted(maps.Keys(DeviceProfiles))
+}
+
+// deviceMatcher holds the compiled patterns of a profile
+type deviceMatcher struct {
+	prompt *regexp.Regexp
+	errors []*regexp.Regexp
+}
+
+// ansiEscape matches the terminal control sequences some CLIs color and
+// redraw their output with
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]|\x1b[()][A-Z0-9]|\x1b[=>]`)
+
+// Validate checks that the patterns of the profile compile
+func (p *DeviceProfile) Validate() error {
+	_, err := p.compile()
+	return err
+}
+
+// compile compiles the prompt and error patterns
+func (p *DeviceProfile) compile() (*deviceMatcher, error) {
+	if p.Prompt == "" {
+		return nil, fmt.Errorf("device profile %s has no prompt", p.Name)
+	}
+	prompt, err := regexp.Compile(`^(?:` + p.Prompt + `)\s*$`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt of device profile %s: %w", p.Name, err)
+	}
+	matcher := &deviceMatcher{prompt: prompt}
+	for _, pattern := range p.Errors {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid error pattern of device profile %s: %w", p.Name, err)
+		}
+		matcher.errors = append(matcher.errors, re)
+	}
+	return matcher, nil
+}
+
+// expand splits command into the lines sent to the device, replacing
+// "@<name>" lines with the template of that name
+func (p *DeviceProfile) expand(command string) ([]string, error) {
+	var lines []string
+	for _, line := range strings.Split(command, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, ok := strings.CutPrefix(line, deviceTemplatePrefix)
+		if !ok {
+			lines = append(lines, line)
+			continue
+		}
+		template, ok := p.Templates[name]
+		if !ok {
+			return nil, fmt.Errorf("device profile %s has no template '%s' (templates: %s)",
+				p.Name, name, strings.Join(slices.Sorted(maps.Keys(p.Templates)), ", "))
+		}
+		expanded, err := p.expand(template)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, expanded...)
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no command given")
+	}
+	return lines, nil
+}
+
+// checkDevice rejects the options that need a POSIX shell on the host
+func checkDevice(config *Config) error {
+	if config.Device == nil {
+		return nil
+	}
+	switch {
+	case config.RunAs != "":
+		return fmt.Errorf("run as another user is not supported on network devices")
+	case config.Container != "":
+		return fmt.Errorf("containers are not supported on network devices")
+	case config.WorkDir != "":
+		return fmt.Errorf("a working directory is not supported on network devices")
+	case config.Sandbox.enabled():
+		return fmt.Errorf("the sandbox is not supported on network devices")
+	case config.BinaryOutput:
+		return fmt.Errorf("binary output is not supported on network devices")
+	}
+	return nil
+}
+
+// newSFTPClient opens an SFTP session, unless the host is a network device
+// that has no SFTP subsystem
+func newSFTPClient(client *ssh.Client, config *Config) (*sftp.Client, error) {
+	if config != nil && config.Device != nil {
+		return nil, fmt.Errorf("SFTP is not available on network device %s (profile %s); run CLI commands instead", config.Host, config.Device.Name)
+	}
+	return sftp.NewClient(client)
+}
+
+// shellOutput collects what an interactive session sends and signals each
+// write
+type shellOutput struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+	notify chan struct{}
+}
+
+// Write appends p and wakes the reader
+func (o *shellOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	o.buffer.Write(p)
+	o.mu.Unlock()
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// deviceShell is an interactive shell channel driven line by line
+type deviceShell struct {
+	profile *DeviceProfile
+	matcher *deviceMatcher
+	stdin   io.Writer
+	output  *shellOutput
+	done    chan struct{}
+	timeout time.Duration
+}
+
+// waitPrompt waits for the prompt and returns the output before it, with
+// terminal control sequences and carriage returns removed, and the prompt
+func (s *deviceShell) waitPrompt() (output, prompt string, err error) {
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	for {
+		s.output.mu.Lock()
+		text := terminalText(s.output.buffer.String())
+		start := strings.LastIndex(text, "\n") + 1
+		if s.matcher.prompt.MatchString(text[start:]) {
+			s.output.buffer.Reset()
+			s.output.mu.Unlock()
+			return text[:start], strings.TrimSpace(text[start:]), nil
+		}
+		s.output.mu.Unlock()
+
+		select {
+		case <-s.output.notify:
+		case <-s.done:
+			return text, "", fmt.Errorf("the device closed the session before showing its prompt")
+		case <-timer.C:
+			return text, "", fmt.Errorf("no %s prompt after %s (last output: %q)", s.profile.Name, s.timeout, lastOutputLine(text))
+		}
+	}
+}
+
+// send types a line into the shell
+func (s *deviceShell) send(line string) error {
+	_, err := io.WriteString(s.stdin, line+s.profile.LineEnding)
+	return err
+}
+
+// run sends a command and returns its output without the echoed command
+func (s *deviceShell) run(command string) (string, string, error) {
+	if err := s.send(command); err != nil {
+		return "", "", fmt.Errorf("failed to send '%s': %w", command, err)
+	}
+	output, prompt, err := s.waitPrompt()
+	if first, rest, _ := strings.Cut(output, "\n"); strings.HasSuffix(strings.TrimSpace(first), command) {
+		output = rest
+	}
+	return output, prompt, err
+}
+
+// refused returns the first output line an error pattern matches
+func (m *deviceMatcher) refused(output string) (string, bool) {
+	for _, line := range strings.Split(output, "\n") {
+		for _, re := range m.errors {
+			if re.MatchString(line) {
+				return strings.TrimSpace(line), true
+			}
+		}
+	}
+	return "", false
+}
+
+// terminalText removes terminal control sequences and carriage returns
+func terminalText(output string) string {
+	return strings.ReplaceAll(ansiEscape.ReplaceAllString(output, ""), "\r", "")
+}
+
+// lastOutputLine returns the last non-empty line of text
+func lastOutputLine(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n "), "\n")
+	return lines[len(lines)-1]
+}
+
+// executeOnDevice runs the command lines on a network device through an
+// interactive shell channel. The output of several commands is returned as
+// a transcript, each command after the prompt it was typed at.
+func (c *operation) executeOnDevice() (output string, err error) {
+	lg := logger.GetLogger()
+	profile := *c.config.Device
+	if profile.LineEnding == "" {
+		profile.LineEnding = "\n"
+	}
+	if profile.Timeout <= 0 {
+		profile.Timeout = DefaultDeviceTimeout
+	}
+	matcher, err := profile.compile()
+	if err != nil {
+		return "", err
+	}
+	commands, err := profile.expand(c.config.Command)
+	if err != nil {
+		return "", err
+	}
+
+	session, err := c.client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	defer errutil.HandleCloseError(&err, session)
+	if profile.PTY {
+		if err = session.RequestPty("vt100", 0, 511, ssh.TerminalModes{ssh.TTY_OP_ISPEED: 14400, ssh.TTY_OP_OSPEED: 14400}); err != nil {
+			return "", fmt.Errorf("failed to request PTY: %w", err)
+		}
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return "", fmt.Errorf("failed to open stdin: %w", err)
+	}
+	shell := &deviceShell{
+		profile: &profile,
+		matcher: matcher,
+		stdin:   c.recordInput(stdin),
+		output:  &shellOutput{notify: make(chan struct{}, 1)},
+		done:    make(chan struct{}),
+		timeout: profile.Timeout,
+	}
+	session.Stdout = c.tee(shell.output)
+	session.Stderr = c.tee(shell.output)
+	if err = session.Shell(); err != nil {
+		return "", fmt.Errorf("failed to start shell: %w", err)
+	}
+	go func() {
+		_ = session.Wait() //nolint:errcheck // the end of the session is all that matters
+		close(shell.done)
+	}()
+
+	lg.Debug("Driving %s as a %s device", c.config.Host, profile.Name)
+	_, prompt, err := shell.waitPrompt()
+	if err != nil {
+		return "", err
+	}
+	for _, line := range profile.Setup {
+		var setupOutput string
+		if setupOutput, prompt, err = shell.run(line); err != nil {
+			return "", err
+		}
+		if refusal, ok := matcher.refused(setupOutput); ok {
+			lg.Debug("%s refused '%s': %s", c.config.Host, line, refusal)
+		}
+	}
+
+	var transcript strings.Builder
+	for _, command := range commands {
+		typedAt := prompt
+		var commandOutput string
+		commandOutput, prompt, err = shell.run(command)
+		if len(commands) > 1 {
+			transcript.WriteString(typedAt + " " + command + "\n")
+		}
+		transcript.WriteString(commandOutput)
+		if err != nil {
+			return transcript.String(), err
+		}
+		if refusal, ok := matcher.refused(commandOutput); ok {
+			return transcript.String(), fmt.Errorf("%s refused '%s': %s", c.config.Host, command, refusal)
+		}
+	}
+
+	if profile.Exit != "" {
+		_ = shell.send(profile.Exit) //nolint:errcheck // the session is closed anyway
+		select {
+		case <-shell.done:
+		case <-time.After(deviceExitWait):
+		}
+	}
+
+	output, c.outputTruncated = CapOutput([]byte(transcript.String()), c.config.MaxOutputBytes)
+	return output, nil
+}
@@ -0,0 +1,134 @@
+package sshclient
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeSwitchReplies are the answers of the fake switch CLI, by command
+var fakeSwitchReplies = map[string]string{
+	"terminal length 0":  "",
+	"terminal width 511": "",
+	"show version":       "Cisco IOS Software, Version 15.2(4)E10\r\nsw1 uptime is 3 weeks\r\n",
+	"show clock":         "*10:15:42.123 UTC Fri Oct 16 2026\r\n",
+}
+
+// listenFakeSwitch starts an SSH server that behaves like a switch: exec
+// requests are refused, the shell echoes each line and answers it after a
+// "sw1#" prompt
+func listenFakeSwitch(t *testing.T) string {
+	return listenInteractive(t, func(command string) func(ssh.Channel) {
+		if command != "" {
+			return nil
+		}
+		return runFakeSwitchCLI
+	})
+}
+
+func runFakeSwitchCLI(channel ssh.Channel) {
+	defer func() { _ = channel.Close() }()
+	_, _ = channel.Write([]byte("\r\nUnauthorized access prohibited\r\n\r\nsw1#"))
+	reader := bufio.NewReader(channel)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimSpace(line)
+		_, _ = channel.Write([]byte(command + "\r\n"))
+		if command == "exit" {
+			return
+		}
+		reply, ok := fakeSwitchReplies[command]
+		if !ok {
+			reply = "                ^\r\n% Invalid input detected at '^' marker.\r\n"
+		}
+		// Write the reply in pieces, as a slow device would
+		for _, piece := range []string{reply[:len(reply)/2], reply[len(reply)/2:], "\r\n", "sw1#"} {
+			_, _ = channel.Write([]byte(piece))
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func connectFakeSwitch(t *testing.T) *SSHClient {
+	t.Helper()
+	profile := DeviceProfiles["cisco_ios"]
+	profile.Timeout = 5 * time.Second
+	return connectInteractive(t, listenFakeSwitch(t), &profile)
+}
+
+func TestExecuteOnDevice(t *testing.T) {
+	client := connectFakeSwitch(t)
+
+	output, err := client.ExecuteCommandWithOutput(Request{Command: "show version"})
+	require.NoError(t, err)
+	assert.Equal(t, "Cisco IOS Software, Version 15.2(4)E10\nsw1 uptime is 3 weeks\n\n", output)
+
+	output, err = client.ExecuteCommandWithOutput(Request{Command: "@version\nshow clock"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "sw1# show version\nCisco IOS Software")
+	assert.Contains(t, output, "sw1# show clock\n*10:15:42.123 UTC")
+
+	output, err = client.ExecuteCommandWithOutput(Request{Command: "show bogus"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refused 'show bogus': % Invalid input")
+	assert.Contains(t, output, "% Invalid input detected", "refused output is kept for the caller")
+
+	_, err = client.ExecuteCommandWithOutput(Request{Command: "@backup"})
+	assert.ErrorContains(t, err, "no template 'backup'")
+	_, err = client.ExecuteCommandWithOutput(Request{Command: "show version", RunAs: "root"})
+	assert.ErrorContains(t, err, "not supported on network devices")
+
+	err = client.ExecuteSftp(Request{SftpAction: "list", RemotePath: "/"})
+	assert.ErrorContains(t, err, "SFTP is not available on network device")
+}
+
+func TestDeviceProfiles_Prompts(t *testing.T) {
+	prompts := map[string][]string{
+		"cisco_ios": {"sw1#", "sw1>", "core-sw.lab(config-if)# ", "Gi1/0/1#"},
+		"junos":     {"admin@mx1> ", "admin@mx1# ", "root@srx-lab%"},
+		"routeros":  {"[admin@MikroTik] > ", "[admin@MikroTik] /interface> "},
+		"generic":   {"admin@fw01:~$ ", "OPNsense> "},
+	}
+	for _, name := range DeviceProfileNames() {
+		profile := DeviceProfiles[name]
+		matcher, err := profile.compile()
+		require.NoError(t, err, name)
+		for _, prompt := range prompts[name] {
+			assert.True(t, matcher.prompt.MatchString(prompt), "%s prompt %q", name, prompt)
+		}
+	}
+
+	cisco := DeviceProfiles["cisco_ios"]
+	matcher, err := cisco.compile()
+	require.NoError(t, err)
+	assert.False(t, matcher.prompt.MatchString("Cisco IOS Software, Version 15.2"))
+	refusal, ok := matcher.refused("show bogus\n% Invalid input detected at '^' marker.\n")
+	assert.True(t, ok)
+	assert.Equal(t, "% Invalid input detected at '^' marker.", refusal)
+
+	broken := DeviceProfile{Name: "broken", Prompt: "[unclosed"}
+	assert.ErrorContains(t, broken.Validate(), "invalid prompt")
+}
+
+func TestDeviceProfile_Expand(t *testing.T) {
+	profile := DeviceProfile{Name: "lab", Templates: map[string]string{
+		"backup": "show running-config\n@vlans",
+		"vlans":  "show vlan brief",
+	}}
+	lines, err := profile.expand("terminal monitor\n\n @backup \nshow clock")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"terminal monitor", "show running-config", "show vlan brief", "show clock"}, lines)
+
+	_, err = profile.expand("@missing")
+	assert.ErrorContains(t, err, "templates: backup, vlans")
+	_, err = profile.expand(" \n ")
+	assert.ErrorContains(t, err, "no command given")
+}
@@ -0,0 +1,312 @@
+package sshclient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Diagnostic step statuses
+const (
+	DiagnoseOK      = "ok"
+	DiagnoseWarning = "warning"
+	DiagnoseFailed  = "failed"
+	DiagnoseSkipped = "skipped"
+)
+
+// DiagnoseStep is one stage of a connection diagnosis
+type DiagnoseStep struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Detail     string `json:"detail"`
+}
+
+// DiagnoseReport describes every stage of connecting to a host, stopping at
+// the first step that fails
+type DiagnoseReport struct {
+	Host        string         `json:"host"`
+	Port        string         `json:"port"`
+	User        string         `json:"user"`
+	Steps       []DiagnoseStep `json:"steps"`
+	FirstFailed string         `json:"first_failed,omitempty"`
+	Fingerprint string         `json:"fingerprint,omitempty"`
+	Offered     []string       `json:"auth_offered,omitempty"`
+	Attempted   []string       `json:"auth_attempted,omitempty"`
+}
+
+// errProbeDone aborts probe handshakes once they have what they need
+var errProbeDone = errors.New("probe complete")
+
+// probedAuthMethods are checked one per handshake so no credentials are sent
+var probedAuthMethods = []string{"publickey", "password", "keyboard-interactive"}
+
+// Diagnose walks through DNS resolution, TCP connect, the SSH banner, host
+// key verification, the authentication methods offered by the server and a
+// real authentication attempt. The probes never record host keys; the final
+// login honours the config like a normal connection would.
+func Diagnose(config *Config) *DiagnoseReport {
+	cfg := *config
+	if cfg.Port == "" {
+		cfg.Port = DefaultSSHPort
+	}
+	if cfg.User == "" {
+		cfg.User = DefaultSSHUser
+	}
+	timeout := cfg.DialTimeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	report := &DiagnoseReport{Host: cfg.Host, Port: cfg.Port, User: cfg.User}
+	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+
+	steps := []struct {
+		name string
+		run  func() (string, string)
+	}{
+		{"dns", func() (string, string) { return diagnoseDNS(cfg.Host, timeout) }},
+		{"tcp_connect", func() (string, string) { return diagnoseTCP(addr, timeout) }},
+		{"ssh_banner", func() (string, string) { return diagnoseBanner(addr, timeout) }},
+		{"host_key", func() (string, string) { return diagnoseHostKey(&cfg, addr, timeout, report) }},
+		{"auth_methods", func() (string, string) { return diagnoseAuthMethods(&cfg, addr, timeout, report) }},
+		{"authentication", func() (string, string) { return diagnoseAuth(&cfg) }},
+	}
+
+	for _, step := range steps {
+		if report.FirstFailed != "" {
+			report.Steps = append(report.Steps, DiagnoseStep{Name: step.name, Status: DiagnoseSkipped, Detail: "skipped after " + report.FirstFailed + " failed"})
+			continue
+		}
+
+		start := time.Now()
+		status, detail := step.run()
+		report.Steps = append(report.Steps, DiagnoseStep{
+			Name:       step.name,
+			Status:     status,
+			DurationMs: time.Since(start).Milliseconds(),
+			Detail:     detail,
+		})
+		if status == DiagnoseFailed {
+			report.FirstFailed = step.name
+		}
+	}
+	return report
+}
+
+// diagnoseDNS resolves the host name
+func diagnoseDNS(host string, timeout time.Duration) (string, string) {
+	if net.ParseIP(host) != nil {
+		return DiagnoseOK, host + " is an IP address, no lookup needed"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return DiagnoseFailed, fmt.Sprintf("cannot resolve %s: %v", host, err)
+	}
+	return DiagnoseOK, "resolved to " + strings.Join(addrs, ", ")
+}
+
+// diagnoseTCP opens a TCP connection to the SSH port
+func diagnoseTCP(addr string, timeout time.Duration) (string, string) {
+	latency, err := pingTCP(addr, timeout)
+	if err != nil {
+		return DiagnoseFailed, fmt.Sprintf("cannot connect to %s: %v", addr, err)
+	}
+	return DiagnoseOK, fmt.Sprintf("connected to %s in %s", addr, latency.Round(time.Millisecond))
+}
+
+// diagnoseBanner reads the server identification string
+func diagnoseBanner(addr string, timeout time.Duration) (string, string) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return DiagnoseFailed, fmt.Sprintf("cannot connect to %s: %v", addr, err)
+	}
+	defer func() {
+		_ = conn.Close() //nolint:errcheck // probe connection only
+	}()
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return DiagnoseFailed, err.Error()
+	}
+	// Servers may print other lines before the identification string (RFC 4253 4.2)
+	reader := bufio.NewReader(conn)
+	for i := 0; i < 10; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return DiagnoseFailed, fmt.Sprintf("no SSH banner received: %v", err)
+		}
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "SSH-") {
+			return DiagnoseOK, line
+		}
+	}
+	return DiagnoseFailed, "service on this port does not look like an SSH server"
+}
+
+// diagnoseHostKey fetches the server's host key and checks it against the
+// trust stores without recording anything
+func diagnoseHostKey(cfg *Config, addr string, timeout time.Duration, report *DiagnoseReport) (string, string) {
+	var hostKey ssh.PublicKey
+	clientConfig := &ssh.ClientConfig{
+		User: cfg.User,
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errProbeDone
+		},
+		Timeout: timeout,
+	}
+	if err := dialProbe(addr, clientConfig, timeout); hostKey == nil {
+		return DiagnoseFailed, fmt.Sprintf("SSH handshake failed before the host key was received: %v", err)
+	}
+	report.Fingerprint = ssh.FingerprintSHA256(hostKey)
+
+	verifyConfig := *cfg
+	verifyConfig.AcceptUnknownHost = false
+	verifyConfig.AllowInsecureHostKey = false
+	callback, err := getHostKeyCallback(&verifyConfig)
+	if err != nil {
+		return DiagnoseFailed, fmt.Sprintf("cannot load known_hosts: %v", err)
+	}
+
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr) //nolint:errcheck // remote address only refines known_hosts matching
+	err = callback(addr, tcpAddr, hostKey)
+
+	var revokedErr *HostKeyRevokedError
+	var expiredErr *HostKeyTrustExpiredError
+	var keyErr *knownhosts.KeyError
+	switch {
+	case err == nil:
+		return DiagnoseOK, fmt.Sprintf("%s %s is trusted", hostKey.Type(), report.Fingerprint)
+	case errors.As(err, &revokedErr):
+		return DiagnoseFailed, fmt.Sprintf("%s %s is revoked", hostKey.Type(), report.Fingerprint)
+	case errors.As(err, &expiredErr):
+		return DiagnoseFailed, fmt.Sprintf("%s %s was trusted automatically on %s and must be re-verified",
+			hostKey.Type(), report.Fingerprint, expiredErr.TrustedAt.Format(time.RFC3339))
+	case errors.As(err, &keyErr) && len(keyErr.Want) > 0:
+		return DiagnoseFailed, fmt.Sprintf("host key CHANGED: server presented %s %s, known_hosts has %s",
+			hostKey.Type(), report.Fingerprint, ssh.FingerprintSHA256(keyErr.Want[0].Key))
+	case errors.As(err, &keyErr):
+		if cfg.AcceptUnknownHost || cfg.AllowInsecureHostKey {
+			return DiagnoseWarning, fmt.Sprintf("%s %s is not in known_hosts and will be accepted automatically", hostKey.Type(), report.Fingerprint)
+		}
+		return DiagnoseFailed, fmt.Sprintf("%s %s is not in known_hosts (use --accept-unknown-host or ssh-keyscan)", hostKey.Type(), report.Fingerprint)
+	default:
+		return DiagnoseFailed, err.Error()
+	}
+}
+
+// diagnoseAuthMethods finds which authentication methods the server offers
+// and which sshx would try. Each method gets its own probe handshake whose
+// callback aborts before any credential is sent.
+func diagnoseAuthMethods(cfg *Config, addr string, timeout time.Duration, report *DiagnoseReport) (string, string) {
+	for _, method := range probedAuthMethods {
+		offered := false
+		mark := func() { offered = true }
+
+		var auth ssh.AuthMethod
+		switch method {
+		case "publickey":
+			auth = ssh.PublicKeysCallback(func() ([]ssh.Signer, error) { mark(); return nil, errProbeDone })
+		case "password":
+			auth = ssh.PasswordCallback(func() (string, error) { mark(); return "", errProbeDone })
+		case "keyboard-interactive":
+			auth = ssh.KeyboardInteractive(func(string, string, []string, []bool) ([]string, error) {
+				mark()
+				return nil, errProbeDone
+			})
+		}
+
+		clientConfig := &ssh.ClientConfig{
+			User:            cfg.User,
+			Auth:            []ssh.AuthMethod{auth},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(), // #nosec G106 -- probe only, no credentials are sent
+			Timeout:         timeout,
+		}
+		if err := dialProbe(addr, clientConfig, timeout); err != nil && !offered && !strings.Contains(err.Error(), "unable to authenticate") {
+			return DiagnoseFailed, fmt.Sprintf("probe handshake failed: %v", err)
+		}
+		if offered {
+			report.Offered = append(report.Offered, method)
+		}
+	}
+
+	if cfg.UseKeyAuth && cfg.KeyPath != "" {
+		report.Attempted = append(report.Attempted, "publickey")
+	}
+	if cfg.Password != "" {
+		report.Attempted = append(report.Attempted, "password")
+	}
+
+	detail := fmt.Sprintf("server offers [%s], sshx will try [%s]", strings.Join(report.Offered, " "), strings.Join(report.Attempted, " "))
+	if len(report.Attempted) == 0 {
+		return DiagnoseFailed, detail + ": no key or password configured"
+	}
+	for _, method := range report.Attempted {
+		for _, offered := range report.Offered {
+			if method == offered {
+				return DiagnoseOK, detail
+			}
+		}
+	}
+	return DiagnoseFailed, detail + ": none of them is accepted by the server"
+}
+
+// diagnoseAuth performs a real login with the configured credentials
+func diagnoseAuth(cfg *Config) (string, string) {
+	if cfg.UseKeyAuth && cfg.KeyPath != "" {
+		keyPath := cfg.KeyPath
+		if strings.HasPrefix(keyPath, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				keyPath = filepath.Join(home, keyPath[2:])
+			}
+		}
+		if _, err := os.Stat(keyPath); err != nil {
+			return DiagnoseFailed, fmt.Sprintf("SSH key %s is not readable: %v", cfg.KeyPath, err)
+		}
+	}
+
+	client, err := NewSSHClient(cfg)
+	if err != nil {
+		return DiagnoseFailed, err.Error()
+	}
+	if err := client.ConnectDirect(); err != nil {
+		return DiagnoseFailed, err.Error()
+	}
+	defer func() {
+		_ = client.ForceClose() //nolint:errcheck // diagnostic connection only
+	}()
+	return DiagnoseOK, fmt.Sprintf("logged in as %s using %s", cfg.User, client.AuthMethodUsed())
+}
+
+// dialProbe performs an SSH handshake that is expected to be aborted by one
+// of the config's callbacks
+func dialProbe(addr string, clientConfig *ssh.ClientConfig, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		_ = conn.Close() //nolint:errcheck
+		return err
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, clientConfig)
+	if err != nil {
+		_ = conn.Close() //nolint:errcheck
+		return err
+	}
+	_ = ssh.NewClient(sshConn, chans, reqs).Close() //nolint:errcheck // probes never keep the connection
+	return nil
+}
@@ -0,0 +1,145 @@
+package sshclient
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// startPasswordOnlyServer runs an SSH server that only offers password
+// authentication and rejects every password
+func startPasswordOnlyServer(t *testing.T) (host, port string) {
+	t.Helper()
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, assert.AnError
+		},
+	}
+	address := listenTestSSHServer(t, serverConfig, func(conn net.Conn, serverConfig *ssh.ServerConfig) {
+		defer func() { _ = conn.Close() }()
+		_, _, _, _ = ssh.NewServerConn(conn, serverConfig) //nolint:dogsled // handshake only
+	})
+
+	host, port, err := net.SplitHostPort(address)
+	require.NoError(t, err)
+	return host, port
+}
+
+func stepStatuses(report *DiagnoseReport) map[string]string {
+	statuses := make(map[string]string)
+	for _, step := range report.Steps {
+		statuses[step.Name] = step.Status
+	}
+	return statuses
+}
+
+func TestDiagnose_ConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	report := Diagnose(&Config{Host: "127.0.0.1", Port: port, DialTimeout: time.Second})
+
+	assert.Equal(t, "tcp_connect", report.FirstFailed)
+	statuses := stepStatuses(report)
+	assert.Equal(t, DiagnoseOK, statuses["dns"])
+	assert.Equal(t, DiagnoseFailed, statuses["tcp_connect"])
+	assert.Equal(t, DiagnoseSkipped, statuses["ssh_banner"])
+	assert.Equal(t, DiagnoseSkipped, statuses["authentication"])
+	assert.Len(t, report.Steps, 6)
+}
+
+func TestDiagnose_NotSSH(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+			_ = conn.Close()
+		}
+	}()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	report := Diagnose(&Config{Host: "127.0.0.1", Port: port, DialTimeout: time.Second})
+
+	assert.Equal(t, "ssh_banner", report.FirstFailed)
+	assert.Equal(t, DiagnoseOK, stepStatuses(report)["tcp_connect"])
+}
+
+func TestDiagnose_UnknownHostKeyAndAuthMethods(t *testing.T) {
+	host, port := startPasswordOnlyServer(t)
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+
+	report := Diagnose(&Config{
+		Host:           host,
+		Port:           port,
+		User:           "deploy",
+		Password:       "wrong",
+		KnownHostsPath: knownHosts,
+		DialTimeout:    2 * time.Second,
+	})
+
+	assert.Equal(t, "host_key", report.FirstFailed)
+	assert.NotEmpty(t, report.Fingerprint)
+	for _, step := range report.Steps {
+		if step.Name == "host_key" {
+			assert.Contains(t, step.Detail, "not in known_hosts")
+		}
+		if step.Name == "ssh_banner" {
+			assert.Contains(t, step.Detail, "SSH-2.0-")
+		}
+	}
+
+	// Accepting unknown hosts lets the diagnosis reach authentication
+	report = Diagnose(&Config{
+		Host:              host,
+		Port:              port,
+		User:              "deploy",
+		Password:          "wrong",
+		KnownHostsPath:    knownHosts,
+		AcceptUnknownHost: true,
+		DialTimeout:       2 * time.Second,
+	})
+
+	statuses := stepStatuses(report)
+	assert.Equal(t, DiagnoseWarning, statuses["host_key"])
+	assert.Equal(t, DiagnoseOK, statuses["auth_methods"])
+	assert.Equal(t, []string{"password"}, report.Offered)
+	assert.Equal(t, []string{"password"}, report.Attempted)
+	assert.Equal(t, "authentication", report.FirstFailed)
+}
+
+func TestDiagnose_NoMatchingAuthMethod(t *testing.T) {
+	host, port := startPasswordOnlyServer(t)
+
+	report := Diagnose(&Config{
+		Host:                 host,
+		Port:                 port,
+		UseKeyAuth:           true,
+		KeyPath:              filepath.Join(t.TempDir(), "id_ed25519"),
+		KnownHostsPath:       filepath.Join(t.TempDir(), "known_hosts"),
+		AllowInsecureHostKey: true,
+		DialTimeout:          2 * time.Second,
+	})
+
+	assert.Equal(t, "auth_methods", report.FirstFailed)
+	assert.Equal(t, []string{"publickey"}, report.Attempted)
+	for _, step := range report.Steps {
+		if step.Name == "auth_methods" {
+			assert.Contains(t, step.Detail, "none of them is accepted")
+		}
+	}
+}
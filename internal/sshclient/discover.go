@@ -0,0 +1,205 @@
+package sshclient
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDiscoverTimeout bounds the connection and banner read of each
+	// probed address
+	DefaultDiscoverTimeout = 500 * time.Millisecond
+	// DefaultDiscoverConcurrency is how many addresses are probed at once
+	DefaultDiscoverConcurrency = 128
+	// maxDiscoverAddresses caps the size of a scanned subnet (a /20)
+	maxDiscoverAddresses = 4096
+	// maxBannerLines is how many lines a server may send before its
+	// identification string (RFC 4253 4.2)
+	maxBannerLines = 5
+)
+
+// DiscoverOptions controls a LAN scan
+type DiscoverOptions struct {
+	Port        string        // SSH port probed on every address (default: 22)
+	Timeout     time.Duration // Per-address timeout (default: DefaultDiscoverTimeout)
+	Concurrency int           // Addresses probed in parallel (default: DefaultDiscoverConcurrency)
+	MDNS        bool          // Also browse _ssh._tcp services over multicast DNS
+	// Progress, when set, is called as each address finishes
+	Progress ProgressFunc
+}
+
+// DiscoveredHost is an address that answered with an SSH banner
+type DiscoveredHost struct {
+	Address string `json:"address"`
+	Port    string `json:"port"`
+	Banner  string `json:"banner"`         // Identification string, e.g. SSH-2.0-OpenSSH_9.6
+	Name    string `json:"name,omitempty"` // mDNS or reverse DNS name, if any
+}
+
+// LocalSubnet returns the IPv4 network of the first interface that is up
+// and not a loopback. Networks larger than a /24 are narrowed to the /24
+// around the interface address, so a default scan stays quick.
+func LocalSubnet() (string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "", fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			prefix, err := netip.ParsePrefix(addr.String())
+			if err != nil || !prefix.Addr().Is4() || prefix.Addr().IsLinkLocalUnicast() {
+				continue
+			}
+			bits := max(prefix.Bits(), 24)
+			return netip.PrefixFrom(prefix.Addr(), bits).Masked().String(), nil
+		}
+	}
+	return "", fmt.Errorf("no IPv4 network found (give one, e.g. --discover=192.168.1.0/24)")
+}
+
+// subnetAddresses lists the host addresses of an IPv4 or IPv6 CIDR; the
+// network and broadcast addresses of IPv4 subnets larger than a /31 are
+// left out
+func subnetAddresses(cidr string) ([]string, error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		if addr, addrErr := netip.ParseAddr(strings.TrimSpace(cidr)); addrErr == nil {
+			return []string{addr.String()}, nil
+		}
+		return nil, fmt.Errorf("invalid subnet '%s' (e.g. 192.168.1.0/24)", cidr)
+	}
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 12 {
+		return nil, fmt.Errorf("subnet %s is too large to scan (at most %d addresses, e.g. a /20)", prefix, maxDiscoverAddresses)
+	}
+
+	var addresses []string
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		addresses = append(addresses, addr.String())
+	}
+	if prefix.Addr().Is4() && hostBits > 1 {
+		addresses = addresses[1 : len(addresses)-1]
+	}
+	return addresses, nil
+}
+
+// Discover probes every address of cidr, and with MDNS the hosts that
+// advertise SSH over multicast DNS, for an SSH server. It returns the ones
+// that sent an identification string, sorted by address.
+func Discover(cidr string, opts DiscoverOptions) ([]DiscoveredHost, error) {
+	if opts.Port == "" {
+		opts.Port = DefaultSSHPort
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultDiscoverTimeout
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultDiscoverConcurrency
+	}
+	addresses, err := subnetAddresses(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make(map[string]string, len(addresses))
+	for _, address := range addresses {
+		ports[address] = opts.Port
+	}
+	var names map[string]mdnsService
+	if opts.MDNS {
+		// mDNS is best effort: networks without responders still get scanned
+		names, _ = browseMDNS(max(opts.Timeout, time.Second)) //nolint:errcheck // see above
+		for address, service := range names {
+			if _, ok := ports[address]; !ok {
+				addresses = append(addresses, address)
+			}
+			if service.Port != "" {
+				ports[address] = service.Port
+			}
+		}
+	}
+
+	configs := make([]*Config, len(addresses))
+	for i, address := range addresses {
+		configs[i] = &Config{Host: address, Port: ports[address]}
+	}
+	found := make([]*DiscoveredHost, len(configs))
+	forEachHost(configs, opts.Concurrency, opts.Progress, func(i int, config *Config) {
+		banner, err := readSSHBanner(net.JoinHostPort(config.Host, config.Port), opts.Timeout)
+		if err != nil {
+			return
+		}
+		host := &DiscoveredHost{Address: config.Host, Port: config.Port, Banner: banner}
+		if service, ok := names[config.Host]; ok {
+			host.Name = service.Name
+		} else {
+			host.Name = reverseName(config.Host, opts.Timeout)
+		}
+		found[i] = host
+	})
+
+	var hosts []DiscoveredHost
+	for _, host := range found {
+		if host != nil {
+			hosts = append(hosts, *host)
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		a, _ := netip.ParseAddr(hosts[i].Address) //nolint:errcheck // addresses come from netip
+		b, _ := netip.ParseAddr(hosts[j].Address) //nolint:errcheck // addresses come from netip
+		return a.Less(b)
+	})
+	return hosts, nil
+}
+
+// readSSHBanner connects to address and returns the identification string
+// the server sends, skipping the lines a server may send before it
+func readSSHBanner(address string, timeout time.Duration) (string, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = conn.Close() }() //nolint:errcheck // probe connection only
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return "", err
+	}
+
+	reader := bufio.NewReaderSize(conn, 256)
+	for range maxBannerLines {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "SSH-") {
+			return line, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("no SSH banner from %s: %w", address, err)
+		}
+	}
+	return "", fmt.Errorf("no SSH banner from %s", address)
+}
+
+// reverseName returns the reverse DNS name of address without its trailing
+// dot, or "" when it has none
+func reverseName(address string, timeout time.Duration) string {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	names, err := net.DefaultResolver.LookupAddr(ctx, address)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
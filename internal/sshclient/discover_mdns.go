@@ -0,0 +1,136 @@
+package sshclient
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mdnsServiceTypes are the DNS-SD service types browsed for SSH servers
+var mdnsServiceTypes = []string{"_ssh._tcp.local.", "_sftp-ssh._tcp.local."}
+
+// mdnsGroup is the IPv4 multicast DNS address (RFC 6762)
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// mdnsService is a host advertising SSH over multicast DNS
+type mdnsService struct {
+	Name string // Host name without .local
+	Port string // Advertised port, "" when unknown
+}
+
+// browseMDNS asks the local network which hosts offer SSH and collects the
+// answers for timeout. The query is sent from an ephemeral port, so
+// responders answer by unicast (RFC 6762 6.7) and no multicast membership
+// is needed.
+func browseMDNS(timeout time.Duration) (map[string]mdnsService, error) {
+	query, err := mdnsQuery()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open an mDNS socket: %w", err)
+	}
+	defer func() { _ = conn.Close() }() //nolint:errcheck // read-only socket
+	if _, err := conn.WriteTo(query, mdnsGroup); err != nil {
+		return nil, fmt.Errorf("failed to send the mDNS query: %w", err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]mdnsService)
+	buffer := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return services, nil
+		}
+		parseMDNSResponse(buffer[:n], services)
+	}
+}
+
+// mdnsQuery builds a PTR query for every browsed service type
+func mdnsQuery() ([]byte, error) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	for _, service := range mdnsServiceTypes {
+		name, err := dnsmessage.NewName(service)
+		if err != nil {
+			return nil, err
+		}
+		question := dnsmessage.Question{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}
+		if err := builder.Question(question); err != nil {
+			return nil, err
+		}
+	}
+	return builder.Finish()
+}
+
+// parseMDNSResponse adds the hosts of an mDNS response to services, keyed
+// by IPv4 address: the SRV records give the host name and port of a
+// service instance, the A records its address
+func parseMDNSResponse(message []byte, services map[string]mdnsService) {
+	var parser dnsmessage.Parser
+	if _, err := parser.Start(message); err != nil {
+		return
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return
+	}
+
+	ports := make(map[string]string)
+	addresses := make(map[string][]string)
+	for {
+		header, err := parser.AnswerHeader()
+		if err != nil || collectMDNSRecord(&parser, header, parser.SkipAnswer, ports, addresses) != nil {
+			break
+		}
+	}
+	if err := parser.SkipAllAuthorities(); err == nil {
+		for {
+			header, err := parser.AdditionalHeader()
+			if err != nil || collectMDNSRecord(&parser, header, parser.SkipAdditional, ports, addresses) != nil {
+				break
+			}
+		}
+	}
+
+	for host, port := range ports {
+		for _, address := range addresses[host] {
+			services[address] = mdnsService{Name: strings.TrimSuffix(host, ".local."), Port: port}
+		}
+	}
+}
+
+// collectMDNSRecord reads the resource after header: the target and port of
+// an SRV record, the address of an A record; other records are passed to
+// skip, which moves past them in their section. A malformed record ends
+// the walk of the message.
+func collectMDNSRecord(parser *dnsmessage.Parser, header dnsmessage.ResourceHeader, skip func() error, ports map[string]string, addresses map[string][]string) error {
+	switch header.Type {
+	case dnsmessage.TypeSRV:
+		srv, err := parser.SRVResource()
+		if err != nil {
+			return err
+		}
+		ports[strings.ToLower(srv.Target.String())] = strconv.Itoa(int(srv.Port))
+	case dnsmessage.TypeA:
+		a, err := parser.AResource()
+		if err != nil {
+			return err
+		}
+		host := strings.ToLower(header.Name.String())
+		addresses[host] = append(addresses[host], netip.AddrFrom4(a.A).String())
+	default:
+		return skip()
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package sshclient
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestSubnetAddresses(t *testing.T) {
+	addresses, err := subnetAddresses("192.168.1.77/30")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.168.1.77", "192.168.1.78"}, addresses)
+
+	addresses, err = subnetAddresses("10.0.0.0/24")
+	require.NoError(t, err)
+	assert.Len(t, addresses, 254)
+	assert.Equal(t, "10.0.0.1", addresses[0])
+	assert.Equal(t, "10.0.0.254", addresses[253])
+
+	addresses, err = subnetAddresses("10.0.0.8/31")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.8", "10.0.0.9"}, addresses)
+
+	addresses, err = subnetAddresses("10.0.0.5")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.5"}, addresses)
+
+	_, err = subnetAddresses("10.0.0.0/16")
+	assert.ErrorContains(t, err, "too large")
+	_, err = subnetAddresses("lan")
+	assert.ErrorContains(t, err, "invalid subnet")
+}
+
+// listenBanner serves lines to every connection and returns the port
+func listenBanner(t *testing.T, lines string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte(lines))
+			_ = conn.Close()
+		}
+	}()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	return port
+}
+
+func TestReadSSHBanner(t *testing.T) {
+	port := listenBanner(t, "Welcome to the lab\r\nSSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13\r\n")
+	banner, err := readSSHBanner(net.JoinHostPort("127.0.0.1", port), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13", banner)
+
+	port = listenBanner(t, "HTTP/1.1 400 Bad Request\r\n\r\n")
+	_, err = readSSHBanner(net.JoinHostPort("127.0.0.1", port), time.Second)
+	assert.ErrorContains(t, err, "no SSH banner")
+}
+
+func TestDiscover(t *testing.T) {
+	port := listenBanner(t, "SSH-2.0-dropbear_2022.83\r\n")
+
+	hosts, err := Discover("127.0.0.1/32", DiscoverOptions{Port: port, Timeout: time.Second})
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, "127.0.0.1", hosts[0].Address)
+	assert.Equal(t, port, hosts[0].Port)
+	assert.Equal(t, "SSH-2.0-dropbear_2022.83", hosts[0].Banner)
+
+	_, closedPort, err := net.SplitHostPort(closedAddress(t))
+	require.NoError(t, err)
+	hosts, err = Discover("127.0.0.1", DiscoverOptions{Port: closedPort, Timeout: time.Second})
+	require.NoError(t, err)
+	assert.Empty(t, hosts)
+}
+
+func TestParseMDNSResponse(t *testing.T) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	require.NoError(t, builder.StartAnswers())
+	service := dnsmessage.MustNewName("_ssh._tcp.local.")
+	instance := dnsmessage.MustNewName("nas._ssh._tcp.local.")
+	target := dnsmessage.MustNewName("NAS.local.")
+	header := func(name dnsmessage.Name) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: 120}
+	}
+	require.NoError(t, builder.PTRResource(header(service), dnsmessage.PTRResource{PTR: instance}))
+	require.NoError(t, builder.StartAdditionals())
+	require.NoError(t, builder.SRVResource(header(instance), dnsmessage.SRVResource{Port: 2222, Target: target}))
+	require.NoError(t, builder.TXTResource(header(instance), dnsmessage.TXTResource{TXT: []string{""}}))
+	require.NoError(t, builder.AResource(header(target), dnsmessage.AResource{A: [4]byte{192, 168, 1, 20}}))
+	message, err := builder.Finish()
+	require.NoError(t, err)
+
+	services := make(map[string]mdnsService)
+	parseMDNSResponse(message, services)
+	assert.Equal(t, map[string]mdnsService{"192.168.1.20": {Name: "nas", Port: "2222"}}, services)
+
+	parseMDNSResponse([]byte("garbage"), services)
+	assert.Len(t, services, 1)
+}
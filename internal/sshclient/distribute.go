@@ -0,0 +1,279 @@
+package sshclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/talkincode/sshmcp/pkg/errutil"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// DefaultFleetConcurrency caps how many hosts are contacted at once by
+// multi-host operations
+const DefaultFleetConcurrency = 10
+
+// DistributeOptions describes a file pushed to many hosts
+type DistributeOptions struct {
+	LocalPath   string
+	RemotePath  string
+	Validate    string // Optional command run on each host after the copy; failure triggers rollback
+	Concurrency int    // Hosts processed in parallel (default: DefaultFleetConcurrency)
+	// Rollback is an optional command run on a host after its previous file
+	// was restored, e.g. to reload the service that read the new one
+	Rollback string
+	// Render, when set, produces the content for each host from the local file
+	Render func(config *Config, content []byte) ([]byte, error)
+	// Progress, when set, is called as each host finishes
+	Progress ProgressFunc
+	// Verify reads each uploaded file back and compares its SHA-256 with the
+	// content sent; a mismatch fails the host and rolls it back
+	Verify bool
+
+	// keepBackup leaves the backup of a successful upload in place for
+	// settleUpload, so a rollout check can still restore it
+	keepBackup bool
+}
+
+// DistributeResult describes the outcome of distributing a file to one host
+type DistributeResult struct {
+	Key            string        // Pool key (user@host:port)
+	Bytes          int64         // Bytes written
+	Validated      bool          // Validation command ran and succeeded
+	ValidateOutput string        // Output of the validation command
+	RolledBack     bool          // The previous file was restored after a failure
+	SHA256         string        // Hex SHA-256 of the content sent (rendered for this host)
+	Verified       bool          // The file read back matched SHA256 (DistributeOptions.Verify)
+	Duration       time.Duration // Total time spent on this host
+	Err            error         // Upload, validation or rollback error, nil on success
+
+	backupPath string // Backup kept by DistributeOptions.keepBackup
+}
+
+// Distribute uploads the same local file to every host in parallel. On each
+// host the existing remote file is backed up first; if the upload or the
+// validation command fails the backup is restored (or the new file removed
+// when there was none). Results are returned in the same order as configs.
+func Distribute(configs []*Config, opts DistributeOptions) []DistributeResult {
+	results := make([]DistributeResult, len(configs))
+
+	content, err := os.ReadFile(opts.LocalPath)
+	if err != nil {
+		for i := range results {
+			results[i].Err = fmt.Errorf("failed to read local file: %w", err)
+		}
+		return results
+	}
+
+	forEachHost(configs, opts.Concurrency, opts.Progress, func(i int, config *Config) {
+		results[i] = distributeOne(config, content, opts)
+	})
+	return results
+}
+
+// forEachHost runs fn for every config with at most concurrency goroutines,
+// reporting each finished host to progress when it is set
+func forEachHost(configs []*Config, concurrency int, progress ProgressFunc, fn func(i int, config *Config)) {
+	if concurrency <= 0 {
+		concurrency = DefaultFleetConcurrency
+	}
+	hosts := &hostProgress{report: progress, total: int64(len(configs))}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, config := range configs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, config *Config) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i, config)
+			hosts.finished(config)
+		}(i, config)
+	}
+	wg.Wait()
+}
+
+// distributeOne pushes content to a single host
+func distributeOne(config *Config, content []byte, opts DistributeOptions) (result DistributeResult) {
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	if config == nil || config.Host == "" {
+		result.Err = fmt.Errorf("host is required")
+		return result
+	}
+
+	client, err := NewSSHClient(config)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Key = GetConnectionPool().makeKey(config)
+
+	if opts.Render != nil {
+		if content, err = opts.Render(config, content); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	if err := client.Connect(); err != nil {
+		result.Err = fmt.Errorf("failed to connect: %w", err)
+		return result
+	}
+	defer func() {
+		_ = client.CloseWithError(result.Err) //nolint:errcheck
+	}()
+
+	sftpClient, err := newSFTPClient(client.client, client.config)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to create SFTP client: %w", err)
+		return result
+	}
+	defer errutil.HandleCloseError(&result.Err, sftpClient)
+
+	if err := checkRemotePath(sftpClient, config.PathPolicy, opts.RemotePath, true); err != nil {
+		result.Err = err
+		return result
+	}
+
+	backupPath, err := backupRemoteFile(sftpClient, opts.RemotePath)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	sum := sha256.Sum256(content)
+	result.SHA256 = hex.EncodeToString(sum[:])
+	written, err := writeRemoteFile(sftpClient, opts.RemotePath, content, false, config.UploadOwnership.Mode)
+	result.Bytes = written
+	if err == nil {
+		err = applyOwnership(client.client, sftpClient, config, opts.RemotePath)
+	}
+	if err == nil && opts.Verify {
+		if err = verifyRemoteFile(sftpClient, opts.RemotePath, result.SHA256); err == nil {
+			result.Verified = true
+		}
+	}
+	if err == nil && opts.Validate != "" {
+		result.ValidateOutput, err = client.ExecuteCommandWithOutput(Request{Command: opts.Validate, RunAs: config.RunAs})
+		if err != nil {
+			err = fmt.Errorf("validation failed: %w", err)
+		} else {
+			result.Validated = true
+		}
+	}
+
+	if err != nil {
+		result.Err = err
+		rollbackErr := rollbackRemoteFile(sftpClient, opts.RemotePath, backupPath)
+		if rollbackErr == nil && opts.Rollback != "" {
+			rollbackErr = runRollback(client, opts.Rollback, uploadStep(opts))
+		}
+		if rollbackErr != nil {
+			result.Err = fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		} else {
+			result.RolledBack = true
+		}
+		return result
+	}
+
+	if opts.keepBackup {
+		result.backupPath = backupPath
+	} else if backupPath != "" {
+		if removeErr := sftpClient.Remove(backupPath); removeErr != nil {
+			logger.GetLogger().Warning("failed to remove backup %s on %s: %v", backupPath, result.Key, removeErr)
+		}
+	}
+	return result
+}
+
+// verifyRemoteFile reads remotePath back and checks that its SHA-256 is
+// sum (hex)
+func verifyRemoteFile(sftpClient *sftp.Client, remotePath, sum string) (err error) {
+	file, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for verification: %w", remotePath, err)
+	}
+	defer errutil.HandleCloseError(&err, file)
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return fmt.Errorf("failed to read %s for verification: %w", remotePath, err)
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != sum {
+		return fmt.Errorf("verification failed: %s has SHA-256 %s, expected %s", remotePath, actual, sum)
+	}
+	return nil
+}
+
+// backupRemoteFile copies an existing remote file next to itself and returns
+// the backup path, or an empty path when the file does not exist yet.
+func backupRemoteFile(sftpClient *sftp.Client, remotePath string) (string, error) {
+	if _, err := sftpClient.Stat(remotePath); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to stat remote file: %w", err)
+	}
+
+	backupPath := fmt.Sprintf("%s.sshx-bak-%d", remotePath, time.Now().UnixNano())
+	if err := copyRemoteFile(sftpClient, remotePath, backupPath); err != nil {
+		return "", fmt.Errorf("failed to back up %s: %w", remotePath, err)
+	}
+	return backupPath, nil
+}
+
+// rollbackRemoteFile restores the backup, or removes the new file when the
+// path did not exist before the upload.
+func rollbackRemoteFile(sftpClient *sftp.Client, remotePath, backupPath string) error {
+	if backupPath == "" {
+		if err := sftpClient.Remove(remotePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := copyRemoteFile(sftpClient, backupPath, remotePath); err != nil {
+		return err
+	}
+	return sftpClient.Remove(backupPath)
+}
+
+// copyRemoteFile copies a remote file, preserving its permissions
+func copyRemoteFile(sftpClient *sftp.Client, from, to string) (err error) {
+	src, err := sftpClient.Open(from)
+	if err != nil {
+		return err
+	}
+	defer errutil.HandleCloseError(&err, src)
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := sftpClient.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	defer errutil.HandleCloseError(&err, dst)
+
+	if _, err = io.Copy(dst, src); err != nil {
+		return err
+	}
+	return dst.Chmod(info.Mode().Perm())
+}
+
+// writeRemoteFile atomically replaces remotePath with content, giving it
+// mode or keeping the permissions of an existing file
+func writeRemoteFile(sftpClient *sftp.Client, remotePath string, content []byte, keepBackup bool, mode os.FileMode) (int64, error) {
+	return atomicWriteRemote(sftpClient, remotePath, keepBackup, mode, func(remoteFile io.Writer) (int64, error) {
+		n, err := remoteFile.Write(content)
+		return int64(n), err
+	})
+}
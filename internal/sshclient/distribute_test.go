@@ -0,0 +1,99 @@
+package sshclient
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistribute_MissingLocalFile(t *testing.T) {
+	results := Distribute([]*Config{{Host: "a"}, {Host: "b"}}, DistributeOptions{
+		LocalPath:  filepath.Join(t.TempDir(), "missing.conf"),
+		RemotePath: "/etc/app.conf",
+	})
+
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.ErrorContains(t, result.Err, "failed to read local file")
+	}
+}
+
+func TestDistribute_InvalidConfigs(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "app.conf")
+	require.NoError(t, os.WriteFile(local, []byte("key=value\n"), 0o600))
+
+	results := Distribute([]*Config{nil, {}}, DistributeOptions{LocalPath: local, RemotePath: "/etc/app.conf"})
+
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.ErrorContains(t, result.Err, "host is required")
+		assert.False(t, result.RolledBack)
+	}
+}
+
+func TestDistribute_RenderError(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "app.conf.tmpl")
+	require.NoError(t, os.WriteFile(local, []byte("{{ .Missing }}"), 0o600))
+
+	results := Distribute([]*Config{{Host: "10.0.0.1", Alias: "web1"}}, DistributeOptions{
+		LocalPath:  local,
+		RemotePath: "/etc/app.conf",
+		Render: func(config *Config, _ []byte) ([]byte, error) {
+			return nil, fmt.Errorf("cannot render for %s", config.Alias)
+		},
+	})
+
+	require.Len(t, results, 1)
+	assert.ErrorContains(t, results[0].Err, "cannot render for web1")
+	assert.Zero(t, results[0].Bytes)
+}
+
+func TestForEachHost_Concurrency(t *testing.T) {
+	configs := make([]*Config, 8)
+	var running, peak int32
+	var mu sync.Mutex
+	seen := make(map[int]bool)
+
+	forEachHost(configs, 3, nil, func(i int, _ *Config) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		mu.Lock()
+		seen[i] = true
+		mu.Unlock()
+	})
+
+	assert.Len(t, seen, 8)
+	assert.LessOrEqual(t, peak, int32(3))
+}
+
+func TestVerifyRemoteFile(t *testing.T) {
+	client := startLocalSFTP(t)
+	path := filepath.Join(t.TempDir(), "app.jar")
+	require.NoError(t, os.WriteFile(path, []byte("release 1.4.2"), 0o600))
+	sum := "42e8f212fefed73d15d6aa0ec70f97125b3734437a2395193436aa589f4cb168" // sha256 of "release 1.4.2"
+
+	assert.NoError(t, verifyRemoteFile(client, path, sum))
+	assert.ErrorContains(t, verifyRemoteFile(client, path, strings.Repeat("0", 64)), "verification failed: "+path+" has SHA-256 "+sum)
+	assert.ErrorContains(t, verifyRemoteFile(client, path+".missing", sum), "failed to open")
+
+	written, collected, err := downloadCapped(client, path, filepath.Join(t.TempDir(), "copy.jar"), 1024)
+	require.NoError(t, err)
+	assert.EqualValues(t, 13, written)
+	assert.Equal(t, sum, collected, "a collected file carries its checksum")
+}
@@ -0,0 +1,178 @@
+package sshclient
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// Execution event types
+const (
+	EventStarted  = "started"
+	EventOutput   = "output"
+	EventFinished = "finished"
+	EventBlocked  = "blocked"
+)
+
+const (
+	// eventSubscriberBuffer is how many events a subscriber may fall behind
+	// before events are dropped for it
+	eventSubscriberBuffer = 256
+	// eventHistorySize is how many lifecycle events RecentEvents keeps
+	eventHistorySize = 200
+)
+
+// Event is a step of a command execution. The events of one execution share
+// an ID: started, any number of output chunks, then finished or blocked.
+type Event struct {
+	Type       string    `json:"type"`
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Source     string    `json:"source,omitempty"`
+	Caller     string    `json:"caller,omitempty"`
+	Client     string    `json:"client,omitempty"`
+	Host       string    `json:"host,omitempty"` // Configured host name
+	Address    string    `json:"address"`
+	User       string    `json:"user,omitempty"`
+	Command    string    `json:"command,omitempty"`
+	Output     string    `json:"output,omitempty"` // Output chunk of an output event
+	Error      string    `json:"error,omitempty"`  // Why the command failed or was blocked
+	DurationMs int64     `json:"duration_ms,omitempty"`
+}
+
+// eventBus fans events out to subscribers and keeps the latest lifecycle
+// events
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+	history     []Event
+}
+
+var events = &eventBus{subscribers: make(map[chan Event]struct{})}
+
+// SubscribeEvents returns a channel receiving every execution event published
+// from now on, and the function ending the subscription. A subscriber that
+// falls behind misses events instead of slowing down commands.
+func SubscribeEvents() (<-chan Event, func()) {
+	ch := make(chan Event, eventSubscriberBuffer)
+	events.mu.Lock()
+	events.subscribers[ch] = struct{}{}
+	events.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			events.mu.Lock()
+			delete(events.subscribers, ch)
+			events.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// RecentEvents returns the latest started, finished and blocked events,
+// oldest first; output chunks are not kept
+func RecentEvents() []Event {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	return append([]Event(nil), events.history...)
+}
+
+// publishEvent hands an event to the subscribers
+func publishEvent(event Event) {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if event.Type != EventOutput {
+		if len(events.history) == eventHistorySize {
+			events.history = append(events.history[:0], events.history[1:]...)
+		}
+		events.history = append(events.history, event)
+	}
+	for ch := range events.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// hasEventSubscribers reports whether output chunks are worth publishing
+func hasEventSubscribers() bool {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	return len(events.subscribers) > 0
+}
+
+// EventsMiddleware publishes the events of every command execution: started
+// before the command runs, its output as it arrives and finished or blocked
+// once it ends. Commands the safety check refuses are started and blocked.
+func EventsMiddleware(next Executor) Executor {
+	return func(config *Config) (string, error) {
+		base := Event{
+			ID:      newEventID(),
+			Source:  config.Source,
+			Caller:  config.Caller,
+			Client:  config.Client,
+			Host:    config.Alias,
+			Address: config.Host,
+			User:    config.User,
+			Command: logger.Redact(config.Command),
+		}
+		started := time.Now()
+		publishEvent(base.at(EventStarted, started))
+
+		tap := config.OutputTap
+		config.OutputTap = &eventOutputWriter{base: base}
+		if tap != nil {
+			config.OutputTap = io.MultiWriter(tap, config.OutputTap)
+		}
+		output, err := next(config)
+		config.OutputTap = tap
+
+		end := base.at(EventFinished, time.Now())
+		end.DurationMs = time.Since(started).Milliseconds()
+		if err != nil {
+			end.Error = logger.Redact(err.Error())
+			var blocked *BlockedCommandError
+			if errors.As(err, &blocked) {
+				end.Type = EventBlocked
+			}
+		}
+		publishEvent(end)
+		return output, err
+	}
+}
+
+func (e Event) at(eventType string, t time.Time) Event {
+	e.Type = eventType
+	e.Time = t.UTC()
+	return e
+}
+
+// eventOutputWriter publishes command output as output events
+type eventOutputWriter struct {
+	base Event
+}
+
+func (w *eventOutputWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 && hasEventSubscribers() {
+		event := w.base.at(EventOutput, time.Now())
+		event.Output = logger.Redact(string(p))
+		publishEvent(event)
+	}
+	return len(p), nil
+}
+
+// newEventID returns a random identifier for the events of one execution
+func newEventID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,95 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// receiveEvents reads n events or fails after a second
+func receiveEvents(t *testing.T, ch <-chan Event, n int) []Event {
+	t.Helper()
+	var received []Event
+	for len(received) < n {
+		select {
+		case event := <-ch:
+			received = append(received, event)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d of %d events", len(received), n)
+		}
+	}
+	return received
+}
+
+func TestEventsMiddleware(t *testing.T) {
+	ch, unsubscribe := SubscribeEvents()
+	defer unsubscribe()
+
+	config := &Config{Host: "10.0.0.5", Alias: "web1", User: "deploy", Source: "mcp", Command: "echo hi"}
+	exec := Chain(func(config *Config) (string, error) {
+		_, err := config.OutputTap.Write([]byte("hi\n"))
+		return "hi\n", err
+	}, EventsMiddleware)
+	_, err := exec(config)
+	require.NoError(t, err)
+	assert.Nil(t, config.OutputTap, "the tap is removed once the command ends")
+
+	events := receiveEvents(t, ch, 3)
+	assert.Equal(t, []string{EventStarted, EventOutput, EventFinished},
+		[]string{events[0].Type, events[1].Type, events[2].Type})
+	assert.Equal(t, events[0].ID, events[2].ID)
+	assert.Equal(t, "web1", events[0].Host)
+	assert.Equal(t, "10.0.0.5", events[0].Address)
+	assert.Equal(t, "echo hi", events[0].Command)
+	assert.Equal(t, "hi\n", events[1].Output)
+	assert.Empty(t, events[2].Error)
+
+	recent := RecentEvents()
+	require.GreaterOrEqual(t, len(recent), 2)
+	assert.Equal(t, events[2], recent[len(recent)-1], "output chunks are not kept")
+	assert.Equal(t, events[0], recent[len(recent)-2])
+}
+
+func TestEventsMiddleware_FailedAndBlocked(t *testing.T) {
+	ch, unsubscribe := SubscribeEvents()
+	defer unsubscribe()
+
+	failing := Chain(func(*Config) (string, error) { return "", errors.New("exit status 1") }, EventsMiddleware)
+	_, err := failing(&Config{Host: "10.0.0.5", Command: "false"})
+	require.Error(t, err)
+	events := receiveEvents(t, ch, 2)
+	assert.Equal(t, EventFinished, events[1].Type)
+	// Whatever secrets other tests left registered are masked here too
+	assert.Equal(t, logger.Redact("exit status 1"), events[1].Error)
+
+	blocked := Chain(func(*Config) (string, error) { return "", nil }, EventsMiddleware, SafetyCheckMiddleware)
+	_, err = blocked(&Config{Host: "10.0.0.5", Command: "rm -rf /", SafetyCheck: true})
+	require.Error(t, err)
+	events = receiveEvents(t, ch, 2)
+	assert.Equal(t, EventStarted, events[0].Type)
+	assert.Equal(t, EventBlocked, events[1].Type)
+	assert.NotEmpty(t, events[1].Error)
+}
+
+func TestRecentEvents_Bounded(t *testing.T) {
+	for i := range eventHistorySize + 10 {
+		publishEvent(Event{Type: EventFinished, ID: fmt.Sprint(i)})
+	}
+	recent := RecentEvents()
+	require.Len(t, recent, eventHistorySize)
+	assert.Equal(t, fmt.Sprint(eventHistorySize+9), recent[len(recent)-1].ID)
+	assert.Equal(t, "10", recent[0].ID)
+}
+
+func TestSubscribeEvents_Unsubscribe(t *testing.T) {
+	ch, unsubscribe := SubscribeEvents()
+	unsubscribe()
+	unsubscribe()
+	_, open := <-ch
+	assert.False(t, open)
+}
@@ -0,0 +1,329 @@
+package sshclient
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/talkincode/sshmcp/pkg/errutil"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// DefaultExpectTimeout bounds each expect step without a timeout of its own,
+// and the wait for the command to exit after the last step
+const DefaultExpectTimeout = 30 * time.Second
+
+// ExpectStep is one step of an interactive session: wait until the output
+// matches Expect, then type Send (or the keyring password SendKey)
+// followed by a newline
+type ExpectStep struct {
+	// Expect is a regular expression the output must match (empty: send
+	// right away). Each step searches the output after the previous match.
+	Expect string
+	// Send is the line typed once Expect matched
+	Send string
+	// SendKey names a password in the system keyring (see --password-set)
+	// typed instead of Send; it never shows up in logs or the transcript
+	SendKey string
+	// Timeout bounds the wait for Expect (0 = DefaultExpectTimeout)
+	Timeout time.Duration
+}
+
+// String describes the step for logs, without secrets
+func (s ExpectStep) String() string {
+	var parts []string
+	if s.Expect != "" {
+		parts = append(parts, fmt.Sprintf("expect %q", s.Expect))
+	}
+	switch {
+	case s.SendKey != "":
+		parts = append(parts, "send-key "+s.SendKey)
+	case s.Send != "" || s.Expect == "":
+		parts = append(parts, fmt.Sprintf("send %q", s.Send))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseExpectScript parses an expect script, one instruction per line:
+//
+//	# comment
+//	expect <regular expression>
+//	send <line>             (a quoted line is unquoted as a Go string, e.g. "\x03")
+//	send-key <keyring key>
+//	timeout <duration>      (for the expect lines that follow)
+//
+// A send after an expect completes its step; any other send, and an empty
+// line, is a step of its own, typed as soon as the previous step is done.
+func ParseExpectScript(script string) ([]ExpectStep, error) {
+	var steps []ExpectStep
+	var timeout time.Duration
+	for number, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keyword, argument, _ := strings.Cut(line, " ")
+		argument = strings.TrimSpace(argument)
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("expect script line %d: %s", number+1, fmt.Sprintf(format, args...))
+		}
+
+		switch keyword {
+		case "expect":
+			if argument == "" {
+				return nil, fail("expect needs a regular expression")
+			}
+			steps = append(steps, ExpectStep{Expect: argument, Timeout: timeout})
+		case "send", "send-key":
+			step := ExpectStep{Timeout: timeout}
+			if keyword == "send-key" {
+				if argument == "" {
+					return nil, fail("send-key needs a keyring key")
+				}
+				step.SendKey = argument
+			} else if strings.HasPrefix(argument, `"`) {
+				unquoted, err := strconv.Unquote(argument)
+				if err != nil {
+					return nil, fail("invalid quoted line %s", argument)
+				}
+				step.Send = unquoted
+			} else {
+				step.Send = argument
+			}
+			// An empty line stays a step of its own: an expect step with
+			// nothing to send only waits
+			if last := len(steps) - 1; last >= 0 && steps[last].Expect != "" && !steps[last].sends() && (step.Send != "" || step.SendKey != "") {
+				steps[last].Send, steps[last].SendKey = step.Send, step.SendKey
+				continue
+			}
+			steps = append(steps, step)
+		case "timeout":
+			d, err := time.ParseDuration(argument)
+			if err != nil || d <= 0 {
+				return nil, fail("invalid timeout '%s'", argument)
+			}
+			timeout = d
+		default:
+			return nil, fail("unknown instruction '%s' (use expect, send, send-key or timeout)", keyword)
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("expect script has no steps")
+	}
+	return steps, nil
+}
+
+// sends reports whether the step types anything. A step without Expect
+// always does, if only an empty line.
+func (s ExpectStep) sends() bool {
+	return s.Send != "" || s.SendKey != "" || s.Expect == ""
+}
+
+// ExpectTimeoutError is returned by Interact when the output did not match
+// a step in time
+type ExpectTimeoutError struct {
+	Expect  string
+	Timeout time.Duration
+	// Last is the last line of output, to show what the command waits for
+	Last string
+}
+
+func (e *ExpectTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for %q (last output: %q)", e.Timeout, e.Expect, e.Last)
+}
+
+// expectSession is the running command of Interact
+type expectSession struct {
+	stdin      io.Writer
+	output     *shellOutput
+	done       chan struct{}
+	lineEnding string
+	// cursor is where the next expect starts searching the output
+	cursor int
+}
+
+// expect waits until the output after the cursor matches re
+func (s *expectSession) expect(re *regexp.Regexp, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	exited := false
+	for {
+		s.output.mu.Lock()
+		text := terminalText(s.output.buffer.String())
+		s.output.mu.Unlock()
+		if s.cursor > len(text) {
+			s.cursor = len(text)
+		}
+		if match := re.FindStringIndex(text[s.cursor:]); match != nil {
+			s.cursor += match[1]
+			return nil
+		}
+		if exited {
+			return fmt.Errorf("the command exited before %q showed up (last output: %q)", re.String(), lastOutputLine(text))
+		}
+
+		select {
+		case <-s.output.notify:
+		case <-s.done:
+			// Look at the output once more, it may have arrived with the exit
+			exited = true
+		case <-timer.C:
+			return &ExpectTimeoutError{Expect: re.String(), Timeout: timeout, Last: lastOutputLine(text)}
+		}
+	}
+}
+
+// send types a line
+func (s *expectSession) send(line string) error {
+	_, err := io.WriteString(s.stdin, line+s.lineEnding)
+	return err
+}
+
+// Interact starts req.Command (or a login shell when it is empty) with a
+// pseudo-terminal and works through the steps, waiting for each Expect
+// and typing its answer, so prompts a plain exec cannot answer (installer
+// confirmations, passphrases, a switch's enable mode) can be automated. A
+// login shell is left with "exit" after the last step; a command must end
+// within DefaultExpectTimeout. The output is the whole transcript.
+func (c *SSHClient) Interact(steps []ExpectStep, req Request) (Result, error) {
+	op := c.newOperation(req)
+	output, err := registeredChain(func(*Config) (string, error) {
+		defer acquireHostSlot(op.config)()
+		return op.interact(steps)
+	})(op.config)
+	return Result{Output: output, Truncated: op.outputTruncated, Safety: op.config.SafetyFinding}, err
+}
+
+// interact runs the steps once middleware has approved the command
+func (c *operation) interact(steps []ExpectStep) (output string, err error) {
+	lg := logger.GetLogger()
+	if len(steps) == 0 {
+		return "", fmt.Errorf("no expect steps given")
+	}
+	if err = checkShell(c.config); err != nil {
+		return "", err
+	}
+	if err = checkDevice(c.config); err != nil {
+		return "", err
+	}
+	patterns := make([]*regexp.Regexp, len(steps))
+	secrets := make([]string, len(steps))
+	for i, step := range steps {
+		if step.Expect != "" {
+			if patterns[i], err = regexp.Compile(step.Expect); err != nil {
+				return "", fmt.Errorf("step %d: invalid expect pattern: %w", i+1, err)
+			}
+		}
+		if step.SendKey != "" {
+			if secrets[i], err = GetSudoPassword(step.SendKey); err != nil {
+				return "", fmt.Errorf("step %d: %w", i+1, err)
+			}
+			// A program echoing its input must not reveal the secret
+			logger.RegisterSecret(secrets[i])
+		} else if c.config.SafetyCheck && step.Send != "" {
+			// Typed lines reach a shell as often as not
+			if _, err = CheckCommand(step.Send, c.config.Safety); err != nil {
+				return "", fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+	}
+
+	if err = c.startRecording(); err != nil {
+		return "", err
+	}
+	defer c.stopRecording()
+
+	session, err := c.client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	defer errutil.HandleCloseError(&err, session)
+	// Without echo the transcript shows each answer once, as the program
+	// prints it, and never a typed secret
+	modes := ssh.TerminalModes{ssh.ECHO: 0, ssh.TTY_OP_ISPEED: 14400, ssh.TTY_OP_OSPEED: 14400}
+	if err = session.RequestPty("xterm", 40, 200, modes); err != nil {
+		return "", fmt.Errorf("failed to request PTY: %w", err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return "", fmt.Errorf("failed to open stdin: %w", err)
+	}
+	s := &expectSession{
+		stdin:      c.recordInput(stdin),
+		output:     &shellOutput{notify: make(chan struct{}, 1)},
+		done:       make(chan struct{}),
+		lineEnding: "\n",
+	}
+	exitCommand := "exit"
+	if c.config.Device != nil {
+		exitCommand = c.config.Device.Exit
+		if c.config.Device.LineEnding != "" {
+			s.lineEnding = c.config.Device.LineEnding
+		}
+	}
+	session.Stdout = c.tee(s.output)
+	session.Stderr = c.tee(s.output)
+
+	if c.config.Command == "" {
+		err = session.Shell()
+	} else {
+		err = session.Start(c.sandboxed(c.commandLine()))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to start: %w", err)
+	}
+	var exitErr error
+	go func() {
+		exitErr = session.Wait()
+		close(s.done)
+	}()
+
+	transcript := func() string {
+		s.output.mu.Lock()
+		defer s.output.mu.Unlock()
+		text, truncated := CapOutput([]byte(logger.Redact(terminalText(s.output.buffer.String()))), c.config.MaxOutputBytes)
+		c.outputTruncated = truncated
+		return text
+	}
+
+	for i, step := range steps {
+		if patterns[i] != nil {
+			timeout := step.Timeout
+			if timeout <= 0 {
+				timeout = DefaultExpectTimeout
+			}
+			if err = s.expect(patterns[i], timeout); err != nil {
+				return transcript(), fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+		if !step.sends() {
+			continue
+		}
+		line := step.Send
+		if step.SendKey != "" {
+			line = secrets[i]
+		}
+		lg.Debug("Interact %s: %s", c.config.Host, step)
+		if err = s.send(line); err != nil {
+			return transcript(), fmt.Errorf("step %d: failed to send: %w", i+1, err)
+		}
+	}
+
+	if c.config.Command == "" && exitCommand != "" {
+		_ = s.send(exitCommand) //nolint:errcheck // a closed session has ended anyway
+	}
+	select {
+	case <-s.done:
+	case <-time.After(DefaultExpectTimeout):
+		return transcript(), fmt.Errorf("the command was still running %s after the last step", DefaultExpectTimeout)
+	}
+	if exitErr != nil {
+		return transcript(), fmt.Errorf("command failed: %w", exitErr)
+	}
+	return transcript(), nil
+}
@@ -0,0 +1,204 @@
+package sshclient
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// listenInteractive starts an SSH server that grants PTYs and hands each
+// shell (command "") or exec request to the program handler returns, or
+// refuses it when there is none
+func listenInteractive(t *testing.T, handler func(command string) func(ssh.Channel)) string {
+	t.Helper()
+	return listenTestSSHServer(t, nil, func(conn net.Conn, serverConfig *ssh.ServerConfig) {
+		serveInteractive(conn, serverConfig, handler)
+	})
+}
+
+func serveInteractive(conn net.Conn, serverConfig *ssh.ServerConfig, handler func(string) func(ssh.Channel)) {
+	defer func() { _ = conn.Close() }()
+	_, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				var program func(ssh.Channel)
+				switch req.Type {
+				case "pty-req":
+					_ = req.Reply(true, nil)
+					continue
+				case "shell":
+					program = handler("")
+				case "exec":
+					var payload struct{ Command string }
+					if ssh.Unmarshal(req.Payload, &payload) == nil {
+						program = handler(payload.Command)
+					}
+				}
+				_ = req.Reply(program != nil, nil)
+				if program != nil {
+					go program(channel)
+				}
+			}
+		}()
+	}
+}
+
+// exitWith reports status to the client and closes the channel
+func exitWith(channel ssh.Channel, status uint32) {
+	_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+	_ = channel.Close()
+}
+
+func connectInteractive(t *testing.T, address string, device *DeviceProfile) *SSHClient {
+	t.Helper()
+	host, port, err := net.SplitHostPort(address)
+	require.NoError(t, err)
+	client, err := NewSSHClient(&Config{
+		Host:              host,
+		Port:              port,
+		User:              "admin",
+		Password:          "unused",
+		KnownHostsPath:    filepath.Join(t.TempDir(), "known_hosts"),
+		AcceptUnknownHost: true,
+		Device:            device,
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.ConnectDirect())
+	t.Cleanup(func() { _ = client.ForceClose() })
+	return client
+}
+
+// runFakeInstaller asks for a confirmation and a passphrase, typed without
+// echo as the PTY was requested
+func runFakeInstaller(channel ssh.Channel) {
+	reader := bufio.NewReader(channel)
+	_, _ = channel.Write([]byte("Reading package lists... Done\r\nDo you want to continue? [Y/n] "))
+	if answer, _ := reader.ReadString('\n'); strings.TrimSpace(answer) != "y" {
+		_, _ = channel.Write([]byte("\r\nAbort.\r\n"))
+		exitWith(channel, 1)
+		return
+	}
+	_, _ = channel.Write([]byte("\r\nUnpacking...\r\nEnter passphrase for key: "))
+	if passphrase, _ := reader.ReadString('\n'); strings.TrimSpace(passphrase) != "s3cret" {
+		_, _ = channel.Write([]byte("\r\nBad passphrase\r\n"))
+		exitWith(channel, 2)
+		return
+	}
+	_, _ = channel.Write([]byte("\r\nSetting up app (1.2.0) ...\r\n"))
+	exitWith(channel, 0)
+}
+
+func TestInteract(t *testing.T) {
+	address := listenInteractive(t, func(command string) func(ssh.Channel) {
+		switch {
+		case strings.Contains(command, "apt-get install app"):
+			return runFakeInstaller
+		case strings.Contains(command, "sleep"):
+			return func(ssh.Channel) {}
+		case strings.Contains(command, "cat"):
+			return func(channel ssh.Channel) {
+				line, _ := bufio.NewReader(channel).ReadString('\n')
+				_, _ = channel.Write([]byte(line))
+				exitWith(channel, 0)
+			}
+		}
+		return nil
+	})
+	client := connectInteractive(t, address, nil)
+	sudoPasswordCache.put("app-key", "s3cret")
+	t.Cleanup(func() { ForgetSudoPassword("app-key") })
+
+	steps, err := ParseExpectScript("expect continue\\? \\[Y/n\\]\nsend y\nexpect passphrase\nsend-key app-key\n")
+	require.NoError(t, err)
+	result, err := client.Interact(steps, Request{Command: "apt-get install app"})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "Do you want to continue? [Y/n] \nUnpacking...")
+	assert.Contains(t, result.Output, "Setting up app (1.2.0) ...")
+	assert.NotContains(t, result.Output, "s3cret")
+
+	// A recording shows what was typed, with the secret masked
+	recording := filepath.Join(t.TempDir(), "install.cast")
+	client.config.RecordPath = recording
+	_, err = client.Interact(steps, Request{Command: "apt-get install app"})
+	require.NoError(t, err)
+	client.config.RecordPath = ""
+	cast, err := os.ReadFile(recording)
+	require.NoError(t, err)
+	assert.Contains(t, string(cast), `"i","y\n"]`)
+	assert.Contains(t, string(cast), `"i","`+logger.RedactedMask+`\n"]`)
+	assert.NotContains(t, string(cast), "s3cret")
+
+	result, err = client.Interact([]ExpectStep{{SendKey: "app-key"}}, Request{Command: "cat"})
+	require.NoError(t, err)
+	assert.Equal(t, logger.RedactedMask+"\n", result.Output, "an echoed secret is redacted")
+
+	result, err = client.Interact([]ExpectStep{{Expect: `\[Y/n\]`, Send: "n"}}, Request{Command: "apt-get install app"})
+	assert.ErrorContains(t, err, "exited with status 1")
+	assert.Contains(t, result.Output, "Abort.")
+
+	_, err = client.Interact([]ExpectStep{{Expect: "password:", Timeout: 50 * time.Millisecond}}, Request{Command: "apt-get install app"})
+	var timeout *ExpectTimeoutError
+	require.True(t, errors.As(err, &timeout), "got %v", err)
+	assert.Equal(t, "Do you want to continue? [Y/n]", timeout.Last)
+
+	client.config.SafetyCheck = true
+	_, err = client.Interact([]ExpectStep{{Send: "rm -rf /"}}, Request{Command: "sleep 1"})
+	assert.ErrorContains(t, err, "step 1: ")
+	assert.ErrorContains(t, err, "blocked")
+}
+
+func TestParseExpectScript(t *testing.T) {
+	steps, err := ParseExpectScript(`
+# Enable mode on the switch
+timeout 5s
+expect >$
+send enable
+expect Password:
+send-key core-enable
+send "terminal length 0"
+timeout 2m
+expect #$
+send ""
+`)
+	require.NoError(t, err)
+	assert.Equal(t, []ExpectStep{
+		{Expect: ">$", Send: "enable", Timeout: 5 * time.Second},
+		{Expect: "Password:", SendKey: "core-enable", Timeout: 5 * time.Second},
+		{Send: "terminal length 0", Timeout: 5 * time.Second},
+		{Expect: "#$", Timeout: 2 * time.Minute},
+		{Timeout: 2 * time.Minute},
+	}, steps)
+	assert.Equal(t, `expect "Password:", send-key core-enable`, steps[1].String())
+
+	for script, message := range map[string]string{
+		"":                 "no steps",
+		"expect":           "line 1: expect needs a regular expression",
+		"send y\nsend-key": "line 2: send-key needs a keyring key",
+		"timeout soon":     "invalid timeout 'soon'",
+		"type y":           "unknown instruction 'type'",
+		`send "unclosed`:   "invalid quoted line",
+	} {
+		_, err := ParseExpectScript(script)
+		assert.ErrorContains(t, err, message, "script %q", script)
+	}
+}
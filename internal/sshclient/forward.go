@@ -0,0 +1,260 @@
+package sshclient
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// x11AuthProtocol is the only X authorization protocol forwarded
+const x11AuthProtocol = "MIT-MAGIC-COOKIE-1"
+
+// forwarding is the agent and X11 forwarding set up on a connection for
+// the commands run by ExecuteCommand
+type forwarding struct {
+	agent bool
+	x11   *x11Forwarding
+}
+
+// x11Forwarding connects the X11 channels the server opens to the local
+// display. The server gets a random cookie; the real one is put in place
+// of it on the way to the local X server, as ssh -X does, so the remote
+// host never learns it.
+type x11Forwarding struct {
+	network, address string
+	screen           uint32
+	fakeCookie       []byte
+	// authName and authData authorize the connections to the local
+	// display; both are empty when it needs no cookie
+	authName string
+	authData []byte
+}
+
+// startForwarding registers the channel handlers for the forwarding that
+// config asks for on a new connection. It returns nil when it asks for none.
+func startForwarding(client *ssh.Client, config *Config) (*forwarding, error) {
+	if !config.ForwardAgent && !config.ForwardX11 {
+		return nil, nil
+	}
+	f := &forwarding{}
+	if config.ForwardAgent {
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return nil, fmt.Errorf("agent forwarding needs a running ssh-agent (SSH_AUTH_SOCK is not set)")
+		}
+		if err := agent.ForwardToRemote(client, socket); err != nil {
+			return nil, fmt.Errorf("failed to forward the agent: %w", err)
+		}
+		f.agent = true
+	}
+	if config.ForwardX11 {
+		x11, err := newX11Forwarding(os.Getenv("DISPLAY"))
+		if err != nil {
+			return nil, err
+		}
+		channels := client.HandleChannelOpen("x11")
+		if channels == nil {
+			return nil, fmt.Errorf("X11 channels are already handled on this connection")
+		}
+		go func() {
+			for channel := range channels {
+				go x11.serve(channel)
+			}
+		}()
+		f.x11 = x11
+	}
+	return f, nil
+}
+
+// request asks the server to forward the agent and X11 connections of
+// session. A nil forwarding requests nothing.
+func (f *forwarding) request(session *ssh.Session) error {
+	if f == nil {
+		return nil
+	}
+	if f.agent {
+		if err := agent.RequestAgentForwarding(session); err != nil {
+			return fmt.Errorf("the server refused agent forwarding (AllowAgentForwarding in sshd_config): %w", err)
+		}
+	}
+	if f.x11 != nil {
+		payload := ssh.Marshal(struct {
+			SingleConnection bool
+			AuthProtocol     string
+			AuthCookie       string
+			ScreenNumber     uint32
+		}{false, x11AuthProtocol, hex.EncodeToString(f.x11.fakeCookie), f.x11.screen})
+		ok, err := session.SendRequest("x11-req", true, payload)
+		if err != nil {
+			return fmt.Errorf("failed to request X11 forwarding: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("the server refused X11 forwarding (X11Forwarding in sshd_config, xauth on the host)")
+		}
+	}
+	return nil
+}
+
+// newX11Forwarding prepares forwarding to display, the local DISPLAY
+func newX11Forwarding(display string) (*x11Forwarding, error) {
+	if display == "" {
+		return nil, fmt.Errorf("X11 forwarding needs a local X server (DISPLAY is not set)")
+	}
+	network, address, screen, err := parseDisplay(display)
+	if err != nil {
+		return nil, err
+	}
+	x := &x11Forwarding{network: network, address: address, screen: screen, fakeCookie: make([]byte, 16)}
+	if _, err = rand.Read(x.fakeCookie); err != nil {
+		return nil, fmt.Errorf("failed to create X11 cookie: %w", err)
+	}
+	x.authName, x.authData = localXAuth(display)
+	return x, nil
+}
+
+// parseDisplay returns where the X server of display ("[host]:N[.S]")
+// listens and its screen. A host of "" or "unix" means the local socket
+// /tmp/.X11-unix/XN; a path (XQuartz) is the socket itself.
+func parseDisplay(display string) (network, address string, screen uint32, err error) {
+	i := strings.LastIndex(display, ":")
+	if i < 0 {
+		return "", "", 0, fmt.Errorf("invalid DISPLAY '%s'", display)
+	}
+	host := display[:i]
+	numberText, screenText, hasScreen := strings.Cut(display[i+1:], ".")
+	number, err := strconv.Atoi(numberText)
+	if err != nil || number < 0 {
+		return "", "", 0, fmt.Errorf("invalid DISPLAY '%s'", display)
+	}
+	if hasScreen {
+		parsed, parseErr := strconv.ParseUint(screenText, 10, 32)
+		if parseErr != nil {
+			return "", "", 0, fmt.Errorf("invalid DISPLAY '%s'", display)
+		}
+		screen = uint32(parsed)
+	}
+
+	switch {
+	case host == "" || host == "unix":
+		return "unix", fmt.Sprintf("/tmp/.X11-unix/X%d", number), screen, nil
+	case strings.HasPrefix(host, "/"):
+		return "unix", host + ":" + numberText, screen, nil
+	default:
+		return "tcp", net.JoinHostPort(host, strconv.Itoa(6000+number)), screen, nil
+	}
+}
+
+// localXAuth returns the cookie xauth holds for display, or nothing when
+// xauth is missing or has none (the X server may not need one)
+func localXAuth(display string) (string, []byte) {
+	output, err := exec.Command("xauth", "list", display).Output() // #nosec G204 -- DISPLAY of this user's session
+	if err != nil {
+		logger.GetLogger().Debug("No X11 cookie for %s: %v", display, err)
+		return "", nil
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[1] != x11AuthProtocol {
+			continue
+		}
+		if cookie, decodeErr := hex.DecodeString(fields[2]); decodeErr == nil {
+			return x11AuthProtocol, cookie
+		}
+	}
+	return "", nil
+}
+
+// serve connects an X11 channel the server opened to the local display
+func (x *x11Forwarding) serve(newChannel ssh.NewChannel) {
+	lg := logger.GetLogger()
+	local, err := net.Dial(x.network, x.address)
+	if err != nil {
+		lg.Warning("X11 connection to %s failed: %v", x.address, err)
+		_ = newChannel.Reject(ssh.ConnectionFailed, "cannot reach the X server") //nolint:errcheck // nothing left to do
+		return
+	}
+	defer func() { _ = local.Close() }() //nolint:errcheck // the connection is done
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = channel.Close() }() //nolint:errcheck // the connection is done
+	go ssh.DiscardRequests(requests)
+
+	setup, err := x.rewriteSetup(channel)
+	if err != nil {
+		lg.Warning("X11 connection rejected: %v", err)
+		return
+	}
+	if _, err = local.Write(setup); err != nil {
+		return
+	}
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(local, channel) //nolint:errcheck // either side closing ends the connection
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(channel, local) //nolint:errcheck // either side closing ends the connection
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// rewriteSetup reads the connection setup of a remote X client, checks
+// that it carries the fake cookie and returns it with the local display's
+// authorization instead
+func (x *x11Forwarding) rewriteSetup(r io.Reader) ([]byte, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read the connection setup: %w", err)
+	}
+	var order binary.ByteOrder
+	switch header[0] {
+	case 'B':
+		order = binary.BigEndian
+	case 'l':
+		order = binary.LittleEndian
+	default:
+		return nil, fmt.Errorf("unknown byte order %#x", header[0])
+	}
+	nameLength, dataLength := int(order.Uint16(header[6:])), int(order.Uint16(header[8:]))
+	body := make([]byte, pad4(nameLength)+pad4(dataLength))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("failed to read the connection setup: %w", err)
+	}
+	name := string(body[:nameLength])
+	data := body[pad4(nameLength) : pad4(nameLength)+dataLength]
+	if name != x11AuthProtocol || subtle.ConstantTimeCompare(data, x.fakeCookie) != 1 {
+		return nil, fmt.Errorf("wrong X11 cookie")
+	}
+
+	var setup bytes.Buffer
+	order.PutUint16(header[6:], uint16(len(x.authName)))
+	order.PutUint16(header[8:], uint16(len(x.authData)))
+	setup.Write(header)
+	setup.WriteString(x.authName)
+	setup.Write(make([]byte, pad4(len(x.authName))-len(x.authName)))
+	setup.Write(x.authData)
+	setup.Write(make([]byte, pad4(len(x.authData))-len(x.authData)))
+	return setup.Bytes(), nil
+}
+
+// pad4 rounds n up to a multiple of four, the X11 field alignment
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
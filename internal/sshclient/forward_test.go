@@ -0,0 +1,258 @@
+package sshclient
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// listenForwardingServer starts an SSH server whose "ssh-add" and "xclock"
+// commands open the agent and X11 channels a remote ssh-add or X client
+// would; they exit 0 only when the forwarded agent holds a key commented
+// "deploy" and the X server answered
+func listenForwardingServer(t *testing.T) string {
+	t.Helper()
+	return listenTestSSHServer(t, nil, serveForwarding)
+}
+
+func serveForwarding(conn net.Conn, serverConfig *ssh.ServerConfig) {
+	defer func() { _ = conn.Close() }()
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			agentForwarded := false
+			var x11 struct {
+				SingleConnection bool
+				AuthProtocol     string
+				AuthCookie       string
+				ScreenNumber     uint32
+			}
+			for req := range requests {
+				switch req.Type {
+				case "auth-agent-req@openssh.com":
+					agentForwarded = true
+					_ = req.Reply(true, nil)
+				case "x11-req":
+					_ = req.Reply(ssh.Unmarshal(req.Payload, &x11) == nil, nil)
+				case "pty-req":
+					_ = req.Reply(true, nil)
+				case "exec":
+					var payload struct{ Command string }
+					_ = ssh.Unmarshal(req.Payload, &payload)
+					_ = req.Reply(true, nil)
+					switch payload.Command {
+					case "ssh-add -l":
+						go exitWith(channel, fakeSSHAdd(serverConn, agentForwarded))
+					case "xclock":
+						cookie, _ := hex.DecodeString(x11.AuthCookie)
+						go exitWith(channel, fakeXClient(serverConn, cookie))
+					default:
+						go exitWith(channel, 127)
+					}
+				default:
+					_ = req.Reply(false, nil)
+				}
+			}
+		}()
+	}
+}
+
+func fakeSSHAdd(serverConn *ssh.ServerConn, agentForwarded bool) uint32 {
+	if !agentForwarded {
+		return 2
+	}
+	channel, requests, err := serverConn.OpenChannel("auth-agent@openssh.com", nil)
+	if err != nil {
+		return 3
+	}
+	defer func() { _ = channel.Close() }()
+	go ssh.DiscardRequests(requests)
+	keys, err := agent.NewClient(channel).List()
+	if err != nil || len(keys) != 1 || keys[0].Comment != "deploy" {
+		return 1
+	}
+	return 0
+}
+
+func fakeXClient(serverConn *ssh.ServerConn, cookie []byte) uint32 {
+	channel, requests, err := serverConn.OpenChannel("x11", ssh.Marshal(struct {
+		Address string
+		Port    uint32
+	}{"127.0.0.1", 40000}))
+	if err != nil {
+		return 3
+	}
+	defer func() { _ = channel.Close() }()
+	go ssh.DiscardRequests(requests)
+	_, _ = channel.Write(x11SetupRequest(binary.LittleEndian, x11AuthProtocol, cookie))
+	reply := make([]byte, 2)
+	if _, err = io.ReadFull(channel, reply); err != nil || string(reply) != "OK" {
+		return 1
+	}
+	return 0
+}
+
+// x11SetupRequest builds the connection setup an X client sends
+func x11SetupRequest(order binary.ByteOrder, name string, data []byte) []byte {
+	header := make([]byte, 12)
+	header[0] = 'l'
+	if order == binary.BigEndian {
+		header[0] = 'B'
+	}
+	order.PutUint16(header[2:], 11)
+	order.PutUint16(header[6:], uint16(len(name)))
+	order.PutUint16(header[8:], uint16(len(data)))
+	setup := append(header, name...)
+	setup = append(setup, make([]byte, pad4(len(name))-len(name))...)
+	setup = append(setup, data...)
+	return append(setup, make([]byte, pad4(len(data))-len(data))...)
+}
+
+// listenFakeAgent serves a keyring holding one key commented "deploy" and
+// points SSH_AUTH_SOCK at it
+func listenFakeAgent(t *testing.T) {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: privateKey, Comment: "deploy"}))
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() { _ = agent.ServeAgent(keyring, conn) }()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+}
+
+// listenFakeXServer accepts X connections on a socket named like an
+// XQuartz display, answers "OK" and reports the setup it received
+func listenFakeXServer(t *testing.T) <-chan []byte {
+	t.Helper()
+	display := filepath.Join(t.TempDir(), "xsock") + ":0"
+	listener, err := net.Listen("unix", display)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	t.Setenv("DISPLAY", display)
+	setups := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		setup := make([]byte, 12)
+		if _, err = io.ReadFull(conn, setup); err == nil {
+			setups <- setup
+			_, _ = conn.Write([]byte("OK"))
+		}
+	}()
+	return setups
+}
+
+func connectForwarding(t *testing.T, address string, agentForwarding, x11 bool) (*SSHClient, error) {
+	t.Helper()
+	host, port, err := net.SplitHostPort(address)
+	require.NoError(t, err)
+	client, err := NewSSHClient(&Config{
+		Host:              host,
+		Port:              port,
+		User:              "admin",
+		Password:          "unused",
+		KnownHostsPath:    filepath.Join(t.TempDir(), "known_hosts"),
+		AcceptUnknownHost: true,
+		ForwardAgent:      agentForwarding,
+		ForwardX11:        x11,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.ForceClose() })
+	return client, client.ConnectDirect()
+}
+
+func TestForwarding(t *testing.T) {
+	address := listenForwardingServer(t)
+	listenFakeAgent(t)
+	setups := listenFakeXServer(t)
+
+	client, err := connectForwarding(t, address, true, true)
+	require.NoError(t, err)
+	require.NoError(t, client.ExecuteCommand(Request{Command: "ssh-add -l"}), "the remote ssh-add should list the local agent's key")
+	require.NoError(t, client.ExecuteCommand(Request{Command: "xclock"}), "the remote X client should reach the local display")
+	setup := <-setups
+	assert.Equal(t, byte('l'), setup[0])
+	assert.Zero(t, binary.LittleEndian.Uint16(setup[6:]), "the fake cookie must not reach the X server")
+
+	client, err = connectForwarding(t, address, false, false)
+	require.NoError(t, err)
+	assert.Error(t, client.ExecuteCommand(Request{Command: "ssh-add -l"}), "no agent without --forward-agent")
+
+	refusing := listenInteractive(t, func(string) func(ssh.Channel) { return func(channel ssh.Channel) { exitWith(channel, 0) } })
+	client, err = connectForwarding(t, refusing, true, false)
+	require.NoError(t, err)
+	assert.ErrorContains(t, client.ExecuteCommand(Request{Command: "ssh-add -l"}), "refused agent forwarding")
+
+	t.Setenv("SSH_AUTH_SOCK", "")
+	_, err = connectForwarding(t, address, true, false)
+	assert.ErrorContains(t, err, "SSH_AUTH_SOCK is not set")
+}
+
+func TestParseDisplay(t *testing.T) {
+	for display, want := range map[string][3]string{
+		":0":             {"unix", "/tmp/.X11-unix/X0", "0"},
+		"unix:1":         {"unix", "/tmp/.X11-unix/X1", "0"},
+		":0.1":           {"unix", "/tmp/.X11-unix/X0", "1"},
+		"localhost:10.0": {"tcp", "localhost:6010", "0"},
+		"/private/tmp/com.apple.launchd.x/org.xquartz:0": {"unix", "/private/tmp/com.apple.launchd.x/org.xquartz:0", "0"},
+	} {
+		network, address, screen, err := parseDisplay(display)
+		require.NoError(t, err, display)
+		assert.Equal(t, want, [3]string{network, address, string('0' + byte(screen))}, display)
+	}
+	for _, display := range []string{"", "x11", ":a", ":0.b"} {
+		_, _, _, err := parseDisplay(display)
+		assert.ErrorContains(t, err, "invalid DISPLAY", display)
+	}
+}
+
+func TestX11RewriteSetup(t *testing.T) {
+	fake := []byte("0123456789abcdef")
+	real := []byte("real-cookie-1234")
+	x := &x11Forwarding{fakeCookie: fake, authName: x11AuthProtocol, authData: real}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		setup, err := x.rewriteSetup(bytes.NewReader(x11SetupRequest(order, x11AuthProtocol, fake)))
+		require.NoError(t, err)
+		assert.Equal(t, x11SetupRequest(order, x11AuthProtocol, real), setup)
+	}
+
+	_, err := x.rewriteSetup(bytes.NewReader(x11SetupRequest(binary.LittleEndian, x11AuthProtocol, real)))
+	assert.ErrorContains(t, err, "wrong X11 cookie")
+	_, err = x.rewriteSetup(bytes.NewReader([]byte("GET / HTTP/1.1\r\n")))
+	assert.ErrorContains(t, err, "unknown byte order")
+}
@@ -0,0 +1,311 @@
+package sshclient
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/pkg/errutil"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// trustedMarker prefixes the comment sshx writes on automatically trusted
+// known_hosts entries, followed by the RFC 3339 time the key was accepted.
+const trustedMarker = "sshx-trusted@"
+
+// HostKeyRevokedError is returned when a server presents a revoked host key
+type HostKeyRevokedError struct {
+	Host   string
+	Source string // File that lists the key as revoked
+}
+
+func (e *HostKeyRevokedError) Error() string {
+	return fmt.Sprintf("⚠️  HOST KEY REVOKED!\n"+
+		"The host key presented by %s is listed as revoked in %s.\n"+
+		"Do not connect until the server's key has been replaced and verified.", e.Host, e.Source)
+}
+
+// HostKeyTrustExpiredError is returned when an automatically trusted host key
+// is older than the configured trust TTL and must be re-verified.
+type HostKeyTrustExpiredError struct {
+	Host      string
+	TrustedAt time.Time
+	Path      string
+}
+
+func (e *HostKeyTrustExpiredError) Error() string {
+	return fmt.Sprintf("⚠️  Trust for host %s expired (automatically trusted on %s).\n"+
+		"Verify the host key fingerprint, then either remove the '%s' comment from its entry in %s to trust it permanently,\n"+
+		"or delete the entry and reconnect with --accept-unknown-host.",
+		e.Host, e.TrustedAt.Format(time.RFC3339), trustedMarker, e.Path)
+}
+
+// loadRevokedKeys reads a revoked keys file containing one public key per
+// line in authorized_keys format. Blank lines and # comments are ignored.
+// A missing file yields no revoked keys.
+func loadRevokedKeys(path string) (keys [][]byte, err error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(path) // #nosec G304 -- user-provided revoked keys path
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open revoked keys file %s: %w", path, err)
+	}
+	defer errutil.HandleCloseError(&err, file)
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, _, _, _, parseErr := ssh.ParseAuthorizedKey([]byte(line))
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid key in revoked keys file %s line %d: %w", path, lineNum, parseErr)
+		}
+		keys = append(keys, key.Marshal())
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return nil, fmt.Errorf("failed to read revoked keys file %s: %w", path, scanErr)
+	}
+	return keys, nil
+}
+
+// isRevoked reports whether key is in the revoked list
+func isRevoked(revoked [][]byte, key ssh.PublicKey) bool {
+	if len(revoked) == 0 {
+		return false
+	}
+	marshaled := key.Marshal()
+	for _, r := range revoked {
+		if bytes.Equal(r, marshaled) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedAt returns when sshx automatically trusted key for any of hosts in
+// the known_hosts file, or false if the entry was not added by sshx (entries
+// added manually or by OpenSSH never expire).
+func trustedAt(path string, hosts []string, key ssh.PublicKey) (time.Time, bool) {
+	data, err := os.ReadFile(path) // #nosec G304 -- known_hosts path validated by caller
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	marshaled := key.Marshal()
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "@") {
+			continue
+		}
+		if !matchesAnyHost(strings.Split(fields[0], ","), hosts) {
+			continue
+		}
+
+		entryKey, comment, _, _, parseErr := ssh.ParseAuthorizedKey([]byte(strings.Join(fields[1:], " ")))
+		if parseErr != nil || !bytes.Equal(entryKey.Marshal(), marshaled) {
+			continue
+		}
+		if !strings.HasPrefix(comment, trustedMarker) {
+			return time.Time{}, false
+		}
+		ts, parseErr := time.Parse(time.RFC3339, strings.TrimPrefix(comment, trustedMarker))
+		if parseErr != nil {
+			return time.Time{}, false
+		}
+		return ts, true
+	}
+	return time.Time{}, false
+}
+
+func matchesAnyHost(entryHosts, hosts []string) bool {
+	for _, entry := range entryHosts {
+		for _, host := range hosts {
+			if entry == host {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// KnownHostEntry is a parsed known_hosts line
+type KnownHostEntry struct {
+	Hosts       []string
+	KeyType     string
+	Fingerprint string
+	TrustedAt   time.Time // Zero unless the entry was trusted automatically by sshx
+	Revoked     bool
+	key         ssh.PublicKey
+}
+
+// MatchesHost reports whether the entry applies to host, with or without a
+// non-standard port ("web1" matches both "web1" and "[web1]:2222").
+func (e KnownHostEntry) MatchesHost(host string) bool {
+	for _, h := range e.Hosts {
+		if h == host || strings.HasPrefix(h, "["+host+"]:") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseKnownHostLine parses a single known_hosts line, returning false for
+// blank lines, comments and lines it cannot parse.
+func parseKnownHostLine(line string) (KnownHostEntry, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return KnownHostEntry{}, false
+	}
+
+	var entry KnownHostEntry
+	if strings.HasPrefix(fields[0], "@") {
+		if fields[0] != "@revoked" {
+			return KnownHostEntry{}, false
+		}
+		entry.Revoked = true
+		fields = fields[1:]
+	}
+	if len(fields) < 3 {
+		return KnownHostEntry{}, false
+	}
+
+	key, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.Join(fields[1:], " ")))
+	if err != nil {
+		return KnownHostEntry{}, false
+	}
+
+	entry.Hosts = strings.Split(fields[0], ",")
+	entry.KeyType = key.Type()
+	entry.Fingerprint = ssh.FingerprintSHA256(key)
+	entry.key = key
+	if ts, ok := strings.CutPrefix(comment, trustedMarker); ok {
+		if parsed, parseErr := time.Parse(time.RFC3339, ts); parseErr == nil {
+			entry.TrustedAt = parsed
+		}
+	}
+	return entry, true
+}
+
+// ListKnownHosts returns the entries of a known_hosts file. A missing file
+// has no entries.
+func ListKnownHosts(path string) ([]KnownHostEntry, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- known_hosts path from configuration
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var entries []KnownHostEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		if entry, ok := parseKnownHostLine(line); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// rewriteKnownHosts replaces every line of path for which fn returns a new
+// value. fn receives parsed entries only; other lines are kept verbatim.
+func rewriteKnownHosts(path string, fn func(entry KnownHostEntry, line string) (string, bool)) (int, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- known_hosts path from configuration
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	kept := make([]string, 0, len(lines))
+	changed := 0
+	for _, line := range lines {
+		entry, ok := parseKnownHostLine(line)
+		if !ok {
+			kept = append(kept, line)
+			continue
+		}
+		replacement, replace := fn(entry, line)
+		if !replace {
+			kept = append(kept, line)
+			continue
+		}
+		changed++
+		if replacement != "" {
+			kept = append(kept, replacement)
+		}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	content := strings.Join(kept, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return changed, nil
+}
+
+// PromoteKnownHost moves the trusted entries for host from one known_hosts
+// file to another (e.g. from the MCP trust store to ~/.ssh/known_hosts).
+// Promoted entries are trusted permanently and never expire. They are only
+// removed from the source once the destination holds them, so a failed
+// write leaves the host trusted as it was.
+func PromoteKnownHost(from, to, host string) (int, error) {
+	entries, err := ListKnownHosts(from)
+	if err != nil {
+		return 0, err
+	}
+	var lines []string
+	for _, entry := range entries {
+		if !entry.Revoked && entry.MatchesHost(host) {
+			lines = append(lines, knownhosts.Line(entry.Hosts, entry.key))
+		}
+	}
+	if len(lines) == 0 {
+		return 0, fmt.Errorf("no trusted entry for host %s in %s", host, from)
+	}
+
+	if err = ensureKnownHostsFile(to); err != nil {
+		return 0, err
+	}
+	if err = appendKnownHostLine(to, strings.Join(lines, "\n")); err != nil {
+		return 0, err
+	}
+	return rewriteKnownHosts(from, func(entry KnownHostEntry, _ string) (string, bool) {
+		return "", !entry.Revoked && entry.MatchesHost(host)
+	})
+}
+
+// DenyKnownHost marks the entries for host as revoked so the keys are refused
+// on every future connection.
+func DenyKnownHost(path, host string) (int, error) {
+	count, err := rewriteKnownHosts(path, func(entry KnownHostEntry, _ string) (string, bool) {
+		if entry.Revoked || !entry.MatchesHost(host) {
+			return "", false
+		}
+		return "@revoked " + knownhosts.Line(entry.Hosts, entry.key), true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("no trusted entry for host %s in %s", host, path)
+	}
+	return count, nil
+}
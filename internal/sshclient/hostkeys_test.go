@@ -0,0 +1,191 @@
+package sshclient
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestLoadRevokedKeys(t *testing.T) {
+	key := generateTestPublicKey(t)
+	path := filepath.Join(t.TempDir(), "revoked_keys")
+	content := "# revoked after incident\n\n" + string(ssh.MarshalAuthorizedKey(key))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	revoked, err := loadRevokedKeys(path)
+	require.NoError(t, err)
+	assert.True(t, isRevoked(revoked, key))
+	assert.False(t, isRevoked(revoked, generateTestPublicKey(t)))
+
+	missing, err := loadRevokedKeys(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	require.NoError(t, os.WriteFile(path, []byte("not a key\n"), 0o600))
+	_, err = loadRevokedKeys(path)
+	assert.Error(t, err)
+}
+
+func TestGetHostKeyCallback_RevokedKey(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	key := generateTestPublicKey(t)
+	revokedPath := filepath.Join(home, "revoked_keys")
+	require.NoError(t, os.WriteFile(revokedPath, ssh.MarshalAuthorizedKey(key), 0o600))
+
+	callback, err := getHostKeyCallback(&Config{AcceptUnknownHost: true, RevokedKeysPath: revokedPath})
+	require.NoError(t, err)
+
+	remote := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 22}
+	err = callback(net.JoinHostPort("revoked-host", "22"), remote, key)
+
+	var revokedErr *HostKeyRevokedError
+	require.True(t, errors.As(err, &revokedErr))
+	assert.Equal(t, revokedPath, revokedErr.Source)
+
+	data, readErr := os.ReadFile(filepath.Join(home, ".ssh", "known_hosts")) //nolint:gosec // G304: test temp dir
+	require.NoError(t, readErr)
+	assert.Empty(t, string(data), "revoked key must not be trusted")
+}
+
+func TestGetHostKeyCallback_KnownHostsRevokedMarker(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	key := generateTestPublicKey(t)
+	knownHostsPath := filepath.Join(home, ".ssh", "known_hosts")
+	require.NoError(t, os.MkdirAll(filepath.Dir(knownHostsPath), 0o700))
+	line := "@revoked * " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))) + "\n"
+	require.NoError(t, os.WriteFile(knownHostsPath, []byte(line), 0o600))
+
+	callback, err := getHostKeyCallback(&Config{})
+	require.NoError(t, err)
+
+	err = callback(net.JoinHostPort("any-host", "22"), &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 22}, key)
+	var revokedErr *HostKeyRevokedError
+	assert.True(t, errors.As(err, &revokedErr))
+}
+
+func TestGetHostKeyCallback_TrustExpiry(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	remote := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 22}
+	hostname := net.JoinHostPort("ttl-host", "22")
+	key := generateTestPublicKey(t)
+
+	accept, err := getHostKeyCallback(&Config{AcceptUnknownHost: true})
+	require.NoError(t, err)
+	require.NoError(t, accept(hostname, remote, key))
+
+	knownHostsPath := filepath.Join(home, ".ssh", "known_hosts")
+	data, err := os.ReadFile(knownHostsPath) //nolint:gosec // G304: test temp dir
+	require.NoError(t, err)
+	assert.Contains(t, string(data), trustedMarker)
+
+	// A fresh entry is still trusted
+	strict, err := getHostKeyCallback(&Config{TrustTTL: time.Hour})
+	require.NoError(t, err)
+	require.NoError(t, strict(hostname, remote, key))
+
+	// Backdate the entry beyond the TTL
+	old := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	line := knownhosts.Line([]string{"ttl-host"}, key) + " " + trustedMarker + old + "\n"
+	require.NoError(t, os.WriteFile(knownHostsPath, []byte(line), 0o600))
+
+	strict, err = getHostKeyCallback(&Config{TrustTTL: time.Hour})
+	require.NoError(t, err)
+	err = strict(hostname, remote, key)
+	var expiredErr *HostKeyTrustExpiredError
+	require.True(t, errors.As(err, &expiredErr))
+
+	// Without a TTL the same entry is accepted
+	noTTL, err := getHostKeyCallback(&Config{})
+	require.NoError(t, err)
+	assert.NoError(t, noTTL(hostname, remote, key))
+
+	// Entries without the sshx marker never expire
+	require.NoError(t, os.WriteFile(knownHostsPath, []byte(knownhosts.Line([]string{"ttl-host"}, key)+"\n"), 0o600))
+	strict, err = getHostKeyCallback(&Config{TrustTTL: time.Hour})
+	require.NoError(t, err)
+	assert.NoError(t, strict(hostname, remote, key))
+}
+
+func TestGetHostKeyCallback_AcceptedHostsPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	acceptedPath := filepath.Join(home, ".sshmcp", "known_hosts_mcp")
+	remote := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 22}
+	hostname := net.JoinHostPort("mcp-host", "22")
+	key := generateTestPublicKey(t)
+
+	callback, err := getHostKeyCallback(&Config{AcceptUnknownHost: true, AcceptedHostsPath: acceptedPath})
+	require.NoError(t, err)
+	require.NoError(t, callback(hostname, remote, key))
+
+	userData, err := os.ReadFile(filepath.Join(home, ".ssh", "known_hosts")) //nolint:gosec // G304: test temp dir
+	require.NoError(t, err)
+	assert.Empty(t, string(userData), "user trust store must stay untouched")
+
+	entries, err := ListKnownHosts(acceptedPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].MatchesHost("mcp-host"))
+	assert.False(t, entries[0].TrustedAt.IsZero())
+
+	// The accepted store is consulted on later connections
+	strict, err := getHostKeyCallback(&Config{AcceptedHostsPath: acceptedPath})
+	require.NoError(t, err)
+	assert.NoError(t, strict(hostname, remote, key))
+
+	// Without it the host is unknown
+	plain, err := getHostKeyCallback(&Config{})
+	require.NoError(t, err)
+	assert.Error(t, plain(hostname, remote, key))
+}
+
+func TestPromoteKnownHost(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, "known_hosts_mcp")
+	to := filepath.Join(dir, "ssh", "known_hosts")
+	web1 := knownhosts.Line([]string{"[web1]:2222"}, generateTestPublicKey(t)) + " " + trustedMarker + "2026-10-01T00:00:00Z"
+	db1 := knownhosts.Line([]string{"db1"}, generateTestPublicKey(t))
+	original := web1 + "\n" + db1 + "\n"
+	require.NoError(t, os.WriteFile(from, []byte(original), 0o600))
+
+	// A destination that cannot be written keeps the entries in the source
+	blocked := filepath.Join(dir, "not-a-directory")
+	require.NoError(t, os.WriteFile(blocked, nil, 0o600))
+	_, err := PromoteKnownHost(from, filepath.Join(blocked, "known_hosts"), "web1")
+	require.Error(t, err)
+	data, err := os.ReadFile(from) //nolint:gosec // G304: test temp dir
+	require.NoError(t, err)
+	assert.Equal(t, original, string(data))
+
+	count, err := PromoteKnownHost(from, to, "web1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	promoted, err := ListKnownHosts(to)
+	require.NoError(t, err)
+	require.Len(t, promoted, 1)
+	assert.True(t, promoted[0].MatchesHost("web1"))
+	assert.True(t, promoted[0].TrustedAt.IsZero(), "promoted entries never expire")
+	remaining, err := ListKnownHosts(from)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.True(t, remaining[0].MatchesHost("db1"))
+
+	_, err = PromoteKnownHost(from, to, "web1")
+	assert.ErrorContains(t, err, "no trusted entry for host web1")
+}
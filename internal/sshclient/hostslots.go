@@ -0,0 +1,62 @@
+package sshclient
+
+import (
+	"net"
+	"sync"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// hostSlots holds one semaphore per host address, shared by every client
+// and connection of this process, so Config.MaxParallel holds no matter
+// how many callers (MCP tool calls, daemon requests, scheduled jobs, fleet
+// operations) reach the same host at once
+var hostSlots = struct {
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}{sems: make(map[string]chan struct{})}
+
+// hostSlotKey identifies a host by address and port, so aliases and users
+// of the same machine share its slots
+func hostSlotKey(config *Config) string {
+	port := config.Port
+	if port == "" {
+		port = DefaultSSHPort
+	}
+	return net.JoinHostPort(config.Host, port)
+}
+
+// acquireHostSlot waits until the host of config runs fewer than
+// MaxParallel commands and returns the function releasing the slot taken.
+// Hosts without MaxParallel are not limited. Waiting callers are served as
+// slots free up; a changed limit applies to commands started afterwards.
+func acquireHostSlot(config *Config) (release func()) {
+	if config.MaxParallel <= 0 {
+		return func() {}
+	}
+	key := hostSlotKey(config)
+
+	hostSlots.mu.Lock()
+	sem, ok := hostSlots.sems[key]
+	if !ok || cap(sem) != config.MaxParallel {
+		sem = make(chan struct{}, config.MaxParallel)
+		hostSlots.sems[key] = sem
+	}
+	hostSlots.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+	default:
+		logger.GetLogger().Info("waiting for %s: %d command(s) already running (max_parallel %d)", hostLabel(config), len(sem), config.MaxParallel)
+		sem <- struct{}{}
+	}
+	return func() { <-sem }
+}
+
+// hostLabel names the host of config in messages: its alias when it has one
+func hostLabel(config *Config) string {
+	if config.Alias != "" {
+		return config.Alias
+	}
+	return config.Host
+}
@@ -0,0 +1,89 @@
+package sshclient
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// runConcurrently runs n commands at once on clients (in turn) and returns
+// the most commands the server saw running together
+func runConcurrently(t *testing.T, n int, clients ...func() *SSHClient) int64 {
+	t.Helper()
+	var running, peak atomic.Int64
+	conn := startExecServer(t, func(string, ssh.Channel, <-chan struct{}) uint32 {
+		now := running.Add(1)
+		for {
+			old := peak.Load()
+			if now <= old || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		return 0
+	})
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client := clients[i%len(clients)]()
+			client.client = conn
+			_, errs[i] = client.Execute(Request{Command: fmt.Sprintf("step %d", i)})
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	return peak.Load()
+}
+
+func TestExecute_MaxParallel(t *testing.T) {
+	limited := func() *SSHClient {
+		return &SSHClient{config: &Config{Host: "10.0.0.70", MaxParallel: 2}}
+	}
+	assert.LessOrEqual(t, runConcurrently(t, 6, limited), int64(2))
+
+	// Aliases and users of the same address share its slots
+	web := func() *SSHClient {
+		return &SSHClient{config: &Config{Host: "10.0.0.71", Alias: "web", MaxParallel: 1}}
+	}
+	deploy := func() *SSHClient {
+		return &SSHClient{config: &Config{Host: "10.0.0.71", Port: "22", User: "deploy", MaxParallel: 1}}
+	}
+	assert.Equal(t, int64(1), runConcurrently(t, 6, web, deploy))
+
+	unlimited := func() *SSHClient { return &SSHClient{config: &Config{Host: "10.0.0.72"}} }
+	assert.Greater(t, runConcurrently(t, 4, unlimited), int64(1))
+}
+
+func TestAcquireHostSlot_Release(t *testing.T) {
+	config := &Config{Host: "10.0.0.73", MaxParallel: 1}
+	release := acquireHostSlot(config)
+
+	acquired := make(chan func())
+	go func() { acquired <- acquireHostSlot(config) }()
+	select {
+	case <-acquired:
+		t.Fatal("a second command ran while the only slot was taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case release = <-acquired:
+		release()
+	case <-time.After(time.Second):
+		t.Fatal("the queued command did not get the released slot")
+	}
+}
@@ -0,0 +1,266 @@
+package sshclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits of JournalCommand
+const (
+	DefaultJournalLines = 100
+	MaxJournalLines     = 2000
+	// maxJournalMessage caps each message, so one stack trace cannot fill
+	// the result
+	maxJournalMessage = 4096
+)
+
+// JournalPriorities are the syslog priorities, most severe first; a
+// priority also selects the more severe ones, like journalctl -p
+var JournalPriorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// Sources JournalCommand reads
+const (
+	JournalSourceJournald = "journald"
+	JournalSourceSyslog   = "syslog"
+)
+
+// journalMarker prefixes the line of JournalCommand naming the source
+const journalMarker = "@sshx-journal:"
+
+// unitNamePattern matches systemd unit names and syslog identifiers
+var unitNamePattern = regexp.MustCompile(`^[A-Za-z0-9@._:\\-]+$`)
+
+// JournalQuery selects journal entries
+type JournalQuery struct {
+	Units    []string // Units to include, e.g. nginx.service (empty = all)
+	Priority string   // One of JournalPriorities: this and more severe
+	Since    string   // journalctl time, e.g. "1 hour ago" or "2026-01-02 15:04"
+	Until    string
+	Grep     string // Pattern messages must match
+	Lines    int    // Most recent entries to return (0 = DefaultJournalLines)
+}
+
+// JournalEntry is one log entry
+type JournalEntry struct {
+	// Time is RFC 3339 for journald, and as written for syslog files
+	Time       string `json:"time"`
+	Priority   string `json:"priority,omitempty"`
+	Unit       string `json:"unit,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	PID        int    `json:"pid,omitempty"`
+	Host       string `json:"host,omitempty"`
+	Message    string `json:"message"`
+}
+
+// JournalResult is the result of the journal_query tool
+type JournalResult struct {
+	Source  string         `json:"source"`
+	Entries []JournalEntry `json:"entries"`
+	// Truncated is set when the output limit cut entries, or messages were
+	// shortened to maxJournalMessage bytes
+	Truncated bool     `json:"truncated,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// Validate checks the query and applies the default number of lines
+func (q *JournalQuery) Validate() error {
+	for _, unit := range q.Units {
+		if !unitNamePattern.MatchString(unit) || strings.HasPrefix(unit, "-") {
+			return fmt.Errorf("invalid unit name '%s'", unit)
+		}
+	}
+	if q.Priority != "" && !slices.Contains(JournalPriorities, q.Priority) {
+		level, err := strconv.Atoi(q.Priority)
+		if err != nil || level < 0 || level >= len(JournalPriorities) {
+			return fmt.Errorf("unknown priority '%s' (use %s or 0-7)", q.Priority, strings.Join(JournalPriorities, ", "))
+		}
+		q.Priority = JournalPriorities[level]
+	}
+	if q.Lines == 0 {
+		q.Lines = DefaultJournalLines
+	}
+	if q.Lines < 1 || q.Lines > MaxJournalLines {
+		return fmt.Errorf("lines must be between 1 and %d", MaxJournalLines)
+	}
+	return nil
+}
+
+// JournalCommand reads the last q.Lines matching entries with journalctl
+// -o json or, on hosts without journald, from /var/log/syslog or
+// /var/log/messages, where only the units and the pattern apply. Call
+// q.Validate first.
+func JournalCommand(q JournalQuery) string {
+	journalctl := []string{"journalctl", "--no-pager", "-o", "json", "-n", strconv.Itoa(q.Lines)}
+	for _, unit := range q.Units {
+		journalctl = append(journalctl, "-u", unit)
+	}
+	if q.Priority != "" {
+		journalctl = append(journalctl, "-p", q.Priority)
+	}
+	if q.Since != "" {
+		journalctl = append(journalctl, "--since", q.Since)
+	}
+	if q.Until != "" {
+		journalctl = append(journalctl, "--until", q.Until)
+	}
+	if q.Grep != "" {
+		journalctl = append(journalctl, "--grep", q.Grep)
+	}
+
+	// Syslog lines carry the identifier, which is the unit without .service
+	var pipeline []string
+	if len(q.Units) > 0 {
+		identifiers := make([]string, len(q.Units))
+		for i, unit := range q.Units {
+			identifiers[i] = regexp.QuoteMeta(strings.TrimSuffix(unit, ".service"))
+		}
+		pipeline = append(pipeline, BuildCommand("grep", "-E", "-e", " ("+strings.Join(identifiers, "|")+`)(\[[0-9]+\])?: `))
+	}
+	if q.Grep != "" {
+		pipeline = append(pipeline, BuildCommand("grep", "-E", "-e", q.Grep))
+	}
+	pipeline = append(pipeline, "tail -n "+strconv.Itoa(q.Lines))
+	pipeline[0] += ` "$f"`
+	syslog := strings.Join(pipeline, " | ")
+
+	return strings.Join([]string{
+		"if command -v journalctl >/dev/null 2>&1; then",
+		"  echo " + ShellQuote(journalMarker+JournalSourceJournald),
+		"  " + BuildCommand(journalctl...),
+		"  exit",
+		"fi",
+		"for f in /var/log/syslog /var/log/messages; do",
+		`  if [ -r "$f" ]; then`,
+		"    echo " + ShellQuote(journalMarker+JournalSourceSyslog),
+		"    " + syslog,
+		"    exit 0",
+		"  fi",
+		"done",
+		"echo 'no journalctl, /var/log/syslog or /var/log/messages on the host' >&2",
+		"exit 1",
+	}, "\n")
+}
+
+// journaldRecord holds the journal fields journal_query reports.
+// MESSAGE is a string, an array of bytes for binary data, or null.
+type journaldRecord struct {
+	Timestamp  string          `json:"__REALTIME_TIMESTAMP"`
+	Priority   string          `json:"PRIORITY"`
+	Unit       string          `json:"_SYSTEMD_UNIT"`
+	Identifier string          `json:"SYSLOG_IDENTIFIER"`
+	PID        string          `json:"_PID"`
+	Host       string          `json:"_HOSTNAME"`
+	Message    json.RawMessage `json:"MESSAGE"`
+}
+
+// ParseJournal reads the output of JournalCommand. query is what was asked
+// for, to warn about filters a syslog file cannot apply.
+func ParseJournal(output string, query JournalQuery) *JournalResult {
+	result := &JournalResult{Entries: []JournalEntry{}}
+	for _, line := range splitLines(output) {
+		line = strings.TrimRight(line, "\r")
+		if source, ok := strings.CutPrefix(line, journalMarker); ok {
+			result.Source = source
+			continue
+		}
+		if strings.HasPrefix(line, "... [output truncated:") {
+			result.Truncated = true
+			continue
+		}
+		var entry JournalEntry
+		var ok bool
+		switch result.Source {
+		case JournalSourceJournald:
+			entry, ok = parseJournaldLine(line)
+		case JournalSourceSyslog:
+			entry, ok = parseSyslogLine(line)
+		}
+		if !ok {
+			continue
+		}
+		if len(entry.Message) > maxJournalMessage {
+			entry.Message = strings.ToValidUTF8(entry.Message[:maxJournalMessage], "") + " …"
+			result.Truncated = true
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+
+	if result.Source == JournalSourceSyslog {
+		var ignored []string
+		for name, value := range map[string]string{"priority": query.Priority, "since": query.Since, "until": query.Until} {
+			if value != "" {
+				ignored = append(ignored, name)
+			}
+		}
+		if len(ignored) > 0 {
+			slices.Sort(ignored)
+			result.Warnings = append(result.Warnings, "the host has no journald: "+strings.Join(ignored, ", ")+" ignored for the syslog file")
+		}
+	}
+	return result
+}
+
+// parseJournaldLine reads one entry of journalctl -o json
+func parseJournaldLine(line string) (JournalEntry, bool) {
+	if !strings.HasPrefix(line, "{") {
+		return JournalEntry{}, false
+	}
+	var record journaldRecord
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		return JournalEntry{}, false
+	}
+	entry := JournalEntry{Unit: record.Unit, Identifier: record.Identifier, Host: record.Host}
+	if micros, err := strconv.ParseInt(record.Timestamp, 10, 64); err == nil {
+		entry.Time = time.UnixMicro(micros).UTC().Format(time.RFC3339Nano)
+	}
+	if level, err := strconv.Atoi(record.Priority); err == nil && level >= 0 && level < len(JournalPriorities) {
+		entry.Priority = JournalPriorities[level]
+	}
+	entry.PID, _ = strconv.Atoi(record.PID) //nolint:errcheck // 0 when missing
+
+	var text string
+	var data []byte
+	switch {
+	case json.Unmarshal(record.Message, &text) == nil:
+		entry.Message = text
+	case json.Unmarshal(record.Message, &data) == nil:
+		entry.Message = strings.ToValidUTF8(string(data), "?")
+	}
+	return entry, true
+}
+
+// parseSyslogLine reads a line of a syslog file: "Oct 16 18:55:03 host
+// ident[pid]: message", or with an RFC 3339 time in place of the first three
+// fields
+func parseSyslogLine(line string) (JournalEntry, bool) {
+	fields := strings.Fields(line)
+	timeFields := 3
+	if len(fields) > 0 && strings.Contains(fields[0], "T") && len(fields[0]) >= 19 {
+		timeFields = 1
+	}
+	if len(fields) < timeFields+2 {
+		return JournalEntry{}, false
+	}
+	entry := JournalEntry{Time: strings.Join(fields[:timeFields], " "), Host: fields[timeFields]}
+
+	rest := line
+	for range timeFields + 1 {
+		rest = strings.TrimLeft(rest, " ")
+		rest = rest[strings.Index(rest, " "):]
+	}
+	rest = strings.TrimLeft(rest, " ")
+	if tag, message, ok := strings.Cut(rest, ": "); ok && !strings.Contains(tag, " ") {
+		entry.Identifier, rest = tag, message
+		if name, pid, hasPID := strings.Cut(tag, "["); hasPID {
+			entry.Identifier = name
+			entry.PID, _ = strconv.Atoi(strings.TrimSuffix(pid, "]")) //nolint:errcheck // 0 when malformed
+		}
+	}
+	entry.Message = rest
+	return entry, true
+}
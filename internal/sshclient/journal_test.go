@@ -0,0 +1,88 @@
+package sshclient
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalQuery_Validate(t *testing.T) {
+	q := JournalQuery{Units: []string{"nginx.service", "getty@tty1.service"}, Priority: "3"}
+	require.NoError(t, q.Validate())
+	assert.Equal(t, "err", q.Priority)
+	assert.Equal(t, DefaultJournalLines, q.Lines)
+
+	assert.ErrorContains(t, (&JournalQuery{Units: []string{"nginx;reboot"}}).Validate(), "invalid unit name")
+	assert.ErrorContains(t, (&JournalQuery{Units: []string{"--system"}}).Validate(), "invalid unit name")
+	assert.ErrorContains(t, (&JournalQuery{Priority: "fatal"}).Validate(), "unknown priority 'fatal'")
+	assert.ErrorContains(t, (&JournalQuery{Priority: "8"}).Validate(), "unknown priority '8'")
+	assert.ErrorContains(t, (&JournalQuery{Lines: MaxJournalLines + 1}).Validate(), "lines must be between 1 and 2000")
+}
+
+func TestJournalCommand(t *testing.T) {
+	q := JournalQuery{Units: []string{"nginx.service"}, Priority: "warning", Since: "1 hour ago", Until: "2026-10-16 12:00", Grep: "upstream timed out|502", Lines: 50}
+	command := JournalCommand(q)
+	assert.False(t, Explain(command, nil).Flagged, "%s must pass the safety check", command)
+	assert.Contains(t, command, "journalctl --no-pager -o json -n 50 -u nginx.service -p warning --since '1 hour ago' --until '2026-10-16 12:00' --grep 'upstream timed out|502'")
+	assert.Contains(t, command, `grep -E -e ' (nginx)(\[[0-9]+\])?: ' "$f" | grep -E -e 'upstream timed out|502' | tail -n 50`)
+	assert.Contains(t, JournalCommand(JournalQuery{Lines: 10}), `tail -n 10 "$f"`)
+
+	// The arguments reach journalctl intact
+	bin := t.TempDir()
+	fake := "#!/bin/sh\nfor a in \"$@\"; do echo \"[$a]\"; done\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "journalctl"), []byte(fake), 0o700)) // #nosec G306 -- test helper
+	cmd := exec.Command("sh", "-c", command)                                                // #nosec G204 -- test command
+	cmd.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"))
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "@sshx-journal:journald", strings.Split(string(output), "\n")[0])
+	assert.Contains(t, string(output), "[1 hour ago]\n")
+	assert.Contains(t, string(output), "[upstream timed out|502]\n")
+}
+
+func TestParseJournal_Journald(t *testing.T) {
+	output := `@sshx-journal:journald
+{"__REALTIME_TIMESTAMP":"1760600000123456","PRIORITY":"3","_SYSTEMD_UNIT":"nginx.service","SYSLOG_IDENTIFIER":"nginx","_PID":"812","_HOSTNAME":"web1","MESSAGE":"upstream timed out"}
+{"__REALTIME_TIMESTAMP":"1760600001000000","PRIORITY":"6","SYSLOG_IDENTIFIER":"kernel","MESSAGE":[104,105,255]}
+{"__REALTIME_TIMESTAMP":"1760600002000000","MESSAGE":null}
+-- No entries --
+{"__REALTIME_TIMESTAMP":"1760600003000000","MESSAGE":"` + strings.Repeat("x", maxJournalMessage+10) + `"}
+`
+	result := ParseJournal(output, JournalQuery{Since: "1 hour ago"})
+	assert.Equal(t, JournalSourceJournald, result.Source)
+	require.Len(t, result.Entries, 4)
+	assert.Equal(t, JournalEntry{
+		Time: "2025-10-16T07:33:20.123456Z", Priority: "err", Unit: "nginx.service", Identifier: "nginx", PID: 812, Host: "web1", Message: "upstream timed out",
+	}, result.Entries[0])
+	assert.Equal(t, "hi?", result.Entries[1].Message, "binary messages arrive as byte arrays")
+	assert.Equal(t, "info", result.Entries[1].Priority)
+	assert.Empty(t, result.Entries[2].Message)
+	assert.Len(t, result.Entries[3].Message, maxJournalMessage+len(" …"))
+	assert.True(t, result.Truncated)
+	assert.Empty(t, result.Warnings)
+}
+
+func TestParseJournal_Syslog(t *testing.T) {
+	output := `@sshx-journal:syslog
+Oct 16 18:55:03 web1 nginx[812]: upstream timed out
+2026-10-16T18:55:04.123456+00:00 web1 CRON[900]: (root) CMD (run-parts /etc/cron.hourly)
+Oct 16 18:55:05 web1 kernel: [12345.678] eth0: link up
+Oct  6 08:00:00 web1 message without a tag
+... [output truncated: 100 of 2000 bytes omitted]
+`
+	result := ParseJournal(output, JournalQuery{Priority: "err", Since: "1 hour ago"})
+	assert.Equal(t, JournalSourceSyslog, result.Source)
+	require.Len(t, result.Entries, 4)
+	assert.Equal(t, JournalEntry{Time: "Oct 16 18:55:03", Identifier: "nginx", PID: 812, Host: "web1", Message: "upstream timed out"}, result.Entries[0])
+	assert.Equal(t, JournalEntry{Time: "2026-10-16T18:55:04.123456+00:00", Identifier: "CRON", PID: 900, Host: "web1", Message: "(root) CMD (run-parts /etc/cron.hourly)"}, result.Entries[1])
+	assert.Equal(t, "kernel", result.Entries[2].Identifier)
+	assert.Equal(t, "[12345.678] eth0: link up", result.Entries[2].Message)
+	assert.Equal(t, JournalEntry{Time: "Oct 6 08:00:00", Host: "web1", Message: "message without a tag"}, result.Entries[3])
+	assert.True(t, result.Truncated)
+	assert.Equal(t, []string{"the host has no journald: priority, since ignored for the syslog file"}, result.Warnings)
+}
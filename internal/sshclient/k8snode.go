@@ -0,0 +1,76 @@
+package sshclient
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultKubeconfig is the kubeconfig kubectl uses on a node: the kubelet's
+// own credentials, which may read the node and the pods bound to it
+const DefaultKubeconfig = "/etc/kubernetes/kubelet.conf"
+
+// k8sNodeNamePattern matches Kubernetes node names (DNS subdomains)
+var k8sNodeNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// ValidateK8sNodeName rejects names Kubernetes would not accept for a node;
+// an empty name means the host's own hostname
+func ValidateK8sNodeName(name string) error {
+	if name != "" && !k8sNodeNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid node name '%s'", name)
+	}
+	return nil
+}
+
+// K8sNodeCommand prints the node object as JSON
+func K8sNodeCommand(kubeconfig, node string) string {
+	return kubectlCommand(kubeconfig, "get node "+k8sNodeRef("", node)+" -o json")
+}
+
+// K8sNodePodsCommand prints the pods scheduled on the node as JSON
+func K8sNodePodsCommand(kubeconfig, node string) string {
+	return kubectlCommand(kubeconfig, "get pods --all-namespaces --field-selector "+k8sNodeRef("spec.nodeName=", node)+" -o json")
+}
+
+// KubeletLogsCommand prints the last lines of the kubelet journal (all when
+// 0), optionally only entries newer than since (a journalctl time such as
+// "10 min ago" or "2026-01-02 15:04")
+func KubeletLogsCommand(lines int, since string) string {
+	command := "journalctl -u kubelet --no-pager -o short-iso"
+	if lines > 0 {
+		command += fmt.Sprintf(" -n %d", lines)
+	}
+	if since != "" {
+		command += " " + BuildCommand("--since", since)
+	}
+	return command
+}
+
+// CrictlPsCommand lists the containers of the node's container runtime,
+// optionally including exited ones or only those whose name matches name
+func CrictlPsCommand(all bool, name string) string {
+	command := "crictl ps"
+	if all {
+		command += " -a"
+	}
+	if name != "" {
+		command += " " + BuildCommand("--name", name)
+	}
+	return command
+}
+
+// kubectlCommand runs kubectl with kubeconfig (default DefaultKubeconfig)
+func kubectlCommand(kubeconfig, args string) string {
+	if kubeconfig == "" {
+		kubeconfig = DefaultKubeconfig
+	}
+	return BuildCommand("kubectl", "--kubeconfig", kubeconfig) + " " + args
+}
+
+// k8sNodeRef is the shell word for prefix followed by the node name, the
+// remote hostname when node is empty
+func k8sNodeRef(prefix, node string) string {
+	if node == "" {
+		return `"` + prefix + `$(hostname)"`
+	}
+	return ShellQuote(prefix + node)
+}
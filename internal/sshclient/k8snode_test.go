@@ -0,0 +1,30 @@
+package sshclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestK8sNodeCommands(t *testing.T) {
+	assert.Equal(t, `kubectl --kubeconfig /etc/kubernetes/kubelet.conf get node "$(hostname)" -o json`, K8sNodeCommand("", ""))
+	assert.Equal(t, `kubectl --kubeconfig /etc/kubernetes/admin.conf get node 'node-1.example' -o json`,
+		K8sNodeCommand("/etc/kubernetes/admin.conf", "node-1.example"))
+	assert.Equal(t, `kubectl --kubeconfig /etc/kubernetes/kubelet.conf get pods --all-namespaces --field-selector "spec.nodeName=$(hostname)" -o json`,
+		K8sNodePodsCommand("", ""))
+	assert.Contains(t, K8sNodePodsCommand("", "node1"), `--field-selector 'spec.nodeName=node1'`)
+
+	assert.Equal(t, "journalctl -u kubelet --no-pager -o short-iso -n 200", KubeletLogsCommand(200, ""))
+	assert.Equal(t, "journalctl -u kubelet --no-pager -o short-iso --since '10 min ago'", KubeletLogsCommand(0, "10 min ago"))
+
+	assert.Equal(t, "crictl ps", CrictlPsCommand(false, ""))
+	assert.Equal(t, "crictl ps -a --name 'coredns.*'", CrictlPsCommand(true, "coredns.*"))
+}
+
+func TestValidateK8sNodeName(t *testing.T) {
+	assert.NoError(t, ValidateK8sNodeName(""))
+	assert.NoError(t, ValidateK8sNodeName("ip-10-0-1-5.ec2.internal"))
+	assert.Error(t, ValidateK8sNodeName("Node1"))
+	assert.Error(t, ValidateK8sNodeName("node1; reboot"))
+	assert.Error(t, ValidateK8sNodeName("-node"))
+}
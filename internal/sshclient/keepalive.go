@@ -0,0 +1,61 @@
+package sshclient
+
+import (
+	"time"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+	"golang.org/x/crypto/ssh"
+)
+
+// keepaliveMaxMissed is how many keepalives may go unanswered before the
+// connection is considered dead (like OpenSSH's ServerAliveCountMax)
+const keepaliveMaxMissed = 3
+
+// keepAlive sends keepalive@openssh.com requests every interval until the
+// connection closes. A connection that misses keepaliveMaxMissed replies in
+// a row is closed so that the pool and callers see it as dead.
+func keepAlive(client *ssh.Client, interval time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		_ = client.Wait() //nolint:errcheck // only used to stop the ticker
+		close(done)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		reply := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			reply <- err
+		}()
+
+		select {
+		case <-done:
+			return
+		case err := <-reply:
+			if err == nil {
+				missed = 0
+				continue
+			}
+			missed++
+		case <-time.After(interval):
+			missed++
+		}
+
+		if missed >= keepaliveMaxMissed {
+			logger.GetLogger().Warning("No keepalive reply from %s after %d attempts, closing connection",
+				client.RemoteAddr(), missed)
+			_ = client.Close() //nolint:errcheck // connection is already unresponsive
+			return
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package sshclient
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// startUnresponsiveServer accepts SSH logins without authentication but
+// never answers global requests
+func startUnresponsiveServer(t *testing.T) string {
+	t.Helper()
+	return listenTestSSHServer(t, nil, func(conn net.Conn, serverConfig *ssh.ServerConfig) {
+		sshConn, chans, _, err := ssh.NewServerConn(conn, serverConfig)
+		if err != nil {
+			return
+		}
+		defer func() { _ = sshConn.Close() }()
+		// Leave global requests unanswered
+		for newChannel := range chans {
+			_ = newChannel.Reject(ssh.Prohibited, "no channels")
+		}
+	})
+}
+
+func TestKeepAlive_ClosesUnresponsiveConnection(t *testing.T) {
+	addr := startUnresponsiveServer(t)
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // #nosec G106 -- test server
+		Timeout:         time.Second,
+	})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	go keepAlive(client, 20*time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("keepalive did not close the unresponsive connection")
+	}
+}
@@ -0,0 +1,101 @@
+package sshclient
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key types GenerateKey creates
+const (
+	KeyTypeEd25519 = "ed25519"
+	KeyTypeECDSA   = "ecdsa"
+	KeyTypeRSA     = "rsa"
+)
+
+// KeyTypes lists the key types GenerateKey supports
+var KeyTypes = []string{KeyTypeEd25519, KeyTypeECDSA, KeyTypeRSA}
+
+// rsaKeyBits is the size of generated RSA keys
+const rsaKeyBits = 4096
+
+// GenerateKey creates a keypair of keyType and returns the private key in
+// OpenSSH format, encrypted with passphrase unless it is empty, and the
+// public key in authorized_keys format followed by comment
+func GenerateKey(keyType, comment, passphrase string) (privateKey []byte, publicKey string, err error) {
+	var key crypto.Signer
+	switch keyType {
+	case KeyTypeEd25519:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case KeyTypeECDSA:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeRSA:
+		key, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return nil, "", fmt.Errorf("unsupported key type '%s' (use %s)", keyType, strings.Join(KeyTypes, ", "))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate %s key: %w", keyType, err)
+	}
+
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, comment, []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(key, comment)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode private key: %w", err)
+	}
+	signer, err := ssh.NewSignerFromSigner(key)
+	if err != nil {
+		return nil, "", err
+	}
+	return pem.EncodeToMemory(block), authorizedKeyLine(signer.PublicKey(), comment), nil
+}
+
+// authorizedKeyLine formats key in authorized_keys format with comment
+func authorizedKeyLine(key ssh.PublicKey, comment string) string {
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	if comment != "" {
+		line += " " + comment
+	}
+	return line
+}
+
+// ParsePrivateKey reads a private key, decrypting it with the passphrase
+// stored in the keyring under passphraseKey when it is protected by one
+func ParsePrivateKey(key []byte, passphraseKey string) (ssh.Signer, error) {
+	signer, err := ssh.ParsePrivateKey(key)
+	var missing *ssh.PassphraseMissingError
+	if err == nil || !errors.As(err, &missing) {
+		return signer, err
+	}
+	if passphraseKey == "" {
+		return nil, fmt.Errorf("%w (store the passphrase with --password-set and set key_passphrase_key on the host)", err)
+	}
+	passphrase, err := GetSudoPassword(passphraseKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key passphrase: %w", err)
+	}
+	return ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+}
+
+// PublicKeyOf returns the public key of a private key in authorized_keys
+// format, for private keys whose .pub file is missing
+func PublicKeyOf(key []byte, passphraseKey, comment string) (string, error) {
+	signer, err := ParsePrivateKey(key, passphraseKey)
+	if err != nil {
+		return "", err
+	}
+	return authorizedKeyLine(signer.PublicKey(), comment), nil
+}
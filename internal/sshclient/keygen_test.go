@@ -0,0 +1,48 @@
+package sshclient
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateKey(t *testing.T) {
+	for _, keyType := range []string{KeyTypeEd25519, KeyTypeECDSA} {
+		privateKey, publicKey, err := GenerateKey(keyType, "ops@laptop", "")
+		require.NoError(t, err, keyType)
+		assert.Contains(t, string(privateKey), "BEGIN OPENSSH PRIVATE KEY")
+
+		signer, err := ParsePrivateKey(privateKey, "")
+		require.NoError(t, err, keyType)
+		key, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+		require.NoError(t, err, keyType)
+		assert.Equal(t, "ops@laptop", comment)
+		assert.Equal(t, signer.PublicKey().Marshal(), key.Marshal())
+	}
+
+	_, _, err := GenerateKey("dsa", "", "")
+	assert.ErrorContains(t, err, "unsupported key type 'dsa'")
+}
+
+func TestParsePrivateKey_Passphrase(t *testing.T) {
+	keyring.MockInit()
+	require.NoError(t, keyring.Set(KeyringServiceName, "ops-key", "correct horse"))
+	t.Cleanup(func() { ForgetSudoPassword("ops-key") })
+
+	privateKey, publicKey, err := GenerateKey(KeyTypeEd25519, "", "correct horse")
+	require.NoError(t, err)
+
+	_, err = ParsePrivateKey(privateKey, "")
+	assert.ErrorContains(t, err, "key_passphrase_key")
+	_, err = ParsePrivateKey(privateKey, "missing-key")
+	assert.ErrorContains(t, err, "failed to get key passphrase")
+
+	derived, err := PublicKeyOf(privateKey, "ops-key", "")
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, derived)
+	assert.False(t, strings.HasSuffix(derived, " "))
+}
@@ -0,0 +1,207 @@
+package sshclient
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultListLimit is the page size of List when no limit is given
+	DefaultListLimit = 200
+	// maxListScan bounds how many entries a recursive listing reads, so a
+	// listing of / cannot exhaust memory
+	maxListScan = 100000
+)
+
+// ListSortKeys are the keys List can sort by
+var ListSortKeys = []string{"name", "size", "mtime"}
+
+// ListOptions controls SSHClient.List
+type ListOptions struct {
+	// Recursive descends into subdirectories (symlinks are not followed)
+	Recursive bool
+	// Pattern keeps entries whose name matches this glob (e.g. "*.log")
+	Pattern string
+	// Type keeps only entries of this type: file, dir or symlink
+	Type string
+	// Sort is the sort key: name (default, the relative path when
+	// recursive), size or mtime
+	Sort string
+	// Reverse sorts in descending order
+	Reverse bool
+	// Offset skips this many matching entries
+	Offset int
+	// Limit caps the entries returned (default DefaultListLimit)
+	Limit int
+}
+
+// Validate checks the sort key, type and pattern
+func (o ListOptions) Validate() error {
+	if o.Sort != "" && !slices.Contains(ListSortKeys, o.Sort) {
+		return fmt.Errorf("invalid sort key '%s' (use %s)", o.Sort, strings.Join(ListSortKeys, ", "))
+	}
+	if o.Type != "" && !slices.Contains([]string{"file", "dir", "symlink"}, o.Type) {
+		return fmt.Errorf("invalid type '%s' (use file, dir or symlink)", o.Type)
+	}
+	if _, err := path.Match(o.Pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern '%s': %w", o.Pattern, err)
+	}
+	if o.Offset < 0 || o.Limit < 0 {
+		return fmt.Errorf("offset and limit must not be negative")
+	}
+	return nil
+}
+
+// FileEntry describes one entry of a listing
+type FileEntry struct {
+	Path     string    `json:"path"` // Relative to the listed directory
+	Type     string    `json:"type"` // file, dir, symlink or other
+	Size     int64     `json:"size"`
+	Mode     string    `json:"mode"`
+	Modified time.Time `json:"modified"`
+}
+
+// Listing is one page of a directory listing
+type Listing struct {
+	Path    string      `json:"path"`
+	Total   int         `json:"total"` // Entries matching the filters
+	Offset  int         `json:"offset"`
+	Entries []FileEntry `json:"entries"`
+	// NextOffset is the offset of the next page, 0 on the last page
+	NextOffset int `json:"next_offset,omitempty"`
+	// Incomplete is set when the scan stopped after maxListScan entries
+	// or could not read some subdirectories
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// dirReader reads directories; *sftp.Client implements it
+type dirReader interface {
+	ReadDir(dir string) ([]os.FileInfo, error)
+}
+
+// List returns one page of the entries of a remote directory, filtered,
+// sorted and paginated by opts
+func (c *SSHClient) List(remotePath string, opts ListOptions) (listing *Listing, err error) {
+	if err = opts.Validate(); err != nil {
+		return nil, err
+	}
+	op := c.newOperation(Request{})
+	err = op.withSFTP(func() error {
+		if err := op.checkPath(remotePath, false); err != nil {
+			return err
+		}
+		listing, err = listDirectory(op.sftpClient, remotePath, opts)
+		return err
+	})
+	return listing, err
+}
+
+// listDirectory scans root and builds the requested page
+func listDirectory(reader dirReader, root string, opts ListOptions) (*Listing, error) {
+	if root == "" {
+		root = "."
+	}
+	listing := &Listing{Path: root, Offset: opts.Offset, Entries: []FileEntry{}}
+
+	var matched []FileEntry
+	scanned := 0
+	pending := []string{""}
+	for len(pending) > 0 {
+		relative := pending[0]
+		pending = pending[1:]
+
+		infos, err := reader.ReadDir(path.Join(root, relative))
+		if err != nil {
+			if relative == "" {
+				return nil, fmt.Errorf("failed to list directory: %w", err)
+			}
+			// Unreadable subdirectories are skipped
+			listing.Incomplete = true
+			continue
+		}
+		for _, info := range infos {
+			if scanned++; scanned > maxListScan {
+				listing.Incomplete = true
+				pending = nil
+				break
+			}
+			entry := FileEntry{
+				Path:     path.Join(relative, info.Name()),
+				Type:     fileType(info.Mode()),
+				Size:     info.Size(),
+				Mode:     info.Mode().String(),
+				Modified: info.ModTime(),
+			}
+			if opts.Recursive && entry.Type == "dir" {
+				pending = append(pending, entry.Path)
+			}
+			if opts.Type != "" && entry.Type != opts.Type {
+				continue
+			}
+			if opts.Pattern != "" {
+				if ok, _ := path.Match(opts.Pattern, info.Name()); !ok { //nolint:errcheck // validated
+					continue
+				}
+			}
+			matched = append(matched, entry)
+		}
+	}
+
+	sortEntries(matched, opts.Sort, opts.Reverse)
+
+	limit := opts.Limit
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	listing.Total = len(matched)
+	if opts.Offset < len(matched) {
+		end := min(opts.Offset+limit, len(matched))
+		listing.Entries = matched[opts.Offset:end]
+		if end < len(matched) {
+			listing.NextOffset = end
+		}
+	}
+	return listing, nil
+}
+
+// sortEntries sorts by key (name, size or mtime), ties broken by path
+func sortEntries(entries []FileEntry, key string, reverse bool) {
+	less := func(a, b FileEntry) bool {
+		switch key {
+		case "size":
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+		case "mtime":
+			if !a.Modified.Equal(b.Modified) {
+				return a.Modified.Before(b.Modified)
+			}
+		}
+		return a.Path < b.Path
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if reverse {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+}
+
+// fileType names the type of a file mode
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode.IsRegular():
+		return "file"
+	default:
+		return "other"
+	}
+}
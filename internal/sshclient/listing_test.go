@@ -0,0 +1,150 @@
+package sshclient
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// localDirReader reads local directories, standing in for an SFTP client
+type localDirReader struct {
+	unreadable string
+}
+
+func (r localDirReader) ReadDir(dir string) ([]os.FileInfo, error) {
+	if r.unreadable != "" && dir == r.unreadable {
+		return nil, errors.New("permission denied")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// listingTree creates app.log (3 bytes), big.log (10 bytes), notes.txt,
+// a link to app.log and sub/nested.log (1 byte), with increasing mtimes
+func listingTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	files := []struct {
+		name string
+		size int
+	}{{"app.log", 3}, {"big.log", 10}, {"notes.txt", 5}, {"sub/nested.log", 1}}
+	for i, file := range files {
+		name := filepath.Join(root, file.name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+		require.NoError(t, os.WriteFile(name, make([]byte, file.size), 0o644))
+		modified := base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(name, modified, modified))
+	}
+	require.NoError(t, os.Symlink("app.log", filepath.Join(root, "link")))
+	return root
+}
+
+func entryPaths(listing *Listing) []string {
+	paths := make([]string, 0, len(listing.Entries))
+	for _, entry := range listing.Entries {
+		paths = append(paths, entry.Path)
+	}
+	return paths
+}
+
+func TestListDirectory(t *testing.T) {
+	root := listingTree(t)
+
+	listing, err := listDirectory(localDirReader{}, root, ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.log", "big.log", "link", "notes.txt", "sub"}, entryPaths(listing))
+	assert.Equal(t, 5, listing.Total)
+	assert.Zero(t, listing.NextOffset)
+	assert.False(t, listing.Incomplete)
+
+	types := map[string]string{}
+	for _, entry := range listing.Entries {
+		types[entry.Path] = entry.Type
+	}
+	assert.Equal(t, map[string]string{"app.log": "file", "big.log": "file", "link": "symlink", "notes.txt": "file", "sub": "dir"}, types)
+	assert.EqualValues(t, 3, listing.Entries[0].Size)
+
+	listing, err = listDirectory(localDirReader{}, root, ListOptions{Recursive: true, Pattern: "*.log"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.log", "big.log", "sub/nested.log"}, entryPaths(listing))
+
+	listing, err = listDirectory(localDirReader{}, root, ListOptions{Recursive: true, Type: "dir"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub"}, entryPaths(listing))
+
+	_, err = listDirectory(localDirReader{}, filepath.Join(root, "missing"), ListOptions{})
+	assert.ErrorContains(t, err, "failed to list directory")
+}
+
+func TestListDirectorySort(t *testing.T) {
+	root := listingTree(t)
+	opts := ListOptions{Recursive: true, Type: "file"}
+
+	opts.Sort = "size"
+	listing, err := listDirectory(localDirReader{}, root, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub/nested.log", "app.log", "notes.txt", "big.log"}, entryPaths(listing))
+
+	opts.Sort, opts.Reverse = "mtime", true
+	listing, err = listDirectory(localDirReader{}, root, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub/nested.log", "notes.txt", "big.log", "app.log"}, entryPaths(listing))
+}
+
+func TestListDirectoryPagination(t *testing.T) {
+	root := listingTree(t)
+	opts := ListOptions{Recursive: true, Limit: 2}
+
+	listing, err := listDirectory(localDirReader{}, root, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.log", "big.log"}, entryPaths(listing))
+	assert.Equal(t, 6, listing.Total)
+	assert.Equal(t, 2, listing.NextOffset)
+
+	opts.Offset = 4
+	listing, err = listDirectory(localDirReader{}, root, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub", "sub/nested.log"}, entryPaths(listing))
+	assert.Zero(t, listing.NextOffset)
+
+	opts.Offset = 10
+	listing, err = listDirectory(localDirReader{}, root, opts)
+	require.NoError(t, err)
+	assert.Empty(t, listing.Entries)
+	assert.NotNil(t, listing.Entries, "entries encode as [] rather than null")
+}
+
+func TestListDirectoryUnreadableSubdirectory(t *testing.T) {
+	root := listingTree(t)
+
+	listing, err := listDirectory(localDirReader{unreadable: filepath.Join(root, "sub")}, root, ListOptions{Recursive: true})
+	require.NoError(t, err)
+	assert.True(t, listing.Incomplete)
+	assert.Equal(t, 5, listing.Total)
+}
+
+func TestListOptionsValidate(t *testing.T) {
+	assert.NoError(t, ListOptions{}.Validate())
+	assert.NoError(t, ListOptions{Sort: "mtime", Type: "symlink", Pattern: "*.gz"}.Validate())
+	assert.ErrorContains(t, ListOptions{Sort: "owner"}.Validate(), "invalid sort key")
+	assert.ErrorContains(t, ListOptions{Type: "socket"}.Validate(), "invalid type")
+	assert.ErrorContains(t, ListOptions{Pattern: "[a-"}.Validate(), "invalid pattern")
+	assert.Error(t, ListOptions{Offset: -1}.Validate())
+}
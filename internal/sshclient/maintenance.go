@@ -0,0 +1,204 @@
+package sshclient
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow is a weekly period in which a safety policy lets
+// flagged commands run
+type MaintenanceWindow struct {
+	// Days are the weekdays the window opens on (empty = every day)
+	Days []time.Weekday
+	// Start and End are the opening and closing times as offsets from
+	// midnight; an End before Start closes the window on the next day
+	Start time.Duration
+	End   time.Duration
+	// Location is the time zone of Start and End (nil = local time)
+	Location *time.Location
+}
+
+// maintenanceWindowWire is how a MaintenanceWindow travels in a gob,
+// which cannot encode a *time.Location, e.g. to the control master
+type maintenanceWindowWire struct {
+	Days       []time.Weekday
+	Start, End time.Duration
+	Timezone   string
+}
+
+// GobEncode sends the window with the name of its time zone
+func (w MaintenanceWindow) GobEncode() ([]byte, error) {
+	wire := maintenanceWindowWire{Days: w.Days, Start: w.Start, End: w.End}
+	if w.Location != nil {
+		wire.Timezone = w.Location.String()
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(wire)
+	return buf.Bytes(), err
+}
+
+// GobDecode loads the time zone of the window by name
+func (w *MaintenanceWindow) GobDecode(data []byte) error {
+	var wire maintenanceWindowWire
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&wire); err != nil {
+		return err
+	}
+	*w = MaintenanceWindow{Days: wire.Days, Start: wire.Start, End: wire.End}
+	if wire.Timezone != "" {
+		location, err := time.LoadLocation(wire.Timezone)
+		if err != nil {
+			return fmt.Errorf("maintenance window: %w", err)
+		}
+		w.Location = location
+	}
+	return nil
+}
+
+// weekdays maps the day names ParseMaintenanceWindow accepts
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseMaintenanceWindow parses a window from day names (mon, tuesday, ...),
+// HH:MM opening and closing times and an IANA time zone (empty = local)
+func ParseMaintenanceWindow(days []string, start, end, timezone string) (MaintenanceWindow, error) {
+	var window MaintenanceWindow
+	for _, day := range days {
+		name := strings.ToLower(strings.TrimSpace(day))
+		weekday, ok := weekdays[name]
+		if !ok && len(name) > 3 {
+			weekday, ok = weekdays[name[:3]]
+			ok = ok && strings.EqualFold(weekday.String(), name)
+		}
+		if !ok {
+			return window, fmt.Errorf("invalid day %q (want mon, tue, ... or monday, tuesday, ...)", day)
+		}
+		window.Days = append(window.Days, weekday)
+	}
+	var err error
+	if window.Start, err = parseClock(start); err != nil {
+		return window, fmt.Errorf("invalid start: %w", err)
+	}
+	if window.End, err = parseClock(end); err != nil {
+		return window, fmt.Errorf("invalid end: %w", err)
+	}
+	if window.Start == window.End {
+		return window, fmt.Errorf("the window opens and closes at %s", start)
+	}
+	if timezone != "" {
+		if window.Location, err = time.LoadLocation(timezone); err != nil {
+			return window, fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+	return window, nil
+}
+
+// parseClock parses HH:MM as an offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// String describes the window, e.g. "sat,sun 02:00-05:00 UTC"
+func (w MaintenanceWindow) String() string {
+	days := "daily"
+	if len(w.Days) > 0 {
+		names := make([]string, len(w.Days))
+		for i, day := range w.Days {
+			names[i] = strings.ToLower(day.String()[:3])
+		}
+		days = strings.Join(names, ",")
+	}
+	return fmt.Sprintf("%s %s-%s %s", days, formatClock(w.Start), formatClock(w.End), w.location())
+}
+
+func formatClock(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset/time.Hour), int(offset%time.Hour/time.Minute))
+}
+
+func (w MaintenanceWindow) location() *time.Location {
+	if w.Location == nil {
+		return time.Local
+	}
+	return w.Location
+}
+
+// opensOn reports whether the window opens on day
+func (w MaintenanceWindow) opensOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// period returns when the window that opens on the day of midnight opens
+// and closes
+func (w MaintenanceWindow) period(midnight time.Time) (time.Time, time.Time) {
+	at := func(day time.Time, offset time.Duration) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, day.Location())
+	}
+	closing := midnight
+	if w.End < w.Start {
+		closing = midnight.AddDate(0, 0, 1)
+	}
+	return at(midnight, w.Start), at(closing, w.End)
+}
+
+// Contains reports whether the window is open at t
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	t = t.In(w.location())
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	// A window past midnight is still open from the day before
+	for _, day := range []time.Time{today, today.AddDate(0, 0, -1)} {
+		if !w.opensOn(day.Weekday()) {
+			continue
+		}
+		if opens, closes := w.period(day); !t.Before(opens) && t.Before(closes) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpening returns when the window opens next after t
+func (w MaintenanceWindow) NextOpening(t time.Time) time.Time {
+	t = t.In(w.location())
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for i := 0; i <= 7; i++ {
+		day := today.AddDate(0, 0, i)
+		if !w.opensOn(day.Weekday()) {
+			continue
+		}
+		if opens, _ := w.period(day); opens.After(t) {
+			return opens
+		}
+	}
+	return time.Time{}
+}
+
+// NextMaintenanceWindow reports whether one of windows is open at t, and
+// otherwise when the first of them opens; the time is zero when none will
+func NextMaintenanceWindow(windows []MaintenanceWindow, t time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, window := range windows {
+		if window.Contains(t) {
+			return time.Time{}, true
+		}
+		if opens := window.NextOpening(t); !opens.IsZero() && (next.IsZero() || opens.Before(next)) {
+			next = opens
+		}
+	}
+	return next, false
+}
@@ -0,0 +1,100 @@
+package sshclient
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	window, err := ParseMaintenanceWindow([]string{"Sat", "sunday"}, "22:30", "04:00", "UTC")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Weekday{time.Saturday, time.Sunday}, window.Days)
+	assert.Equal(t, 22*time.Hour+30*time.Minute, window.Start)
+	assert.Equal(t, 4*time.Hour, window.End)
+	assert.Equal(t, "sat,sun 22:30-04:00 UTC", window.String())
+
+	for _, bad := range []struct {
+		days             []string
+		start, end, zone string
+		want             string
+	}{
+		{[]string{"someday"}, "02:00", "04:00", "", "invalid day"},
+		{[]string{"thurs"}, "02:00", "04:00", "", "invalid day"},
+		{nil, "2am", "04:00", "", "invalid start"},
+		{nil, "02:00", "24:00", "", "invalid end"},
+		{nil, "02:00", "02:00", "", "opens and closes"},
+		{nil, "02:00", "04:00", "Mars/Olympus", "invalid timezone"},
+	} {
+		_, err := ParseMaintenanceWindow(bad.days, bad.start, bad.end, bad.zone)
+		assert.ErrorContains(t, err, bad.want, bad.days, bad.start, bad.end, bad.zone)
+	}
+}
+
+func TestMaintenanceWindow_Contains(t *testing.T) {
+	// Saturday 22:30 to Sunday 04:00 and Sunday 22:30 to Monday 04:00
+	window, err := ParseMaintenanceWindow([]string{"sat", "sun"}, "22:30", "04:00", "UTC")
+	require.NoError(t, err)
+	at := func(day int, clock string) time.Time {
+		hour, err := time.Parse("15:04", clock)
+		require.NoError(t, err)
+		return time.Date(2026, time.October, day, hour.Hour(), hour.Minute(), 0, 0, time.UTC)
+	}
+
+	assert.False(t, window.Contains(at(16, "23:00")), "friday")
+	assert.False(t, window.Contains(at(17, "22:29")))
+	assert.True(t, window.Contains(at(17, "22:30")))
+	assert.True(t, window.Contains(at(18, "03:59")), "past midnight")
+	assert.False(t, window.Contains(at(18, "04:00")))
+	assert.True(t, window.Contains(at(19, "01:00")), "the sunday window ends on monday")
+	assert.False(t, window.Contains(at(19, "23:00")))
+
+	// The time zone of the window counts, not that of the time: 00:45 on
+	// Sunday in Berlin is 22:45 on Saturday in UTC
+	berlin := at(17, "22:45").In(time.FixedZone("CEST", 2*3600))
+	assert.True(t, window.Contains(berlin))
+
+	assert.Equal(t, at(17, "22:30"), window.NextOpening(at(16, "09:00")))
+	assert.Equal(t, at(18, "22:30"), window.NextOpening(at(17, "22:30")))
+	assert.Equal(t, at(24, "22:30"), window.NextOpening(at(19, "01:00")))
+
+	daily, err := ParseMaintenanceWindow(nil, "02:00", "03:00", "UTC")
+	require.NoError(t, err)
+	assert.Equal(t, at(17, "02:00"), daily.NextOpening(at(16, "02:00")))
+}
+
+func TestNextMaintenanceWindow(t *testing.T) {
+	weekend, err := ParseMaintenanceWindow([]string{"sat"}, "02:00", "05:00", "UTC")
+	require.NoError(t, err)
+	nightly, err := ParseMaintenanceWindow([]string{"mon", "tue", "wed", "thu", "fri"}, "23:00", "23:30", "UTC")
+	require.NoError(t, err)
+	windows := []MaintenanceWindow{weekend, nightly}
+
+	friday := time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC)
+	next, open := NextMaintenanceWindow(windows, friday)
+	assert.False(t, open)
+	assert.Equal(t, time.Date(2026, time.October, 16, 23, 0, 0, 0, time.UTC), next)
+
+	_, open = NextMaintenanceWindow(windows, time.Date(2026, time.October, 17, 3, 0, 0, 0, time.UTC))
+	assert.True(t, open)
+
+	next, open = NextMaintenanceWindow([]MaintenanceWindow{}, friday)
+	assert.False(t, open)
+	assert.True(t, next.IsZero(), "no window ever opens")
+}
+
+func TestMaintenanceWindow_Gob(t *testing.T) {
+	window, err := ParseMaintenanceWindow([]string{"sun"}, "01:00", "03:00", "UTC")
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(&SafetyPolicy{Windows: []MaintenanceWindow{window, {Start: time.Hour, End: 2 * time.Hour}}}))
+	var policy SafetyPolicy
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&policy))
+	require.Len(t, policy.Windows, 2)
+	assert.Equal(t, "sun 01:00-03:00 UTC", policy.Windows[0].String())
+	assert.Nil(t, policy.Windows[1].Location)
+}
@@ -0,0 +1,268 @@
+package sshclient
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits of MetricsCommand, which blocks for samples × interval
+const (
+	DefaultMetricsSamples  = 5
+	DefaultMetricsInterval = 2 * time.Second
+	MaxMetricsSamples      = 30
+	MaxMetricsWindow       = 60 * time.Second
+)
+
+// metricsMarker prefixes the lines of MetricsCommand that start a section
+const metricsMarker = "@sshx-metrics:"
+
+// pseudoFilesystems are df entries that are not disks
+var pseudoFilesystems = map[string]bool{
+	"tmpfs": true, "devtmpfs": true, "overlay": true, "shm": true, "udev": true, "none": true, "run": true,
+}
+
+// MetricsSample is the utilization of a host over one interval
+type MetricsSample struct {
+	Time          time.Time `json:"time"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	IOWaitPercent float64   `json:"iowait_percent"`
+	MemoryPercent float64   `json:"memory_percent"`
+	MemoryUsed    uint64    `json:"memory_used_bytes"`
+	SwapPercent   float64   `json:"swap_percent"`
+	Load1         float64   `json:"load1"`
+	Load5         float64   `json:"load5"`
+	Load15        float64   `json:"load15"`
+}
+
+// DiskUsage is the usage of a mounted filesystem
+type DiskUsage struct {
+	Filesystem  string  `json:"filesystem"`
+	Mount       string  `json:"mount"`
+	Size        uint64  `json:"size_bytes"`
+	Used        uint64  `json:"used_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// MetricsSummary aggregates the samples
+type MetricsSummary struct {
+	CPUAvg    float64 `json:"cpu_avg"`
+	CPUMax    float64 `json:"cpu_max"`
+	MemoryAvg float64 `json:"memory_avg"`
+	MemoryMax float64 `json:"memory_max"`
+	Load1Max  float64 `json:"load1_max"`
+}
+
+// HostMetrics is the result of the host_metrics tool
+type HostMetrics struct {
+	CPUs        int             `json:"cpus"`
+	MemoryTotal uint64          `json:"memory_total_bytes"`
+	SwapTotal   uint64          `json:"swap_total_bytes"`
+	Interval    float64         `json:"interval_seconds"`
+	Samples     []MetricsSample `json:"samples"`
+	Summary     MetricsSummary  `json:"summary"`
+	Disks       []DiskUsage     `json:"disks"`
+}
+
+// ValidateMetricsWindow checks the number of samples and the interval
+// between them
+func ValidateMetricsWindow(samples int, interval time.Duration) error {
+	if samples < 1 || samples > MaxMetricsSamples {
+		return fmt.Errorf("samples must be between 1 and %d", MaxMetricsSamples)
+	}
+	if interval < time.Second || interval%time.Second != 0 {
+		return fmt.Errorf("interval must be a whole number of seconds, at least 1s")
+	}
+	if time.Duration(samples)*interval > MaxMetricsWindow {
+		return fmt.Errorf("samples × interval must be at most %s", MaxMetricsWindow)
+	}
+	return nil
+}
+
+// MetricsCommand reads /proc/stat, /proc/loadavg and /proc/meminfo
+// samples+1 times, interval apart, so that every sample has the CPU time
+// spent since the previous read, then prints the CPU count and df
+func MetricsCommand(samples int, interval time.Duration) string {
+	section := func(name string) string {
+		return "echo " + ShellQuote(metricsMarker+name)
+	}
+	return strings.Join([]string{
+		"i=0",
+		fmt.Sprintf("while [ $i -le %d ]; do", samples),
+		"  " + section("sample"),
+		"  date +%s",
+		"  head -n 1 /proc/stat",
+		"  cat /proc/loadavg",
+		"  grep -E '^(MemTotal|MemFree|MemAvailable|Buffers|Cached|SwapTotal|SwapFree):' /proc/meminfo",
+		fmt.Sprintf("  if [ $i -lt %d ]; then sleep %d; fi", samples, int(interval/time.Second)),
+		"  i=$((i + 1))",
+		"done",
+		section("cpus"),
+		"grep -c '^processor' /proc/cpuinfo",
+		section("df"),
+		"df -P -k 2>/dev/null",
+		"exit 0",
+	}, "\n")
+}
+
+// rawMetrics is one read of the /proc files
+type rawMetrics struct {
+	time              time.Time
+	cpuTotal, cpuIdle uint64
+	cpuIOWait         uint64
+	load              [3]float64
+	memory            map[string]uint64 // kB
+}
+
+// ParseMetrics reads the output of MetricsCommand
+func ParseMetrics(output string, interval time.Duration) (*HostMetrics, error) {
+	metrics := &HostMetrics{Interval: interval.Seconds(), Samples: []MetricsSample{}, Disks: []DiskUsage{}}
+	var reads []*rawMetrics
+	section := ""
+	for _, line := range splitLines(output) {
+		line = strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(line, metricsMarker); ok {
+			section = name
+			if name == "sample" {
+				reads = append(reads, &rawMetrics{memory: map[string]uint64{}})
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		switch section {
+		case "sample":
+			reads[len(reads)-1].parseLine(line)
+		case "cpus":
+			metrics.CPUs, _ = strconv.Atoi(line) //nolint:errcheck // 0 when unknown
+		case "df":
+			if disk, ok := parseDiskUsage(line); ok {
+				metrics.Disks = append(metrics.Disks, disk)
+			}
+		}
+	}
+	if len(reads) < 2 {
+		return nil, fmt.Errorf("no metrics in output (is /proc mounted?)")
+	}
+
+	for i := 1; i < len(reads); i++ {
+		previous, current := reads[i-1], reads[i]
+		sample := MetricsSample{Time: current.time, Load1: current.load[0], Load5: current.load[1], Load15: current.load[2]}
+		if total := float64(current.cpuTotal - previous.cpuTotal); current.cpuTotal > previous.cpuTotal {
+			sample.CPUPercent = percent(total-float64(current.cpuIdle-previous.cpuIdle), total)
+			sample.IOWaitPercent = percent(float64(current.cpuIOWait-previous.cpuIOWait), total)
+		}
+		memoryTotal, memoryUsed := current.memoryUsage()
+		sample.MemoryUsed = memoryUsed
+		sample.MemoryPercent = percent(float64(memoryUsed), float64(memoryTotal))
+		swapTotal := current.memory["SwapTotal"] * 1024
+		sample.SwapPercent = percent(float64(swapTotal-current.memory["SwapFree"]*1024), float64(swapTotal))
+		metrics.MemoryTotal, metrics.SwapTotal = memoryTotal, swapTotal
+		metrics.Samples = append(metrics.Samples, sample)
+	}
+	metrics.summarize()
+	return metrics, nil
+}
+
+// parseLine reads one line of a sample section
+func (r *rawMetrics) parseLine(line string) {
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 1 && r.time.IsZero():
+		if seconds, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			r.time = time.Unix(seconds, 0).UTC()
+		}
+	case fields[0] == "cpu":
+		// user nice system idle iowait irq softirq steal (guest time is
+		// already counted in user)
+		for i, field := range fields[1:min(len(fields), 9)] {
+			value, _ := strconv.ParseUint(field, 10, 64) //nolint:errcheck // 0 when malformed
+			r.cpuTotal += value
+			switch i {
+			case 3:
+				r.cpuIdle += value
+			case 4:
+				r.cpuIdle += value
+				r.cpuIOWait = value
+			}
+		}
+	case strings.HasSuffix(fields[0], ":"):
+		if len(fields) >= 2 {
+			value, _ := strconv.ParseUint(fields[1], 10, 64) //nolint:errcheck // 0 when malformed
+			r.memory[strings.TrimSuffix(fields[0], ":")] = value
+		}
+	case len(fields) >= 3:
+		for i := range r.load {
+			r.load[i], _ = strconv.ParseFloat(fields[i], 64) //nolint:errcheck // 0 when malformed
+		}
+	}
+}
+
+// memoryUsage returns the total and used memory in bytes. Kernels before
+// 3.14 lack MemAvailable, so free, buffers and cache count as available.
+func (r *rawMetrics) memoryUsage() (total, used uint64) {
+	total = r.memory["MemTotal"]
+	available, ok := r.memory["MemAvailable"]
+	if !ok {
+		available = r.memory["MemFree"] + r.memory["Buffers"] + r.memory["Cached"]
+	}
+	if available > total {
+		available = total
+	}
+	return total * 1024, (total - available) * 1024
+}
+
+// summarize computes the averages and maximums of the samples
+func (m *HostMetrics) summarize() {
+	var cpu, memory float64
+	for _, sample := range m.Samples {
+		cpu += sample.CPUPercent
+		memory += sample.MemoryPercent
+		m.Summary.CPUMax = max(m.Summary.CPUMax, sample.CPUPercent)
+		m.Summary.MemoryMax = max(m.Summary.MemoryMax, sample.MemoryPercent)
+		m.Summary.Load1Max = max(m.Summary.Load1Max, sample.Load1)
+	}
+	count := float64(len(m.Samples))
+	m.Summary.CPUAvg = round1(cpu / count)
+	m.Summary.MemoryAvg = round1(memory / count)
+}
+
+// parseDiskUsage reads one line of df -P -k, skipping pseudo filesystems
+func parseDiskUsage(line string) (DiskUsage, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 6 || pseudoFilesystems[fields[0]] || strings.HasPrefix(fields[0], "/dev/loop") {
+		return DiskUsage{}, false
+	}
+	size, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil || size == 0 {
+		return DiskUsage{}, false
+	}
+	used, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return DiskUsage{}, false
+	}
+	available, _ := strconv.ParseUint(fields[3], 10, 64) //nolint:errcheck // 0 when malformed
+	// Like df, the reserved blocks do not count as free
+	return DiskUsage{
+		Filesystem:  fields[0],
+		Mount:       strings.Join(fields[5:], " "),
+		Size:        size * 1024,
+		Used:        used * 1024,
+		UsedPercent: percent(float64(used), float64(used+available)),
+	}, true
+}
+
+// percent returns part/total as a percentage rounded to one decimal
+func percent(part, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return round1(100 * part / total)
+}
+
+func round1(value float64) float64 {
+	return math.Round(value*10) / 10
+}
@@ -0,0 +1,127 @@
+package sshclient
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMetricsWindow(t *testing.T) {
+	assert.NoError(t, ValidateMetricsWindow(DefaultMetricsSamples, DefaultMetricsInterval))
+	assert.NoError(t, ValidateMetricsWindow(30, 2*time.Second))
+	assert.ErrorContains(t, ValidateMetricsWindow(0, time.Second), "samples must be between 1 and 30")
+	assert.ErrorContains(t, ValidateMetricsWindow(31, time.Second), "samples must be between 1 and 30")
+	assert.ErrorContains(t, ValidateMetricsWindow(5, 500*time.Millisecond), "whole number of seconds")
+	assert.ErrorContains(t, ValidateMetricsWindow(10, 10*time.Second), "at most 1m0s")
+}
+
+func TestMetricsCommand(t *testing.T) {
+	command := MetricsCommand(2, time.Second)
+	assert.False(t, Explain(command, nil).Flagged, "%s must pass the safety check", command)
+	assert.Contains(t, command, "while [ $i -le 2 ]")
+	assert.Contains(t, command, "sleep 1")
+
+	if _, err := os.Stat("/proc/stat"); err != nil {
+		t.Skip("no /proc on this system")
+	}
+	output, err := exec.Command("sh", "-c", command).Output()
+	require.NoError(t, err)
+	metrics, err := ParseMetrics(string(output), time.Second)
+	require.NoError(t, err)
+	require.Len(t, metrics.Samples, 2)
+	assert.Positive(t, metrics.CPUs)
+	assert.Positive(t, metrics.MemoryTotal)
+	for _, sample := range metrics.Samples {
+		assert.False(t, sample.Time.IsZero())
+		assert.GreaterOrEqual(t, sample.CPUPercent, 0.0)
+		assert.LessOrEqual(t, sample.CPUPercent, 100.0)
+		assert.Positive(t, sample.MemoryUsed)
+	}
+}
+
+func TestParseMetrics(t *testing.T) {
+	output := `@sshx-metrics:sample
+1760000000
+cpu  1000 0 500 8000 500 0 0 0 0 0
+0.50 0.40 0.30 1/200 1234
+MemTotal:        8000000 kB
+MemFree:         1000000 kB
+MemAvailable:    6000000 kB
+Buffers:          100000 kB
+Cached:          3000000 kB
+SwapTotal:       2000000 kB
+SwapFree:        2000000 kB
+@sshx-metrics:sample
+1760000002
+cpu  1300 0 600 8500 600 0 0 0 0 0
+1.25 0.50 0.32 3/201 1240
+MemTotal:        8000000 kB
+MemFree:          900000 kB
+MemAvailable:    4000000 kB
+Buffers:          100000 kB
+Cached:          3000000 kB
+SwapTotal:       2000000 kB
+SwapFree:        1500000 kB
+@sshx-metrics:sample
+1760000004
+cpu  1300 0 600 9500 600 0 0 0 0 0
+0.90 0.50 0.32 1/201 1241
+MemTotal:        8000000 kB
+MemFree:          900000 kB
+MemAvailable:    6000000 kB
+Buffers:          100000 kB
+Cached:          3000000 kB
+SwapTotal:       2000000 kB
+SwapFree:        1500000 kB
+@sshx-metrics:cpus
+4
+@sshx-metrics:df
+Filesystem     1024-blocks     Used Available Capacity Mounted on
+/dev/sda1         41152812 20576406  18463276      53% /
+tmpfs               814080     1296    812784       1% /run
+/dev/loop0           65536    65536         0     100% /snap/core/1
+/dev/sdb1        104857600 10485760  94371840      10% /srv/my data
+`
+	metrics, err := ParseMetrics(output, 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 4, metrics.CPUs)
+	assert.Equal(t, 2.0, metrics.Interval)
+	assert.Equal(t, uint64(8000000*1024), metrics.MemoryTotal)
+	assert.Equal(t, uint64(2000000*1024), metrics.SwapTotal)
+
+	require.Len(t, metrics.Samples, 2)
+	first := metrics.Samples[0]
+	assert.Equal(t, time.Unix(1760000002, 0).UTC(), first.Time)
+	// 1000 jiffies passed: 400 busy, 600 idle of which 100 iowait
+	assert.Equal(t, 40.0, first.CPUPercent)
+	assert.Equal(t, 10.0, first.IOWaitPercent)
+	assert.Equal(t, 50.0, first.MemoryPercent)
+	assert.Equal(t, uint64(4000000*1024), first.MemoryUsed)
+	assert.Equal(t, 25.0, first.SwapPercent)
+	assert.Equal(t, [3]float64{1.25, 0.5, 0.32}, [3]float64{first.Load1, first.Load5, first.Load15})
+
+	second := metrics.Samples[1]
+	assert.Equal(t, 0.0, second.CPUPercent)
+	assert.Equal(t, 25.0, second.MemoryPercent)
+
+	assert.Equal(t, MetricsSummary{CPUAvg: 20, CPUMax: 40, MemoryAvg: 37.5, MemoryMax: 50, Load1Max: 1.25}, metrics.Summary)
+
+	require.Len(t, metrics.Disks, 2)
+	assert.Equal(t, DiskUsage{Filesystem: "/dev/sda1", Mount: "/", Size: 41152812 * 1024, Used: 20576406 * 1024, UsedPercent: 52.7}, metrics.Disks[0])
+	assert.Equal(t, "/srv/my data", metrics.Disks[1].Mount)
+}
+
+func TestParseMetrics_WithoutMemAvailable(t *testing.T) {
+	sample := "@sshx-metrics:sample\n1\ncpu 1 1 1 1\n0 0 0 1/1 1\nMemTotal: 1000 kB\nMemFree: 200 kB\nBuffers: 100 kB\nCached: 200 kB\n"
+	metrics, err := ParseMetrics(sample+sample, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, metrics.Samples[0].MemoryPercent)
+	assert.Equal(t, 0.0, metrics.Samples[0].SwapPercent)
+
+	_, err = ParseMetrics("sh: /proc/stat: not found\n", time.Second)
+	assert.ErrorContains(t, err, "no metrics in output")
+}
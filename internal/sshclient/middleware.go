@@ -0,0 +1,86 @@
+package sshclient
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// Executor runs the command described by config and returns its output.
+// Streaming executions (CLI) write directly to the terminal and return an
+// empty output.
+type Executor func(config *Config) (string, error)
+
+// Middleware wraps an Executor. A middleware sees the resolved config and
+// command before calling next and the result after it returns; it may also
+// short-circuit by returning without calling next.
+type Middleware func(next Executor) Executor
+
+var (
+	middlewareMu sync.RWMutex
+	middlewares  []Middleware
+)
+
+// Use registers middleware applied to every command executed by an SSHClient.
+// Middleware registered first runs outermost.
+func Use(mw ...Middleware) {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	middlewares = append(middlewares, mw...)
+}
+
+// ResetMiddleware removes all registered middleware
+func ResetMiddleware() {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	middlewares = nil
+}
+
+// Chain wraps final with mw so that mw[0] is the outermost layer
+func Chain(final Executor, mw ...Middleware) Executor {
+	for i := len(mw) - 1; i >= 0; i-- {
+		final = mw[i](final)
+	}
+	return final
+}
+
+// registeredChain wraps final with the registered middleware and the built-in
+// safety check, which runs innermost so middleware also observes blocked commands.
+func registeredChain(final Executor) Executor {
+	middlewareMu.RLock()
+	chain := make([]Middleware, 0, len(middlewares)+1)
+	chain = append(chain, middlewares...)
+	middlewareMu.RUnlock()
+
+	chain = append(chain, SafetyCheckMiddleware)
+	return Chain(final, chain...)
+}
+
+// SafetyCheckMiddleware rejects dangerous commands unless the config
+// disables the safety check. Commands the config's SafetyPolicy rates below
+// block run, and Force overrides a block once authorized (see
+// Config.ForceReason); either way the finding is left in
+// Config.SafetyFinding.
+func SafetyCheckMiddleware(next Executor) Executor {
+	return func(config *Config) (string, error) {
+		if config.SafetyCheck {
+			finding, err := CheckCommand(config.Command, config.Safety)
+			var blocked *BlockedCommandError
+			switch {
+			case err != nil && config.Force && errors.As(err, &blocked):
+				if err = authorizeForce(config, blocked); err != nil {
+					return "", err
+				}
+				finding = &SafetyFinding{Rule: blocked.Rule, Reason: blocked.Reason, Pattern: blocked.Pattern, Severity: SeverityBlock}
+				logger.GetLogger().Warning("Safety check bypassed by force (%s: %s), reason: %s", blocked.Rule, blocked.Reason, config.ForceReason)
+			case err != nil:
+				return "", err
+			case finding != nil:
+				logSafetyFinding(finding, config.Command)
+			}
+			config.SafetyFinding = finding
+		}
+		return next(config)
+	}
+}
@@ -0,0 +1,116 @@
+package sshclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain_Order(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Executor) Executor {
+			return func(config *Config) (string, error) {
+				calls = append(calls, name+":before")
+				output, err := next(config)
+				calls = append(calls, name+":after")
+				return output, err
+			}
+		}
+	}
+
+	exec := Chain(func(config *Config) (string, error) {
+		calls = append(calls, "exec")
+		return config.Command, nil
+	}, trace("outer"), trace("inner"))
+
+	output, err := exec(&Config{Command: "uptime"})
+	require.NoError(t, err)
+	assert.Equal(t, "uptime", output)
+	assert.Equal(t, []string{"outer:before", "inner:before", "exec", "inner:after", "outer:after"}, calls)
+}
+
+func TestRegisteredChain_SafetyCheck(t *testing.T) {
+	ResetMiddleware()
+	defer ResetMiddleware()
+
+	var seen error
+	Use(func(next Executor) Executor {
+		return func(config *Config) (string, error) {
+			output, err := next(config)
+			seen = err
+			return output, err
+		}
+	})
+
+	executed := false
+	final := func(*Config) (string, error) {
+		executed = true
+		return "ok", nil
+	}
+
+	_, err := registeredChain(final)(&Config{Command: "rm -rf /", SafetyCheck: true})
+	var blocked *BlockedCommandError
+	require.True(t, errors.As(err, &blocked))
+	assert.False(t, executed, "blocked command must not reach the executor")
+	assert.Equal(t, err, seen, "middleware observes blocked commands")
+
+	// Forcing a blocked command needs a reason
+	_, err = registeredChain(final)(&Config{Command: "rm -rf /", SafetyCheck: true, Force: true})
+	var refused *ForceRefusedError
+	require.True(t, errors.As(err, &refused))
+	require.True(t, errors.As(err, &blocked), "a refused force still reports the command as blocked")
+	assert.False(t, executed)
+
+	config := &Config{Command: "rm -rf /", SafetyCheck: true, Force: true, ForceReason: "wiping a scratch VM"}
+	output, err := registeredChain(final)(config)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", output)
+	assert.True(t, executed)
+	require.NotNil(t, config.SafetyFinding)
+	assert.Equal(t, "delete-root", config.SafetyFinding.Rule)
+	assert.Equal(t, SeverityBlock, config.SafetyFinding.Severity)
+}
+
+func TestSafetyCheckMiddleware_ConfirmForce(t *testing.T) {
+	executed := false
+	final := func(*Config) (string, error) {
+		executed = true
+		return "ok", nil
+	}
+
+	var asked *BlockedCommandError
+	config := &Config{Command: "sudo reboot", SafetyCheck: true, Force: true}
+	config.ConfirmForce = func(blocked *BlockedCommandError) (string, error) {
+		asked = blocked
+		return "kernel update", nil
+	}
+	_, err := SafetyCheckMiddleware(final)(config)
+	require.NoError(t, err)
+	assert.True(t, executed)
+	require.NotNil(t, asked)
+	assert.Equal(t, "reboot", asked.Rule)
+	assert.Equal(t, "kernel update", config.ForceReason)
+
+	executed = false
+	config = &Config{Command: "sudo reboot", SafetyCheck: true, Force: true, ForceReason: "given up front"}
+	config.ConfirmForce = func(*BlockedCommandError) (string, error) {
+		return "", errors.New("confirmation phrase did not match")
+	}
+	_, err = SafetyCheckMiddleware(final)(config)
+	var refused *ForceRefusedError
+	require.True(t, errors.As(err, &refused))
+	assert.Contains(t, err.Error(), "confirmation phrase did not match")
+	assert.False(t, executed)
+
+	// Commands that are not blocked need no confirmation
+	config = &Config{Command: "uptime", SafetyCheck: true, Force: true}
+	config.ConfirmForce = func(*BlockedCommandError) (string, error) {
+		t.Fatal("confirmation requested for a safe command")
+		return "", nil
+	}
+	_, err = SafetyCheckMiddleware(final)(config)
+	require.NoError(t, err)
+}
@@ -1,12 +1,54 @@
 package sshclient
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"fmt"
 	"io"
+	"net"
+	"testing"
 
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/ssh"
 )
 
+// testServerBanner is the pre-authentication banner of the test servers
+const testServerBanner = "authorized use only\n"
+
+// listenTestSSHServer starts an SSH server on a loopback port and hands each
+// connection to serve, with the server configuration to handshake with.
+// serverConfig sets how clients authenticate; nil accepts logins without
+// authentication. It returns the address; the server stops when the test
+// ends.
+func listenTestSSHServer(t *testing.T, serverConfig *ssh.ServerConfig, serve func(net.Conn, *ssh.ServerConfig)) string {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	require.NoError(t, err)
+	if serverConfig == nil {
+		serverConfig = &ssh.ServerConfig{
+			NoClientAuth:   true,
+			BannerCallback: func(ssh.ConnMetadata) string { return testServerBanner },
+		}
+	}
+	serverConfig.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn, serverConfig)
+		}
+	}()
+	return listener.Addr().String()
+}
+
 // MockSSHClient is a mock implementation of ssh.Client for testing
 type MockSSHClient struct {
 	ShouldFail      bool
@@ -0,0 +1,169 @@
+package sshclient
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// DefaultMaxOutputBytes caps the output captured by ExecuteCommandWithOutput
+// when Config.MaxOutputBytes is zero
+const DefaultMaxOutputBytes int64 = 1024 * 1024
+
+// outputBuffer captures command output up to a limit, keeping the first and
+// last halves so both the start of the output and the final lines (usually
+// the error) survive truncation. Memory use is bounded by the limit. It is
+// safe for concurrent writers, so stdout and stderr may share one buffer.
+type outputBuffer struct {
+	mu    sync.Mutex
+	limit int64
+	head  []byte
+	tail  []byte // ring buffer holding the most recent bytes
+	next  int    // write position in tail once it is full
+	total int64
+}
+
+// newOutputBuffer returns a buffer keeping at most limit bytes; limit <= 0
+// keeps everything
+func newOutputBuffer(limit int64) *outputBuffer {
+	return &outputBuffer{limit: limit}
+}
+
+// Write implements io.Writer
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.total += int64(len(p))
+	if b.limit <= 0 {
+		b.head = append(b.head, p...)
+		return len(p), nil
+	}
+
+	headLimit := int(b.limit / 2)
+	tailLimit := int(b.limit) - headLimit
+	data := p
+	if room := headLimit - len(b.head); room > 0 {
+		n := min(room, len(data))
+		b.head = append(b.head, data[:n]...)
+		data = data[n:]
+	}
+
+	for len(data) > 0 {
+		if len(b.tail) < tailLimit {
+			n := min(tailLimit-len(b.tail), len(data))
+			b.tail = append(b.tail, data[:n]...)
+			data = data[n:]
+			continue
+		}
+		if len(data) >= tailLimit {
+			copy(b.tail, data[len(data)-tailLimit:])
+			b.next = 0
+			break
+		}
+		n := copy(b.tail[b.next:], data)
+		b.next = (b.next + n) % tailLimit
+		data = data[n:]
+	}
+	return len(p), nil
+}
+
+// Truncated reports whether output was dropped
+func (b *outputBuffer) Truncated() bool {
+	return b.limit > 0 && b.total > b.limit
+}
+
+// String returns the captured output, with a marker where bytes were dropped
+func (b *outputBuffer) String(spillPath string) string {
+	if !b.Truncated() {
+		return string(b.head) + string(b.tail)
+	}
+
+	tail := append(append([]byte{}, b.tail[b.next:]...), b.tail[:b.next]...)
+	marker := fmt.Sprintf("\n... [output truncated: %d of %d bytes omitted", b.total-b.limit, b.total)
+	if spillPath != "" {
+		marker += "; full output saved to " + spillPath
+	}
+	return string(b.head) + marker + "] ...\n" + string(tail)
+}
+
+// outputCapture holds the capped stdout/stderr buffers of one command and the
+// optional spill file receiving the complete output
+type outputCapture struct {
+	stdout    *outputBuffer
+	stderr    *outputBuffer
+	spillPath string
+	spill     *os.File
+}
+
+// newOutputCapture prepares capped buffers for the config's output limit. When
+// OutputSpillPath is set the full output is also written there; the file is
+// removed again if nothing was truncated.
+func newOutputCapture(config *Config) *outputCapture {
+	limit := config.MaxOutputBytes
+	if limit == 0 {
+		limit = DefaultMaxOutputBytes
+	}
+	capture := &outputCapture{stdout: newOutputBuffer(limit), stderr: newOutputBuffer(limit)}
+
+	if config.OutputSpillPath != "" && limit > 0 {
+		if err := os.MkdirAll(filepath.Dir(config.OutputSpillPath), 0o700); err != nil {
+			logger.GetLogger().Warning("failed to create output directory: %v", err)
+			return capture
+		}
+		file, err := os.OpenFile(config.OutputSpillPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) // #nosec G304 -- path chosen by the caller
+		if err != nil {
+			logger.GetLogger().Warning("failed to create output file %s: %v", config.OutputSpillPath, err)
+			return capture
+		}
+		capture.spill = file
+		capture.spillPath = config.OutputSpillPath
+	}
+	return capture
+}
+
+// writers returns the stdout and stderr writers for a session
+func (o *outputCapture) writers() (io.Writer, io.Writer) {
+	if o.spill == nil {
+		return o.stdout, o.stderr
+	}
+	return io.MultiWriter(o.stdout, o.spill), io.MultiWriter(o.stderr, o.spill)
+}
+
+// finish closes the spill file, keeping it only when output was truncated,
+// and returns the captured stdout and stderr
+func (o *outputCapture) finish() (stdout, stderr string) {
+	spillPath := ""
+	if o.spill != nil {
+		if err := o.spill.Close(); err != nil {
+			logger.GetLogger().Warning("failed to close output file %s: %v", o.spillPath, err)
+		}
+		if o.truncated() {
+			spillPath = o.spillPath
+		} else if err := os.Remove(o.spillPath); err != nil {
+			logger.GetLogger().Warning("failed to remove output file %s: %v", o.spillPath, err)
+		}
+	}
+	return o.stdout.String(spillPath), o.stderr.String(spillPath)
+}
+
+// truncated reports whether either stream was cut
+func (o *outputCapture) truncated() bool {
+	return o.stdout.Truncated() || o.stderr.Truncated()
+}
+
+// CapOutput keeps output within limit the way the output of remote commands
+// is kept, first and last halves around a marker; limit 0 uses
+// DefaultMaxOutputBytes and a negative limit keeps everything
+func CapOutput(output []byte, limit int64) (string, bool) {
+	if limit == 0 {
+		limit = DefaultMaxOutputBytes
+	}
+	buffer := newOutputBuffer(limit)
+	_, _ = buffer.Write(output) //nolint:errcheck // never fails
+	return buffer.String(""), buffer.Truncated()
+}
@@ -0,0 +1,88 @@
+package sshclient
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputBuffer_UnderLimit(t *testing.T) {
+	buf := newOutputBuffer(16)
+	_, _ = buf.Write([]byte("hello "))
+	_, _ = buf.Write([]byte("world"))
+
+	assert.False(t, buf.Truncated())
+	assert.Equal(t, "hello world", buf.String(""))
+}
+
+func TestOutputBuffer_KeepsHeadAndTail(t *testing.T) {
+	buf := newOutputBuffer(10)
+	for _, chunk := range []string{"abc", "defgh", "ijklmnop", "qr", "stuvwxyz"} {
+		n, err := buf.Write([]byte(chunk))
+		require.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+
+	assert.True(t, buf.Truncated())
+	output := buf.String("")
+	assert.True(t, strings.HasPrefix(output, "abcde\n... [output truncated: 16 of 26 bytes omitted] ...\n"), output)
+	assert.True(t, strings.HasSuffix(output, "vwxyz"), output)
+}
+
+func TestOutputBuffer_LargeSingleWrite(t *testing.T) {
+	buf := newOutputBuffer(4)
+	_, _ = buf.Write([]byte("0123456789"))
+
+	assert.Equal(t, "01\n... [output truncated: 6 of 10 bytes omitted] ...\n89", buf.String(""))
+}
+
+func TestOutputBuffer_Unlimited(t *testing.T) {
+	buf := newOutputBuffer(-1)
+	_, _ = buf.Write([]byte(strings.Repeat("x", 100)))
+
+	assert.False(t, buf.Truncated())
+	assert.Len(t, buf.String(""), 100)
+}
+
+func TestOutputCapture_SpillFile(t *testing.T) {
+	spill := filepath.Join(t.TempDir(), "out", "full.log")
+
+	capture := newOutputCapture(&Config{MaxOutputBytes: 4, OutputSpillPath: spill})
+	stdout, _ := capture.writers()
+	_, _ = stdout.Write([]byte("0123456789"))
+	output, _ := capture.finish()
+
+	assert.Contains(t, output, "full output saved to "+spill)
+	data, err := os.ReadFile(spill) // #nosec G304 -- test temp file
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+
+	capture = newOutputCapture(&Config{MaxOutputBytes: 64, OutputSpillPath: spill})
+	stdout, _ = capture.writers()
+	_, _ = stdout.Write([]byte("short"))
+	output, _ = capture.finish()
+
+	assert.Equal(t, "short", output)
+	_, err = os.Stat(spill)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBinaryResult(t *testing.T) {
+	payload := string([]byte{0x1f, 0x8b, 0x00, 0xff, '\n', '\r'})
+	client := &operation{config: &Config{BinaryOutput: true}}
+
+	output, err := client.binaryResult(payload, "tar: warning", nil)
+	require.NoError(t, err)
+	assert.Equal(t, payload, output)
+	assert.Equal(t, "tar: warning", client.stderr)
+
+	client.outputTruncated = true
+	client.config.MaxOutputBytes = 1024
+	client.config.OutputSpillPath = "/tmp/out.bin"
+	_, err = client.binaryResult(payload, "", nil)
+	assert.ErrorContains(t, err, "exceeds the output limit of 1024 bytes; full output saved to /tmp/out.bin")
+}
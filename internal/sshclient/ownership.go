@@ -0,0 +1,155 @@
+package sshclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/talkincode/sshmcp/pkg/errutil"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// FileOwnership is the mode, owner and group uploads give the files they
+// write, so artifacts do not end up owned by the SSH user
+type FileOwnership struct {
+	// Mode is the permission bits (0 keeps those of the replaced file, or
+	// the server's default for a new one)
+	Mode os.FileMode
+	// Owner is a user name or uid ("" keeps the owner)
+	Owner string
+	// Group is a group name or gid ("" keeps the group)
+	Group string
+}
+
+// ParseFileMode parses permission bits in octal, e.g. "0640" or "755"
+func ParseFileMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(strings.TrimSpace(s), 8, 32)
+	if err != nil || mode == 0 || mode > 0o7777 {
+		return 0, fmt.Errorf("invalid file mode '%s' (use octal permissions such as 0640)", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// Validate rejects owners and groups chown cannot take
+func (o FileOwnership) Validate() error {
+	for _, name := range []string{o.Owner, o.Group} {
+		if strings.ContainsAny(name, ": \t\n") || strings.HasPrefix(name, "-") {
+			return fmt.Errorf("invalid owner or group '%s'", name)
+		}
+	}
+	if o.Mode > 0o7777 {
+		return fmt.Errorf("invalid file mode %#o", o.Mode)
+	}
+	return nil
+}
+
+// changesOwner reports whether the owner or group is set
+func (o FileOwnership) changesOwner() bool {
+	return o.Owner != "" || o.Group != ""
+}
+
+// String describes the ownership for logs, e.g. "www-data:www-data 0640"
+func (o FileOwnership) String() string {
+	var parts []string
+	if o.changesOwner() {
+		owner := o.Owner
+		if o.Group != "" {
+			owner += ":" + o.Group
+		}
+		parts = append(parts, owner)
+	}
+	if o.Mode != 0 {
+		parts = append(parts, fmt.Sprintf("%04o", o.Mode))
+	}
+	return strings.Join(parts, " ")
+}
+
+// applyOwnership gives the uploaded remotePath the owner and group of
+// config.UploadOwnership. Numeric ids are set over SFTP, which works when
+// the SSH user is root; names, and ids the server refuses, go through
+// chown, run with sudo (the password from config) unless the user is root.
+func applyOwnership(client *ssh.Client, sftpClient *sftp.Client, config *Config, remotePath string) (err error) {
+	ownership := config.UploadOwnership
+	if !ownership.changesOwner() {
+		return nil
+	}
+	if uid, gid, ok := numericOwnership(sftpClient, ownership, remotePath); ok {
+		chownErr := sftpClient.Chown(remotePath, uid, gid)
+		if chownErr == nil {
+			return nil
+		}
+		logger.GetLogger().Debug("SFTP chown of %s failed, using chown: %v", remotePath, chownErr)
+	}
+	if client == nil {
+		return fmt.Errorf("failed to change the owner of %s to %s: no connection for chown", remotePath, ownership)
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer CloseIgnore(&err, session, io.EOF)
+	command, stdin := chownCommand(config, ownership, remotePath)
+	var stderr bytes.Buffer
+	session.Stdin = strings.NewReader(stdin)
+	session.Stderr = &stderr
+	if err = session.Run(command); err != nil {
+		if enhancedErr := errutil.EnhanceError(err, "", stderr.String()); enhancedErr != nil {
+			err = enhancedErr
+		}
+		return fmt.Errorf("failed to change the owner of %s to %s: %w", remotePath, ownership, err)
+	}
+	return nil
+}
+
+// numericOwnership returns the uid and gid to set over SFTP when the owner
+// and group are ids, taking those of remotePath for the one not given
+func numericOwnership(sftpClient *sftp.Client, ownership FileOwnership, remotePath string) (uid, gid int, ok bool) {
+	uid, uidErr := strconv.Atoi(ownership.Owner)
+	gid, gidErr := strconv.Atoi(ownership.Group)
+	if (ownership.Owner != "" && uidErr != nil) || (ownership.Group != "" && gidErr != nil) {
+		return 0, 0, false
+	}
+	if ownership.Owner == "" || ownership.Group == "" {
+		info, err := sftpClient.Stat(remotePath)
+		if err != nil {
+			return 0, 0, false
+		}
+		stat, isStat := info.Sys().(*sftp.FileStat)
+		if !isStat {
+			return 0, 0, false
+		}
+		if ownership.Owner == "" {
+			uid = int(stat.UID)
+		}
+		if ownership.Group == "" {
+			gid = int(stat.GID)
+		}
+	}
+	return uid, gid, true
+}
+
+// chownCommand returns the command changing the owner of remotePath and its
+// stdin: root runs chown itself, other users through sudo, reading the
+// password from stdin or failing instead of prompting without one
+func chownCommand(config *Config, ownership FileOwnership, remotePath string) (command, stdin string) {
+	owner := ownership.Owner
+	if ownership.Group != "" {
+		owner += ":" + ownership.Group
+	}
+	command = BuildCommand("chown", "--", owner, remotePath)
+	switch {
+	case config.User == "root":
+		return command, ""
+	case config.Password != "":
+		return "sudo -S -p '' " + command, config.Password + "\n"
+	default:
+		return "sudo -n " + command, ""
+	}
+}
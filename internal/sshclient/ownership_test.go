@@ -0,0 +1,73 @@
+package sshclient
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFileMode(t *testing.T) {
+	for text, want := range map[string]os.FileMode{"0640": 0o640, "755": 0o755, " 2775 ": 0o2775} {
+		mode, err := ParseFileMode(text)
+		require.NoError(t, err, text)
+		assert.Equal(t, want, mode, text)
+	}
+	for _, text := range []string{"", "0", "rw-r--r--", "0888", "17777"} {
+		_, err := ParseFileMode(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestFileOwnership(t *testing.T) {
+	assert.NoError(t, FileOwnership{Mode: 0o640, Owner: "www-data", Group: "1001"}.Validate())
+	assert.Error(t, FileOwnership{Owner: "www-data:www-data"}.Validate())
+	assert.Error(t, FileOwnership{Group: "-R"}.Validate())
+
+	assert.Equal(t, "www-data:deploy 0640", FileOwnership{Mode: 0o640, Owner: "www-data", Group: "deploy"}.String())
+	assert.Equal(t, ":deploy", FileOwnership{Group: "deploy"}.String())
+}
+
+func TestChownCommand(t *testing.T) {
+	ownership := FileOwnership{Owner: "www-data", Group: "www-data"}
+	command, stdin := chownCommand(&Config{User: "root"}, ownership, "/var/www/app/index.html")
+	assert.Equal(t, "chown -- www-data:www-data /var/www/app/index.html", command)
+	assert.Empty(t, stdin)
+
+	command, stdin = chownCommand(&Config{User: "deploy", Password: "s3cret"}, FileOwnership{Group: "app"}, "/srv/app/release 1.tar")
+	assert.Equal(t, "sudo -S -p '' chown -- :app '/srv/app/release 1.tar'", command)
+	assert.Equal(t, "s3cret\n", stdin)
+
+	command, _ = chownCommand(&Config{User: "deploy"}, ownership, "/srv/app.jar")
+	assert.Equal(t, "sudo -n chown -- www-data:www-data /srv/app.jar", command)
+}
+
+func TestUploadOwnership(t *testing.T) {
+	client := startLocalSFTP(t)
+	path := filepath.Join(t.TempDir(), "app.conf")
+
+	_, err := writeRemoteFile(client, path, []byte("port=80\n"), false, 0o640)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	require.NoError(t, os.Chmod(path, 0o600))
+	_, err = writeRemoteFile(client, path, []byte("port=8080\n"), false, 0o644)
+	require.NoError(t, err)
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm(), "the configured mode wins over the replaced file's")
+
+	// Numeric ids go over SFTP, which may set the ids a file already has
+	config := &Config{UploadOwnership: FileOwnership{Group: strconv.Itoa(os.Getgid())}}
+	assert.NoError(t, applyOwnership(nil, client, config, path))
+	assert.NoError(t, applyOwnership(nil, client, &Config{}, path), "nothing to change")
+
+	// Names need chown on the host
+	config.UploadOwnership = FileOwnership{Owner: "www-data"}
+	assert.ErrorContains(t, applyOwnership(nil, client, config, path), "no connection for chown")
+}
@@ -0,0 +1,280 @@
+package sshclient
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Package managers PackageCommand generates commands for
+const (
+	PackageManagerApt    = "apt"
+	PackageManagerDnf    = "dnf"
+	PackageManagerYum    = "yum"
+	PackageManagerZypper = "zypper"
+	PackageManagerApk    = "apk"
+)
+
+// PackageManagers lists the supported package managers in the order
+// DetectPackageManagerCommand prefers them
+var PackageManagers = []string{PackageManagerApt, PackageManagerDnf, PackageManagerYum, PackageManagerZypper, PackageManagerApk}
+
+// Package actions
+const (
+	PackageInstall = "install"
+	PackageRemove  = "remove"
+	PackageUpdate  = "update"
+	PackageList    = "list"
+)
+
+// PackageActions lists the actions PackageCommand understands
+var PackageActions = []string{PackageInstall, PackageRemove, PackageUpdate, PackageList}
+
+// ProtectedPackages are the packages ManagePackages refuses to remove by
+// default: losing them cuts off SSH access, privilege escalation, the init
+// system, the kernel or the package manager itself. Entries may be
+// path.Match patterns.
+var ProtectedPackages = []string{
+	"openssh-server", "openssh", "ssh", "sudo", "systemd", "bash", "coreutils",
+	"libc6", "glibc", "musl", "kernel", "kernel-core", "linux-image-*", "linux-generic",
+	"apt", "dpkg", "dnf", "yum", "rpm", "zypper", "apk-tools",
+}
+
+// packageNamePattern matches package names with an optional version pin
+// (nginx, libssl3, python3.11, nginx=1.24.0-1, nginx-1.24.0): no options,
+// globs or shell syntax
+var packageNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9+._:~=-]*$`)
+
+// ValidatePackageNames rejects names that are not plain package names
+func ValidatePackageNames(packages []string) error {
+	for _, name := range packages {
+		if !packageNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid package name '%s'", name)
+		}
+	}
+	return nil
+}
+
+// ProtectedPackage returns the entry of protected (ProtectedPackages when
+// nil) that name matches, ignoring a version pin, or "" when none does
+func ProtectedPackage(name string, protected []string) string {
+	if protected == nil {
+		protected = ProtectedPackages
+	}
+	base, _, _ := strings.Cut(name, "=")
+	for _, pattern := range protected {
+		if matched, err := path.Match(pattern, base); err == nil && matched {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// DetectPackageManagerCommand prints the first package manager of
+// PackageManagers found on the host, or nothing when none is
+func DetectPackageManagerCommand() string {
+	return `for m in apt-get dnf yum zypper apk; do if command -v "$m" >/dev/null 2>&1; then echo "$m"; break; fi; done`
+}
+
+// ParsePackageManager reads the output of DetectPackageManagerCommand
+func ParsePackageManager(output string) (string, error) {
+	found := strings.TrimSpace(output)
+	if found == "apt-get" {
+		return PackageManagerApt, nil
+	}
+	for _, manager := range PackageManagers {
+		if found == manager {
+			return manager, nil
+		}
+	}
+	return "", fmt.Errorf("no supported package manager found (%s)", strings.Join(PackageManagers, ", "))
+}
+
+// PackageCommand builds the command running action with manager. With
+// dryRun, install, remove and update only simulate the transaction using the
+// manager's own dry-run mode and report what would change. Update without
+// packages upgrades everything; list without packages lists every installed
+// package. The commands need root for real changes (run them with RunAs).
+func PackageCommand(manager, action string, packages []string, dryRun bool) (string, error) {
+	if err := ValidatePackageNames(packages); err != nil {
+		return "", err
+	}
+	if (action == PackageInstall || action == PackageRemove) && len(packages) == 0 {
+		return "", fmt.Errorf("%s needs at least one package", action)
+	}
+	names := strings.Join(packages, " ")
+	withNames := func(command string) string {
+		if names == "" {
+			return command
+		}
+		return command + " " + names
+	}
+
+	switch manager {
+	case PackageManagerApt:
+		apt := "DEBIAN_FRONTEND=noninteractive apt-get -y"
+		if dryRun {
+			apt += " -s"
+		}
+		switch action {
+		case PackageInstall:
+			return withNames(apt + " install"), nil
+		case PackageRemove:
+			return withNames(apt + " remove"), nil
+		case PackageUpdate:
+			upgrade := apt + " upgrade"
+			if names != "" {
+				upgrade = withNames(apt + " install --only-upgrade")
+			}
+			if dryRun {
+				return upgrade, nil
+			}
+			return "apt-get update && " + upgrade, nil
+		case PackageList:
+			return withNames(`dpkg-query -W -f='${Package} ${Version}\n'`), nil
+		}
+	case PackageManagerDnf, PackageManagerYum:
+		verbs := map[string]string{PackageInstall: "install", PackageRemove: "remove", PackageUpdate: "upgrade"}
+		if manager == PackageManagerYum {
+			verbs[PackageUpdate] = "update"
+		}
+		if action == PackageList {
+			return rpmListCommand(names), nil
+		}
+		verb, ok := verbs[action]
+		if !ok {
+			break
+		}
+		if dryRun {
+			// --assumeno prints the transaction and exits 1 without changing anything
+			return withNames(manager+" --assumeno "+verb) + " || [ $? -eq 1 ]", nil
+		}
+		return withNames(manager + " -y " + verb), nil
+	case PackageManagerZypper:
+		verbs := map[string]string{PackageInstall: "install", PackageRemove: "remove", PackageUpdate: "update"}
+		if action == PackageList {
+			return rpmListCommand(names), nil
+		}
+		verb, ok := verbs[action]
+		if !ok {
+			break
+		}
+		command := "zypper --non-interactive " + verb
+		if dryRun {
+			command += " --dry-run"
+		}
+		return withNames(command), nil
+	case PackageManagerApk:
+		simulate := ""
+		if dryRun {
+			simulate = " --simulate"
+		}
+		switch action {
+		case PackageInstall:
+			return withNames("apk add" + simulate), nil
+		case PackageRemove:
+			return withNames("apk del" + simulate), nil
+		case PackageUpdate:
+			if dryRun {
+				return withNames("apk upgrade" + simulate), nil
+			}
+			return withNames("apk update && apk upgrade"), nil
+		case PackageList:
+			return withNames("apk list --installed"), nil
+		}
+	default:
+		return "", fmt.Errorf("unsupported package manager '%s' (use %s)", manager, strings.Join(PackageManagers, ", "))
+	}
+	return "", fmt.Errorf("unknown package action '%s' (use %s)", action, strings.Join(PackageActions, ", "))
+}
+
+// rpmListCommand lists installed rpm packages, all or only names
+func rpmListCommand(names string) string {
+	if names == "" {
+		return `rpm -qa --qf '%{NAME} %{VERSION}-%{RELEASE}\n'`
+	}
+	return `rpm -q --qf '%{NAME} %{VERSION}-%{RELEASE}\n' ` + names
+}
+
+// PackageOptions describes a package_manage request
+type PackageOptions struct {
+	Manager  string // One of PackageManagers (empty = detect on the host)
+	Action   string // One of PackageActions
+	Packages []string
+	// DryRun simulates install, remove and update without changing anything
+	DryRun bool
+	// Protected are the packages Action remove refuses (nil = ProtectedPackages)
+	Protected []string
+}
+
+// PackageResult is the outcome of ManagePackages
+type PackageResult struct {
+	Manager  string   `json:"manager"`
+	Action   string   `json:"action"`
+	Packages []string `json:"packages,omitempty"`
+	DryRun   bool     `json:"dry_run"`
+	Command  string   `json:"command"`
+	Output   string   `json:"output"`
+}
+
+// Validate checks the action and package names and refuses to remove
+// protected packages
+func (o PackageOptions) Validate() error {
+	if !slices.Contains(PackageActions, o.Action) {
+		return fmt.Errorf("unknown package action '%s' (use %s)", o.Action, strings.Join(PackageActions, ", "))
+	}
+	if err := ValidatePackageNames(o.Packages); err != nil {
+		return err
+	}
+	if o.Action == PackageRemove {
+		for _, name := range o.Packages {
+			if pattern := ProtectedPackage(name, o.Protected); pattern != "" {
+				return fmt.Errorf("package '%s' is protected (%s) and cannot be removed", name, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// ManagePackages runs a package action with the host's package manager,
+// detecting it first unless opts.Manager is set. Detection and the action
+// run through Execute, so they are validated and audited like any other
+// command. The result carries the command that ran, also on failure.
+func (c *SSHClient) ManagePackages(opts PackageOptions, req Request) (*PackageResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if isPowerShell(c.config.Shell) {
+		return nil, fmt.Errorf("package management is not supported with shell %s", c.config.Shell)
+	}
+
+	manager := opts.Manager
+	if manager == "" {
+		detect := req
+		detect.Command = DetectPackageManagerCommand()
+		result, err := c.Execute(detect)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect the package manager: %w", err)
+		}
+		if manager, err = ParsePackageManager(result.Output); err != nil {
+			return nil, err
+		}
+	}
+	command, err := PackageCommand(manager, opts.Action, opts.Packages, opts.DryRun && opts.Action != PackageList)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Command = command
+	result, err := c.Execute(req)
+	return &PackageResult{
+		Manager:  manager,
+		Action:   opts.Action,
+		Packages: opts.Packages,
+		DryRun:   opts.DryRun && opts.Action != PackageList,
+		Command:  command,
+		Output:   result.Output,
+	}, err
+}
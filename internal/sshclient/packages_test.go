@@ -0,0 +1,101 @@
+package sshclient
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPackageCommand(t *testing.T) {
+	tests := []struct {
+		manager, action string
+		packages        []string
+		dryRun          bool
+		want            string
+	}{
+		{PackageManagerApt, PackageInstall, []string{"nginx", "curl"}, true, "DEBIAN_FRONTEND=noninteractive apt-get -y -s install nginx curl"},
+		{PackageManagerApt, PackageRemove, []string{"nginx"}, false, "DEBIAN_FRONTEND=noninteractive apt-get -y remove nginx"},
+		{PackageManagerApt, PackageUpdate, nil, false, "apt-get update && DEBIAN_FRONTEND=noninteractive apt-get -y upgrade"},
+		{PackageManagerApt, PackageUpdate, []string{"nginx"}, true, "DEBIAN_FRONTEND=noninteractive apt-get -y -s install --only-upgrade nginx"},
+		{PackageManagerApt, PackageList, nil, false, `dpkg-query -W -f='${Package} ${Version}\n'`},
+		{PackageManagerDnf, PackageInstall, []string{"nginx"}, true, "dnf --assumeno install nginx || [ $? -eq 1 ]"},
+		{PackageManagerDnf, PackageUpdate, nil, false, "dnf -y upgrade"},
+		{PackageManagerYum, PackageUpdate, []string{"nginx"}, false, "yum -y update nginx"},
+		{PackageManagerYum, PackageList, []string{"nginx"}, false, `rpm -q --qf '%{NAME} %{VERSION}-%{RELEASE}\n' nginx`},
+		{PackageManagerZypper, PackageRemove, []string{"nginx"}, true, "zypper --non-interactive remove --dry-run nginx"},
+		{PackageManagerApk, PackageInstall, []string{"nginx"}, true, "apk add --simulate nginx"},
+		{PackageManagerApk, PackageUpdate, nil, false, "apk update && apk upgrade"},
+	}
+	for _, tt := range tests {
+		got, err := PackageCommand(tt.manager, tt.action, tt.packages, tt.dryRun)
+		require.NoError(t, err, tt.want)
+		assert.Equal(t, tt.want, got)
+		assert.False(t, Explain(got, nil).Flagged, "%s must pass the safety check", got)
+	}
+
+	_, err := PackageCommand(PackageManagerApt, PackageInstall, nil, true)
+	assert.ErrorContains(t, err, "needs at least one package")
+	_, err = PackageCommand("pacman", PackageInstall, []string{"nginx"}, true)
+	assert.ErrorContains(t, err, "unsupported package manager")
+	for _, name := range []string{"-y", "nginx;reboot", "ngin*", "$(id)", ""} {
+		_, err = PackageCommand(PackageManagerApt, PackageInstall, []string{name}, true)
+		assert.ErrorContains(t, err, "invalid package name", name)
+	}
+}
+
+func TestPackageOptions_Validate(t *testing.T) {
+	assert.NoError(t, PackageOptions{Action: PackageRemove, Packages: []string{"nginx"}}.Validate())
+	assert.NoError(t, PackageOptions{Action: PackageInstall, Packages: []string{"openssh-server"}}.Validate())
+	assert.ErrorContains(t, PackageOptions{Action: PackageRemove, Packages: []string{"linux-image-6.1.0-18-amd64"}}.Validate(), "(linux-image-*)")
+	assert.ErrorContains(t, PackageOptions{Action: PackageRemove, Packages: []string{"sudo=1.9.13"}}.Validate(), "'sudo=1.9.13' is protected")
+	assert.NoError(t, PackageOptions{Action: PackageRemove, Packages: []string{"sudo"}, Protected: []string{}}.Validate())
+	assert.ErrorContains(t, PackageOptions{Action: "purge"}.Validate(), "unknown package action")
+}
+
+func TestParsePackageManager(t *testing.T) {
+	manager, err := ParsePackageManager("apt-get\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, PackageManagerApt, manager)
+	manager, err = ParsePackageManager("zypper\n")
+	require.NoError(t, err)
+	assert.Equal(t, PackageManagerZypper, manager)
+	_, err = ParsePackageManager("")
+	assert.ErrorContains(t, err, "no supported package manager")
+}
+
+func TestSSHClient_ManagePackages(t *testing.T) {
+	var ran []string
+	conn := startExecServer(t, func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		ran = append(ran, command)
+		if strings.Contains(command, "command -v") {
+			_, _ = channel.Write([]byte("dnf\n"))
+			return 0
+		}
+		_, _ = channel.Write([]byte("Installing: nginx\nOperation aborted.\n"))
+		if strings.Contains(command, "--assumeno") {
+			// The shell turns the expected exit status 1 into success
+			return 0
+		}
+		return 1
+	})
+	client := &SSHClient{config: &Config{Host: "10.0.0.5"}, client: conn}
+
+	result, err := client.ManagePackages(PackageOptions{Action: PackageInstall, Packages: []string{"nginx"}, DryRun: true}, Request{})
+	require.NoError(t, err)
+	assert.Equal(t, PackageManagerDnf, result.Manager)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, "dnf --assumeno install nginx || [ $? -eq 1 ]", result.Command)
+	assert.Contains(t, result.Output, "Installing: nginx")
+	require.Len(t, ran, 2)
+
+	// An explicit manager skips detection; failures keep the command
+	ran = nil
+	result, err = client.ManagePackages(PackageOptions{Manager: PackageManagerYum, Action: PackageInstall, Packages: []string{"nginx"}}, Request{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Operation aborted")
+	assert.Equal(t, "yum -y install nginx", result.Command)
+	assert.Len(t, ran, 1)
+}
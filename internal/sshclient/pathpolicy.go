@@ -0,0 +1,107 @@
+package sshclient
+
+import (
+	"fmt"
+	"path"
+)
+
+// PathPolicy restricts the remote paths SFTP operations may use. Entries
+// are path prefixes matched on whole components, so /opt/app covers
+// /opt/app/current but not /opt/application.
+type PathPolicy struct {
+	// Read limits downloads and listings to these prefixes (empty = anywhere)
+	Read []string
+	// Write limits uploads, mkdir and removals to these prefixes (empty =
+	// anywhere)
+	Write []string
+	// Deny excludes these prefixes from every SFTP operation
+	Deny []string
+}
+
+// PathDeniedError is returned when the PathPolicy of a host forbids an SFTP
+// operation
+type PathDeniedError struct {
+	Path   string
+	Write  bool
+	Reason string
+}
+
+func (e *PathDeniedError) Error() string {
+	access := "read"
+	if e.Write {
+		access = "write"
+	}
+	return fmt.Sprintf("%s access to %s denied by SFTP path policy: %s", access, e.Path, e.Reason)
+}
+
+// Check reports whether the policy allows reading or writing remotePath,
+// an absolute and cleaned path
+func (p *PathPolicy) Check(remotePath string, write bool) error {
+	if p == nil {
+		return nil
+	}
+	for _, prefix := range p.Deny {
+		if isWithin(remotePath, path.Clean(prefix)) {
+			return &PathDeniedError{Path: remotePath, Write: write, Reason: "under denied prefix " + prefix}
+		}
+	}
+	allowed := p.Read
+	if write {
+		allowed = p.Write
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, prefix := range allowed {
+		if isWithin(remotePath, path.Clean(prefix)) {
+			return nil
+		}
+	}
+	return &PathDeniedError{Path: remotePath, Write: write, Reason: "outside the allowed prefixes"}
+}
+
+// pathResolver resolves remote paths; *sftp.Client implements it
+type pathResolver interface {
+	Getwd() (string, error)
+	RealPath(path string) (string, error)
+}
+
+// checkRemotePath applies policy to remotePath both as given, made absolute
+// against the remote working directory, and with symlinks resolved by the
+// server, so neither ".." nor a symlink leads out of an allowed prefix. A
+// path that does not exist yet is resolved through its parent directory.
+func checkRemotePath(resolver pathResolver, policy *PathPolicy, remotePath string, write bool) error {
+	if policy == nil {
+		return nil
+	}
+	absolute := remotePath
+	if !path.IsAbs(absolute) {
+		home, err := resolver.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", remotePath, err)
+		}
+		absolute = path.Join(home, absolute)
+	}
+	absolute = path.Clean(absolute)
+	if err := policy.Check(absolute, write); err != nil {
+		return err
+	}
+
+	resolved, err := resolver.RealPath(absolute)
+	if err != nil {
+		parent, parentErr := resolver.RealPath(path.Dir(absolute))
+		if parentErr != nil {
+			return nil
+		}
+		resolved = path.Join(parent, path.Base(absolute))
+	}
+	if resolved == absolute {
+		return nil
+	}
+	return policy.Check(path.Clean(resolved), write)
+}
+
+// checkPath applies the path policy of the operation's host
+func (c *operation) checkPath(remotePath string, write bool) error {
+	return checkRemotePath(c.sftpClient, c.config.PathPolicy, remotePath, write)
+}
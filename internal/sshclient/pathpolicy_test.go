@@ -0,0 +1,70 @@
+package sshclient
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver resolves paths through a table of symlinked directories
+type fakeResolver struct {
+	home  string
+	links map[string]string
+}
+
+func (r fakeResolver) Getwd() (string, error) { return r.home, nil }
+
+func (r fakeResolver) RealPath(p string) (string, error) {
+	if target, ok := r.links[p]; ok {
+		return target, nil
+	}
+	if p == "/opt/app/missing" || p == "/tmp/new/file" {
+		return "", os.ErrNotExist
+	}
+	return p, nil
+}
+
+func TestPathPolicyCheck(t *testing.T) {
+	policy := &PathPolicy{Write: []string{"/opt/app", "/tmp/"}, Deny: []string{"/etc/ssh"}}
+
+	assert.NoError(t, policy.Check("/opt/app/config.yml", true))
+	assert.NoError(t, policy.Check("/opt/app", true))
+	assert.NoError(t, policy.Check("/tmp/upload.bin", true))
+	assert.NoError(t, policy.Check("/etc/hosts", false), "reads are unrestricted without read prefixes")
+
+	var denied *PathDeniedError
+	err := policy.Check("/opt/application/run.sh", true)
+	require.True(t, errors.As(err, &denied))
+	assert.True(t, denied.Write)
+	assert.EqualError(t, err, "write access to /opt/application/run.sh denied by SFTP path policy: outside the allowed prefixes")
+
+	assert.ErrorContains(t, policy.Check("/etc/ssh/sshd_config", false), "under denied prefix /etc/ssh")
+	assert.Error(t, policy.Check("/etc/ssh", false))
+
+	var none *PathPolicy
+	assert.NoError(t, none.Check("/etc/shadow", true))
+}
+
+func TestCheckRemotePath(t *testing.T) {
+	resolver := fakeResolver{
+		home: "/home/deploy",
+		links: map[string]string{
+			"/opt/app/logs":     "/var/log/app",
+			"/opt/app/etc-link": "/etc/ssh",
+		},
+	}
+	policy := &PathPolicy{Write: []string{"/opt/app", "/home/deploy"}, Deny: []string{"/etc/ssh"}}
+
+	assert.NoError(t, checkRemotePath(resolver, policy, "/opt/app/config.yml", true))
+	assert.NoError(t, checkRemotePath(resolver, policy, "notes.txt", true), "relative paths resolve against the home directory")
+	assert.NoError(t, checkRemotePath(resolver, policy, "/opt/app/missing", true), "new files resolve through their parent")
+	assert.NoError(t, checkRemotePath(resolver, nil, "/etc/passwd", true))
+
+	assert.Error(t, checkRemotePath(resolver, policy, "/opt/app/../../etc/passwd", true), "'..' is cleaned before the check")
+	assert.Error(t, checkRemotePath(resolver, policy, "/opt/app/logs", true), "symlinks out of an allowed prefix are denied")
+	assert.Error(t, checkRemotePath(resolver, policy, "/opt/app/etc-link", false), "symlinks into a denied prefix are denied")
+	assert.Error(t, checkRemotePath(resolver, policy, "/tmp/new/file", true))
+}
@@ -0,0 +1,136 @@
+package sshclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// DefaultPingTimeout bounds each reachability probe
+const DefaultPingTimeout = 2 * time.Second
+
+// PingOptions controls a reachability check
+type PingOptions struct {
+	Timeout     time.Duration // Per-probe timeout (default: DefaultPingTimeout)
+	ICMP        bool          // Also run the system ping command
+	Concurrency int           // Hosts probed in parallel (default: DefaultFleetConcurrency)
+	// Progress, when set, is called as each host finishes
+	Progress ProgressFunc
+}
+
+// PingResult describes the reachability of one host
+type PingResult struct {
+	Address     string        // host:port probed
+	TCPLatency  time.Duration // Time to open a TCP connection to the SSH port
+	TCPErr      error         // nil when the SSH port accepted the connection
+	ICMPChecked bool          // ICMP was requested
+	ICMPLatency time.Duration // Round-trip time reported by ping
+	ICMPErr     error         // nil when the host answered the ping
+}
+
+// Reachable reports whether the SSH port accepted a TCP connection
+func (r PingResult) Reachable() bool {
+	return r.TCPErr == nil
+}
+
+// Ping checks that each host's SSH port accepts TCP connections without
+// performing a handshake or authentication, and optionally pings it.
+// Results are returned in the same order as configs.
+func Ping(configs []*Config, opts PingOptions) []PingResult {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultPingTimeout
+	}
+
+	results := make([]PingResult, len(configs))
+	forEachHost(configs, opts.Concurrency, opts.Progress, func(i int, config *Config) {
+		results[i] = pingOne(config, opts)
+	})
+	return results
+}
+
+// pingOne probes a single host
+func pingOne(config *Config, opts PingOptions) PingResult {
+	if config == nil || config.Host == "" {
+		return PingResult{TCPErr: fmt.Errorf("host is required")}
+	}
+
+	port := config.Port
+	if port == "" {
+		port = DefaultSSHPort
+	}
+	result := PingResult{Address: net.JoinHostPort(config.Host, port)}
+	// The first of the host's addresses that answers counts, as it would
+	// for the dialer
+	for _, target := range dialTargets(config) {
+		latency, err := pingTCP(target, opts.Timeout)
+		if err == nil {
+			result.Address, result.TCPLatency, result.TCPErr = target, latency, nil
+			break
+		}
+		result.TCPErr = err
+	}
+
+	if opts.ICMP {
+		result.ICMPChecked = true
+		result.ICMPLatency, result.ICMPErr = pingICMP(config.Host, opts.Timeout)
+	}
+	return result
+}
+
+// pingTCP measures how long it takes to open a TCP connection
+func pingTCP(address string, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return 0, err
+	}
+	latency := time.Since(start)
+	_ = conn.Close() //nolint:errcheck // probe connection only
+	return latency, nil
+}
+
+// icmpTimePattern extracts the round-trip time from ping output
+// ("time=12.3 ms" on Unix, "time=12ms" or "time<1ms" on Windows)
+var icmpTimePattern = regexp.MustCompile(`time[=<]([0-9.]+)\s*ms`)
+
+// pingICMP runs the system ping command once, since raw ICMP sockets need
+// elevated privileges
+func pingICMP(host string, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+time.Second)
+	defer cancel()
+
+	seconds := strconv.Itoa(max(1, int(timeout.Round(time.Second)/time.Second)))
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.CommandContext(ctx, "ping", "-n", "1", "-w", strconv.Itoa(int(timeout/time.Millisecond)), host) // #nosec G204 -- host is passed as a single argument
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "ping", "-c", "1", "-t", seconds, host) // #nosec G204 -- host is passed as a single argument
+	default:
+		cmd = exec.CommandContext(ctx, "ping", "-c", "1", "-W", seconds, host) // #nosec G204 -- host is passed as a single argument
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("no ICMP reply: %w", err)
+	}
+	return parsePingTime(string(output))
+}
+
+// parsePingTime returns the round-trip time printed by ping
+func parsePingTime(output string) (time.Duration, error) {
+	match := icmpTimePattern.FindStringSubmatch(output)
+	if match == nil {
+		return 0, fmt.Errorf("could not parse ping output")
+	}
+	ms, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse ping time: %w", err)
+	}
+	return time.Duration(ms * float64(time.Millisecond)), nil
+}
@@ -0,0 +1,54 @@
+package sshclient
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing_OpenAndClosedPorts(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	_, openPort, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, closedPort, err := net.SplitHostPort(closed.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, closed.Close())
+
+	results := Ping([]*Config{
+		{Host: "127.0.0.1", Port: openPort},
+		{Host: "127.0.0.1", Port: closedPort},
+		nil,
+	}, PingOptions{Timeout: time.Second})
+
+	require.Len(t, results, 3)
+	assert.True(t, results[0].Reachable())
+	assert.Equal(t, net.JoinHostPort("127.0.0.1", openPort), results[0].Address)
+	assert.False(t, results[0].ICMPChecked)
+	assert.False(t, results[1].Reachable())
+	assert.ErrorContains(t, results[2].TCPErr, "host is required")
+}
+
+func TestParsePingTime(t *testing.T) {
+	tests := map[string]time.Duration{
+		"64 bytes from 10.0.0.1: icmp_seq=1 ttl=64 time=12.3 ms":     12300 * time.Microsecond,
+		"Reply from 10.0.0.1: bytes=32 time=4ms TTL=128":             4 * time.Millisecond,
+		"Reply from 127.0.0.1: bytes=32 time<1ms TTL=128":            time.Millisecond,
+		"64 bytes from 127.0.0.1: icmp_seq=0 ttl=64 time=0.045 ms\n": 45 * time.Microsecond,
+	}
+	for output, expected := range tests {
+		latency, err := parsePingTime(output)
+		require.NoError(t, err, output)
+		assert.Equal(t, expected, latency, output)
+	}
+
+	_, err := parsePingTime("Request timed out.")
+	assert.Error(t, err)
+}
@@ -0,0 +1,170 @@
+package sshclient
+
+import (
+	"sort"
+	"time"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// pinnedRetryBase is the delay before the second reconnect attempt of a
+	// pinned connection; it doubles after every further failure
+	pinnedRetryBase = 5 * time.Second
+	// pinnedRetryMax caps the delay between reconnect attempts
+	pinnedRetryMax = 5 * time.Minute
+)
+
+// pinnedHost is a connection the pool keeps open in the background
+type pinnedHost struct {
+	config       *Config
+	failures     int
+	lastError    string
+	lastAttempt  time.Time
+	nextAttempt  time.Time
+	reconnecting bool
+}
+
+// PinnedConnectionInfo describes the health of a pinned connection
+type PinnedConnectionInfo struct {
+	Key         string     `json:"key"`
+	Host        string     `json:"host"`
+	Connected   bool       `json:"connected"`
+	Failures    int        `json:"consecutive_failures"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	NextAttempt *time.Time `json:"next_attempt,omitempty"`
+}
+
+// Pin marks hosts whose connections the pool keeps open: they are never
+// closed for being idle, and the maintenance loop re-establishes them after
+// a failure with exponential backoff. It returns the pinned pool keys and
+// connects them in the background.
+func (p *ConnectionPool) Pin(configs ...*Config) []string {
+	keys := make([]string, 0, len(configs))
+	p.mu.Lock()
+	if p.pinned == nil {
+		p.pinned = make(map[string]*pinnedHost)
+	}
+	for _, config := range configs {
+		key := p.makeKey(config)
+		p.pinned[key] = &pinnedHost{config: config}
+		keys = append(keys, key)
+	}
+	p.mu.Unlock()
+
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		go p.reconnectPinned(time.Now())
+	}
+	return keys
+}
+
+// Forget unpins and evicts every connection to the host address, for a host
+// that no longer exists: unlike Evict, nothing reconnects it. It returns the
+// evicted keys.
+func (p *ConnectionPool) Forget(host string) []string {
+	p.mu.Lock()
+	for key, pin := range p.pinned {
+		if pin.config.Host == host {
+			delete(p.pinned, key)
+		}
+	}
+	p.mu.Unlock()
+	return p.Evict(host)
+}
+
+// isPinned reports whether key is pinned; the caller holds p.mu
+func (p *ConnectionPool) isPinned(key string) bool {
+	_, ok := p.pinned[key]
+	return ok
+}
+
+// reconnectPinned connects every pinned host that has no pooled connection
+// and whose backoff has elapsed
+func (p *ConnectionPool) reconnectPinned(now time.Time) {
+	var due []*pinnedHost
+	p.mu.Lock()
+	for key, pin := range p.pinned {
+		if _, connected := p.connections[key]; connected || pin.reconnecting || now.Before(pin.nextAttempt) {
+			continue
+		}
+		pin.reconnecting = true
+		due = append(due, pin)
+	}
+	p.mu.Unlock()
+
+	lg := logger.GetLogger()
+	for _, pin := range due {
+		key := p.makeKey(pin.config)
+		_, err := p.GetConnection(pin.config)
+
+		p.mu.Lock()
+		pin.reconnecting = false
+		pin.lastAttempt = time.Now()
+		delay := time.Duration(0)
+		if err != nil {
+			pin.failures++
+			pin.lastError = err.Error()
+			delay = pinnedBackoff(pin.failures)
+			pin.nextAttempt = pin.lastAttempt.Add(delay)
+		} else {
+			pin.failures = 0
+			pin.lastError = ""
+			pin.nextAttempt = time.Time{}
+		}
+		failures := pin.failures
+		p.mu.Unlock()
+
+		if err != nil {
+			lg.Warning("Failed to reconnect pinned host %s (attempt %d, next in %s): %v", key, failures, delay, err)
+		} else {
+			lg.Debug("Pinned connection %s is up", key)
+		}
+	}
+}
+
+// pinnedBackoff is the delay after the given number of consecutive failures
+func pinnedBackoff(failures int) time.Duration {
+	delay := pinnedRetryBase
+	for i := 1; i < failures && delay < pinnedRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > pinnedRetryMax {
+		delay = pinnedRetryMax
+	}
+	return delay
+}
+
+// PinnedConnections returns the health of every pinned connection, sorted by key
+func (p *ConnectionPool) PinnedConnections() []PinnedConnectionInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.pinnedInfo()
+}
+
+// pinnedInfo describes the pinned connections; the caller holds p.mu
+func (p *ConnectionPool) pinnedInfo() []PinnedConnectionInfo {
+	infos := make([]PinnedConnectionInfo, 0, len(p.pinned))
+	for key, pin := range p.pinned {
+		_, connected := p.connections[key]
+		info := PinnedConnectionInfo{
+			Key:       key,
+			Host:      pin.config.Host,
+			Connected: connected,
+			Failures:  pin.failures,
+			LastError: pin.lastError,
+		}
+		if !pin.lastAttempt.IsZero() {
+			lastAttempt := pin.lastAttempt
+			info.LastAttempt = &lastAttempt
+		}
+		if !connected && !pin.nextAttempt.IsZero() {
+			nextAttempt := pin.nextAttempt
+			info.NextAttempt = &nextAttempt
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos
+}
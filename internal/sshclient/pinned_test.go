@@ -0,0 +1,104 @@
+package sshclient
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPinnedBackoff(t *testing.T) {
+	assert.Equal(t, 5*time.Second, pinnedBackoff(1))
+	assert.Equal(t, 10*time.Second, pinnedBackoff(2))
+	assert.Equal(t, 40*time.Second, pinnedBackoff(4))
+	assert.Equal(t, pinnedRetryMax, pinnedBackoff(20))
+}
+
+func TestReconnectPinned_BacksOffAfterFailure(t *testing.T) {
+	pool := NewConnectionPool()
+	pool.retryDelay = time.Millisecond
+	config := &Config{
+		Host: "127.0.0.1", Port: "1", User: "test", Password: "x",
+		DialTimeout: 100 * time.Millisecond, MaxRetries: 1,
+	}
+	key := pool.makeKey(config)
+	pool.pinned[key] = &pinnedHost{config: config}
+
+	pool.reconnectPinned(time.Now())
+	pinned := pool.PinnedConnections()
+	require.Len(t, pinned, 1)
+	assert.False(t, pinned[0].Connected)
+	assert.Equal(t, 1, pinned[0].Failures)
+	assert.NotEmpty(t, pinned[0].LastError)
+	require.NotNil(t, pinned[0].NextAttempt)
+	assert.WithinDuration(t, pinned[0].LastAttempt.Add(pinnedRetryBase), *pinned[0].NextAttempt, 0)
+
+	// Within the backoff window the host is not dialed again
+	pool.reconnectPinned(time.Now())
+	assert.Equal(t, 1, pool.PinnedConnections()[0].Failures)
+}
+
+func TestReconnectPinned_RestoresConnection(t *testing.T) {
+	addr := listenExecServer(t, func(string, ssh.Channel, <-chan struct{}) uint32 { return 0 })
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	pool := NewConnectionPool()
+	defer pool.Close()
+	config := &Config{
+		Host:              host,
+		Port:              port,
+		User:              "test",
+		Password:          "unused",
+		KnownHostsPath:    filepath.Join(t.TempDir(), "known_hosts"),
+		AcceptUnknownHost: true,
+	}
+	key := pool.makeKey(config)
+	pool.pinned[key] = &pinnedHost{config: config, failures: 3, lastError: "boom"}
+
+	pool.reconnectPinned(time.Now())
+	pinned := pool.PinnedConnections()
+	require.Len(t, pinned, 1)
+	assert.True(t, pinned[0].Connected)
+	assert.Zero(t, pinned[0].Failures)
+	assert.Empty(t, pinned[0].LastError)
+	assert.Nil(t, pinned[0].NextAttempt)
+
+	// Pinned connections survive the idle timeout
+	pool.maxIdle = time.Nanosecond
+	pool.connections[key].lastUsed = time.Now().Add(-time.Minute)
+	pool.cleanup()
+	assert.Contains(t, pool.connections, key)
+}
+
+func TestForget_Unpins(t *testing.T) {
+	addr := listenExecServer(t, func(string, ssh.Channel, <-chan struct{}) uint32 { return 0 })
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	pool := NewConnectionPool()
+	defer pool.Close()
+	config := &Config{
+		Host:              host,
+		Port:              port,
+		User:              "test",
+		Password:          "unused",
+		KnownHostsPath:    filepath.Join(t.TempDir(), "known_hosts"),
+		AcceptUnknownHost: true,
+	}
+	key := pool.makeKey(config)
+	pool.pinned[key] = &pinnedHost{config: config}
+	pool.reconnectPinned(time.Now())
+	require.Contains(t, pool.connections, key)
+
+	assert.Equal(t, []string{key}, pool.Forget(host))
+	assert.Empty(t, pool.PinnedConnections())
+
+	// Nothing reconnects a forgotten host
+	pool.reconnectPinned(time.Now())
+	assert.NotContains(t, pool.connections, key)
+}
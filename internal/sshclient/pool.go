@@ -2,6 +2,8 @@ package sshclient
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,24 +12,44 @@ import (
 	"golang.org/x/crypto/ssh"
 )
 
+// DefaultPoolMaxLifetime is how long a pooled connection may be reused
+// before it is replaced, regardless of activity
+const DefaultPoolMaxLifetime = time.Hour
+
 // ConnectionPool manages SSH connections with pooling and health checks
 type ConnectionPool struct {
 	mu          sync.RWMutex
 	connections map[string]*PooledConnection
-	maxIdle     time.Duration // Maximum idle time
-	healthCheck time.Duration // Health check interval
-	maxRetries  int           // Maximum retry attempts
-	retryDelay  time.Duration // Retry delay
+	pinned      map[string]*pinnedHost // Connections re-established in the background
+	maxIdle     time.Duration          // Maximum idle time
+	maxLifetime time.Duration          // Maximum connection age (0 = unlimited)
+	healthCheck time.Duration          // Health check interval
+	maxRetries  int                    // Maximum retry attempts
+	retryDelay  time.Duration          // Retry delay
 }
 
 // PooledConnection represents a pooled SSH connection
 type PooledConnection struct {
 	client     *ssh.Client
 	config     *Config
+	createdAt  time.Time
 	lastUsed   time.Time
 	mu         sync.Mutex
 	inUse      bool
 	retryCount int
+	authMethod AuthMethod
+}
+
+// PoolConnectionInfo describes one pooled connection
+type PoolConnectionInfo struct {
+	Key        string     `json:"key"`
+	Host       string     `json:"host"`
+	InUse      bool       `json:"in_use"`
+	Age        string     `json:"age"`
+	LastUsed   time.Time  `json:"last_used"`
+	Idle       string     `json:"idle"`
+	RetryCount int        `json:"retry_count"`
+	AuthMethod AuthMethod `json:"auth_method"`
 }
 
 var (
@@ -49,10 +71,12 @@ func GetConnectionPool() *ConnectionPool {
 func NewConnectionPool() *ConnectionPool {
 	return &ConnectionPool{
 		connections: make(map[string]*PooledConnection),
-		maxIdle:     5 * time.Minute,  // Auto-close after 5 minutes of inactivity
-		healthCheck: 30 * time.Second, // Health check every 30 seconds
-		maxRetries:  3,                // Maximum 3 retry attempts
-		retryDelay:  1 * time.Second,  // 1 second retry delay
+		pinned:      make(map[string]*pinnedHost),
+		maxIdle:     5 * time.Minute,        // Auto-close after 5 minutes of inactivity
+		maxLifetime: DefaultPoolMaxLifetime, // Replace connections after 1 hour even if active
+		healthCheck: 30 * time.Second,       // Health check every 30 seconds
+		maxRetries:  3,                      // Maximum 3 retry attempts
+		retryDelay:  1 * time.Second,        // 1 second retry delay
 	}
 }
 
@@ -66,7 +90,7 @@ func (p *ConnectionPool) GetConnection(config *Config) (*ssh.Client, error) {
 
 	if exists {
 		// Check if connection is still valid
-		if p.isConnectionAlive(pooledConn.client) {
+		if !p.expired(pooledConn, time.Now()) && p.pooledConnectionAlive(pooledConn) {
 			pooledConn.mu.Lock()
 			pooledConn.lastUsed = time.Now()
 			// Note: SSH connections can handle multiple concurrent sessions
@@ -78,8 +102,8 @@ func (p *ConnectionPool) GetConnection(config *Config) (*ssh.Client, error) {
 			return pooledConn.client, nil
 		}
 
-		// Connection is invalid, remove and recreate
-		lg.Debug("❌ Connection invalid, removing from pool for %s", key)
+		// Connection is invalid or too old, remove and recreate
+		lg.Debug("❌ Connection invalid or expired, removing from pool for %s", key)
 		pooledConn.mu.Lock()
 		if pooledConn.client != nil {
 			_ = errutil.SafeClose(pooledConn.client) //nolint:errcheck
@@ -91,7 +115,7 @@ func (p *ConnectionPool) GetConnection(config *Config) (*ssh.Client, error) {
 
 	lg.Debug("➕ Creating new connection for pool key %s", key)
 	// Create new connection with retry mechanism
-	client, err := p.createConnectionWithRetry(config)
+	client, authMethod, err := p.createConnectionWithRetry(config)
 	if err != nil {
 		return nil, err
 	}
@@ -100,9 +124,11 @@ func (p *ConnectionPool) GetConnection(config *Config) (*ssh.Client, error) {
 	pooledConn = &PooledConnection{
 		client:     client,
 		config:     config,
+		createdAt:  time.Now(),
 		lastUsed:   time.Now(),
 		inUse:      false, // SSH connections can handle multiple sessions
 		retryCount: 0,
+		authMethod: authMethod,
 	}
 
 	p.mu.Lock()
@@ -148,39 +174,151 @@ func (p *ConnectionPool) RemoveConnection(config *Config) {
 	}
 }
 
+// Evict closes and removes the pooled connections matching hostKey, either
+// a pool key (user@host:port) or a host address matching every user and
+// port. It returns the evicted keys; the next request reconnects, which
+// picks up rotated keys or a rebooted server.
+func (p *ConnectionPool) Evict(hostKey string) []string {
+	return p.evict(func(key string, pooledConn *PooledConnection) bool {
+		return key == hostKey || (pooledConn.config != nil && pooledConn.config.Host == hostKey)
+	})
+}
+
+// Flush closes and removes every pooled connection, returning their keys
+func (p *ConnectionPool) Flush() []string {
+	return p.evict(func(string, *PooledConnection) bool { return true })
+}
+
+// evict removes the connections selected by match, sorted by key
+func (p *ConnectionPool) evict(match func(key string, pooledConn *PooledConnection) bool) []string {
+	lg := logger.GetLogger()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	evicted := []string{}
+	for key, pooledConn := range p.connections {
+		if !match(key, pooledConn) {
+			continue
+		}
+		pooledConn.mu.Lock()
+		if pooledConn.client != nil {
+			if err := errutil.SafeClose(pooledConn.client); err != nil {
+				lg.Debug("Failed to close pooled connection %s: %v", key, err)
+			}
+		}
+		pooledConn.mu.Unlock()
+		delete(p.connections, key)
+		evicted = append(evicted, key)
+	}
+	sort.Strings(evicted)
+	if len(evicted) > 0 {
+		lg.Debug("Evicted %d pooled connection(s): %s", len(evicted), strings.Join(evicted, ", "))
+	}
+	return evicted
+}
+
+// WarmResult describes the outcome of pre-connecting a single host
+type WarmResult struct {
+	Key      string        // Pool key (user@host:port)
+	Reused   bool          // Connection was already pooled and healthy
+	Duration time.Duration // Time spent establishing (or validating) the connection
+	Err      error         // Connection error, nil on success
+}
+
+// Warm establishes pooled connections to the given hosts in parallel so that
+// the first command sent to each host does not pay the dial+auth latency.
+// Results are returned in the same order as the provided configs.
+func (p *ConnectionPool) Warm(configs ...*Config) []WarmResult {
+	results := make([]WarmResult, len(configs))
+
+	var wg sync.WaitGroup
+	for i, config := range configs {
+		wg.Add(1)
+		go func(i int, config *Config) {
+			defer wg.Done()
+			results[i] = p.warmOne(config)
+		}(i, config)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// warmOne pre-connects a single host and records the result
+func (p *ConnectionPool) warmOne(config *Config) WarmResult {
+	lg := logger.GetLogger()
+	start := time.Now()
+
+	if config == nil || config.Host == "" {
+		return WarmResult{Err: fmt.Errorf("host is required")}
+	}
+
+	// Apply the same defaults NewSSHClient would so the pool key matches later lookups
+	if _, err := NewSSHClient(config); err != nil {
+		return WarmResult{Key: p.makeKey(config), Err: err}
+	}
+	key := p.makeKey(config)
+
+	var previous *ssh.Client
+	p.mu.RLock()
+	if pooledConn, exists := p.connections[key]; exists {
+		previous = pooledConn.client
+	}
+	p.mu.RUnlock()
+
+	client, err := p.GetConnection(config)
+	result := WarmResult{
+		Key:      key,
+		Reused:   err == nil && previous != nil && client == previous,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if err != nil {
+		lg.Debug("Failed to warm connection %s: %v", key, err)
+	} else {
+		lg.Debug("Warmed connection %s in %s", key, result.Duration)
+	}
+	return result
+}
+
 // createConnectionWithRetry creates a connection with retry mechanism
-func (p *ConnectionPool) createConnectionWithRetry(config *Config) (*ssh.Client, error) {
+func (p *ConnectionPool) createConnectionWithRetry(config *Config) (*ssh.Client, AuthMethod, error) {
 	var lastErr error
 
-	for i := 0; i < p.maxRetries; i++ {
+	attempts := p.maxRetries
+	if config.MaxRetries > 0 {
+		attempts = config.MaxRetries
+	}
+	for i := 0; i < attempts; i++ {
 		if i > 0 {
 			time.Sleep(p.retryDelay * time.Duration(i)) // Exponential backoff
 		}
 
-		client, err := p.createConnection(config)
+		client, authMethod, err := p.createConnection(config)
 		if err == nil {
-			return client, nil
+			return client, authMethod, nil
 		}
 
 		lastErr = err
 	}
 
-	return nil, fmt.Errorf("failed after %d retries: %w", p.maxRetries, lastErr)
+	return nil, AuthMethodUnknown, fmt.Errorf("failed after %d retries: %w", attempts, lastErr)
 }
 
 // createConnection creates a single SSH connection (direct connection, not using pool)
-func (p *ConnectionPool) createConnection(config *Config) (*ssh.Client, error) {
+// and reports the authentication method that succeeded
+func (p *ConnectionPool) createConnection(config *Config) (*ssh.Client, AuthMethod, error) {
 	sshClient, err := NewSSHClient(config)
 	if err != nil {
-		return nil, err
+		return nil, AuthMethodUnknown, err
 	}
 
 	// Use ConnectDirect() to avoid recursive pool calls
 	if err := sshClient.ConnectDirect(); err != nil {
-		return nil, err
+		return nil, AuthMethodUnknown, err
 	}
 
-	return sshClient.client, nil
+	return sshClient.client, sshClient.AuthMethodUsed(), nil
 }
 
 // isConnectionAlive checks if a connection is alive
@@ -215,6 +353,47 @@ func (p *ConnectionPool) isConnectionAlive(client *ssh.Client) bool {
 	}
 }
 
+// pooledConnectionAlive checks a pooled connection. Network devices may
+// refuse exec requests, so for them a keepalive request has to be answered
+// instead.
+func (p *ConnectionPool) pooledConnectionAlive(pooledConn *PooledConnection) bool {
+	if pooledConn.config == nil || pooledConn.config.Device == nil || pooledConn.client == nil {
+		return p.isConnectionAlive(pooledConn.client)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := pooledConn.client.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err == nil
+	case <-time.After(5 * time.Second):
+		return false
+	}
+}
+
+// SetMaxIdle sets how long an unused connection stays in the pool
+func (p *ConnectionPool) SetMaxIdle(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxIdle = d
+}
+
+// SetMaxLifetime sets how long a connection may be reused before it is
+// replaced, even while active. Zero disables the limit.
+func (p *ConnectionPool) SetMaxLifetime(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxLifetime = d
+}
+
+// expired reports whether a pooled connection exceeded its maximum lifetime.
+// Caller must hold p.mu.
+func (p *ConnectionPool) expired(pooledConn *PooledConnection, now time.Time) bool {
+	return p.maxLifetime > 0 && !pooledConn.createdAt.IsZero() && now.Sub(pooledConn.createdAt) > p.maxLifetime
+}
+
 // makeKey generates a connection pool key
 func (p *ConnectionPool) makeKey(config *Config) string {
 	return fmt.Sprintf("%s@%s:%s", config.User, config.Host, config.Port)
@@ -227,6 +406,7 @@ func (p *ConnectionPool) startMaintenance() {
 
 	for range ticker.C {
 		p.cleanup()
+		p.reconnectPinned(time.Now())
 	}
 }
 
@@ -239,10 +419,11 @@ func (p *ConnectionPool) cleanup() {
 	for key, pooledConn := range p.connections {
 		pooledConn.mu.Lock()
 
-		// Check if exceeded max idle time
-		if now.Sub(pooledConn.lastUsed) > p.maxIdle {
+		// Check if exceeded max idle time or lifetime; pinned connections never go idle
+		idle := now.Sub(pooledConn.lastUsed) > p.maxIdle && !p.isPinned(key)
+		if idle || p.expired(pooledConn, now) {
 			toRemove = append(toRemove, key)
-		} else if !p.isConnectionAlive(pooledConn.client) {
+		} else if !p.pooledConnectionAlive(pooledConn) {
 			// Connection is invalid
 			toRemove = append(toRemove, key)
 		}
@@ -287,6 +468,7 @@ func (p *ConnectionPool) Close() {
 	}
 
 	p.connections = make(map[string]*PooledConnection)
+	p.pinned = make(map[string]*pinnedHost)
 
 	if len(errs) > 0 {
 		lg.Warning("Closed connection pool with %d errors", len(errs))
@@ -319,6 +501,45 @@ func (p *ConnectionPool) Stats() map[string]interface{} {
 		"recently_used_connections": recentlyUsed,
 		"idle_connections":          totalConns - recentlyUsed,
 		"max_idle_duration":         p.maxIdle.String(),
+		"max_lifetime":              p.maxLifetime.String(),
 		"health_check_interval":     p.healthCheck.String(),
+		"connections":               p.connectionInfo(now),
+		"pinned_connections":        p.pinnedInfo(),
+	}
+}
+
+// Connections returns a snapshot of every pooled connection, sorted by key
+func (p *ConnectionPool) Connections() []PoolConnectionInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.connectionInfo(time.Now())
+}
+
+// connectionInfo describes the pooled connections; the caller holds p.mu
+func (p *ConnectionPool) connectionInfo(now time.Time) []PoolConnectionInfo {
+	infos := make([]PoolConnectionInfo, 0, len(p.connections))
+	for key, pooledConn := range p.connections {
+		pooledConn.mu.Lock()
+		info := PoolConnectionInfo{
+			Key:        key,
+			InUse:      pooledConn.inUse,
+			LastUsed:   pooledConn.lastUsed,
+			Idle:       now.Sub(pooledConn.lastUsed).Round(time.Second).String(),
+			RetryCount: pooledConn.retryCount,
+			AuthMethod: pooledConn.authMethod,
+		}
+		if !pooledConn.createdAt.IsZero() {
+			info.Age = now.Sub(pooledConn.createdAt).Round(time.Second).String()
+		}
+		if pooledConn.config != nil {
+			info.Host = pooledConn.config.Host
+		}
+		pooledConn.mu.Unlock()
+		if info.AuthMethod == "" {
+			info.AuthMethod = AuthMethodUnknown
+		}
+		infos = append(infos, info)
 	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewConnectionPool(t *testing.T) {
@@ -184,6 +185,40 @@ func TestStats(t *testing.T) {
 	assert.Equal(t, "30s", stats["health_check_interval"])
 }
 
+func TestConnections(t *testing.T) {
+	pool := NewConnectionPool()
+	now := time.Now()
+	webConfig := &Config{Host: "web-host", Port: "22", User: "deploy"}
+	dbConfig := &Config{Host: "db-host", Port: "22", User: "root"}
+
+	pool.connections[pool.makeKey(webConfig)] = &PooledConnection{
+		config:     webConfig,
+		createdAt:  now.Add(-10 * time.Minute),
+		lastUsed:   now.Add(-30 * time.Second),
+		retryCount: 1,
+		authMethod: AuthMethodKey,
+	}
+	pool.connections[pool.makeKey(dbConfig)] = &PooledConnection{
+		config:    dbConfig,
+		createdAt: now,
+		lastUsed:  now,
+		inUse:     true,
+	}
+
+	connections := pool.Connections()
+	require.Len(t, connections, 2)
+	assert.Equal(t, "deploy@web-host:22", connections[0].Key)
+	assert.Equal(t, "web-host", connections[0].Host)
+	assert.Equal(t, "10m0s", connections[0].Age)
+	assert.Equal(t, "30s", connections[0].Idle)
+	assert.Equal(t, 1, connections[0].RetryCount)
+	assert.Equal(t, AuthMethodKey, connections[0].AuthMethod)
+	assert.True(t, connections[1].InUse)
+	assert.Equal(t, AuthMethodUnknown, connections[1].AuthMethod)
+
+	assert.Len(t, pool.Stats()["connections"], 2)
+}
+
 func TestStats_EmptyPool(t *testing.T) {
 	pool := NewConnectionPool()
 
@@ -395,3 +430,84 @@ func TestCleanup_ThreadSafeRemoval(t *testing.T) {
 	// All expired connections should be removed
 	assert.Empty(t, pool.connections)
 }
+
+func TestWarm_InvalidConfigs(t *testing.T) {
+	pool := NewConnectionPool()
+
+	results := pool.Warm(nil, &Config{})
+
+	assert.Len(t, results, 2)
+	for _, result := range results {
+		assert.Error(t, result.Err)
+		assert.False(t, result.Reused)
+	}
+	assert.Empty(t, pool.connections)
+}
+
+func TestWarm_NoHosts(t *testing.T) {
+	pool := NewConnectionPool()
+	assert.Empty(t, pool.Warm())
+}
+
+func TestCleanup_ExpiredLifetime(t *testing.T) {
+	pool := NewConnectionPool()
+	pool.SetMaxLifetime(100 * time.Millisecond)
+
+	config := &Config{Host: "test-host", Port: "22", User: "testuser"}
+	key := pool.makeKey(config)
+
+	// Recently used, but created before the lifetime window
+	pool.connections[key] = &PooledConnection{
+		config:    config,
+		createdAt: time.Now().Add(-200 * time.Millisecond),
+		lastUsed:  time.Now(),
+	}
+
+	pool.cleanup()
+
+	assert.Empty(t, pool.connections)
+}
+
+func TestExpired(t *testing.T) {
+	pool := NewConnectionPool()
+	now := time.Now()
+
+	assert.Equal(t, DefaultPoolMaxLifetime.String(), pool.Stats()["max_lifetime"])
+	assert.False(t, pool.expired(&PooledConnection{createdAt: now.Add(-time.Minute)}, now))
+	assert.True(t, pool.expired(&PooledConnection{createdAt: now.Add(-2 * time.Hour)}, now))
+	assert.False(t, pool.expired(&PooledConnection{}, now), "connections without a creation time never expire")
+
+	pool.SetMaxLifetime(0)
+	assert.False(t, pool.expired(&PooledConnection{createdAt: now.Add(-48 * time.Hour)}, now))
+}
+
+func TestCreateConnectionWithRetry_UsesConfigMaxRetries(t *testing.T) {
+	pool := NewConnectionPool()
+	pool.retryDelay = time.Millisecond
+
+	_, _, err := pool.createConnectionWithRetry(&Config{
+		Host: "127.0.0.1", Port: "1", User: "test", Password: "x",
+		DialTimeout: 100 * time.Millisecond, MaxRetries: 2,
+	})
+	assert.ErrorContains(t, err, "failed after 2 retries")
+}
+
+func TestConnectionPool_EvictAndFlush(t *testing.T) {
+	pool := &ConnectionPool{connections: make(map[string]*PooledConnection)}
+	for _, config := range []*Config{
+		{Host: "10.0.0.1", Port: "22", User: "root"},
+		{Host: "10.0.0.1", Port: "2222", User: "deploy"},
+		{Host: "10.0.0.2", Port: "22", User: "root"},
+		{Host: "10.0.0.3", Port: "22", User: "root"},
+	} {
+		pool.connections[pool.makeKey(config)] = &PooledConnection{config: config, lastUsed: time.Now()}
+	}
+
+	assert.Equal(t, []string{"deploy@10.0.0.1:2222", "root@10.0.0.1:22"}, pool.Evict("10.0.0.1"))
+	assert.Equal(t, []string{"root@10.0.0.2:22"}, pool.Evict("root@10.0.0.2:22"))
+	assert.Empty(t, pool.Evict("10.0.0.9"))
+	assert.Len(t, pool.connections, 1)
+
+	assert.Equal(t, []string{"root@10.0.0.3:22"}, pool.Flush())
+	assert.Empty(t, pool.connections)
+}
@@ -0,0 +1,389 @@
+package sshclient
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Firewall tools PortCheckCommand reads
+const (
+	FirewallUFW       = "ufw"
+	FirewallFirewalld = "firewalld"
+	FirewallIptables  = "iptables"
+	FirewallNone      = "none"
+)
+
+// Firewall verdicts for a port
+const (
+	PortAllowed = "allowed"
+	PortBlocked = "blocked"
+	PortUnknown = "unknown"
+)
+
+// portCheckMarker prefixes the lines of PortCheckCommand that start a
+// section: the listener tool (ss or netstat) or the firewall tool
+const portCheckMarker = "@sshx-ports:"
+
+// firewalldServicePorts are the ports of the firewalld services commonly
+// enabled on servers
+var firewalldServicePorts = map[string]string{
+	"ssh": "22/tcp", "http": "80/tcp", "https": "443/tcp", "dns": "53/udp",
+	"mysql": "3306/tcp", "postgresql": "5432/tcp", "redis": "6379/tcp",
+	"smtp": "25/tcp", "imaps": "993/tcp", "ntp": "123/udp", "dhcpv6-client": "546/udp",
+}
+
+// Listener is a socket listening on the host
+type Listener struct {
+	Protocol string `json:"protocol"` // tcp or udp
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	Process  string `json:"process,omitempty"` // Known when run as root
+}
+
+// FirewallStatus summarizes the host firewall
+type FirewallStatus struct {
+	Tool   string `json:"tool"` // ufw, firewalld, iptables or none
+	Active bool   `json:"active"`
+	// DefaultIncoming is the policy for incoming traffic no rule matches
+	// (allow, deny, reject or drop)
+	DefaultIncoming string   `json:"default_incoming,omitempty"`
+	Rules           []string `json:"rules"` // As printed by the tool
+}
+
+// PortStatus tells whether one port is reachable
+type PortStatus struct {
+	Port      int        `json:"port"`
+	Protocol  string     `json:"protocol"`
+	Listening bool       `json:"listening"`
+	Listeners []Listener `json:"listeners,omitempty"`
+	// Firewall is allowed, blocked or unknown (rules sshx cannot evaluate,
+	// such as jumps to other chains)
+	Firewall string `json:"firewall"`
+	// Open is true when something listens and the firewall does not block it
+	Open bool `json:"open"`
+}
+
+// PortReport is the result of the port_check tool
+type PortReport struct {
+	Listeners []Listener     `json:"listeners"`
+	Firewall  FirewallStatus `json:"firewall"`
+	Port      *PortStatus    `json:"port,omitempty"`
+}
+
+// PortCheckCommand prints the listening sockets (ss, or netstat) and the
+// firewall status (ufw, firewalld or the iptables INPUT chain), each after
+// a marker line. Every command only reads state.
+func PortCheckCommand() string {
+	section := func(name string) string {
+		return "echo " + ShellQuote(portCheckMarker+name)
+	}
+	return strings.Join([]string{
+		`if command -v ss >/dev/null 2>&1; then ` + section("ss") + `; ss -tulnp 2>/dev/null; ` +
+			`elif command -v netstat >/dev/null 2>&1; then ` + section("netstat") + `; netstat -tulnp 2>/dev/null; fi`,
+		`if command -v ufw >/dev/null 2>&1 && ufw status >/dev/null 2>&1; then ` + section(FirewallUFW) + `; ufw status verbose; ` +
+			`elif command -v firewall-cmd >/dev/null 2>&1 && firewall-cmd --state >/dev/null 2>&1; then ` + section(FirewallFirewalld) + `; firewall-cmd --list-all; ` +
+			`elif command -v iptables >/dev/null 2>&1 && iptables -S INPUT >/dev/null 2>&1; then ` + section(FirewallIptables) + `; iptables -S INPUT; ` +
+			`else ` + section(FirewallNone) + `; fi`,
+	}, "\n")
+}
+
+// ParsePortCheck reads the output of PortCheckCommand
+func ParsePortCheck(output string) *PortReport {
+	report := &PortReport{Listeners: []Listener{}, Firewall: FirewallStatus{Tool: FirewallNone, Rules: []string{}}}
+	section := ""
+	var firewall []string
+	for _, line := range splitLines(output) {
+		line = strings.TrimRight(line, "\r")
+		if name, ok := strings.CutPrefix(line, portCheckMarker); ok {
+			section = name
+			if name != "ss" && name != "netstat" {
+				report.Firewall.Tool = name
+			}
+			continue
+		}
+		switch section {
+		case "ss", "netstat":
+			if listener, ok := parseListener(section, line); ok {
+				report.Listeners = append(report.Listeners, listener)
+			}
+		case FirewallUFW, FirewallFirewalld, FirewallIptables:
+			firewall = append(firewall, line)
+		}
+	}
+
+	switch report.Firewall.Tool {
+	case FirewallUFW:
+		parseUFW(&report.Firewall, firewall)
+	case FirewallFirewalld:
+		parseFirewalld(&report.Firewall, firewall)
+	case FirewallIptables:
+		parseIptables(&report.Firewall, firewall)
+	}
+	return report
+}
+
+// parseListener reads one line of ss -tulnp or netstat -tulnp
+func parseListener(tool, line string) (Listener, bool) {
+	fields := strings.Fields(line)
+	var proto, local, process string
+	switch {
+	case tool == "ss" && len(fields) >= 5:
+		proto, local = fields[0], fields[4]
+		if users := strings.Index(line, `users:(("`); users >= 0 {
+			process, _, _ = strings.Cut(line[users+len(`users:(("`):], `"`)
+		}
+	case tool == "netstat" && len(fields) >= 4:
+		proto, local = fields[0], fields[3]
+		if last := fields[len(fields)-1]; strings.Contains(last, "/") {
+			_, process, _ = strings.Cut(last, "/")
+		}
+	default:
+		return Listener{}, false
+	}
+	proto = strings.TrimSuffix(proto, "6")
+	if proto != "tcp" && proto != "udp" {
+		return Listener{}, false
+	}
+
+	separator := strings.LastIndex(local, ":")
+	if separator < 0 {
+		return Listener{}, false
+	}
+	port, err := strconv.Atoi(local[separator+1:])
+	if err != nil {
+		return Listener{}, false
+	}
+	address := strings.Trim(local[:separator], "[]")
+	if address == "" {
+		address = "::"
+	}
+	return Listener{Protocol: proto, Address: address, Port: port, Process: process}, true
+}
+
+// parseUFW reads ufw status verbose
+func parseUFW(status *FirewallStatus, lines []string) {
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "Status:"):
+			status.Active = strings.TrimSpace(strings.TrimPrefix(trimmed, "Status:")) == "active"
+		case strings.HasPrefix(trimmed, "Default:"):
+			// Default: deny (incoming), allow (outgoing), disabled (routed)
+			for _, policy := range strings.Split(strings.TrimPrefix(trimmed, "Default:"), ",") {
+				if value, ok := strings.CutSuffix(strings.TrimSpace(policy), " (incoming)"); ok {
+					status.DefaultIncoming = value
+				}
+			}
+		case strings.Contains(trimmed, " ALLOW") || strings.Contains(trimmed, " DENY") ||
+			strings.Contains(trimmed, " REJECT") || strings.Contains(trimmed, " LIMIT"):
+			status.Rules = append(status.Rules, trimmed)
+		}
+	}
+}
+
+// parseFirewalld reads firewall-cmd --list-all, keeping the services and
+// ports of the default zone as rules
+func parseFirewalld(status *FirewallStatus, lines []string) {
+	status.Active = true
+	status.DefaultIncoming = "reject"
+	for _, line := range lines {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "target":
+			if target := strings.TrimSpace(value); target == "ACCEPT" || target == "DROP" {
+				status.DefaultIncoming = map[string]string{"ACCEPT": "allow", "DROP": "drop"}[target]
+			}
+		case "services", "ports":
+			for _, item := range strings.Fields(value) {
+				status.Rules = append(status.Rules, name+" "+item)
+			}
+		case "rich rules":
+			if rule := strings.TrimSpace(value); rule != "" {
+				status.Rules = append(status.Rules, "rich "+rule)
+			}
+		}
+	}
+}
+
+// parseIptables reads iptables -S INPUT
+func parseIptables(status *FirewallStatus, lines []string) {
+	status.Active = true
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "-P INPUT "):
+			status.DefaultIncoming = iptablesVerdict(strings.TrimPrefix(line, "-P INPUT "))
+		case strings.HasPrefix(line, "-A INPUT "):
+			status.Rules = append(status.Rules, line)
+		}
+	}
+	// An empty chain accepting everything filters nothing
+	if status.DefaultIncoming == "allow" && len(status.Rules) == 0 {
+		status.Active = false
+	}
+}
+
+// iptablesVerdict maps an iptables target to the ufw policy names
+func iptablesVerdict(target string) string {
+	switch target {
+	case "ACCEPT":
+		return "allow"
+	case "DROP":
+		return "drop"
+	case "REJECT":
+		return "reject"
+	}
+	return ""
+}
+
+// CheckPort tells whether port/protocol is listening and let through the
+// firewall described by report
+func (r *PortReport) CheckPort(port int, protocol string) *PortStatus {
+	status := &PortStatus{Port: port, Protocol: protocol}
+	for _, listener := range r.Listeners {
+		if listener.Port == port && listener.Protocol == protocol {
+			status.Listening = true
+			status.Listeners = append(status.Listeners, listener)
+		}
+	}
+	status.Firewall = r.Firewall.verdict(port, protocol)
+	status.Open = status.Listening && status.Firewall != PortBlocked
+	return status
+}
+
+// verdict evaluates the firewall rules for port/protocol
+func (f FirewallStatus) verdict(port int, protocol string) string {
+	if !f.Active {
+		return PortAllowed
+	}
+	policy := func(value string) string {
+		switch value {
+		case "allow":
+			return PortAllowed
+		case "deny", "drop", "reject":
+			return PortBlocked
+		}
+		return PortUnknown
+	}
+
+	switch f.Tool {
+	case FirewallUFW:
+		for _, rule := range f.Rules {
+			fields := strings.Fields(rule)
+			if len(fields) < 2 || !ufwRuleMatches(fields[0], port, protocol) || strings.Contains(rule, "(out)") || strings.Contains(rule, " OUT") {
+				continue
+			}
+			if fields[1] == "ALLOW" || fields[1] == "LIMIT" {
+				return PortAllowed
+			}
+			return PortBlocked
+		}
+	case FirewallFirewalld:
+		want := strconv.Itoa(port) + "/" + protocol
+		for _, rule := range f.Rules {
+			kind, item, _ := strings.Cut(rule, " ")
+			if (kind == "ports" && portRangeMatches(item, port, protocol)) ||
+				(kind == "services" && firewalldServicePorts[item] == want) {
+				return PortAllowed
+			}
+			if kind == "rich" {
+				return PortUnknown
+			}
+		}
+	case FirewallIptables:
+		for _, rule := range f.Rules {
+			switch matched, target := iptablesRuleMatches(rule, port, protocol); {
+			case !matched:
+			case target == "ACCEPT":
+				return PortAllowed
+			case target == "DROP" || target == "REJECT":
+				return PortBlocked
+			default:
+				return PortUnknown
+			}
+		}
+	}
+	return policy(f.DefaultIncoming)
+}
+
+// ufwRuleMatches reports whether a ufw rule target (22, 22/tcp, 6000:6007/tcp
+// or an application name) covers port/protocol
+func ufwRuleMatches(target string, port int, protocol string) bool {
+	ports, proto, hasProto := strings.Cut(target, "/")
+	if hasProto && proto != protocol {
+		return false
+	}
+	for _, item := range strings.Split(ports, ",") {
+		if portRangeMatches(item, port, "") {
+			return true
+		}
+	}
+	return false
+}
+
+// portRangeMatches reports whether item (80, 80/tcp, 1000-2000/udp or
+// 1000:2000) covers port and, when item names one, protocol
+func portRangeMatches(item string, port int, protocol string) bool {
+	ports, proto, hasProto := strings.Cut(item, "/")
+	if hasProto && protocol != "" && proto != protocol {
+		return false
+	}
+	low, high, isRange := strings.Cut(strings.ReplaceAll(ports, ":", "-"), "-")
+	first, err := strconv.Atoi(low)
+	if err != nil {
+		return false
+	}
+	last := first
+	if isRange {
+		if last, err = strconv.Atoi(high); err != nil {
+			return false
+		}
+	}
+	return port >= first && port <= last
+}
+
+// iptablesRuleMatches reports whether an iptables -S rule applies to all
+// incoming port/protocol traffic and returns its target. Rules limited to
+// an interface, a source or a connection state do not count.
+func iptablesRuleMatches(rule string, port int, protocol string) (bool, string) {
+	fields := strings.Fields(rule)
+	target := ""
+	var ruleProto string
+	portMatched, hasPort, restricted := false, false, false
+	for i := 2; i < len(fields); i++ {
+		value := ""
+		if i+1 < len(fields) {
+			value = fields[i+1]
+		}
+		switch fields[i] {
+		case "-j":
+			target = value
+			i++
+		case "-p":
+			ruleProto = value
+			i++
+		case "-m":
+			i++
+		case "--dport", "--dports":
+			hasPort = true
+			for _, item := range strings.Split(value, ",") {
+				if portRangeMatches(item, port, "") {
+					portMatched = true
+				}
+			}
+			i++
+		default:
+			restricted = true
+		}
+	}
+	if ruleProto != "" && ruleProto != protocol {
+		return false, ""
+	}
+	if hasPort {
+		return portMatched && !restricted, target
+	}
+	return !restricted, target
+}
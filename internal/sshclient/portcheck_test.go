@@ -0,0 +1,143 @@
+package sshclient
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortCheckCommand(t *testing.T) {
+	command := PortCheckCommand()
+	assert.False(t, Explain(command, nil).Flagged, "%s must pass the safety check", command)
+	assert.Contains(t, command, "ss -tulnp")
+	assert.Contains(t, command, "iptables -S INPUT")
+	assert.NotContains(t, command, "iptables -A")
+
+	// Whatever the local host has, the output parses
+	output, err := exec.Command("sh", "-c", command).Output()
+	require.NoError(t, err)
+	report := ParsePortCheck(string(output))
+	assert.NotEmpty(t, report.Firewall.Tool)
+}
+
+func TestParsePortCheck_SSAndUFW(t *testing.T) {
+	output := `@sshx-ports:ss
+Netid State  Recv-Q Send-Q Local Address:Port  Peer Address:Port Process
+udp   UNCONN 0      0          127.0.0.53%lo:53         0.0.0.0:*     users:(("systemd-resolve",pid=512,fd=13))
+tcp   LISTEN 0      128          0.0.0.0:22         0.0.0.0:*     users:(("sshd",pid=801,fd=3))
+tcp   LISTEN 0      511             [::]:443           [::]:*     users:(("nginx",pid=900,fd=7))
+tcp   LISTEN 0      128        127.0.0.1:5432       0.0.0.0:*
+@sshx-ports:ufw
+Status: active
+Logging: on (low)
+Default: deny (incoming), allow (outgoing), disabled (routed)
+New profiles: skip
+
+To                         Action      From
+--                         ------      ----
+22/tcp                     ALLOW IN    Anywhere
+443                        ALLOW IN    Anywhere
+6000:6007/tcp              DENY IN     Anywhere
+22/tcp (v6)                ALLOW IN    Anywhere (v6)
+`
+	report := ParsePortCheck(output)
+	require.Len(t, report.Listeners, 4)
+	assert.Equal(t, Listener{Protocol: "udp", Address: "127.0.0.53%lo", Port: 53, Process: "systemd-resolve"}, report.Listeners[0])
+	assert.Equal(t, Listener{Protocol: "tcp", Address: "::", Port: 443, Process: "nginx"}, report.Listeners[2])
+	assert.Equal(t, Listener{Protocol: "tcp", Address: "127.0.0.1", Port: 5432}, report.Listeners[3])
+
+	assert.Equal(t, FirewallUFW, report.Firewall.Tool)
+	assert.True(t, report.Firewall.Active)
+	assert.Equal(t, "deny", report.Firewall.DefaultIncoming)
+	assert.Len(t, report.Firewall.Rules, 4)
+
+	status := report.CheckPort(443, "tcp")
+	assert.True(t, status.Listening)
+	assert.Equal(t, PortAllowed, status.Firewall)
+	assert.True(t, status.Open)
+
+	status = report.CheckPort(5432, "tcp")
+	assert.True(t, status.Listening)
+	assert.Equal(t, PortBlocked, status.Firewall, "the default policy applies")
+	assert.False(t, status.Open)
+
+	status = report.CheckPort(6001, "tcp")
+	assert.False(t, status.Listening)
+	assert.Equal(t, PortBlocked, status.Firewall)
+
+	assert.Equal(t, PortBlocked, report.CheckPort(22, "udp").Firewall)
+}
+
+func TestParsePortCheck_NetstatAndIptables(t *testing.T) {
+	output := `@sshx-ports:netstat
+Active Internet connections (only servers)
+Proto Recv-Q Send-Q Local Address           Foreign Address         State       PID/Program name
+tcp        0      0 0.0.0.0:22              0.0.0.0:*               LISTEN      801/sshd
+tcp6       0      0 :::8080                 :::*                    LISTEN      -
+udp        0      0 0.0.0.0:68              0.0.0.0:*                           -
+@sshx-ports:iptables
+-P INPUT DROP
+-A INPUT -i lo -j ACCEPT
+-A INPUT -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 22 -j ACCEPT
+-A INPUT -p tcp -m multiport --dports 80,443,8000:8100 -j ACCEPT
+-A INPUT -p udp -j f2b-custom
+`
+	report := ParsePortCheck(output)
+	require.Len(t, report.Listeners, 3)
+	assert.Equal(t, Listener{Protocol: "tcp", Address: "0.0.0.0", Port: 22, Process: "sshd"}, report.Listeners[0])
+	assert.Equal(t, Listener{Protocol: "tcp", Address: "::", Port: 8080}, report.Listeners[1])
+	assert.Equal(t, Listener{Protocol: "udp", Address: "0.0.0.0", Port: 68}, report.Listeners[2])
+
+	assert.Equal(t, FirewallIptables, report.Firewall.Tool)
+	assert.True(t, report.Firewall.Active)
+	assert.Equal(t, "drop", report.Firewall.DefaultIncoming)
+
+	assert.True(t, report.CheckPort(22, "tcp").Open)
+	status := report.CheckPort(8080, "tcp")
+	assert.Equal(t, PortAllowed, status.Firewall, "8080 is in the multiport range")
+	assert.True(t, status.Open)
+	assert.Equal(t, PortBlocked, report.CheckPort(3306, "tcp").Firewall)
+	assert.Equal(t, PortUnknown, report.CheckPort(68, "udp").Firewall, "jumps to other chains are not evaluated")
+	assert.True(t, report.CheckPort(68, "udp").Open)
+}
+
+func TestParsePortCheck_Firewalld(t *testing.T) {
+	output := `@sshx-ports:ss
+tcp   LISTEN 0      128          0.0.0.0:3306       0.0.0.0:*
+@sshx-ports:firewalld
+public (active)
+  target: default
+  interfaces: eth0
+  services: dhcpv6-client ssh https
+  ports: 8443/tcp 9000-9100/udp
+  rich rules:
+`
+	report := ParsePortCheck(output)
+	assert.Equal(t, FirewallFirewalld, report.Firewall.Tool)
+	assert.Equal(t, []string{"services dhcpv6-client", "services ssh", "services https", "ports 8443/tcp", "ports 9000-9100/udp"}, report.Firewall.Rules)
+
+	assert.Equal(t, PortAllowed, report.CheckPort(443, "tcp").Firewall)
+	assert.Equal(t, PortAllowed, report.CheckPort(9050, "udp").Firewall)
+	assert.Equal(t, PortBlocked, report.CheckPort(9050, "tcp").Firewall)
+	status := report.CheckPort(3306, "tcp")
+	assert.True(t, status.Listening)
+	assert.False(t, status.Open)
+}
+
+func TestParsePortCheck_NoFirewall(t *testing.T) {
+	report := ParsePortCheck("@sshx-ports:none\n")
+	assert.Equal(t, FirewallNone, report.Firewall.Tool)
+	assert.False(t, report.Firewall.Active)
+	assert.Empty(t, report.Listeners)
+
+	status := report.CheckPort(80, "tcp")
+	assert.Equal(t, PortAllowed, status.Firewall)
+	assert.False(t, status.Open, "nothing listens")
+
+	// An accept-all iptables chain without rules filters nothing
+	report = ParsePortCheck("@sshx-ports:iptables\n-P INPUT ACCEPT\n")
+	assert.False(t, report.Firewall.Active)
+}
@@ -0,0 +1,313 @@
+package sshclient
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Signals SignalProcess sends, without the SIG prefix
+var Signals = []string{"TERM", "KILL", "HUP", "INT", "QUIT", "USR1", "USR2"}
+
+// terminatingSignals are the signals SignalProcess waits for the process
+// to exit after
+var terminatingSignals = []string{"TERM", "KILL", "INT", "QUIT"}
+
+// signalExitWait is how many seconds SignalProcessCommand waits for the
+// process to exit after a terminating signal
+const signalExitWait = 5
+
+// Sort orders of FilterProcesses
+const (
+	ProcessSortCPU    = "cpu"
+	ProcessSortMemory = "memory"
+	ProcessSortPID    = "pid"
+)
+
+// ProcessSorts lists the orders FilterProcesses understands
+var ProcessSorts = []string{ProcessSortCPU, ProcessSortMemory, ProcessSortPID}
+
+// ProtectedProcesses are the process names SignalProcess refuses to signal
+// by default: losing them cuts off SSH access or takes the host down.
+// Entries may be path.Match patterns.
+var ProtectedProcesses = []string{
+	"sshd", "systemd", "init", "systemd-journal", "systemd-logind", "systemd-udevd",
+	"dbus-daemon", "dbus-broker",
+}
+
+// processMarker prefixes the lines of ProcessListCommand that start a section
+const processMarker = "@sshx-ps:"
+
+// Process is a process running on a host
+type Process struct {
+	PID           int     `json:"pid"`
+	PPID          int     `json:"ppid"`
+	User          string  `json:"user"`
+	State         string  `json:"state"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryPercent float64 `json:"memory_percent"`
+	RSS           uint64  `json:"rss_bytes"`
+	Elapsed       int64   `json:"elapsed_seconds"`
+	Name          string  `json:"name"` // Command name, as pgrep matches it
+	Command       string  `json:"command"`
+}
+
+// ProcessListCommand lists the processes of the host, or only pid when it
+// is not 0. The command names come from a separate ps run because they may
+// contain spaces, which only the last column can.
+func ProcessListCommand(pid int) string {
+	selector := "-e"
+	if pid > 0 {
+		selector = "-p " + strconv.Itoa(pid)
+	}
+	return strings.Join([]string{
+		"echo " + ShellQuote(processMarker+"comm"),
+		"ps " + selector + " -o pid=,comm= || true",
+		"echo " + ShellQuote(processMarker+"ps"),
+		"ps " + selector + " -o pid=,ppid=,user:32=,stat=,pcpu=,pmem=,rss=,etimes=,args= || true",
+	}, "\n")
+}
+
+// ParseProcesses reads the output of ProcessListCommand
+func ParseProcesses(output string) []Process {
+	names := map[int]string{}
+	processes := []Process{}
+	section := ""
+	for _, line := range splitLines(output) {
+		line = strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(line, processMarker); ok {
+			section = name
+			continue
+		}
+		switch section {
+		case "comm":
+			pid, name, ok := strings.Cut(line, " ")
+			if id, err := strconv.Atoi(pid); ok && err == nil {
+				names[id] = strings.TrimSpace(name)
+			}
+		case "ps":
+			if process, ok := parseProcess(line); ok {
+				processes = append(processes, process)
+			}
+		}
+	}
+
+	for i := range processes {
+		if name, ok := names[processes[i].PID]; ok {
+			processes[i].Name = name
+		} else {
+			// Started between the two ps runs
+			processes[i].Name = path.Base(strings.Fields(processes[i].Command + " ?")[0])
+		}
+	}
+	return processes
+}
+
+// parseProcess reads one line of the ps section
+func parseProcess(line string) (Process, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 9 {
+		return Process{}, false
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return Process{}, false
+	}
+	process := Process{PID: pid, User: fields[2], State: fields[3]}
+	process.PPID, _ = strconv.Atoi(fields[1])                    //nolint:errcheck // 0 when malformed
+	process.CPUPercent, _ = strconv.ParseFloat(fields[4], 64)    //nolint:errcheck // 0 when malformed
+	process.MemoryPercent, _ = strconv.ParseFloat(fields[5], 64) //nolint:errcheck // 0 when malformed
+	rss, _ := strconv.ParseUint(fields[6], 10, 64)               //nolint:errcheck // 0 when malformed
+	process.Elapsed, _ = strconv.ParseInt(fields[7], 10, 64)     //nolint:errcheck // 0 when malformed
+	process.RSS = rss * 1024
+	// args keeps its own spacing
+	rest := line
+	for range 8 {
+		rest = strings.TrimLeft(rest, " \t")
+		rest = rest[strings.IndexAny(rest, " \t"):]
+	}
+	process.Command = strings.TrimSpace(rest)
+	return process, true
+}
+
+// ProcessFilter selects and orders processes for process_list
+type ProcessFilter struct {
+	Name  string // Regular expression matched against the name and command line
+	User  string // Owner
+	Sort  string // One of ProcessSorts (empty = cpu)
+	Limit int    // Maximum number of processes (0 = all)
+}
+
+// FilterProcesses returns the processes matching filter in its order, and
+// how many matched before the limit
+func FilterProcesses(processes []Process, filter ProcessFilter) ([]Process, int, error) {
+	var pattern *regexp.Regexp
+	if filter.Name != "" {
+		var err error
+		if pattern, err = regexp.Compile(filter.Name); err != nil {
+			return nil, 0, fmt.Errorf("invalid name pattern: %w", err)
+		}
+	}
+	if filter.Sort != "" && !slices.Contains(ProcessSorts, filter.Sort) {
+		return nil, 0, fmt.Errorf("unknown sort '%s' (use %s)", filter.Sort, strings.Join(ProcessSorts, ", "))
+	}
+
+	matched := []Process{}
+	for _, process := range processes {
+		if filter.User != "" && process.User != filter.User {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(process.Name) && !pattern.MatchString(process.Command) {
+			continue
+		}
+		matched = append(matched, process)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		switch filter.Sort {
+		case ProcessSortMemory:
+			return matched[i].RSS > matched[j].RSS
+		case ProcessSortPID:
+			return matched[i].PID < matched[j].PID
+		}
+		return matched[i].CPUPercent > matched[j].CPUPercent
+	})
+	total := len(matched)
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total, nil
+}
+
+// SignalOptions describes a process_signal request
+type SignalOptions struct {
+	PID    int
+	Signal string // One of Signals, with or without the SIG prefix (empty = TERM)
+	// ExpectName must be the name of the process to send the signal: it
+	// confirms the caller looked at the process and guards against PID reuse
+	ExpectName string
+	// DryRun only looks the process up and reports what would be signaled
+	DryRun bool
+	// Protected are the process names refused (nil = ProtectedProcesses)
+	Protected []string
+}
+
+// SignalResult is the outcome of SignalProcess
+type SignalResult struct {
+	Process Process `json:"process"`
+	Signal  string  `json:"signal"`
+	DryRun  bool    `json:"dry_run"`
+	Command string  `json:"command,omitempty"`
+	// State is exited or running after a terminating signal, and empty
+	// otherwise
+	State string `json:"state,omitempty"`
+}
+
+// Validate checks the PID and signal and normalizes the signal name
+func (o *SignalOptions) Validate() error {
+	if o.PID < 1 {
+		return fmt.Errorf("pid must be a positive number")
+	}
+	o.Signal = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(o.Signal)), "SIG")
+	if o.Signal == "" {
+		o.Signal = "TERM"
+	}
+	if !slices.Contains(Signals, o.Signal) {
+		return fmt.Errorf("unsupported signal '%s' (use %s)", o.Signal, strings.Join(Signals, ", "))
+	}
+	if !o.DryRun && o.ExpectName == "" {
+		return fmt.Errorf("expect_name is required to send a signal: run with dry_run first and pass the process name it reports")
+	}
+	return nil
+}
+
+// ProtectedProcess returns why process must not be signaled, or "" when it
+// may be: PID 1, kernel threads and the names in protected
+// (ProtectedProcesses when nil)
+func ProtectedProcess(process Process, protected []string) string {
+	if process.PID == 1 {
+		return "PID 1 is the init process"
+	}
+	if process.PID == 2 || process.PPID == 2 {
+		return "kernel threads cannot be signaled"
+	}
+	if protected == nil {
+		protected = ProtectedProcesses
+	}
+	for _, pattern := range protected {
+		if matched, err := path.Match(pattern, process.Name); err == nil && matched {
+			return fmt.Sprintf("'%s' is protected (%s)", process.Name, pattern)
+		}
+	}
+	return ""
+}
+
+// SignalProcessCommand sends signal to pid if it is still named name, then
+// waits up to signalExitWait seconds for a terminating signal to end it
+func SignalProcessCommand(pid int, signal, name string) string {
+	id := strconv.Itoa(pid)
+	lines := []string{
+		`if [ "$(ps -o comm= -p ` + id + `)" != ` + ShellQuote(name) + ` ]; then echo ` + ShellQuote("process "+id+" is no longer "+name) + ` >&2; exit 3; fi`,
+		"kill -s " + signal + " " + id,
+	}
+	if slices.Contains(terminatingSignals, signal) {
+		lines = append(lines,
+			"i=0",
+			fmt.Sprintf("while [ $i -lt %d ]; do", signalExitWait),
+			"  if ! kill -0 "+id+" 2>/dev/null; then echo exited; exit 0; fi",
+			"  sleep 1",
+			"  i=$((i + 1))",
+			"done",
+			"echo running")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SignalProcess looks up opts.PID and, unless it is protected or a dry run,
+// sends it opts.Signal. The process has to have the name opts.ExpectName,
+// which is checked again on the host right before the signal. Both steps
+// run through Execute, so they are validated and audited.
+func (c *SSHClient) SignalProcess(opts SignalOptions, req Request) (*SignalResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if isPowerShell(c.config.Shell) {
+		return nil, fmt.Errorf("process signals are not supported with shell %s", c.config.Shell)
+	}
+
+	lookup := req
+	lookup.Command = ProcessListCommand(opts.PID)
+	listed, err := c.Execute(lookup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up process %d: %w", opts.PID, err)
+	}
+	processes := ParseProcesses(listed.Output)
+	if len(processes) == 0 {
+		return nil, fmt.Errorf("no process with PID %d", opts.PID)
+	}
+	result := &SignalResult{Process: processes[0], Signal: opts.Signal, DryRun: opts.DryRun}
+	if reason := ProtectedProcess(result.Process, opts.Protected); reason != "" {
+		return nil, fmt.Errorf("refusing to signal process %d: %s", opts.PID, reason)
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+	if opts.ExpectName != result.Process.Name {
+		return nil, fmt.Errorf("process %d is '%s', not '%s'", opts.PID, result.Process.Name, opts.ExpectName)
+	}
+
+	req.Command = SignalProcessCommand(opts.PID, opts.Signal, result.Process.Name)
+	result.Command = req.Command
+	signaled, err := c.Execute(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send SIG%s to process %d: %w", opts.Signal, opts.PID, err)
+	}
+	if slices.Contains(terminatingSignals, opts.Signal) {
+		result.State = lastLine(signaled.Output)
+	}
+	return result, nil
+}
@@ -0,0 +1,165 @@
+package sshclient
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+const processListOutput = `@sshx-ps:comm
+    1 systemd
+    2 kthreadd
+   15 kworker/0:1
+  812 sshd
+ 4242 Web Content
+ 5001 celery
+@sshx-ps:ps
+    1     0 root                             Ss    0.0  0.1  12000 864000 /sbin/init splash
+    2     0 root                             S     0.0  0.0      0 864000 [kthreadd]
+   15     2 root                             I     0.0  0.0      0 100 [kworker/0:1]
+  812     1 root                             Ss    0.0  0.1   8000 864000 sshd: /usr/sbin/sshd -D [listener]
+ 4242     1 alice                            Sl   12.5  4.0 400000 3600 /usr/lib/firefox/firefox -contentproc  -childID 1
+ 5001     1 app                              R    97.0  2.5 250000 120 /usr/bin/python3 -m celery worker
+ 6000     1 app                              S     1.0  0.5  50000 5 /usr/bin/python3 -m celery beat
+`
+
+func TestParseProcesses(t *testing.T) {
+	processes := ParseProcesses(processListOutput)
+	require.Len(t, processes, 7)
+	assert.Equal(t, Process{
+		PID: 4242, PPID: 1, User: "alice", State: "Sl", CPUPercent: 12.5, MemoryPercent: 4,
+		RSS: 400000 * 1024, Elapsed: 3600, Name: "Web Content", Command: "/usr/lib/firefox/firefox -contentproc  -childID 1",
+	}, processes[4])
+	assert.Equal(t, "sshd", processes[3].Name)
+	assert.Equal(t, "python3", processes[6].Name, "started after the comm list")
+	assert.Empty(t, ParseProcesses(""))
+}
+
+func TestFilterProcesses(t *testing.T) {
+	processes := ParseProcesses(processListOutput)
+
+	matched, total, err := FilterProcesses(processes, ProcessFilter{Name: "celery"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []int{5001, 6000}, pids(matched), "sorted by CPU")
+
+	matched, total, err = FilterProcesses(processes, ProcessFilter{User: "root", Sort: ProcessSortMemory, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Equal(t, []int{1, 812}, pids(matched))
+
+	matched, _, err = FilterProcesses(processes, ProcessFilter{Name: "^kworker", Sort: ProcessSortPID})
+	require.NoError(t, err)
+	assert.Equal(t, []int{15}, pids(matched))
+
+	_, _, err = FilterProcesses(processes, ProcessFilter{Name: "("})
+	assert.ErrorContains(t, err, "invalid name pattern")
+	_, _, err = FilterProcesses(processes, ProcessFilter{Sort: "name"})
+	assert.ErrorContains(t, err, "unknown sort 'name'")
+}
+
+func pids(processes []Process) []int {
+	var ids []int
+	for _, process := range processes {
+		ids = append(ids, process.PID)
+	}
+	return ids
+}
+
+func TestSignalOptions_Validate(t *testing.T) {
+	opts := SignalOptions{PID: 42, Signal: "sigkill", DryRun: true}
+	require.NoError(t, opts.Validate())
+	assert.Equal(t, "KILL", opts.Signal)
+
+	opts = SignalOptions{PID: 42, DryRun: true}
+	require.NoError(t, opts.Validate())
+	assert.Equal(t, "TERM", opts.Signal)
+
+	assert.ErrorContains(t, (&SignalOptions{PID: 0, DryRun: true}).Validate(), "pid must be a positive number")
+	assert.ErrorContains(t, (&SignalOptions{PID: 42, Signal: "STOP", DryRun: true}).Validate(), "unsupported signal 'STOP'")
+	assert.ErrorContains(t, (&SignalOptions{PID: 42}).Validate(), "expect_name is required")
+}
+
+func TestProtectedProcess(t *testing.T) {
+	processes := ParseProcesses(processListOutput)
+	assert.Contains(t, ProtectedProcess(processes[0], nil), "PID 1")
+	assert.Contains(t, ProtectedProcess(processes[1], nil), "kernel threads")
+	assert.Contains(t, ProtectedProcess(processes[2], nil), "kernel threads")
+	assert.Equal(t, "'sshd' is protected (sshd)", ProtectedProcess(processes[3], nil))
+	assert.Empty(t, ProtectedProcess(processes[5], nil))
+	assert.Equal(t, "'celery' is protected (cel*)", ProtectedProcess(processes[5], []string{"cel*"}))
+}
+
+func TestProcessCommands(t *testing.T) {
+	for _, command := range []string{ProcessListCommand(0), ProcessListCommand(42), SignalProcessCommand(42, "KILL", "my worker")} {
+		assert.False(t, Explain(command, nil).Flagged, "%s must pass the safety check", command)
+	}
+	assert.Contains(t, ProcessListCommand(42), "ps -p 42 -o pid=,comm=")
+	assert.Contains(t, SignalProcessCommand(42, "KILL", "my worker"), `!= 'my worker' ]`)
+	assert.NotContains(t, SignalProcessCommand(42, "HUP", "nginx"), "kill -0", "HUP does not wait for the process to exit")
+}
+
+// shellExecHandler runs each command with sh and returns its exit status
+func shellExecHandler(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+	cmd := exec.Command("sh", "-c", command) // #nosec G204 -- test command
+	cmd.Stdout = channel
+	cmd.Stderr = channel.Stderr()
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); errors.As(err, &exitErr) {
+		return uint32(exitErr.ExitCode()) // #nosec G115 -- exit codes are small
+	} else if err != nil {
+		return 127
+	}
+	return 0
+}
+
+func TestSSHClient_SignalProcess(t *testing.T) {
+	if _, err := exec.Command("sh", "-c", "ps -p 1 -o pid=,comm=").Output(); err != nil {
+		t.Skip("procps ps not available")
+	}
+	sleeper := exec.Command("sleep", "60")
+	require.NoError(t, sleeper.Start())
+	done := make(chan struct{})
+	go func() {
+		_ = sleeper.Wait()
+		close(done)
+	}()
+	t.Cleanup(func() { _ = sleeper.Process.Kill() })
+	pid := sleeper.Process.Pid
+
+	conn := startExecServer(t, shellExecHandler)
+	client := &SSHClient{config: &Config{Host: "10.0.0.5", User: "deploy"}, client: conn}
+
+	// A dry run only reports the process
+	result, err := client.SignalProcess(SignalOptions{PID: pid, DryRun: true}, Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "sleep", result.Process.Name)
+	assert.Equal(t, "sleep 60", result.Process.Command)
+	assert.True(t, result.DryRun)
+	assert.Empty(t, result.Command)
+
+	_, err = client.SignalProcess(SignalOptions{PID: pid, ExpectName: "nginx"}, Request{})
+	assert.ErrorContains(t, err, "process "+strconv.Itoa(pid)+" is 'sleep', not 'nginx'")
+
+	result, err = client.SignalProcess(SignalOptions{PID: pid, ExpectName: "sleep"}, Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "TERM", result.Signal)
+	assert.Equal(t, "exited", result.State)
+	<-done
+
+	_, err = client.SignalProcess(SignalOptions{PID: pid, DryRun: true}, Request{})
+	assert.ErrorContains(t, err, "no process with PID")
+	_, err = client.SignalProcess(SignalOptions{PID: 1, DryRun: true}, Request{})
+	assert.ErrorContains(t, err, "refusing to signal process 1")
+}
+
+func TestSignalProcessCommand_NameChanged(t *testing.T) {
+	output, err := exec.Command("sh", "-c", SignalProcessCommand(1, "TERM", "not-init")).CombinedOutput() // #nosec G204 -- test command
+	require.Error(t, err)
+	assert.Contains(t, string(output), "process 1 is no longer not-init")
+}
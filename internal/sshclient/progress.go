@@ -0,0 +1,120 @@
+package sshclient
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// progressMinStep is the smallest transfer step reported, so small
+	// files do not produce a report for every write
+	progressMinStep = 64 << 10
+	// progressUnknownStep is the reporting step of transfers of unknown size
+	progressUnknownStep = 1 << 20
+	// progressLineInterval throttles reports of script output lines
+	progressLineInterval = time.Second
+	// progressMaxLine caps the script output line reported as the item
+	progressMaxLine = 200
+)
+
+// ProgressFunc receives the progress of a long-running operation: done out
+// of total units (bytes for transfers, hosts for multi-host operations,
+// steps for scripts; total is 0 when unknown) and the item being worked on.
+// done increases with every call. Multi-host operations call it from several goroutines, one call at a time.
+type ProgressFunc func(done, total int64, item string)
+
+// progressWriter reports the bytes written through it in steps of about
+// one percent of total
+type progressWriter struct {
+	w      io.Writer
+	report ProgressFunc
+	item   string
+	total  int64
+	done   int64
+	next   int64
+	step   int64
+}
+
+// newProgressWriter wraps w so writes to it are reported as progress on item
+func newProgressWriter(w io.Writer, report ProgressFunc, total int64, item string) *progressWriter {
+	step := int64(progressUnknownStep)
+	if total > 0 {
+		step = total / 100
+		if step < progressMinStep {
+			step = progressMinStep
+		}
+	}
+	report(0, total, item)
+	return &progressWriter{w: w, report: report, item: item, total: total, next: step, step: step}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if n > 0 && (p.done >= p.next || p.done == p.total) {
+		p.report(p.done, p.total, p.item)
+		p.next = p.done + p.step
+	}
+	return n, err
+}
+
+// progressLineWriter reports the latest line of script output as the
+// current item, at most once per progressLineInterval
+type progressLineWriter struct {
+	report func(item string)
+	last   time.Time
+	line   strings.Builder
+}
+
+func (p *progressLineWriter) Write(b []byte) (int, error) {
+	for _, c := range string(b) {
+		if c == '\n' || c == '\r' {
+			p.flush()
+			continue
+		}
+		if p.line.Len() < progressMaxLine {
+			p.line.WriteRune(c)
+		}
+	}
+	return len(b), nil
+}
+
+// flush reports the completed line unless a line was reported recently
+func (p *progressLineWriter) flush() {
+	line := strings.TrimSpace(p.line.String())
+	p.line.Reset()
+	if line == "" || time.Since(p.last) < progressLineInterval {
+		return
+	}
+	p.last = time.Now()
+	p.report(line)
+}
+
+// hostProgress counts finished hosts of a multi-host operation and reports
+// each one as it completes
+type hostProgress struct {
+	mu     sync.Mutex
+	report ProgressFunc
+	total  int64
+	done   int64
+}
+
+// finished records that config is done
+func (h *hostProgress) finished(config *Config) {
+	if h == nil || h.report == nil {
+		return
+	}
+	item := ""
+	if config != nil {
+		item = config.Alias
+		if item == "" {
+			item = config.Host
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.done++
+	h.report(h.done, h.total, item)
+}
@@ -0,0 +1,75 @@
+package sshclient
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type progressReport struct {
+	done, total int64
+	item        string
+}
+
+func TestProgressWriter(t *testing.T) {
+	var reports []progressReport
+	record := func(done, total int64, item string) {
+		reports = append(reports, progressReport{done, total, item})
+	}
+
+	var buf bytes.Buffer
+	const size = 1 << 20
+	w := newProgressWriter(&buf, record, size, "/srv/app.tar")
+	chunk := make([]byte, 4096)
+	for written := 0; written < size; written += len(chunk) {
+		_, err := w.Write(chunk)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, size, buf.Len())
+	require.NotEmpty(t, reports)
+	assert.Equal(t, progressReport{0, size, "/srv/app.tar"}, reports[0])
+	assert.Equal(t, progressReport{size, size, "/srv/app.tar"}, reports[len(reports)-1])
+	assert.LessOrEqual(t, len(reports), 20, "small transfers are reported in steps of at least 64K")
+	for i := 1; i < len(reports); i++ {
+		assert.Greater(t, reports[i].done, reports[i-1].done)
+	}
+}
+
+func TestProgressLineWriter(t *testing.T) {
+	var items []string
+	w := &progressLineWriter{report: func(item string) { items = append(items, item) }}
+
+	_, err := w.Write([]byte("  step 1\nstep 2\npartial"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"step 1"}, items, "lines within a second of the last report are skipped")
+
+	_, err = w.Write([]byte(strings.Repeat("x", 500) + "\n"))
+	require.NoError(t, err)
+	w.last = w.last.Add(-progressLineInterval)
+	_, err = w.Write([]byte("step 3\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"step 1", "step 3"}, items)
+}
+
+func TestForEachHost_Progress(t *testing.T) {
+	configs := []*Config{{Host: "10.0.0.1", Alias: "web1"}, {Host: "10.0.0.2"}, {Host: "10.0.0.3"}}
+	var reports []progressReport
+	forEachHost(configs, 2, func(done, total int64, item string) {
+		reports = append(reports, progressReport{done, total, item})
+	}, func(int, *Config) {})
+
+	require.Len(t, reports, 3)
+	items := make([]string, len(reports))
+	for i, report := range reports {
+		assert.Equal(t, int64(i+1), report.done)
+		assert.Equal(t, int64(3), report.total)
+		items[i] = report.item
+	}
+	sort.Strings(items)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.3", "web1"}, items)
+}
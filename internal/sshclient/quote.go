@@ -0,0 +1,45 @@
+package sshclient
+
+import "strings"
+
+// ShellQuote quotes s as a single POSIX shell word, whatever characters it
+// holds
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// BuildCommand joins args into a POSIX command line that runs them as given:
+// each argument is one word, quoted when it is empty or holds anything but
+// letters, digits and -_./,:@%+. Use it instead of formatting paths and
+// arguments into a command string.
+func BuildCommand(args ...string) string {
+	words := make([]string, len(args))
+	for i, arg := range args {
+		words[i] = quoteWord(arg)
+	}
+	return strings.Join(words, " ")
+}
+
+// quoteWord leaves words the shell reads literally as they are, so built
+// commands stay readable in logs and audit entries
+func quoteWord(word string) string {
+	if word == "" {
+		return "''"
+	}
+	for _, r := range word {
+		if !isPlainShellChar(r) {
+			return ShellQuote(word)
+		}
+	}
+	return word
+}
+
+// isPlainShellChar reports whether r has no special meaning to the shell
+// anywhere in a word
+func isPlainShellChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("-_./,:@%+", r)
+}
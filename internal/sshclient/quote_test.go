@@ -0,0 +1,30 @@
+package sshclient
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "'/tmp/my dir'", ShellQuote("/tmp/my dir"))
+	assert.Equal(t, `'it'\''s'`, ShellQuote("it's"))
+	assert.Equal(t, "''", ShellQuote(""))
+}
+
+func TestBuildCommand(t *testing.T) {
+	assert.Equal(t, "rm -f -- /tmp/a.sh", BuildCommand("rm", "-f", "--", "/tmp/a.sh"))
+	assert.Equal(t, "mv -- '/tmp/my dir' ''", BuildCommand("mv", "--", "/tmp/my dir", ""))
+	assert.Equal(t, `echo 'it'\''s' '$HOME' '*' '~'`, BuildCommand("echo", "it's", "$HOME", "*", "~"))
+	assert.Equal(t, "", BuildCommand())
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	args := []string{"plain", "", "two words", "it's", `a\b`, "$(id) `id` ;|&", "line1\nline2", "*.go", "-n"}
+	out, err := exec.Command("sh", "-c", BuildCommand(append([]string{"printf", "[%s]"}, args...)...)).Output()
+	require.NoError(t, err)
+	assert.Equal(t, "[plain][][two words][it's][a\\b][$(id) `id` ;|&][line1\nline2][*.go][-n]", string(out))
+}
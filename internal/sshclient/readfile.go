@@ -0,0 +1,190 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/sftp"
+
+	"github.com/talkincode/sshmcp/pkg/errutil"
+)
+
+// FileTooLargeError is returned by ReadFile when the remote file is larger
+// than the limit
+type FileTooLargeError struct {
+	Path  string
+	Size  int64
+	Limit int64
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("%s is %d bytes, more than the limit of %d bytes", e.Path, e.Size, e.Limit)
+}
+
+// ReadFile returns the content of a remote file over SFTP, or a
+// *FileTooLargeError when it holds more than maxBytes
+func (c *SSHClient) ReadFile(remotePath string, maxBytes int64) (data []byte, err error) {
+	op := c.newOperation(Request{})
+	err = op.withSFTP(func() (err error) {
+		if err = op.checkPath(remotePath, false); err != nil {
+			return err
+		}
+		file, err := op.sftpClient.Open(remotePath)
+		if err != nil {
+			return fmt.Errorf("failed to open remote file: %w", err)
+		}
+		defer errutil.HandleCloseError(&err, file)
+
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to stat remote file: %w", err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", remotePath)
+		}
+		data, err = readLimited(file, remotePath, info.Size(), maxBytes)
+		return err
+	})
+	return data, err
+}
+
+// WriteFile atomically replaces a remote file with content over SFTP, giving
+// it the UploadOwnership of the config or keeping the permissions of an
+// existing file, and returns the bytes written
+func (c *SSHClient) WriteFile(remotePath string, content []byte) (written int64, err error) {
+	op := c.newOperation(Request{})
+	err = op.withSFTP(func() error {
+		if err := op.checkPath(remotePath, true); err != nil {
+			return err
+		}
+		if written, err = writeRemoteFile(op.sftpClient, remotePath, content, op.config.UploadBackup, op.config.UploadOwnership.Mode); err != nil {
+			return err
+		}
+		return applyOwnership(op.client, op.sftpClient, op.config, remotePath)
+	})
+	return written, err
+}
+
+// FileRange is a slice of a remote file read by ReadFileRange
+type FileRange struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"` // Where the slice starts
+	Size   int64  `json:"size"`   // Size of the whole file
+	EOF    bool   `json:"eof"`    // The slice reaches the end of the file
+	Data   []byte `json:"-"`
+}
+
+// ReadFileRange reads up to length bytes of a remote file over SFTP,
+// starting at offset. A negative offset counts from the end of the file,
+// so -65536 reads the last 64 KiB; offsets outside the file are clamped.
+func (c *SSHClient) ReadFileRange(remotePath string, offset, length int64) (result FileRange, err error) {
+	if length <= 0 {
+		return FileRange{}, fmt.Errorf("length must be positive")
+	}
+	op := c.newOperation(Request{})
+	err = op.withSFTP(func() error {
+		if err := op.checkPath(remotePath, false); err != nil {
+			return err
+		}
+		result, err = readRemoteRange(op.sftpClient, remotePath, offset, length)
+		return err
+	})
+	return result, err
+}
+
+// AppendFile appends content to a remote file over SFTP, creating it when
+// it does not exist, and returns the new size of the file
+func (c *SSHClient) AppendFile(remotePath string, content []byte) (size int64, err error) {
+	op := c.newOperation(Request{})
+	err = op.withSFTP(func() error {
+		if err := op.checkPath(remotePath, true); err != nil {
+			return err
+		}
+		size, err = appendRemoteFile(op.sftpClient, remotePath, content)
+		return err
+	})
+	return size, err
+}
+
+// readRemoteRange reads the slice of ReadFileRange
+func readRemoteRange(sftpClient *sftp.Client, remotePath string, offset, length int64) (result FileRange, err error) {
+	file, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return FileRange{}, fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer errutil.HandleCloseError(&err, file)
+
+	info, err := file.Stat()
+	if err != nil {
+		return FileRange{}, fmt.Errorf("failed to stat remote file: %w", err)
+	}
+	if info.IsDir() {
+		return FileRange{}, fmt.Errorf("%s is a directory", remotePath)
+	}
+
+	size := info.Size()
+	if offset < 0 {
+		offset = max(size+offset, 0)
+	}
+	offset = min(offset, size)
+	length = min(length, size-offset)
+
+	data := make([]byte, length)
+	n, err := file.ReadAt(data, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return FileRange{}, fmt.Errorf("failed to read remote file: %w", err)
+	}
+	return FileRange{
+		Path:   remotePath,
+		Offset: offset,
+		Size:   size,
+		EOF:    offset+int64(n) >= size,
+		Data:   data[:n],
+	}, nil
+}
+
+// appendRemoteFile appends content with the SFTP append flag, so servers
+// that honour it (OpenSSH) add each write at the end of the file even when
+// another process appends at the same time. The writes also carry the
+// current size as offset for servers that ignore the flag.
+func appendRemoteFile(sftpClient *sftp.Client, remotePath string, content []byte) (size int64, err error) {
+	file, err := sftpClient.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer errutil.HandleCloseError(&err, file)
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat remote file: %w", err)
+	}
+	if info.IsDir() {
+		return 0, fmt.Errorf("%s is a directory", remotePath)
+	}
+	if _, err = file.WriteAt(content, info.Size()); err != nil {
+		return 0, fmt.Errorf("failed to append to remote file: %w", err)
+	}
+
+	if info, err = file.Stat(); err != nil {
+		return 0, fmt.Errorf("failed to stat remote file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// readLimited reads r when its stated size is within maxBytes. The read
+// itself is capped too, since the file may grow after the stat.
+func readLimited(r io.Reader, path string, size, maxBytes int64) ([]byte, error) {
+	if size > maxBytes {
+		return nil, &FileTooLargeError{Path: path, Size: size, Limit: maxBytes}
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote file: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, &FileTooLargeError{Path: path, Size: int64(len(data)), Limit: maxBytes}
+	}
+	return data, nil
+}
@@ -0,0 +1,102 @@
+package sshclient
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLimited(t *testing.T) {
+	data, err := readLimited(strings.NewReader("hello"), "/etc/motd", 5, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	_, err = readLimited(strings.NewReader("hello"), "/etc/motd", 5, 4)
+	var tooLarge *FileTooLargeError
+	require.True(t, errors.As(err, &tooLarge))
+	assert.Equal(t, FileTooLargeError{Path: "/etc/motd", Size: 5, Limit: 4}, *tooLarge)
+	assert.EqualError(t, err, "/etc/motd is 5 bytes, more than the limit of 4 bytes")
+
+	// A file that grew after the stat is still capped
+	_, err = readLimited(strings.NewReader("hello world"), "/var/log/app.log", 3, 5)
+	require.True(t, errors.As(err, &tooLarge))
+	assert.EqualValues(t, 6, tooLarge.Size)
+}
+
+// startLocalSFTP returns an SFTP client served in-process from the local
+// filesystem
+func startLocalSFTP(t *testing.T) *sftp.Client {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	server, err := sftp.NewServer(serverConn)
+	require.NoError(t, err)
+	go func() { _ = server.Serve() }()
+
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client
+}
+
+func TestReadRemoteRange(t *testing.T) {
+	client := startLocalSFTP(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("0123456789"), 0o600))
+
+	result, err := readRemoteRange(client, path, 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "234", string(result.Data))
+	assert.Equal(t, FileRange{Path: path, Offset: 2, Size: 10, Data: result.Data}, result)
+
+	// A negative offset reads the tail
+	result, err = readRemoteRange(client, path, -4, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "6789", string(result.Data))
+	assert.EqualValues(t, 6, result.Offset)
+	assert.True(t, result.EOF)
+
+	// Offsets outside the file are clamped
+	result, err = readRemoteRange(client, path, -100, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "01", string(result.Data))
+	result, err = readRemoteRange(client, path, 50, 2)
+	require.NoError(t, err)
+	assert.Empty(t, result.Data)
+	assert.EqualValues(t, 10, result.Offset)
+	assert.True(t, result.EOF)
+
+	_, err = readRemoteRange(client, filepath.Dir(path), 0, 1)
+	assert.ErrorContains(t, err, "is a directory")
+}
+
+func TestAppendRemoteFile(t *testing.T) {
+	client := startLocalSFTP(t)
+	path := filepath.Join(t.TempDir(), "deploy.log")
+
+	size, err := appendRemoteFile(client, path, []byte("first\n"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, size)
+
+	size, err = appendRemoteFile(client, path, []byte("second\n"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 13, size)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(data))
+}
+
+func TestReadFileRange_RejectsEmptyLength(t *testing.T) {
+	_, err := (&SSHClient{config: &Config{}}).ReadFileRange("/var/log/syslog", 0, 0)
+	assert.EqualError(t, err, "length must be positive")
+}
@@ -0,0 +1,288 @@
+package sshclient
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// Defaults of RebootOptions
+const (
+	// DefaultRebootMinUptime refuses to reboot a host that booted less than
+	// this long ago, so a retried call cannot put it in a reboot loop
+	DefaultRebootMinUptime = 10 * time.Minute
+	// DefaultRebootTimeout is how long Reboot waits for the host to return
+	DefaultRebootTimeout = 10 * time.Minute
+	// DefaultRebootInterval is the pause between reconnection attempts
+	DefaultRebootInterval = 5 * time.Second
+)
+
+// rebootDelay is how many seconds the host waits before rebooting, so the
+// command that schedules the reboot can return
+const rebootDelay = 2
+
+// rebootMarker prefixes the lines of BootStateCommand that start a section
+const rebootMarker = "@sshx-boot:"
+
+// RebootOptions describes a host_reboot request
+type RebootOptions struct {
+	// MinUptime refuses the reboot when the host booted less than this long
+	// ago (0 = DefaultRebootMinUptime, negative = no check)
+	MinUptime time.Duration
+	// AllowSessions reboots even when users are logged in
+	AllowSessions bool
+	// Verify is run once the host is back; the reboot fails when it does
+	Verify string
+	// Timeout bounds the wait for the host to return (0 = DefaultRebootTimeout)
+	Timeout time.Duration
+	// Interval is the pause between reconnection attempts (0 = DefaultRebootInterval)
+	Interval time.Duration
+	// DryRun only runs the pre-checks
+	DryRun bool
+	// Progress, when set, is told about each step
+	Progress func(message string)
+}
+
+// BootState is what BootStateCommand reports about a host
+type BootState struct {
+	BootID   string   `json:"boot_id"`
+	Uptime   int64    `json:"uptime_seconds"`
+	Sessions []string `json:"sessions"` // Lines of who
+	// UID is the user the command ran as; rebooting needs root
+	UID int `json:"-"`
+}
+
+// RebootVerification is the outcome of RebootOptions.Verify
+type RebootVerification struct {
+	Command string `json:"command"`
+	Passed  bool   `json:"passed"`
+	Output  string `json:"output"`
+}
+
+// RebootResult is the outcome of Reboot
+type RebootResult struct {
+	Host   string     `json:"host"`
+	DryRun bool       `json:"dry_run"`
+	Before BootState  `json:"before"`
+	After  *BootState `json:"after,omitempty"`
+	// ReturnedAfter is the time from the reboot to the first connection to
+	// the new boot
+	ReturnedAfter int64               `json:"returned_after_seconds,omitempty"`
+	Verify        *RebootVerification `json:"verify,omitempty"`
+}
+
+// BootStateCommand prints the boot ID, uptime, user ID and logged-in users
+// of the host
+func BootStateCommand() string {
+	return strings.Join([]string{
+		"echo " + ShellQuote(rebootMarker+"boot_id"),
+		"cat /proc/sys/kernel/random/boot_id",
+		"echo " + ShellQuote(rebootMarker+"uptime"),
+		"cut -d ' ' -f 1 /proc/uptime",
+		"echo " + ShellQuote(rebootMarker+"uid"),
+		"id -u",
+		"echo " + ShellQuote(rebootMarker+"sessions"),
+		"who 2>/dev/null || true",
+	}, "\n")
+}
+
+// ParseBootState reads the output of BootStateCommand
+func ParseBootState(output string) (BootState, error) {
+	state := BootState{UID: -1, Sessions: []string{}}
+	section := ""
+	for _, line := range splitLines(output) {
+		line = strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(line, rebootMarker); ok {
+			section = name
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		switch section {
+		case "boot_id":
+			state.BootID = line
+		case "uptime":
+			if seconds, err := strconv.ParseFloat(line, 64); err == nil {
+				state.Uptime = int64(math.Floor(seconds))
+			}
+		case "uid":
+			if uid, err := strconv.Atoi(line); err == nil {
+				state.UID = uid
+			}
+		case "sessions":
+			state.Sessions = append(state.Sessions, strings.Join(strings.Fields(line), " "))
+		}
+	}
+	if state.BootID == "" {
+		return state, fmt.Errorf("could not read the boot ID of the host (not Linux?)")
+	}
+	return state, nil
+}
+
+// RebootCommand reboots the host rebootDelay seconds after it returns
+func RebootCommand() string {
+	return strings.Join([]string{
+		fmt.Sprintf("nohup sh -c 'sleep %d; systemctl reboot || shutdown -r now' >/dev/null 2>&1 &", rebootDelay),
+		"echo scheduled",
+	}, "\n")
+}
+
+// checkReboot returns why the host in state must not be rebooted with opts
+func checkReboot(state BootState, opts RebootOptions) error {
+	if state.UID != 0 {
+		return fmt.Errorf("rebooting needs root: run it with run_as root")
+	}
+	if opts.MinUptime > 0 && time.Duration(state.Uptime)*time.Second < opts.MinUptime {
+		return fmt.Errorf("the host booted %s ago, less than the minimum uptime of %s: it may be in a reboot loop",
+			time.Duration(state.Uptime)*time.Second, opts.MinUptime)
+	}
+	if len(state.Sessions) > 0 && !opts.AllowSessions {
+		return fmt.Errorf("%d user session(s) open on the host (%s): allow sessions to reboot anyway",
+			len(state.Sessions), strings.Join(state.Sessions, "; "))
+	}
+	return nil
+}
+
+// Reboot reboots the host and waits for it to come back. It first reads
+// the boot state and refuses unless the command runs as root, the host has
+// been up for opts.MinUptime and nobody is logged in. After the reboot it
+// reconnects every opts.Interval until the boot ID changes, then runs
+// opts.Verify. The reboot command itself bypasses the safety validator,
+// which blocks reboots, but is audited like every other command and keeps
+// to the maintenance windows of the safety policy; the pre-checks and the
+// verification are validated as usual.
+//
+// The connection of c is closed by the reboot; c must not be used
+// afterwards.
+func (c *SSHClient) Reboot(opts RebootOptions, req Request) (*RebootResult, error) {
+	if isPowerShell(c.config.Shell) {
+		return nil, fmt.Errorf("reboot is not supported with shell %s", c.config.Shell)
+	}
+	if opts.MinUptime == 0 {
+		opts.MinUptime = DefaultRebootMinUptime
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultRebootTimeout
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultRebootInterval
+	}
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(string) {}
+	}
+
+	result := &RebootResult{Host: c.config.Host, DryRun: opts.DryRun}
+	before, err := c.bootState(req)
+	if err != nil {
+		return nil, err
+	}
+	result.Before = before
+	if err := checkReboot(before, opts); err != nil {
+		return nil, fmt.Errorf("refusing to reboot %s: %w", c.config.Host, err)
+	}
+	if err := c.checkRebootWindow(opts.DryRun); err != nil {
+		return nil, fmt.Errorf("refusing to reboot %s: %w", c.config.Host, err)
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	config := *c.config
+	config.SafetyCheck = false
+	rebooter := &SSHClient{config: &config, client: c.client}
+	req.Command = RebootCommand()
+	if _, err := rebooter.Execute(req); err != nil {
+		return nil, fmt.Errorf("failed to reboot %s: %w", c.config.Host, err)
+	}
+	rebooted := time.Now()
+	progress(fmt.Sprintf("Rebooting %s, waiting up to %s for it to return...", c.config.Host, opts.Timeout))
+
+	// The connection dies with the host
+	GetConnectionPool().RemoveConnection(c.config)
+	_ = c.ForceClose() //nolint:errcheck // the host is going down
+	c.client = nil
+
+	probe, after, err := c.awaitBoot(before.BootID, rebooted.Add(opts.Timeout), opts.Interval, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = probe.ForceClose() //nolint:errcheck // the result is already read
+	}()
+	result.After = after
+	result.ReturnedAfter = int64(time.Since(rebooted).Round(time.Second) / time.Second)
+	progress(fmt.Sprintf("%s is back after %ds", c.config.Host, result.ReturnedAfter))
+
+	if opts.Verify != "" {
+		req.Command = opts.Verify
+		verified, err := probe.Execute(req)
+		result.Verify = &RebootVerification{Command: opts.Verify, Passed: err == nil, Output: verified.Output}
+		if err != nil {
+			result.Verify.Output = strings.TrimSpace(verified.Output + "\n" + err.Error())
+		}
+	}
+	return result, nil
+}
+
+// checkRebootWindow blocks the reboot when the safety policy has
+// maintenance windows and none is open, unless Config.Force overrides it
+// with a reason. A forced dry run passes without asking for the reason.
+func (c *SSHClient) checkRebootWindow(dryRun bool) error {
+	policy := c.config.Safety
+	finding := &SafetyFinding{Rule: "reboot", Reason: "System reboot", Severity: policy.SeverityOf("reboot")}
+	blocked := policy.windowBlocked(RebootCommand(), finding)
+	if blocked == nil || (dryRun && c.config.Force) {
+		return nil
+	}
+	if !c.config.Force {
+		return blocked
+	}
+	if err := authorizeForce(c.config, blocked); err != nil {
+		return err
+	}
+	logger.GetLogger().Warning("Maintenance window bypassed by force for the reboot of %s, reason: %s", c.config.Host, c.config.ForceReason)
+	return nil
+}
+
+// bootState runs BootStateCommand
+func (c *SSHClient) bootState(req Request) (BootState, error) {
+	req.Command = BootStateCommand()
+	output, err := c.Execute(req)
+	if err != nil {
+		return BootState{}, fmt.Errorf("failed to read the boot state of %s: %w", c.config.Host, err)
+	}
+	return ParseBootState(output.Output)
+}
+
+// awaitBoot reconnects to the host until it reports a boot ID other than
+// bootID, and returns the new connection
+func (c *SSHClient) awaitBoot(bootID string, deadline time.Time, interval time.Duration, req Request) (*SSHClient, *BootState, error) {
+	config := *c.config
+	config.DialTimeout = min(DefaultTimeout, max(interval, 5*time.Second))
+	var lastErr error
+	for {
+		time.Sleep(interval)
+		probe := &SSHClient{config: &config}
+		if lastErr = probe.ConnectDirect(); lastErr == nil {
+			state, err := probe.bootState(req)
+			if err == nil && state.BootID != bootID {
+				return probe, &state, nil
+			}
+			// Still shutting down
+			lastErr = err
+			_ = probe.ForceClose() //nolint:errcheck // retried
+		}
+		if time.Now().After(deadline) {
+			if lastErr == nil {
+				return nil, nil, fmt.Errorf("%s did not reboot before the timeout: its boot ID is unchanged", c.config.Host)
+			}
+			return nil, nil, fmt.Errorf("%s did not return before the timeout: %w", c.config.Host, lastErr)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package sshclient
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+const bootStateOutput = `@sshx-boot:boot_id
+2f3b1c9e-0d4a-4c55-9a61-7b1f0e2d3c4b
+@sshx-boot:uptime
+86400.52
+@sshx-boot:uid
+0
+@sshx-boot:sessions
+alice    pts/0        2026-10-16 09:12 (10.0.0.9)
+`
+
+func TestParseBootState(t *testing.T) {
+	state, err := ParseBootState(bootStateOutput)
+	require.NoError(t, err)
+	assert.Equal(t, BootState{
+		BootID: "2f3b1c9e-0d4a-4c55-9a61-7b1f0e2d3c4b", Uptime: 86400, UID: 0,
+		Sessions: []string{"alice pts/0 2026-10-16 09:12 (10.0.0.9)"},
+	}, state)
+
+	_, err = ParseBootState("@sshx-boot:boot_id\n@sshx-boot:uptime\n12.0\n")
+	assert.ErrorContains(t, err, "could not read the boot ID")
+}
+
+func TestCheckReboot(t *testing.T) {
+	state := BootState{BootID: "a", Uptime: 3600, UID: 0, Sessions: []string{}}
+	assert.NoError(t, checkReboot(state, RebootOptions{MinUptime: DefaultRebootMinUptime}))
+	assert.ErrorContains(t, checkReboot(state, RebootOptions{MinUptime: 2 * time.Hour}), "booted 1h0m0s ago, less than the minimum uptime of 2h0m0s")
+	assert.NoError(t, checkReboot(state, RebootOptions{MinUptime: -1}))
+
+	state.Sessions = []string{"alice pts/0"}
+	assert.ErrorContains(t, checkReboot(state, RebootOptions{}), "1 user session(s) open on the host (alice pts/0)")
+	assert.NoError(t, checkReboot(state, RebootOptions{AllowSessions: true}))
+
+	state.UID = 1000
+	assert.ErrorContains(t, checkReboot(state, RebootOptions{AllowSessions: true}), "rebooting needs root")
+}
+
+func TestRebootCommands(t *testing.T) {
+	assert.False(t, Explain(BootStateCommand(), nil).Flagged)
+	assert.True(t, Explain(RebootCommand(), nil).Flagged, "only Reboot may run the reboot command")
+}
+
+// fakeRebootHost answers BootStateCommand with bootID, which becomes next
+// after a reboot once staleReads more reads saw the old boot; a host
+// without next never reboots
+type fakeRebootHost struct {
+	mu         sync.Mutex
+	bootID     string
+	next       string
+	staleReads int
+	rebooted   bool
+	sessions   string
+	commands   []string
+}
+
+func (h *fakeRebootHost) handle(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commands = append(h.commands, command)
+	switch {
+	case strings.Contains(command, "/proc/sys/kernel/random/boot_id"):
+		if h.rebooted && h.next != "" {
+			if h.staleReads > 0 {
+				h.staleReads--
+			} else {
+				h.bootID, h.next, h.rebooted = h.next, "", false
+			}
+		}
+		_, _ = channel.Write([]byte("@sshx-boot:boot_id\n" + h.bootID + "\n@sshx-boot:uptime\n7200.1\n@sshx-boot:uid\n0\n@sshx-boot:sessions\n" + h.sessions))
+	case strings.Contains(command, "systemctl reboot"):
+		h.rebooted = true
+		_, _ = channel.Write([]byte("scheduled\n"))
+	case command == "systemctl is-active nginx":
+		_, _ = channel.Write([]byte("active\n"))
+	default:
+		_, _ = channel.Write([]byte("inactive\n"))
+		return 3
+	}
+	return 0
+}
+
+func connectFakeRebootHost(t *testing.T, host *fakeRebootHost) *SSHClient {
+	t.Helper()
+	address, port, err := net.SplitHostPort(listenExecServer(t, host.handle))
+	require.NoError(t, err)
+	client, err := NewSSHClient(&Config{
+		Host:              address,
+		Port:              port,
+		User:              "test",
+		Password:          "unused",
+		KnownHostsPath:    filepath.Join(t.TempDir(), "known_hosts"),
+		AcceptUnknownHost: true,
+		SafetyCheck:       true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.ConnectDirect())
+	return client
+}
+
+func TestSSHClient_Reboot(t *testing.T) {
+	// The first reconnection still sees the old boot
+	host := &fakeRebootHost{bootID: "boot-1", next: "boot-2", staleReads: 1}
+	client := connectFakeRebootHost(t, host)
+
+	dryRun, err := client.Reboot(RebootOptions{DryRun: true}, Request{})
+	require.NoError(t, err)
+	assert.True(t, dryRun.DryRun)
+	assert.Equal(t, "boot-1", dryRun.Before.BootID)
+	assert.Nil(t, dryRun.After)
+
+	var messages []string
+	result, err := client.Reboot(RebootOptions{
+		Verify:   "systemctl is-active nginx",
+		Interval: 10 * time.Millisecond,
+		Timeout:  5 * time.Second,
+		Progress: func(message string) { messages = append(messages, message) },
+	}, Request{})
+	require.NoError(t, err)
+	require.NotNil(t, result.After)
+	assert.Equal(t, "boot-2", result.After.BootID)
+	assert.Equal(t, &RebootVerification{Command: "systemctl is-active nginx", Passed: true, Output: "active\n"}, result.Verify)
+	assert.Len(t, messages, 2)
+	assert.Nil(t, client.client, "the old connection is dropped")
+}
+
+func TestSSHClient_RebootRefusedAndFailures(t *testing.T) {
+	host := &fakeRebootHost{bootID: "boot-1", next: "boot-2", sessions: "alice pts/0\n"}
+	client := connectFakeRebootHost(t, host)
+	_, err := client.Reboot(RebootOptions{}, Request{})
+	assert.ErrorContains(t, err, "refusing to reboot")
+	for _, command := range host.commands {
+		assert.NotContains(t, command, "systemctl reboot")
+	}
+
+	// A failing verification is reported, not hidden
+	host.sessions = ""
+	result, err := client.Reboot(RebootOptions{Verify: "systemctl is-active app", Interval: 10 * time.Millisecond, Timeout: 5 * time.Second}, Request{})
+	require.NoError(t, err)
+	assert.False(t, result.Verify.Passed)
+	assert.Contains(t, result.Verify.Output, "inactive")
+
+	// Outside the maintenance windows only a forced reboot runs
+	window, err := ParseMaintenanceWindow([]string{"sat"}, "02:00", "05:00", "UTC")
+	require.NoError(t, err)
+	fixClock(t, time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC))
+	host = &fakeRebootHost{bootID: "boot-1", next: "boot-2"}
+	client = connectFakeRebootHost(t, host)
+	client.config.Safety = &SafetyPolicy{Windows: []MaintenanceWindow{window}}
+	_, err = client.Reboot(RebootOptions{}, Request{})
+	var blocked *BlockedCommandError
+	require.True(t, errors.As(err, &blocked))
+	assert.Equal(t, time.Date(2026, time.October, 17, 2, 0, 0, 0, time.UTC), *blocked.NextWindow)
+	client.config.Force = true
+	_, err = client.Reboot(RebootOptions{}, Request{})
+	assert.ErrorContains(t, err, "a reason for the bypass is required")
+	client.config.ForceReason = "kernel fix"
+	_, err = client.Reboot(RebootOptions{Interval: 10 * time.Millisecond, Timeout: 5 * time.Second}, Request{})
+	require.NoError(t, err)
+
+	// The host never reboots
+	host = &fakeRebootHost{bootID: "boot-1"}
+	client = connectFakeRebootHost(t, host)
+	_, err = client.Reboot(RebootOptions{Interval: 10 * time.Millisecond, Timeout: 50 * time.Millisecond}, Request{})
+	assert.ErrorContains(t, err, "did not reboot before the timeout")
+}
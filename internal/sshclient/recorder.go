@@ -0,0 +1,200 @@
+package sshclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// CastHeader is the header line of an asciinema v2 recording
+type CastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder writes command output as an asciinema v2 (.cast) stream.
+// It is safe for concurrent use by the stdout and stderr copiers.
+type Recorder struct {
+	mu       sync.Mutex
+	w        io.Writer
+	closer   io.Closer
+	start    time.Time
+	lastByte byte
+}
+
+// NewRecorder writes the cast header to w and returns a recorder for events
+func NewRecorder(w io.Writer, header CastHeader) (*Recorder, error) {
+	if header.Version == 0 {
+		header.Version = 2
+	}
+	if header.Width == 0 {
+		header.Width = 80
+	}
+	if header.Height == 0 {
+		header.Height = 40
+	}
+	start := time.Now()
+	if header.Timestamp == 0 {
+		header.Timestamp = start.Unix()
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recording header: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+		return nil, fmt.Errorf("failed to write recording header: %w", err)
+	}
+
+	return &Recorder{w: w, start: start}, nil
+}
+
+// CreateRecording creates (or truncates) a .cast file at path and starts recording
+func CreateRecording(path string, header CastHeader) (*Recorder, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create recording directory: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) // #nosec G304 -- user-provided recording path
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+
+	rec, err := NewRecorder(file, header)
+	if err != nil {
+		_ = file.Close() //nolint:errcheck // cleanup on error path
+		return nil, err
+	}
+	rec.closer = file
+	return rec, nil
+}
+
+// Write records p as an output ("o") event. Bare line feeds are converted to
+// CRLF so that output captured without a PTY replays correctly.
+func (r *Recorder) Write(p []byte) (int, error) {
+	if err := r.event("o", p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Input records data sent to the remote process as an input ("i") event,
+// with registered secrets masked
+func (r *Recorder) Input(p []byte) error {
+	return r.event("i", []byte(logger.Redact(string(p))))
+}
+
+func (r *Recorder) event(kind string, p []byte) error {
+	if r == nil || len(p) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := p
+	if kind == "o" {
+		data = make([]byte, 0, len(p))
+		prev := r.lastByte
+		for _, b := range p {
+			if b == '\n' && prev != '\r' {
+				data = append(data, '\r')
+			}
+			data = append(data, b)
+			prev = b
+		}
+		r.lastByte = prev
+	}
+
+	event, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), kind, string(data)})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.w, "%s\n", event)
+	return err
+}
+
+// Close closes the underlying recording file, if any
+func (r *Recorder) Close() error {
+	if r == nil || r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// PlayRecording replays an asciinema v2 stream to w, honoring event timing.
+// speed scales playback (2 = twice as fast); maxIdle caps pauses between events
+// (0 disables the cap).
+func PlayRecording(r io.Reader, w io.Writer, speed float64, maxIdle time.Duration) error {
+	if speed <= 0 {
+		speed = 1
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read recording: %w", err)
+		}
+		return fmt.Errorf("recording is empty")
+	}
+
+	var header CastHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return fmt.Errorf("invalid recording header: %w", err)
+	}
+	if header.Version != 2 {
+		return fmt.Errorf("unsupported recording version: %d", header.Version)
+	}
+
+	var last float64
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("invalid recording event: %w", err)
+		}
+		if len(event) != 3 {
+			return fmt.Errorf("invalid recording event: expected 3 fields, got %d", len(event))
+		}
+
+		at, okTime := event[0].(float64)
+		kind, okKind := event[1].(string)
+		data, okData := event[2].(string)
+		if !okTime || !okKind || !okData {
+			return fmt.Errorf("invalid recording event: %s", scanner.Text())
+		}
+
+		delay := time.Duration((at - last) / speed * float64(time.Second))
+		if maxIdle > 0 && delay > maxIdle {
+			delay = maxIdle
+		}
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		last = at
+
+		if kind != "o" {
+			continue
+		}
+		if _, err := io.WriteString(w, data); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
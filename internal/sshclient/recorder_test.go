@@ -0,0 +1,95 @@
+package sshclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_WritesCastFormat(t *testing.T) {
+	var buf bytes.Buffer
+	rec, err := NewRecorder(&buf, CastHeader{Command: "uptime", Title: "root@host"})
+	require.NoError(t, err)
+
+	_, err = rec.Write([]byte("line1\nline2\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, rec.Input([]byte("y\n")))
+	require.NoError(t, rec.Close())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+
+	var header CastHeader
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+	assert.Equal(t, 2, header.Version)
+	assert.Equal(t, 80, header.Width)
+	assert.Equal(t, "uptime", header.Command)
+	assert.NotZero(t, header.Timestamp)
+
+	var event []interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, "o", event[1])
+	assert.Equal(t, "line1\r\nline2\r\n", event[2])
+
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &event))
+	assert.Equal(t, "i", event[1])
+	assert.Equal(t, "y\n", event[2])
+}
+
+func TestRecorder_CRLFAcrossWrites(t *testing.T) {
+	var buf bytes.Buffer
+	rec, err := NewRecorder(&buf, CastHeader{})
+	require.NoError(t, err)
+
+	_, err = rec.Write([]byte("a\r"))
+	require.NoError(t, err)
+	_, err = rec.Write([]byte("\nb"))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, PlayRecording(&buf, &out, 1000, 0))
+	assert.Equal(t, "a\r\nb", out.String())
+}
+
+func TestRecorder_NilSafe(t *testing.T) {
+	var rec *Recorder
+	assert.NoError(t, rec.Input([]byte("x")))
+	assert.NoError(t, rec.Close())
+}
+
+func TestCreateRecordingAndPlay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "session.cast")
+	rec, err := CreateRecording(path, CastHeader{Command: "ls"})
+	require.NoError(t, err)
+	_, err = rec.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	require.NoError(t, rec.Close())
+
+	var out bytes.Buffer
+	data := readFile(t, path)
+	require.NoError(t, PlayRecording(strings.NewReader(data), &out, 1, 10*time.Millisecond))
+	assert.Equal(t, "hello\r\n", out.String())
+}
+
+func TestPlayRecording_InvalidInput(t *testing.T) {
+	var out bytes.Buffer
+
+	assert.ErrorContains(t, PlayRecording(strings.NewReader(""), &out, 1, 0), "empty")
+	assert.ErrorContains(t, PlayRecording(strings.NewReader("not json\n"), &out, 1, 0), "invalid recording header")
+	assert.ErrorContains(t, PlayRecording(strings.NewReader(`{"version":1}`+"\n"), &out, 1, 0), "unsupported recording version")
+	assert.ErrorContains(t, PlayRecording(strings.NewReader(`{"version":2}`+"\n[1]\n"), &out, 1, 0), "expected 3 fields")
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path) //nolint:gosec // G304: test reads file from controlled temp dir
+	require.NoError(t, err)
+	return string(content)
+}
@@ -0,0 +1,86 @@
+package sshclient
+
+import (
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Request describes one operation on a connected SSHClient: a command, a
+// script run or an SFTP action. Connection, safety and output options come
+// from the client's Config; the request supplies what changes from one
+// operation to the next.
+//
+// Every call works on its own copy of the Config with the request applied,
+// so a single SSHClient can serve concurrent requests over one connection
+// without one request seeing another's command or paths.
+type Request struct {
+	// Command is the remote command run by ExecuteCommand and Execute
+	Command string
+	// RunAs runs the command or script as this user through sudo -u
+	RunAs string
+	// Container runs the command inside this container (see Config.Container)
+	Container string
+
+	// SftpAction is the ExecuteSftp action (upload, download, list, mkdir, remove)
+	SftpAction string
+	LocalPath  string
+	RemotePath string
+
+	// ScriptArgs are the arguments passed to the script run by ExecuteScript
+	ScriptArgs []string
+}
+
+// RequestFromConfig returns the request described by the per-operation
+// fields of config, for callers that build one Config per operation
+func RequestFromConfig(config *Config) Request {
+	return Request{
+		Command:    config.Command,
+		RunAs:      config.RunAs,
+		Container:  config.Container,
+		SftpAction: config.SftpAction,
+		LocalPath:  config.LocalPath,
+		RemotePath: config.RemotePath,
+		ScriptArgs: config.ScriptArgs,
+	}
+}
+
+// Result is the outcome of a command run with Execute
+type Result struct {
+	// Output is the captured output; stderr is appended to it unless
+	// Config.BinaryOutput keeps the two apart
+	Output string
+	// Stderr is the standard error of a command run with Config.BinaryOutput
+	Stderr string
+	// Truncated reports that the output exceeded Config.MaxOutputBytes
+	Truncated bool
+	// Safety is the validator finding for a flagged command the safety
+	// policy let run
+	Safety *SafetyFinding
+}
+
+// operation is a single request running on an SSHClient. It owns a copy of
+// the configuration and all per-run state (recording, SFTP session, output
+// bookkeeping), sharing only the SSH connection with other operations.
+type operation struct {
+	config          *Config
+	client          *ssh.Client
+	sftpClient      *sftp.Client
+	recorder        *Recorder
+	outputTruncated bool
+	stderr          string
+	progressSteps   int64
+	forwarding      *forwarding
+}
+
+// newOperation prepares req to run over the client's connection
+func (c *SSHClient) newOperation(req Request) *operation {
+	config := *c.config
+	config.Command = req.Command
+	config.RunAs = req.RunAs
+	config.Container = req.Container
+	config.SftpAction = req.SftpAction
+	config.LocalPath = req.LocalPath
+	config.RemotePath = req.RemotePath
+	config.ScriptArgs = req.ScriptArgs
+	return &operation{config: &config, client: c.client, forwarding: c.forwarding}
+}
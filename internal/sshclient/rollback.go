@@ -0,0 +1,83 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/talkincode/sshmcp/pkg/errutil"
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// rollbackApplies reports whether a step that failed with err ran at all,
+// so undoing it makes sense: a command the safety check refused never did
+func rollbackApplies(err error) bool {
+	var blocked *BlockedCommandError
+	return err != nil && !errors.As(err, &blocked)
+}
+
+// runRollback runs command on the host of client to undo step, which
+// failed. It runs through Execute, so it is validated like any command, and
+// the audit log records it with Config.RollbackFor set to step.
+func runRollback(client *SSHClient, command, step string) error {
+	config := *client.config
+	config.RollbackFor = step
+	rollbackClient := &SSHClient{config: &config, client: client.client, authMethodUsed: client.authMethodUsed}
+	logger.GetLogger().Warning("Rolling back '%s' on %s: %s", logger.Redact(step), config.Host, logger.Redact(command))
+	_, err := rollbackClient.Execute(Request{Command: command, RunAs: config.RunAs})
+	return err
+}
+
+// rollbackHost connects to the host of config and runs the rollback of step
+func rollbackHost(config *Config, command, step string) (err error) {
+	client, err := NewSSHClient(config)
+	if err != nil {
+		return err
+	}
+	if err = client.Connect(); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	return runRollback(client, command, step)
+}
+
+// settleUpload finishes an upload kept for a rollout check: a passed host
+// drops the backup, a failed one gets the previous file back and runs the
+// rollback command, if any
+func settleUpload(config *Config, opts DistributeOptions, backupPath string, failed bool) (err error) {
+	if backupPath == "" && !failed {
+		return nil
+	}
+	client, err := NewSSHClient(config)
+	if err != nil {
+		return err
+	}
+	if err = client.Connect(); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	sftpClient, err := newSFTPClient(client.client, client.config)
+	if err != nil {
+		return fmt.Errorf("failed to create SFTP client: %w", err)
+	}
+	defer errutil.HandleCloseError(&err, sftpClient)
+
+	if !failed {
+		return sftpClient.Remove(backupPath)
+	}
+	if err = rollbackRemoteFile(sftpClient, opts.RemotePath, backupPath); err != nil {
+		return err
+	}
+	if opts.Rollback != "" {
+		return runRollback(client, opts.Rollback, uploadStep(opts))
+	}
+	return nil
+}
+
+// uploadStep names an upload as the step a rollback undoes
+func uploadStep(opts DistributeOptions) string {
+	return "upload " + opts.RemotePath
+}
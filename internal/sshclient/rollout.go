@@ -0,0 +1,262 @@
+package sshclient
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// RolloutOptions stages a multi-host operation: the canary hosts first,
+// then the others in batches, halting once too many hosts failed. A host
+// fails when the operation fails, its output does not match Expect or the
+// Check command fails on it afterwards.
+type RolloutOptions struct {
+	// Canary is how many hosts run first; any failure among them halts the
+	// rollout (0 = no canary stage)
+	Canary int
+	// BatchSize is how many hosts run per stage after the canary
+	// (0 = all the rest at once)
+	BatchSize int
+	// MaxFailures is how many failed hosts the batches tolerate before the
+	// rollout halts (0 = none)
+	MaxFailures int
+	// Expect is a regular expression the output of each host must match
+	Expect string
+	// Check is a command run on each host after the operation; it has to
+	// succeed
+	Check string
+}
+
+// Enabled reports whether the options stage the operation at all
+func (o RolloutOptions) Enabled() bool {
+	return o.Canary > 0 || o.BatchSize > 0 || o.Expect != "" || o.Check != ""
+}
+
+// Validate checks the options before anything runs
+func (o RolloutOptions) Validate() error {
+	if o.Canary < 0 || o.BatchSize < 0 || o.MaxFailures < 0 {
+		return fmt.Errorf("canary, batch size and max failures cannot be negative")
+	}
+	if o.Expect != "" {
+		if _, err := regexp.Compile(o.Expect); err != nil {
+			return fmt.Errorf("invalid expect pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// RolloutStage is one stage of a rollout
+type RolloutStage struct {
+	Name   string `json:"name"`   // "canary" or "batch N"
+	Hosts  []int  `json:"hosts"`  // Indexes into the configs
+	Failed []int  `json:"failed"` // Indexes of the hosts that failed
+}
+
+// RolloutResult describes how far a rollout got
+type RolloutResult struct {
+	Stages []RolloutStage `json:"stages"`
+	// Halted reports that hosts were skipped because of failures
+	Halted bool   `json:"halted"`
+	Reason string `json:"reason,omitempty"`
+	// Skipped are the indexes of the hosts that never ran
+	Skipped []int `json:"skipped,omitempty"`
+}
+
+// rolloutStages splits n hosts into the stages of opts
+func rolloutStages(n int, opts RolloutOptions) []RolloutStage {
+	var stages []RolloutStage
+	next := 0
+	take := func(name string, count int) {
+		if count <= 0 || count > n-next {
+			count = n - next
+		}
+		stage := RolloutStage{Name: name, Failed: []int{}}
+		for ; count > 0; count-- {
+			stage.Hosts = append(stage.Hosts, next)
+			next++
+		}
+		stages = append(stages, stage)
+	}
+	if opts.Canary > 0 && n > 0 {
+		take("canary", opts.Canary)
+	}
+	for batch := 1; next < n; batch++ {
+		take(fmt.Sprintf("batch %d", batch), opts.BatchSize)
+	}
+	return stages
+}
+
+// rollout runs the stages of opts over configs. run performs the operation
+// on the hosts with the given indexes and returns the output and error of
+// each of them in the same order; settle is called for each host the
+// operation succeeded on, with the success condition it failed or nil, and
+// skip for each host that never ran. progress, when set, sees the hosts of
+// all stages as one operation.
+func rollout(configs []*Config, opts RolloutOptions, progress ProgressFunc,
+	run func(indexes []int, configs []*Config, progress ProgressFunc) ([]string, []error),
+	settle func(i int, err error), skip func(i int)) *RolloutResult {
+	expect, _ := regexp.Compile(opts.Expect) //nolint:errcheck // checked by Validate
+	result := &RolloutResult{}
+	failures := 0
+	done := int64(0)
+	for s, stage := range rolloutStages(len(configs), opts) {
+		if result.Halted {
+			result.Skipped = append(result.Skipped, stage.Hosts...)
+			for _, i := range stage.Hosts {
+				skip(i)
+			}
+			continue
+		}
+
+		subset := make([]*Config, len(stage.Hosts))
+		for j, i := range stage.Hosts {
+			subset[j] = configs[i]
+		}
+		var stageProgress ProgressFunc
+		if progress != nil {
+			offset := done
+			stageProgress = func(finished, _ int64, item string) {
+				progress(offset+finished, int64(len(configs)), item)
+			}
+		}
+		outputs, errs := run(stage.Hosts, subset, stageProgress)
+		done += int64(len(stage.Hosts))
+
+		checks := make([]error, len(subset))
+		if opts.Check != "" {
+			forEachHost(subset, 0, nil, func(j int, config *Config) {
+				if errs[j] == nil {
+					checks[j] = runRolloutCheck(config, opts.Check)
+				}
+			})
+		}
+		settled := make([]error, len(subset))
+		for j, i := range stage.Hosts {
+			switch {
+			case errs[j] != nil:
+			case expect != nil && !expect.MatchString(outputs[j]):
+				settled[j] = fmt.Errorf("output does not match %q", opts.Expect)
+			case checks[j] != nil:
+				settled[j] = fmt.Errorf("check failed: %w", checks[j])
+			default:
+				continue
+			}
+			stage.Failed = append(stage.Failed, i)
+		}
+		forEachHost(subset, 0, nil, func(j int, _ *Config) {
+			if errs[j] == nil {
+				settle(stage.Hosts[j], settled[j])
+			}
+		})
+		result.Stages = append(result.Stages, stage)
+
+		failures += len(stage.Failed)
+		switch {
+		case s == 0 && stage.Name == "canary" && len(stage.Failed) > 0:
+			result.Halted = true
+			result.Reason = fmt.Sprintf("%d of %d canary host(s) failed", len(stage.Failed), len(stage.Hosts))
+		case failures > opts.MaxFailures:
+			result.Halted = true
+			result.Reason = fmt.Sprintf("%d host(s) failed, more than the %d allowed", failures, opts.MaxFailures)
+		}
+	}
+	sort.Ints(result.Skipped)
+	return result
+}
+
+// runRolloutCheck runs the check command of a rollout on one host
+func runRolloutCheck(config *Config, command string) error {
+	client, err := NewSSHClient(config)
+	if err != nil {
+		return err
+	}
+	if err = client.Connect(); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() {
+		_ = client.CloseWithError(err) //nolint:errcheck
+	}()
+	_, err = client.Execute(Request{Command: command, RunAs: config.RunAs})
+	return err
+}
+
+// errRolloutSkipped marks the hosts a halted rollout never reached
+var errRolloutSkipped = fmt.Errorf("skipped: the rollout halted")
+
+// RolloutCollect runs Collect over configs in the stages of rollout. Hosts
+// that failed a success condition, or were skipped, get an error in their
+// result; the first also get CollectOptions.Rollback.
+func RolloutCollect(configs []*Config, opts CollectOptions, rolloutOpts RolloutOptions) ([]CollectResult, *RolloutResult) {
+	results := make([]CollectResult, len(configs))
+	progress := opts.Progress
+	status := rollout(configs, rolloutOpts, progress,
+		func(indexes []int, subset []*Config, stageProgress ProgressFunc) ([]string, []error) {
+			opts.Progress = stageProgress
+			outputs := make([]string, len(subset))
+			errs := make([]error, len(subset))
+			for j, result := range Collect(subset, opts) {
+				results[indexes[j]] = result
+				outputs[j], errs[j] = result.Output, result.Err
+			}
+			return outputs, errs
+		},
+		func(i int, err error) {
+			if err == nil {
+				return
+			}
+			results[i].Err = err
+			if opts.Rollback == "" {
+				return
+			}
+			if rollbackErr := rollbackHost(configs[i], opts.Rollback, opts.Command); rollbackErr != nil {
+				results[i].Err = fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+			} else {
+				results[i].RolledBack = true
+			}
+		},
+		func(i int) {
+			results[i] = CollectResult{Key: GetConnectionPool().makeKey(configs[i]), Err: errRolloutSkipped}
+		})
+	return results, status
+}
+
+// RolloutDistribute runs Distribute over configs in the stages of rollout;
+// Expect is matched against the output of DistributeOptions.Validate. The
+// backups are kept until the success conditions are known, so a host that
+// fails them gets its previous file back and DistributeOptions.Rollback.
+func RolloutDistribute(configs []*Config, opts DistributeOptions, rolloutOpts RolloutOptions) ([]DistributeResult, *RolloutResult) {
+	results := make([]DistributeResult, len(configs))
+	progress := opts.Progress
+	opts.keepBackup = true
+	status := rollout(configs, rolloutOpts, progress,
+		func(indexes []int, subset []*Config, stageProgress ProgressFunc) ([]string, []error) {
+			opts.Progress = stageProgress
+			outputs := make([]string, len(subset))
+			errs := make([]error, len(subset))
+			for j, result := range Distribute(subset, opts) {
+				results[indexes[j]] = result
+				outputs[j], errs[j] = result.ValidateOutput, result.Err
+			}
+			return outputs, errs
+		},
+		func(i int, err error) {
+			settleErr := settleUpload(configs[i], opts, results[i].backupPath, err != nil)
+			results[i].backupPath = ""
+			switch {
+			case err == nil && settleErr != nil:
+				logger.GetLogger().Warning("failed to remove backup on %s: %v", results[i].Key, settleErr)
+			case err == nil:
+			case settleErr != nil:
+				results[i].Err = fmt.Errorf("%w (rollback failed: %v)", err, settleErr)
+			default:
+				results[i].Err = err
+				results[i].RolledBack = true
+			}
+		},
+		func(i int) {
+			results[i] = DistributeResult{Key: GetConnectionPool().makeKey(configs[i]), Err: errRolloutSkipped}
+		})
+	return results, status
+}
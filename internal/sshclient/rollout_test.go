@@ -0,0 +1,138 @@
+package sshclient
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestRolloutOptions_Validate(t *testing.T) {
+	assert.NoError(t, RolloutOptions{Canary: 1, BatchSize: 5, Expect: "^ok"}.Validate())
+	assert.ErrorContains(t, RolloutOptions{Canary: -1}.Validate(), "cannot be negative")
+	assert.ErrorContains(t, RolloutOptions{Expect: "("}.Validate(), "invalid expect pattern")
+
+	assert.False(t, RolloutOptions{MaxFailures: 2}.Enabled())
+	assert.True(t, RolloutOptions{Check: "true"}.Enabled())
+}
+
+func TestRolloutStages(t *testing.T) {
+	stages := rolloutStages(8, RolloutOptions{Canary: 1, BatchSize: 3})
+	require.Len(t, stages, 4)
+	assert.Equal(t, "canary", stages[0].Name)
+	assert.Equal(t, []int{0}, stages[0].Hosts)
+	assert.Equal(t, []int{1, 2, 3}, stages[1].Hosts)
+	assert.Equal(t, "batch 3", stages[3].Name)
+	assert.Equal(t, []int{7}, stages[3].Hosts)
+
+	stages = rolloutStages(3, RolloutOptions{Canary: 5})
+	require.Len(t, stages, 1, "the canary takes at most every host")
+	assert.Len(t, stages[0].Hosts, 3)
+
+	stages = rolloutStages(4, RolloutOptions{Expect: "ok"})
+	require.Len(t, stages, 1)
+	assert.Equal(t, "batch 1", stages[0].Name)
+}
+
+// fakeRollout runs rollout over n hosts whose outputs are given, a host
+// failing when its output starts with "error"
+func fakeRollout(outputs []string, opts RolloutOptions) (*RolloutResult, [][]int, map[int]error, []int) {
+	configs := make([]*Config, len(outputs))
+	var ran [][]int
+	failed := map[int]error{}
+	var skipped []int
+	result := rollout(configs, opts, nil,
+		func(indexes []int, _ []*Config, _ ProgressFunc) ([]string, []error) {
+			ran = append(ran, indexes)
+			out := make([]string, len(indexes))
+			errs := make([]error, len(indexes))
+			for j, i := range indexes {
+				out[j] = outputs[i]
+				if strings.HasPrefix(outputs[i], "error") {
+					errs[j] = errors.New(outputs[i])
+				}
+			}
+			return out, errs
+		},
+		func(i int, err error) {
+			if err != nil {
+				failed[i] = err
+			}
+		},
+		func(i int) { skipped = append(skipped, i) })
+	return result, ran, failed, skipped
+}
+
+func TestRollout(t *testing.T) {
+	// A failing canary halts before the batches
+	result, ran, _, skipped := fakeRollout([]string{"error", "ok", "ok"}, RolloutOptions{Canary: 1, BatchSize: 1})
+	assert.True(t, result.Halted)
+	assert.Equal(t, "1 of 1 canary host(s) failed", result.Reason)
+	assert.Equal(t, [][]int{{0}}, ran)
+	assert.Equal(t, []int{1, 2}, skipped)
+	assert.Equal(t, []int{1, 2}, result.Skipped)
+
+	// Output that does not match expect counts as a failure
+	result, ran, failed, _ := fakeRollout([]string{"ok", "ok", "degraded", "ok", "ok"}, RolloutOptions{Canary: 1, BatchSize: 2, MaxFailures: 1, Expect: "^ok$"})
+	assert.False(t, result.Halted, "one failure is tolerated")
+	assert.Len(t, ran, 3)
+	assert.ErrorContains(t, failed[2], `output does not match "^ok$"`)
+	assert.Equal(t, []int{2}, result.Stages[1].Failed)
+
+	// The batch that crosses the threshold finishes, the rest is skipped
+	result, ran, _, skipped = fakeRollout([]string{"ok", "error", "error", "ok", "ok"}, RolloutOptions{Canary: 1, BatchSize: 2, MaxFailures: 1})
+	assert.True(t, result.Halted)
+	assert.Equal(t, "2 host(s) failed, more than the 1 allowed", result.Reason)
+	assert.Equal(t, [][]int{{0}, {1, 2}}, ran)
+	assert.Equal(t, []int{3, 4}, skipped)
+}
+
+func TestRolloutCollect_Check(t *testing.T) {
+	var mu sync.Mutex
+	var rollbacks int
+	handler := func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		switch command {
+		case "app rollback":
+			mu.Lock()
+			rollbacks++
+			mu.Unlock()
+		case "cat /etc/app.version":
+			_, _ = channel.Write([]byte("2.1\n"))
+		case "systemctl is-active app":
+			return 3
+		}
+		return 0
+	}
+	var configs []*Config
+	for range 3 {
+		address, port, err := net.SplitHostPort(listenExecServer(t, handler))
+		require.NoError(t, err)
+		configs = append(configs, &Config{
+			Host: address, Port: port, User: "test", Password: "unused",
+			KnownHostsPath: filepath.Join(t.TempDir(), "known_hosts"), AcceptUnknownHost: true,
+		})
+	}
+
+	var progress []int64
+	results, status := RolloutCollect(configs, CollectOptions{
+		Command:  "cat /etc/app.version",
+		Rollback: "app rollback",
+		Progress: func(done, total int64, _ string) { progress = append(progress, done); assert.Equal(t, int64(3), total) },
+	}, RolloutOptions{Canary: 1, Expect: `^2\.1`, Check: "systemctl is-active app"})
+
+	assert.True(t, status.Halted)
+	require.Len(t, status.Stages, 1)
+	assert.Equal(t, "2.1\n", results[0].Output)
+	assert.ErrorContains(t, results[0].Err, "check failed")
+	assert.True(t, results[0].RolledBack)
+	assert.Equal(t, 1, rollbacks, "only the failed host is rolled back")
+	assert.ErrorIs(t, results[1].Err, errRolloutSkipped)
+	assert.NotEmpty(t, results[2].Key)
+	assert.Equal(t, []int64{1}, progress)
+}
@@ -0,0 +1,280 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+// Severity is what happens to a command the safety validator flags
+type Severity string
+
+const (
+	// SeverityInfo runs the command and only records the finding
+	SeverityInfo Severity = "info"
+	// SeverityWarn runs the command, logs a warning and annotates the result
+	SeverityWarn Severity = "warn"
+	// SeverityBlock refuses the command unless it is forced
+	SeverityBlock Severity = "block"
+)
+
+// ParseSeverity parses info, warn or block
+func ParseSeverity(value string) (Severity, error) {
+	switch severity := Severity(strings.ToLower(strings.TrimSpace(value))); severity {
+	case SeverityInfo, SeverityWarn, SeverityBlock:
+		return severity, nil
+	}
+	return "", fmt.Errorf("invalid severity %q (want info, warn or block)", value)
+}
+
+// safetyRules maps the reasons reported by ValidateCommand, by prefix, to
+// the rule names severities are configured by. The first match wins.
+var safetyRules = []struct {
+	prefix string
+	rule   string
+}{
+	{"Delete root directory", "delete-root"},
+	{"Delete all files in root directory", "delete-root"},
+	{"Delete user home directory", "delete-home"},
+	{"Delete $HOME directory", "delete-home"},
+	{"Delete system directory", "delete-system"},
+	{"Fork bomb", "fork-bomb"},
+	{"Overwrite system", "system-files"},
+	{"Overwrite block device", "disk-write"},
+	{"Dangerous dd operation", "disk-write"},
+	{"Format filesystem", "disk-format"},
+	{"Disk partition operation", "disk-format"},
+	{"Create swap partition", "disk-format"},
+	{"System reboot", "reboot"},
+	{"System kexec", "reboot"},
+	{"System ", "shutdown"},
+	{"Download and execute script", "remote-script"},
+	{"Execute decoded payload", "decoded-payload"},
+	{"Flush firewall rules", "firewall"},
+	{"Delete firewall chain", "firewall"},
+	{"Set root directory permissions", "root-permissions"},
+	{"Recursively", "root-permissions"},
+}
+
+// ruleSuggestions are safer ways to do what the commands of each rule are
+// usually meant to do
+var ruleSuggestions = map[string]string{
+	"delete-root":      "Name the directory to delete, e.g. rm -rf /srv/app/releases/old, and check that path variables are set (${DIR:?})",
+	"delete-home":      "Delete the specific files or subdirectories below the home directory",
+	"delete-system":    "Remove the specific files below the system directory, or use the package manager",
+	"fork-bomb":        "There is none; this command exhausts the process table of the host",
+	"system-files":     "Change accounts with useradd, usermod, passwd or vipw instead of overwriting the file",
+	"disk-write":       "Write to a regular file, or check the device with lsblk before writing to it",
+	"disk-format":      "Check the target device with lsblk and blkid first; formatting cannot be undone",
+	"reboot":           "Check who is logged in and what is running first, or have the host's safety policy warn about reboots",
+	"shutdown":         "Restart the affected service instead (systemctl restart UNIT), or have the host's safety policy warn about shutdowns",
+	"remote-script":    "Download the script to a file with curl -fsSL -o /tmp/install.sh URL, review it, then run sh /tmp/install.sh",
+	"decoded-payload":  "Decode into a file, review it, then run it",
+	"firewall":         "Delete single rules with iptables -D, and save the rules with iptables-save first",
+	"root-permissions": "Change the permissions of the specific directory that needs it",
+}
+
+// SafetyRules lists the rule names a SafetyPolicy can configure
+func SafetyRules() []string {
+	var rules []string
+	for _, entry := range safetyRules {
+		if len(rules) == 0 || rules[len(rules)-1] != entry.rule {
+			rules = append(rules, entry.rule)
+		}
+	}
+	return rules
+}
+
+// ruleOf is the rule a validator reason belongs to
+func ruleOf(reason string) string {
+	for _, entry := range safetyRules {
+		if strings.HasPrefix(reason, entry.prefix) {
+			return entry.rule
+		}
+	}
+	return "other"
+}
+
+// SafetyPolicy sets the severity of the commands the safety validator flags,
+// so a reboot can be allowed on a dev box and refused on a database server
+type SafetyPolicy struct {
+	// Default applies to rules without an entry in Rules (empty = block)
+	Default Severity
+	// Rules sets the severity per rule name (see SafetyRules)
+	Rules map[string]Severity
+	// Windows, when set, are the only times flagged commands the policy lets
+	// run may run; outside them they are blocked like the rest
+	Windows []MaintenanceWindow
+	// ForceTOTPKey names the keyring entry of the TOTP secret whose codes
+	// MCP clients have to send to force a blocked command (see
+	// TOTPConfirmation; empty = none)
+	ForceTOTPKey string
+}
+
+// timeNow is the clock maintenance windows are checked against
+var timeNow = time.Now
+
+// windowBlocked returns the error that blocks a command the policy lets
+// run with finding, when the policy has maintenance windows and none of
+// them is open; nil otherwise
+func (p *SafetyPolicy) windowBlocked(command string, finding *SafetyFinding) *BlockedCommandError {
+	if p == nil || finding == nil || len(p.Windows) == 0 {
+		return nil
+	}
+	next, open := NextMaintenanceWindow(p.Windows, timeNow())
+	if open {
+		return nil
+	}
+	blocked := &BlockedCommandError{
+		Command:    command,
+		Reason:     finding.Reason,
+		Rule:       finding.Rule,
+		Pattern:    finding.Pattern,
+		Severity:   SeverityBlock,
+		Suggestion: "Run it in the maintenance window, or force it with a reason if it cannot wait",
+	}
+	if !next.IsZero() {
+		blocked.NextWindow = &next
+	}
+	return blocked
+}
+
+// SeverityOf returns the severity of rule; a nil policy blocks everything
+func (p *SafetyPolicy) SeverityOf(rule string) Severity {
+	if p == nil {
+		return SeverityBlock
+	}
+	if severity, ok := p.Rules[rule]; ok {
+		return severity
+	}
+	if p.Default != "" {
+		return p.Default
+	}
+	return SeverityBlock
+}
+
+// SafetyFinding describes a flagged command that policy, or Config.Force
+// for a blocked one, let run
+type SafetyFinding struct {
+	Rule     string   `json:"rule"`
+	Reason   string   `json:"reason"`
+	Pattern  string   `json:"pattern,omitempty"`
+	Severity Severity `json:"severity"`
+}
+
+// ForceRefusedError is returned when Config.Force would override a blocked
+// command without the confirmation or reason the bypass requires. It wraps
+// the *BlockedCommandError, so the command is still reported as blocked.
+type ForceRefusedError struct {
+	Blocked *BlockedCommandError
+	// Cause says what was missing
+	Cause string
+}
+
+func (e *ForceRefusedError) Error() string {
+	return fmt.Sprintf("⚠️  Refusing to force a blocked command: %s\nCommand: %s\nRule: %s (%s)",
+		e.Cause, e.Blocked.Command, e.Blocked.Rule, e.Blocked.Reason)
+}
+
+func (e *ForceRefusedError) Unwrap() error {
+	return e.Blocked
+}
+
+// authorizeForce decides whether Config.Force may override a blocked
+// command: ConfirmForce, when set, has to approve it, and there has to be a
+// reason for the audit log
+func authorizeForce(config *Config, blocked *BlockedCommandError) error {
+	if config.ConfirmForce != nil {
+		reason, err := config.ConfirmForce(blocked)
+		if err != nil {
+			return &ForceRefusedError{Blocked: blocked, Cause: err.Error()}
+		}
+		if strings.TrimSpace(config.ForceReason) == "" {
+			config.ForceReason = strings.TrimSpace(reason)
+		}
+	}
+	if strings.TrimSpace(config.ForceReason) == "" {
+		return &ForceRefusedError{Blocked: blocked, Cause: "a reason for the bypass is required (--force-reason, or force_reason over MCP)"}
+	}
+	return nil
+}
+
+// CheckCommand validates command and applies policy to what the validator
+// flags: a *BlockedCommandError when the severity is block, otherwise the
+// finding (nil for a command that was not flagged)
+func CheckCommand(command string, policy *SafetyPolicy) (*SafetyFinding, error) {
+	err := ValidateCommand(command)
+	if err == nil {
+		return nil, nil
+	}
+	var blocked *BlockedCommandError
+	if !errors.As(err, &blocked) {
+		return nil, err
+	}
+	severity := policy.SeverityOf(blocked.Rule)
+	if severity == SeverityBlock {
+		return nil, err
+	}
+	finding := &SafetyFinding{Rule: blocked.Rule, Reason: blocked.Reason, Pattern: blocked.Pattern, Severity: severity}
+	if windowBlocked := policy.windowBlocked(blocked.Command, finding); windowBlocked != nil {
+		return nil, windowBlocked
+	}
+	return finding, nil
+}
+
+// logSafetyFinding reports a flagged command that is allowed to run
+func logSafetyFinding(finding *SafetyFinding, command string) {
+	lg := logger.GetLogger()
+	if finding.Severity == SeverityWarn {
+		lg.Warning("Safety policy allows flagged command (%s: %s): %s", finding.Rule, finding.Reason, logger.Redact(command))
+		return
+	}
+	lg.Info("Safety policy allows flagged command (%s: %s): %s", finding.Rule, finding.Reason, logger.Redact(command))
+}
+
+// Explanation is what the safety validator makes of a command
+type Explanation struct {
+	Command string `json:"command"`
+	// Flagged reports whether the validator matched a dangerous pattern
+	Flagged bool `json:"flagged"`
+	// Blocked reports whether the safety policy refuses the command, by
+	// Severity or because none of its maintenance windows is open
+	Blocked    bool     `json:"blocked"`
+	Rule       string   `json:"rule,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+	Pattern    string   `json:"pattern,omitempty"`
+	Severity   Severity `json:"severity,omitempty"`
+	Suggestion string   `json:"suggestion,omitempty"`
+	// NextWindow is when the next maintenance window opens, for a command
+	// blocked because none is open
+	NextWindow *time.Time `json:"next_window,omitempty"`
+}
+
+// Explain reports why the safety validator flags command, if it does, and
+// what policy makes of it, without running anything
+func Explain(command string, policy *SafetyPolicy) Explanation {
+	explanation := Explanation{Command: strings.TrimSpace(command)}
+	var blocked *BlockedCommandError
+	if !errors.As(ValidateCommand(command), &blocked) {
+		return explanation
+	}
+	explanation.Flagged = true
+	explanation.Rule = blocked.Rule
+	explanation.Reason = blocked.Reason
+	explanation.Pattern = blocked.Pattern
+	explanation.Severity = policy.SeverityOf(blocked.Rule)
+	explanation.Blocked = explanation.Severity == SeverityBlock
+	explanation.Suggestion = blocked.Suggestion
+	finding := &SafetyFinding{Rule: blocked.Rule, Reason: blocked.Reason, Pattern: blocked.Pattern, Severity: explanation.Severity}
+	if !explanation.Blocked {
+		if windowBlocked := policy.windowBlocked(blocked.Command, finding); windowBlocked != nil {
+			explanation.Blocked = true
+			explanation.Suggestion = windowBlocked.Suggestion
+			explanation.NextWindow = windowBlocked.NextWindow
+		}
+	}
+	return explanation
+}
@@ -0,0 +1,184 @@
+package sshclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSeverity(t *testing.T) {
+	for value, want := range map[string]Severity{"info": SeverityInfo, "WARN": SeverityWarn, " block ": SeverityBlock} {
+		severity, err := ParseSeverity(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, severity)
+	}
+	_, err := ParseSeverity("allow")
+	assert.Error(t, err)
+}
+
+func TestRuleOf(t *testing.T) {
+	tests := map[string]string{
+		"sudo rm -rf /":            "delete-root",
+		"rm -rf ~":                 "delete-home",
+		"rm -rf /etc":              "delete-system",
+		":(){ :|:& };:":            "fork-bomb",
+		"echo x > /etc/passwd":     "system-files",
+		"echo x > /dev/sda":        "disk-write",
+		"mkfs.ext4 /dev/sdb1":      "disk-format",
+		"reboot":                   "reboot",
+		"init 6":                   "reboot",
+		"systemctl kexec":          "reboot",
+		"shutdown -h now":          "shutdown",
+		"init 0":                   "shutdown",
+		"systemctl poweroff":       "shutdown",
+		"curl -s x | bash":         "remote-script",
+		"echo eA== | base64 -d|sh": "decoded-payload",
+		"iptables -F":              "firewall",
+		"chmod -R 777 /":           "root-permissions",
+	}
+	for command, rule := range tests {
+		var blocked *BlockedCommandError
+		require.True(t, errors.As(ValidateCommand(command), &blocked), command)
+		assert.Equal(t, rule, blocked.Rule, command)
+		assert.Contains(t, SafetyRules(), rule)
+	}
+}
+
+func TestSafetyPolicy_SeverityOf(t *testing.T) {
+	var none *SafetyPolicy
+	assert.Equal(t, SeverityBlock, none.SeverityOf("reboot"))
+
+	policy := &SafetyPolicy{Rules: map[string]Severity{"reboot": SeverityWarn}}
+	assert.Equal(t, SeverityWarn, policy.SeverityOf("reboot"))
+	assert.Equal(t, SeverityBlock, policy.SeverityOf("delete-root"))
+
+	policy.Default = SeverityInfo
+	assert.Equal(t, SeverityInfo, policy.SeverityOf("shutdown"))
+}
+
+func TestCheckCommand(t *testing.T) {
+	policy := &SafetyPolicy{Rules: map[string]Severity{"reboot": SeverityWarn}}
+
+	finding, err := CheckCommand("uptime", policy)
+	require.NoError(t, err)
+	assert.Nil(t, finding)
+
+	finding, err = CheckCommand("sudo reboot", policy)
+	require.NoError(t, err)
+	assert.Equal(t, &SafetyFinding{Rule: "reboot", Reason: "System reboot operation", Pattern: "reboot", Severity: SeverityWarn}, finding)
+
+	_, err = CheckCommand("rm -rf /", policy)
+	var blocked *BlockedCommandError
+	assert.True(t, errors.As(err, &blocked))
+
+	_, err = CheckCommand("sudo reboot", nil)
+	assert.True(t, errors.As(err, &blocked))
+}
+
+// fixClock sets the clock maintenance windows are checked against
+func fixClock(t *testing.T, now time.Time) {
+	t.Helper()
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+}
+
+func TestCheckCommand_MaintenanceWindows(t *testing.T) {
+	window, err := ParseMaintenanceWindow([]string{"sat"}, "02:00", "05:00", "UTC")
+	require.NoError(t, err)
+	policy := &SafetyPolicy{Rules: map[string]Severity{"reboot": SeverityWarn}, Windows: []MaintenanceWindow{window}}
+
+	fixClock(t, time.Date(2026, time.October, 17, 3, 0, 0, 0, time.UTC))
+	finding, err := CheckCommand("sudo reboot", policy)
+	require.NoError(t, err)
+	assert.Equal(t, SeverityWarn, finding.Severity)
+
+	fixClock(t, time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC))
+	_, err = CheckCommand("sudo reboot", policy)
+	var blocked *BlockedCommandError
+	require.True(t, errors.As(err, &blocked))
+	assert.Equal(t, "reboot", blocked.Rule)
+	require.NotNil(t, blocked.NextWindow)
+	assert.Equal(t, time.Date(2026, time.October, 17, 2, 0, 0, 0, time.UTC), *blocked.NextWindow)
+	assert.Contains(t, blocked.Error(), "the next one opens Sat 2026-10-17 02:00 UTC")
+
+	explanation := Explain("sudo reboot", policy)
+	assert.True(t, explanation.Blocked)
+	assert.Equal(t, SeverityWarn, explanation.Severity)
+	assert.Equal(t, blocked.NextWindow, explanation.NextWindow)
+
+	// Commands that are not flagged run at any time
+	finding, err = CheckCommand("uptime", policy)
+	require.NoError(t, err)
+	assert.Nil(t, finding)
+
+	// Forcing overrides the window like any block
+	config := &Config{Command: "sudo reboot", SafetyCheck: true, Safety: policy, Force: true, ForceReason: "kernel fix"}
+	_, err = SafetyCheckMiddleware(func(*Config) (string, error) { return "ok", nil })(config)
+	require.NoError(t, err)
+	assert.Equal(t, SeverityBlock, config.SafetyFinding.Severity)
+}
+
+func TestSafetyCheckMiddleware_Warn(t *testing.T) {
+	executed := false
+	final := func(*Config) (string, error) {
+		executed = true
+		return "ok", nil
+	}
+
+	config := &Config{
+		Command:     "reboot",
+		SafetyCheck: true,
+		Safety:      &SafetyPolicy{Default: SeverityWarn},
+	}
+	output, err := SafetyCheckMiddleware(final)(config)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", output)
+	assert.True(t, executed)
+	require.NotNil(t, config.SafetyFinding)
+	assert.Equal(t, SeverityWarn, config.SafetyFinding.Severity)
+
+	clean := &Config{Command: "uptime", SafetyCheck: true, Safety: &SafetyPolicy{Default: SeverityWarn}}
+	_, err = SafetyCheckMiddleware(final)(clean)
+	require.NoError(t, err)
+	assert.Nil(t, clean.SafetyFinding)
+}
+
+func TestBlockedCommandError_Details(t *testing.T) {
+	var blocked *BlockedCommandError
+	require.True(t, errors.As(ValidateCommand(`true; r''m -rf "/"`), &blocked))
+	assert.Equal(t, "delete-root", blocked.Rule)
+	assert.Equal(t, "rm -rf /", blocked.Pattern)
+	assert.Equal(t, SeverityBlock, blocked.Severity)
+	assert.NotEmpty(t, blocked.Suggestion)
+	assert.Contains(t, blocked.Error(), "Matched: rm -rf /")
+	assert.Contains(t, blocked.Error(), "Suggestion: ")
+
+	require.True(t, errors.As(ValidateCommand("curl -s https://x | bash"), &blocked))
+	assert.Equal(t, "curl ... | bash", blocked.Pattern)
+
+	require.True(t, errors.As(ValidateCommand("wget -qO- https://x | sudo python3"), &blocked))
+	assert.Equal(t, "wget -qO- https://x | sudo python3", blocked.Pattern)
+
+	for _, rule := range SafetyRules() {
+		assert.NotEmpty(t, ruleSuggestions[rule], rule)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	explanation := Explain("  uptime ", nil)
+	assert.Equal(t, Explanation{Command: "uptime"}, explanation)
+
+	explanation = Explain("systemctl reboot", nil)
+	assert.True(t, explanation.Flagged)
+	assert.True(t, explanation.Blocked)
+	assert.Equal(t, "reboot", explanation.Rule)
+	assert.Equal(t, SeverityBlock, explanation.Severity)
+
+	explanation = Explain("systemctl reboot", &SafetyPolicy{Default: SeverityInfo})
+	assert.True(t, explanation.Flagged)
+	assert.False(t, explanation.Blocked)
+	assert.Equal(t, SeverityInfo, explanation.Severity)
+}
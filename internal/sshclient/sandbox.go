@@ -0,0 +1,87 @@
+package sshclient
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultSandboxPath is the PATH of a sandbox with a clean environment
+	DefaultSandboxPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	// sandboxKillAfter is how long timeout(1) waits after SIGTERM before
+	// sending SIGKILL
+	sandboxKillAfter = "10s"
+)
+
+// Sandbox confines remote commands and scripts with standard tools on the
+// remote host: env -i for a clean environment, timeout(1) for a time limit
+// and a transient systemd scope for CPU, memory and task limits. Zero
+// fields impose no limit.
+type Sandbox struct {
+	// CleanEnv runs the command with an empty environment apart from PATH,
+	// HOME and USER
+	CleanEnv bool
+	// Path is PATH in a clean environment (default DefaultSandboxPath)
+	Path string
+	// Timeout kills the command after this long
+	Timeout time.Duration
+	// CPUQuota is the systemd CPUQuota of the scope, e.g. "50%"
+	CPUQuota string
+	// MemoryMax is the systemd MemoryMax of the scope, e.g. "512M"
+	MemoryMax string
+	// TasksMax is the systemd TasksMax of the scope
+	TasksMax int
+}
+
+// enabled reports whether the sandbox imposes anything
+func (s *Sandbox) enabled() bool {
+	return s != nil && (s.CleanEnv || s.Timeout > 0 || s.usesScope())
+}
+
+// usesScope reports whether the command must run in a systemd scope
+func (s *Sandbox) usesScope() bool {
+	return s.CPUQuota != "" || s.MemoryMax != "" || s.TasksMax > 0
+}
+
+// Wrap returns command confined by the sandbox, for a login as user. The
+// scope is created in the user's own systemd instance unless user is root.
+// A nil or empty sandbox leaves the command unchanged.
+func (s *Sandbox) Wrap(command, user string) string {
+	if !s.enabled() {
+		return command
+	}
+
+	var parts []string
+	if s.usesScope() {
+		parts = append(parts, "systemd-run")
+		if user != "root" {
+			parts = append(parts, "--user")
+		}
+		parts = append(parts, "--scope", "--quiet", "--collect")
+		if s.CPUQuota != "" {
+			parts = append(parts, "-p", ShellQuote("CPUQuota="+s.CPUQuota))
+		}
+		if s.MemoryMax != "" {
+			parts = append(parts, "-p", ShellQuote("MemoryMax="+s.MemoryMax))
+		}
+		if s.TasksMax > 0 {
+			parts = append(parts, "-p", fmt.Sprintf("TasksMax=%d", s.TasksMax))
+		}
+		parts = append(parts, "--")
+	}
+	if s.CleanEnv {
+		path := s.Path
+		if path == "" {
+			path = DefaultSandboxPath
+		}
+		// HOME and USER are expanded by the login shell before env clears the rest
+		parts = append(parts, "env", "-i", ShellQuote("PATH="+path), `HOME="$HOME"`, `USER="$USER"`)
+	}
+	if s.Timeout > 0 {
+		seconds := int64((s.Timeout + time.Second - 1) / time.Second)
+		parts = append(parts, "timeout", "--kill-after="+sandboxKillAfter, fmt.Sprintf("%ds", seconds))
+	}
+	parts = append(parts, "sh", "-c", ShellQuote(command))
+	return strings.Join(parts, " ")
+}
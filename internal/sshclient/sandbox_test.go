@@ -0,0 +1,95 @@
+package sshclient
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSandboxWrap(t *testing.T) {
+	var none *Sandbox
+	assert.Equal(t, "uptime", none.Wrap("uptime", "ops"))
+	assert.Equal(t, "uptime", (&Sandbox{}).Wrap("uptime", "ops"))
+
+	assert.Equal(t, "timeout --kill-after=10s 300s sh -c 'uptime'",
+		(&Sandbox{Timeout: 5 * time.Minute}).Wrap("uptime", "ops"))
+	assert.Equal(t, "timeout --kill-after=10s 2s sh -c 'uptime'",
+		(&Sandbox{Timeout: 1500 * time.Millisecond}).Wrap("uptime", "ops"))
+
+	assert.Equal(t, `env -i 'PATH=/usr/bin:/bin' HOME="$HOME" USER="$USER" sh -c 'echo '\''hi'\'''`,
+		(&Sandbox{CleanEnv: true, Path: "/usr/bin:/bin"}).Wrap("echo 'hi'", "ops"))
+	assert.Contains(t, (&Sandbox{CleanEnv: true}).Wrap("id", "ops"), "'PATH="+DefaultSandboxPath+"'")
+
+	full := &Sandbox{CleanEnv: true, Timeout: time.Minute, CPUQuota: "50%", MemoryMax: "512M", TasksMax: 64}
+	assert.Equal(t, "systemd-run --user --scope --quiet --collect -p 'CPUQuota=50%' -p 'MemoryMax=512M' -p TasksMax=64 -- "+
+		`env -i 'PATH=`+DefaultSandboxPath+`' HOME="$HOME" USER="$USER" timeout --kill-after=10s 60s sh -c 'make'`,
+		full.Wrap("make", "ops"))
+	assert.Equal(t, "systemd-run --scope --quiet --collect -p 'MemoryMax=1G' -- sh -c 'make'",
+		(&Sandbox{MemoryMax: "1G"}).Wrap("make", "root"))
+}
+
+func TestExecute_Sandboxed(t *testing.T) {
+	var received []string
+	var password string
+	conn := startExecServer(t, func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		received = append(received, command)
+		if strings.HasPrefix(command, "timeout --kill-after=10s 30s sh -c 'sudo") {
+			password, _ = bufio.NewReader(channel).ReadString('\n') //nolint:errcheck // compared below
+		}
+		return 0
+	})
+
+	client := &SSHClient{client: conn, config: &Config{
+		User:     "ops",
+		Password: "secret",
+		Sandbox:  &Sandbox{Timeout: 30 * time.Second},
+	}}
+	_, err := client.Execute(Request{Command: "uptime"})
+	require.NoError(t, err)
+	_, err = client.Execute(Request{Command: "id", RunAs: "app"})
+	require.NoError(t, err)
+
+	require.Len(t, received, 2)
+	assert.Equal(t, "timeout --kill-after=10s 30s sh -c 'uptime'", received[0])
+	// sudo reads the password from stdin inside the sandbox
+	assert.Equal(t, `timeout --kill-after=10s 30s sh -c 'sudo -S -p '\'''\'' -H -u app -- sh -c id'`, received[1])
+	assert.Equal(t, "secret\n", password)
+}
+
+func TestExecute_CallerEnv(t *testing.T) {
+	var received []string
+	conn := startExecServer(t, func(command string, _ ssh.Channel, _ <-chan struct{}) uint32 {
+		received = append(received, command)
+		return 0
+	})
+
+	client := &SSHClient{client: conn, config: &Config{
+		User:   "ops",
+		Client: "claude-desktop/0.9.2",
+		Caller: "ci",
+	}}
+	_, err := client.Execute(Request{Command: "uptime"})
+	require.NoError(t, err)
+
+	// The identity survives a clean environment
+	client.config.Sandbox = &Sandbox{CleanEnv: true}
+	client.config.Caller = ""
+	_, err = client.Execute(Request{Command: "env"})
+	require.NoError(t, err)
+
+	require.Len(t, received, 2)
+	assert.Equal(t, "export SSHX_CALLER='claude-desktop/0.9.2 (ci)'; uptime", received[0])
+	assert.Equal(t, `env -i 'PATH=`+DefaultSandboxPath+`' HOME="$HOME" USER="$USER" sh -c 'export SSHX_CALLER='\''claude-desktop/0.9.2'\''; env'`, received[1])
+}
+
+func TestConfigIdentity(t *testing.T) {
+	assert.Empty(t, (&Config{}).Identity())
+	assert.Equal(t, "ci", (&Config{Caller: "ci"}).Identity())
+	assert.Equal(t, "cursor/1.0", (&Config{Client: "cursor/1.0"}).Identity())
+	assert.Equal(t, "cursor/1.0 (ci)", (&Config{Client: "cursor/1.0", Caller: "ci"}).Identity())
+}
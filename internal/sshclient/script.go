@@ -1,22 +1,79 @@
 package sshclient
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/talkincode/sshmcp/pkg/logger"
+)
+
+const (
+	// DefaultTempDir is the remote directory scripts are uploaded to when
+	// no temp_dir is configured
+	DefaultTempDir = "/tmp"
+	// TempFilePrefix starts the name of every file sshx leaves in the
+	// remote temp directory
+	TempFilePrefix = "sshx-script-"
+	// staleTempFileAge is how old a leftover temp file must be before a
+	// later script run removes it
+	staleTempFileAge = 24 * time.Hour
 )
 
+// scriptKillGrace is how long a killed script may take to exit before its
+// session is closed
+const scriptKillGrace = 5 * time.Second
+
+// ScriptTimeoutError reports a script killed after exceeding its timeout.
+// The output captured before the kill is returned alongside it.
+type ScriptTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *ScriptTimeoutError) Error() string {
+	return fmt.Sprintf("script timed out after %s and was killed", e.Timeout)
+}
+
+// ScriptSyntaxError reports a script rejected by the pre-flight syntax
+// check; the script was not run
+type ScriptSyntaxError struct {
+	Output string
+}
+
+func (e *ScriptSyntaxError) Error() string {
+	if e.Output == "" {
+		return "syntax check failed, script not run"
+	}
+	return "syntax check failed, script not run:\n" + e.Output
+}
+
 // ExecuteScript executes a local script file
-// 1. Upload script to remote temp directory
-// 2. Add execute permission
-// 3. Execute script
-// 4. Clean up temp file
-func (c *SSHClient) ExecuteScript(localScriptPath string) (output string, err error) {
+// 1. Upload script to the remote temp directory under a random name
+// 2. Execute script
+// 3. Clean up temp files
+// The script receives req.ScriptArgs as arguments.
+func (c *SSHClient) ExecuteScript(localScriptPath string, req Request) (output string, err error) {
+	op := c.newOperation(req)
+	defer acquireHostSlot(op.config)()
+	return op.executeScript(localScriptPath)
+}
+
+// uploadScript copies a local script to a new file in the remote temp
+// directory. The name carries a random component and is created
+// exclusively with owner-only permissions, so other users on the host can
+// neither predict nor replace it. Scripts run as another user (RunAs) must
+// be readable by that user and are made world-readable instead.
+func (c *operation) uploadScript(localScriptPath string) (remotePath string, err error) {
 	// 1. Check if local script exists
 	if _, statErr := os.Stat(localScriptPath); statErr != nil {
 		return "", fmt.Errorf("local script not found: %w", statErr)
@@ -29,90 +86,209 @@ func (c *SSHClient) ExecuteScript(localScriptPath string) (output string, err er
 	}
 
 	// 3. Generate remote temp file path
-	scriptName := filepath.Base(localScriptPath)
-	timestamp := time.Now().Unix()
-	remotePath := fmt.Sprintf("/tmp/sshx-script-%d-%s", timestamp, scriptName)
+	remotePath, err = remoteScriptPath(tempDir(c.config), filepath.Base(localScriptPath))
+	if err != nil {
+		return "", err
+	}
 
 	// 4. Ensure SFTP client is available
 	if c.sftpClient == nil {
-		sftpClient, sftpErr := sftp.NewClient(c.client)
+		sftpClient, sftpErr := newSFTPClient(c.client, c.config)
 		if sftpErr != nil {
 			return "", fmt.Errorf("failed to create SFTP client: %w", sftpErr)
 		}
 		c.sftpClient = sftpClient
-		defer CloseIgnore(&err, c.sftpClient, io.EOF)
+		defer func() {
+			CloseIgnore(&err, sftpClient, io.EOF)
+			c.sftpClient = nil
+		}()
 	}
 
-	// 5. Upload script to remote
-	remoteFile, err := c.sftpClient.Create(remotePath)
+	// 5. Create the file exclusively and restrict it before writing content
+	remoteFile, err := c.sftpClient.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
 	if err != nil {
-		return "", fmt.Errorf("failed to create remote file: %w", err)
+		return "", fmt.Errorf("failed to create remote file in %s: %w", tempDir(c.config), err)
+	}
+	mode := os.FileMode(0o700)
+	if c.config.RunAs != "" {
+		mode = 0o755
+	}
+	if err = remoteFile.Chmod(mode); err != nil {
+		_ = remoteFile.Close()              //nolint:errcheck // chmod already failed
+		_ = c.sftpClient.Remove(remotePath) //nolint:errcheck // best effort
+		return "", fmt.Errorf("failed to chmod script: %w", err)
 	}
-
 	if _, err = remoteFile.Write(scriptContent); err != nil {
-		if closeErr := remoteFile.Close(); closeErr != nil {
-			// Ignore close error when write already failed
-			_ = closeErr
-		}
+		_ = remoteFile.Close()              //nolint:errcheck // write already failed
+		_ = c.sftpClient.Remove(remotePath) //nolint:errcheck // best effort
 		return "", fmt.Errorf("failed to write script: %w", err)
 	}
 	if err = remoteFile.Close(); err != nil {
+		_ = c.sftpClient.Remove(remotePath) //nolint:errcheck // best effort
 		return "", fmt.Errorf("failed to close remote file: %w", err)
 	}
+	return remotePath, nil
+}
 
-	// 6. Add execute permission
-	if err := c.sftpClient.Chmod(remotePath, 0755); err != nil {
-		return "", fmt.Errorf("failed to chmod script: %w", err)
+// tempDir is the remote directory scripts are uploaded to
+func tempDir(config *Config) string {
+	if config != nil && config.TempDir != "" {
+		return config.TempDir
 	}
+	return DefaultTempDir
+}
 
-	// 7. Execute script
-	output, execErr := c.executeRemoteScript(remotePath)
+// remoteScriptPath returns a fresh, unpredictable path for a script in dir
+func remoteScriptPath(dir, scriptName string) (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate script name: %w", err)
+	}
+	return path.Join(dir, fmt.Sprintf("%s%s-%s", TempFilePrefix, hex.EncodeToString(random), scriptName)), nil
+}
 
-	// 8. Clean up temp file (regardless of execution result)
-	cleanupCmd := fmt.Sprintf("rm -f %s", remotePath)
-	if cleanupErr := c.executeSimpleCommand(cleanupCmd); cleanupErr != nil {
-		// Log cleanup error but don't fail the operation
-		_ = cleanupErr // Cleanup is best-effort
+// cleanupScript removes an uploaded script and its PID file. Files of
+// earlier runs that were never removed (a dropped connection, a killed
+// sshx) are swept at the same time once they are older than
+// staleTempFileAge.
+func (c *operation) cleanupScript(remotePath string) {
+	cleanupCmd := BuildCommand("rm", "-f", "--", remotePath, scriptPIDFile(remotePath)) + "; " +
+		staleTempFilesCommand(path.Dir(remotePath), staleTempFileAge, false)
+	if err := c.executeSimpleCommand(cleanupCmd); err != nil {
+		// Cleanup is best-effort
+		logger.GetLogger().Debug("failed to clean up %s: %v", remotePath, err)
 	}
+}
 
-	// 9. Return execution result
-	if execErr != nil {
-		return output, fmt.Errorf("script execution failed: %w", execErr)
+// runScript runs a script command, streaming its interleaved output to
+// StreamOutput when set. The remote shell records its PID next to the
+// script so a script exceeding ScriptTimeout can be killed from a second
+// session; the output captured until then is returned with a
+// *ScriptTimeoutError.
+func (c *operation) runScript(session *ssh.Session, command, remotePath string) (string, error) {
+	capture := newOutputCapture(c.config)
+	capture.stderr = capture.stdout
+	stdout, _ := capture.writers()
+	if c.config.StreamOutput != nil {
+		stdout = &lockedWriter{w: io.MultiWriter(stdout, c.config.StreamOutput)}
 	}
+	if c.config.Progress != nil {
+		lines := &progressLineWriter{report: c.reportStep}
+		stdout = &lockedWriter{w: io.MultiWriter(stdout, lines)}
+	}
+	session.Stdout = stdout
+	session.Stderr = stdout
 
-	return output, nil
+	pidFile := scriptPIDFile(remotePath)
+	if err := session.Start(fmt.Sprintf("echo $$ > %s; exec %s", ShellQuote(pidFile), command)); err != nil {
+		return "", fmt.Errorf("failed to start script: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+
+	var timeout <-chan time.Time
+	if c.config.ScriptTimeout > 0 {
+		timer := time.NewTimer(c.config.ScriptTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case err = <-done:
+	case <-timeout:
+		c.killRemoteScript(session, pidFile)
+		select {
+		case <-done:
+		case <-time.After(scriptKillGrace):
+			// Closing the channel stops further output even if the kill failed
+			_ = session.Close() //nolint:errcheck // best effort after timeout
+			<-done
+		}
+		err = &ScriptTimeoutError{Timeout: c.config.ScriptTimeout}
+	}
+
+	output, _ := capture.finish()
+	c.outputTruncated = capture.truncated()
+	return output, err
+}
+
+// killRemoteScript sends SIGKILL to the script's process group, so child
+// processes die too, both as a channel signal and with kill(1) over a second
+// session for servers that ignore signal requests. A script run as another
+// user is owned by sudo, which cannot relay SIGKILL, so it first gets a
+// SIGTERM that sudo passes on.
+func (c *operation) killRemoteScript(session *ssh.Session, pidFile string) {
+	lg := logger.GetLogger()
+	if err := session.Signal(ssh.SIGKILL); err != nil {
+		lg.Debug("failed to signal script session: %v", err)
+	}
+	killCmd := fmt.Sprintf("pid=$(cat %s 2>/dev/null) && { kill -KILL -- -$pid 2>/dev/null || kill -KILL $pid; }", ShellQuote(pidFile))
+	if c.config.RunAs != "" {
+		killCmd = fmt.Sprintf("pid=$(cat %s 2>/dev/null) && { kill -TERM $pid; sleep 2; kill -KILL -- -$pid 2>/dev/null || kill -KILL $pid; }", ShellQuote(pidFile))
+	}
+	if err := c.executeSimpleCommand(killCmd); err != nil {
+		lg.Warning("failed to kill timed out script on %s: %v", c.config.Host, err)
+	}
+}
+
+// lockedWriter serializes writes from the stdout and stderr copy goroutines
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// staleTempFilesCommand builds a find(1) command removing this user's temp
+// files in dir older than age, printing each removed path when verbose
+func staleTempFilesCommand(dir string, age time.Duration, verbose bool) string {
+	action := "-exec rm -f {} +"
+	if verbose {
+		action = "-print -exec rm -f {} +"
+	}
+	return BuildCommand("find", dir, "-maxdepth", "1", "-type", "f", "-name", TempFilePrefix+"*") +
+		fmt.Sprintf(` -user "$(id -u)" -mmin +%d %s 2>/dev/null`, int(age.Minutes()), action)
 }
 
-// executeRemoteScript executes a remote script
-func (c *SSHClient) executeRemoteScript(remotePath string) (output string, err error) {
+// CleanupTempFiles removes temp files left in the remote temp directory by
+// scripts that were never cleaned up, skipping files modified within
+// olderThan so running scripts keep theirs. It returns the removed paths.
+func (c *SSHClient) CleanupTempFiles(olderThan time.Duration) ([]string, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	dir := tempDir(c.config)
 	session, err := c.client.NewSession()
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
+		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
-	defer CloseIgnore(&err, session, io.EOF)
+	defer func() { _ = session.Close() }() //nolint:errcheck // Output already waited
 
-	// Detect script type and execute
-	var command string
-	if strings.HasSuffix(remotePath, ".sh") || strings.HasSuffix(remotePath, ".bash") {
-		command = fmt.Sprintf("bash %s", remotePath)
-	} else if strings.HasSuffix(remotePath, ".py") || strings.HasSuffix(remotePath, ".python") {
-		command = fmt.Sprintf("python3 %s", remotePath)
-	} else if strings.HasSuffix(remotePath, ".pl") {
-		command = fmt.Sprintf("perl %s", remotePath)
-	} else if strings.HasSuffix(remotePath, ".rb") {
-		command = fmt.Sprintf("ruby %s", remotePath)
-	} else {
-		// Default to shell script
-		command = fmt.Sprintf("bash %s", remotePath)
+	output, err := session.Output(staleTempFilesCommand(dir, olderThan, true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to clean up %s: %w", dir, err)
+	}
+	removed := []string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			removed = append(removed, line)
+		}
 	}
+	return removed, nil
+}
 
-	outputBytes, err := session.CombinedOutput(command)
-	output = string(outputBytes)
-	return output, err
+// scriptPIDFile is where the remote shell running a script records its PID
+func scriptPIDFile(remotePath string) string {
+	return remotePath + ".pid"
 }
 
 // executeSimpleCommand executes a simple command (used for cleanup, etc.)
-func (c *SSHClient) executeSimpleCommand(command string) (err error) {
+func (c *operation) executeSimpleCommand(command string) (err error) {
 	session, err := c.client.NewSession()
 	if err != nil {
 		return err
@@ -122,90 +298,109 @@ func (c *SSHClient) executeSimpleCommand(command string) (err error) {
 	return session.Run(command)
 }
 
-// ExecuteScriptWithArgs executes a script with arguments
-func (c *SSHClient) ExecuteScriptWithArgs(localScriptPath string, args []string) (output string, err error) {
-	// 1. Check if local script exists
-	if _, statErr := os.Stat(localScriptPath); statErr != nil {
-		return "", fmt.Errorf("local script not found: %w", statErr)
+// executeScript uploads, runs and removes a script with ScriptArgs
+func (c *operation) executeScript(localScriptPath string) (output string, err error) {
+	if isPowerShell(c.config.Shell) {
+		return "", fmt.Errorf("scripts are not supported with shell %s", c.config.Shell)
 	}
-
-	// 2. Read script content
-	scriptContent, err := os.ReadFile(localScriptPath) //nolint:gosec // G304: script path is provided by user
+	name := filepath.Base(localScriptPath)
+	c.reportStep("uploading " + name)
+	remotePath, err := c.uploadScript(localScriptPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read script: %w", err)
+		return "", err
 	}
+	defer c.cleanupScript(remotePath)
 
-	// 3. Generate remote temp file path
-	scriptName := filepath.Base(localScriptPath)
-	timestamp := time.Now().Unix()
-	remotePath := fmt.Sprintf("/tmp/sshx-script-%d-%s", timestamp, scriptName)
-
-	// 4. Ensure SFTP client is available
-	if c.sftpClient == nil {
-		sftpClient, sftpErr := sftp.NewClient(c.client)
-		if sftpErr != nil {
-			return "", fmt.Errorf("failed to create SFTP client: %w", sftpErr)
+	if c.config.CheckSyntax {
+		if err = c.checkScriptSyntax(remotePath); err != nil {
+			return "", err
 		}
-		c.sftpClient = sftpClient
-		defer CloseIgnore(&err, c.sftpClient, io.EOF)
 	}
 
-	// 5. Upload script
-	remoteFile, err := c.sftpClient.Create(remotePath)
+	// Build command with arguments
+	command := BuildCommand(append([]string{detectInterpreter(remotePath), remotePath}, c.config.ScriptArgs...)...)
+	command = shellCommandLine(c.config, command)
+
+	session, err := c.client.NewSession()
 	if err != nil {
-		return "", fmt.Errorf("failed to create remote file: %w", err)
+		return "", fmt.Errorf("failed to create session: %w", err)
 	}
-	defer CloseIgnore(&err, remoteFile, io.EOF)
+	defer CloseIgnore(&err, session, io.EOF)
 
-	if _, err = remoteFile.Write(scriptContent); err != nil {
-		return "", fmt.Errorf("failed to write script: %w", err)
+	if c.config.RunAs != "" {
+		command = runAsCommand(c.config.RunAs, command)
+		if c.config.Password != "" {
+			if err = feedPassword(session, c.config.Password); err != nil {
+				return "", err
+			}
+			command = sudoStdinCommand(command)
+		}
 	}
 
-	// 6. Add execute permission
-	if err = c.sftpClient.Chmod(remotePath, 0755); err != nil {
-		return "", fmt.Errorf("failed to chmod script: %w", err)
+	command = c.sandboxed(command)
+
+	c.reportStep("running " + name)
+	output, execErr := c.runScript(session, command, remotePath)
+	if execErr != nil {
+		return output, fmt.Errorf("script execution failed: %w", execErr)
 	}
 
-	// 7. Build command with arguments
-	var command string
-	interpreter := c.detectInterpreter(remotePath)
-	escapedArgs := make([]string, len(args))
-	for i, arg := range args {
-		// Simple argument escaping
-		escapedArgs[i] = fmt.Sprintf("'%s'", strings.ReplaceAll(arg, "'", "'\\''"))
+	if c.config.Progress != nil {
+		c.progressSteps++
+		c.config.Progress(c.progressSteps, c.progressSteps, "finished "+name)
 	}
+	return output, nil
+}
 
-	command = fmt.Sprintf("%s %s %s", interpreter, remotePath, strings.Join(escapedArgs, " "))
+// reportStep reports a script step (upload, run, a line of output) when
+// Config.Progress is set. A script's length is unknown, so the total stays
+// 0 until the final report.
+func (c *operation) reportStep(item string) {
+	if c.config.Progress != nil {
+		c.progressSteps++
+		c.config.Progress(c.progressSteps, 0, item)
+	}
+}
 
-	// 8. Execute script
+// checkScriptSyntax parses an uploaded script with its interpreter without
+// running it, returning a *ScriptSyntaxError with the interpreter's
+// diagnostics when the script does not parse
+func (c *operation) checkScriptSyntax(remotePath string) (err error) {
 	session, err := c.client.NewSession()
 	if err != nil {
-		// Try to clean up on error
-		if cleanupErr := c.executeSimpleCommand(fmt.Sprintf("rm -f %s", remotePath)); cleanupErr != nil {
-			_ = cleanupErr // Cleanup is best-effort
-		}
-		return "", fmt.Errorf("failed to create session: %w", err)
+		return fmt.Errorf("failed to create session: %w", err)
 	}
 	defer CloseIgnore(&err, session, io.EOF)
 
-	outputBytes, execErr := session.CombinedOutput(command)
-	output = string(outputBytes)
-
-	// 9. Clean up temp file
-	if cleanupErr := c.executeSimpleCommand(fmt.Sprintf("rm -f %s", remotePath)); cleanupErr != nil {
-		_ = cleanupErr // Cleanup is best-effort
+	output, runErr := session.CombinedOutput(syntaxCheckCommand(remotePath))
+	var exitErr *ssh.ExitError
+	if errors.As(runErr, &exitErr) {
+		return &ScriptSyntaxError{Output: strings.TrimSpace(string(output))}
 	}
-
-	// 10. Return execution result
-	if execErr != nil {
-		return output, fmt.Errorf("script execution failed: %w", execErr)
+	if runErr != nil {
+		return fmt.Errorf("failed to check script syntax: %w", runErr)
 	}
+	return nil
+}
 
-	return output, nil
+// syntaxCheckCommand returns the command that parses a script without
+// executing it, for the interpreter detectInterpreter picks
+func syntaxCheckCommand(remotePath string) string {
+	switch detectInterpreter(remotePath) {
+	case "python3":
+		// py_compile would leave a __pycache__ next to the script
+		return BuildCommand("python3", "-c", "import ast, sys; ast.parse(open(sys.argv[1]).read(), sys.argv[1])", remotePath)
+	case "perl":
+		return BuildCommand("perl", "-c", remotePath)
+	case "ruby":
+		return BuildCommand("ruby", "-c", remotePath)
+	default:
+		return BuildCommand("bash", "-n", remotePath)
+	}
 }
 
 // detectInterpreter detects the script interpreter
-func (c *SSHClient) detectInterpreter(remotePath string) string {
+func detectInterpreter(remotePath string) string {
 	if strings.HasSuffix(remotePath, ".sh") || strings.HasSuffix(remotePath, ".bash") {
 		return "bash"
 	} else if strings.HasSuffix(remotePath, ".py") || strings.HasSuffix(remotePath, ".python") {
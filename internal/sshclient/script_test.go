@@ -1,14 +1,90 @@
 package sshclient
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
 )
 
-func TestDetectInterpreter(t *testing.T) {
-	client := &SSHClient{}
+// execHandler serves one exec request and returns the exit status. stop is
+// closed when the client signals the session or closes the channel.
+type execHandler func(command string, channel ssh.Channel, stop <-chan struct{}) uint32
+
+// startExecServer runs an SSH server without authentication that passes
+// every exec request to handler, and returns a client connected to it
+func startExecServer(t *testing.T, handler execHandler) *ssh.Client {
+	t.Helper()
+
+	client, err := ssh.Dial("tcp", listenExecServer(t, handler), &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // #nosec G106 -- in-process test server
+		Timeout:         5 * time.Second,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// listenExecServer starts the server of startExecServer and returns its
+// address
+func listenExecServer(t *testing.T, handler execHandler) string {
+	t.Helper()
+	return listenTestSSHServer(t, nil, func(conn net.Conn, serverConfig *ssh.ServerConfig) {
+		serveExecConn(conn, serverConfig, handler)
+	})
+}
+
+func serveExecConn(conn net.Conn, serverConfig *ssh.ServerConfig, handler execHandler) {
+	defer func() { _ = conn.Close() }()
+	_, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			stop := make(chan struct{})
+			var stopOnce sync.Once
+			closeStop := func() { stopOnce.Do(func() { close(stop) }) }
+			defer closeStop()
 
+			for req := range requests {
+				switch req.Type {
+				case "exec":
+					command := string(req.Payload[4:])
+					_ = req.Reply(true, nil)
+					go func() {
+						status := handler(command, channel, stop)
+						payload := make([]byte, 4)
+						binary.BigEndian.PutUint32(payload, status)
+						_, _ = channel.SendRequest("exit-status", false, payload)
+						_ = channel.Close()
+					}()
+				case "signal":
+					closeStop()
+				default:
+					_ = req.Reply(false, nil)
+				}
+			}
+		}()
+	}
+}
+
+func TestDetectInterpreter(t *testing.T) {
 	tests := []struct {
 		name           string
 		remotePath     string
@@ -63,15 +139,13 @@ func TestDetectInterpreter(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			interp := client.detectInterpreter(tt.remotePath)
+			interp := detectInterpreter(tt.remotePath)
 			assert.Equal(t, tt.expectedInterp, interp)
 		})
 	}
 }
 
 func TestDetectInterpreter_EdgeCases(t *testing.T) {
-	client := &SSHClient{}
-
 	tests := []struct {
 		name       string
 		remotePath string
@@ -87,7 +161,7 @@ func TestDetectInterpreter_EdgeCases(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := client.detectInterpreter(tt.remotePath)
+			result := detectInterpreter(tt.remotePath)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -98,5 +172,119 @@ func TestSSHClient_NilClientHandling(t *testing.T) {
 
 	// Test behavior without a connection
 	assert.Nil(t, client.client)
-	assert.Nil(t, client.sftpClient)
+}
+
+func TestRunScript_StreamsOutput(t *testing.T) {
+	client := startExecServer(t, func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		if !strings.HasPrefix(command, "echo $$ > '/tmp/s.sh.pid'; exec bash /tmp/s.sh") {
+			_, _ = channel.Stderr().Write([]byte("unexpected command: " + command))
+			return 1
+		}
+		_, _ = channel.Write([]byte("step 1\n"))
+		_, _ = channel.Stderr().Write([]byte("warning\n"))
+		_, _ = channel.Write([]byte("step 2\n"))
+		return 0
+	})
+
+	var streamed bytes.Buffer
+	c := &operation{client: client, config: &Config{StreamOutput: &streamed}}
+	session, err := client.NewSession()
+	require.NoError(t, err)
+	defer func() { _ = session.Close() }()
+
+	output, err := c.runScript(session, "bash /tmp/s.sh", "/tmp/s.sh")
+	require.NoError(t, err)
+	assert.Contains(t, output, "step 1\n")
+	assert.Contains(t, output, "warning\n")
+	assert.Contains(t, output, "step 2\n")
+	assert.Equal(t, len(output), streamed.Len())
+}
+
+func TestRunScript_TimeoutKillsScript(t *testing.T) {
+	var mu sync.Mutex
+	var commands []string
+	client := startExecServer(t, func(command string, channel ssh.Channel, stop <-chan struct{}) uint32 {
+		mu.Lock()
+		commands = append(commands, command)
+		mu.Unlock()
+		if strings.Contains(command, "kill -KILL") {
+			return 0
+		}
+		_, _ = channel.Write([]byte("partial output\n"))
+		<-stop
+		return 137
+	})
+
+	c := &operation{client: client, config: &Config{Host: "test", ScriptTimeout: 200 * time.Millisecond}}
+	session, err := client.NewSession()
+	require.NoError(t, err)
+	defer func() { _ = session.Close() }()
+
+	started := time.Now()
+	output, err := c.runScript(session, "bash /tmp/hang.sh", "/tmp/hang.sh")
+	assert.Less(t, time.Since(started), scriptKillGrace)
+
+	var timeoutErr *ScriptTimeoutError
+	require.True(t, errors.As(err, &timeoutErr), "expected ScriptTimeoutError, got %v", err)
+	assert.Equal(t, 200*time.Millisecond, timeoutErr.Timeout)
+	assert.Equal(t, "partial output\n", output)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, commands, 2)
+	assert.Contains(t, commands[1], "cat '/tmp/hang.sh.pid'")
+	assert.Contains(t, commands[1], "kill -KILL -- -$pid")
+}
+
+func TestRemoteScriptPath(t *testing.T) {
+	first, err := remoteScriptPath("/var/tmp/sshx", "deploy.sh")
+	require.NoError(t, err)
+	second, err := remoteScriptPath("/var/tmp/sshx", "deploy.sh")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(first, "/var/tmp/sshx/"+TempFilePrefix), first)
+	assert.True(t, strings.HasSuffix(first, "-deploy.sh"), first)
+	assert.NotEqual(t, first, second)
+}
+
+func TestCleanupTempFiles(t *testing.T) {
+	var received string
+	client := startExecServer(t, func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		received = command
+		_, _ = channel.Write([]byte("/scratch/sshx-script-ab12-old.sh\n/scratch/sshx-script-ab12-old.sh.pid\n"))
+		return 0
+	})
+
+	c := &SSHClient{client: client, config: &Config{TempDir: "/scratch"}}
+	removed, err := c.CleanupTempFiles(2 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/scratch/sshx-script-ab12-old.sh", "/scratch/sshx-script-ab12-old.sh.pid"}, removed)
+	assert.Contains(t, received, "find /scratch -maxdepth 1 -type f -name 'sshx-script-*'")
+	assert.Contains(t, received, "-mmin +120 -print")
+}
+
+func TestSyntaxCheckCommand(t *testing.T) {
+	assert.Equal(t, "bash -n /tmp/a.sh", syntaxCheckCommand("/tmp/a.sh"))
+	assert.Equal(t, "perl -c /tmp/a.pl", syntaxCheckCommand("/tmp/a.pl"))
+	assert.Equal(t, "ruby -c /tmp/a.rb", syntaxCheckCommand("/tmp/a.rb"))
+	assert.Contains(t, syntaxCheckCommand("/tmp/a.py"), "ast.parse")
+}
+
+func TestCheckScriptSyntax(t *testing.T) {
+	client := startExecServer(t, func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		if strings.Contains(command, "broken") {
+			_, _ = channel.Stderr().Write([]byte("/tmp/broken.sh: line 3: syntax error near unexpected token `fi'\n"))
+			return 2
+		}
+		return 0
+	})
+	c := &operation{client: client, config: &Config{}}
+
+	require.NoError(t, c.checkScriptSyntax("/tmp/ok.sh"))
+
+	err := c.checkScriptSyntax("/tmp/broken.sh")
+	var syntaxErr *ScriptSyntaxError
+	require.True(t, errors.As(err, &syntaxErr), "expected ScriptSyntaxError, got %v", err)
+	assert.Contains(t, syntaxErr.Output, "unexpected token `fi'")
+	assert.Contains(t, err.Error(), "script not run")
 }
@@ -0,0 +1,181 @@
+package sshclient
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSudoPasswordTTL is how long sudo passwords read from the keyring are
+// kept in memory before the keyring is consulted again.
+const DefaultSudoPasswordTTL = 5 * time.Minute
+
+// cachedSecret is a secret held in memory until it expires
+type cachedSecret struct {
+	value    []byte
+	loadedAt time.Time
+	expires  time.Time
+	// expiry scrubs the secret when its TTL is up, looked up again or not
+	expiry *time.Timer
+}
+
+// scrub overwrites the secret bytes so they do not linger in memory
+func (s *cachedSecret) scrub() {
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
+	for i := range s.value {
+		s.value[i] = 0
+	}
+	s.value = nil
+}
+
+// secretCache caches keyring secrets per key with a TTL
+type secretCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	lockFile string
+	entries  map[string]*cachedSecret
+	now      func() time.Time
+}
+
+// sudoReset enables `sudo -k` on the remote host after privileged commands
+var sudoReset atomic.Bool
+
+var sudoPasswordCache = newSecretCache(DefaultSudoPasswordTTL)
+
+func newSecretCache(ttl time.Duration) *secretCache {
+	return &secretCache{
+		ttl:     ttl,
+		entries: make(map[string]*cachedSecret),
+		now:     time.Now,
+	}
+}
+
+// get returns the cached secret for key if it is still valid
+func (c *secretCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if c.now().After(entry.expires) || c.lockedSince(entry.loadedAt) {
+		entry.scrub()
+		delete(c.entries, key)
+		return "", false
+	}
+	return string(entry.value), true
+}
+
+// put caches a secret for the configured TTL; a TTL of zero disables caching
+func (c *secretCache) put(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl <= 0 {
+		return
+	}
+	if old, ok := c.entries[key]; ok {
+		old.scrub()
+	}
+	now := c.now()
+	entry := &cachedSecret{
+		value:    []byte(value),
+		loadedAt: now,
+		expires:  now.Add(c.ttl),
+	}
+	entry.expiry = time.AfterFunc(c.ttl, func() { c.expire(key, entry) })
+	c.entries[key] = entry
+}
+
+// expire scrubs and removes entry once its TTL is up, unless it has been
+// replaced or removed in the meantime
+func (c *secretCache) expire(key string, entry *cachedSecret) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries[key] == entry {
+		entry.scrub()
+		delete(c.entries, key)
+	}
+}
+
+// forget removes a single key from the cache
+func (c *secretCache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok {
+		entry.scrub()
+		delete(c.entries, key)
+	}
+}
+
+// purge scrubs and removes every cached secret
+func (c *secretCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		entry.scrub()
+		delete(c.entries, key)
+	}
+}
+
+// lockedSince reports whether the lock file was touched after t, which means
+// another process ran `sshx --lock`. Caller must hold c.mu.
+func (c *secretCache) lockedSince(t time.Time) bool {
+	if c.lockFile == "" {
+		return false
+	}
+	info, err := os.Stat(c.lockFile)
+	if err != nil {
+		return false
+	}
+	return info.ModTime().After(t)
+}
+
+// SetSudoPasswordTTL configures how long sudo passwords stay cached in memory.
+// A TTL of zero disables caching; existing entries are purged.
+func SetSudoPasswordTTL(ttl time.Duration) {
+	sudoPasswordCache.purge()
+
+	sudoPasswordCache.mu.Lock()
+	defer sudoPasswordCache.mu.Unlock()
+	sudoPasswordCache.ttl = ttl
+}
+
+// SudoPasswordTTL returns how long sudo passwords stay cached in memory
+func SudoPasswordTTL() time.Duration {
+	sudoPasswordCache.mu.Lock()
+	defer sudoPasswordCache.mu.Unlock()
+	return sudoPasswordCache.ttl
+}
+
+// SetSecretLockFile sets the file whose modification time invalidates cached
+// secrets, letting `sshx --lock` purge caches held by other processes.
+func SetSecretLockFile(path string) {
+	sudoPasswordCache.mu.Lock()
+	defer sudoPasswordCache.mu.Unlock()
+	sudoPasswordCache.lockFile = path
+}
+
+// ForgetSudoPassword drops a cached sudo password, e.g. after it changed
+func ForgetSudoPassword(key string) {
+	sudoPasswordCache.forget(key)
+}
+
+// SetSudoReset makes every command that used sudo run `sudo -k` afterwards,
+// so the remote sudo timestamp cannot be reused by later commands on the
+// same connection.
+func SetSudoReset(enabled bool) {
+	sudoReset.Store(enabled)
+}
+
+// PurgeSecrets scrubs and removes all cached secrets
+func PurgeSecrets() {
+	sudoPasswordCache.purge()
+}
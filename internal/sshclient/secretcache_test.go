@@ -0,0 +1,97 @@
+package sshclient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretCache_TTL(t *testing.T) {
+	now := time.Now()
+	cache := newSecretCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.put("master", "s3cret")
+	value, ok := cache.get("master")
+	assert.True(t, ok)
+	assert.Equal(t, "s3cret", value)
+
+	entry := cache.entries["master"]
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.get("master")
+	assert.False(t, ok)
+	assert.Empty(t, cache.entries)
+	assert.Nil(t, entry.value, "expired secret is scrubbed")
+}
+
+func TestSecretCache_ScrubsOnExpiry(t *testing.T) {
+	cache := newSecretCache(20 * time.Millisecond)
+	cache.put("master", "s3cret")
+	cache.mu.Lock()
+	entry := cache.entries["master"]
+	value := entry.value
+	cache.mu.Unlock()
+
+	// Nothing looks the key up again, the timer alone scrubs it
+	require.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return entry.value == nil && len(cache.entries) == 0
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, make([]byte, len("s3cret")), value, "the buffer is zeroed")
+}
+
+func TestSecretCache_Disabled(t *testing.T) {
+	cache := newSecretCache(0)
+	cache.put("master", "s3cret")
+
+	_, ok := cache.get("master")
+	assert.False(t, ok)
+}
+
+func TestSecretCache_ForgetAndPurge(t *testing.T) {
+	cache := newSecretCache(time.Minute)
+	cache.put("a", "1")
+	cache.put("b", "2")
+
+	cache.forget("a")
+	_, ok := cache.get("a")
+	assert.False(t, ok)
+
+	cache.purge()
+	assert.Empty(t, cache.entries)
+}
+
+func TestSecretCache_LockFile(t *testing.T) {
+	cache := newSecretCache(time.Minute)
+	cache.lockFile = filepath.Join(t.TempDir(), "lock")
+
+	cache.put("master", "s3cret")
+	_, ok := cache.get("master")
+	require.True(t, ok, "missing lock file does not invalidate")
+
+	future := time.Now().Add(time.Second)
+	require.NoError(t, os.WriteFile(cache.lockFile, nil, 0o600))
+	require.NoError(t, os.Chtimes(cache.lockFile, future, future))
+
+	_, ok = cache.get("master")
+	assert.False(t, ok, "lock file newer than entry purges it")
+}
+
+func TestInvalidateSudo_SkipsWhenDisabled(t *testing.T) {
+	SetSudoReset(false)
+	t.Cleanup(func() { SetSudoReset(false) })
+
+	// Neither call needs a connection: the reset is disabled or the command has no sudo
+	client := &operation{config: &Config{Command: "sudo systemctl restart nginx"}}
+	client.invalidateSudo()
+
+	SetSudoReset(true)
+	client = &operation{config: &Config{Command: "uptime"}}
+	client.invalidateSudo()
+	assert.True(t, sudoReset.Load())
+}
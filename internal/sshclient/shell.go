@@ -0,0 +1,162 @@
+package sshclient
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf16"
+)
+
+// Shells are the shells Config.Shell can run commands in
+var Shells = []string{"bash", "sh", "zsh", "fish", "powershell", "pwsh"}
+
+// ValidateShell rejects shells other than Shells; an empty shell means the
+// login shell of the remote user
+func ValidateShell(shell string) error {
+	if shell == "" || slices.Contains(Shells, shell) {
+		return nil
+	}
+	return fmt.Errorf("unsupported shell '%s' (use %s)", shell, strings.Join(Shells, ", "))
+}
+
+// envNamePattern matches the names Config.Env may export
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateEnvName rejects names a shell cannot export
+func ValidateEnvName(name string) error {
+	if !envNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid environment variable name '%s' (use letters, digits and _, not starting with a digit)", name)
+	}
+	return nil
+}
+
+// isPowerShell reports whether shell is Windows PowerShell or PowerShell Core
+func isPowerShell(shell string) bool {
+	return shell == "powershell" || shell == "pwsh"
+}
+
+// checkShell rejects options that only exist for POSIX shells when commands
+// run in PowerShell
+func checkShell(config *Config) error {
+	if !isPowerShell(config.Shell) {
+		return nil
+	}
+	switch {
+	case config.RunAs != "":
+		return fmt.Errorf("run as another user is not supported with shell %s", config.Shell)
+	case config.Container != "":
+		return fmt.Errorf("containers are not supported with shell %s", config.Shell)
+	case config.Sandbox.enabled():
+		return fmt.Errorf("the sandbox is not supported with shell %s", config.Shell)
+	}
+	return nil
+}
+
+// shellCommandLine runs command in WorkDir, failing when the directory
+// cannot be entered, inside Shell (bash for LoginShell when no shell is
+// set), with Env exported. Without Shell or LoginShell the command goes to
+// the remote login shell as is.
+func shellCommandLine(config *Config, command string) string {
+	if isPowerShell(config.Shell) {
+		return powerShellCommandLine(config, command)
+	}
+	if config.WorkDir != "" {
+		command = BuildCommand("cd", config.WorkDir) + " || exit 1; " + command
+	}
+	// Exported inside the shell, so a login profile cannot override them
+	if len(config.Env) > 0 {
+		assignments := make([]string, 0, len(config.Env))
+		for _, name := range slices.Sorted(maps.Keys(config.Env)) {
+			assignments = append(assignments, name+"="+portableQuote(config.Env[name]))
+		}
+		command = "export " + strings.Join(assignments, " ") + "; " + command
+	}
+
+	shell := config.Shell
+	if shell == "" && config.LoginShell {
+		shell = "bash"
+	}
+	switch {
+	case shell == "":
+		return command
+	case config.LoginShell:
+		return shell + " -lc " + portableQuote(command)
+	default:
+		return shell + " -c " + portableQuote(command)
+	}
+}
+
+// powerShellCommandLine runs command as an encoded PowerShell script, which
+// needs no quoting for cmd.exe or PowerShell as the login shell. The script
+// sets SSHX_CALLER, Env and WorkDir itself; LoginShell loads the user's
+// profile.
+func powerShellCommandLine(config *Config, command string) string {
+	var script strings.Builder
+	if identity := config.Identity(); identity != "" {
+		fmt.Fprintf(&script, "$env:%s = %s; ", CallerEnv, powerShellQuote(identity))
+	}
+	for _, name := range slices.Sorted(maps.Keys(config.Env)) {
+		fmt.Fprintf(&script, "$env:%s = %s; ", name, powerShellQuote(config.Env[name]))
+	}
+	if config.WorkDir != "" {
+		fmt.Fprintf(&script, "Set-Location -LiteralPath %s -ErrorAction Stop; ", powerShellQuote(config.WorkDir))
+	}
+	script.WriteString(command)
+
+	parts := []string{config.Shell}
+	if !config.LoginShell {
+		parts = append(parts, "-NoProfile")
+	}
+	parts = append(parts, "-NonInteractive", "-EncodedCommand", encodePowerShell(script.String()))
+	return strings.Join(parts, " ")
+}
+
+// powerShellQuote quotes s as a PowerShell verbatim string
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// encodePowerShell encodes a script for -EncodedCommand: base64 of UTF-16LE
+func encodePowerShell(script string) string {
+	units := utf16.Encode([]rune(script))
+	data := make([]byte, 2*len(units))
+	for i, unit := range units {
+		binary.LittleEndian.PutUint16(data[2*i:], unit)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// portableQuote quotes s as one word for sh, bash, zsh, fish and tcsh alike.
+// Single quotes and backslashes are escaped outside the quoted runs, since
+// fish treats a backslash inside single quotes as an escape.
+func portableQuote(s string) string {
+	var b strings.Builder
+	quoted := false
+	for _, r := range s {
+		if r == '\'' || r == '\\' {
+			if quoted {
+				b.WriteByte('\'')
+				quoted = false
+			}
+			b.WriteByte('\\')
+			b.WriteRune(r)
+			continue
+		}
+		if !quoted {
+			b.WriteByte('\'')
+			quoted = true
+		}
+		b.WriteRune(r)
+	}
+	if quoted {
+		b.WriteByte('\'')
+	}
+	if b.Len() == 0 {
+		return "''"
+	}
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package sshclient
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"os/exec"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateShell(t *testing.T) {
+	for _, shell := range append([]string{""}, Shells...) {
+		assert.NoError(t, ValidateShell(shell), shell)
+	}
+	assert.EqualError(t, ValidateShell("tcsh"), "unsupported shell 'tcsh' (use bash, sh, zsh, fish, powershell, pwsh)")
+}
+
+func TestPortableQuote(t *testing.T) {
+	assert.Equal(t, "''", portableQuote(""))
+	assert.Equal(t, "'ls -la'", portableQuote("ls -la"))
+	assert.Equal(t, `'echo '\''hi'\'`, portableQuote("echo 'hi'"))
+	assert.Equal(t, `'printf a'\\'n'`, portableQuote(`printf a\n`))
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	for _, s := range []string{"echo 'hi' \"$HOME\"", `a\b\\c`, "line1\nline2", "it's $(id) `x` !"} {
+		out, err := exec.Command("sh", "-c", "printf %s "+portableQuote(s)).Output()
+		require.NoError(t, err, s)
+		assert.Equal(t, s, string(out))
+	}
+}
+
+func TestShellCommandLine(t *testing.T) {
+	config := &Config{}
+	assert.Equal(t, "uptime", shellCommandLine(config, "uptime"))
+
+	config.Shell = "zsh"
+	assert.Equal(t, "zsh -c 'echo $ZSH_VERSION'", shellCommandLine(config, "echo $ZSH_VERSION"))
+
+	config.Shell = "fish"
+	config.LoginShell = true
+	config.WorkDir = "/srv"
+	assert.Equal(t, `fish -lc 'cd /srv || exit 1; ls'`, shellCommandLine(config, "ls"))
+
+	config = &Config{WorkDir: "/srv", Env: map[string]string{"NO_PROXY": "localhost,10.0.0.0/8", "HTTP_PROXY": "http://proxy:3128"}}
+	assert.Equal(t, "export HTTP_PROXY='http://proxy:3128' NO_PROXY='localhost,10.0.0.0/8'; cd /srv || exit 1; env",
+		shellCommandLine(config, "env"))
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	config = &Config{Shell: "sh", Env: map[string]string{"GREETING": "it's $HOME", "JAVA_HOME": `C:\jdk`}}
+	out, err := exec.Command("sh", "-c", shellCommandLine(config, `printf '%s|%s' "$GREETING" "$JAVA_HOME"`)).Output()
+	require.NoError(t, err)
+	assert.Equal(t, `it's $HOME|C:\jdk`, string(out))
+}
+
+func TestValidateEnvName(t *testing.T) {
+	for _, name := range []string{"HTTP_PROXY", "http_proxy", "_X", "JAVA_HOME8"} {
+		assert.NoError(t, ValidateEnvName(name), name)
+	}
+	for _, name := range []string{"", "8BALL", "MY-VAR", "A B", "X=1"} {
+		assert.Error(t, ValidateEnvName(name), name)
+	}
+}
+
+// decodePowerShell reverses encodePowerShell
+func decodePowerShell(t *testing.T, encoded string) string {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func TestShellCommandLine_PowerShell(t *testing.T) {
+	config := &Config{Shell: "powershell", WorkDir: `C:\Program Files\App`, Client: "cursor/1.0", Caller: "o'brien", Env: map[string]string{"HTTPS_PROXY": "http://proxy:3128"}}
+	line := shellCommandLine(config, "Get-Service | Where-Object Status -eq 'Running'")
+
+	fields := strings.Fields(line)
+	require.Len(t, fields, 5)
+	assert.Equal(t, []string{"powershell", "-NoProfile", "-NonInteractive", "-EncodedCommand"}, fields[:4])
+	assert.Equal(t, `$env:SSHX_CALLER = 'cursor/1.0 (o''brien)'; $env:HTTPS_PROXY = 'http://proxy:3128'; Set-Location -LiteralPath 'C:\Program Files\App' -ErrorAction Stop; Get-Service | Where-Object Status -eq 'Running'`,
+		decodePowerShell(t, fields[4]))
+
+	config = &Config{Shell: "pwsh", LoginShell: true}
+	fields = strings.Fields(shellCommandLine(config, "$PROFILE"))
+	assert.Equal(t, []string{"pwsh", "-NonInteractive", "-EncodedCommand"}, fields[:3])
+	assert.Equal(t, "$PROFILE", decodePowerShell(t, fields[3]))
+
+	// No POSIX wrapping around the encoded command
+	op := &operation{config: &Config{Shell: "powershell", Command: "hostname", Client: "cursor/1.0"}}
+	assert.True(t, strings.HasPrefix(op.sandboxed(op.commandLine()), "powershell "))
+}
+
+func TestCheckShell(t *testing.T) {
+	assert.NoError(t, checkShell(&Config{Shell: "bash", RunAs: "app"}))
+	assert.NoError(t, checkShell(&Config{Shell: "powershell"}))
+	assert.EqualError(t, checkShell(&Config{Shell: "powershell", RunAs: "app"}), "run as another user is not supported with shell powershell")
+	assert.EqualError(t, checkShell(&Config{Shell: "pwsh", Container: "web"}), "containers are not supported with shell pwsh")
+	assert.EqualError(t, checkShell(&Config{Shell: "pwsh", Sandbox: &Sandbox{CleanEnv: true}}), "the sandbox is not supported with shell pwsh")
+}
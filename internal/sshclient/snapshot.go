@@ -0,0 +1,229 @@
+package sshclient
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot sections
+const (
+	SnapshotFacts    = "facts"
+	SnapshotPackages = "packages"
+	SnapshotServices = "services"
+	SnapshotFiles    = "files"
+)
+
+// File states recorded instead of a hash
+const (
+	SnapshotFileMissing    = "missing"
+	SnapshotFileUnreadable = "unreadable"
+)
+
+// snapshotMarker prefixes the lines of SnapshotCommand that start a section
+const snapshotMarker = "@sshx-snapshot:"
+
+// DefaultSnapshotFiles are the configuration files whose hashes a snapshot
+// records when no other list is given
+var DefaultSnapshotFiles = []string{
+	"/etc/passwd",
+	"/etc/group",
+	"/etc/sudoers",
+	"/etc/ssh/sshd_config",
+	"/etc/hosts",
+	"/etc/fstab",
+	"/etc/resolv.conf",
+	"/etc/crontab",
+}
+
+// Snapshot is the state of a host at one point in time: a few facts, the
+// installed packages, the enabled services and the hashes of key
+// configuration files
+type Snapshot struct {
+	Host     string            `json:"host"`
+	Address  string            `json:"address"`
+	Time     time.Time         `json:"time"`
+	Facts    map[string]string `json:"facts"`    // hostname, kernel and os
+	Packages map[string]string `json:"packages"` // Package name to version (dpkg, rpm or apk)
+	Services []string          `json:"services"` // Enabled systemd services, sorted
+	Files    map[string]string `json:"files"`    // Path to sha256, SnapshotFileMissing or SnapshotFileUnreadable
+}
+
+// SnapshotChange is one difference between two snapshots. Before is empty
+// for something added and After for something removed.
+type SnapshotChange struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+}
+
+// ValidateSnapshotFiles rejects paths that are not absolute or span lines
+func ValidateSnapshotFiles(files []string) error {
+	for _, file := range files {
+		if !strings.HasPrefix(file, "/") || strings.ContainsAny(file, "\r\n") {
+			return fmt.Errorf("invalid snapshot file '%s' (use an absolute path)", file)
+		}
+	}
+	return nil
+}
+
+// SnapshotCommand prints the sections of a snapshot of the host, hashing
+// files. Each section starts with a marker line; packages come from
+// dpkg, rpm or apk, whichever the host has.
+func SnapshotCommand(files []string) string {
+	quoted := make([]string, len(files))
+	for i, file := range files {
+		quoted[i] = ShellQuote(file)
+	}
+	section := func(name string) string {
+		return "echo " + ShellQuote(snapshotMarker+name)
+	}
+	return strings.Join([]string{
+		section(SnapshotFacts),
+		`echo "hostname $(hostname 2>/dev/null)"`,
+		`echo "kernel $(uname -sr 2>/dev/null)"`,
+		`(. /etc/os-release 2>/dev/null && echo "os $PRETTY_NAME")`,
+		section(SnapshotPackages),
+		`if command -v dpkg-query >/dev/null 2>&1; then dpkg-query -W -f='${Package} ${Version}\n' 2>/dev/null; ` +
+			`elif command -v rpm >/dev/null 2>&1; then rpm -qa --qf '%{NAME} %{VERSION}-%{RELEASE}\n' 2>/dev/null; ` +
+			`elif command -v apk >/dev/null 2>&1; then apk info -v 2>/dev/null; fi`,
+		section(SnapshotServices),
+		`systemctl list-unit-files --type=service --state=enabled --no-legend --no-pager 2>/dev/null | awk '{print $1}'`,
+		section(SnapshotFiles),
+		`for f in ` + strings.Join(quoted, " ") + `; do ` +
+			`if [ ! -e "$f" ]; then printf '%s  %s\n' ` + SnapshotFileMissing + ` "$f"; ` +
+			`elif [ ! -r "$f" ]; then printf '%s  %s\n' ` + SnapshotFileUnreadable + ` "$f"; ` +
+			`else sha256sum -- "$f" 2>/dev/null || printf '%s  %s\n' ` + SnapshotFileUnreadable + ` "$f"; fi; done`,
+	}, "\n")
+}
+
+// ParseSnapshot reads the output of SnapshotCommand
+func ParseSnapshot(output string) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Facts:    map[string]string{},
+		Packages: map[string]string{},
+		Services: []string{},
+		Files:    map[string]string{},
+	}
+	section := ""
+	for _, line := range splitLines(output) {
+		line = strings.TrimRight(line, "\r")
+		if name, ok := strings.CutPrefix(line, snapshotMarker); ok {
+			section = name
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		switch section {
+		case SnapshotFacts:
+			if name, value, ok := strings.Cut(line, " "); ok && strings.TrimSpace(value) != "" {
+				snapshot.Facts[name] = strings.TrimSpace(value)
+			}
+		case SnapshotPackages:
+			name, version, _ := strings.Cut(strings.TrimSpace(line), " ")
+			if version == "" {
+				// apk prints name-version as one word
+				version = "installed"
+			}
+			if previous, ok := snapshot.Packages[name]; ok {
+				// Several versions of a package may be installed (rpm kernels)
+				version = previous + ", " + version
+			}
+			snapshot.Packages[name] = version
+		case SnapshotServices:
+			snapshot.Services = append(snapshot.Services, strings.TrimSpace(line))
+		case SnapshotFiles:
+			if hash, path, ok := strings.Cut(line, "  "); ok {
+				snapshot.Files[path] = hash
+			}
+		}
+	}
+	if section != SnapshotFiles {
+		return nil, fmt.Errorf("incomplete snapshot output: %s", strings.TrimSpace(output))
+	}
+	sort.Strings(snapshot.Services)
+	return snapshot, nil
+}
+
+// TakeSnapshot records the facts, packages, enabled services and hashes of
+// files (DefaultSnapshotFiles when empty) of the host with one command run
+// through Execute, so it is validated and audited like any other command
+func (c *SSHClient) TakeSnapshot(files []string, req Request) (*Snapshot, error) {
+	if isPowerShell(c.config.Shell) {
+		return nil, fmt.Errorf("snapshots are not supported with shell %s", c.config.Shell)
+	}
+	if len(files) == 0 {
+		files = DefaultSnapshotFiles
+	}
+	if err := ValidateSnapshotFiles(files); err != nil {
+		return nil, err
+	}
+
+	req.Command = SnapshotCommand(files)
+	result, err := c.Execute(req)
+	if err != nil {
+		return nil, err
+	}
+	if result.Truncated {
+		return nil, fmt.Errorf("snapshot output exceeded the output limit")
+	}
+	snapshot, err := ParseSnapshot(result.Output)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Host = c.config.Alias
+	if snapshot.Host == "" {
+		snapshot.Host = c.config.Host
+	}
+	snapshot.Address = c.config.Host
+	snapshot.Time = time.Now().UTC()
+	return snapshot, nil
+}
+
+// DiffSnapshots lists what changed from before to after, section by
+// section and sorted by name within a section
+func DiffSnapshots(before, after *Snapshot) []SnapshotChange {
+	changes := []SnapshotChange{}
+	changes = append(changes, diffSnapshotMaps(SnapshotFacts, before.Facts, after.Facts)...)
+	changes = append(changes, diffSnapshotMaps(SnapshotPackages, before.Packages, after.Packages)...)
+	changes = append(changes, diffSnapshotMaps(SnapshotServices, enabledSet(before.Services), enabledSet(after.Services))...)
+	changes = append(changes, diffSnapshotMaps(SnapshotFiles, before.Files, after.Files)...)
+	return changes
+}
+
+// enabledSet maps each service to "enabled" so services diff like the
+// other sections
+func enabledSet(services []string) map[string]string {
+	set := make(map[string]string, len(services))
+	for _, service := range services {
+		set[service] = "enabled"
+	}
+	return set
+}
+
+func diffSnapshotMaps(section string, before, after map[string]string) []SnapshotChange {
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []SnapshotChange
+	for _, name := range names {
+		old, hadOld := before[name]
+		current, hasNew := after[name]
+		if hadOld && hasNew && old == current {
+			continue
+		}
+		changes = append(changes, SnapshotChange{Section: section, Name: name, Before: old, After: current})
+	}
+	return changes
+}
@@ -0,0 +1,98 @@
+package sshclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSnapshotCommand(t *testing.T) {
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum not available")
+	}
+	dir := t.TempDir()
+	config := filepath.Join(dir, "app config.conf")
+	require.NoError(t, os.WriteFile(config, []byte("listen 80\n"), 0o600))
+	missing := filepath.Join(dir, "absent")
+
+	command := SnapshotCommand([]string{config, missing})
+	assert.False(t, Explain(command, nil).Flagged, "the snapshot command must pass the safety check")
+	output, err := exec.Command("sh", "-c", command).CombinedOutput() // #nosec G204 -- test command
+	require.NoError(t, err, string(output))
+
+	snapshot, err := ParseSnapshot(string(output))
+	require.NoError(t, err)
+	sum := sha256.Sum256([]byte("listen 80\n"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), snapshot.Files[config])
+	assert.Equal(t, SnapshotFileMissing, snapshot.Files[missing])
+	assert.NotEmpty(t, snapshot.Facts["kernel"])
+}
+
+func TestParseSnapshot(t *testing.T) {
+	output := "@sshx-snapshot:facts\r\nhostname web1\r\nkernel Linux 6.1.0\r\n" +
+		"@sshx-snapshot:packages\r\nnginx 1.24.0-1\r\nkernel 5.14.0-1\r\nkernel 5.14.0-2\r\nmusl-1.2.4-r2\r\n" +
+		"@sshx-snapshot:services\r\nsshd.service\r\ncron.service\r\n" +
+		"@sshx-snapshot:files\r\nabc123  /etc/hosts\r\nunreadable  /etc/sudoers\r\n"
+	snapshot, err := ParseSnapshot(output)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"hostname": "web1", "kernel": "Linux 6.1.0"}, snapshot.Facts)
+	assert.Equal(t, map[string]string{"nginx": "1.24.0-1", "kernel": "5.14.0-1, 5.14.0-2", "musl-1.2.4-r2": "installed"}, snapshot.Packages)
+	assert.Equal(t, []string{"cron.service", "sshd.service"}, snapshot.Services)
+	assert.Equal(t, map[string]string{"/etc/hosts": "abc123", "/etc/sudoers": SnapshotFileUnreadable}, snapshot.Files)
+
+	_, err = ParseSnapshot("@sshx-snapshot:facts\nhostname web1\n")
+	assert.ErrorContains(t, err, "incomplete snapshot output")
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	before := &Snapshot{
+		Facts:    map[string]string{"kernel": "Linux 6.1.0"},
+		Packages: map[string]string{"nginx": "1.18.0", "telnet": "0.17"},
+		Services: []string{"cron.service", "sshd.service"},
+		Files:    map[string]string{"/etc/hosts": "aaa", "/etc/fstab": "bbb"},
+	}
+	after := &Snapshot{
+		Facts:    map[string]string{"kernel": "Linux 6.1.0"},
+		Packages: map[string]string{"nginx": "1.24.0", "curl": "8.5.0"},
+		Services: []string{"sshd.service", "nginx.service"},
+		Files:    map[string]string{"/etc/hosts": "ccc", "/etc/fstab": "bbb"},
+	}
+	assert.Equal(t, []SnapshotChange{
+		{Section: SnapshotPackages, Name: "curl", After: "8.5.0"},
+		{Section: SnapshotPackages, Name: "nginx", Before: "1.18.0", After: "1.24.0"},
+		{Section: SnapshotPackages, Name: "telnet", Before: "0.17"},
+		{Section: SnapshotServices, Name: "cron.service", Before: "enabled"},
+		{Section: SnapshotServices, Name: "nginx.service", After: "enabled"},
+		{Section: SnapshotFiles, Name: "/etc/hosts", Before: "aaa", After: "ccc"},
+	}, DiffSnapshots(before, after))
+	assert.Empty(t, DiffSnapshots(after, after))
+}
+
+func TestSSHClient_TakeSnapshot(t *testing.T) {
+	var ran string
+	conn := startExecServer(t, func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		ran = command
+		_, _ = channel.Write([]byte("@sshx-snapshot:facts\nhostname web1\n@sshx-snapshot:packages\n@sshx-snapshot:services\n@sshx-snapshot:files\nmissing  /etc/app.conf\n"))
+		return 0
+	})
+	client := &SSHClient{config: &Config{Host: "10.0.0.5", Alias: "web1"}, client: conn}
+
+	snapshot, err := client.TakeSnapshot([]string{"/etc/app.conf"}, Request{})
+	require.NoError(t, err)
+	assert.Contains(t, ran, "for f in '/etc/app.conf'; do")
+	assert.Equal(t, "web1", snapshot.Host)
+	assert.Equal(t, "10.0.0.5", snapshot.Address)
+	assert.WithinDuration(t, time.Now(), snapshot.Time, time.Minute)
+	assert.Equal(t, map[string]string{"/etc/app.conf": SnapshotFileMissing}, snapshot.Files)
+
+	_, err = client.TakeSnapshot([]string{"etc/app.conf"}, Request{})
+	assert.ErrorContains(t, err, "invalid snapshot file")
+}
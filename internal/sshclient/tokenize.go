@@ -0,0 +1,513 @@
+package sshclient
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxParseDepth bounds how deeply command strings run by other commands
+// (substitutions, sh -c, eval) are parsed
+const maxParseDepth = 4
+
+// Words standing in for command substitutions whose output is not known
+// before the command runs
+const (
+	dynamicWord = "\x00dynamic\x00"
+	// decodedWord marks output produced by a decoder such as base64 -d
+	decodedWord = "\x00decoded\x00"
+)
+
+// shellCommand is one simple command with quotes and escapes removed
+type shellCommand struct {
+	words []string
+	// redirects are the files its output is redirected to (> file, >> file)
+	redirects []string
+}
+
+// String renders the command as the shell sees it once quotes are removed
+func (c shellCommand) String() string {
+	parts := make([]string, 0, len(c.words)+len(c.redirects))
+	for _, word := range c.words {
+		parts = append(parts, displayWord(word))
+	}
+	for _, target := range c.redirects {
+		parts = append(parts, "> "+displayWord(target))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellPipeline is a sequence of commands connected by pipes
+type shellPipeline []shellCommand
+
+func (p shellPipeline) String() string {
+	commands := make([]string, len(p))
+	for i, command := range p {
+		commands[i] = command.String()
+	}
+	return strings.Join(commands, " | ")
+}
+
+// displayWord shows a parsed word, quoting it when it holds whitespace or
+// quotes and spelling out substitution markers
+func displayWord(word string) string {
+	word = strings.NewReplacer(dynamicWord, "$(...)", decodedWord, "$(decoded)").Replace(word)
+	if strings.ContainsAny(word, " \t\n'\"") {
+		return ShellQuote(word)
+	}
+	return word
+}
+
+// commandLine is a command line split into pipelines for inspection
+type commandLine struct {
+	pipelines []shellPipeline
+	// nested are the command lines of its command substitutions
+	nested []commandLine
+}
+
+// parseCommandLine splits a shell command line the way the shell would:
+// quotes and escapes are removed, pipelines are split on ; && || & and
+// newlines, commands on |, and command substitutions are parsed as command
+// lines of their own. Substitutions that only echo literal text are
+// replaced by that text, so $(echo rm) reads as rm. Here-document bodies
+// and comments are skipped. The parser does not expand variables, that is
+// left to inspectCommandLine, which knows the assignments of the chain.
+func parseCommandLine(input string, depth int) commandLine {
+	input = strings.NewReplacer("${IFS}", " ", "$IFS", " ").Replace(input)
+	p := &shellParser{input: []rune(input), depth: depth}
+	p.parse()
+	return p.line
+}
+
+// shellParser holds the state of parseCommandLine
+type shellParser struct {
+	input []rune
+	pos   int
+	depth int
+
+	line     commandLine
+	pipeline shellPipeline
+	command  shellCommand
+
+	word     strings.Builder
+	inWord   bool
+	redirect bool     // The next word is a redirection target
+	reading  bool     // The redirection reads from its target (< file)
+	heredocs []string // Delimiters of here-documents starting at the next newline
+}
+
+func (p *shellParser) parse() {
+	for p.pos < len(p.input) {
+		r := p.input[p.pos]
+		switch {
+		case r == '\\':
+			p.pos++
+			if p.pos < len(p.input) {
+				if p.input[p.pos] != '\n' {
+					p.add(string(p.input[p.pos]))
+				}
+				p.pos++
+			}
+		case r == '\'':
+			end := p.find(p.pos+1, '\'')
+			p.add(string(p.input[p.pos+1 : end]))
+			p.pos = end + 1
+		case r == '"':
+			p.readDoubleQuoted()
+		case r == '$' && p.peek(1) == '\'':
+			end := p.find(p.pos+2, '\'')
+			p.add(unescapeANSI(string(p.input[p.pos+2 : end])))
+			p.pos = end + 1
+		case r == '$' && p.peek(1) == '(':
+			p.readSubstitution(p.pos + 2)
+		case r == '`':
+			end := p.find(p.pos+1, '`')
+			p.substitute(string(p.input[p.pos+1 : end]))
+			p.pos = end + 1
+		case (r == '<' || r == '>') && p.peek(1) == '(':
+			p.endWord()
+			p.readSubstitution(p.pos + 2)
+		case r == ' ' || r == '\t' || r == '\r':
+			p.endWord()
+			p.pos++
+		case r == '#' && !p.inWord:
+			for p.pos < len(p.input) && p.input[p.pos] != '\n' {
+				p.pos++
+			}
+		case r == '\n':
+			p.endPipeline()
+			p.pos++
+			p.skipHeredocs()
+		case r == ';' || r == '(' || r == ')':
+			p.endPipeline()
+			p.pos++
+		case r == '&' && p.peek(1) == '>':
+			p.startRedirect()
+		case r == '&':
+			p.endPipeline()
+			p.pos++
+			if p.peek(0) == '&' {
+				p.pos++
+			}
+		case r == '|':
+			p.pos++
+			switch p.peek(0) {
+			case '|':
+				p.pos++
+				p.endPipeline()
+			case '&':
+				p.pos++
+				p.endCommand()
+			default:
+				p.endCommand()
+			}
+		case r == '<' || r == '>':
+			p.startRedirect()
+		default:
+			p.add(string(r))
+			p.pos++
+		}
+	}
+	p.endPipeline()
+}
+
+// peek returns the rune offset positions ahead, or 0 past the end
+func (p *shellParser) peek(offset int) rune {
+	if p.pos+offset < len(p.input) {
+		return p.input[p.pos+offset]
+	}
+	return 0
+}
+
+// find returns the position of the next unescaped r from start, or the end
+// of the input when it is missing
+func (p *shellParser) find(start int, r rune) int {
+	for i := start; i < len(p.input); i++ {
+		if p.input[i] == '\\' && r != '\'' {
+			i++
+			continue
+		}
+		if p.input[i] == r {
+			return i
+		}
+	}
+	return len(p.input)
+}
+
+func (p *shellParser) add(text string) {
+	p.word.WriteString(text)
+	p.inWord = true
+}
+
+func (p *shellParser) readDoubleQuoted() {
+	p.pos++
+	p.inWord = true
+	for p.pos < len(p.input) {
+		r := p.input[p.pos]
+		switch {
+		case r == '"':
+			p.pos++
+			return
+		case r == '\\' && p.pos+1 < len(p.input) && strings.ContainsRune("$`\"\\\n", p.input[p.pos+1]):
+			if p.input[p.pos+1] != '\n' {
+				p.add(string(p.input[p.pos+1]))
+			}
+			p.pos += 2
+		case r == '$' && p.peek(1) == '(':
+			p.readSubstitution(p.pos + 2)
+		case r == '`':
+			end := p.find(p.pos+1, '`')
+			p.substitute(string(p.input[p.pos+1 : end]))
+			p.pos = end + 1
+		default:
+			p.add(string(r))
+			p.pos++
+		}
+	}
+}
+
+// readSubstitution reads a $( ... ) whose content starts at start, up to
+// the matching parenthesis
+func (p *shellParser) readSubstitution(start int) {
+	level := 1
+	i := start
+	for ; i < len(p.input) && level > 0; i++ {
+		switch p.input[i] {
+		case '\\':
+			i++
+		case '\'', '"', '`':
+			i = p.find(i+1, p.input[i])
+		case '(':
+			level++
+		case ')':
+			level--
+		}
+	}
+	end := i
+	if level == 0 {
+		end = i - 1
+	}
+	p.substitute(string(p.input[start:min(end, len(p.input))]))
+	p.pos = i
+}
+
+// substitute parses the command line of a substitution and adds its
+// output to the current word: the text of a substitution that only echoes
+// literal text, decodedWord for the output of a decoder and dynamicWord
+// otherwise
+func (p *shellParser) substitute(inner string) {
+	if p.depth >= maxParseDepth {
+		p.add(dynamicWord)
+		return
+	}
+	nested := parseCommandLine(inner, p.depth+1)
+	p.line.nested = append(p.line.nested, nested)
+
+	if output, ok := staticOutput(nested); ok {
+		p.add(output)
+		return
+	}
+	for _, pipeline := range nested.pipelines {
+		for _, command := range pipeline {
+			if isDecoder(command.words) {
+				p.add(decodedWord)
+				return
+			}
+		}
+	}
+	p.add(dynamicWord)
+}
+
+// startRedirect reads a redirection operator; the next word is its target
+func (p *shellParser) startRedirect() {
+	// A file descriptor number such as the 2 of 2> is not a word
+	if p.inWord && strings.Trim(p.word.String(), "0123456789") == "" {
+		p.word.Reset()
+		p.inWord = false
+	}
+	p.endWord()
+
+	heredoc := p.peek(0) == '<' && p.peek(1) == '<' && p.peek(2) != '<'
+	start := p.pos
+	for p.pos < len(p.input) && strings.ContainsRune("<>&|-", p.input[p.pos]) {
+		p.pos++
+	}
+	if heredoc {
+		p.readHeredocDelimiter()
+		return
+	}
+	p.redirect = true
+	p.reading = !strings.ContainsRune(string(p.input[start:p.pos]), '>')
+}
+
+// readHeredocDelimiter reads the delimiter of a here-document
+func (p *shellParser) readHeredocDelimiter() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(" \t\n;|&<>()", p.input[p.pos]) {
+		p.pos++
+	}
+	delimiter := strings.Trim(string(p.input[start:p.pos]), `'"\`)
+	if delimiter != "" {
+		p.heredocs = append(p.heredocs, delimiter)
+	}
+}
+
+// skipHeredocs skips the bodies of here-documents started on the line that
+// just ended
+func (p *shellParser) skipHeredocs() {
+	for _, delimiter := range p.heredocs {
+		for p.pos < len(p.input) {
+			end := p.pos
+			for end < len(p.input) && p.input[end] != '\n' {
+				end++
+			}
+			line := strings.TrimLeft(string(p.input[p.pos:end]), "\t")
+			p.pos = min(end+1, len(p.input))
+			if line == delimiter {
+				break
+			}
+		}
+	}
+	p.heredocs = nil
+}
+
+func (p *shellParser) endWord() {
+	if !p.inWord {
+		return
+	}
+	if p.redirect {
+		if !p.reading {
+			p.command.redirects = append(p.command.redirects, p.word.String())
+		}
+		p.redirect = false
+	} else {
+		p.command.words = append(p.command.words, p.word.String())
+	}
+	p.word.Reset()
+	p.inWord = false
+}
+
+func (p *shellParser) endCommand() {
+	p.endWord()
+	p.redirect = false
+	if len(p.command.words) > 0 || len(p.command.redirects) > 0 {
+		p.pipeline = append(p.pipeline, p.command)
+	}
+	p.command = shellCommand{}
+}
+
+func (p *shellParser) endPipeline() {
+	p.endCommand()
+	if len(p.pipeline) > 0 {
+		p.line.pipelines = append(p.line.pipelines, p.pipeline)
+	}
+	p.pipeline = nil
+}
+
+// staticOutput returns the output of a command line that only echoes
+// literal text: echo, printf, or a command lookup such as which rm
+func staticOutput(line commandLine) (string, bool) {
+	if len(line.pipelines) != 1 || len(line.pipelines[0]) != 1 {
+		return "", false
+	}
+	words := line.pipelines[0][0].words
+	if len(words) == 0 {
+		return "", false
+	}
+	for _, word := range words {
+		if strings.Contains(word, dynamicWord) || strings.Contains(word, decodedWord) {
+			return "", false
+		}
+	}
+
+	args := words[1:]
+	switch commandName(words[0]) {
+	case "echo":
+		escapes := false
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") && strings.Trim(args[0], "-neE") == "" {
+			escapes = escapes || strings.Contains(args[0], "e")
+			args = args[1:]
+		}
+		output := strings.Join(args, " ")
+		if escapes {
+			output = unescapeANSI(output)
+		}
+		return output, true
+	case "printf":
+		if len(args) == 0 {
+			return "", false
+		}
+		format := unescapeANSI(args[0])
+		for _, arg := range args[1:] {
+			format = strings.Replace(format, "%s", arg, 1)
+		}
+		if strings.Contains(strings.ReplaceAll(format, "%%", ""), "%") {
+			return "", false
+		}
+		return strings.ReplaceAll(format, "%%", "%"), true
+	case "which":
+		if len(args) == 1 {
+			return args[0], true
+		}
+	case "command", "type":
+		if len(args) == 2 && (args[0] == "-v" || args[0] == "-P") {
+			return args[1], true
+		}
+	}
+	return "", false
+}
+
+// unescapeANSI interprets the backslash escapes of $'...', echo -e and
+// printf: \n, \t, \\, \xHH and octal \NNN
+func unescapeANSI(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			b.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch c := text[i]; {
+		case c == 'n':
+			b.WriteByte('\n')
+		case c == 't':
+			b.WriteByte('\t')
+		case c == 'x':
+			end := i + 1
+			for end < len(text) && end < i+3 && strings.ContainsRune("0123456789abcdefABCDEF", rune(text[end])) {
+				end++
+			}
+			if value, err := strconv.ParseUint(text[i+1:end], 16, 8); err == nil {
+				b.WriteByte(byte(value))
+				i = end - 1
+			} else {
+				b.WriteString(`\x`)
+			}
+		case c >= '0' && c <= '7':
+			end := i
+			for end < len(text) && end < i+4 && text[end] >= '0' && text[end] <= '7' {
+				end++
+			}
+			digits := strings.TrimPrefix(text[i:end], "0")
+			if digits == "" {
+				digits = "0"
+			}
+			if value, err := strconv.ParseUint(digits, 8, 8); err == nil {
+				b.WriteByte(byte(value))
+			}
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// commandName is the lower-case base name of a command word, so /bin/rm
+// and RM both read as rm
+func commandName(word string) string {
+	word = strings.ToLower(word)
+	if i := strings.LastIndex(word, "/"); i >= 0 && i < len(word)-1 {
+		word = word[i+1:]
+	}
+	return word
+}
+
+// isDecoder reports whether a command decodes its input, the usual way to
+// hide a payload from pattern checks
+func isDecoder(words []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	name := commandName(words[0])
+	args := strings.Join(words[1:], " ")
+	switch name {
+	case "base64", "base32", "basenc":
+		return hasFlag(words[1:], 'd') || hasFlag(words[1:], 'D') || strings.Contains(args, "--decode")
+	case "xxd":
+		return hasFlag(words[1:], 'r')
+	case "openssl":
+		return hasFlag(words[1:], 'd') && (strings.Contains(args, "base64") || strings.Contains(args, "enc"))
+	case "uudecode", "rev", "gunzip", "zcat", "bzcat", "xzcat":
+		return true
+	case "gzip", "bzip2", "xz":
+		return hasFlag(words[1:], 'd') || strings.Contains(args, "--decompress")
+	}
+	return false
+}
+
+// hasFlag reports whether a short option is among args, alone or combined
+// with others as in -rf
+func hasFlag(args []string, flag byte) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if len(arg) > 1 && arg[0] == '-' && arg[1] != '-' && strings.IndexByte(arg[1:], flag) >= 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package sshclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commandWords flattens a parsed command line into the words of each command
+func commandWords(line commandLine) [][]string {
+	var words [][]string
+	for _, pipeline := range line.pipelines {
+		for _, command := range pipeline {
+			words = append(words, command.words)
+		}
+	}
+	return words
+}
+
+func TestParseCommandLine_Words(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  [][]string
+	}{
+		{"plain", "ls -la /tmp", [][]string{{"ls", "-la", "/tmp"}}},
+		{"quotes", `echo "a b" 'c d' e\ f`, [][]string{{"echo", "a b", "c d", "e f"}}},
+		{"empty quotes join", `r''m -rf "/"`, [][]string{{"rm", "-rf", "/"}}},
+		{"escaped quote", `echo "say \"hi\""`, [][]string{{"echo", `say "hi"`}}},
+		{"ansi c quoting", `$'\x72\x6d' -rf /`, [][]string{{"rm", "-rf", "/"}}},
+		{"ifs", "rm${IFS}-rf$IFS/", [][]string{{"rm", "-rf", "/"}}},
+		{"chain", "cd /tmp && make || echo failed; ls &", [][]string{{"cd", "/tmp"}, {"make"}, {"echo", "failed"}, {"ls"}}},
+		{"pipes", "ps aux | grep ssh |& wc -l", [][]string{{"ps", "aux"}, {"grep", "ssh"}, {"wc", "-l"}}},
+		{"newlines", "uptime\nwho", [][]string{{"uptime"}, {"who"}}},
+		{"subshell", "(cd / && ls)", [][]string{{"cd", "/"}, {"ls"}}},
+		{"comment", "ls # rm -rf /", [][]string{{"ls"}}},
+		{"hash inside word", "echo a#b", [][]string{{"echo", "a#b"}}},
+		{"heredoc", "cat <<EOF > /tmp/x\nrm -rf /\nEOF\nls", [][]string{{"cat"}, {"ls"}}},
+		{"echo substitution", "$(echo rm) -rf /", [][]string{{"rm", "-rf", "/"}}},
+		{"backticks", "`printf 'r%s' m` -rf /", [][]string{{"rm", "-rf", "/"}}},
+		{"dynamic substitution", "$(whoami)", [][]string{{dynamicWord}}},
+		{"decoded substitution", "$(echo eA== | base64 -d)", [][]string{{decodedWord}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, commandWords(parseCommandLine(tt.input, 0)))
+		})
+	}
+}
+
+func TestParseCommandLine_Redirects(t *testing.T) {
+	line := parseCommandLine("dd if=/dev/zero 2>/dev/null >/dev/sda; cat < in.txt >> out.log", 0)
+	require.Len(t, line.pipelines, 2)
+
+	first := line.pipelines[0][0]
+	assert.Equal(t, []string{"dd", "if=/dev/zero"}, first.words)
+	assert.Equal(t, []string{"/dev/null", "/dev/sda"}, first.redirects)
+
+	second := line.pipelines[1][0]
+	assert.Equal(t, []string{"cat"}, second.words)
+	assert.Equal(t, []string{"out.log"}, second.redirects)
+}
+
+func TestParseCommandLine_Nested(t *testing.T) {
+	line := parseCommandLine(`echo "$(id -u)" $(date)`, 0)
+	require.Len(t, line.nested, 2)
+	assert.Equal(t, [][]string{{"id", "-u"}}, commandWords(line.nested[0]))
+	assert.Equal(t, [][]string{{"date"}}, commandWords(line.nested[1]))
+}
+
+func TestParseCommandLine_DepthLimit(t *testing.T) {
+	line := parseCommandLine("$($($($($(echo rm)))))", 0)
+	assert.Equal(t, [][]string{{dynamicWord}}, commandWords(line))
+}
+
+func TestUnescapeANSI(t *testing.T) {
+	assert.Equal(t, "rm", unescapeANSI(`\x72\x6d`))
+	assert.Equal(t, "rm", unescapeANSI(`\162\155`))
+	assert.Equal(t, "a\tb\nc\\", unescapeANSI(`a\tb\nc\\`))
+	assert.Equal(t, `\xZZ`, unescapeANSI(`\xZZ`))
+}
+
+func TestCommandName(t *testing.T) {
+	assert.Equal(t, "rm", commandName("/usr/bin/rm"))
+	assert.Equal(t, "rm", commandName("RM"))
+	assert.Equal(t, "/", commandName("/"))
+}
+
+func TestIsDecoder(t *testing.T) {
+	assert.True(t, isDecoder([]string{"base64", "-d"}))
+	assert.True(t, isDecoder([]string{"base64", "--decode"}))
+	assert.True(t, isDecoder([]string{"xxd", "-r", "-p"}))
+	assert.True(t, isDecoder([]string{"rev"}))
+	assert.False(t, isDecoder([]string{"base64"}))
+	assert.False(t, isDecoder([]string{"xxd", "file"}))
+	assert.False(t, isDecoder(nil))
+}
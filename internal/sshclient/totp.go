@@ -0,0 +1,114 @@
+package sshclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // TOTP (RFC 6238) is defined over HMAC-SHA1
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+// TOTP parameters, the defaults of authenticator apps
+const (
+	totpStep   = 30 // seconds
+	totpDigits = 1000000
+	// totpSkew is how many steps a code may be early or late, for clock drift
+	totpSkew = 1
+)
+
+// totpUsed remembers the last time step accepted per keyring entry, so a
+// code cannot be replayed
+var totpUsed = struct {
+	sync.Mutex
+	steps map[string]int64
+}{steps: make(map[string]int64)}
+
+// decodeTOTPSecret decodes a base32 secret as authenticator apps show it
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	cleaned := strings.TrimRight(strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(secret), " ", "")), "=")
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(cleaned)
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("invalid TOTP secret: want base32")
+	}
+	return key, nil
+}
+
+// hotp is the HOTP code (RFC 4226) of key for counter
+func hotp(key []byte, counter int64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", code%totpDigits)
+}
+
+// TOTPCode returns the TOTP code (RFC 6238) of the base32 secret at t
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, t.Unix()/totpStep), nil
+}
+
+// verifyTOTP checks code against the secret of the keyring entry name at
+// now, accepting each time step only once per entry
+func verifyTOTP(name, secret, code string, now time.Time) error {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return fmt.Errorf("keyring entry '%s': %w", name, err)
+	}
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	step := now.Unix() / totpStep
+	for candidate := step - totpSkew; candidate <= step+totpSkew; candidate++ {
+		if !hmac.Equal([]byte(hotp(key, candidate)), []byte(code)) {
+			continue
+		}
+		totpUsed.Lock()
+		defer totpUsed.Unlock()
+		if candidate <= totpUsed.steps[name] {
+			return fmt.Errorf("confirm_token was already used, wait for the next code")
+		}
+		totpUsed.steps[name] = candidate
+		return nil
+	}
+	return fmt.Errorf("confirm_token is not a valid TOTP code")
+}
+
+// TOTPConfirmation returns a Config.ConfirmForce that lets Force override a
+// block only with code, a current TOTP code of the base32 secret stored in
+// the keyring entry name. It is checked on the server, so a client that
+// can send force and a reason still cannot force commands on its own.
+// A code is spent once, so a command sent to several hosts is confirmed for
+// all of them by the first check.
+func TOTPConfirmation(name, code string) func(*BlockedCommandError) (string, error) {
+	var mu sync.Mutex
+	confirmed := ""
+	return func(blocked *BlockedCommandError) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if confirmed != "" && confirmed == blocked.Command {
+			return "", nil
+		}
+		if strings.TrimSpace(code) == "" {
+			return "", fmt.Errorf("the safety policy of the host requires a TOTP code as confirm_token to force %s", blocked.Rule)
+		}
+		secret, err := keyring.Get(KeyringServiceName, name)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the TOTP secret '%s' from the keyring: %w", name, err)
+		}
+		if err := verifyTOTP(name, secret, code, timeNow()); err != nil {
+			return "", err
+		}
+		confirmed = blocked.Command
+		return "", nil
+	}
+}
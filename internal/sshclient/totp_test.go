@@ -0,0 +1,108 @@
+package sshclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+// rfcSecret is the base32 secret of the RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// resetTOTPReplay gives the test an empty replay record, so codes spent by
+// an earlier run (go test -count) are accepted again
+func resetTOTPReplay(t *testing.T) {
+	t.Helper()
+	totpUsed.Lock()
+	saved := totpUsed.steps
+	totpUsed.steps = make(map[string]int64)
+	totpUsed.Unlock()
+	t.Cleanup(func() {
+		totpUsed.Lock()
+		totpUsed.steps = saved
+		totpUsed.Unlock()
+	})
+}
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA1, truncated to six digits
+	for unix, want := range map[int64]string{59: "287082", 1111111109: "081804", 1234567890: "005924", 2000000000: "279037"} {
+		code, err := TOTPCode(rfcSecret, time.Unix(unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, unix)
+	}
+	code, err := TOTPCode("gezd gnbv gy3t qojq gezd gnbv gy3t qojq", time.Unix(59, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "287082", code, "spaces and lower case as apps show secrets")
+
+	_, err = TOTPCode("not base32!", time.Now())
+	assert.ErrorContains(t, err, "invalid TOTP secret")
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	resetTOTPReplay(t)
+	now := time.Unix(1234567890, 0)
+	code, err := TOTPCode(rfcSecret, now)
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, verifyTOTP("verify-totp", rfcSecret, "123456", now), "not a valid TOTP code")
+	require.NoError(t, verifyTOTP("verify-totp", rfcSecret, code, now.Add(25*time.Second)), "a step late")
+	assert.ErrorContains(t, verifyTOTP("verify-totp", rfcSecret, code, now), "already used")
+
+	next, err := TOTPCode(rfcSecret, now.Add(totpStep*time.Second))
+	require.NoError(t, err)
+	assert.NoError(t, verifyTOTP("verify-totp", rfcSecret, next, now), "a step early")
+
+	old, err := TOTPCode(rfcSecret, now.Add(-2*totpStep*time.Second))
+	require.NoError(t, err)
+	assert.Error(t, verifyTOTP("verify-totp-old", rfcSecret, old, now), "two steps late")
+}
+
+func TestTOTPConfirmation(t *testing.T) {
+	resetTOTPReplay(t)
+	keyring.MockInit()
+	require.NoError(t, keyring.Set(KeyringServiceName, "prod-totp", rfcSecret))
+	now := time.Unix(2000000000, 0)
+	fixClock(t, now)
+	code, err := TOTPCode(rfcSecret, now)
+	require.NoError(t, err)
+
+	run := func(token string) error {
+		config := &Config{
+			Command:      "sudo reboot",
+			SafetyCheck:  true,
+			Force:        true,
+			ForceReason:  "stuck kernel",
+			ConfirmForce: TOTPConfirmation("prod-totp", token),
+		}
+		_, err := SafetyCheckMiddleware(func(*Config) (string, error) { return "ok", nil })(config)
+		return err
+	}
+
+	err = run("")
+	var refused *ForceRefusedError
+	require.True(t, errors.As(err, &refused))
+	assert.Contains(t, refused.Cause, "requires a TOTP code as confirm_token to force reboot")
+	var blocked *BlockedCommandError
+	assert.True(t, errors.As(err, &blocked), "still reported as blocked")
+
+	assert.ErrorContains(t, run("000000"), "not a valid TOTP code")
+	assert.NoError(t, run(code))
+	assert.ErrorContains(t, run(code), "already used")
+
+	// One confirmation shared by the hosts of a fleet command spends the code once
+	next, err := TOTPCode(rfcSecret, now.Add(totpStep*time.Second))
+	require.NoError(t, err)
+	confirm := TOTPConfirmation("prod-totp", next)
+	for range 2 {
+		_, err = confirm(&BlockedCommandError{Rule: "reboot", Command: "sudo reboot"})
+		assert.NoError(t, err)
+	}
+
+	_, err = TOTPConfirmation("missing-totp", "123456")(&BlockedCommandError{Rule: "reboot"})
+	assert.ErrorContains(t, err, "failed to read the TOTP secret 'missing-totp'")
+}
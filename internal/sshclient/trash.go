@@ -0,0 +1,158 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// TrashDir is the directory below the remote home that holds files
+	// removed with Config.RemoveBackup
+	TrashDir = ".sshx-trash"
+	// trashStampLayout names the directory created for each removal
+	trashStampLayout = "20060102-150405"
+)
+
+// TrashPurgeResult describes a trash purge
+type TrashPurgeResult struct {
+	Removed []string `json:"removed"` // Trash directories deleted
+	Kept    int      `json:"kept"`    // Trash directories younger than the cutoff
+}
+
+// Trash moves remotePath into a timestamped directory below ~/.sshx-trash
+// instead of deleting it, and returns where it was moved
+func (c *SSHClient) Trash(remotePath string) (trashed string, err error) {
+	op := c.newOperation(Request{})
+	err = op.withSFTP(func() error {
+		if err := op.checkPath(remotePath, true); err != nil {
+			return err
+		}
+		trashed, err = op.trashFile(remotePath, time.Now())
+		return err
+	})
+	return trashed, err
+}
+
+// PurgeTrash deletes the trash directories older than olderThan (all of
+// them when olderThan is 0)
+func (c *SSHClient) PurgeTrash(olderThan time.Duration) (result TrashPurgeResult, err error) {
+	op := c.newOperation(Request{})
+	err = op.withSFTP(func() error {
+		result, err = op.purgeTrash(olderThan, time.Now())
+		return err
+	})
+	return result, err
+}
+
+// trashFile moves remotePath below the trash directory, keeping its
+// absolute path so the origin of every file stays visible
+func (c *operation) trashFile(remotePath string, now time.Time) (string, error) {
+	if _, err := c.sftpClient.Lstat(remotePath); err != nil {
+		return "", fmt.Errorf("failed to stat file: %w", err)
+	}
+	home, err := c.sftpClient.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+	source := remotePath
+	if !path.IsAbs(source) {
+		source = path.Join(home, source)
+	}
+	source = path.Clean(source)
+	root := path.Join(home, TrashDir)
+	if err := checkTrashable(source, root); err != nil {
+		return "", err
+	}
+
+	// A second removal of the same path within a second gets its own
+	// directory
+	stamp := now.Format(trashStampLayout)
+	dest := trashPath(root, stamp, source)
+	for i := 1; ; i++ {
+		if _, err := c.sftpClient.Lstat(dest); err != nil {
+			break
+		}
+		dest = trashPath(root, fmt.Sprintf("%s-%d", stamp, i), source)
+	}
+
+	if err := c.sftpClient.MkdirAll(path.Dir(dest)); err != nil {
+		return "", fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	if err := c.sftpClient.Rename(source, dest); err != nil {
+		// SFTP cannot rename across filesystems; mv copies instead
+		if mvErr := c.executeSimpleCommand(BuildCommand("mv", "--", source, dest)); mvErr != nil {
+			return "", fmt.Errorf("failed to move %s to trash: %w", source, err)
+		}
+	}
+	return dest, nil
+}
+
+// purgeTrash deletes the trash directories older than olderThan
+func (c *operation) purgeTrash(olderThan time.Duration, now time.Time) (TrashPurgeResult, error) {
+	result := TrashPurgeResult{Removed: []string{}}
+	home, err := c.sftpClient.Getwd()
+	if err != nil {
+		return result, fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+	root := path.Join(home, TrashDir)
+	entries, err := c.sftpClient.ReadDir(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to read trash: %w", err)
+	}
+
+	for _, entry := range entries {
+		if olderThan > 0 && now.Sub(trashEntryTime(entry)) < olderThan {
+			result.Kept++
+			continue
+		}
+		target := path.Join(root, entry.Name())
+		if entry.IsDir() {
+			err = c.removeDirectory(target)
+		} else {
+			err = c.sftpClient.Remove(target)
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to purge %s: %w", target, err)
+		}
+		result.Removed = append(result.Removed, entry.Name())
+	}
+	return result, nil
+}
+
+// checkTrashable refuses sources that contain the trash directory or lie
+// inside it
+func checkTrashable(source, root string) error {
+	if source == "/" || isWithin(root, source) || isWithin(source, root) {
+		return fmt.Errorf("refusing to move %s to trash", source)
+	}
+	return nil
+}
+
+// isWithin reports whether p is dir or below it
+func isWithin(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// trashPath is where source goes in the trash directory stamp
+func trashPath(root, stamp, source string) string {
+	return path.Join(root, stamp, strings.TrimPrefix(source, "/"))
+}
+
+// trashEntryTime is the time a trash directory was created: the time in its
+// name, or its modification time for entries not created by Trash
+func trashEntryTime(entry os.FileInfo) time.Time {
+	name := entry.Name()
+	if len(name) >= len(trashStampLayout) {
+		if stamp, err := time.ParseInLocation(trashStampLayout, name[:len(trashStampLayout)], time.Local); err == nil {
+			return stamp
+		}
+	}
+	return entry.ModTime()
+}
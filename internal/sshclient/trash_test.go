@@ -0,0 +1,45 @@
+package sshclient
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type trashEntry struct {
+	os.FileInfo
+	name    string
+	modTime time.Time
+}
+
+func (e trashEntry) Name() string       { return e.name }
+func (e trashEntry) ModTime() time.Time { return e.modTime }
+
+func TestTrashPath(t *testing.T) {
+	assert.Equal(t, "/home/deploy/.sshx-trash/20240501-101500/etc/nginx/nginx.conf",
+		trashPath("/home/deploy/.sshx-trash", "20240501-101500", "/etc/nginx/nginx.conf"))
+	assert.Equal(t, "/root/.sshx-trash/20240501-101500-1/root/app",
+		trashPath("/root/.sshx-trash", "20240501-101500-1", "/root/app"))
+}
+
+func TestCheckTrashable(t *testing.T) {
+	root := "/home/deploy/.sshx-trash"
+	assert.NoError(t, checkTrashable("/etc/nginx/nginx.conf", root))
+	assert.NoError(t, checkTrashable("/home/deploy/.sshx-trashcan", root))
+	assert.NoError(t, checkTrashable("/home/deploy/app", root))
+
+	for _, source := range []string{"/", "/home", "/home/deploy", root, root + "/20240501-101500/etc"} {
+		assert.Error(t, checkTrashable(source, root), source)
+	}
+}
+
+func TestTrashEntryTime(t *testing.T) {
+	modified := time.Date(2023, 1, 2, 3, 4, 5, 0, time.Local)
+	stamp := time.Date(2024, 5, 1, 10, 15, 0, 0, time.Local)
+
+	assert.Equal(t, stamp, trashEntryTime(trashEntry{name: "20240501-101500", modTime: modified}))
+	assert.Equal(t, stamp, trashEntryTime(trashEntry{name: "20240501-101500-2", modTime: modified}))
+	assert.Equal(t, modified, trashEntryTime(trashEntry{name: "manual-backup", modTime: modified}))
+}
@@ -2,7 +2,10 @@ package sshclient
 
 import (
 	"fmt"
+	"path"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/talkincode/sshmcp/pkg/logger"
 	"github.com/zalando/go-keyring"
@@ -10,6 +13,65 @@ import (
 
 const KeyringServiceName = "sshx"
 
+// BlockedCommandError is returned by ValidateCommand when a command matches a
+// dangerous pattern. Its fields tell users and MCP clients what matched and
+// how to reach the same goal without forcing the command.
+type BlockedCommandError struct {
+	Command string `json:"command"`
+	Reason  string `json:"reason"`
+	// Rule names the kind of danger (e.g. "reboot") for SafetyPolicy
+	Rule string `json:"rule"`
+	// Pattern is the pattern, or the command with quotes removed, that matched
+	Pattern string `json:"pattern,omitempty"`
+	// Severity is the severity the safety policy gives Rule
+	Severity Severity `json:"severity"`
+	// Suggestion is a safer way to the same goal, if there is one
+	Suggestion string `json:"suggestion,omitempty"`
+	// NextWindow is when the next maintenance window opens, for a command
+	// the safety policy allows only inside its windows
+	NextWindow *time.Time `json:"next_window,omitempty"`
+}
+
+// blockedCommand reports command as blocked for reason, because of the
+// matched pattern or command
+func blockedCommand(command, reason, pattern string) *BlockedCommandError {
+	rule := ruleOf(reason)
+	return &BlockedCommandError{
+		Command:    command,
+		Reason:     reason,
+		Rule:       rule,
+		Pattern:    pattern,
+		Severity:   SeverityBlock,
+		Suggestion: ruleSuggestions[rule],
+	}
+}
+
+// keywordPattern shows the keywords of a dangerous pattern
+func keywordPattern(keywords []string) string {
+	parts := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		parts[i] = strings.TrimSpace(strings.TrimSuffix(keyword, "$"))
+	}
+	return strings.Join(parts, " ... ")
+}
+
+func (e *BlockedCommandError) Error() string {
+	var details strings.Builder
+	if e.Rule != "" {
+		fmt.Fprintf(&details, "Rule: %s\n", e.Rule)
+	}
+	if e.Pattern != "" {
+		fmt.Fprintf(&details, "Matched: %s\n", e.Pattern)
+	}
+	if e.Suggestion != "" {
+		fmt.Fprintf(&details, "Suggestion: %s\n", e.Suggestion)
+	}
+	if e.NextWindow != nil {
+		fmt.Fprintf(&details, "Outside the maintenance windows of the host; the next one opens %s\n", e.NextWindow.Format("Mon 2006-01-02 15:04 MST"))
+	}
+	return fmt.Sprintf("⚠️  Dangerous command blocked\nCommand: %s\nReason: %s\n%sIf you are sure, use --force with a reason (--force-reason, or force_reason over MCP)", e.Command, e.Reason, details.String())
+}
+
 // ValidateCommand validates command safety
 func ValidateCommand(command string) error {
 	cmd := strings.TrimSpace(command)
@@ -45,10 +107,10 @@ func ValidateCommand(command string) error {
 		if strings.HasSuffix(pattern.pattern, "$") {
 			patternLower = strings.TrimSuffix(patternLower, "$")
 			if strings.HasSuffix(cmdLower, patternLower) {
-				return fmt.Errorf("⚠️  Dangerous command blocked\nCommand: %s\nReason: %s\nIf you are sure, use --force or -f flag", cmd, pattern.reason)
+				return blockedCommand(cmd, pattern.reason, strings.TrimSpace(patternLower))
 			}
 		} else if strings.Contains(cmdWithSpaces, patternLower) {
-			return fmt.Errorf("⚠️  Dangerous command blocked\nCommand: %s\nReason: %s\nIf you are sure, use --force or -f flag", cmd, pattern.reason)
+			return blockedCommand(cmd, pattern.reason, strings.TrimSpace(patternLower))
 		}
 	}
 
@@ -104,16 +166,522 @@ func ValidateCommand(command string) error {
 			}
 		}
 		if allMatch {
-			return fmt.Errorf("⚠️  Dangerous command blocked\nCommand: %s\nReason: %s\nIf you are sure, use --force or -f flag", cmd, pattern.reason)
+			return blockedCommand(cmd, pattern.reason, keywordPattern(pattern.keywords))
 		}
 	}
 
+	// The patterns above see the command as typed; quoting, chaining and
+	// substitutions are resolved by inspecting each command on its own
+	if isForkBomb(cmdLower) {
+		return blockedCommand(cmd, "Fork bomb", ":(){ :|:& };:")
+	}
+	if reason, matched := inspectCommandLine(parseCommandLine(cmd, 0), 0); reason != "" {
+		return blockedCommand(cmd, reason, matched)
+	}
+
 	return nil
 }
 
+// systemDirectories are the directories whose recursive removal breaks the
+// host
+var systemDirectories = []string{"/bin", "/boot", "/dev", "/etc", "/lib", "/lib64", "/proc", "/sbin", "/sys", "/usr", "/var"}
+
+var (
+	// shells run the command string given with -c
+	shells = []string{"sh", "bash", "zsh", "dash", "ksh", "ash", "fish"}
+	// scriptInterpreters run a script read from stdin when given no script
+	scriptInterpreters = append([]string{"python", "python2", "python3", "perl", "ruby", "node", "php"}, shells...)
+)
+
+// inspectCommandLine checks every command of a parsed command line and of
+// the command lines it runs through substitutions, sh -c and eval. It
+// returns the reason a command is dangerous and the command, as the shell
+// sees it, that matched.
+func inspectCommandLine(line commandLine, depth int) (reason, matched string) {
+	chain := &chainState{vars: make(map[string]string)}
+	for _, pipeline := range line.pipelines {
+		pipeline = chain.apply(pipeline)
+		if reason, matched = inspectPipeline(pipeline, depth); reason != "" {
+			return reason, matched
+		}
+		chain.track(pipeline)
+	}
+	for _, nested := range line.nested {
+		if reason, matched = inspectCommandLine(nested, depth+1); reason != "" {
+			return reason, matched
+		}
+	}
+	return "", ""
+}
+
+// chainState is what earlier commands of a chain leave behind for later
+// ones: simple variable assignments, so x=rm; $x -rf / reads as rm -rf /,
+// and the directory cd changed to, so cd / && rm -rf * reads as rm -rf /*
+type chainState struct {
+	vars map[string]string
+	// dir is the working directory cd set, empty while it is unknown
+	dir string
+}
+
+// apply expands the variables assigned so far in the words of pipeline and
+// resolves the relative targets of commands that are checked by path
+func (s *chainState) apply(pipeline shellPipeline) shellPipeline {
+	expanded := make(shellPipeline, len(pipeline))
+	for i, command := range pipeline {
+		words := make([]string, len(command.words))
+		for j, word := range command.words {
+			words[j] = expandVariables(word, s.vars)
+		}
+		redirects := make([]string, len(command.redirects))
+		for j, target := range command.redirects {
+			redirects[j] = s.resolve(expandVariables(target, s.vars))
+		}
+		if unwrapped, _ := unwrapCommand(words); s.dir != "" && len(unwrapped) > 0 {
+			switch commandName(unwrapped[0]) {
+			case "rm", "chmod", "chown", "chgrp", "find":
+				offset := len(words) - len(unwrapped)
+				for j, arg := range unwrapped[1:] {
+					if !strings.HasPrefix(arg, "-") {
+						words[offset+1+j] = s.resolve(arg)
+					}
+				}
+			}
+		}
+		expanded[i] = shellCommand{words: words, redirects: redirects}
+	}
+	return expanded
+}
+
+// track records the variables a pipeline assigns and the directory it
+// changes to. Commands of a longer pipeline run in subshells and leave
+// nothing behind.
+func (s *chainState) track(pipeline shellPipeline) {
+	if len(pipeline) != 1 {
+		return
+	}
+	words := pipeline[0].words
+	if len(words) > 0 && (words[0] == "export" || words[0] == "readonly") {
+		words = words[1:]
+	}
+	if len(words) > 0 && !slices.ContainsFunc(words, func(word string) bool { return !isAssignment(word) }) {
+		for _, word := range words {
+			name, value, _ := strings.Cut(word, "=")
+			s.vars[name] = value
+		}
+		return
+	}
+	words, _ = unwrapCommand(words)
+	if len(words) == 0 || (commandName(words[0]) != "cd" && commandName(words[0]) != "pushd") {
+		return
+	}
+	args := positional(words[1:])
+	switch {
+	case len(args) == 0:
+		s.dir = "~"
+	case args[0] == "-" || strings.Contains(args[0], dynamicWord):
+		s.dir = ""
+	default:
+		s.dir = s.resolve(args[0])
+	}
+}
+
+// resolve makes a relative path argument absolute against the directory cd
+// changed to. Paths are left as they are while that directory is unknown.
+func (s *chainState) resolve(target string) string {
+	if s.dir == "" || target == "" || strings.HasPrefix(target, "/") || strings.HasPrefix(target, "~") || strings.HasPrefix(target, "$") {
+		return target
+	}
+	return path.Join(s.dir, target)
+}
+
+// expandVariables substitutes the variables in vars into word, as $name or
+// ${name}. Other variables are left as they are.
+func expandVariables(word string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(word, "$") {
+		return word
+	}
+	var b strings.Builder
+	for i := 0; i < len(word); i++ {
+		if word[i] != '$' {
+			b.WriteByte(word[i])
+			continue
+		}
+		name, end := "", i+1
+		if end < len(word) && word[end] == '{' {
+			if closing := strings.IndexByte(word[end:], '}'); closing > 0 {
+				name, end = word[end+1:end+closing], end+closing+1
+			}
+		} else {
+			for end < len(word) && isNameByte(word[end]) && word[end] != '-' {
+				end++
+			}
+			name = word[i+1 : end]
+		}
+		value, ok := vars[name]
+		if !ok {
+			b.WriteByte('$')
+			continue
+		}
+		b.WriteString(value)
+		i = end - 1
+	}
+	return b.String()
+}
+
+// inspectPipeline checks the commands of a pipeline, and what is piped into
+// an interpreter
+func inspectPipeline(pipeline shellPipeline, depth int) (reason, matched string) {
+	downloaded, decoded := false, false
+	for i, command := range pipeline {
+		words, script := unwrapCommand(command.words)
+		if script != "" {
+			if strings.Contains(script, decodedWord) {
+				return "Execute decoded payload", command.String()
+			}
+			if depth < maxParseDepth {
+				if reason, matched = inspectCommandLine(parseCommandLine(script, depth+1), depth+1); reason != "" {
+					return reason, matched
+				}
+			}
+		}
+		if reason = inspectRedirects(command.redirects); reason != "" {
+			return reason, command.String()
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if strings.Contains(words[0], decodedWord) {
+			return "Execute decoded payload", command.String()
+		}
+
+		name := commandName(words[0])
+		if script == "" && readsStdinScript(name, words[1:]) {
+			if downloaded {
+				return "Download and execute script from network", pipeline[:i+1].String()
+			}
+			if decoded {
+				return "Execute decoded payload", pipeline[:i+1].String()
+			}
+		}
+		if reason = inspectSimpleCommand(name, words[1:]); reason != "" {
+			return reason, command.String()
+		}
+		downloaded = downloaded || name == "curl" || name == "wget" || name == "fetch"
+		decoded = decoded || isDecoder(words)
+	}
+	return "", ""
+}
+
+// unwrapCommand strips variable assignments and wrappers such as sudo, env
+// and nohup from a command. For sh -c, su -c and eval it also returns the
+// command string they run.
+func unwrapCommand(words []string) ([]string, string) {
+	for len(words) > 0 {
+		for len(words) > 0 && isAssignment(words[0]) {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			return nil, ""
+		}
+		name := commandName(words[0])
+		args := words[1:]
+		switch name {
+		case "{", "}", "!", "if", "elif", "then", "else", "while", "until", "do", "time", "nohup", "exec", "builtin", "setsid", "chronic", "busybox", "unbuffer":
+			words = args
+		case "sudo", "doas":
+			words = skipOptions(args, "-u", "-g", "-h", "-p", "-C", "-D", "-r", "-t", "-U", "-T")
+		case "env":
+			words = skipOptions(args, "-u", "-C", "-S")
+		case "nice", "ionice", "stdbuf", "taskset", "chrt":
+			words = skipOptions(args, "-n", "-c", "-p", "-i", "-o", "-e")
+		case "xargs":
+			words = skipOptions(args, "-I", "-n", "-P", "-L", "-d", "-E", "-s", "-a")
+		case "timeout", "chroot", "flock":
+			// The duration, root directory or lock file comes first
+			words = skipOptions(args, "-s", "-k")
+			if len(words) > 0 {
+				words = words[1:]
+			}
+		case "command":
+			if len(args) > 0 && (args[0] == "-v" || args[0] == "-V") {
+				return words, ""
+			}
+			words = args
+		case "eval":
+			return words, strings.Join(args, " ")
+		case "su":
+			return words, optionValue(args, "-c", "--command")
+		default:
+			if slices.Contains(shells, name) {
+				for i, arg := range args {
+					if arg == "--" || !strings.HasPrefix(arg, "-") {
+						break
+					}
+					if !strings.HasPrefix(arg, "--") && strings.Contains(arg, "c") && i+1 < len(args) {
+						return words, args[i+1]
+					}
+				}
+			}
+			return words, ""
+		}
+	}
+	return words, ""
+}
+
+// isAssignment reports whether word is a variable assignment (NAME=value)
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// skipOptions drops the leading options of args, and the values of those
+// listed as taking one
+func skipOptions(args []string, withValue ...string) []string {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		option := args[0]
+		args = args[1:]
+		if option == "--" {
+			break
+		}
+		if slices.Contains(withValue, option) && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	for len(args) > 0 && isAssignment(args[0]) {
+		args = args[1:]
+	}
+	return args
+}
+
+// optionValue returns the value following the first of names in args
+func optionValue(args []string, names ...string) string {
+	for i, arg := range args {
+		if slices.Contains(names, arg) && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// readsStdinScript reports whether an interpreter runs the script piped
+// into it: no script argument, -s, - or /dev/stdin
+func readsStdinScript(name string, args []string) bool {
+	if name == "source" || name == "." {
+		return len(args) > 0 && (args[0] == "/dev/stdin" || args[0] == "-")
+	}
+	if !slices.Contains(scriptInterpreters, name) {
+		return false
+	}
+	for _, arg := range args {
+		switch {
+		case arg == "-s" || arg == "-" || arg == "/dev/stdin":
+			return true
+		case !strings.HasPrefix(arg, "-"):
+			return false
+		}
+	}
+	return true
+}
+
+// inspectSimpleCommand checks one command without its wrappers
+func inspectSimpleCommand(name string, args []string) string {
+	switch name {
+	case "rm":
+		return inspectRemove(args)
+	case "chmod", "chown", "chgrp":
+		if hasFlag(args, 'R') || slices.Contains(args, "--recursive") {
+			for _, target := range positional(args) {
+				if cleanTarget(target) == "/" {
+					return "Recursively change permissions of root directory"
+				}
+			}
+		}
+	case "find":
+		if paths := positional(args); len(paths) > 0 && cleanTarget(paths[0]) == "/" &&
+			(slices.Contains(args, "-delete") || (slices.Contains(args, "-exec") && slices.Contains(args, "rm"))) {
+			return "Delete root directory"
+		}
+	case "dd":
+		for _, arg := range args {
+			if target, ok := strings.CutPrefix(arg, "of="); ok && isBlockDevice(target) {
+				return "Dangerous dd operation"
+			}
+		}
+	case "fdisk", "sfdisk", "parted", "wipefs":
+		return "Disk partition operation"
+	case "mkswap":
+		return "Create swap partition"
+	case "shutdown":
+		return "System shutdown operation"
+	case "halt":
+		return "System halt operation"
+	case "poweroff":
+		return "System poweroff operation"
+	case "reboot":
+		return "System reboot operation"
+	case "init", "telinit":
+		if len(args) > 0 && args[0] == "0" {
+			return "System shutdown (init 0)"
+		}
+		if len(args) > 0 && args[0] == "6" {
+			return "System reboot (init 6)"
+		}
+	case "systemctl":
+		for _, arg := range args {
+			switch strings.ToLower(arg) {
+			case "halt", "poweroff", "reboot", "kexec":
+				return "System " + strings.ToLower(arg) + " operation"
+			}
+		}
+	case "iptables", "ip6tables":
+		if hasFlag(args, 'F') || slices.Contains(args, "--flush") {
+			return "Flush firewall rules"
+		}
+		if hasFlag(args, 'X') || slices.Contains(args, "--delete-chain") {
+			return "Delete firewall chain"
+		}
+	}
+	if strings.HasPrefix(name, "mkfs") {
+		return "Format filesystem"
+	}
+	return ""
+}
+
+// inspectRemove checks the targets of a recursive rm
+func inspectRemove(args []string) string {
+	recursive := hasFlag(args, 'r') || hasFlag(args, 'R') || slices.Contains(args, "--recursive")
+	if !recursive {
+		return ""
+	}
+	if slices.Contains(args, "--no-preserve-root") {
+		return "Delete root directory"
+	}
+	for _, target := range positional(args) {
+		switch clean := cleanTarget(target); {
+		case clean == "/":
+			return "Delete root directory"
+		case clean == "/*":
+			return "Delete all files in root directory"
+		case clean == "~" || clean == "~/*":
+			return "Delete user home directory"
+		case clean == "$home" || clean == "${home}" || clean == "$home/*" || clean == "${home}/*":
+			return "Delete $HOME directory"
+		case slices.Contains(systemDirectories, clean):
+			return "Delete system directory " + clean
+		}
+	}
+	return ""
+}
+
+// inspectRedirects checks the files a command writes to
+func inspectRedirects(targets []string) string {
+	for _, target := range targets {
+		switch clean := cleanTarget(target); {
+		case clean == "/etc/passwd":
+			return "Overwrite system password file"
+		case clean == "/etc/shadow":
+			return "Overwrite system shadow file"
+		case isBlockDevice(clean):
+			return "Overwrite block device"
+		}
+	}
+	return ""
+}
+
+// positional returns the arguments that are not options
+func positional(args []string) []string {
+	var result []string
+	for i, arg := range args {
+		if arg == "--" {
+			return append(result, args[i+1:]...)
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			result = append(result, arg)
+		}
+	}
+	return result
+}
+
+// cleanTarget normalizes a path argument: lower case, repeated slashes and
+// . or .. components resolved, so "//", "/." and "/tmp/.." all read as /
+func cleanTarget(target string) string {
+	target = strings.ToLower(target)
+	glob := strings.HasSuffix(target, "/*")
+	if glob {
+		target = strings.TrimSuffix(target, "/*")
+		if target == "" {
+			target = "/"
+		}
+	}
+	switch {
+	case strings.HasPrefix(target, "/"):
+		target = path.Clean(target)
+	case strings.HasPrefix(target, "~") || strings.HasPrefix(target, "$"):
+		if trimmed := strings.TrimRight(target, "/"); trimmed != "" {
+			target = trimmed
+		}
+	}
+	if glob {
+		return strings.TrimSuffix(target, "/") + "/*"
+	}
+	return target
+}
+
+// isBlockDevice reports whether a path names a disk device
+func isBlockDevice(target string) bool {
+	for _, prefix := range []string{"/dev/sd", "/dev/hd", "/dev/vd", "/dev/xvd", "/dev/nvme", "/dev/mmcblk", "/dev/dm-", "/dev/mapper/"} {
+		if strings.HasPrefix(target, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isForkBomb detects the classic fork bomb under any function name and
+// spacing, e.g. ":(){ :|:& };:" or "bomb() { bomb | bomb & }; bomb"
+func isForkBomb(command string) bool {
+	compact := strings.Join(strings.Fields(command), "")
+	for i := strings.Index(compact, "(){"); i > 0; {
+		start := i
+		for start > 0 && (isNameByte(compact[start-1]) || compact[start-1] == ':') {
+			start--
+		}
+		if name := compact[start:i]; name != "" {
+			if strings.HasPrefix(compact[i:], "(){"+name+"|"+name+"&};"+name) ||
+				strings.HasPrefix(compact[i:], "(){"+name+"|"+name+"&;};"+name) {
+				return true
+			}
+		}
+		next := strings.Index(compact[i+3:], "(){")
+		if next < 0 {
+			break
+		}
+		i += 3 + next
+	}
+	return false
+}
+
+func isNameByte(b byte) bool {
+	return b == '_' || b == '-' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
 // GetSudoPassword reads sudo password from system keyring (cross-platform support)
 // macOS: Keychain, Linux: Secret Service (gnome-keyring/kwallet), Windows: Credential Manager
+// Passwords are cached in memory for the configured TTL (see SetSudoPasswordTTL).
 func GetSudoPassword(key string) (string, error) {
+	if password, ok := sudoPasswordCache.get(key); ok {
+		logger.GetLogger().Debug("Sudo password loaded from memory cache for key: %s", key)
+		return password, nil
+	}
+
 	serviceName := KeyringServiceName
 
 	password, err := keyring.Get(serviceName, key)
@@ -133,6 +701,8 @@ func GetSudoPassword(key string) (string, error) {
 		return "", fmt.Errorf("empty sudo password in keyring for key: %s", key)
 	}
 
+	logger.RegisterSecret(password)
+	sudoPasswordCache.put(key, password)
 	logger.GetLogger().Success("Sudo password loaded from system keyring for key: %s", key)
 	return password, nil
 }
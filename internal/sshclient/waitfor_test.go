@@ -0,0 +1,109 @@
+package sshclient
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseWaitCondition(t *testing.T) {
+	tests := []struct {
+		spec string
+		want WaitCondition
+	}{
+		{"port:5432", WaitCondition{Kind: WaitPort, Target: "5432"}},
+		{"port:db1:5432", WaitCondition{Kind: WaitPort, Target: "db1:5432"}},
+		{"file:/run/app.pid", WaitCondition{Kind: WaitFile, Target: "/run/app.pid"}},
+		{"service:nginx.service", WaitCondition{Kind: WaitService, Target: "nginx.service"}},
+		{"http:http://localhost:8080/health", WaitCondition{Kind: WaitHTTP, Target: "http://localhost:8080/health"}},
+		{"https://example.com/ready", WaitCondition{Kind: WaitHTTP, Target: "https://example.com/ready"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseWaitCondition(tt.spec)
+		require.NoError(t, err, tt.spec)
+		assert.Equal(t, tt.want, got, tt.spec)
+	}
+
+	for _, spec := range []string{
+		"5432",
+		"port:",
+		"port:http",
+		"port:70000",
+		"port:db1;reboot:22",
+		"service:-nginx",
+		"service:nginx;reboot",
+		"http:ftp://example.com",
+		"socket:/run/app.sock",
+	} {
+		_, err := ParseWaitCondition(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestWaitConditionCheck(t *testing.T) {
+	assert.Equal(t, "test -e /run/app.pid", WaitCondition{Kind: WaitFile, Target: "/run/app.pid"}.Check())
+	assert.Equal(t, "systemctl is-active --quiet nginx", WaitCondition{Kind: WaitService, Target: "nginx"}.Check())
+	assert.Contains(t, WaitCondition{Kind: WaitPort, Target: "5432"}.Check(), "nc -z -w 2 '127.0.0.1' 5432")
+	assert.Contains(t, WaitCondition{Kind: WaitPort, Target: "db1:5432"}.Check(), "/dev/tcp/db1/5432")
+	assert.Contains(t, WaitCondition{Kind: WaitHTTP, Target: "http://x/health"}.Check(), "--max-time 5 'http://x/health')\" = 200")
+}
+
+// runWaitCommand runs the remote wait loop with the local shell
+func runWaitCommand(t *testing.T, condition WaitCondition, timeout time.Duration) string {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	output, err := exec.Command("sh", "-c", WaitForCommand(condition, timeout, time.Second)).CombinedOutput() // #nosec G204 -- test command
+	require.NoError(t, err, string(output))
+	return string(output)
+}
+
+func TestWaitForCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ready")
+	condition := WaitCondition{Kind: WaitFile, Target: path}
+
+	output := runWaitCommand(t, condition, time.Second)
+	assert.True(t, strings.HasPrefix(output, "sshx-wait: timeout "), output)
+	_, err := parseWaitOutput(output, condition, time.Second)
+	var timeoutErr *WaitTimeoutError
+	assert.ErrorAs(t, err, &timeoutErr)
+
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	output = runWaitCommand(t, condition, time.Second)
+	assert.Equal(t, "sshx-wait: ready 0\n", output)
+	waited, err := parseWaitOutput(output, condition, time.Second)
+	require.NoError(t, err)
+	assert.Zero(t, waited)
+}
+
+func TestSSHClient_WaitFor(t *testing.T) {
+	var ran string
+	conn := startExecServer(t, func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		ran = command
+		_, _ = channel.Write([]byte("sshx-wait: ready 4\n"))
+		return 0
+	})
+	client := &SSHClient{config: &Config{Host: "test"}, client: conn}
+
+	condition := WaitCondition{Kind: WaitService, Target: "nginx"}
+	waited, err := client.WaitFor(condition, 30*time.Second, 0, Request{})
+	require.NoError(t, err)
+	assert.Equal(t, 4*time.Second, waited)
+	assert.Contains(t, ran, "systemctl is-active --quiet nginx")
+	assert.Contains(t, ran, "-ge 30 ]")
+	assert.Contains(t, ran, "sleep 2;")
+
+	_, err = client.WaitFor(WaitCondition{Kind: "socket", Target: "x"}, 0, 0, Request{})
+	assert.ErrorContains(t, err, "unknown condition")
+
+	assert.Equal(t, 1, wholeSeconds(0))
+	assert.Equal(t, 2, wholeSeconds(1500*time.Millisecond))
+}
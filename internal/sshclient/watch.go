@@ -0,0 +1,197 @@
+package sshclient
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultWatchInterval is the pause between runs when none is given
+	DefaultWatchInterval = 2 * time.Second
+	// maxDiffCells bounds the line comparison of two outputs; larger
+	// changes are reported as the old lines removed and the new ones added
+	maxDiffCells = 1 << 20
+)
+
+// WatchOptions controls SSHClient.Watch
+type WatchOptions struct {
+	// Interval is the pause between the end of a run and the next one
+	// (default DefaultWatchInterval)
+	Interval time.Duration
+	// Until ends the watch once the output of a run matches
+	Until *regexp.Regexp
+	// MaxRuns ends the watch after this many runs (0 = until Stop)
+	MaxRuns int
+	// Stop ends the watch when closed; a run in progress completes first
+	Stop <-chan struct{}
+	// OnRun, when set, is called after every run
+	OnRun func(WatchRun)
+}
+
+// WatchRun is the outcome of one run of a watched command
+type WatchRun struct {
+	Run    int
+	Time   time.Time
+	Output string
+	// Err is the error of the run; the watch continues after failed runs
+	Err error
+	// Changed reports whether the output or error differs from the
+	// previous run; the first run always counts as changed
+	Changed bool
+	// Diff lists the changed lines, prefixed "+ " when added and "- " when
+	// removed (empty for the first run)
+	Diff    []string
+	Matched bool
+}
+
+// WatchResult summarizes a watch
+type WatchResult struct {
+	Runs    int
+	Changes int // Runs whose output differed from the previous run
+	Matched bool
+	Last    WatchRun
+}
+
+// Watch runs req repeatedly, reporting each run and how its output differs
+// from the previous one, until the output matches opts.Until, MaxRuns runs
+// have been made or opts.Stop is closed. Every run goes through Execute, so
+// middleware such as the safety check applies to each of them; a command the
+// safety check blocks ends the watch after its first run.
+func (c *SSHClient) Watch(req Request, opts WatchOptions) WatchResult {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	var result WatchResult
+	previous := ""
+	for {
+		output, err := c.Execute(req)
+		run := WatchRun{Run: result.Runs + 1, Time: time.Now(), Output: output.Output, Err: err}
+
+		current := watchText(run)
+		if result.Runs == 0 {
+			run.Changed = true
+		} else if current != previous {
+			run.Changed = true
+			run.Diff = DiffLines(previous, current)
+		}
+		previous = current
+		run.Matched = opts.Until != nil && err == nil && opts.Until.MatchString(run.Output)
+
+		result.Runs++
+		if run.Changed && result.Runs > 1 {
+			result.Changes++
+		}
+		result.Matched = run.Matched
+		result.Last = run
+		if opts.OnRun != nil {
+			opts.OnRun(run)
+		}
+
+		if run.Matched || (opts.MaxRuns > 0 && result.Runs >= opts.MaxRuns) {
+			return result
+		}
+		// A blocked command would be blocked on every run
+		var blocked *BlockedCommandError
+		if errors.As(err, &blocked) {
+			return result
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-opts.Stop:
+			timer.Stop()
+			return result
+		case <-timer.C:
+		}
+	}
+}
+
+// watchText is what Watch compares between runs: the output followed by
+// the error, if any
+func watchText(run WatchRun) string {
+	if run.Err == nil {
+		return run.Output
+	}
+	text := run.Output
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text + "error: " + run.Err.Error()
+}
+
+// DiffLines compares two texts line by line and returns the lines of a
+// missing from b prefixed "- " and the lines of b missing from a prefixed
+// "+ ", in order
+func DiffLines(a, b string) []string {
+	oldLines := splitLines(a)
+	newLines := splitLines(b)
+
+	// Lines shared at both ends are unchanged
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	oldLines = oldLines[prefix : len(oldLines)-suffix]
+	newLines = newLines[prefix : len(newLines)-suffix]
+
+	var diff []string
+	if len(oldLines)*len(newLines) > maxDiffCells {
+		for _, line := range oldLines {
+			diff = append(diff, "- "+line)
+		}
+		for _, line := range newLines {
+			diff = append(diff, "+ "+line)
+		}
+		return diff
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// oldLines[i:] and newLines[j:]
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			i++
+			j++
+		case i < len(oldLines) && (j == len(newLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "- "+oldLines[i])
+			i++
+		default:
+			diff = append(diff, "+ "+newLines[j])
+			j++
+		}
+	}
+	return diff
+}
+
+// splitLines splits text into lines without line endings
+func splitLines(text string) []string {
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,84 @@
+package sshclient
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDiffLines(t *testing.T) {
+	assert.Empty(t, DiffLines("a\nb\n", "a\nb"))
+	assert.Equal(t, []string{"- b", "+ B"}, DiffLines("a\nb\nc\n", "a\nB\nc\n"))
+	assert.Equal(t, []string{"+ d"}, DiffLines("a\nb\n", "a\nb\nd\n"))
+	assert.Equal(t, []string{"- a"}, DiffLines("a\nb\n", "b\n"))
+	assert.Equal(t, []string{"+ x"}, DiffLines("", "x"))
+	assert.Equal(t, []string{"- 1", "- 2", "+ 3", "+ 4", "+ 5"},
+		DiffLines("keep\n1\n2\nend\n", "keep\n3\n4\n5\nend\n"))
+	assert.Equal(t, []string{"- Filesystem 10%", "+ Filesystem 20%"},
+		DiffLines("header\r\nFilesystem 10%\r\n", "header\r\nFilesystem 20%\r\n"))
+}
+
+func TestSSHClient_Watch(t *testing.T) {
+	var runs atomic.Int32
+	conn := startExecServer(t, func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		n := runs.Add(1)
+		state := "progressing"
+		if n >= 3 {
+			state = "rolled out"
+		}
+		_, _ = fmt.Fprintf(channel, "deployment\nstatus: %s\n", state)
+		return 0
+	})
+	client := &SSHClient{config: &Config{Host: "test"}, client: conn}
+
+	var seen []WatchRun
+	result := client.Watch(Request{Command: "kubectl rollout status"}, WatchOptions{
+		Interval: time.Millisecond,
+		Until:    regexp.MustCompile(`rolled out`),
+		MaxRuns:  10,
+		OnRun:    func(run WatchRun) { seen = append(seen, run) },
+	})
+
+	assert.True(t, result.Matched)
+	assert.Equal(t, 3, result.Runs)
+	assert.Equal(t, 1, result.Changes)
+	require.Len(t, seen, 3)
+	assert.True(t, seen[0].Changed)
+	assert.Empty(t, seen[0].Diff)
+	assert.False(t, seen[1].Changed)
+	assert.Equal(t, []string{"- status: progressing", "+ status: rolled out"}, seen[2].Diff)
+	assert.True(t, seen[2].Matched)
+}
+
+func TestSSHClient_WatchLimits(t *testing.T) {
+	var runs atomic.Int32
+	conn := startExecServer(t, func(command string, channel ssh.Channel, _ <-chan struct{}) uint32 {
+		runs.Add(1)
+		_, _ = channel.Write([]byte("same\n"))
+		return 0
+	})
+	client := &SSHClient{config: &Config{Host: "test", SafetyCheck: true}, client: conn}
+
+	result := client.Watch(Request{Command: "uptime"}, WatchOptions{Interval: time.Millisecond, MaxRuns: 4})
+	assert.Equal(t, 4, result.Runs)
+	assert.Zero(t, result.Changes)
+	assert.False(t, result.Matched)
+
+	stop := make(chan struct{})
+	close(stop)
+	result = client.Watch(Request{Command: "uptime"}, WatchOptions{Interval: time.Hour, Stop: stop})
+	assert.Equal(t, 1, result.Runs)
+
+	runs.Store(0)
+	result = client.Watch(Request{Command: "rm -rf /"}, WatchOptions{Interval: time.Millisecond, MaxRuns: 5})
+	assert.Equal(t, 1, result.Runs, "blocked commands end the watch")
+	var blocked *BlockedCommandError
+	assert.ErrorAs(t, result.Last.Err, &blocked)
+	assert.Zero(t, runs.Load())
+}
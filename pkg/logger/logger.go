@@ -13,8 +13,10 @@ import (
 type LogLevel int
 
 const (
+	// LogLevelTrace 跟踪级别（连接协议细节）
+	LogLevelTrace LogLevel = iota
 	// LogLevelDebug 调试级别
-	LogLevelDebug LogLevel = iota
+	LogLevelDebug
 	// LogLevelInfo 信息级别
 	LogLevelInfo
 	// LogLevelWarning 警告级别
@@ -46,12 +48,19 @@ type Logger struct {
 	maxFiles    int       // 最大文件数量
 	currentSize int64     // 当前文件大小
 	prefix      string
+	traceLog    *log.Logger
 	debugLog    *log.Logger
 	infoLog     *log.Logger
 	warnLog     *log.Logger
 	errorLog    *log.Logger
+	sink        Sink     // 额外的日志接收者（如 MCP 客户端）
+	sinkLevel   LogLevel // 转发给 sink 的最低级别
 }
 
+// Sink 接收已屏蔽敏感信息的日志消息，与控制台和文件输出的级别无关。
+// Sink 内部不得再调用 Logger，否则会递归。
+type Sink func(level LogLevel, message string)
+
 var (
 	globalLogger     *Logger
 	globalLoggerOnce sync.Once
@@ -100,7 +109,10 @@ func (l *Logger) initLoggers() {
 	} else {
 		output = l.consoleOut
 	}
+	// 所有输出都经过敏感信息屏蔽，并在纯 ASCII 模式下替换表情符号
+	output = redactWriter{w: plainWriter{w: output}}
 
+	l.traceLog = log.New(output, l.prefix+"[TRACE] ", log.LstdFlags)
 	l.debugLog = log.New(output, l.prefix+"[DEBUG] ", log.LstdFlags)
 	l.infoLog = log.New(output, l.prefix+"", log.LstdFlags)
 	l.warnLog = log.New(output, l.prefix+"⚠️  ", log.LstdFlags)
@@ -255,6 +267,26 @@ func (l *Logger) GetLevel() LogLevel {
 	return l.level
 }
 
+// SetSink 设置额外的日志接收者，level 及以上的消息都会转发给它；
+// sink 为 nil 时停止转发
+func (l *Logger) SetSink(level LogLevel, sink Sink) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sink = sink
+	l.sinkLevel = level
+}
+
+// forward 将消息转发给 sink
+func (l *Logger) forward(level LogLevel, format string, args ...interface{}) {
+	l.mu.RLock()
+	sink, sinkLevel := l.sink, l.sinkLevel
+	l.mu.RUnlock()
+
+	if sink != nil && level >= sinkLevel {
+		sink(level, Redact(fmt.Sprintf(format, args...)))
+	}
+}
+
 // SetMaxSize 设置最大文件大小
 func (l *Logger) SetMaxSize(size int64) {
 	l.mu.Lock()
@@ -269,8 +301,24 @@ func (l *Logger) SetMaxFiles(count int) {
 	l.maxFiles = count
 }
 
+// Trace 记录连接协议等跟踪信息
+func (l *Logger) Trace(format string, args ...interface{}) {
+	l.forward(LogLevelTrace, format, args...)
+
+	l.mu.RLock()
+	level := l.level
+	l.mu.RUnlock()
+
+	if level <= LogLevelTrace {
+		l.traceLog.Printf(format, args...)
+		l.checkRotation()
+	}
+}
+
 // Debug 记录调试信息
 func (l *Logger) Debug(format string, args ...interface{}) {
+	l.forward(LogLevelDebug, format, args...)
+
 	l.mu.RLock()
 	level := l.level
 	l.mu.RUnlock()
@@ -283,6 +331,8 @@ func (l *Logger) Debug(format string, args ...interface{}) {
 
 // Info 记录普通信息
 func (l *Logger) Info(format string, args ...interface{}) {
+	l.forward(LogLevelInfo, format, args...)
+
 	l.mu.RLock()
 	level := l.level
 	l.mu.RUnlock()
@@ -295,6 +345,8 @@ func (l *Logger) Info(format string, args ...interface{}) {
 
 // Warning 记录警告信息
 func (l *Logger) Warning(format string, args ...interface{}) {
+	l.forward(LogLevelWarning, format, args...)
+
 	l.mu.RLock()
 	level := l.level
 	l.mu.RUnlock()
@@ -307,6 +359,8 @@ func (l *Logger) Warning(format string, args ...interface{}) {
 
 // Error 记录错误信息
 func (l *Logger) Error(format string, args ...interface{}) {
+	l.forward(LogLevelError, format, args...)
+
 	l.mu.RLock()
 	level := l.level
 	l.mu.RUnlock()
@@ -319,6 +373,8 @@ func (l *Logger) Error(format string, args ...interface{}) {
 
 // Success 记录成功信息（带 ✓ 标记）
 func (l *Logger) Success(format string, args ...interface{}) {
+	l.forward(LogLevelInfo, "✓ "+format, args...)
+
 	l.mu.RLock()
 	level := l.level
 	l.mu.RUnlock()
@@ -332,6 +388,8 @@ func (l *Logger) Success(format string, args ...interface{}) {
 
 // Tip 记录提示信息（带 💡 标记）
 func (l *Logger) Tip(format string, args ...interface{}) {
+	l.forward(LogLevelInfo, "💡 "+format, args...)
+
 	l.mu.RLock()
 	level := l.level
 	l.mu.RUnlock()
@@ -362,6 +420,17 @@ func (l *Logger) checkRotation() {
 	}
 }
 
+// Sync 将日志文件内容刷新到磁盘
+func (l *Logger) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.logFile != nil {
+		return l.logFile.Sync()
+	}
+	return nil
+}
+
 // Close 关闭日志记录器
 func (l *Logger) Close() error {
 	l.mu.Lock()
@@ -376,6 +445,8 @@ func (l *Logger) Close() error {
 // LogLevelFromString 从字符串解析日志级别
 func LogLevelFromString(level string) LogLevel {
 	switch level {
+	case "trace", "TRACE":
+		return LogLevelTrace
 	case "debug", "DEBUG":
 		return LogLevelDebug
 	case "info", "INFO":
@@ -392,6 +463,8 @@ func LogLevelFromString(level string) LogLevel {
 // String 返回日志级别的字符串表示
 func (l LogLevel) String() string {
 	switch l {
+	case LogLevelTrace:
+		return "TRACE"
 	case LogLevelDebug:
 		return "DEBUG"
 	case LogLevelInfo:
@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"bytes"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -84,11 +86,34 @@ func TestLogLevels(t *testing.T) {
 	}
 }
 
+func TestTraceLevel(t *testing.T) {
+	logger := NewLogger(LogLevelDebug, "")
+	var buf bytes.Buffer
+	logger.consoleOut = &buf
+	logger.initLoggers()
+
+	// Debug 级别不输出跟踪信息
+	logger.Trace("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("Trace message written at debug level: %q", buf.String())
+	}
+
+	logger.SetLevel(LogLevelTrace)
+	logger.Trace("server version %s", "SSH-2.0-OpenSSH_9.6")
+	if !strings.HasPrefix(buf.String(), "[TRACE] ") || !strings.Contains(buf.String(), "server version SSH-2.0-OpenSSH_9.6") {
+		t.Errorf("Trace message missing: %q", buf.String())
+	}
+	if LogLevelTrace.String() != "TRACE" {
+		t.Errorf("LogLevelTrace.String() = %q", LogLevelTrace.String())
+	}
+}
+
 func TestLogLevelFromString(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected LogLevel
 	}{
+		{"trace", LogLevelTrace},
 		{"debug", LogLevelDebug},
 		{"DEBUG", LogLevelDebug},
 		{"info", LogLevelInfo},
@@ -106,3 +131,52 @@ func TestLogLevelFromString(t *testing.T) {
 		}
 	}
 }
+
+func TestSync(t *testing.T) {
+	logger := NewLogger(LogLevelInfo, "")
+
+	// 未启用文件日志时 Sync 不应报错
+	if err := logger.Sync(); err != nil {
+		t.Errorf("Sync without file logging returned error: %v", err)
+	}
+
+	logPath := t.TempDir() + "/sync.log"
+	if err := logger.EnableFileLogging(logPath); err != nil {
+		t.Fatalf("Failed to enable file logging: %v", err)
+	}
+	defer func() { _ = logger.Close() }() //nolint:errcheck // test cleanup
+
+	logger.Info("flush me")
+	if err := logger.Sync(); err != nil {
+		t.Errorf("Sync returned error: %v", err)
+	}
+}
+
+func TestSink(t *testing.T) {
+	logger := NewLogger(LogLevelError, "")
+	logger.consoleOut = &bytes.Buffer{}
+	logger.initLoggers()
+
+	RegisterSecret("hunter2")
+	defer UnregisterSecret("hunter2")
+
+	var got []string
+	logger.SetSink(LogLevelInfo, func(level LogLevel, message string) {
+		got = append(got, level.String()+" "+message)
+	})
+	logger.Debug("dropped")
+	logger.Info("connecting to %s", "web1")
+	logger.Warning("password hunter2 rejected")
+	logger.Success("done")
+
+	want := []string{"INFO connecting to web1", "WARNING password ****** rejected", "INFO ✓ done"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("sink got %q, want %q", got, want)
+	}
+
+	logger.SetSink(LogLevelInfo, nil)
+	logger.Error("after")
+	if len(got) != len(want) {
+		t.Errorf("sink still called after it was removed: %q", got)
+	}
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+// plainMode 为 true 时输出中的表情符号替换为 ASCII 标记
+var plainMode atomic.Bool
+
+// plainReplacer 将输出中使用的符号映射为 ASCII 标记（较长的匹配在前）
+var plainReplacer = strings.NewReplacer(
+	"⚠️  ", "[WARN] ",
+	"⚠️", "[WARN]",
+	"⚠", "[WARN]",
+	"❌ ", "[FAIL] ",
+	"❌", "[FAIL]",
+	"✅", "[OK]",
+	"✓", "[OK]",
+	"💡 ", "[TIP] ",
+	"💡", "[TIP]",
+	"→", "->",
+)
+
+// SetPlain 启用或关闭纯 ASCII 输出（--no-color、NO_COLOR），
+// 适用于无法正确显示表情符号的终端和日志解析器
+func SetPlain(enabled bool) {
+	plainMode.Store(enabled)
+}
+
+// PlainEnabled 返回是否启用了纯 ASCII 输出
+func PlainEnabled() bool {
+	return plainMode.Load()
+}
+
+// Plain 在纯 ASCII 模式下将字符串中的表情符号替换为 ASCII 标记，否则原样返回
+func Plain(s string) string {
+	if !plainMode.Load() {
+		return s
+	}
+	return plainReplacer.Replace(s)
+}
+
+// plainWriter 在写入前按需替换表情符号
+type plainWriter struct {
+	w io.Writer
+}
+
+func (p plainWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(p.w, Plain(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPlain(t *testing.T) {
+	input := "⚠️  HOST KEY REVOKED! ✓ ok ❌ failed ✅ 💡 tip a → b"
+	if Plain(input) != input {
+		t.Errorf("Plain() changed text while disabled: %q", Plain(input))
+	}
+
+	SetPlain(true)
+	defer SetPlain(false)
+
+	got := Plain(input)
+	want := "[WARN] HOST KEY REVOKED! [OK] ok [FAIL] failed [OK] [TIP] tip a -> b"
+	if got != want {
+		t.Errorf("Plain() = %q, want %q", got, want)
+	}
+}
+
+func TestPlainLoggerOutput(t *testing.T) {
+	SetPlain(true)
+	defer SetPlain(false)
+
+	logger := NewLogger(LogLevelInfo, "")
+	var buf bytes.Buffer
+	logger.consoleOut = &buf
+	logger.initLoggers()
+
+	logger.Warning("disk almost full")
+	logger.Error("connection refused")
+	logger.Success("uploaded")
+	logger.Tip("use --force")
+
+	output := buf.String()
+	for _, want := range []string{"[WARN] ", "[FAIL] ", "[OK] uploaded", "[TIP] use --force"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	for _, r := range output {
+		if r > 127 {
+			t.Fatalf("output contains non-ASCII %q:\n%s", r, output)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RedactedMask 替换敏感信息的掩码
+const RedactedMask = "******"
+
+// minSecretLength 是会被屏蔽的最短敏感值长度：按子串屏蔽一两个字符
+// 会破坏无关的日志内容，而它们本身也透露不了多少
+const minSecretLength = 4
+
+var (
+	secretsMu sync.RWMutex
+	// secrets 记录每个敏感值被注册的次数，多个连接可能共用同一个密码
+	secrets = make(map[string]int)
+)
+
+// RegisterSecret 注册需要在日志中屏蔽的敏感值（如 sudo 密码），
+// 过短的值不会被屏蔽
+func RegisterSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets[secret]++
+}
+
+// UnregisterSecret 撤销一次 RegisterSecret，最后一次撤销后不再屏蔽该值
+func UnregisterSecret(secret string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if secrets[secret] <= 1 {
+		delete(secrets, secret)
+		return
+	}
+	secrets[secret]--
+}
+
+// Redact 将字符串中所有已注册的敏感值替换为掩码
+func Redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+
+	if len(secrets) == 0 || s == "" {
+		return s
+	}
+
+	// 先替换较长的值，避免短值是长值子串时泄露部分内容
+	values := make([]string, 0, len(secrets))
+	for secret := range secrets {
+		values = append(values, secret)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	for _, secret := range values {
+		s = strings.ReplaceAll(s, secret, RedactedMask)
+	}
+	return s
+}
+
+// redactWriter 在写入前屏蔽敏感值
+type redactWriter struct {
+	w io.Writer
+}
+
+func (r redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	RegisterSecret("hunter2")
+	RegisterSecret("hunter2-long")
+	defer UnregisterSecret("hunter2")
+	defer UnregisterSecret("hunter2-long")
+
+	got := Redact("printf hunter2 | sudo -S id; echo hunter2-long")
+	if strings.Contains(got, "hunter2") {
+		t.Errorf("Redact() leaked secret: %s", got)
+	}
+	if got != "printf ****** | sudo -S id; echo ******" {
+		t.Errorf("Redact() = %q", got)
+	}
+
+	if Redact("") != "" {
+		t.Error("Redact(\"\") should be empty")
+	}
+}
+
+func TestRegisterSecret_IgnoresEmpty(t *testing.T) {
+	RegisterSecret("")
+	if Redact("plain text") != "plain text" {
+		t.Error("empty secret should not affect output")
+	}
+}
+
+func TestRegisterSecret_IgnoresShort(t *testing.T) {
+	RegisterSecret("x")
+	defer UnregisterSecret("x")
+	if got := Redact("exit status 1"); got != "exit status 1" {
+		t.Errorf("short secret should not be masked, got %q", got)
+	}
+}
+
+func TestUnregisterSecret_Counted(t *testing.T) {
+	RegisterSecret("shared-pass")
+	RegisterSecret("shared-pass")
+
+	UnregisterSecret("shared-pass")
+	if Redact("shared-pass") != RedactedMask {
+		t.Error("secret still registered once should stay masked")
+	}
+	UnregisterSecret("shared-pass")
+	if Redact("shared-pass") != "shared-pass" {
+		t.Error("secret should no longer be masked")
+	}
+}
+
+func TestLogger_RedactsOutput(t *testing.T) {
+	RegisterSecret("s3cr3t-pass")
+	defer UnregisterSecret("s3cr3t-pass")
+
+	var buf bytes.Buffer
+	l := NewLogger(LogLevelDebug, "")
+	l.consoleOut = &buf
+	l.initLoggers()
+
+	l.Debug("Executing: echo %s", "s3cr3t-pass")
+	l.Success("password is s3cr3t-pass")
+
+	if strings.Contains(buf.String(), "s3cr3t-pass") {
+		t.Errorf("log output leaked secret:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), RedactedMask) {
+		t.Errorf("log output should contain mask:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,167 @@
+// Package sshx exposes the sshx SSH client for programs that embed it.
+//
+// A minimal embedding connects, registers middleware and runs a command:
+//
+//	sshx.Use(func(next sshx.Executor) sshx.Executor {
+//		return func(config *sshx.Config) (string, error) {
+//			log.Printf("running %q on %s", config.Command, config.Host)
+//			return next(config)
+//		}
+//	})
+//
+//	client, err := sshx.NewClient(&sshx.Config{Host: "10.0.0.5", User: "root", SafetyCheck: true})
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//	if err := client.Connect(); err != nil {
+//		return err
+//	}
+//	output, err := client.ExecuteCommandWithOutput(sshx.Request{Command: "uptime"})
+//
+// A connected Client may run requests from several goroutines at once.
+package sshx
+
+import (
+	"github.com/talkincode/sshmcp/internal/sshclient"
+)
+
+type (
+	// Config describes the target host and the command to execute
+	Config = sshclient.Config
+	// Client is an SSH client bound to a single Config
+	Client = sshclient.SSHClient
+	// Request is one command, script or SFTP operation run by a Client
+	Request = sshclient.Request
+	// Result is the output of a command run with Client.Execute
+	Result = sshclient.Result
+	// ProgressFunc receives the progress of transfers and scripts (Config.Progress)
+	ProgressFunc = sshclient.ProgressFunc
+	// DBOptions describes a database dump or restore (Client.DumpDatabase, Client.RestoreDatabase)
+	DBOptions = sshclient.DBOptions
+	// DBTransferResult describes a verified database dump or restore
+	DBTransferResult = sshclient.DBTransferResult
+	// WatchOptions controls Client.Watch (interval, until pattern, run limit)
+	WatchOptions = sshclient.WatchOptions
+	// WatchRun is one run of a watched command with the lines that changed
+	WatchRun = sshclient.WatchRun
+	// WatchResult summarizes a watch
+	WatchResult = sshclient.WatchResult
+	// WaitCondition is a readiness check for Client.WaitFor (port, file, service or http)
+	WaitCondition = sshclient.WaitCondition
+	// WaitTimeoutError is returned by Client.WaitFor when the condition never held
+	WaitTimeoutError = sshclient.WaitTimeoutError
+	// ListOptions filters, sorts and paginates Client.List
+	ListOptions = sshclient.ListOptions
+	// Listing is one page of a remote directory listing
+	Listing = sshclient.Listing
+	// FileEntry is one entry of a Listing
+	FileEntry = sshclient.FileEntry
+	// FileTooLargeError is returned by Client.ReadFile when a file exceeds the limit
+	FileTooLargeError = sshclient.FileTooLargeError
+	// FileRange is a slice of a remote file returned by Client.ReadFileRange
+	FileRange = sshclient.FileRange
+	// PathPolicy restricts the remote paths of SFTP operations (Config.PathPolicy)
+	PathPolicy = sshclient.PathPolicy
+	// PathDeniedError is returned when a PathPolicy forbids an SFTP operation
+	PathDeniedError = sshclient.PathDeniedError
+	// Sandbox confines commands with an environment, time and resource limits (Config.Sandbox)
+	Sandbox = sshclient.Sandbox
+	// Executor runs the command described by a Config and returns its output
+	Executor = sshclient.Executor
+	// Middleware wraps an Executor with pre/post execution logic
+	Middleware = sshclient.Middleware
+	// BlockedCommandError is returned when the safety validator rejects a command
+	BlockedCommandError = sshclient.BlockedCommandError
+	// Severity is what happens to a flagged command: info, warn or block
+	Severity = sshclient.Severity
+	// SafetyPolicy sets the severity of flagged commands per rule (Config.Safety)
+	SafetyPolicy = sshclient.SafetyPolicy
+	// SafetyFinding describes a flagged command a SafetyPolicy let run
+	SafetyFinding = sshclient.SafetyFinding
+	// ForceRefusedError is returned when Config.Force lacks the reason or
+	// confirmation required to run a blocked command
+	ForceRefusedError = sshclient.ForceRefusedError
+	// Event is a step of a command execution published by EventsMiddleware
+	Event = sshclient.Event
+)
+
+// Execution event types
+const (
+	EventStarted  = sshclient.EventStarted
+	EventOutput   = sshclient.EventOutput
+	EventFinished = sshclient.EventFinished
+	EventBlocked  = sshclient.EventBlocked
+)
+
+// Severities of flagged commands
+const (
+	SeverityInfo  = sshclient.SeverityInfo
+	SeverityWarn  = sshclient.SeverityWarn
+	SeverityBlock = sshclient.SeverityBlock
+)
+
+// NewClient creates a client for config, applying defaults for empty fields
+func NewClient(config *Config) (*Client, error) {
+	return sshclient.NewSSHClient(config)
+}
+
+// Use registers middleware applied to every command executed by any Client.
+// Middleware registered first runs outermost.
+func Use(mw ...Middleware) {
+	sshclient.Use(mw...)
+}
+
+// Chain composes middleware around final so that mw[0] is the outermost layer
+func Chain(final Executor, mw ...Middleware) Executor {
+	return sshclient.Chain(final, mw...)
+}
+
+// ValidateCommand reports whether command is blocked by the safety validator
+func ValidateCommand(command string) error {
+	return sshclient.ValidateCommand(command)
+}
+
+// CheckCommand applies policy to what the safety validator flags in
+// command: an error when it blocks the command, otherwise the finding
+func CheckCommand(command string, policy *SafetyPolicy) (*SafetyFinding, error) {
+	return sshclient.CheckCommand(command, policy)
+}
+
+// EventsMiddleware publishes started, output, finished and blocked events
+// for every command; register it with Use to receive them
+func EventsMiddleware(next Executor) Executor {
+	return sshclient.EventsMiddleware(next)
+}
+
+// SubscribeEvents returns a channel receiving execution events from now on
+// and the function ending the subscription. Slow subscribers miss events.
+func SubscribeEvents() (<-chan Event, func()) {
+	return sshclient.SubscribeEvents()
+}
+
+// RecentEvents returns the latest started, finished and blocked events
+func RecentEvents() []Event {
+	return sshclient.RecentEvents()
+}
+
+// ShellQuote quotes s as a single POSIX shell word
+func ShellQuote(s string) string {
+	return sshclient.ShellQuote(s)
+}
+
+// BuildCommand joins args into a command line that passes each of them to
+// the remote command unchanged, quoting only the words that need it
+func BuildCommand(args ...string) string {
+	return sshclient.BuildCommand(args...)
+}
+
+// DiffLines compares two outputs line by line ("- " removed, "+ " added)
+func DiffLines(a, b string) []string {
+	return sshclient.DiffLines(a, b)
+}
+
+// ClosePool closes all pooled connections
+func ClosePool() {
+	sshclient.GetConnectionPool().Close()
+}
@@ -0,0 +1,6 @@
+// Package sshxpb holds the protobuf messages and gRPC clients of the API
+// served by `sshx daemon --grpc-listen`. Clients in other languages are
+// generated from sshx.proto.
+package sshxpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative sshx.proto
@@ -0,0 +1,625 @@
+// gRPC API of `sshx daemon --grpc-listen=127.0.0.1:7423`.
+//
+// Every call needs an "authorization: Bearer <token>" metadata entry holding
+// an mcp_auth token, or the token in ~/.sshmcp/daemon.token when no mcp_auth
+// policies are configured. Calls run through the same token policies, safety
+// checks and audit log as MCP tool calls.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: sshx.proto
+
+package sshxpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Exec_Run_FullMethodName = "/sshx.v1.Exec/Run"
+)
+
+// ExecClient is the client API for Exec service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Exec runs commands on remote hosts (the ssh_execute tool).
+type ExecClient interface {
+	// Run runs one command and returns its output. A command refused by the
+	// safety check fails with PERMISSION_DENIED.
+	Run(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunResponse, error)
+}
+
+type execClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewExecClient(cc grpc.ClientConnInterface) ExecClient {
+	return &execClient{cc}
+}
+
+func (c *execClient) Run(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunResponse)
+	err := c.cc.Invoke(ctx, Exec_Run_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExecServer is the server API for Exec service.
+// All implementations must embed UnimplementedExecServer
+// for forward compatibility.
+//
+// Exec runs commands on remote hosts (the ssh_execute tool).
+type ExecServer interface {
+	// Run runs one command and returns its output. A command refused by the
+	// safety check fails with PERMISSION_DENIED.
+	Run(context.Context, *RunRequest) (*RunResponse, error)
+	mustEmbedUnimplementedExecServer()
+}
+
+// UnimplementedExecServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedExecServer struct{}
+
+func (UnimplementedExecServer) Run(context.Context, *RunRequest) (*RunResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Run not implemented")
+}
+func (UnimplementedExecServer) mustEmbedUnimplementedExecServer() {}
+func (UnimplementedExecServer) testEmbeddedByValue()              {}
+
+// UnsafeExecServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ExecServer will
+// result in compilation errors.
+type UnsafeExecServer interface {
+	mustEmbedUnimplementedExecServer()
+}
+
+func RegisterExecServer(s grpc.ServiceRegistrar, srv ExecServer) {
+	// If the following call pancis, it indicates UnimplementedExecServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Exec_ServiceDesc, srv)
+}
+
+func _Exec_Run_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecServer).Run(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Exec_Run_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecServer).Run(ctx, req.(*RunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Exec_ServiceDesc is the grpc.ServiceDesc for Exec service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Exec_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sshx.v1.Exec",
+	HandlerType: (*ExecServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Run",
+			Handler:    _Exec_Run_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "sshx.proto",
+}
+
+const (
+	Transfer_Upload_FullMethodName   = "/sshx.v1.Transfer/Upload"
+	Transfer_Download_FullMethodName = "/sshx.v1.Transfer/Download"
+)
+
+// TransferClient is the client API for Transfer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Transfer copies files over SFTP (the sftp_upload and sftp_download tools).
+type TransferClient interface {
+	// Upload writes local_path or content to remote_path.
+	Upload(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*TransferResponse, error)
+	// Download copies remote_path to local_path, or returns it inline.
+	Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (*TransferResponse, error)
+}
+
+type transferClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTransferClient(cc grpc.ClientConnInterface) TransferClient {
+	return &transferClient{cc}
+}
+
+func (c *transferClient) Upload(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*TransferResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransferResponse)
+	err := c.cc.Invoke(ctx, Transfer_Upload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transferClient) Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (*TransferResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransferResponse)
+	err := c.cc.Invoke(ctx, Transfer_Download_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TransferServer is the server API for Transfer service.
+// All implementations must embed UnimplementedTransferServer
+// for forward compatibility.
+//
+// Transfer copies files over SFTP (the sftp_upload and sftp_download tools).
+type TransferServer interface {
+	// Upload writes local_path or content to remote_path.
+	Upload(context.Context, *UploadRequest) (*TransferResponse, error)
+	// Download copies remote_path to local_path, or returns it inline.
+	Download(context.Context, *DownloadRequest) (*TransferResponse, error)
+	mustEmbedUnimplementedTransferServer()
+}
+
+// UnimplementedTransferServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTransferServer struct{}
+
+func (UnimplementedTransferServer) Upload(context.Context, *UploadRequest) (*TransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Upload not implemented")
+}
+func (UnimplementedTransferServer) Download(context.Context, *DownloadRequest) (*TransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Download not implemented")
+}
+func (UnimplementedTransferServer) mustEmbedUnimplementedTransferServer() {}
+func (UnimplementedTransferServer) testEmbeddedByValue()                  {}
+
+// UnsafeTransferServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TransferServer will
+// result in compilation errors.
+type UnsafeTransferServer interface {
+	mustEmbedUnimplementedTransferServer()
+}
+
+func RegisterTransferServer(s grpc.ServiceRegistrar, srv TransferServer) {
+	// If the following call pancis, it indicates UnimplementedTransferServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Transfer_ServiceDesc, srv)
+}
+
+func _Transfer_Upload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransferServer).Upload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Transfer_Upload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransferServer).Upload(ctx, req.(*UploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Transfer_Download_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DownloadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransferServer).Download(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Transfer_Download_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransferServer).Download(ctx, req.(*DownloadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Transfer_ServiceDesc is the grpc.ServiceDesc for Transfer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Transfer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sshx.v1.Transfer",
+	HandlerType: (*TransferServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Upload",
+			Handler:    _Transfer_Upload_Handler,
+		},
+		{
+			MethodName: "Download",
+			Handler:    _Transfer_Download_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "sshx.proto",
+}
+
+const (
+	HostAdmin_List_FullMethodName   = "/sshx.v1.HostAdmin/List"
+	HostAdmin_Add_FullMethodName    = "/sshx.v1.HostAdmin/Add"
+	HostAdmin_Update_FullMethodName = "/sshx.v1.HostAdmin/Update"
+	HostAdmin_Remove_FullMethodName = "/sshx.v1.HostAdmin/Remove"
+)
+
+// HostAdminClient is the client API for HostAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// HostAdmin manages the configured hosts (the host_* tools).
+type HostAdminClient interface {
+	// List returns the configured hosts.
+	List(ctx context.Context, in *ListHostsRequest, opts ...grpc.CallOption) (*ListHostsResponse, error)
+	// Add adds a host; port, user and type default to 22, master and linux.
+	Add(ctx context.Context, in *AddHostRequest, opts ...grpc.CallOption) (*HostAdminResponse, error)
+	// Update changes the fields of a host named in update_mask.
+	Update(ctx context.Context, in *UpdateHostRequest, opts ...grpc.CallOption) (*HostAdminResponse, error)
+	// Remove removes a host.
+	Remove(ctx context.Context, in *RemoveHostRequest, opts ...grpc.CallOption) (*HostAdminResponse, error)
+}
+
+type hostAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHostAdminClient(cc grpc.ClientConnInterface) HostAdminClient {
+	return &hostAdminClient{cc}
+}
+
+func (c *hostAdminClient) List(ctx context.Context, in *ListHostsRequest, opts ...grpc.CallOption) (*ListHostsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListHostsResponse)
+	err := c.cc.Invoke(ctx, HostAdmin_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostAdminClient) Add(ctx context.Context, in *AddHostRequest, opts ...grpc.CallOption) (*HostAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HostAdminResponse)
+	err := c.cc.Invoke(ctx, HostAdmin_Add_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostAdminClient) Update(ctx context.Context, in *UpdateHostRequest, opts ...grpc.CallOption) (*HostAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HostAdminResponse)
+	err := c.cc.Invoke(ctx, HostAdmin_Update_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostAdminClient) Remove(ctx context.Context, in *RemoveHostRequest, opts ...grpc.CallOption) (*HostAdminResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HostAdminResponse)
+	err := c.cc.Invoke(ctx, HostAdmin_Remove_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HostAdminServer is the server API for HostAdmin service.
+// All implementations must embed UnimplementedHostAdminServer
+// for forward compatibility.
+//
+// HostAdmin manages the configured hosts (the host_* tools).
+type HostAdminServer interface {
+	// List returns the configured hosts.
+	List(context.Context, *ListHostsRequest) (*ListHostsResponse, error)
+	// Add adds a host; port, user and type default to 22, master and linux.
+	Add(context.Context, *AddHostRequest) (*HostAdminResponse, error)
+	// Update changes the fields of a host named in update_mask.
+	Update(context.Context, *UpdateHostRequest) (*HostAdminResponse, error)
+	// Remove removes a host.
+	Remove(context.Context, *RemoveHostRequest) (*HostAdminResponse, error)
+	mustEmbedUnimplementedHostAdminServer()
+}
+
+// UnimplementedHostAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedHostAdminServer struct{}
+
+func (UnimplementedHostAdminServer) List(context.Context, *ListHostsRequest) (*ListHostsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedHostAdminServer) Add(context.Context, *AddHostRequest) (*HostAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Add not implemented")
+}
+func (UnimplementedHostAdminServer) Update(context.Context, *UpdateHostRequest) (*HostAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedHostAdminServer) Remove(context.Context, *RemoveHostRequest) (*HostAdminResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Remove not implemented")
+}
+func (UnimplementedHostAdminServer) mustEmbedUnimplementedHostAdminServer() {}
+func (UnimplementedHostAdminServer) testEmbeddedByValue()                   {}
+
+// UnsafeHostAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HostAdminServer will
+// result in compilation errors.
+type UnsafeHostAdminServer interface {
+	mustEmbedUnimplementedHostAdminServer()
+}
+
+func RegisterHostAdminServer(s grpc.ServiceRegistrar, srv HostAdminServer) {
+	// If the following call pancis, it indicates UnimplementedHostAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&HostAdmin_ServiceDesc, srv)
+}
+
+func _HostAdmin_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListHostsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostAdminServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostAdmin_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostAdminServer).List(ctx, req.(*ListHostsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostAdmin_Add_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddHostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostAdminServer).Add(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostAdmin_Add_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostAdminServer).Add(ctx, req.(*AddHostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostAdmin_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateHostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostAdminServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostAdmin_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostAdminServer).Update(ctx, req.(*UpdateHostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostAdmin_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveHostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostAdminServer).Remove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostAdmin_Remove_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostAdminServer).Remove(ctx, req.(*RemoveHostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// HostAdmin_ServiceDesc is the grpc.ServiceDesc for HostAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HostAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sshx.v1.HostAdmin",
+	HandlerType: (*HostAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _HostAdmin_List_Handler,
+		},
+		{
+			MethodName: "Add",
+			Handler:    _HostAdmin_Add_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _HostAdmin_Update_Handler,
+		},
+		{
+			MethodName: "Remove",
+			Handler:    _HostAdmin_Remove_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "sshx.proto",
+}
+
+const (
+	Events_Subscribe_FullMethodName = "/sshx.v1.Events/Subscribe"
+)
+
+// EventsClient is the client API for Events service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Events streams what sshx does.
+type EventsClient interface {
+	// Subscribe streams every audit log entry recorded from now on, limited
+	// to the hosts the token may use.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type eventsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEventsClient(cc grpc.ClientConnInterface) EventsClient {
+	return &eventsClient{cc}
+}
+
+func (c *eventsClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Events_ServiceDesc.Streams[0], Events_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Events_SubscribeClient = grpc.ServerStreamingClient[Event]
+
+// EventsServer is the server API for Events service.
+// All implementations must embed UnimplementedEventsServer
+// for forward compatibility.
+//
+// Events streams what sshx does.
+type EventsServer interface {
+	// Subscribe streams every audit log entry recorded from now on, limited
+	// to the hosts the token may use.
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedEventsServer()
+}
+
+// UnimplementedEventsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedEventsServer struct{}
+
+func (UnimplementedEventsServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedEventsServer) mustEmbedUnimplementedEventsServer() {}
+func (UnimplementedEventsServer) testEmbeddedByValue()                {}
+
+// UnsafeEventsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EventsServer will
+// result in compilation errors.
+type UnsafeEventsServer interface {
+	mustEmbedUnimplementedEventsServer()
+}
+
+func RegisterEventsServer(s grpc.ServiceRegistrar, srv EventsServer) {
+	// If the following call pancis, it indicates UnimplementedEventsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Events_ServiceDesc, srv)
+}
+
+func _Events_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EventsServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Events_SubscribeServer = grpc.ServerStreamingServer[Event]
+
+// Events_ServiceDesc is the grpc.ServiceDesc for Events service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Events_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sshx.v1.Events",
+	HandlerType: (*EventsServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Events_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sshx.proto",
+}